@@ -56,16 +56,18 @@ var agentCmd = &cobra.Command{
 
 			case <-sigs.Reload():
 				reloadTotal++
+				actor := ctr.ReloadActor()
 
 				// 需要重新加载配置文件 reload 失败则保持原配置运行
 				cfg, err := confengine.LoadConfigPath(configPath)
 				if err != nil {
+					ctr.RecordAudit(actor, controller.AuditActionReload, "path="+configPath, err)
 					fmt.Fprintf(os.Stderr, "failed to load config (count=%d): %v\n", reloadTotal, err)
 					continue
 				}
 
 				start := time.Now()
-				if err := ctr.Reload(cfg); err != nil {
+				if err := ctr.Reload(actor, cfg); err != nil {
 					logger.Errorf("failed to reload config: %v", err)
 				}
 				logger.Infof("reload count=%d, take %s", reloadTotal, time.Since(start))
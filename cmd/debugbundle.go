@@ -0,0 +1,82 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/packetd/packetd/internal/debugbundle"
+	"github.com/packetd/packetd/internal/sigs"
+)
+
+type debugBundleOptions struct {
+	Addr    string
+	Config  string
+	Output  string
+	Timeout time.Duration
+}
+
+var debugBundleConfig debugBundleOptions
+
+var debugBundleCmd = &cobra.Command{
+	Use:   "debug-bundle",
+	Short: "Collect config, self-metrics, recent errors and connection summaries of a running agent into a tar.gz for bug reports",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-sigs.Terminate()
+			cancel()
+		}()
+
+		output := debugBundleConfig.Output
+		if output == "" {
+			output = "packetd-debug-" + time.Now().Format("20060102-150405") + ".tar.gz"
+		}
+		f, err := os.Create(output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create bundle: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+
+		manifest, err := debugbundle.Collect(ctx, debugbundle.Config{
+			Addr:       debugBundleConfig.Addr,
+			ConfigPath: debugBundleConfig.Config,
+			Timeout:    debugBundleConfig.Timeout,
+		}, f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write bundle: %v\n", err)
+			os.Exit(1)
+		}
+
+		for file, reason := range manifest.Failures {
+			fmt.Fprintf(os.Stderr, "skipped %s: %s\n", file, reason)
+		}
+		fmt.Println(output)
+	},
+	Example: "# packetd debug-bundle --addr localhost:9091 --config packetd.yaml",
+}
+
+func init() {
+	debugBundleCmd.Flags().StringVar(&debugBundleConfig.Addr, "addr", "localhost:9091", "HTTP server address of the running agent")
+	debugBundleCmd.Flags().StringVar(&debugBundleConfig.Config, "config", "packetd.yaml", "Configuration file path, credentials are scrubbed")
+	debugBundleCmd.Flags().StringVar(&debugBundleConfig.Output, "output", "", "Bundle file path, defaults to packetd-debug-<time>.tar.gz")
+	debugBundleCmd.Flags().DurationVar(&debugBundleConfig.Timeout, "timeout", 10*time.Second, "Timeout of each request to the agent")
+	rootCmd.AddCommand(debugBundleCmd)
+}
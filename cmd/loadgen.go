@@ -0,0 +1,61 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/packetd/packetd/internal/loadgen"
+	"github.com/packetd/packetd/internal/sigs"
+)
+
+var loadgenConfig loadgen.Config
+
+var loadgenCmd = &cobra.Command{
+	Use:   "loadgen",
+	Short: "Generate synthetic traffic through the decode path and report achievable throughput",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-sigs.Terminate()
+			cancel()
+		}()
+
+		fmt.Printf("generating %s traffic for %s (conns=%d, rate=%d/s)...\n",
+			strings.Join(loadgenConfig.Protocols, ","), loadgenConfig.Duration, loadgenConfig.Conns, loadgenConfig.Rate)
+
+		result, err := loadgen.Run(ctx, loadgenConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to generate traffic: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(result)
+	},
+	Example: "# packetd loadgen --proto http,mysql --conns 100 --duration 30s",
+}
+
+func init() {
+	loadgenCmd.Flags().StringSliceVar(&loadgenConfig.Protocols, "proto", loadgen.Protocols(), "Protocols to generate, supported: "+strings.Join(loadgen.Protocols(), ","))
+	loadgenCmd.Flags().IntVar(&loadgenConfig.Conns, "conns", 16, "Concurrent connections per protocol")
+	loadgenCmd.Flags().IntVar(&loadgenConfig.Rate, "rate", 0, "Maximum roundtrips per second, 0 for unlimited")
+	loadgenCmd.Flags().DurationVar(&loadgenConfig.Duration, "duration", 10*time.Second, "Duration of the load test")
+	loadgenCmd.Flags().IntVar(&loadgenConfig.Workers, "workers", 0, "Number of decode workers, defaults to 2*NumCPU")
+	rootCmd.AddCommand(loadgenCmd)
+}
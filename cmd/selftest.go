@@ -0,0 +1,65 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/selftest"
+	"github.com/packetd/packetd/internal/sigs"
+)
+
+type selftestOptions struct {
+	Protocols []string
+	Duration  time.Duration
+}
+
+var selftestConfig selftestOptions
+
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Run every registered protocol decoder against built-in samples and report pass/fail and throughput",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-sigs.Terminate()
+			cancel()
+		}()
+
+		protos := make([]socket.L7Proto, 0, len(selftestConfig.Protocols))
+		for _, proto := range selftestConfig.Protocols {
+			protos = append(protos, socket.L7Proto(proto))
+		}
+
+		results := selftest.Run(ctx, protos, selftestConfig.Duration)
+		selftest.Print(os.Stdout, results)
+		if !selftest.Passed(results) {
+			fmt.Fprintln(os.Stderr, "selftest failed")
+			os.Exit(1)
+		}
+	},
+	Example: "# packetd selftest --duration 2s",
+}
+
+func init() {
+	selftestCmd.Flags().StringSliceVar(&selftestConfig.Protocols, "proto", nil, "Protocols to test, defaults to all registered decoders")
+	selftestCmd.Flags().DurationVar(&selftestConfig.Duration, "duration", time.Second, "Duration of the throughput run per decoder")
+	rootCmd.AddCommand(selftestCmd)
+}
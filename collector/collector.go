@@ -0,0 +1,239 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"google.golang.org/grpc"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/confengine"
+	"github.com/packetd/packetd/exporter"
+	_ "github.com/packetd/packetd/exporter/sinker/metrics"
+	_ "github.com/packetd/packetd/exporter/sinker/roundtrips"
+	_ "github.com/packetd/packetd/exporter/sinker/traces"
+	"github.com/packetd/packetd/internal/metricstorage"
+	"github.com/packetd/packetd/internal/relay"
+	"github.com/packetd/packetd/logger"
+	"github.com/packetd/packetd/server"
+)
+
+// Collector 接收多个 agent 推送的指标以及 Span 去重后统一写入 exporter 配置的存储
+//
+// 指标在 collector 内部按照 labels 全局聚合 TSDB 仅需承接 collector 的写入
+type Collector struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	conf   Config
+
+	exp            *exporter.Exporter
+	svr            *server.Server
+	grpc           *grpc.Server
+	metricsStorage *metricstorage.Storage
+
+	metrics    *deduper
+	traces     *deduper
+	correlator *correlator
+
+	auth       *authenticator
+	roundTrips *roundTripHub
+}
+
+func New(conf *confengine.Config) (*Collector, error) {
+	var cfg Config
+	if err := conf.UnpackChild("collector", &cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	metricsStorage, err := metricstorage.New(conf)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := exporter.New(conf, metricsStorage)
+	if err != nil {
+		return nil, err
+	}
+	svr, err := server.New(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Collector{
+		ctx:            ctx,
+		cancel:         cancel,
+		conf:           cfg,
+		exp:            exp,
+		svr:            svr,
+		metricsStorage: metricsStorage,
+		auth:           newAuthenticator(cfg.Auth),
+		roundTrips:     newRoundTripHub(cfg.RoundTrips.Recent),
+	}
+	if cfg.Dedup.Enabled {
+		c.metrics = newDeduper(cfg.Dedup.Window, cfg.Dedup.Tolerance)
+		c.traces = newDeduper(cfg.Dedup.Window, cfg.Dedup.Tolerance)
+	}
+	if cfg.Correlation.Enabled {
+		c.correlator = newCorrelator(cfg.Correlation.Window)
+	}
+	c.grpc, err = relay.NewServer(c.handle, relay.ServerConfig{TLS: cfg.TLS, Tokens: cfg.PushTokens})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Collector) Start() error {
+	l, err := net.Listen("tcp", c.conf.Listen)
+	if err != nil {
+		return errors.Wrapf(err, "listen collector address '%s' failed", c.conf.Listen)
+	}
+
+	c.exp.Start()
+	go func() {
+		if err := c.grpc.Serve(l); err != nil {
+			logger.Errorf("failed to serve collector: %v", err)
+		}
+	}()
+
+	if c.svr != nil {
+		c.svr.RegisterGetRoute("/metrics", promhttp.Handler().ServeHTTP)
+		c.svr.RegisterGetRoute("/protocol/metrics", c.routeProtoMetrics)
+		c.svr.RegisterGetRoute("/watch", c.routeWatch)
+		c.svr.RegisterGetRoute("/roundtrips", c.routeRoundTrips)
+		go func() {
+			if err := c.svr.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Errorf("failed to start server: %v", err)
+			}
+		}()
+	}
+
+	if c.conf.Dedup.Enabled || c.conf.Correlation.Enabled {
+		go c.loopGc()
+	}
+	logger.Infof("collector listening on %s", c.conf.Listen)
+	return nil
+}
+
+func (c *Collector) Stop() {
+	c.cancel()
+	c.grpc.GracefulStop()
+	c.exp.Close()
+	c.metricsStorage.Close()
+}
+
+func (c *Collector) routeProtoMetrics(w http.ResponseWriter, _ *http.Request) {
+	c.metricsStorage.WritePrometheus(w)
+}
+
+func (c *Collector) loopGc() {
+	ticker := time.NewTicker(min(c.conf.Dedup.Window, c.conf.Correlation.Window))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case now := <-ticker.C:
+			if c.metrics != nil {
+				c.metrics.gc(now)
+				c.traces.gc(now)
+			}
+			if c.correlator != nil {
+				c.correlator.gc(now)
+			}
+		}
+	}
+}
+
+func (c *Collector) handle(_ context.Context, b *relay.Batch) (*relay.Ack, error) {
+	now := time.Now()
+	ack := &relay.Ack{}
+
+	for _, obs := range b.Metrics {
+		// 关联需要两端的观测 因此先于去重
+		if c.correlator != nil {
+			if cr, ok := c.correlator.correlate(b.Agent, obs.Key, now); ok {
+				correlatedTransitSeconds.Observe(cr.transit.Seconds())
+				correlatedServerSeconds.Observe(cr.server.Seconds())
+			}
+		}
+		if c.metrics != nil && c.metrics.duplicated(b.Agent, obs.Key, now) {
+			ack.Duplicated++
+			duplicatedTotal.WithLabelValues(string(common.RecordMetrics)).Inc()
+			continue
+		}
+		ack.Accepted++
+		c.exp.Export(&common.Record{
+			RecordType: common.RecordMetrics,
+			Data:       &common.MetricsData{Data: obs.Data},
+		})
+	}
+	receivedTotal.WithLabelValues(b.Agent, string(common.RecordMetrics)).Add(float64(len(b.Metrics)))
+
+	if len(b.RoundTrips) > 0 {
+		c.roundTrips.publish(b.Agent, b.RoundTrips)
+		receivedTotal.WithLabelValues(b.Agent, string(common.RecordRoundTrips)).Add(float64(len(b.RoundTrips)))
+	}
+
+	if len(b.Traces) == 0 {
+		return ack, nil
+	}
+
+	var unmarshaler ptrace.ProtoUnmarshaler
+	traces, err := unmarshaler.UnmarshalTraces(b.Traces)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unmarshal traces from agent (%s) failed", b.Agent)
+	}
+
+	var idx int
+	rss := traces.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		sss := rss.At(i).ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			spans := sss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				var key relay.Key
+				if idx < len(b.SpanKeys) {
+					key = b.SpanKeys[idx]
+				}
+				idx++
+
+				if c.traces != nil && c.traces.duplicated(b.Agent, key, now) {
+					ack.Duplicated++
+					duplicatedTotal.WithLabelValues(string(common.RecordTraces)).Inc()
+					continue
+				}
+				ack.Accepted++
+				c.exp.Export(&common.Record{
+					RecordType: common.RecordTraces,
+					Data:       &common.TracesData{Data: spans.At(k)},
+				})
+			}
+		}
+	}
+	receivedTotal.WithLabelValues(b.Agent, string(common.RecordTraces)).Add(float64(idx))
+	return ack, nil
+}
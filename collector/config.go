@@ -0,0 +1,87 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"time"
+
+	"github.com/packetd/packetd/internal/relay"
+)
+
+type Config struct {
+	// Listen gRPC 监听地址 接收 agent 推送的数据
+	Listen string `config:"listen"`
+
+	// TLS gRPC 传输层加密配置
+	TLS relay.TLSConfig `config:"tls"`
+
+	// PushTokens 允许 agent 推送数据的 token 列表 为空时不做校验
+	PushTokens []string `config:"pushTokens"`
+
+	Dedup DedupConfig `config:"dedup"`
+
+	Correlation CorrelationConfig `config:"correlation"`
+
+	// RoundTrips agent 转发的 roundtrips 的查询配置
+	RoundTrips RoundTripsConfig `config:"roundTrips"`
+
+	Auth AuthConfig `config:"auth"`
+}
+
+type RoundTripsConfig struct {
+	// Recent 缓存最近的 roundtrips 数量 供 /roundtrips 查询
+	Recent int `config:"recent"`
+}
+
+// DedupConfig 跨 agent 去重配置
+//
+// 同一条链接的客户端与服务端均部署了 agent 时 同一个 roundtrip 会被观测两次
+type DedupConfig struct {
+	Enabled bool `config:"enabled"`
+
+	// Window 去重索引的保留时长
+	Window time.Duration `config:"window"`
+
+	// Tolerance 两个 agent 观测到的请求时间允许的最大偏差
+	Tolerance time.Duration `config:"tolerance"`
+}
+
+// CorrelationConfig 跨主机请求关联配置（实验性）
+//
+// 链接两端的 agent 均开启 controller.tcpTimestamps 时 以链接以及请求首个数据段的 TSval 配对同一个请求
+type CorrelationConfig struct {
+	Enabled bool `config:"enabled"`
+
+	// Window 等待另一端观测的最长时长
+	Window time.Duration `config:"window"`
+}
+
+func (c *Config) Validate() error {
+	if c.Listen == "" {
+		c.Listen = ":9093"
+	}
+	if c.Dedup.Window <= 0 {
+		c.Dedup.Window = 30 * time.Second
+	}
+	if c.Dedup.Tolerance <= 0 {
+		c.Dedup.Tolerance = 20 * time.Millisecond
+	}
+	if c.Correlation.Window <= 0 {
+		c.Correlation.Window = 30 * time.Second
+	}
+	if c.RoundTrips.Recent <= 0 {
+		c.RoundTrips.Recent = 1000
+	}
+	return c.Auth.Validate()
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/packetd/packetd/internal/relay"
+)
+
+type correlationKey struct {
+	hash  uint64
+	tsval uint32
+}
+
+type pendingSighting struct {
+	agent     string
+	duration  time.Duration
+	seen      time.Time
+	ambiguous bool
+}
+
+// correlation 两端 agent 观测到的同一个请求的耗时拆分
+//
+// 客户端一侧的耗时包含往返的网络传输 服务端一侧仅包含服务端处理 两者之差即为网络传输耗时
+// 仅依赖各自主机上的耗时 不受两端时钟偏差影响
+type correlation struct {
+	transit time.Duration
+	server  time.Duration
+}
+
+// correlator 以链接以及 TSval 关联两端 agent 上报的同一个请求
+type correlator struct {
+	mut     sync.Mutex
+	window  time.Duration
+	pending map[correlationKey]pendingSighting
+}
+
+func newCorrelator(window time.Duration) *correlator {
+	return &correlator{
+		window:  window,
+		pending: make(map[correlationKey]pendingSighting),
+	}
+}
+
+// correlate 记录观测 与另一个 agent 的观测配对成功时返回耗时拆分
+//
+// TSval 精度通常为毫秒 同一 agent 在同一链接上出现相同 TSval 的多个请求时无法确定配对关系 直接放弃
+func (c *correlator) correlate(agent string, key relay.Key, now time.Time) (correlation, bool) {
+	if !key.Valid() || key.TSVal == 0 {
+		return correlation{}, false
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	ck := correlationKey{hash: key.Hash, tsval: key.TSVal}
+	s, ok := c.pending[ck]
+	if !ok {
+		c.pending[ck] = pendingSighting{agent: agent, duration: key.Duration, seen: now}
+		return correlation{}, false
+	}
+	if s.agent == agent {
+		s.ambiguous = true
+		c.pending[ck] = s
+		return correlation{}, false
+	}
+
+	delete(c.pending, ck)
+	if s.ambiguous {
+		correlatedTotal.WithLabelValues("ambiguous").Inc()
+		return correlation{}, false
+	}
+	correlatedTotal.WithLabelValues("matched").Inc()
+	client, server := max(s.duration, key.Duration), min(s.duration, key.Duration)
+	return correlation{transit: client - server, server: server}, true
+}
+
+func (c *correlator) gc(now time.Time) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	for ck, s := range c.pending {
+		if now.Sub(s.seen) > c.window {
+			delete(c.pending, ck)
+			correlatedTotal.WithLabelValues("unmatched").Inc()
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/internal/relay"
+)
+
+func TestCorrelator(t *testing.T) {
+	now := time.Now()
+	key := func(hash uint64, tsval uint32, d time.Duration) relay.Key {
+		return relay.Key{Hash: hash, Time: now, TSVal: tsval, Duration: d}
+	}
+
+	tests := []struct {
+		name   string
+		agent  string
+		key    relay.Key
+		expect correlation
+		ok     bool
+	}{
+		{name: "server side", agent: "server", key: key(1, 100, 8*time.Millisecond)},
+		{name: "client side", agent: "client", key: key(1, 100, 10*time.Millisecond), expect: correlation{transit: 2 * time.Millisecond, server: 8 * time.Millisecond}, ok: true},
+		{name: "client first", agent: "client", key: key(1, 101, 5*time.Millisecond)},
+		{name: "server after", agent: "server", key: key(1, 101, 4*time.Millisecond), expect: correlation{transit: time.Millisecond, server: 4 * time.Millisecond}, ok: true},
+		{name: "same tsval", agent: "server", key: key(1, 102, time.Millisecond)},
+		{name: "same tsval again", agent: "server", key: key(1, 102, time.Millisecond)},
+		{name: "ambiguous", agent: "client", key: key(1, 102, 3*time.Millisecond)},
+		{name: "without tsval", agent: "client", key: key(1, 0, time.Millisecond)},
+		{name: "without tsval other", agent: "server", key: key(1, 0, time.Millisecond)},
+	}
+
+	c := newCorrelator(time.Minute)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := c.correlate(tt.agent, tt.key, now)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expect, got)
+		})
+	}
+	assert.Empty(t, c.pending)
+}
+
+func TestCorrelatorGc(t *testing.T) {
+	now := time.Now()
+	c := newCorrelator(time.Second)
+
+	_, ok := c.correlate("a", relay.Key{Hash: 1, TSVal: 100}, now)
+	assert.False(t, ok)
+	c.gc(now.Add(2 * time.Second))
+	assert.Empty(t, c.pending)
+
+	_, ok = c.correlate("b", relay.Key{Hash: 1, TSVal: 100}, now)
+	assert.False(t, ok)
+}
@@ -0,0 +1,93 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/packetd/packetd/internal/relay"
+)
+
+type sighting struct {
+	agent string
+	time  time.Time
+	seen  time.Time
+}
+
+// deduper 跨 agent 去重
+//
+// 仅丢弃来自不同 agent 的重复观测 同一 agent 在容忍区间内的多个 roundtrip 均为真实请求
+type deduper struct {
+	mut       sync.Mutex
+	window    time.Duration
+	tolerance time.Duration
+	index     map[uint64][]sighting
+}
+
+func newDeduper(window, tolerance time.Duration) *deduper {
+	return &deduper{
+		window:    window,
+		tolerance: tolerance,
+		index:     make(map[uint64][]sighting),
+	}
+}
+
+// duplicated 返回该观测是否已由其他 agent 上报 未上报时记录至索引
+func (d *deduper) duplicated(agent string, key relay.Key, now time.Time) bool {
+	if !key.Valid() {
+		return false
+	}
+
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	sightings := d.index[key.Hash]
+	for i, s := range sightings {
+		if s.agent == agent {
+			continue
+		}
+		delta := s.time.Sub(key.Time)
+		if delta < 0 {
+			delta = -delta
+		}
+		if delta <= d.tolerance {
+			// 每个观测至多抵消一次 避免第三个 agent 的真实请求被误判
+			d.index[key.Hash] = append(sightings[:i], sightings[i+1:]...)
+			return true
+		}
+	}
+	d.index[key.Hash] = append(sightings, sighting{agent: agent, time: key.Time, seen: now})
+	return false
+}
+
+func (d *deduper) gc(now time.Time) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	for hash, sightings := range d.index {
+		n := 0
+		for _, s := range sightings {
+			if now.Sub(s.seen) <= d.window {
+				sightings[n] = s
+				n++
+			}
+		}
+		if n == 0 {
+			delete(d.index, hash)
+			continue
+		}
+		d.index[hash] = sightings[:n]
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/internal/relay"
+)
+
+func TestDeduper(t *testing.T) {
+	now := time.Now()
+	key := relay.Key{Hash: 1, Time: now}
+
+	tests := []struct {
+		name   string
+		agent  string
+		key    relay.Key
+		expect bool
+	}{
+		{name: "first sighting", agent: "a", key: key},
+		{name: "same agent", agent: "a", key: relay.Key{Hash: 1, Time: now.Add(time.Millisecond)}},
+		{name: "other agent", agent: "b", key: relay.Key{Hash: 1, Time: now.Add(5 * time.Millisecond)}, expect: true},
+		{name: "beyond tolerance", agent: "b", key: relay.Key{Hash: 1, Time: now.Add(time.Second)}},
+		{name: "other hash", agent: "b", key: relay.Key{Hash: 2, Time: now}},
+		{name: "invalid key", agent: "b", key: relay.Key{}},
+	}
+
+	d := newDeduper(time.Minute, 20*time.Millisecond)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, d.duplicated(tt.agent, tt.key, now))
+		})
+	}
+}
+
+func TestDeduperGc(t *testing.T) {
+	now := time.Now()
+	d := newDeduper(time.Second, 20*time.Millisecond)
+
+	assert.False(t, d.duplicated("a", relay.Key{Hash: 1, Time: now}, now))
+	d.gc(now.Add(2 * time.Second))
+	assert.Empty(t, d.index)
+	assert.False(t, d.duplicated("b", relay.Key{Hash: 1, Time: now}, now))
+}
@@ -0,0 +1,68 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/packetd/packetd/common"
+)
+
+var (
+	receivedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: common.App,
+			Name:      "collector_received_total",
+			Help:      "Collector received records total",
+		},
+		[]string{"agent", "type"},
+	)
+
+	duplicatedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: common.App,
+			Name:      "collector_duplicated_total",
+			Help:      "Collector dropped duplicated records total",
+		},
+		[]string{"type"},
+	)
+
+	correlatedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: common.App,
+			Name:      "collector_correlated_total",
+			Help:      "Collector cross-host correlation results total",
+		},
+		[]string{"result"},
+	)
+
+	correlatedTransitSeconds = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: common.App,
+			Name:      "collector_correlated_transit_seconds",
+			Help:      "Network transit time of correlated requests",
+			Buckets:   prometheus.ExponentialBuckets(0.00005, 2, 18),
+		},
+	)
+
+	correlatedServerSeconds = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: common.App,
+			Name:      "collector_correlated_server_seconds",
+			Help:      "Server time of correlated requests",
+			Buckets:   prometheus.ExponentialBuckets(0.00005, 2, 18),
+		},
+	)
+)
@@ -0,0 +1,176 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	stdjson "encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/packetd/packetd/internal/json"
+	"github.com/packetd/packetd/internal/pubsub"
+	"github.com/packetd/packetd/internal/relay"
+)
+
+// scopedRoundTrip collector 输出的 roundtrip 附带来源 agent 以及标签
+type scopedRoundTrip struct {
+	Agent     string
+	Labels    map[string]string `json:",omitempty"`
+	RoundTrip stdjson.RawMessage
+}
+
+type roundTripEntry struct {
+	labels map[string]string
+	data   []byte
+}
+
+// roundTripHub 缓存最近的 roundtrips 并分发至订阅方
+type roundTripHub struct {
+	mut    sync.Mutex
+	recent []roundTripEntry
+	next   int
+	full   bool
+	bus    *pubsub.PubSub
+}
+
+func newRoundTripHub(size int) *roundTripHub {
+	return &roundTripHub{
+		recent: make([]roundTripEntry, size),
+		bus:    pubsub.New(),
+	}
+}
+
+func (h *roundTripHub) publish(agent string, records []relay.RoundTripRecord) {
+	for _, record := range records {
+		b, err := json.Marshal(scopedRoundTrip{
+			Agent:     agent,
+			Labels:    record.Labels,
+			RoundTrip: record.Data,
+		})
+		if err != nil {
+			continue
+		}
+
+		entry := roundTripEntry{labels: record.Labels, data: b}
+		h.mut.Lock()
+		if len(h.recent) > 0 {
+			h.recent[h.next] = entry
+			h.next = (h.next + 1) % len(h.recent)
+			h.full = h.full || h.next == 0
+		}
+		h.mut.Unlock()
+
+		if h.bus.Num() > 0 {
+			h.bus.Publish(entry)
+		}
+	}
+}
+
+// query 返回范围内最近的 limit 条 roundtrips 按照时间先后排列
+func (h *roundTripHub) query(s scope, limit int) [][]byte {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	n := h.next
+	if h.full {
+		n = len(h.recent)
+	}
+
+	var lst [][]byte
+	for i := 0; i < n && len(lst) < limit; i++ {
+		idx := (h.next - 1 - i + len(h.recent)) % len(h.recent)
+		if entry := h.recent[idx]; s.match(entry.labels) {
+			lst = append(lst, entry.data)
+		}
+	}
+	for i, j := 0, len(lst)-1; i < j; i, j = i+1, j-1 {
+		lst[i], lst[j] = lst[j], lst[i]
+	}
+	return lst
+}
+
+func (c *Collector) authorize(w http.ResponseWriter, r *http.Request) (scope, bool) {
+	s, ok := c.auth.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+	return s, ok
+}
+
+// routeWatch 持续输出权限范围内的 roundtrips 参数同 agent 的 /watch
+func (c *Collector) routeWatch(w http.ResponseWriter, r *http.Request) {
+	s, ok := c.authorize(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return
+	}
+
+	var maxMessage int
+	maxMessage, _ = strconv.Atoi(r.URL.Query().Get("max_message"))
+	if maxMessage <= 0 {
+		maxMessage = 100
+	}
+
+	var timeout time.Duration
+	timeout, _ = time.ParseDuration(r.URL.Query().Get("timeout"))
+	if timeout <= 0 {
+		timeout = time.Second * 5
+	}
+
+	queue := c.roundTrips.bus.Subscribe(10)
+	defer c.roundTrips.bus.Unsubscribe(queue)
+
+	for i := 0; i < maxMessage; {
+		data, ok := queue.PopTimeout(timeout)
+		if !ok {
+			return
+		}
+
+		entry := data.(roundTripEntry)
+		if !s.match(entry.labels) {
+			continue
+		}
+		i++
+		w.Write(entry.data)
+		w.Write([]byte{'\n'})
+		flusher.Flush()
+	}
+}
+
+// routeRoundTrips 返回权限范围内最近的 roundtrips
+//
+// - limit: 最多返回的数量 默认为 100
+func (c *Collector) routeRoundTrips(w http.ResponseWriter, r *http.Request) {
+	s, ok := c.authorize(w, r)
+	if !ok {
+		return
+	}
+
+	var limit int
+	limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 100
+	}
+
+	for _, b := range c.roundTrips.query(s, limit) {
+		w.Write(b)
+		w.Write([]byte{'\n'})
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/packetd/packetd/internal/relay"
+)
+
+// AuthConfig roundtrips 查询的鉴权配置
+//
+// 开启后请求需携带 `Authorization: Bearer <token>` 仅返回 token 权限范围内的 roundtrips
+type AuthConfig struct {
+	Enabled bool          `config:"enabled"`
+	Tokens  []TokenConfig `config:"tokens"`
+}
+
+// TokenConfig 单个 token 及其权限范围
+//
+// Scope 的 key 为 agent 附加的标签名 value 为允许的取值（支持 glob）
+// 所有标签均命中时可见 缺少对应标签的 roundtrip 不可见 Scope 为空时可查看全部数据
+type TokenConfig struct {
+	Name  string              `config:"name"`
+	Token string              `config:"token"`
+	Scope map[string][]string `config:"scope"`
+}
+
+func (c *AuthConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	names := make(map[string]struct{})
+	for _, tc := range c.Tokens {
+		if tc.Token == "" {
+			return errors.Errorf("token (%s) got empty value", tc.Name)
+		}
+		if _, ok := names[tc.Token]; ok {
+			return errors.Errorf("token (%s) duplicated", tc.Name)
+		}
+		names[tc.Token] = struct{}{}
+
+		for _, patterns := range tc.Scope {
+			for _, pattern := range patterns {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return errors.Wrapf(err, "token (%s) got invalid scope pattern '%s'", tc.Name, pattern)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// scope token 对应的可见范围 nil 表示不做限制
+type scope map[string][]string
+
+func (s scope) match(lbs map[string]string) bool {
+	for name, patterns := range s {
+		v, ok := lbs[name]
+		if !ok {
+			return false
+		}
+
+		var matched bool
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, v); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+type authenticator struct {
+	enabled bool
+	tokens  []string
+	scopes  []scope
+}
+
+func newAuthenticator(conf AuthConfig) *authenticator {
+	a := &authenticator{enabled: conf.Enabled}
+	for _, tc := range conf.Tokens {
+		a.tokens = append(a.tokens, tc.Token)
+		a.scopes = append(a.scopes, tc.Scope)
+	}
+	return a
+}
+
+// authenticate 返回请求对应的可见范围 token 无效时返回 false
+func (a *authenticator) authenticate(r *http.Request) (scope, bool) {
+	if !a.enabled {
+		return nil, true
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, false
+	}
+
+	idx := relay.MatchToken(token, a.tokens)
+	if idx < 0 {
+		return nil, false
+	}
+	return a.scopes[idx], true
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/internal/json"
+	"github.com/packetd/packetd/internal/relay"
+)
+
+func TestScopeMatch(t *testing.T) {
+	s := scope{
+		"namespace": {"payment"},
+		"service":   {"checkout-*", "billing"},
+	}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   bool
+	}{
+		{name: "exact", labels: map[string]string{"namespace": "payment", "service": "billing"}, want: true},
+		{name: "glob", labels: map[string]string{"namespace": "payment", "service": "checkout-api"}, want: true},
+		{name: "other namespace", labels: map[string]string{"namespace": "stream", "service": "billing"}},
+		{name: "missing label", labels: map[string]string{"namespace": "payment"}},
+		{name: "no labels"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.match(tt.labels))
+		})
+	}
+
+	assert.True(t, scope(nil).match(nil))
+}
+
+func TestAuthenticator(t *testing.T) {
+	conf := AuthConfig{
+		Enabled: true,
+		Tokens: []TokenConfig{
+			{Name: "admin", Token: "t-admin"},
+			{Name: "payment", Token: "t-payment", Scope: map[string][]string{"namespace": {"payment"}}},
+		},
+	}
+	require.NoError(t, conf.Validate())
+	a := newAuthenticator(conf)
+
+	newRequest := func(header string) *http.Request {
+		r, _ := http.NewRequest(http.MethodGet, "/watch", nil)
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+		return r
+	}
+
+	s, ok := a.authenticate(newRequest("Bearer t-payment"))
+	assert.True(t, ok)
+	assert.Equal(t, scope{"namespace": {"payment"}}, s)
+
+	s, ok = a.authenticate(newRequest("Bearer t-admin"))
+	assert.True(t, ok)
+	assert.Nil(t, s)
+
+	_, ok = a.authenticate(newRequest("Bearer t-unknown"))
+	assert.False(t, ok)
+	_, ok = a.authenticate(newRequest(""))
+	assert.False(t, ok)
+
+	_, ok = newAuthenticator(AuthConfig{}).authenticate(newRequest(""))
+	assert.True(t, ok)
+}
+
+func TestAuthConfigValidate(t *testing.T) {
+	assert.Error(t, (&AuthConfig{Enabled: true, Tokens: []TokenConfig{{Name: "empty"}}}).Validate())
+	assert.Error(t, (&AuthConfig{Enabled: true, Tokens: []TokenConfig{{Name: "a", Token: "x"}, {Name: "b", Token: "x"}}}).Validate())
+	assert.Error(t, (&AuthConfig{Enabled: true, Tokens: []TokenConfig{{Name: "a", Token: "x", Scope: map[string][]string{"service": {"["}}}}}).Validate())
+}
+
+func TestRoundTripHubQuery(t *testing.T) {
+	h := newRoundTripHub(3)
+	h.publish("agent-1", []relay.RoundTripRecord{
+		{Labels: map[string]string{"namespace": "payment"}, Data: []byte(`{"Proto":"http","Seq":1}`)},
+		{Labels: map[string]string{"namespace": "stream"}, Data: []byte(`{"Proto":"kafka","Seq":2}`)},
+		{Labels: map[string]string{"namespace": "payment"}, Data: []byte(`{"Proto":"http","Seq":3}`)},
+		{Labels: map[string]string{"namespace": "payment"}, Data: []byte(`{"Proto":"http","Seq":4}`)},
+	})
+
+	seqs := func(lst [][]byte) []int {
+		var seqs []int
+		for _, b := range lst {
+			var rt struct {
+				Agent     string
+				RoundTrip struct{ Seq int }
+			}
+			require.NoError(t, json.Unmarshal(b, &rt))
+			assert.Equal(t, "agent-1", rt.Agent)
+			seqs = append(seqs, rt.RoundTrip.Seq)
+		}
+		return seqs
+	}
+
+	assert.Equal(t, []int{2, 3, 4}, seqs(h.query(nil, 10)))
+	assert.Equal(t, []int{3, 4}, seqs(h.query(scope{"namespace": {"payment"}}, 10)))
+	assert.Equal(t, []int{4}, seqs(h.query(scope{"namespace": {"payment"}}, 1)))
+}
@@ -15,9 +15,9 @@ package common
 
 // BuildInfo 代表程序构建信息
 type BuildInfo struct {
-	Version string
-	GitHash string
-	Time    string
+	Version string `json:"version"`
+	GitHash string `json:"gitHash"`
+	Time    string `json:"time"`
 }
 
 var (
@@ -14,6 +14,8 @@
 package common
 
 import (
+	"time"
+
 	"github.com/spf13/cast"
 )
 
@@ -31,6 +33,10 @@ func (o Options) GetBool(k string) (bool, error) {
 	return cast.ToBoolE(o[k])
 }
 
+func (o Options) GetDuration(k string) (time.Duration, error) {
+	return cast.ToDurationE(o[k])
+}
+
 func (o Options) GetStringSlice(k string) ([]string, error) {
 	return cast.ToStringSliceE(o[k])
 }
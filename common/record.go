@@ -14,8 +14,12 @@
 package common
 
 import (
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 
+	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/metricstorage"
 )
 
@@ -25,19 +29,56 @@ const (
 	RecordRoundTrips RecordType = "roundtrips"
 	RecordMetrics    RecordType = "metrics"
 	RecordTraces     RecordType = "traces"
+	RecordFlows      RecordType = "flows"
+	RecordEvents     RecordType = "events"
 )
 
 type MetricsData struct {
 	Data []metricstorage.ConstMetric
+
+	// RoundTrip 生成指标的原始数据 转发至 collector 时用于跨 agent 去重
+	RoundTrip socket.RoundTrip
 }
 
 type TracesData struct {
 	Data ptrace.Span
+
+	// RoundTrip 生成 Span 的原始数据 用于导出前按照协议字段过滤
+	RoundTrip socket.RoundTrip
+}
+
+// Flow 单方向的流记录 按照 IPFIX / NetFlow 的语义 Packets / Bytes 均为区间内的增量
+type Flow struct {
+	Tuple       socket.Tuple
+	L4Proto     socket.L4Proto
+	L7Proto     socket.L7Proto
+	Packets     uint64
+	Bytes       uint64
+	Retransmits uint64
+	Start       time.Time
+	End         time.Time
+
+	// Sampled 流统计来自 sFlow 等采样数据源 Packets / Bytes 为按照采样率放大后的估算值
+	Sampled bool `json:",omitempty"`
+}
+
+type FlowsData struct {
+	Data []Flow
+}
+
+// EventsData 处理器周期性汇总生成的结构化事件 每个元素序列化为一行 JSON 输出
+type EventsData struct {
+	Data []any
 }
 
 type Record struct {
 	RecordType RecordType
 	Data       any
+
+	// TraceID/SpanID 由 roundtripstotraces 生成 Span 后回填到 roundtrips Record 上
+	// 同一 pipeline 中排在其后的处理器可据此进行关联（如指标 exemplar）
+	TraceID pcommon.TraceID
+	SpanID  pcommon.SpanID
 }
 
 func NewRecord(recordType RecordType, data any) *Record {
@@ -14,7 +14,11 @@
 package socket
 
 import (
+	"encoding/hex"
 	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strconv"
 	"time"
 
 	"github.com/packetd/packetd/internal/json"
@@ -40,18 +44,446 @@ type RoundTrip interface {
 	Validate() bool
 }
 
+// Peer 请求或者响应的发送方
+type Peer struct {
+	Host string
+	Port uint16
+	Time time.Time
+	Size int // 结构体不包含 Size 字段时为 0
+}
+
+// PeerOf 读取协议 Request/Response 结构体的 Host/Port/Time 字段
+//
+// 所有协议的 Request/Response 均包含上述字段 但不存在公共接口 因此使用反射读取
+func PeerOf(v any) (Peer, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return Peer{}, false
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return Peer{}, false
+	}
+
+	host, port, t := rv.FieldByName("Host"), rv.FieldByName("Port"), rv.FieldByName("Time")
+	if !host.IsValid() || host.Kind() != reflect.String || !port.IsValid() || port.Kind() != reflect.Uint16 || !t.IsValid() {
+		return Peer{}, false
+	}
+
+	p := Peer{Host: host.String(), Port: uint16(port.Uint())}
+	p.Time, _ = t.Interface().(time.Time)
+	if size := rv.FieldByName("Size"); size.IsValid() && size.CanInt() {
+		p.Size = int(size.Int())
+	}
+	return p, true
+}
+
+// OneWayRoundTrip 单向事件
+//
+// 部分协议存在不需要响应的请求 如 AMQP 未开启 Confirm 模式的 Publish 以及 Kafka acks=0 的 Produce
+// 此类 RoundTrip 的 Response 为 nil 且 Duration 恒为 0 消费方需要据此跳过响应相关的处理
+type OneWayRoundTrip interface {
+	OneWay() bool
+}
+
+// IsOneWay 判断 RoundTrip 是否为单向事件
+func IsOneWay(rt RoundTrip) bool {
+	ow, ok := rt.(OneWayRoundTrip)
+	return ok && ow.OneWay()
+}
+
+// TruncatedCaptureRoundTrip 截断抓包产生的 RoundTrip
+//
+// 抓包长度（snaplen）小于数据包长度时 Payload 不完整 无法解析应用层内容
+// 此类 RoundTrip 仅包含请求响应的字节数以及耗时 Request/Response 不是协议自身的结构体
+type TruncatedCaptureRoundTrip interface {
+	TruncatedCapture() bool
+}
+
+// IsTruncatedCapture 判断 RoundTrip 是否由截断的数据包生成
+func IsTruncatedCapture(rt RoundTrip) bool {
+	tc, ok := rt.(TruncatedCaptureRoundTrip)
+	return ok && tc.TruncatedCapture()
+}
+
+// CaptureQualityRoundTrip 采集期间发生丢包的 RoundTrip
+//
+// CaptureQuality 为同一时段内成功捕获的数据包占比 取值 (0, 1) 丢包可能导致耗时偏大（如重传的数据包被丢弃）
+type CaptureQualityRoundTrip interface {
+	CaptureQuality() float64
+}
+
+// CaptureQuality 返回 RoundTrip 的采集质量 未发生丢包时为 1
+func CaptureQuality(rt RoundTrip) float64 {
+	cq, ok := rt.(CaptureQualityRoundTrip)
+	if !ok {
+		return 1
+	}
+	return cq.CaptureQuality()
+}
+
+// qualityRoundTrip 为 RoundTrip 附加采集质量 其余可选接口均透传给原始 RoundTrip
+type qualityRoundTrip struct {
+	RoundTrip
+	quality float64
+}
+
+func (rt qualityRoundTrip) CaptureQuality() float64 {
+	return rt.quality
+}
+
+func (rt qualityRoundTrip) OneWay() bool {
+	return IsOneWay(rt.RoundTrip)
+}
+
+func (rt qualityRoundTrip) TruncatedCapture() bool {
+	return IsTruncatedCapture(rt.RoundTrip)
+}
+
+func (rt qualityRoundTrip) TCPTimestamp() uint32 {
+	return TCPTimestamp(rt.RoundTrip)
+}
+
+func (rt qualityRoundTrip) Iface() string {
+	return Iface(rt.RoundTrip)
+}
+
+func (rt qualityRoundTrip) HalfOpen() bool {
+	return IsHalfOpen(rt.RoundTrip)
+}
+
+func (rt qualityRoundTrip) Sequence() uint64 {
+	return Sequence(rt.RoundTrip)
+}
+
+func (rt qualityRoundTrip) Injected() bool {
+	return IsInjected(rt.RoundTrip)
+}
+
+// WithCaptureQuality 为 RoundTrip 附加采集质量 quality 不小于 1 时原样返回
+func WithCaptureQuality(rt RoundTrip, quality float64) RoundTrip {
+	if quality >= 1 {
+		return rt
+	}
+	return qualityRoundTrip{RoundTrip: rt, quality: quality}
+}
+
+// TCPTimestampRoundTrip 记录了请求首个数据段 TSval 的 RoundTrip
+//
+// 同一个数据段在链路两端的 TSval 相同 可用于关联客户端与服务端主机上观测到的同一个请求
+type TCPTimestampRoundTrip interface {
+	TCPTimestamp() uint32
+}
+
+// TCPTimestamp 返回请求首个数据段的 TSval 未记录时返回 0
+func TCPTimestamp(rt RoundTrip) uint32 {
+	ts, ok := rt.(TCPTimestampRoundTrip)
+	if !ok {
+		return 0
+	}
+	return ts.TCPTimestamp()
+}
+
+// tsvalRoundTrip 为 RoundTrip 附加 TSval 其余可选接口均透传给原始 RoundTrip
+type tsvalRoundTrip struct {
+	RoundTrip
+	tsval uint32
+}
+
+func (rt tsvalRoundTrip) TCPTimestamp() uint32 {
+	return rt.tsval
+}
+
+func (rt tsvalRoundTrip) OneWay() bool {
+	return IsOneWay(rt.RoundTrip)
+}
+
+func (rt tsvalRoundTrip) TruncatedCapture() bool {
+	return IsTruncatedCapture(rt.RoundTrip)
+}
+
+func (rt tsvalRoundTrip) CaptureQuality() float64 {
+	return CaptureQuality(rt.RoundTrip)
+}
+
+func (rt tsvalRoundTrip) Iface() string {
+	return Iface(rt.RoundTrip)
+}
+
+func (rt tsvalRoundTrip) HalfOpen() bool {
+	return IsHalfOpen(rt.RoundTrip)
+}
+
+func (rt tsvalRoundTrip) Sequence() uint64 {
+	return Sequence(rt.RoundTrip)
+}
+
+func (rt tsvalRoundTrip) Injected() bool {
+	return IsInjected(rt.RoundTrip)
+}
+
+// WithTCPTimestamp 为 RoundTrip 附加请求首个数据段的 TSval tsval 为 0 时原样返回
+func WithTCPTimestamp(rt RoundTrip, tsval uint32) RoundTrip {
+	if tsval == 0 {
+		return rt
+	}
+	return tsvalRoundTrip{RoundTrip: rt, tsval: tsval}
+}
+
+// IfaceRoundTrip 记录了所属网卡的 RoundTrip
+//
+// 目前仅回放 pcapng 文件时可用 取值为 pcapng 接口的名称或描述 两者均为空时为 if<序号>
+type IfaceRoundTrip interface {
+	Iface() string
+}
+
+// Iface 返回 RoundTrip 所属的网卡 未记录时返回空
+func Iface(rt RoundTrip) string {
+	ir, ok := rt.(IfaceRoundTrip)
+	if !ok {
+		return ""
+	}
+	return ir.Iface()
+}
+
+// ifaceRoundTrip 为 RoundTrip 附加网卡 其余可选接口均透传给原始 RoundTrip
+type ifaceRoundTrip struct {
+	RoundTrip
+	iface string
+}
+
+func (rt ifaceRoundTrip) Iface() string {
+	return rt.iface
+}
+
+func (rt ifaceRoundTrip) OneWay() bool {
+	return IsOneWay(rt.RoundTrip)
+}
+
+func (rt ifaceRoundTrip) TruncatedCapture() bool {
+	return IsTruncatedCapture(rt.RoundTrip)
+}
+
+func (rt ifaceRoundTrip) CaptureQuality() float64 {
+	return CaptureQuality(rt.RoundTrip)
+}
+
+func (rt ifaceRoundTrip) TCPTimestamp() uint32 {
+	return TCPTimestamp(rt.RoundTrip)
+}
+
+func (rt ifaceRoundTrip) HalfOpen() bool {
+	return IsHalfOpen(rt.RoundTrip)
+}
+
+func (rt ifaceRoundTrip) Sequence() uint64 {
+	return Sequence(rt.RoundTrip)
+}
+
+func (rt ifaceRoundTrip) Injected() bool {
+	return IsInjected(rt.RoundTrip)
+}
+
+// WithIface 为 RoundTrip 附加所属网卡 iface 为空时原样返回
+func WithIface(rt RoundTrip, iface string) RoundTrip {
+	if iface == "" {
+		return rt
+	}
+	return ifaceRoundTrip{RoundTrip: rt, iface: iface}
+}
+
+// HalfOpenRoundTrip 请求期间所在链接被判定为半开的 RoundTrip
+//
+// 此类 RoundTrip 的耗时包含了等待重传或者 keepalive 超时的时间 并不代表服务端的处理耗时
+type HalfOpenRoundTrip interface {
+	HalfOpen() bool
+}
+
+// IsHalfOpen 判断 RoundTrip 是否受到半开链接的影响
+func IsHalfOpen(rt RoundTrip) bool {
+	ho, ok := rt.(HalfOpenRoundTrip)
+	return ok && ho.HalfOpen()
+}
+
+// halfOpenRoundTrip 为 RoundTrip 附加半开标记 其余可选接口均透传给原始 RoundTrip
+type halfOpenRoundTrip struct {
+	RoundTrip
+}
+
+func (rt halfOpenRoundTrip) HalfOpen() bool {
+	return true
+}
+
+func (rt halfOpenRoundTrip) OneWay() bool {
+	return IsOneWay(rt.RoundTrip)
+}
+
+func (rt halfOpenRoundTrip) TruncatedCapture() bool {
+	return IsTruncatedCapture(rt.RoundTrip)
+}
+
+func (rt halfOpenRoundTrip) CaptureQuality() float64 {
+	return CaptureQuality(rt.RoundTrip)
+}
+
+func (rt halfOpenRoundTrip) TCPTimestamp() uint32 {
+	return TCPTimestamp(rt.RoundTrip)
+}
+
+func (rt halfOpenRoundTrip) Iface() string {
+	return Iface(rt.RoundTrip)
+}
+
+func (rt halfOpenRoundTrip) Sequence() uint64 {
+	return Sequence(rt.RoundTrip)
+}
+
+func (rt halfOpenRoundTrip) Injected() bool {
+	return IsInjected(rt.RoundTrip)
+}
+
+// WithHalfOpen 为 RoundTrip 附加半开标记
+func WithHalfOpen(rt RoundTrip) RoundTrip {
+	if IsHalfOpen(rt) {
+		return rt
+	}
+	return halfOpenRoundTrip{RoundTrip: rt}
+}
+
+// SequenceRoundTrip 记录了在所属链接内输出序号的 RoundTrip
+//
+// 流水线请求（Redis pipeline / HTTP2 多路复用 / Kafka）可能在同一数据包内归档 地址 时间以及大小均相同 需依赖序号区分
+type SequenceRoundTrip interface {
+	Sequence() uint64
+}
+
+// Sequence 返回 RoundTrip 在所属链接内的输出序号 未记录时返回 0
+func Sequence(rt RoundTrip) uint64 {
+	sr, ok := rt.(SequenceRoundTrip)
+	if !ok {
+		return 0
+	}
+	return sr.Sequence()
+}
+
+// seqRoundTrip 为 RoundTrip 附加输出序号 其余可选接口均透传给原始 RoundTrip
+type seqRoundTrip struct {
+	RoundTrip
+	seq uint64
+}
+
+func (rt seqRoundTrip) Sequence() uint64 {
+	return rt.seq
+}
+
+func (rt seqRoundTrip) OneWay() bool {
+	return IsOneWay(rt.RoundTrip)
+}
+
+func (rt seqRoundTrip) TruncatedCapture() bool {
+	return IsTruncatedCapture(rt.RoundTrip)
+}
+
+func (rt seqRoundTrip) CaptureQuality() float64 {
+	return CaptureQuality(rt.RoundTrip)
+}
+
+func (rt seqRoundTrip) TCPTimestamp() uint32 {
+	return TCPTimestamp(rt.RoundTrip)
+}
+
+func (rt seqRoundTrip) Iface() string {
+	return Iface(rt.RoundTrip)
+}
+
+func (rt seqRoundTrip) HalfOpen() bool {
+	return IsHalfOpen(rt.RoundTrip)
+}
+
+func (rt seqRoundTrip) Injected() bool {
+	return IsInjected(rt.RoundTrip)
+}
+
+// WithSequence 为 RoundTrip 附加所属链接内的输出序号 seq 为 0 时原样返回
+func WithSequence(rt RoundTrip, seq uint64) RoundTrip {
+	if seq == 0 {
+		return rt
+	}
+	return seqRoundTrip{RoundTrip: rt, seq: seq}
+}
+
+// InjectedRoundTrip 通过管理接口注入的合成 RoundTrip 并非来自真实流量
+type InjectedRoundTrip interface {
+	Injected() bool
+}
+
+// IsInjected 判断 RoundTrip 是否为注入的合成数据
+func IsInjected(rt RoundTrip) bool {
+	ir, ok := rt.(InjectedRoundTrip)
+	return ok && ir.Injected()
+}
+
+// EventID 计算 roundtrip 的确定性标识 由协议 链接内的输出序号以及请求响应双方的地址 / 时间 / 大小哈希得出
+//
+// 同一 roundtrip 无论导出多少次（sink 重试、at-least-once 投递）标识均保持不变 下游可据此去重
+// 与 collector 跨 agent 去重使用的 relay.Key 不同 时间不做任何容忍 两端 agent 观测到的同一请求标识并不相同
+func EventID(rt RoundTrip) string {
+	h := fnv.New128a()
+	h.Write([]byte(rt.Proto()))
+	h.Write([]byte{0})
+	h.Write(strconv.AppendUint(nil, Sequence(rt), 10))
+	for _, v := range []any{rt.Request(), rt.Response()} {
+		p, ok := PeerOf(v)
+		if !ok {
+			h.Write([]byte{0})
+			continue
+		}
+		h.Write([]byte{1})
+		h.Write([]byte(p.Host + ":" + strconv.Itoa(int(p.Port))))
+		h.Write([]byte{0})
+		h.Write(strconv.AppendInt(nil, p.Time.UnixNano(), 10))
+		h.Write([]byte{0})
+		h.Write(strconv.AppendInt(nil, int64(p.Size), 10))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// captureQualityField 未发生丢包时不输出 CaptureQuality 字段
+func captureQualityField(rt RoundTrip) float64 {
+	if q := CaptureQuality(rt); q < 1 {
+		return q
+	}
+	return 0
+}
+
 func JSONMarshalRoundTrip(rt RoundTrip) ([]byte, error) {
 	type R struct {
 		Proto    L7Proto
+		EventID  string
 		Request  any
 		Response any
 		Duration string
+		OneWay   bool `json:",omitempty"`
+
+		TruncatedCapture bool    `json:",omitempty"`
+		CaptureQuality   float64 `json:",omitempty"`
+		Iface            string  `json:",omitempty"`
+		HalfOpen         bool    `json:",omitempty"`
+		Injected         bool    `json:",omitempty"`
 	}
 	return json.Marshal(R{
 		Proto:    rt.Proto(),
+		EventID:  EventID(rt),
 		Request:  rt.Request(),
 		Response: rt.Response(),
 		Duration: rt.Duration().String(),
+		OneWay:   IsOneWay(rt),
+
+		TruncatedCapture: IsTruncatedCapture(rt),
+		CaptureQuality:   captureQualityField(rt),
+		Iface:            Iface(rt),
+		HalfOpen:         IsHalfOpen(rt),
+		Injected:         IsInjected(rt),
 	})
 }
 
@@ -73,9 +505,25 @@ type L4Packet interface {
 type TCPSegment struct {
 	Tuple   Tuple
 	Time    time.Time
+	SYN     bool
 	FIN     bool
+	RST     bool
+	ACK     bool
 	Seq     uint32
+	Ack     uint32
 	Payload []byte
+
+	// TSVal Timestamps 选项中的 TSval 未携带该选项时为 0
+	TSVal uint32
+
+	// Length 链路上 Payload 的实际长度 仅在抓包被截断时大于 len(Payload) 其余情况为 0
+	Length int
+
+	// SamplingRate 数据包来自 sFlow 等采样数据源时的采样率 即代表链路上 SamplingRate 个数据包 其余情况为 0
+	SamplingRate uint32
+
+	// Iface 数据包所属的网卡 目前仅回放 pcapng 文件时设置 其余情况为空
+	Iface string
 }
 
 func (s TCPSegment) Proto() L4Proto {
@@ -90,6 +538,16 @@ func (s TCPSegment) ArrivedTime() time.Time {
 	return s.Time
 }
 
+// PayloadLen 返回 Payload 在链路上的实际长度
+func (s TCPSegment) PayloadLen() int {
+	return max(s.Length, len(s.Payload))
+}
+
+// Truncated 返回 Payload 是否因为抓包长度限制而不完整
+func (s TCPSegment) Truncated() bool {
+	return s.Length > len(s.Payload)
+}
+
 func (s TCPSegment) String() string {
 	return fmt.Sprintf("stream %s seq: %d recv %d bytes", s.Tuple, s.Seq, len(s.Payload))
 }
@@ -99,6 +557,25 @@ type UDPDatagram struct {
 	Tuple   Tuple
 	Time    time.Time
 	Payload []byte
+
+	// Length 链路上 Payload 的实际长度 语义同 TCPSegment.Length
+	Length int
+
+	// SamplingRate 语义同 TCPSegment.SamplingRate
+	SamplingRate uint32
+
+	// Iface 语义同 TCPSegment.Iface
+	Iface string
+}
+
+// PayloadLen 返回 Payload 在链路上的实际长度
+func (s UDPDatagram) PayloadLen() int {
+	return max(s.Length, len(s.Payload))
+}
+
+// Truncated 返回 Payload 是否因为抓包长度限制而不完整
+func (s UDPDatagram) Truncated() bool {
+	return s.Length > len(s.Payload)
 }
 
 func (s UDPDatagram) Proto() L4Proto {
@@ -116,3 +593,27 @@ func (s UDPDatagram) ArrivedTime() time.Time {
 func (s UDPDatagram) String() string {
 	return fmt.Sprintf("stream %s recv %d bytes", s.Tuple, len(s.Payload))
 }
+
+// SamplingRate 返回数据包的采样率 非采样数据包返回 0
+//
+// 同一链接的采样数据包并不连续 无法按序重组 消费方仅能用于四层的流统计
+func SamplingRate(pkt L4Packet) uint32 {
+	switch p := pkt.(type) {
+	case *TCPSegment:
+		return p.SamplingRate
+	case *UDPDatagram:
+		return p.SamplingRate
+	}
+	return 0
+}
+
+// PacketIface 返回数据包所属的网卡 未记录时返回空
+func PacketIface(pkt L4Packet) string {
+	switch p := pkt.(type) {
+	case *TCPSegment:
+		return p.Iface
+	case *UDPDatagram:
+		return p.Iface
+	}
+	return ""
+}
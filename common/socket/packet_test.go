@@ -0,0 +1,207 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package socket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testMessage struct {
+	Host string
+	Port uint16
+	Time time.Time
+	Size int
+}
+
+type testRoundTrip struct {
+	req *testMessage
+	rsp *testMessage
+}
+
+func (rt testRoundTrip) Proto() L7Proto          { return L7ProtoHTTP }
+func (rt testRoundTrip) Request() any            { return rt.req }
+func (rt testRoundTrip) Response() any           { return rt.rsp }
+func (rt testRoundTrip) Duration() time.Duration { return rt.rsp.Time.Sub(rt.req.Time) }
+func (rt testRoundTrip) Validate() bool          { return true }
+
+func TestEventID(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	newRoundTrip := func(rspSize int) testRoundTrip {
+		return testRoundTrip{
+			req: &testMessage{Host: "10.0.0.1", Port: 50001, Time: t0, Size: 10},
+			rsp: &testMessage{Host: "10.0.0.2", Port: 80, Time: t0.Add(time.Millisecond), Size: rspSize},
+		}
+	}
+
+	id := EventID(newRoundTrip(20))
+	assert.Len(t, id, 32)
+	assert.Equal(t, id, EventID(newRoundTrip(20)))
+	assert.NotEqual(t, id, EventID(newRoundTrip(21)))
+
+	// 单向事件没有响应
+	oneWay := newRoundTrip(20)
+	oneWay.rsp = nil
+	assert.NotEqual(t, id, EventID(oneWay))
+}
+
+func TestWithCaptureQuality(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	rt := testRoundTrip{
+		req: &testMessage{Host: "10.0.0.1", Port: 50001, Time: t0},
+		rsp: &testMessage{Host: "10.0.0.2", Port: 80, Time: t0.Add(time.Millisecond)},
+	}
+
+	assert.Equal(t, RoundTrip(rt), WithCaptureQuality(rt, 1))
+	assert.Equal(t, float64(1), CaptureQuality(rt))
+
+	marked := WithCaptureQuality(rt, 0.8)
+	assert.Equal(t, 0.8, CaptureQuality(marked))
+	assert.Equal(t, EventID(rt), EventID(marked))
+	assert.False(t, IsOneWay(marked))
+	assert.False(t, IsTruncatedCapture(marked))
+
+	b, err := JSONMarshalRoundTrip(marked)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"CaptureQuality":0.8`)
+
+	b, err = JSONMarshalRoundTrip(rt)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "CaptureQuality")
+}
+
+func TestWithTCPTimestamp(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	rt := testRoundTrip{
+		req: &testMessage{Host: "10.0.0.1", Port: 50001, Time: t0},
+		rsp: &testMessage{Host: "10.0.0.2", Port: 80, Time: t0.Add(time.Millisecond)},
+	}
+
+	assert.Equal(t, RoundTrip(rt), WithTCPTimestamp(rt, 0))
+	assert.Zero(t, TCPTimestamp(rt))
+
+	// 两种附加顺序均可读取到全部字段
+	marked := WithCaptureQuality(WithTCPTimestamp(rt, 12345), 0.8)
+	assert.Equal(t, uint32(12345), TCPTimestamp(marked))
+	assert.Equal(t, 0.8, CaptureQuality(marked))
+
+	marked = WithTCPTimestamp(WithCaptureQuality(rt, 0.8), 12345)
+	assert.Equal(t, uint32(12345), TCPTimestamp(marked))
+	assert.Equal(t, 0.8, CaptureQuality(marked))
+	assert.Equal(t, EventID(rt), EventID(marked))
+}
+
+func TestWithIface(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	rt := testRoundTrip{
+		req: &testMessage{Host: "10.0.0.1", Port: 50001, Time: t0},
+		rsp: &testMessage{Host: "10.0.0.2", Port: 80, Time: t0.Add(time.Millisecond)},
+	}
+
+	assert.Equal(t, RoundTrip(rt), WithIface(rt, ""))
+	assert.Empty(t, Iface(rt))
+
+	marked := WithCaptureQuality(WithTCPTimestamp(WithIface(rt, "eth0"), 12345), 0.8)
+	assert.Equal(t, "eth0", Iface(marked))
+	assert.Equal(t, uint32(12345), TCPTimestamp(marked))
+	assert.Equal(t, 0.8, CaptureQuality(marked))
+	assert.Equal(t, EventID(rt), EventID(marked))
+
+	b, err := JSONMarshalRoundTrip(marked)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"Iface":"eth0"`)
+
+	b, err = JSONMarshalRoundTrip(rt)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "Iface")
+}
+
+func TestWithHalfOpen(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	rt := testRoundTrip{
+		req: &testMessage{Host: "10.0.0.1", Port: 50001, Time: t0},
+		rsp: &testMessage{Host: "10.0.0.2", Port: 80, Time: t0.Add(time.Minute)},
+	}
+	assert.False(t, IsHalfOpen(rt))
+
+	marked := WithCaptureQuality(WithHalfOpen(WithIface(rt, "eth0")), 0.8)
+	assert.True(t, IsHalfOpen(marked))
+	assert.Equal(t, "eth0", Iface(marked))
+	assert.Equal(t, 0.8, CaptureQuality(marked))
+	assert.Equal(t, EventID(rt), EventID(marked))
+
+	b, err := JSONMarshalRoundTrip(marked)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"HalfOpen":true`)
+}
+
+type testInjectedRoundTrip struct {
+	testRoundTrip
+}
+
+func (rt testInjectedRoundTrip) Injected() bool { return true }
+
+func TestIsInjected(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	rt := testRoundTrip{
+		req: &testMessage{Host: "127.0.0.1", Port: 40000, Time: t0},
+		rsp: &testMessage{Host: "127.0.0.1", Port: 53, Time: t0.Add(time.Millisecond)},
+	}
+	assert.False(t, IsInjected(rt))
+
+	// 经过各类标记包装后仍可识别
+	marked := WithCaptureQuality(WithTCPTimestamp(WithSequence(testInjectedRoundTrip{rt}, 1), 12345), 0.8)
+	assert.True(t, IsInjected(marked))
+
+	b, err := JSONMarshalRoundTrip(marked)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"Injected":true`)
+
+	b, err = JSONMarshalRoundTrip(rt)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "Injected")
+}
+
+func TestWithSequence(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	rt := testRoundTrip{
+		req: &testMessage{Host: "10.0.0.1", Port: 50001, Time: t0, Size: 10},
+		rsp: &testMessage{Host: "10.0.0.2", Port: 6379, Time: t0.Add(time.Millisecond), Size: 5},
+	}
+	assert.Equal(t, RoundTrip(rt), WithSequence(rt, 0))
+	assert.Zero(t, Sequence(rt))
+
+	// 流水线请求的地址 时间以及大小均相同 仅序号不同
+	first := WithCaptureQuality(WithHalfOpen(WithIface(WithSequence(rt, 1), "eth0")), 0.8)
+	second := WithCaptureQuality(WithHalfOpen(WithIface(WithSequence(rt, 2), "eth0")), 0.8)
+	assert.Equal(t, uint64(1), Sequence(first))
+	assert.Equal(t, uint64(2), Sequence(second))
+	assert.True(t, IsHalfOpen(first))
+	assert.Equal(t, "eth0", Iface(first))
+	assert.NotEqual(t, EventID(first), EventID(second))
+	assert.Equal(t, EventID(first), EventID(WithSequence(rt, 1)))
+}
+
+func TestPeerOf(t *testing.T) {
+	p, ok := PeerOf(&testMessage{Host: "10.0.0.1", Port: 80, Size: 3})
+	assert.True(t, ok)
+	assert.Equal(t, Peer{Host: "10.0.0.1", Port: 80, Size: 3}, p)
+
+	_, ok = PeerOf(nil)
+	assert.False(t, ok)
+	_, ok = PeerOf(&struct{ Host string }{})
+	assert.False(t, ok)
+}
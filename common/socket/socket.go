@@ -14,9 +14,13 @@
 package socket
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"net"
 	"time"
+
+	"github.com/cespare/xxhash/v2"
 )
 
 const (
@@ -189,6 +193,36 @@ func (t Tuple) Mirror() Tuple {
 	}
 }
 
+// SymmetricHash 返回与方向无关的哈希值
+//
+// 同一条链接两个方向的 Tuple 得到的结果一致 用于将链接固定分配到同一个处理单元
+func (t Tuple) SymmetricHash() uint64 {
+	a, b := endpoint{t.SrcIP, t.SrcPort}, endpoint{t.DstIP, t.DstPort}
+	if b.less(a) {
+		a, b = b, a
+	}
+
+	var buf [2 * (net.IPv6len + 2)]byte
+	n := copy(buf[:], a.ip.IP[:])
+	binary.BigEndian.PutUint16(buf[n:], uint16(a.port))
+	n += 2
+	n += copy(buf[n:], b.ip.IP[:])
+	binary.BigEndian.PutUint16(buf[n:], uint16(b.port))
+	return xxhash.Sum64(buf[:])
+}
+
+type endpoint struct {
+	ip   IPV
+	port Port
+}
+
+func (e endpoint) less(o endpoint) bool {
+	if c := bytes.Compare(e.ip.IP[:], o.ip.IP[:]); c != 0 {
+		return c < 0
+	}
+	return e.port < o.port
+}
+
 // L4Proto Layer4 传输层协议 即 TCP/UDP
 type L4Proto string
 
@@ -211,6 +245,12 @@ const (
 	L7ProtoPostgreSQL L7Proto = "postgresql"
 	L7ProtoKafka      L7Proto = "kafka"
 	L7ProtoAMQP       L7Proto = "amqp"
+	L7ProtoNTP        L7Proto = "ntp"
+	L7ProtoTLS        L7Proto = "tls"
+	L7ProtoUDPRPC     L7Proto = "udprpc"
+
+	// L7ProtoProbe 主动探测的结果 并非由抓包解析得到 不对应具体的传输层协议
+	L7ProtoProbe L7Proto = "probe"
 )
 
 func L7ProtoBased(l7 L7Proto) (L4Proto, bool) {
@@ -225,6 +265,9 @@ func L7ProtoBased(l7 L7Proto) (L4Proto, bool) {
 		L7ProtoPostgreSQL: L4ProtoTCP,
 		L7ProtoKafka:      L4ProtoTCP,
 		L7ProtoAMQP:       L4ProtoTCP,
+		L7ProtoNTP:        L4ProtoUDP,
+		L7ProtoTLS:        L4ProtoTCP,
+		L7ProtoUDPRPC:     L4ProtoUDP,
 	}
 
 	v, ok := protos[l7]
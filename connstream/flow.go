@@ -0,0 +1,77 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connstream
+
+import (
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+// FlowStats 单方向的流统计
+//
+// Bytes 仅统计四层负载 不包含各层协议头
+type FlowStats struct {
+	Tuple       socket.Tuple
+	Packets     uint64
+	Bytes       uint64
+	Retransmits uint64
+	Start       time.Time // 区间内首个数据包的时间
+	End         time.Time // 区间内最后一个数据包的时间
+}
+
+type flowCounter struct {
+	packets     uint64
+	bytes       uint64
+	retransmits uint64
+	start, end  time.Time
+}
+
+func (fc *flowCounter) add(pkt socket.L4Packet, retransmitted bool) {
+	var n int
+	switch seg := pkt.(type) {
+	case *socket.TCPSegment:
+		n = seg.PayloadLen()
+	case *socket.UDPDatagram:
+		n = seg.PayloadLen()
+	}
+
+	t := pkt.ArrivedTime()
+	if fc.packets == 0 {
+		fc.start = t
+	}
+	fc.end = t
+	fc.packets++
+	fc.bytes += uint64(n)
+	if retransmitted {
+		fc.retransmits++
+	}
+}
+
+// take 返回区间内的统计并重置 区间内无数据包时 ok 为 false
+func (fc *flowCounter) take(st socket.Tuple) (FlowStats, bool) {
+	if fc.packets == 0 {
+		return FlowStats{}, false
+	}
+	fs := FlowStats{
+		Tuple:       st,
+		Packets:     fc.packets,
+		Bytes:       fc.bytes,
+		Retransmits: fc.retransmits,
+		Start:       fc.start,
+		End:         fc.end,
+	}
+	*fc = flowCounter{}
+	return fs, true
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connstream
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+func TestConnFlows(t *testing.T) {
+	t0 := time.Unix(1751356800, 0)
+	client := socket.Tuple{
+		SrcIP:   socket.ToIPV4(net.ParseIP("10.0.0.1").To4()),
+		DstIP:   socket.ToIPV4(net.ParseIP("10.0.0.2").To4()),
+		SrcPort: 50001,
+		DstPort: 6379,
+	}
+	server := client.Mirror()
+
+	seg := func(st socket.Tuple, sec int, seq, ack uint32, payload string) *socket.TCPSegment {
+		return &socket.TCPSegment{
+			Tuple:   st,
+			Time:    t0.Add(time.Duration(sec) * time.Second),
+			ACK:     true,
+			Seq:     seq,
+			Ack:     ack,
+			Payload: []byte(payload),
+		}
+	}
+
+	conn := NewConn(client, NewTCPStream)
+	assert.Empty(t, conn.Flows())
+
+	assert.NoError(t, conn.Write(seg(client, 0, 100, 500, "PING\r\n"), nil))
+	assert.NoError(t, conn.Write(seg(server, 1, 500, 106, "+PONG\r\n"), nil))
+	assert.NoError(t, conn.Write(seg(client, 2, 106, 507, "GET k\r\n"), nil))
+	assert.NoError(t, conn.Write(seg(client, 3, 106, 507, "GET k\r\n"), nil))
+	assert.Equal(t, []FlowStats{
+		{Tuple: client, Packets: 3, Bytes: 20, Retransmits: 1, Start: t0, End: t0.Add(3 * time.Second)},
+		{Tuple: server, Packets: 1, Bytes: 7, Start: t0.Add(time.Second), End: t0.Add(time.Second)},
+	}, conn.Flows())
+
+	// 统计读取后重置
+	assert.Empty(t, conn.Flows())
+	assert.NoError(t, conn.Write(seg(server, 4, 507, 113, ""), nil))
+	assert.Equal(t, []FlowStats{
+		{Tuple: server, Packets: 1, Start: t0.Add(4 * time.Second), End: t0.Add(4 * time.Second)},
+	}, conn.Flows())
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connstream
+
+import (
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+// AckState 单方向已发送数据的确认进度
+//
+// 对端迟迟没有推进 ACK 而发送方持续重传 或者 keepalive 探测长期得不到回应
+// 通常意味着 NAT 映射已失效或者对端已经宕机 应用往往要等到超时才会感知
+type AckState struct {
+	Tuple       socket.Tuple // 数据发送方向的四元组
+	Retransmits int          // 对端 ACK 停滞期间的重传次数
+	Keepalives  int          // 对端无任何回应期间的 keepalive 探测次数
+	UnackedAt   time.Time    // 最早一笔未被确认数据的发送时间 零值表示数据均已确认
+}
+
+// Stalled 返回截至 now 数据未被确认的时长
+func (s AckState) Stalled(now time.Time) time.Duration {
+	if s.UnackedAt.IsZero() {
+		return 0
+	}
+	if d := now.Sub(s.UnackedAt); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// seqLE 判断 a <= b 兼容序号回绕
+func seqLE(a, b uint32) bool {
+	return int32(a-b) <= 0
+}
+
+// ackTracker 跟踪单方向发送数据的确认情况
+//
+// 仅基于旁路观测到的报文推断 不维护完整的 TCP 状态机
+type ackTracker struct {
+	sent        bool
+	nextSeq     uint32 // 已发送数据的最大序号
+	acked       bool
+	ackSeq      uint32 // 对端已确认的最大序号
+	unackedAt   time.Time
+	retransmits int
+	keepalives  int
+}
+
+func (t *ackTracker) outstanding() bool {
+	if !t.sent {
+		return false
+	}
+	return !t.acked || !seqLE(t.nextSeq, t.ackSeq)
+}
+
+// onSend 记录本端发送的报文 返回是否为重传报文
+func (t *ackTracker) onSend(seg *socket.TCPSegment) bool {
+	n := uint32(seg.PayloadLen())
+
+	// keepalive 探测报文的序号为 SND.NXT-1 且携带 0 或 1 字节数据
+	if t.sent && n <= 1 && seg.Seq+1 == t.nextSeq {
+		t.keepalives++
+		return false
+	}
+	if n == 0 {
+		return false
+	}
+
+	var retransmitted bool
+
+	end := seg.Seq + n
+	switch {
+	case !t.sent:
+		t.sent = true
+		t.nextSeq = end
+	case seqLE(end, t.nextSeq):
+		retransmitted = true
+		if t.outstanding() {
+			t.retransmits++
+		}
+	default:
+		t.nextSeq = end
+	}
+
+	if t.unackedAt.IsZero() && t.outstanding() {
+		t.unackedAt = seg.Time
+	}
+	return retransmitted
+}
+
+// onAck 记录对端的确认报文 对端有任何报文均说明其仍然存活
+func (t *ackTracker) onAck(seg *socket.TCPSegment) {
+	t.keepalives = 0
+	if !seg.ACK {
+		return
+	}
+	if t.acked && seqLE(seg.Ack, t.ackSeq) {
+		return
+	}
+
+	// ACK 有推进 重新计算停滞状态
+	t.acked = true
+	t.ackSeq = seg.Ack
+	t.retransmits = 0
+	t.unackedAt = time.Time{}
+	if t.outstanding() {
+		t.unackedAt = seg.Time
+	}
+}
+
+func (t *ackTracker) state(st socket.Tuple) AckState {
+	return AckState{
+		Tuple:       st,
+		Retransmits: t.retransmits,
+		Keepalives:  t.keepalives,
+		UnackedAt:   t.unackedAt,
+	}
+}
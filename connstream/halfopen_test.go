@@ -0,0 +1,73 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connstream
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+func TestConnAckStates(t *testing.T) {
+	t0 := time.Unix(1751356800, 0)
+	client := socket.Tuple{
+		SrcIP:   socket.ToIPV4(net.ParseIP("10.0.0.1").To4()),
+		DstIP:   socket.ToIPV4(net.ParseIP("10.0.0.2").To4()),
+		SrcPort: 50001,
+		DstPort: 6379,
+	}
+	server := client.Mirror()
+
+	seg := func(st socket.Tuple, sec int, seq, ack uint32, payload string) *socket.TCPSegment {
+		return &socket.TCPSegment{
+			Tuple:   st,
+			Time:    t0.Add(time.Duration(sec) * time.Second),
+			ACK:     true,
+			Seq:     seq,
+			Ack:     ack,
+			Payload: []byte(payload),
+		}
+	}
+
+	conn := NewConn(client, NewTCPStream)
+	assert.NoError(t, conn.Write(seg(client, 0, 100, 500, "PING\r\n"), nil))
+	assert.NoError(t, conn.Write(seg(server, 0, 500, 106, "+PONG\r\n"), nil))
+	assert.NoError(t, conn.Write(seg(client, 0, 106, 507, ""), nil))
+	assert.Empty(t, conn.AckStates())
+
+	// 对端失联 数据持续重传
+	assert.NoError(t, conn.Write(seg(client, 1, 106, 507, "GET k\r\n"), nil))
+	assert.NoError(t, conn.Write(seg(client, 2, 106, 507, "GET k\r\n"), nil))
+	assert.NoError(t, conn.Write(seg(client, 4, 106, 507, "GET k\r\n"), nil))
+	assert.Equal(t, []AckState{
+		{Tuple: client, Retransmits: 2, UnackedAt: t0.Add(time.Second)},
+	}, conn.AckStates())
+	assert.Equal(t, 9*time.Second, conn.AckStates()[0].Stalled(t0.Add(10*time.Second)))
+
+	// 对端确认后恢复
+	assert.NoError(t, conn.Write(seg(server, 5, 507, 113, ""), nil))
+	assert.Empty(t, conn.AckStates())
+
+	// 服务端 keepalive 探测未得到回应
+	assert.NoError(t, conn.Write(seg(server, 60, 506, 113, ""), nil))
+	assert.NoError(t, conn.Write(seg(server, 75, 506, 113, ""), nil))
+	assert.Equal(t, []AckState{{Tuple: server, Keepalives: 2}}, conn.AckStates())
+
+	assert.NoError(t, conn.Write(seg(client, 75, 113, 507, ""), nil))
+	assert.Empty(t, conn.AckStates())
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connstream
+
+import (
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+// handshakeTracker 跟踪 TCP 三次握手
+//
+//	Client                   Server
+//	  |  SYN (seq=x)            |
+//	  | ----------------------> |
+//	  |  SYN-ACK (seq=y, x+1)   |
+//	  | <---------------------- |
+//	  |  ACK (ack=y+1)          |
+//	  | ----------------------> |
+//
+// 耗时为首个 SYN 至客户端确认 SYN-ACK 的时间 包含 SYN 重传等待的时间
+// 因此 SYN 被丢弃或者服务端 backlog 溢出时耗时会明显增大 与应用层的响应耗时互不干扰
+// 未观测到 SYN 的链接（如 packetd 启动前已经建立的链接）不做统计
+type handshakeTracker struct {
+	client      int // SYN 发送方在 Conn 中的方向
+	synAt       time.Time
+	synSeq      uint32
+	synAcked    bool
+	synAckSeq   uint32
+	retransmits uint64 // 待上报的 SYN 重传次数
+	duration    time.Duration
+	done        bool
+	reported    bool
+}
+
+func (h *handshakeTracker) onSegment(self int, seg *socket.TCPSegment) {
+	if h.done {
+		return
+	}
+
+	switch {
+	case seg.SYN && !seg.ACK:
+		if h.synAt.IsZero() || h.synSeq != seg.Seq {
+			// 新的建连尝试 重新计时
+			*h = handshakeTracker{client: self, synAt: seg.Time, synSeq: seg.Seq, retransmits: h.retransmits}
+			return
+		}
+		if self == h.client {
+			h.retransmits++
+		}
+
+	case seg.SYN && seg.ACK:
+		if h.synAt.IsZero() || self == h.client || seg.Ack != h.synSeq+1 {
+			return
+		}
+		h.synAcked = true
+		h.synAckSeq = seg.Seq
+
+	case seg.ACK:
+		if !h.synAcked || self != h.client || seg.Ack != h.synAckSeq+1 {
+			return
+		}
+		h.done = true
+		h.duration = seg.Time.Sub(h.synAt)
+	}
+}
+
+// take 将待上报的握手数据填充至客户端方向的 Stats 握手耗时仅上报一次
+func (h *handshakeTracker) take(stats *Stats) {
+	stats.SynRetransmits = h.retransmits
+	h.retransmits = 0
+	if h.done && !h.reported {
+		h.reported = true
+		stats.Handshake = h.duration
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connstream
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+func TestConnHandshake(t *testing.T) {
+	t0 := time.Unix(1751356800, 0)
+	client := socket.Tuple{
+		SrcIP:   socket.ToIPV4(net.ParseIP("10.0.0.1").To4()),
+		DstIP:   socket.ToIPV4(net.ParseIP("10.0.0.2").To4()),
+		SrcPort: 50001,
+		DstPort: 6379,
+	}
+	server := client.Mirror()
+
+	type segment struct {
+		st          socket.Tuple
+		ms          int
+		syn, ack    bool
+		seq, ackSeq uint32
+	}
+	write := func(conn *Conn, segs ...segment) {
+		for _, s := range segs {
+			assert.NoError(t, conn.Write(&socket.TCPSegment{
+				Tuple: s.st,
+				Time:  t0.Add(time.Duration(s.ms) * time.Millisecond),
+				SYN:   s.syn,
+				ACK:   s.ack,
+				Seq:   s.seq,
+				Ack:   s.ackSeq,
+			}, nil))
+		}
+	}
+	statsOf := func(conn *Conn, st socket.Tuple) Stats {
+		for _, ts := range conn.Stats() {
+			if ts.Tuple == st {
+				return ts.Stats
+			}
+		}
+		return Stats{}
+	}
+
+	t.Run("Completed", func(t *testing.T) {
+		conn := NewConn(client, NewTCPStream)
+		write(conn,
+			segment{st: client, ms: 0, syn: true, seq: 100},
+			segment{st: server, ms: 2, syn: true, ack: true, seq: 500, ackSeq: 101},
+			segment{st: client, ms: 3, ack: true, seq: 101, ackSeq: 501},
+		)
+		stats := conn.Stats()
+		assert.Equal(t, client, stats[0].Tuple)
+		assert.Equal(t, 3*time.Millisecond, stats[0].Stats.Handshake)
+		assert.Zero(t, stats[1].Stats.Handshake)
+
+		// 握手耗时仅上报一次
+		assert.Zero(t, statsOf(conn, client).Handshake)
+
+		// Handshake 不受 Stats 读取的影响
+		d, ok := conn.Handshake()
+		assert.True(t, ok)
+		assert.Equal(t, 3*time.Millisecond, d)
+	})
+
+	t.Run("SYN retransmitted", func(t *testing.T) {
+		conn := NewConn(client, NewTCPStream)
+		write(conn,
+			segment{st: client, ms: 0, syn: true, seq: 100},
+			segment{st: client, ms: 1000, syn: true, seq: 100},
+		)
+		assert.Equal(t, Stats{Proto: socket.L4ProtoTCP, ReceivedPackets: 2, SynRetransmits: 1}, statsOf(conn, client))
+		_, ok := conn.Handshake()
+		assert.False(t, ok)
+
+		write(conn,
+			segment{st: client, ms: 3000, syn: true, seq: 100},
+			segment{st: server, ms: 3001, syn: true, ack: true, seq: 500, ackSeq: 101},
+			segment{st: client, ms: 3002, ack: true, seq: 101, ackSeq: 501},
+		)
+		stats := statsOf(conn, client)
+		assert.Equal(t, 3002*time.Millisecond, stats.Handshake)
+		assert.Equal(t, uint64(1), stats.SynRetransmits)
+	})
+
+	t.Run("Mismatched ack", func(t *testing.T) {
+		conn := NewConn(client, NewTCPStream)
+		write(conn,
+			segment{st: client, ms: 0, syn: true, seq: 100},
+			segment{st: server, ms: 1, syn: true, ack: true, seq: 500, ackSeq: 101},
+			segment{st: client, ms: 2, ack: true, seq: 101, ackSeq: 499},
+		)
+		assert.Zero(t, statsOf(conn, client).Handshake)
+	})
+
+	t.Run("Established before capture", func(t *testing.T) {
+		conn := NewConn(client, NewTCPStream)
+		write(conn,
+			segment{st: client, ms: 0, ack: true, seq: 101, ackSeq: 501},
+			segment{st: server, ms: 1, ack: true, seq: 501, ackSeq: 101},
+		)
+		for _, ts := range conn.Stats() {
+			assert.Zero(t, ts.Stats.Handshake)
+		}
+	})
+}
@@ -45,6 +45,12 @@ type Stats struct {
 	ReceivedPackets uint64
 	ReceivedBytes   uint64
 	SkippedPackets  uint64
+
+	// Handshake 三次握手耗时 仅在 TCP 客户端方向完成握手后上报一次
+	Handshake time.Duration
+
+	// SynRetransmits SYN 重传次数 仅在 TCP 客户端方向上报
+	SynRetransmits uint64
 }
 
 // DecodeFunc 字节流的解析方法
@@ -156,6 +162,9 @@ func (p *pipe) isClosed() bool {
 type Conn struct {
 	pipe     *pipe
 	l, r     socket.Tuple
+	acks     [2]ackTracker // 分别对应 l, r 方向发送数据的确认情况
+	flows    [2]flowCounter
+	hs       handshakeTracker
 	activeAt int64 // unix timestamp
 }
 
@@ -195,6 +204,15 @@ func (c *Conn) Stats() []TupleStats {
 			Stats: c.pipe.r.Stats(),
 		})
 	}
+
+	if !c.hs.synAt.IsZero() {
+		client := []socket.Tuple{c.l, c.r}[c.hs.client]
+		for i := range ts {
+			if ts[i].Tuple == client {
+				c.hs.take(&ts[i].Stats)
+			}
+		}
+	}
 	return ts
 }
 
@@ -213,11 +231,56 @@ func (c *Conn) Write(seg socket.L4Packet, decodeFunc DecodeFunc) error {
 		return ErrNotConfirm // 理论上不应出现
 	}
 
+	c.trackFlow(seg)
+
 	// 写入并解码数据
 	c.activeAt = fasttime.UnixTimestamp()
 	return stream.Write(seg, decodeFunc)
 }
 
+func (c *Conn) trackFlow(seg socket.L4Packet) {
+	self, peer := 0, 1
+	if seg.SocketTuple() == c.r {
+		self, peer = 1, 0
+	}
+
+	var retransmitted bool
+	if tcpSeg, ok := seg.(*socket.TCPSegment); ok {
+		retransmitted = c.acks[self].onSend(tcpSeg)
+		c.acks[peer].onAck(tcpSeg)
+		c.hs.onSegment(self, tcpSeg)
+	}
+	c.flows[self].add(seg, retransmitted)
+}
+
+// Flows 返回两个方向自上次调用以来的流统计 读取即重置
+//
+// 与 Stats 分开计数 二者的读取方互不影响
+func (c *Conn) Flows() []FlowStats {
+	var flows []FlowStats
+	for i, st := range []socket.Tuple{c.l, c.r} {
+		if fs, ok := c.flows[i].take(st); ok {
+			flows = append(flows, fs)
+		}
+	}
+	return flows
+}
+
+// AckStates 返回两个方向已发送数据的确认进度
+//
+// 仅包含存在未确认数据或者未应答 keepalive 的方向
+func (c *Conn) AckStates() []AckState {
+	var states []AckState
+	for i, st := range []socket.Tuple{c.l, c.r} {
+		t := &c.acks[i]
+		if t.unackedAt.IsZero() && t.keepalives == 0 {
+			continue
+		}
+		states = append(states, t.state(st))
+	}
+	return states
+}
+
 // IsClosed 返回 Conn 是否已经处于结束态
 func (c *Conn) IsClosed() bool {
 	return c.pipe.isClosed()
@@ -229,3 +292,10 @@ func (c *Conn) IsClosed() bool {
 func (c *Conn) ActiveAt() time.Time {
 	return time.Unix(c.activeAt, 0)
 }
+
+// Handshake 返回 TCP 三次握手耗时 未观测到完整握手时返回 false
+//
+// 与 Stats 不同 读取不会消费握手数据 可重复调用
+func (c *Conn) Handshake() (time.Duration, bool) {
+	return c.hs.duration, c.hs.done
+}
@@ -17,6 +17,7 @@ import (
 	"math"
 	"sync/atomic"
 
+	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/zerocopy"
 )
@@ -102,13 +103,14 @@ func (s *tcpStream) Write(pkt socket.L4Packet, decodeFunc DecodeFunc) error {
 	}
 
 	// 无数据内容不处理
-	if len(seg.Payload) == 0 {
+	if seg.PayloadLen() == 0 {
 		return nil
 	}
 
+	// 序号按照链路上的实际长度推进 截断抓包时 Payload 仅为其中的前缀部分
 	seq := uint64(seg.Seq)
-	n := seq + uint64(len(seg.Payload))
-	s.stats.ReceivedBytes += uint64(len(seg.Payload))
+	n := seq + uint64(seg.PayloadLen())
+	s.stats.ReceivedBytes += uint64(seg.PayloadLen())
 
 	// seq 已经超过了 uint32 上限 将会重头计数
 	if n >= uint64(math.MaxUint32) {
@@ -129,14 +131,20 @@ func (s *tcpStream) Write(pkt socket.L4Packet, decodeFunc DecodeFunc) error {
 	case s.lastAck > seq:
 		// 数据收了一半 此时仅需写入后半部分即可
 		delta := s.lastAck - seq
-		payload = payload[delta:]
+		payload = payload[min(delta, uint64(len(payload))):]
 
 	case s.lastAck < seq:
 	}
 
-	s.zb.Write(payload)
-	if decodeFunc != nil {
-		decodeFunc(s.zb)
+	// GRO/TSO 或者 BIG TCP 合并后的超大分段可能超过 decoder 单次读取的长度
+	// 按照 ReadWriteBlockSize 切分后分批写入 保证每一批数据都能被完整消费
+	for len(payload) > 0 {
+		size := min(len(payload), common.ReadWriteBlockSize)
+		s.zb.Write(payload[:size])
+		if decodeFunc != nil {
+			decodeFunc(s.zb)
+		}
+		payload = payload[size:]
 	}
 	s.lastAck = n // 更新 lastAck 代表字节流`已经`收到的最后一个序号
 	return nil
@@ -0,0 +1,79 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connstream
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+)
+
+func TestTCPStreamSuperSegment(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+	}{
+		{name: "MSS", size: 1448},
+		{name: "GRO 64KB", size: 64 * 1024},
+		{name: "GRO 64KB+Header", size: 64*1024 + 128},
+		{name: "BIG TCP 256KB", size: 256 * 1024},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := bytes.Repeat([]byte("x"), tt.size)
+			stream := NewTCPStream(socket.Tuple{SrcPort: 50001, DstPort: 80})
+
+			// 模拟 decoder 每次回调仅读取一次
+			var got []byte
+			var calls int
+			err := stream.Write(&socket.TCPSegment{Seq: 1, Payload: payload}, func(r zerocopy.Reader) {
+				calls++
+				b, err := r.Read(common.ReadWriteBlockSize)
+				if err != nil {
+					return
+				}
+				got = append(got, b...)
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, payload, got)
+			assert.Equal(t, (tt.size+common.ReadWriteBlockSize-1)/common.ReadWriteBlockSize, calls)
+		})
+	}
+}
+
+func TestTCPStreamTruncatedSegment(t *testing.T) {
+	stream := NewTCPStream(socket.Tuple{SrcPort: 50001, DstPort: 80})
+
+	var got []byte
+	decode := func(r zerocopy.Reader) {
+		b, _ := r.Read(common.ReadWriteBlockSize)
+		got = append(got, b...)
+	}
+
+	// 截断的数据包按照实际长度推进序号 后续数据包不会被视为重传
+	assert.NoError(t, stream.Write(&socket.TCPSegment{Seq: 1, Payload: []byte("abc"), Length: 1000}, decode))
+	assert.NoError(t, stream.Write(&socket.TCPSegment{Seq: 1001, Payload: []byte("def")}, decode))
+	assert.NoError(t, stream.Write(&socket.TCPSegment{Seq: 500, Payload: []byte("xyz"), Length: 300}, decode))
+	assert.Equal(t, []byte("abcdef"), got)
+
+	stats := stream.Stats()
+	assert.Equal(t, uint64(1303), stats.ReceivedBytes)
+	assert.Equal(t, uint64(1), stats.SkippedPackets)
+}
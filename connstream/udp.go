@@ -74,11 +74,11 @@ func (s *udpStream) Write(pkt socket.L4Packet, decodeFunc DecodeFunc) error {
 	s.stats.ReceivedPackets++
 
 	// 无数据内容不处理
-	if len(seg.Payload) == 0 {
+	if seg.PayloadLen() == 0 {
 		return nil
 	}
 
-	s.stats.ReceivedBytes += uint64(len(seg.Payload))
+	s.stats.ReceivedBytes += uint64(seg.PayloadLen())
 	payload := seg.Payload
 	s.zb.Write(payload)
 	if decodeFunc != nil {
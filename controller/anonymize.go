@@ -0,0 +1,61 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package controller
+
+import (
+	"fmt"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+)
+
+// clientIsSrc 判断四元组的源端是否为客户端 服务端为端口命中协议规则的一端
+func (c *Controller) clientIsSrc(st socket.Tuple) bool {
+	return !c.pps.IsServerPort(st.SrcPort)
+}
+
+// anonHosts 返回四元组匿名化后的源以及目标地址 仅处理客户端一端
+func (c *Controller) anonHosts(st socket.Tuple) (string, string) {
+	src, dst := st.SrcIP.String(), st.DstIP.String()
+	if c.anon == nil {
+		return src, dst
+	}
+	if c.clientIsSrc(st) {
+		return c.anon.IP(src), dst
+	}
+	return src, c.anon.IP(dst)
+}
+
+// tupleString 返回用于日志输出的四元组 格式同 socket.Tuple.String
+func (c *Controller) tupleString(st socket.Tuple) string {
+	if c.anon == nil {
+		return st.String()
+	}
+	src, dst := c.anonHosts(st)
+	return fmt.Sprintf("%s:%d > %s:%d", src, st.SrcPort, dst, st.DstPort)
+}
+
+// anonFlows 原地处理流统计中的客户端地址 流统计以二进制形式导出地址 使用 anonymizer.Addr
+func (c *Controller) anonFlows(flows []common.Flow) {
+	if c.anon == nil {
+		return
+	}
+	for i := range flows {
+		st := &flows[i].Tuple
+		if c.clientIsSrc(*st) {
+			st.SrcIP = c.anon.Addr(st.SrcIP)
+		} else {
+			st.DstIP = c.anon.Addr(st.DstIP)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/packetd/packetd/internal/sigs"
+	"github.com/packetd/packetd/logger"
+	"github.com/packetd/packetd/sniffer"
+)
+
+const (
+	// headerOperator 调用方可选地声明操作人 仅作为审计记录的补充信息
+	headerOperator = "X-Packetd-Operator"
+
+	AuditActorSignal     = "signal"
+	auditActorAutoReload = "autoReload"
+
+	AuditActionReload        = "config.reload"
+	auditActionReloadRequest = "config.reload.request"
+	auditActionLoggerLevel   = "logger.level"
+	auditActionCapture       = "capture.trigger"
+	auditActionProfile       = "profile.capture"
+	auditActionInject        = "roundtrip.inject"
+)
+
+// RecordAudit 记录一次运行时控制操作 未开启审计时忽略
+func (c *Controller) RecordAudit(actor, action, detail string, err error) {
+	if auditErr := c.audit.Record(actor, action, detail, err); auditErr != nil {
+		logger.Errorf("failed to record audit (%s): %v", action, auditErr)
+	}
+}
+
+// requestReload 记录操作方并通过 SIGHUP 触发 reload
+//
+// reload 统一由信号处理流程执行 操作方在此暂存 由 ReloadActor 取出后写入审计记录
+func (c *Controller) requestReload(actor string) error {
+	c.reloadActor.Store(&actor)
+	return sigs.SelfReload()
+}
+
+// ReloadActor 取出并清空本次 reload 的操作方 未经 requestReload 触发时为 AuditActorSignal
+func (c *Controller) ReloadActor() string {
+	if actor := c.reloadActor.Swap(nil); actor != nil {
+		return *actor
+	}
+	return AuditActorSignal
+}
+
+// diffRules 返回 reload 前后协议规则的差异摘要 规则以 name 作为标识 未命名时使用 protocol
+func diffRules(prev, curr []sniffer.ProtoRule) string {
+	ruleKey := func(r sniffer.ProtoRule) string {
+		if r.Name != "" {
+			return r.Name
+		}
+		return r.Protocol
+	}
+
+	prevRules := make(map[string]sniffer.ProtoRule, len(prev))
+	for _, r := range prev {
+		prevRules[ruleKey(r)] = r
+	}
+
+	var added, changed []string
+	for _, r := range curr {
+		key := ruleKey(r)
+		p, ok := prevRules[key]
+		switch {
+		case !ok:
+			added = append(added, key)
+		case !reflect.DeepEqual(p, r):
+			changed = append(changed, key)
+		}
+		delete(prevRules, key)
+	}
+
+	var removed []string
+	for _, r := range prev {
+		key := ruleKey(r)
+		if _, ok := prevRules[key]; ok {
+			removed = append(removed, key)
+			delete(prevRules, key)
+		}
+	}
+
+	if len(added)+len(removed)+len(changed) == 0 {
+		return "rules=unchanged"
+	}
+	return fmt.Sprintf("rules.added=[%s] rules.removed=[%s] rules.changed=[%s]",
+		strings.Join(added, ","), strings.Join(removed, ","), strings.Join(changed, ","))
+}
+
+// requestActor 返回管理接口调用方标识 格式为 `operator@remote_addr`
+func requestActor(r *http.Request) string {
+	if operator := r.Header.Get(headerOperator); operator != "" {
+		return operator + "@" + r.RemoteAddr
+	}
+	return r.RemoteAddr
+}
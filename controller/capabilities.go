@@ -0,0 +1,117 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/processor"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/sniffer"
+)
+
+// features 当前构建支持的可选配置段 与 packetd.reference.yaml 中的配置项保持一致
+//
+// 新增需要显式开启的配置段时需同步追加 便于运维工具在下发配置前确认 agent 是否支持
+var features = []string{
+	"controller.anonymize",
+	"controller.audit",
+	"controller.capture",
+	"controller.captureQuality",
+	"controller.clockSkew",
+	"controller.dispatch",
+	"controller.forensics",
+	"controller.halfOpen",
+	"controller.idleConn",
+	"controller.inFlight",
+	"controller.layer4Metrics",
+	"controller.openapi",
+	"controller.probe",
+	"controller.profile",
+	"controller.recentErrors",
+	"exporter.collector",
+	"exporter.events",
+	"exporter.flows",
+	"exporter.routes",
+	"exporter.sinks",
+	"metricsStorage.exemplars",
+	"metricsStorage.vmHistogram",
+	"sniffer.dedup",
+	"sniffer.sflow",
+}
+
+// ProtocolCapability 单个协议的能力描述
+type ProtocolCapability struct {
+	Name     socket.L7Proto `json:"name"`
+	L4Proto  socket.L4Proto `json:"l4Proto"`
+	Versions []string       `json:"versions"`
+	Options  []string       `json:"options"`
+}
+
+// Capabilities 当前构建以及运行实例的能力描述
+//
+// Engine 为正在运行的抓包引擎 通过 CLI 查询时为空
+type Capabilities struct {
+	Build      common.BuildInfo     `json:"build"`
+	Protocols  []ProtocolCapability `json:"protocols"`
+	Engines    []string             `json:"engines"`
+	Engine     string               `json:"engine,omitempty"`
+	Processors []string             `json:"processors"`
+	Features   []string             `json:"features"`
+}
+
+// GetCapabilities 返回编译期注册的协议 抓包引擎以及处理器等能力描述
+func GetCapabilities() Capabilities {
+	protos := protocol.Protocols()
+	lst := make([]ProtocolCapability, 0, len(protos))
+	for _, name := range protos {
+		c := protocol.GetCapability(name)
+		l4, _ := socket.L7ProtoBased(name)
+		lst = append(lst, ProtocolCapability{
+			Name:     name,
+			L4Proto:  l4,
+			Versions: nonNil(c.Versions),
+			Options:  nonNil(c.Options),
+		})
+	}
+
+	return Capabilities{
+		Build:      common.GetBuildInfo(),
+		Protocols:  lst,
+		Engines:    sniffer.Engines(),
+		Processors: processor.Names(),
+		Features:   features,
+	}
+}
+
+// nonNil 保证 JSON 输出为空数组而非 null 方便调用方直接遍历
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func (c *Controller) routeCapabilities(w http.ResponseWriter, _ *http.Request) {
+	caps := GetCapabilities()
+	if c.snif != nil {
+		caps.Engine = c.snif.Name()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(caps)
+}
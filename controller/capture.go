@@ -0,0 +1,125 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/packetd/packetd/internal/capture"
+	"github.com/packetd/packetd/logger"
+)
+
+var (
+	errNegativeDuration     = errors.New("duration must not be negative")
+	errCaptureNotConfigured = errors.New("capture.full not configured")
+)
+
+// captureCheckInterval 时间窗口的最小粒度为分钟 按照更细的周期检查即可
+const captureCheckInterval = 10 * time.Second
+
+// decoderConfig 返回当前采集模式下生效的 decoder 配置
+func (c *Controller) decoderConfig() DecoderConfig {
+	return c.modeDecoderConfig(c.captureFull.Load())
+}
+
+// modeDecoderConfig 返回指定采集模式下的 decoder 配置
+func (c *Controller) modeDecoderConfig(full bool) DecoderConfig {
+	if full {
+		return c.cfg.Decoder.Merge(c.cfg.Capture.Full)
+	}
+	return c.cfg.Decoder
+}
+
+// scheduleCapture 周期性检查采集模式 模式发生变化时重建受影响协议的连接池
+//
+// 仅 capture.full 中声明的协议会被重建 其余协议不受影响
+func (c *Controller) scheduleCapture() {
+	ticker := time.NewTicker(captureCheckInterval)
+	defer ticker.Stop()
+
+	c.applyCapture()
+	for {
+		select {
+		case <-ticker.C:
+			c.applyCapture()
+
+		case <-c.captureNotify:
+			c.applyCapture()
+
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Controller) applyCapture() {
+	status := c.capture.Status(time.Now())
+	full := status.Mode == capture.ModeFull
+	if c.captureFull.Load() == full {
+		return
+	}
+
+	// 重建失败时保持原有模式 下个周期重试
+	if err := c.pps.Recreate(c.cfg.Capture.Full.Protos(), c.modeDecoderConfig(full)); err != nil {
+		logger.Errorf("failed to switch capture mode to %s: %v", status.Mode, err)
+		return
+	}
+
+	c.captureFull.Store(full)
+	if full {
+		captureFullMode.Set(1)
+	} else {
+		captureFullMode.Set(0)
+	}
+	logger.Infof("capture mode switched to %s (%s)", status.Mode, status.Reason)
+}
+
+// routeCapture 返回当前采集模式
+func (c *Controller) routeCapture(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.capture.Status(time.Now()))
+}
+
+// routeTriggerCapture 按需开启完整采集
+//
+// - duration: 完整采集时长 到期后自动回退至 lite 模式 为 0 时立即取消
+func (c *Controller) routeTriggerCapture(w http.ResponseWriter, r *http.Request) {
+	duration := r.FormValue("duration")
+	d, err := time.ParseDuration(duration)
+	if err == nil && d < 0 {
+		err = errNegativeDuration
+	}
+	if err == nil && len(c.cfg.Capture.Full.Protos()) == 0 {
+		err = errCaptureNotConfigured
+	}
+	c.RecordAudit(requestActor(r), auditActionCapture, "duration="+duration, err)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	c.capture.Trigger(time.Now(), d)
+	select {
+	case c.captureNotify <- struct{}{}:
+	default:
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.capture.Status(time.Now()))
+}
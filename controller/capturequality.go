@@ -0,0 +1,59 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+// sampleCaptureDrops 周期性地汇总各网卡的收包以及丢包计数
+func (c *Controller) sampleCaptureDrops() {
+	ticker := time.NewTicker(c.cfg.CaptureQuality.GetInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			var packets, drops uint
+			for _, s := range c.snif.Stats() {
+				packets += s.Packets
+				drops += s.Drops
+			}
+			c.quality.Observe(now, packets, drops)
+
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// markCaptureQuality 为请求至响应期间发生丢包的 RoundTrip 附加采集质量
+func (c *Controller) markCaptureQuality(rt socket.RoundTrip) socket.RoundTrip {
+	req, ok := socket.PeerOf(rt.Request())
+	if !ok {
+		return rt
+	}
+	end := req.Time
+	if rsp, ok := socket.PeerOf(rt.Response()); ok {
+		end = rsp.Time
+	}
+
+	quality := c.quality.Quality(req.Time, end)
+	if quality < 1 {
+		degradedRoundtrips.Inc()
+	}
+	return socket.WithCaptureQuality(rt, quality)
+}
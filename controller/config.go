@@ -13,7 +13,18 @@
 
 package controller
 
-import "time"
+import (
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/anonymizer"
+	"github.com/packetd/packetd/internal/capturequality"
+	"github.com/packetd/packetd/internal/dispatch"
+	"github.com/packetd/packetd/internal/halfopen"
+	"github.com/packetd/packetd/internal/openapi"
+	"github.com/packetd/packetd/internal/prober"
+	"github.com/packetd/packetd/internal/recenterrors"
+)
 
 type Config struct {
 	// Layer4Metrics 四层指标统计
@@ -30,6 +41,145 @@ type Config struct {
 
 	// Decoder 指定每种 decoder 解析特性
 	Decoder DecoderConfig `config:"decoder"`
+
+	// Dispatch 数据包分发至解析 worker 的配置
+	Dispatch dispatch.Config `config:"dispatch"`
+
+	// Forensics 解析错误现场采集 仅用于排查解析问题
+	Forensics ForensicsConfig `config:"forensics"`
+
+	// ClockSkew 请求/响应方向来自不同采集点时的时钟偏移修正
+	ClockSkew ClockSkewConfig `config:"clockSkew"`
+
+	// IdleConn 空闲链接检测
+	IdleConn IdleConnConfig `config:"idleConn"`
+
+	// HalfOpen 半开链接检测
+	HalfOpen HalfOpenConfig `config:"halfOpen"`
+
+	// Audit 运行时控制操作审计
+	Audit AuditConfig `config:"audit"`
+
+	// Capture 采集模式调度
+	Capture CaptureConfig `config:"capture"`
+
+	// Profile 按需采集 CPU profile 以及执行轨迹
+	Profile ProfileConfig `config:"profile"`
+
+	// Probe 主动探测
+	Probe prober.Config `config:"probe"`
+
+	// Anonymize 导出前对客户端 IP 进行匿名化
+	Anonymize anonymizer.Config `config:"anonymize"`
+
+	// RecentErrors 按协议在内存中保留最近的错误 RoundTrip
+	RecentErrors recenterrors.Config `config:"recentErrors"`
+
+	// OpenAPI 依据 OpenAPI 描述为 HTTP 请求匹配接口路由以及 operationId
+	OpenAPI openapi.Config `config:"openapi"`
+
+	// InFlight 按照协议以及服务端统计进行中的请求数
+	InFlight InFlightConfig `config:"inFlight"`
+
+	// CaptureQuality 依据内核丢包计数标记采集质量下降期间的 RoundTrip
+	CaptureQuality capturequality.Config `config:"captureQuality"`
+
+	// TCPTimestamps 记录请求首个数据段的 TSval 供 collector 关联两端主机上的同一个请求（实验性）
+	TCPTimestamps TCPTimestampsConfig `config:"tcpTimestamps"`
+}
+
+type ProfileConfig struct {
+	Dir         string        `config:"dir"`
+	MaxDuration time.Duration `config:"maxDuration"`
+}
+
+// CaptureConfig 采集模式调度配置
+//
+// Windows 为每日开启完整采集的时间窗口 Full 为完整采集模式下覆盖的 decoder 配置
+type CaptureConfig struct {
+	Windows []string      `config:"windows"`
+	Full    DecoderConfig `config:"full"`
+}
+
+type ForensicsConfig struct {
+	Enabled      bool   `config:"enabled"`
+	Filename     string `config:"filename"`
+	MaxDumpBytes int    `config:"maxDumpBytes"`
+	MaxPerMinute int    `config:"maxPerMinute"`
+}
+
+type ClockSkewConfig struct {
+	Enabled   bool          `config:"enabled"`
+	MaxOffset time.Duration `config:"maxOffset"`
+}
+
+type AuditConfig struct {
+	Enabled  bool   `config:"enabled"`
+	Filename string `config:"filename"`
+}
+
+// GetFilename 返回审计日志文件 默认为 packetd.audit.log
+func (c AuditConfig) GetFilename() string {
+	if c.Filename == "" {
+		return "packetd.audit.log"
+	}
+	return c.Filename
+}
+
+type TCPTimestampsConfig struct {
+	Enabled bool `config:"enabled"`
+}
+
+type InFlightConfig struct {
+	Enabled  bool          `config:"enabled"`
+	Interval time.Duration `config:"interval"`
+}
+
+// GetInterval 返回统计周期 默认为 10s
+func (c InFlightConfig) GetInterval() time.Duration {
+	if c.Interval <= 0 {
+		return 10 * time.Second
+	}
+	return c.Interval
+}
+
+type IdleConnConfig struct {
+	Enabled   bool          `config:"enabled"`
+	Threshold time.Duration `config:"threshold"`
+}
+
+// GetThreshold 返回空闲阈值 默认为 2m
+func (c IdleConnConfig) GetThreshold() time.Duration {
+	if c.Threshold <= 0 {
+		return 2 * time.Minute
+	}
+	return c.Threshold
+}
+
+type HalfOpenConfig struct {
+	Enabled     bool          `config:"enabled"`
+	Stalled     time.Duration `config:"stalled"`
+	Retransmits int           `config:"retransmits"`
+	Keepalives  int           `config:"keepalives"`
+}
+
+// Options 返回检测阈值 未配置时默认数据停滞 30s 且重传 3 次 或者 keepalive 连续 3 次无回应
+func (c HalfOpenConfig) Options() halfopen.Options {
+	opts := halfopen.Options{
+		Stalled:     c.Stalled,
+		Retransmits: c.Retransmits,
+		Keepalives:  c.Keepalives,
+	}
+	if opts.Stalled <= 0 {
+		opts.Stalled = 30 * time.Second
+	}
+	if opts.Retransmits <= 0 {
+		opts.Retransmits = 3
+	}
+	if opts.Keepalives <= 0 {
+		opts.Keepalives = 3
+	}
+	return opts
 }
 
 func (c Config) GetConnExpired() time.Duration {
@@ -40,8 +190,19 @@ func (c Config) GetConnExpired() time.Duration {
 }
 
 type DecoderConfig struct {
-	MongoDB map[string]any `config:"mongodb"`
-	Http    map[string]any `config:"http"`
+	MongoDB    map[string]any `config:"mongodb"`
+	Http       map[string]any `config:"http"`
+	HTTP2      map[string]any `config:"http2"`
+	GRPC       map[string]any `config:"grpc"`
+	Kafka      map[string]any `config:"kafka"`
+	DNS        map[string]any `config:"dns"`
+	NTP        map[string]any `config:"ntp"`
+	TLS        map[string]any `config:"tls"`
+	MySQL      map[string]any `config:"mysql"`
+	AMQP       map[string]any `config:"amqp"`
+	PostgreSQL map[string]any `config:"postgresql"`
+	Redis      map[string]any `config:"redis"`
+	UDPRPC     map[string]any `config:"udprpc"`
 }
 
 func (c DecoderConfig) Get(proto string) map[string]any {
@@ -52,12 +213,92 @@ func (c DecoderConfig) Get(proto string) map[string]any {
 	return m
 }
 
+// Merge 返回叠加 overrides 之后的配置 不修改原有配置
+func (c DecoderConfig) Merge(overrides DecoderConfig) DecoderConfig {
+	merge := func(base, override map[string]any) map[string]any {
+		if len(override) == 0 {
+			return base
+		}
+		m := make(map[string]any, len(base)+len(override))
+		for k, v := range base {
+			m[k] = v
+		}
+		for k, v := range override {
+			m[k] = v
+		}
+		return m
+	}
+
+	return DecoderConfig{
+		MongoDB:    merge(c.MongoDB, overrides.MongoDB),
+		Http:       merge(c.Http, overrides.Http),
+		HTTP2:      merge(c.HTTP2, overrides.HTTP2),
+		GRPC:       merge(c.GRPC, overrides.GRPC),
+		Kafka:      merge(c.Kafka, overrides.Kafka),
+		DNS:        merge(c.DNS, overrides.DNS),
+		NTP:        merge(c.NTP, overrides.NTP),
+		TLS:        merge(c.TLS, overrides.TLS),
+		MySQL:      merge(c.MySQL, overrides.MySQL),
+		AMQP:       merge(c.AMQP, overrides.AMQP),
+		PostgreSQL: merge(c.PostgreSQL, overrides.PostgreSQL),
+		Redis:      merge(c.Redis, overrides.Redis),
+		UDPRPC:     merge(c.UDPRPC, overrides.UDPRPC),
+	}
+}
+
+// Protos 返回存在配置项的协议列表
+func (c DecoderConfig) Protos() []socket.L7Proto {
+	var protos []socket.L7Proto
+	for _, proto := range []socket.L7Proto{
+		socket.L7ProtoMongoDB,
+		socket.L7ProtoHTTP,
+		socket.L7ProtoHTTP2,
+		socket.L7ProtoGRPC,
+		socket.L7ProtoKafka,
+		socket.L7ProtoDNS,
+		socket.L7ProtoNTP,
+		socket.L7ProtoTLS,
+		socket.L7ProtoMySQL,
+		socket.L7ProtoAMQP,
+		socket.L7ProtoPostgreSQL,
+		socket.L7ProtoRedis,
+		socket.L7ProtoUDPRPC,
+	} {
+		if len(c.get(string(proto))) > 0 {
+			protos = append(protos, proto)
+		}
+	}
+	return protos
+}
+
 func (c DecoderConfig) get(proto string) map[string]any {
 	switch proto {
 	case "mongodb":
 		return c.MongoDB
 	case "http":
 		return c.Http
+	case "http2":
+		return c.HTTP2
+	case "grpc":
+		return c.GRPC
+	case "kafka":
+		return c.Kafka
+	case "dns":
+		return c.DNS
+	case "ntp":
+		return c.NTP
+	case "tls":
+		return c.TLS
+	case "mysql":
+		return c.MySQL
+	case "amqp":
+		return c.AMQP
+	case "postgresql":
+		return c.PostgreSQL
+	case "redis":
+		return c.Redis
+	case "udprpc":
+		return c.UDPRPC
 	}
 
 	return nil
@@ -16,8 +16,11 @@ package controller
 import (
 	"context"
 	"io"
+	"net"
 	"os"
+	"regexp"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -27,10 +30,19 @@ import (
 	"github.com/packetd/packetd/confengine"
 	"github.com/packetd/packetd/connstream"
 	"github.com/packetd/packetd/exporter"
+	"github.com/packetd/packetd/internal/anonymizer"
+	"github.com/packetd/packetd/internal/auditlog"
+	"github.com/packetd/packetd/internal/capture"
+	"github.com/packetd/packetd/internal/capturequality"
+	"github.com/packetd/packetd/internal/dispatch"
 	"github.com/packetd/packetd/internal/labels"
 	"github.com/packetd/packetd/internal/metricstorage"
+	"github.com/packetd/packetd/internal/openapi"
+	"github.com/packetd/packetd/internal/prober"
+	"github.com/packetd/packetd/internal/profiler"
 	"github.com/packetd/packetd/internal/pubsub"
-	"github.com/packetd/packetd/internal/sigs"
+	"github.com/packetd/packetd/internal/recenterrors"
+	"github.com/packetd/packetd/internal/tcpstamp"
 	"github.com/packetd/packetd/internal/wait"
 	"github.com/packetd/packetd/logger"
 	"github.com/packetd/packetd/pipeline"
@@ -39,6 +51,9 @@ import (
 	"github.com/packetd/packetd/sniffer"
 )
 
+// pipelineFlushInterval 定时输出 pipeline 中已经结束的窗口
+const pipelineFlushInterval = time.Second
+
 type Controller struct {
 	ctx        context.Context
 	cancel     context.CancelFunc
@@ -55,6 +70,27 @@ type Controller struct {
 
 	rtCh  chan socket.RoundTrip
 	rtBus *pubsub.PubSub
+
+	dispatcher *dispatch.Dispatcher
+	audit      *auditlog.Logger
+
+	capture       *capture.Scheduler
+	captureFull   atomic.Bool
+	captureNotify chan struct{}
+
+	profiler *profiler.Profiler
+	prober   *prober.Prober
+	anon     *anonymizer.Anonymizer
+	openapi  *openapi.Router
+	quality  *capturequality.Tracker
+	stamps   *tcpstamp.Index
+	sampled  *sampledFlows
+
+	recentErrors *recenterrors.Store
+
+	// rules 当前生效的协议规则 用于审计记录 reload 前后的差异
+	rules       []sniffer.ProtoRule
+	reloadActor atomic.Pointer[string]
 }
 
 func setupLogger(conf *confengine.Config) error {
@@ -80,6 +116,40 @@ func setupLogger(conf *confengine.Config) error {
 	return nil
 }
 
+// setupForensics 根据配置开启或关闭解析错误现场采集 redact 不为空时隐藏客户端地址
+func setupForensics(cfg ForensicsConfig, redact func(socket.Tuple) string) {
+	if !cfg.Enabled {
+		protocol.SetForensics(nil)
+		return
+	}
+
+	output := func(s string) { logger.Warnf("%s", s) }
+	if cfg.Filename != "" {
+		l := logger.New(logger.Options{
+			Level:      string(logger.LevelWarn),
+			Filename:   cfg.Filename,
+			MaxSize:    10,
+			MaxBackups: 3,
+			MaxAge:     7,
+		})
+		output = func(s string) { l.Warnf("%s", s) }
+	}
+	f := protocol.NewForensics(cfg.MaxDumpBytes, cfg.MaxPerMinute, output)
+	if redact != nil {
+		f.SetRedact(redact)
+	}
+	protocol.SetForensics(f)
+}
+
+// setupClockSkew 根据配置开启或关闭镜像采集的时钟偏移修正
+func setupClockSkew(cfg ClockSkewConfig) {
+	if !cfg.Enabled {
+		protocol.SetClockSkew(nil)
+		return
+	}
+	protocol.SetClockSkew(protocol.NewClockSkew(cfg.MaxOffset))
+}
+
 func New(conf *confengine.Config, configPath string) (*Controller, error) {
 	var cfg Config
 	if err := conf.UnpackChild("controller", &cfg); err != nil {
@@ -89,6 +159,7 @@ func New(conf *confengine.Config, configPath string) (*Controller, error) {
 	if err := setupLogger(conf); err != nil {
 		return nil, err
 	}
+	setupClockSkew(cfg.ClockSkew)
 
 	snif, err := sniffer.New(conf)
 	if err != nil {
@@ -120,9 +191,38 @@ func New(conf *confengine.Config, configPath string) (*Controller, error) {
 		return nil, err
 	}
 
+	captureScheduler, err := capture.NewScheduler(cfg.Capture.Windows)
+	if err != nil {
+		return nil, err
+	}
+
+	var pb *prober.Prober
+	if cfg.Probe.Enabled {
+		if pb, err = prober.New(cfg.Probe); err != nil {
+			return nil, err
+		}
+	}
+
+	anon, err := anonymizer.New(cfg.Anonymize)
+	if err != nil {
+		return nil, err
+	}
+
+	router, err := openapi.New(cfg.OpenAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	var audit *auditlog.Logger
+	if cfg.Audit.Enabled {
+		if audit, err = auditlog.New(cfg.Audit.GetFilename()); err != nil {
+			return nil, err
+		}
+	}
+
 	rtCh := make(chan socket.RoundTrip, common.Concurrency())
 	ctx, cancel := context.WithCancel(context.Background())
-	return &Controller{
+	c := &Controller{
 		ctx:            ctx,
 		cancel:         cancel,
 		cfg:            cfg,
@@ -135,7 +235,82 @@ func New(conf *confengine.Config, configPath string) (*Controller, error) {
 		metricsStorage: metricsStorage,
 		rtCh:           rtCh,
 		rtBus:          pubsub.New(),
-	}, nil
+		audit:          audit,
+		capture:        captureScheduler,
+		captureNotify:  make(chan struct{}, 1),
+		profiler:       profiler.New(cfg.Profile.Dir, cfg.Profile.MaxDuration),
+		prober:         pb,
+		anon:           anon,
+		openapi:        router,
+	}
+	if cfg.CaptureQuality.Enabled {
+		c.quality = capturequality.New()
+	}
+	if cfg.TCPTimestamps.Enabled {
+		c.stamps = tcpstamp.New()
+	}
+	if _, ok := exp.FlowsEnabled(); ok {
+		c.sampled = newSampledFlows()
+	}
+	if cfg.RecentErrors.Enabled {
+		c.recentErrors = recenterrors.New(cfg.RecentErrors.GetSize())
+	}
+	// 仅当监听单个网卡时 worker 才能跟随网卡所在的 NUMA 节点
+	var snifCfg sniffer.Config
+	if err := conf.UnpackChild("sniffer", &snifCfg); err != nil {
+		return nil, err
+	}
+	c.rules = snifCfg.Protocols.Rules
+	c.dispatcher, err = dispatch.New(cfg.Dispatch, numaIface(snifCfg), c.handleL4Packets)
+	if err != nil {
+		return nil, err
+	}
+	c.dispatcher.SetClassifier(c.pps.Proto)
+
+	var redact func(socket.Tuple) string
+	if anon != nil {
+		redact = c.tupleString
+	}
+	setupForensics(cfg.Forensics, redact)
+	return c, nil
+}
+
+// numaIface 返回监听的唯一网卡名称
+//
+// sniffer.ifaces 为正则表达式 匹配到多个网卡或者从文件读取时返回空
+func numaIface(cfg sniffer.Config) string {
+	if cfg.File != "" || (cfg.Engine != "" && cfg.Engine != "pcap") {
+		return ""
+	}
+	if cfg.Ifaces == "" || cfg.Ifaces == "any" {
+		return ""
+	}
+
+	r, err := regexp.Compile(cfg.Ifaces)
+	if err != nil {
+		return ""
+	}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+
+	var name string
+	for _, iface := range ifaces {
+		if !r.MatchString(iface.Name) {
+			continue
+		}
+		// 与 sniffer 保持一致 忽略没有地址的网卡
+		addrs, err := iface.Addrs()
+		if err != nil || len(addrs) == 0 {
+			continue
+		}
+		if name != "" {
+			return ""
+		}
+		name = iface.Name
+	}
+	return name
 }
 
 func (c *Controller) Start() error {
@@ -144,7 +319,29 @@ func (c *Controller) Start() error {
 	for i := 0; i < common.Concurrency(); i++ {
 		go wait.Until(c.ctx, c.consumeRoundTrip)
 	}
+	go c.flushPipeline()
 	go c.removeExpiredConn()
+	if c.cfg.IdleConn.Enabled {
+		go c.detectIdleConn()
+	}
+	if interval, ok := c.exp.FlowsEnabled(); ok {
+		go c.loopExportFlows(interval)
+	}
+	if c.cfg.HalfOpen.Enabled {
+		go c.detectHalfOpenConn()
+	}
+	if c.cfg.InFlight.Enabled {
+		go c.loopUpdateInFlight()
+	}
+	if c.quality != nil {
+		go c.sampleCaptureDrops()
+	}
+	if c.prober != nil {
+		go c.prober.Run(c.ctx, c.exportProbeReport)
+	}
+	if len(c.cfg.Capture.Full.Protos()) > 0 {
+		go c.scheduleCapture()
+	}
 
 	if c.svr != nil {
 		go func() {
@@ -160,54 +357,55 @@ func (c *Controller) Start() error {
 	}
 
 	c.exp.Start()
-	c.snif.SetOnL4Packet(func(pkt socket.L4Packet) {
-		port, pool := c.pps.DecideProto(pkt.SocketTuple())
-		if pool == nil {
-			return
-		}
-		conn := pool.GetOrCreate(pkt.SocketTuple(), port)
-		if conn == nil {
-			return
-		}
-
-		err := conn.OnL4Packet(pkt, c.rtCh)
-		if err == nil {
-			return
-		}
-		if errors.Is(err, protocol.ErrConnClosed) {
-			// 删除链接前先记录 Stats
-			// 避免 metrics 接口还没来得及记录 conn 就已经被删除
-			for _, stat := range conn.Stats() {
-				c.updatePoolStats(stat)
-			}
-			pool.Delete(pkt.SocketTuple())
-			return
-		}
-		logger.Debugf("failed to handle %s packet: %v", pkt.SocketTuple(), err)
-	})
+	protocol.SetProgressHandler(c.exportProgress)
+	c.snif.SetOnL4Packet(c.dispatcher.Dispatch)
 
 	return nil
 }
 
-// Reload 重载配置
+// Reload 重载配置 actor 为触发本次 reload 的操作方 见 ReloadActor
 //
 // - 重载 sniffer，仅支持重新编译 protocols rule
-func (c *Controller) Reload(conf *confengine.Config) error {
+func (c *Controller) Reload(actor string, conf *confengine.Config) error {
+	summary, err := c.reload(conf)
+	c.RecordAudit(actor, AuditActionReload, "path="+c.configPath+" "+summary, err)
+	return err
+}
+
+func (c *Controller) reload(conf *confengine.Config) (string, error) {
 	var cfg sniffer.Config
 	if err := conf.UnpackChild("sniffer", &cfg); err != nil {
-		return err
+		return "", err
 	}
 
+	summary := diffRules(c.rules, cfg.Protocols.Rules)
 	if err := c.snif.Reload(&cfg); err != nil {
-		return err
+		return summary, err
 	}
-	return c.pps.Reload(c.snif.L7Ports(), c.cfg.Decoder)
+	if err := c.pps.Reload(c.snif.L7Ports(), c.decoderConfig()); err != nil {
+		return summary, err
+	}
+	c.rules = cfg.Protocols.Rules
+	return summary, nil
 }
 
 func (c *Controller) Stop() {
 	c.snif.Close()
+	c.dispatcher.Close()
+	protocol.SetProgressHandler(nil)
+	c.pl.Clean(c.exp.Export)
 	c.exp.Close()
 	c.cancel()
+	c.audit.Close()
+}
+
+// exportProgress 将 decoder 输出的响应进度作为事件导出 未开启 exporter.events 时直接丢弃
+func (c *Controller) exportProgress(p protocol.Progress) {
+	p.ClientAddress = c.anon.IP(p.ClientAddress)
+	c.exp.Export(&common.Record{
+		RecordType: common.RecordEvents,
+		Data:       &common.EventsData{Data: []any{p}},
+	})
 }
 
 func (c *Controller) autoReload() {
@@ -229,7 +427,8 @@ func (c *Controller) autoReload() {
 		case <-ticker.C:
 			t := getModeTime()
 			if t != updated {
-				_ = sigs.SelfReload()
+				err := c.requestReload(auditActorAutoReload)
+				c.RecordAudit(auditActorAutoReload, auditActionReloadRequest, "config file modified", err)
 				updated = t
 			}
 
@@ -248,6 +447,9 @@ func (c *Controller) removeExpiredConn() {
 		case <-ticker.C:
 			stats := c.pps.RemoveExpired(c.cfg.GetConnExpired())
 			c.updateRemoveExpired(stats)
+			if c.stamps != nil {
+				c.stamps.RemoveExpired(time.Now().Add(-tcpStampExpired))
+			}
 
 		case <-c.ctx.Done():
 			return
@@ -255,6 +457,89 @@ func (c *Controller) removeExpiredConn() {
 	}
 }
 
+// flowEntry 批次内缓存的链接查找结果
+type flowEntry struct {
+	proto socket.L7Proto
+	pool  protocol.ConnPool
+	conn  protocol.Conn
+}
+
+// handleL4Packets 将一批数据包交由对应协议的链接处理
+//
+// 同一批次内相同链接的数据包仅查找一次链接 摊薄逐包查找 portPools 以及 ConnPool 的开销
+func (c *Controller) handleL4Packets(pkts []socket.L4Packet) {
+	snap := c.pps.Acquire()
+	defer c.pps.Release(snap)
+
+	var flows map[socket.Tuple]flowEntry
+	if len(pkts) > 1 {
+		flows = make(map[socket.Tuple]flowEntry, len(pkts))
+	}
+
+	for _, pkt := range pkts {
+		if rate := socket.SamplingRate(pkt); rate > 0 {
+			c.observeSampled(snap, pkt, rate)
+			continue
+		}
+
+		st := pkt.SocketTuple()
+		entry, ok := flows[st]
+		if !ok {
+			entry = lookupFlow(snap, st)
+			if flows != nil {
+				flows[st] = entry
+				flows[st.Mirror()] = entry
+			}
+		}
+		if entry.conn == nil {
+			continue
+		}
+		if c.stamps != nil {
+			if seg, ok := pkt.(*socket.TCPSegment); ok {
+				c.stamps.Observe(seg)
+			}
+		}
+
+		if c.handleL4Packet(entry, pkt) {
+			continue
+		}
+		// 链接已经被删除 批次内后续数据包需要重新查找
+		delete(flows, st)
+		delete(flows, st.Mirror())
+	}
+}
+
+func lookupFlow(snap *poolSnapshot, st socket.Tuple) flowEntry {
+	port, proto, pool := snap.DecideProto(st)
+	if pool == nil {
+		return flowEntry{}
+	}
+	return flowEntry{proto: proto, pool: pool, conn: pool.GetOrCreate(st, port)}
+}
+
+// handleL4Packet 将数据包交由链接处理 链接被删除时返回 false
+func (c *Controller) handleL4Packet(entry flowEntry, pkt socket.L4Packet) bool {
+	pool, conn := entry.pool, entry.conn
+	err := conn.OnL4Packet(pkt, c.rtCh)
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, protocol.ErrConnClosed) {
+		// 删除链接前先记录 Stats
+		// 避免 metrics 接口还没来得及记录 conn 就已经被删除
+		for _, stat := range conn.Stats() {
+			c.updatePoolStats(stat)
+		}
+		if _, ok := c.exp.FlowsEnabled(); ok {
+			c.exportFlows(collectFlows(entry.proto, pool.L4Proto(), conn))
+		}
+		pool.Delete(pkt.SocketTuple())
+		return false
+	}
+	logger.Debugf("failed to handle %s packet: %v", c.tupleString(pkt.SocketTuple()), err)
+	return true
+}
+
 func (c *Controller) recordMetrics() {
 	uptime.Set(float64(time.Now().Unix() - common.Started()))
 
@@ -263,6 +548,17 @@ func (c *Controller) recordMetrics() {
 	for _, s := range c.snif.Stats() {
 		snifferReceivedPackets.WithLabelValues(s.Name).Set(float64(s.Packets))
 		snifferDroppedPackets.WithLabelValues(s.Name).Set(float64(s.Drops))
+		snifferDuplicatedPackets.WithLabelValues(s.Name).Set(float64(s.Duplicates))
+	}
+	for _, s := range c.dispatcher.Stats() {
+		worker := strconv.Itoa(s.Worker)
+		workerHandledPackets.WithLabelValues(worker).Set(float64(s.Packets))
+		workerHandledBatches.WithLabelValues(worker).Set(float64(s.Batches))
+		workerBusySeconds.WithLabelValues(worker).Set(s.Busy.Seconds())
+		workerQueuedPackets.WithLabelValues(worker).Set(float64(s.Queued))
+	}
+	for proto, n := range c.dispatcher.Shed() {
+		workerShedPackets.WithLabelValues(string(proto)).Add(float64(n))
 	}
 }
 
@@ -271,33 +567,63 @@ func (c *Controller) updatePoolStats(stats connstream.TupleStats) {
 		return
 	}
 
+	lbs := c.layer4Labels(stats.Tuple)
+	ss := stats.Stats
+	switch ss.Proto {
+	case socket.L4ProtoTCP:
+		c.metricsStorage.Update(
+			metricstorage.NewCounterConstMetric("tcp_received_packets_total", float64(ss.ReceivedPackets), lbs),
+			metricstorage.NewCounterConstMetric("tcp_received_bytes_total", float64(ss.ReceivedBytes), lbs),
+			metricstorage.NewCounterConstMetric("tcp_skipped_packets_total", float64(ss.SkippedPackets), lbs),
+		)
+		c.updateHandshakeStats(stats)
+
+	case socket.L4ProtoUDP:
+		c.metricsStorage.Update(
+			metricstorage.NewCounterConstMetric("udp_received_packets_total", float64(ss.ReceivedPackets), lbs),
+			metricstorage.NewCounterConstMetric("udp_received_bytes_total", float64(ss.ReceivedBytes), lbs),
+		)
+	}
+}
+
+// layer4Labels 按照 layer4Metrics.requiredLabels 生成四层指标的维度
+func (c *Controller) layer4Labels(st socket.Tuple) labels.Labels {
 	var lbs labels.Labels
+	srcHost, dstHost := c.anonHosts(st)
 	for _, l := range c.cfg.Layer4Metrics.RequiredLabels {
 		switch l {
 		case "source.host":
-			lbs = append(lbs, labels.Label{Name: "src_host", Value: stats.Tuple.SrcIP.String()})
+			lbs = append(lbs, labels.Label{Name: "src_host", Value: srcHost})
 		case "source.port":
-			lbs = append(lbs, labels.Label{Name: "src_port", Value: strconv.Itoa(int(stats.Tuple.SrcPort))})
+			lbs = append(lbs, labels.Label{Name: "src_port", Value: strconv.Itoa(int(st.SrcPort))})
 		case "destination.host":
-			lbs = append(lbs, labels.Label{Name: "dst_host", Value: stats.Tuple.DstIP.String()})
+			lbs = append(lbs, labels.Label{Name: "dst_host", Value: dstHost})
 		case "destination.port":
-			lbs = append(lbs, labels.Label{Name: "dst_port", Value: strconv.Itoa(int(stats.Tuple.DstPort))})
+			lbs = append(lbs, labels.Label{Name: "dst_port", Value: strconv.Itoa(int(st.DstPort))})
 		}
 	}
+	return lbs
+}
 
+// updateHandshakeStats 记录 TCP 三次握手指标 stats 为客户端方向 以服务端地址作为标签
+func (c *Controller) updateHandshakeStats(stats connstream.TupleStats) {
 	ss := stats.Stats
-	switch ss.Proto {
-	case socket.L4ProtoTCP:
+	if ss.Handshake <= 0 && ss.SynRetransmits == 0 {
+		return
+	}
+
+	lbs := labels.Labels{
+		{Name: "dst_host", Value: stats.Tuple.DstIP.String()},
+		{Name: "dst_port", Value: strconv.Itoa(int(stats.Tuple.DstPort))},
+	}
+	if ss.Handshake > 0 {
 		c.metricsStorage.Update(
-			metricstorage.NewCounterConstMetric("tcp_received_packets_total", float64(ss.ReceivedPackets), lbs),
-			metricstorage.NewCounterConstMetric("tcp_received_bytes_total", float64(ss.ReceivedBytes), lbs),
-			metricstorage.NewCounterConstMetric("tcp_skipped_packets_total", float64(ss.SkippedPackets), lbs),
+			metricstorage.NewHistogramConstMetric("tcp_handshake_duration_seconds", ss.Handshake.Seconds(), metricstorage.UnitSeconds, lbs),
 		)
-
-	case socket.L4ProtoUDP:
+	}
+	if ss.SynRetransmits > 0 {
 		c.metricsStorage.Update(
-			metricstorage.NewCounterConstMetric("udp_received_packets_total", float64(ss.ReceivedPackets), lbs),
-			metricstorage.NewCounterConstMetric("udp_received_bytes_total", float64(ss.ReceivedBytes), lbs),
+			metricstorage.NewCounterConstMetric("tcp_syn_retransmits_total", float64(ss.SynRetransmits), lbs),
 		)
 	}
 }
@@ -316,17 +642,47 @@ func (c *Controller) updateRemoveExpired(stats map[socket.L4Proto]int) {
 	}
 }
 
+// flushPipeline 定时输出 pipeline 中已经结束的窗口 由单个协程负责 避免多个消费协程重复 Flush
+func (c *Controller) flushPipeline() {
+	ticker := time.NewTicker(pipelineFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.pl.Flush(false, c.exp.Export)
+
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
 func (c *Controller) consumeRoundTrip() {
 	for {
 		select {
 		case rt := <-c.rtCh:
 			handledRoundtrips.Inc()
-			record := common.NewRecord(common.RecordRoundTrips, rt)
-			c.publish(record)
-			c.exp.Export(record)
-			c.pl.Range(record, func(dst *common.Record) {
-				c.exp.Export(dst)
-			})
+			if socket.IsHalfOpen(rt) {
+				halfOpenRoundtrips.WithLabelValues(string(rt.Proto())).Inc()
+			}
+			if c.stamps != nil {
+				rt = c.markTCPTimestamp(rt)
+			}
+			c.anon.RoundTrip(rt) // 先于任何消费方 保证所有衍生数据中的客户端地址一致
+			if c.openapi != nil {
+				c.matchOperation(rt)
+			}
+			if c.quality != nil {
+				rt = c.markCaptureQuality(rt)
+			}
+			if c.prober != nil {
+				c.prober.Observe(rt)
+			}
+			if c.recentErrors != nil {
+				c.recordError(rt)
+			}
+			c.exportRoundTrip(rt)
 
 		case <-c.ctx.Done():
 			return
@@ -334,6 +690,16 @@ func (c *Controller) consumeRoundTrip() {
 	}
 }
 
+// exportRoundTrip 导出 roundtrip 并交由 pipeline 处理
+func (c *Controller) exportRoundTrip(rt socket.RoundTrip) {
+	record := common.NewRecord(common.RecordRoundTrips, rt)
+	c.publish(record)
+	c.exp.Export(record)
+	c.pl.Range(record, func(dst *common.Record) {
+		c.exp.Export(dst)
+	})
+}
+
 func (c *Controller) publish(record *common.Record) {
 	switch record.RecordType {
 	case common.RecordRoundTrips:
@@ -0,0 +1,156 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/metricstorage"
+	"github.com/packetd/packetd/protocol"
+)
+
+// collectFlows 读取链接自上次导出以来的流统计
+func collectFlows(proto socket.L7Proto, l4Proto socket.L4Proto, conn protocol.Conn) []common.Flow {
+	var flows []common.Flow
+	for _, fs := range conn.Flows() {
+		flows = append(flows, common.Flow{
+			Tuple:       fs.Tuple,
+			L4Proto:     l4Proto,
+			L7Proto:     proto,
+			Packets:     fs.Packets,
+			Bytes:       fs.Bytes,
+			Retransmits: fs.Retransmits,
+			Start:       fs.Start,
+			End:         fs.End,
+		})
+	}
+	return flows
+}
+
+func (c *Controller) exportFlows(flows []common.Flow) {
+	if len(flows) == 0 {
+		return
+	}
+	c.anonFlows(flows)
+	c.exp.Export(common.NewRecord(common.RecordFlows, &common.FlowsData{Data: flows}))
+}
+
+// loopExportFlows 周期性导出所有活跃链接的流统计
+//
+// 已关闭的链接在删除前会立即导出剩余的统计 参见 handleL4Packet
+func (c *Controller) loopExportFlows(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			var flows []common.Flow
+			c.pps.RangeConns(func(proto socket.L7Proto, _ socket.Tuple, conn protocol.Conn) {
+				l4Proto, _ := socket.L7ProtoBased(proto)
+				flows = append(flows, collectFlows(proto, l4Proto, conn)...)
+			})
+			flows = append(flows, c.sampled.take()...)
+			c.exportFlows(flows)
+
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// observeSampled 处理 sFlow 等采样数据源的数据包
+//
+// 同一链接的采样数据包并不连续 交由按序重组的 decoder 只会产生错误的结果
+// 因此仅按照协议规则归类 并按照采样率放大后计入流统计以及四层指标
+func (c *Controller) observeSampled(snap *poolSnapshot, pkt socket.L4Packet, rate uint32) {
+	_, proto, pool := snap.DecideProto(pkt.SocketTuple())
+	if pool == nil {
+		return
+	}
+
+	n := sampledPayloadLen(pkt)
+	c.sampled.add(proto, pool.L4Proto(), pkt, rate, n)
+	if !c.cfg.Layer4Metrics.Enabled {
+		return
+	}
+	lbs := c.layer4Labels(pkt.SocketTuple())
+	prefix := string(pool.L4Proto())
+	c.metricsStorage.Update(
+		metricstorage.NewCounterConstMetric(prefix+"_sampled_packets_total", float64(rate), lbs),
+		metricstorage.NewCounterConstMetric(prefix+"_sampled_bytes_total", float64(uint64(rate)*uint64(n)), lbs),
+	)
+}
+
+func sampledPayloadLen(pkt socket.L4Packet) int {
+	switch p := pkt.(type) {
+	case *socket.TCPSegment:
+		return p.PayloadLen()
+	case *socket.UDPDatagram:
+		return p.PayloadLen()
+	}
+	return 0
+}
+
+// sampledFlows 采样数据包的流统计 Packets / Bytes 均已按照采样率放大
+//
+// 仅在开启流统计导出时创建 为 nil 时所有方法均为空操作
+type sampledFlows struct {
+	mut   sync.Mutex
+	flows map[socket.Tuple]*common.Flow
+}
+
+func newSampledFlows() *sampledFlows {
+	return &sampledFlows{flows: make(map[socket.Tuple]*common.Flow)}
+}
+
+func (s *sampledFlows) add(proto socket.L7Proto, l4Proto socket.L4Proto, pkt socket.L4Packet, rate uint32, n int) {
+	if s == nil {
+		return
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	st := pkt.SocketTuple()
+	t := pkt.ArrivedTime()
+	flow, ok := s.flows[st]
+	if !ok {
+		flow = &common.Flow{Tuple: st, L4Proto: l4Proto, L7Proto: proto, Start: t, Sampled: true}
+		s.flows[st] = flow
+	}
+	flow.End = t
+	flow.Packets += uint64(rate)
+	flow.Bytes += uint64(rate) * uint64(n)
+}
+
+// take 返回自上次导出以来的流统计并清空
+func (s *sampledFlows) take() []common.Flow {
+	if s == nil {
+		return nil
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	flows := make([]common.Flow, 0, len(s.flows))
+	for st, flow := range s.flows {
+		flows = append(flows, *flow)
+		delete(s.flows, st)
+	}
+	return flows
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/halfopen"
+	"github.com/packetd/packetd/logger"
+	"github.com/packetd/packetd/protocol"
+)
+
+// halfOpenCheckInterval 半开链接检测周期
+const halfOpenCheckInterval = 5 * time.Second
+
+// snapshotAckStates 返回存在未确认数据的链接快照 以及快照中的链接 用于标记受影响的 RoundTrip
+func (c *Controller) snapshotAckStates() ([]halfopen.Conn, map[socket.Tuple]protocol.Conn) {
+	var conns []halfopen.Conn
+	refs := make(map[socket.Tuple]protocol.Conn)
+	c.pps.RangeConns(func(proto socket.L7Proto, st socket.Tuple, conn protocol.Conn) {
+		states := conn.AckStates()
+		if len(states) == 0 {
+			return
+		}
+		refs[st] = conn
+		conns = append(conns, halfopen.Conn{
+			Proto:   proto,
+			Tuple:   st,
+			Closed:  conn.IsClosed(),
+			Pending: conn.Pending(),
+			States:  states,
+		})
+	})
+	return conns, refs
+}
+
+func (c *Controller) detectHalfOpenConn() {
+	detector := halfopen.New(c.cfg.HalfOpen.Options())
+	ticker := time.NewTicker(halfOpenCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			conns, refs := c.snapshotAckStates()
+			events, halfOpen := detector.Detect(now, conns)
+			for _, evt := range events {
+				if conn, ok := refs[evt.Tuple]; ok {
+					conn.MarkHalfOpen(now)
+				}
+				halfOpenConnsDetected.WithLabelValues(string(evt.Proto), string(evt.Reason)).Inc()
+				logger.Warnf("half-open %s connection detected: %s, reason=%s, sender=%s, retransmits=%d, keepalives=%d, unacked=%s, pendingRoundtrips=%d",
+					evt.Proto, c.tupleString(evt.Tuple), evt.Reason, c.tupleString(evt.State.Tuple), evt.State.Retransmits, evt.State.Keepalives, evt.Stalled, evt.Pending)
+			}
+
+			halfOpenConns.Reset()
+			for proto, n := range halfOpen {
+				halfOpenConns.WithLabelValues(string(proto)).Set(float64(n))
+			}
+
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/idleconn"
+	"github.com/packetd/packetd/logger"
+	"github.com/packetd/packetd/protocol"
+)
+
+// snapshotConns 返回当前所有链接的快照
+func (c *Controller) snapshotConns() []idleconn.Conn {
+	var conns []idleconn.Conn
+	c.pps.RangeConns(func(proto socket.L7Proto, st socket.Tuple, conn protocol.Conn) {
+		conns = append(conns, idleconn.Conn{
+			Proto:    proto,
+			Tuple:    st,
+			ActiveAt: conn.ActiveAt(),
+			Closed:   conn.IsClosed(),
+		})
+	})
+	return conns
+}
+
+// idleCheckInterval 检测周期为阈值的 1/4 且限制在 [1s, 30s] 区间
+func idleCheckInterval(threshold time.Duration) time.Duration {
+	interval := threshold / 4
+	if interval < time.Second {
+		return time.Second
+	}
+	if interval > 30*time.Second {
+		return 30 * time.Second
+	}
+	return interval
+}
+
+func (c *Controller) detectIdleConn() {
+	threshold := c.cfg.IdleConn.GetThreshold()
+	if threshold >= c.cfg.GetConnExpired() {
+		logger.Warnf("idleConn threshold (%s) should be less than connExpired (%s)", threshold, c.cfg.GetConnExpired())
+	}
+
+	detector := idleconn.New(threshold)
+	ticker := time.NewTicker(idleCheckInterval(threshold))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			events, idle := detector.Detect(time.Now(), c.snapshotConns())
+			for _, evt := range events {
+				idleConnsDetected.WithLabelValues(string(evt.Proto)).Inc()
+				logger.Warnf("idle %s connection detected: %s, lastActive=%s, idle=%s",
+					evt.Proto, c.tupleString(evt.Tuple), evt.ActiveAt.Format(time.RFC3339), evt.Idle)
+			}
+
+			idleConns.Reset()
+			for proto, n := range idle {
+				idleConns.WithLabelValues(string(proto)).Set(float64(n))
+			}
+
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
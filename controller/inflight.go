@@ -0,0 +1,80 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/labels"
+	"github.com/packetd/packetd/internal/metricstorage"
+	"github.com/packetd/packetd/protocol"
+)
+
+type inFlightKey struct {
+	proto socket.L7Proto
+	ip    socket.IPV
+	port  socket.Port
+}
+
+// snapshotInFlight 按照协议以及服务端汇总已归档请求但尚未配对响应的数量
+//
+// 仍存在链接的服务端即使没有进行中的请求也会返回 0 值 便于观测排队的消退
+func (c *Controller) snapshotInFlight() map[inFlightKey]int {
+	inFlight := make(map[inFlightKey]int)
+	c.pps.RangeConns(func(proto socket.L7Proto, st socket.Tuple, conn protocol.Conn) {
+		key := inFlightKey{proto: proto, ip: st.DstIP, port: st.DstPort}
+		inFlight[key] += conn.Pending()
+	})
+	return inFlight
+}
+
+// loopUpdateInFlight 周期性地将进行中的请求数写入指标存储
+//
+// 排队往往先于单个请求的耗时升高出现 是服务饱和最早的信号
+// 服务端的链接全部过期后 额外写入一次 0 值 避免指标停留在最后一次的取值直至过期
+func (c *Controller) loopUpdateInFlight() {
+	ticker := time.NewTicker(c.cfg.InFlight.GetInterval())
+	defer ticker.Stop()
+
+	var prev map[inFlightKey]int
+	for {
+		select {
+		case <-ticker.C:
+			curr := c.snapshotInFlight()
+			for key := range prev {
+				if _, ok := curr[key]; !ok {
+					c.updateInFlight(key, 0)
+				}
+			}
+			for key, n := range curr {
+				c.updateInFlight(key, n)
+			}
+			prev = curr
+
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Controller) updateInFlight(key inFlightKey, n int) {
+	lbs := labels.Labels{
+		{Name: "server_address", Value: key.ip.String()},
+		{Name: "server_port", Value: strconv.Itoa(int(key.port))},
+	}
+	name := string(key.proto) + "_inflight_requests"
+	c.metricsStorage.Update(metricstorage.NewGaugeConstMetric(name, float64(n), lbs))
+}
@@ -0,0 +1,243 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol/pdns"
+	"github.com/packetd/packetd/protocol/phttp"
+	"github.com/packetd/packetd/protocol/predis"
+)
+
+// headerInjected 注入的 HTTP 请求额外携带该 Header 便于在仅能获取 Header 的下游区分合成数据
+const headerInjected = "X-Packetd-Injected"
+
+var errUnsupportedInjectProto = errors.New("unsupported inject proto")
+
+// injectedRoundTrip 通过管理接口注入的合成 RoundTrip
+//
+// Request / Response 沿用各协议的结构体 下游 Converter 无需感知数据来源
+// 所有协议均通过 socket.IsInjected 标记为合成数据 指标携带 injected="true" 维度 Span 携带 packetd.injected 属性
+type injectedRoundTrip struct {
+	proto    socket.L7Proto
+	request  any
+	response any
+	duration time.Duration
+}
+
+func (rt *injectedRoundTrip) Proto() socket.L7Proto   { return rt.proto }
+func (rt *injectedRoundTrip) Request() any            { return rt.request }
+func (rt *injectedRoundTrip) Response() any           { return rt.response }
+func (rt *injectedRoundTrip) Duration() time.Duration { return rt.duration }
+func (rt *injectedRoundTrip) Validate() bool          { return true }
+func (rt *injectedRoundTrip) Injected() bool          { return true }
+
+// injectParams 注入参数 未指定的字段使用默认值
+type injectParams struct {
+	proto    string
+	client   netip.AddrPort
+	server   netip.AddrPort
+	duration time.Duration
+	status   string
+	method   string
+	path     string
+	name     string
+	qtype    string
+	command  string
+}
+
+var injectDefaultPorts = map[string]uint16{
+	string(socket.L7ProtoHTTP):  80,
+	string(socket.L7ProtoDNS):   53,
+	string(socket.L7ProtoRedis): 6379,
+}
+
+func formValue(r *http.Request, key, def string) string {
+	if v := r.FormValue(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func parseInjectParams(r *http.Request) (*injectParams, error) {
+	p := &injectParams{
+		proto:   formValue(r, "proto", string(socket.L7ProtoHTTP)),
+		status:  r.FormValue("status"),
+		method:  formValue(r, "method", http.MethodGet),
+		path:    formValue(r, "path", "/-/inject"),
+		name:    formValue(r, "name", "packetd.inject."),
+		qtype:   formValue(r, "qtype", "A"),
+		command: formValue(r, "command", "PING"),
+	}
+
+	port, ok := injectDefaultPorts[p.proto]
+	if !ok {
+		return nil, errors.Wrap(errUnsupportedInjectProto, p.proto)
+	}
+
+	var err error
+	if p.client, err = netip.ParseAddrPort(formValue(r, "client", "127.0.0.1:40000")); err != nil {
+		return nil, errors.Wrap(err, "parse client")
+	}
+	if p.server, err = netip.ParseAddrPort(formValue(r, "server", "127.0.0.1:"+strconv.Itoa(int(port)))); err != nil {
+		return nil, errors.Wrap(err, "parse server")
+	}
+	if p.duration, err = time.ParseDuration(formValue(r, "duration", "10ms")); err != nil {
+		return nil, errors.Wrap(err, "parse duration")
+	}
+	if p.duration < 0 {
+		return nil, errNegativeDuration
+	}
+	return p, nil
+}
+
+// newInjectedRoundTrip 按参数构造合成 RoundTrip 响应时间为 end 请求时间为 end - duration
+func newInjectedRoundTrip(p *injectParams, end time.Time) (socket.RoundTrip, error) {
+	start := end.Add(-p.duration)
+	clientHost, clientPort := p.client.Addr().String(), p.client.Port()
+	serverHost, serverPort := p.server.Addr().String(), p.server.Port()
+
+	rt := &injectedRoundTrip{
+		proto:    socket.L7Proto(p.proto),
+		duration: p.duration,
+	}
+	switch rt.proto {
+	case socket.L7ProtoHTTP:
+		code := http.StatusOK
+		if p.status != "" {
+			n, err := strconv.Atoi(p.status)
+			if err != nil || n < 100 || n > 999 {
+				return nil, errors.Errorf("invalid http status (%s)", p.status)
+			}
+			code = n
+		}
+		header := http.Header{}
+		header.Set(headerInjected, "true")
+		rt.request = &phttp.Request{
+			Host:   clientHost,
+			Port:   clientPort,
+			Method: strings.ToUpper(p.method),
+			Header: header,
+			Proto:  "HTTP/1.1",
+			Path:   p.path,
+			URL:    p.path,
+			Scheme: "http",
+			Time:   start,
+		}
+		rt.response = &phttp.Response{
+			Host:          serverHost,
+			Port:          serverPort,
+			Header:        http.Header{},
+			Status:        strconv.Itoa(code) + " " + http.StatusText(code),
+			StatusCode:    code,
+			Proto:         "HTTP/1.1",
+			Time:          end,
+			FirstByteTime: end,
+			HeaderTime:    end,
+		}
+
+	case socket.L7ProtoDNS:
+		status := p.status
+		if status == "" {
+			status = "Success"
+		}
+		question := pdns.Question{Name: p.name, Type: strings.ToUpper(p.qtype)}
+		rt.request = &pdns.Request{
+			Host:  clientHost,
+			Port:  clientPort,
+			Proto: pdns.PROTO,
+			Time:  start,
+			Message: pdns.Message{
+				Header:      pdns.Header{OpCode: "Query", Status: "Success"},
+				QuestionSec: question,
+			},
+		}
+		rt.response = &pdns.Response{
+			Host:  serverHost,
+			Port:  serverPort,
+			Proto: pdns.PROTO,
+			Time:  end,
+			Message: pdns.Message{
+				Header:      pdns.Header{OpCode: "Query", Status: status, Response: true},
+				QuestionSec: question,
+			},
+		}
+
+	case socket.L7ProtoRedis:
+		dataType := predis.SimpleStrings
+		if p.status == "error" {
+			dataType = predis.Errors
+		}
+		rt.request = &predis.Request{
+			Command: strings.ToUpper(p.command),
+			Proto:   predis.PROTO,
+			Host:    clientHost,
+			Port:    clientPort,
+			Time:    start,
+		}
+		rt.response = &predis.Response{
+			DataType: string(dataType),
+			Proto:    predis.PROTO,
+			Host:     serverHost,
+			Port:     serverPort,
+			Time:     end,
+		}
+
+	default:
+		return nil, errors.Wrap(errUnsupportedInjectProto, p.proto)
+	}
+	return rt, nil
+}
+
+// routeInject 注入一条合成 RoundTrip 绕过解码器直接进入聚合 / 导出流程
+//
+// 用于在没有真实流量时验证 Sink 连通性 / relabel 规则 / 看板配置
+func (c *Controller) routeInject(w http.ResponseWriter, r *http.Request) {
+	var rt socket.RoundTrip
+	var b []byte
+	p, err := parseInjectParams(r)
+	if err == nil {
+		rt, err = newInjectedRoundTrip(p, time.Now())
+	}
+	if err == nil {
+		// 入队后 rt 会被脱敏等流程原地修改 需提前序列化
+		b, err = socket.JSONMarshalRoundTrip(rt)
+	}
+	if err == nil {
+		select {
+		case c.rtCh <- rt:
+		case <-r.Context().Done():
+			err = r.Context().Err()
+		case <-c.ctx.Done():
+			err = c.ctx.Err()
+		}
+	}
+	c.RecordAudit(requestActor(r), auditActionInject, "proto="+r.FormValue("proto"), err)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
@@ -56,6 +56,60 @@ var (
 		[]string{"iface"},
 	)
 
+	snifferDuplicatedPackets = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: common.App,
+			Name:      "sniffer_duplicated_packets_total",
+			Help:      "Sniffer duplicated packets total",
+		},
+		[]string{"iface"},
+	)
+
+	workerHandledPackets = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: common.App,
+			Name:      "worker_handled_packets_total",
+			Help:      "Worker handled packets total",
+		},
+		[]string{"worker"},
+	)
+
+	workerHandledBatches = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: common.App,
+			Name:      "worker_handled_batches_total",
+			Help:      "Worker handled batches total",
+		},
+		[]string{"worker"},
+	)
+
+	workerBusySeconds = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: common.App,
+			Name:      "worker_busy_seconds_total",
+			Help:      "Worker busy seconds total",
+		},
+		[]string{"worker"},
+	)
+
+	workerQueuedPackets = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: common.App,
+			Name:      "worker_queued_packets",
+			Help:      "Worker queued packets",
+		},
+		[]string{"worker"},
+	)
+
+	workerShedPackets = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: common.App,
+			Name:      "worker_shed_packets_total",
+			Help:      "Worker shed packets total",
+		},
+		[]string{"proto"},
+	)
+
 	handledRoundtrips = promauto.NewCounter(
 		prometheus.CounterOpts{
 			Namespace: common.App,
@@ -63,4 +117,65 @@ var (
 			Help:      "Handled roundtrips total",
 		},
 	)
+
+	degradedRoundtrips = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: common.App,
+			Name:      "degraded_roundtrips_total",
+			Help:      "Roundtrips observed while the capture was dropping packets total",
+		},
+	)
+
+	idleConnsDetected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: common.App,
+			Name:      "idle_conns_detected_total",
+			Help:      "Idle connections detected total",
+		},
+		[]string{"proto"},
+	)
+
+	idleConns = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: common.App,
+			Name:      "idle_conns",
+			Help:      "Idle connections currently tracked",
+		},
+		[]string{"proto"},
+	)
+
+	halfOpenConnsDetected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: common.App,
+			Name:      "half_open_conns_detected_total",
+			Help:      "Half-open connections detected total",
+		},
+		[]string{"proto", "reason"},
+	)
+
+	halfOpenConns = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: common.App,
+			Name:      "half_open_conns",
+			Help:      "Half-open connections currently tracked",
+		},
+		[]string{"proto"},
+	)
+
+	halfOpenRoundtrips = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: common.App,
+			Name:      "half_open_roundtrips_total",
+			Help:      "Roundtrips affected by half-open connections total",
+		},
+		[]string{"proto"},
+	)
+
+	captureFullMode = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: common.App,
+			Name:      "capture_full_mode",
+			Help:      "Whether full capture mode is active",
+		},
+	)
 )
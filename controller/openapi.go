@@ -0,0 +1,32 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol/phttp"
+	"github.com/packetd/packetd/protocol/phttp2"
+)
+
+// matchOperation 为 HTTP 以及 HTTP/2 请求填充 OpenAPI 描述中匹配的接口
+//
+// 在 pipeline 之前执行 roundtrips / metrics / traces 因此使用同一份匹配结果
+func (c *Controller) matchOperation(rt socket.RoundTrip) {
+	switch req := rt.Request().(type) {
+	case *phttp.Request:
+		req.Operation = c.openapi.Match(req.Method, req.Path)
+	case *phttp2.Request:
+		req.Operation = c.openapi.Match(req.Method, req.Path)
+	}
+}
@@ -14,6 +14,8 @@
 package controller
 
 import (
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/go-multierror"
@@ -26,9 +28,19 @@ import (
 // portPools 记录了端口与协议池的映射关系
 //
 // 可通过 Reload 重新加载配置 pps 会对比新旧配置进行更新
+//
+// 映射关系以只读快照的形式保存 读取方无需加锁 Reload 与 Recreate 之间通过 mut 串行化
+// 被替换的连接池会等待持有旧快照的 worker 处理完当前批次后再释放
 type portPools struct {
+	mut  sync.Mutex
+	snap atomic.Pointer[poolSnapshot]
+}
+
+// poolSnapshot 端口与协议池映射关系的只读快照
+type poolSnapshot struct {
 	ports map[socket.Port]socket.L7Proto
 	pools map[socket.L7Proto]protocol.ConnPool
+	refs  atomic.Int64
 }
 
 func newPortPools(l7ports []socket.L7Ports, decoderConfig DecoderConfig) (*portPools, error) {
@@ -48,13 +60,54 @@ func newPortPools(l7ports []socket.L7Ports, decoderConfig DecoderConfig) (*portP
 		}
 	}
 
-	return &portPools{
+	pps := &portPools{}
+	pps.snap.Store(&poolSnapshot{
 		ports: ports,
 		pools: pools,
-	}, nil
+	})
+	return pps, nil
+}
+
+// Acquire 获取当前快照 使用完毕后需调用 Release
+//
+// 持有快照期间其中的连接池不会被释放
+func (pps *portPools) Acquire() *poolSnapshot {
+	for {
+		s := pps.snap.Load()
+		s.refs.Add(1)
+		if pps.snap.Load() == s {
+			return s
+		}
+		s.refs.Add(-1)
+	}
+}
+
+// Release 释放 Acquire 获取的快照
+func (pps *portPools) Release(s *poolSnapshot) {
+	s.refs.Add(-1)
+}
+
+// swap 替换快照 待旧快照不再被持有后释放 retired 连接池
+//
+// 调用方需持有 mut
+func (pps *portPools) swap(s *poolSnapshot, retired []protocol.ConnPool) {
+	prev := pps.snap.Swap(s)
+	if len(retired) == 0 {
+		return
+	}
+	for prev.refs.Load() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for _, pool := range retired {
+		pool.Clean()
+	}
 }
 
 func (pps *portPools) Reload(l7ports []socket.L7Ports, decoderConfig DecoderConfig) error {
+	pps.mut.Lock()
+	defer pps.mut.Unlock()
+
+	cur := pps.snap.Load()
 	newPorts := make(map[socket.Port]socket.L7Proto)
 	newProto := make(map[socket.L7Proto]struct{})
 
@@ -70,7 +123,7 @@ func (pps *portPools) Reload(l7ports []socket.L7Ports, decoderConfig DecoderConf
 	added := make(map[socket.L7Proto]struct{})
 	newPools := make(map[socket.L7Proto]protocol.ConnPool)
 	for p := range newProto {
-		if conn, ok := pps.pools[p]; !ok {
+		if conn, ok := cur.pools[p]; !ok {
 			added[p] = struct{}{}
 		} else {
 			newPools[p] = conn
@@ -78,10 +131,10 @@ func (pps *portPools) Reload(l7ports []socket.L7Ports, decoderConfig DecoderConf
 	}
 
 	// 新配置中不存在的 protocol 标记为删除
-	deleted := make(map[socket.L7Proto]struct{})
-	for p := range pps.pools {
+	var deleted []protocol.ConnPool
+	for p, pool := range cur.pools {
 		if _, ok := newProto[p]; !ok {
-			deleted[p] = struct{}{}
+			deleted = append(deleted, pool)
 		}
 	}
 
@@ -95,37 +148,104 @@ func (pps *portPools) Reload(l7ports []socket.L7Ports, decoderConfig DecoderConf
 		newPools[p] = f(decoderConfig.Get(string(p)))
 	}
 
-	prev := pps.pools
-	pps.pools = newPools
-	pps.ports = newPorts
+	pps.swap(&poolSnapshot{ports: newPorts, pools: newPools}, deleted)
+	return errs
+}
+
+// Recreate 使用新的 decoder 配置重建指定协议的连接池 未监听的协议忽略
+//
+// 旧连接池中的链接随之释放 后续数据包会在新连接池中重新建立链接
+func (pps *portPools) Recreate(protos []socket.L7Proto, decoderConfig DecoderConfig) error {
+	pps.mut.Lock()
+	defer pps.mut.Unlock()
 
-	for p := range deleted {
-		prev[p].Clean()
+	cur := pps.snap.Load()
+	newPools := make(map[socket.L7Proto]protocol.ConnPool, len(cur.pools))
+	for k, v := range cur.pools {
+		newPools[k] = v
+	}
+
+	var errs error
+	var retired []protocol.ConnPool
+	for _, p := range protos {
+		prev, ok := cur.pools[p]
+		if !ok {
+			continue
+		}
+
+		f, err := protocol.Get(p)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		newPools[p] = f(decoderConfig.Get(string(p)))
+		retired = append(retired, prev)
+	}
+
+	if len(retired) > 0 {
+		pps.swap(&poolSnapshot{ports: cur.ports, pools: newPools}, retired)
 	}
 	return errs
 }
 
-func (pps *portPools) DecideProto(st socket.Tuple) (socket.Port, protocol.ConnPool) {
-	if p, ok := pps.ports[st.SrcPort]; ok {
-		return st.SrcPort, pps.pools[p]
+// DecideProto 返回链接的服务端端口以及所属协议和连接池
+func (s *poolSnapshot) DecideProto(st socket.Tuple) (socket.Port, socket.L7Proto, protocol.ConnPool) {
+	if p, ok := s.ports[st.SrcPort]; ok {
+		return st.SrcPort, p, s.pools[p]
+	}
+	if p, ok := s.ports[st.DstPort]; ok {
+		return st.DstPort, p, s.pools[p]
 	}
-	if p, ok := pps.ports[st.DstPort]; ok {
-		return st.DstPort, pps.pools[p]
+	return 0, "", nil
+}
+
+// IsServerPort 判断 port 是否命中协议规则 即服务端端口
+func (pps *portPools) IsServerPort(port socket.Port) bool {
+	_, ok := pps.snap.Load().ports[port]
+	return ok
+}
+
+// Proto 返回链接所属的协议 未匹配时返回空值
+func (pps *portPools) Proto(st socket.Tuple) socket.L7Proto {
+	s := pps.snap.Load()
+	if p, ok := s.ports[st.SrcPort]; ok {
+		return p
 	}
-	return 0, nil
+	return s.ports[st.DstPort]
 }
 
 func (pps *portPools) RangePoolStats(f func(stats connstream.TupleStats)) {
-	for _, pool := range pps.pools {
+	s := pps.Acquire()
+	defer pps.Release(s)
+
+	for _, pool := range s.pools {
 		pool.OnStats(func(stats connstream.TupleStats) {
 			f(stats)
 		})
 	}
 }
 
+// RangeConns 遍历所有协议的链接 st 统一转换为客户端至服务端方向
+func (pps *portPools) RangeConns(f func(proto socket.L7Proto, st socket.Tuple, conn protocol.Conn)) {
+	s := pps.Acquire()
+	defer pps.Release(s)
+
+	for proto, pool := range s.pools {
+		pool.RangeConns(func(st socket.Tuple, conn protocol.Conn) {
+			if _, ok := s.ports[st.SrcPort]; ok {
+				st = st.Mirror()
+			}
+			f(proto, st, conn)
+		})
+	}
+}
+
 func (pps *portPools) RemoveExpired(duration time.Duration) map[socket.L4Proto]int {
+	s := pps.Acquire()
+	defer pps.Release(s)
+
 	stats := make(map[socket.L4Proto]int)
-	for _, pool := range pps.pools {
+	for _, pool := range s.pools {
 		n := pool.RemoveExpired(duration)
 		stats[pool.L4Proto()] = n
 	}
@@ -133,8 +253,11 @@ func (pps *portPools) RemoveExpired(duration time.Duration) map[socket.L4Proto]i
 }
 
 func (pps *portPools) ActivePoolConns() map[socket.L4Proto]int {
+	s := pps.Acquire()
+	defer pps.Release(s)
+
 	stats := make(map[socket.L4Proto]int)
-	for _, pool := range pps.pools {
+	for _, pool := range s.pools {
 		stats[pool.L4Proto()] = pool.ActiveConns()
 	}
 	return stats
@@ -0,0 +1,31 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package controller
+
+import (
+	"github.com/packetd/packetd/internal/prober"
+	"github.com/packetd/packetd/logger"
+)
+
+// exportProbeReport 探测结果以 roundtrip 的形式输出 指标由 roundtripstometrics 生成
+//
+// 探测并非抓包所得 不经过 captureQuality 等针对抓包数据的处理
+func (c *Controller) exportProbeReport(r prober.Report) {
+	if !r.Success {
+		logger.Warnf("probe (%s) %s failed, verdict=%s: %s", r.Target.Name, r.Target.Address, r.Verdict, r.Error)
+	}
+
+	rt := prober.NewRoundTrip(r)
+	c.anon.RoundTrip(rt)
+	c.exportRoundTrip(rt)
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/recenterrors"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/pamqp"
+	"github.com/packetd/packetd/protocol/pdns"
+	"github.com/packetd/packetd/protocol/pgrpc"
+	"github.com/packetd/packetd/protocol/phttp"
+	"github.com/packetd/packetd/protocol/phttp2"
+	"github.com/packetd/packetd/protocol/pkafka"
+	"github.com/packetd/packetd/protocol/pmongodb"
+	"github.com/packetd/packetd/protocol/pmysql"
+	"github.com/packetd/packetd/protocol/ppostgresql"
+	"github.com/packetd/packetd/protocol/predis"
+)
+
+// classifyError 判断 RoundTrip 是否为错误 并返回对应的错误码
+//
+// HTTP 仅将 5xx 视为错误 4xx 通常由客户端引起 保留下来反而会淹没服务端故障
+func classifyError(rt socket.RoundTrip) (string, bool) {
+	switch rsp := rt.Response().(type) {
+	case *phttp.Response:
+		if code, ok := rpcErrorCode(rt.Request()); ok {
+			return code, true
+		}
+		return strconv.Itoa(rsp.StatusCode), rsp.StatusCode >= http.StatusInternalServerError
+
+	case *phttp2.Response:
+		return rsp.Status, len(rsp.Status) == 3 && rsp.Status[0] == '5'
+
+	case *pgrpc.Response:
+		if rsp != nil {
+			return rsp.Status, rsp.Status != "" && rsp.Status != "0"
+		}
+
+	case *pmysql.Response:
+		if p, ok := rsp.Packet.(*pmysql.ErrorPacket); ok {
+			return strconv.Itoa(p.ErrCode), true
+		}
+
+	case *ppostgresql.Response:
+		if p, ok := rsp.Packet.(*ppostgresql.ErrorPacket); ok {
+			return p.SQLStateCode, true
+		}
+
+	case *pkafka.Response:
+		if rsp != nil && rsp.ErrorCode != "" && rsp.ErrorCode != "NoError" {
+			return rsp.ErrorCode, true
+		}
+
+	case *pmongodb.Response:
+		if rsp.Code != 0 {
+			return strconv.Itoa(int(rsp.Code)), true
+		}
+
+	case *predis.Response:
+		if rsp != nil && rsp.DataType == string(predis.Errors) {
+			return rsp.DataType, true
+		}
+
+	case *pdns.Response:
+		status := rsp.Message.Header.Status
+		return status, status != "" && status != "Success"
+
+	case *pamqp.Response:
+		if rsp != nil {
+			return rsp.ErrCode, rsp.ErrCode != "" && rsp.ErrCode != "OK"
+		}
+	}
+	return "", false
+}
+
+// rpcErrorCode 返回 HTTP 承载的 RPC 调用失败时的错误码 此类调用的 HTTP 状态码可能为 200
+//
+// JSON-RPC 使用响应体中的 error.code Twirp / Connect 直接使用 Outcome
+func rpcErrorCode(req any) (string, bool) {
+	r, ok := req.(*phttp.Request)
+	if !ok || r.RPC == nil {
+		return "", false
+	}
+
+	switch r.RPC.Outcome {
+	case "", protocol.RPCOutcomeOK, protocol.RPCOutcomeUnknown:
+		return "", false
+	case protocol.RPCOutcomeError:
+		return strconv.Itoa(r.RPC.ErrorCode), true
+	}
+	return r.RPC.Outcome, true
+}
+
+// recordError 将错误 RoundTrip 写入最近错误缓存
+func (c *Controller) recordError(rt socket.RoundTrip) {
+	code, ok := classifyError(rt)
+	if !ok {
+		return
+	}
+
+	b, err := socket.JSONMarshalRoundTrip(rt)
+	if err != nil {
+		return
+	}
+	c.recentErrors.Add(string(rt.Proto()), recenterrors.Entry{
+		Time:      time.Now(),
+		Code:      code,
+		RoundTrip: b,
+	})
+}
+
+func (c *Controller) routeErrors(w http.ResponseWriter, r *http.Request) {
+	if c.recentErrors == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("recentErrors disabled"))
+		return
+	}
+
+	var limit int
+	limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+
+	protos := c.recentErrors.Protos()
+	if proto := r.URL.Query().Get("proto"); proto != "" {
+		protos = []string{proto}
+	}
+
+	ret := make(map[string][]recenterrors.Entry)
+	for _, proto := range protos {
+		entries := c.recentErrors.List(proto, limit)
+		if entries == nil {
+			entries = []recenterrors.Entry{}
+		}
+		ret[proto] = entries
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ret)
+}
@@ -14,10 +14,18 @@
 package controller
 
 import (
+	_ "github.com/packetd/packetd/exporter/sinker/events"
+	_ "github.com/packetd/packetd/exporter/sinker/flows"
 	_ "github.com/packetd/packetd/exporter/sinker/metrics"
 	_ "github.com/packetd/packetd/exporter/sinker/roundtrips"
 	_ "github.com/packetd/packetd/exporter/sinker/traces"
+	_ "github.com/packetd/packetd/processor/roundtripstoclientmetrics"
+	_ "github.com/packetd/packetd/processor/roundtripstodnsfailures"
+	_ "github.com/packetd/packetd/processor/roundtripstodnsrollups"
+	_ "github.com/packetd/packetd/processor/roundtripstoerrorcodes"
 	_ "github.com/packetd/packetd/processor/roundtripstometrics"
+	_ "github.com/packetd/packetd/processor/roundtripstoredistopology"
+	_ "github.com/packetd/packetd/processor/roundtripstotlshandshakes"
 	_ "github.com/packetd/packetd/processor/roundtripstotraces"
 	_ "github.com/packetd/packetd/protocol/pamqp"
 	_ "github.com/packetd/packetd/protocol/pdns"
@@ -27,7 +35,11 @@ import (
 	_ "github.com/packetd/packetd/protocol/pkafka"
 	_ "github.com/packetd/packetd/protocol/pmongodb"
 	_ "github.com/packetd/packetd/protocol/pmysql"
+	_ "github.com/packetd/packetd/protocol/pntp"
 	_ "github.com/packetd/packetd/protocol/ppostgresql"
 	_ "github.com/packetd/packetd/protocol/predis"
+	_ "github.com/packetd/packetd/protocol/ptls"
+	_ "github.com/packetd/packetd/protocol/pudprpc"
 	_ "github.com/packetd/packetd/sniffer/libpcap"
+	_ "github.com/packetd/packetd/sniffer/sflow"
 )
@@ -14,14 +14,19 @@
 package controller
 
 import (
+	"encoding/json"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/packetd/packetd/connstream"
-	"github.com/packetd/packetd/internal/sigs"
+	"github.com/packetd/packetd/internal/idleconn"
+	"github.com/packetd/packetd/internal/profiler"
 	"github.com/packetd/packetd/logger"
 )
 
@@ -33,9 +38,16 @@ func (c *Controller) setupServer() {
 	// Admin Routes
 	c.svr.RegisterPostRoute("/-/logger", c.routeLogger)
 	c.svr.RegisterPostRoute("/-/reload", c.recordReload)
+	c.svr.RegisterPostRoute("/-/capture", c.routeTriggerCapture)
+	c.svr.RegisterPostRoute("/-/profile", c.routeProfile)
+	c.svr.RegisterPostRoute("/-/inject", c.routeInject)
 
 	// Watch Routes
 	c.svr.RegisterGetRoute("/watch", c.routeWatch)
+	c.svr.RegisterGetRoute("/connections", c.routeConnections)
+	c.svr.RegisterGetRoute("/capture", c.routeCapture)
+	c.svr.RegisterGetRoute("/capabilities", c.routeCapabilities)
+	c.svr.RegisterGetRoute("/errors", c.routeErrors)
 
 	// Metrics Routes
 	c.svr.RegisterGetRoute("/metrics", c.routeMetrics)
@@ -52,23 +64,65 @@ func (c *Controller) routeProtoMetrics(w http.ResponseWriter, r *http.Request) {
 		c.updatePoolStats(stats)
 	})
 	c.updateActivePoolConns(c.pps.ActivePoolConns())
+
+	// exemplar 仅能通过 OpenMetrics 格式输出 未启用时保持原有格式
+	if c.metricsStorage.ExemplarsEnabled() && acceptOpenMetrics(r) {
+		w.Header().Set("Content-Type", openMetricsContentType)
+		c.metricsStorage.WriteOpenMetrics(w)
+		return
+	}
 	c.metricsStorage.WritePrometheus(w)
 }
 
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+func acceptOpenMetrics(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+}
+
 func (c *Controller) routeLogger(w http.ResponseWriter, r *http.Request) {
 	level := r.FormValue("level")
 	logger.SetLoggerLevel(level)
+	c.RecordAudit(requestActor(r), auditActionLoggerLevel, "level="+level, nil)
 	w.Write([]byte(`{"status": "success"}`))
 }
 
 func (c *Controller) recordReload(w http.ResponseWriter, r *http.Request) {
-	if err := sigs.SelfReload(); err != nil {
+	actor := requestActor(r)
+	err := c.requestReload(actor)
+	c.RecordAudit(actor, auditActionReloadRequest, "", err)
+	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(err.Error()))
 		return
 	}
 }
 
+// routeProfile 按需采集 CPU profile（type=cpu）或执行轨迹（type=trace）持续 duration 后写入 controller.profile.dir
+func (c *Controller) routeProfile(w http.ResponseWriter, r *http.Request) {
+	kind := r.FormValue("type")
+	duration := r.FormValue("duration")
+
+	var capture *profiler.Capture
+	d, err := time.ParseDuration(duration)
+	if err == nil {
+		capture, err = c.profiler.Start(kind, d)
+	}
+	c.RecordAudit(requestActor(r), auditActionProfile, "type="+kind+" duration="+duration, err)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, profiler.ErrBusy) {
+			status = http.StatusConflict
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(capture)
+}
+
 func (c *Controller) routeWatch(w http.ResponseWriter, r *http.Request) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -101,3 +155,55 @@ func (c *Controller) routeWatch(w http.ResponseWriter, r *http.Request) {
 		flusher.Flush()
 	}
 }
+
+// routeConnections 返回当前链接及其最后活跃时间 按照空闲时长降序排列
+//
+// - idle: 仅返回空闲时长不小于该值的链接
+// - limit: 最多返回的链接数量 默认为 100
+func (c *Controller) routeConnections(w http.ResponseWriter, r *http.Request) {
+	var minIdle time.Duration
+	minIdle, _ = time.ParseDuration(r.URL.Query().Get("idle"))
+
+	var limit int
+	limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 100
+	}
+
+	type connection struct {
+		Proto      string
+		Client     string
+		Server     string
+		LastActive time.Time
+		Idle       string
+		Closed     bool
+	}
+
+	now := time.Now()
+	conns := c.snapshotConns()
+	idleconn.SortByIdle(conns)
+
+	lst := make([]connection, 0)
+	for _, conn := range conns {
+		if len(lst) >= limit {
+			break
+		}
+		idle := conn.IdleFor(now)
+		if idle < minIdle {
+			break
+		}
+		// RangeConns 已将四元组统一为客户端至服务端方向
+		st := conn.Tuple
+		lst = append(lst, connection{
+			Proto:      string(conn.Proto),
+			Client:     net.JoinHostPort(c.anon.IP(st.SrcIP.String()), strconv.Itoa(int(st.SrcPort))),
+			Server:     net.JoinHostPort(st.DstIP.String(), strconv.Itoa(int(st.DstPort))),
+			LastActive: conn.ActiveAt,
+			Idle:       idle.String(),
+			Closed:     conn.Closed,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lst)
+}
@@ -0,0 +1,63 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"net"
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+// tcpStampExpired 链接在该时长内再无数据段到达时清理其 TSval 记录
+const tcpStampExpired = time.Minute
+
+// markTCPTimestamp 为 RoundTrip 附加请求首个数据段的 TSval
+//
+// 需在匿名化之前调用 否则无法还原链接四元组
+func (c *Controller) markTCPTimestamp(rt socket.RoundTrip) socket.RoundTrip {
+	req, ok := socket.PeerOf(rt.Request())
+	if !ok {
+		return rt
+	}
+	rsp, ok := socket.PeerOf(rt.Response())
+	if !ok {
+		return rt
+	}
+	src, dst := toIPV(req.Host), toIPV(rsp.Host)
+	if src == nil || dst == nil {
+		return rt
+	}
+
+	st := socket.Tuple{SrcIP: *src, SrcPort: socket.Port(req.Port), DstIP: *dst, DstPort: socket.Port(rsp.Port)}
+	tsval, ok := c.stamps.Lookup(st, req.Time)
+	if !ok {
+		return rt
+	}
+	return socket.WithTCPTimestamp(rt, tsval)
+}
+
+func toIPV(host string) *socket.IPV {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil
+	}
+	var ipv socket.IPV
+	if v4 := ip.To4(); v4 != nil {
+		ipv = socket.ToIPV4(v4)
+	} else {
+		ipv = socket.ToIPV6(ip)
+	}
+	return &ipv
+}
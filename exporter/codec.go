@@ -0,0 +1,147 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"slices"
+	"sync/atomic"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// Compression HTTP 请求体的压缩算法
+type Compression string
+
+const (
+	CompressionNone   Compression = "none"
+	CompressionGzip   Compression = "gzip"
+	CompressionZstd   Compression = "zstd"
+	CompressionSnappy Compression = "snappy"
+)
+
+func (c Compression) validate() error {
+	switch c {
+	case CompressionNone, CompressionGzip, CompressionZstd, CompressionSnappy:
+		return nil
+	}
+	return errors.Errorf("unsupported compression '%s'", c)
+}
+
+// ContentEncoding 返回 Content-Encoding 请求头 不压缩时为空
+func (c Compression) ContentEncoding() string {
+	if c == CompressionNone {
+		return ""
+	}
+	return string(c)
+}
+
+// zstdEncoder EncodeAll 允许并发调用 全局共享即可
+var zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+
+// Compress 按照压缩算法压缩 b
+func (c Compression) Compress(b []byte) ([]byte, error) {
+	switch c {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(b); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+
+	case CompressionZstd:
+		return zstdEncoder.EncodeAll(b, make([]byte, 0, len(b)/4)), nil
+
+	case CompressionSnappy:
+		return snappy.Encode(nil, b), nil
+	}
+	return b, nil
+}
+
+// Encoding 批量数据的序列化格式
+type Encoding string
+
+const (
+	EncodingProtobuf Encoding = "protobuf"
+	EncodingJSON     Encoding = "json"
+)
+
+func (e Encoding) validate() error {
+	switch e {
+	case EncodingProtobuf, EncodingJSON:
+		return nil
+	}
+	return errors.Errorf("unsupported encoding '%s'", e)
+}
+
+// ContentType 返回 Content-Type 请求头
+func (e Encoding) ContentType() string {
+	if e == EncodingJSON {
+		return "application/json"
+	}
+	return "application/x-protobuf"
+}
+
+// Codec 单次请求使用的序列化格式以及压缩算法
+type Codec struct {
+	Encoding    Encoding
+	Compression Compression
+}
+
+// Negotiator 按照优先级依次使用 Codec
+//
+// 服务端以 415 Unsupported Media Type 拒绝当前 Codec 时降级至下一个 Codec 降级后不再恢复
+// 首个 Codec 为用户配置 最后一个 Codec 应为服务端必然支持的格式
+type Negotiator struct {
+	codecs []Codec
+	idx    atomic.Int32
+}
+
+// NewNegotiator 创建 Negotiator 实例 重复的 Codec 会被忽略
+func NewNegotiator(codecs ...Codec) *Negotiator {
+	var lst []Codec
+	for _, c := range codecs {
+		if !slices.Contains(lst, c) {
+			lst = append(lst, c)
+		}
+	}
+	return &Negotiator{codecs: lst}
+}
+
+// Codec 返回当前生效的 Codec
+func (n *Negotiator) Codec() Codec {
+	return n.codecs[n.idx.Load()]
+}
+
+// Fallback 将被拒绝的 Codec 降级至下一个 返回是否还有可用的 Codec
+//
+// 并发请求可能同时被拒绝 仅 rejected 仍为当前 Codec 时才会降级 避免跳过中间的 Codec
+func (n *Negotiator) Fallback(rejected Codec) bool {
+	i := n.idx.Load()
+	if n.codecs[i] != rejected {
+		return true
+	}
+	if int(i)+1 >= len(n.codecs) {
+		return false
+	}
+	n.idx.CompareAndSwap(i, i+1)
+	return true
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression(t *testing.T) {
+	payload := bytes.Repeat([]byte(`{"traceId":"5b8efff798038103d269b633813fc60c"}`), 100)
+
+	decompress := map[Compression]func([]byte) ([]byte, error){
+		CompressionNone: func(b []byte) ([]byte, error) { return b, nil },
+		CompressionGzip: func(b []byte) ([]byte, error) {
+			r, err := gzip.NewReader(bytes.NewReader(b))
+			if err != nil {
+				return nil, err
+			}
+			return io.ReadAll(r)
+		},
+		CompressionZstd: func(b []byte) ([]byte, error) {
+			d, err := zstd.NewReader(nil)
+			if err != nil {
+				return nil, err
+			}
+			defer d.Close()
+			return d.DecodeAll(b, nil)
+		},
+		CompressionSnappy: func(b []byte) ([]byte, error) { return snappy.Decode(nil, b) },
+	}
+
+	for c, f := range decompress {
+		t.Run(string(c), func(t *testing.T) {
+			b, err := c.Compress(payload)
+			require.NoError(t, err)
+			if c != CompressionNone {
+				assert.Less(t, len(b), len(payload)/10)
+			}
+
+			got, err := f(b)
+			require.NoError(t, err)
+			assert.Equal(t, payload, got)
+		})
+	}
+
+	assert.Equal(t, "", CompressionNone.ContentEncoding())
+	assert.Equal(t, "zstd", CompressionZstd.ContentEncoding())
+	assert.Error(t, Compression("lz4").validate())
+	assert.Error(t, Encoding("xml").validate())
+}
+
+func TestNegotiator(t *testing.T) {
+	tc := TracesConfig{Encoding: "json", Compression: "zstd"}
+	neg := NewNegotiator(tc.Codecs()...)
+
+	first := neg.Codec()
+	assert.Equal(t, Codec{Encoding: EncodingJSON, Compression: CompressionZstd}, first)
+
+	assert.True(t, neg.Fallback(first))
+	assert.Equal(t, Codec{Encoding: EncodingJSON, Compression: CompressionNone}, neg.Codec())
+
+	// 并发请求重复拒绝同一 Codec 时不会跳过中间的 Codec
+	assert.True(t, neg.Fallback(first))
+	assert.Equal(t, Codec{Encoding: EncodingJSON, Compression: CompressionNone}, neg.Codec())
+
+	assert.True(t, neg.Fallback(neg.Codec()))
+	assert.Equal(t, Codec{Encoding: EncodingProtobuf, Compression: CompressionNone}, neg.Codec())
+	assert.False(t, neg.Fallback(neg.Codec()))
+
+	// 默认配置仅有一个 Codec
+	mc := MetricsConfig{}
+	require.NoError(t, mc.Validate())
+	neg = NewNegotiator(mc.Codecs()...)
+	assert.Equal(t, CompressionSnappy, neg.Codec().Compression)
+	assert.False(t, neg.Fallback(neg.Codec()))
+}
@@ -14,16 +14,145 @@
 package exporter
 
 import (
+	"net"
 	"net/url"
+	"os"
 	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/internal/relay"
 )
 
-const defaultTimeout = 15 * time.Second
+const (
+	defaultTimeout        = 15 * time.Second
+	defaultQueueSize      = 4096
+	defaultMaxAttempts    = 3
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 30 * time.Second
+)
 
 type Config struct {
 	Traces     TracesConfig     `config:"traces"`
 	Metrics    MetricsConfig    `config:"metrics"`
 	RoundTrips RoundTripsConfig `config:"roundtrips"`
+	Flows      FlowsConfig      `config:"flows"`
+	Events     EventsConfig     `config:"events"`
+
+	// Collector 将指标以及 Span 转发至 collector 由其统一去重聚合后写入最终存储
+	Collector CollectorConfig `config:"collector"`
+
+	// Sinks 额外的输出目标 与 Traces / RoundTrips 并行输出 互不阻塞
+	Sinks []SinkConfig `config:"sinks"`
+
+	// Routes 路由规则 按照声明顺序匹配 命中的数据仅发送至规则指定的输出目标
+	Routes []RouteConfig `config:"routes"`
+}
+
+// RouteConfig 单条路由规则
+//
+// Filter 语法同 TracesConfig.Filter 未命中任何规则的数据发送至未被任何规则引用的输出目标
+type RouteConfig struct {
+	Name   string   `config:"name"`
+	Filter string   `config:"filter"`
+	Sinks  []string `config:"sinks"`
+}
+
+// SinkConfig 单个输出目标配置
+//
+// 每个输出目标拥有独立的队列 批量 重试以及过滤策略 慢速的目标仅会导致自身队列溢出丢弃数据
+type SinkConfig struct {
+	Name       string           `config:"name"`
+	Type       string           `config:"type"` // 支持 traces / roundtrips
+	Filter     string           `config:"filter"`
+	Queue      QueueConfig      `config:"queue"`
+	Retry      RetryConfig      `config:"retry"`
+	Traces     TracesConfig     `config:"traces"`
+	RoundTrips RoundTripsConfig `config:"roundtrips"`
+}
+
+func (sc *SinkConfig) Validate() error {
+	switch common.RecordType(sc.Type) {
+	case common.RecordTraces, common.RecordRoundTrips:
+	default:
+		return errors.Errorf("sink (%s) got unsupported type '%s'", sc.Name, sc.Type)
+	}
+	if sc.Name == "" {
+		sc.Name = sc.Type
+	}
+
+	sc.Queue.Validate()
+	sc.Retry.Validate()
+	return nil
+}
+
+type QueueConfig struct {
+	// Size 队列长度 队列满时丢弃新数据
+	Size int `config:"size"`
+}
+
+func (qc *QueueConfig) Validate() {
+	if qc.Size <= 0 {
+		qc.Size = defaultQueueSize
+	}
+}
+
+type RetryConfig struct {
+	// MaxAttempts 最大尝试次数 包含首次写入
+	MaxAttempts int `config:"maxAttempts"`
+
+	// InitialBackoff 首次重试的等待时间 此后逐次翻倍
+	InitialBackoff time.Duration `config:"initialBackoff"`
+
+	// MaxBackoff 重试等待时间上限
+	MaxBackoff time.Duration `config:"maxBackoff"`
+}
+
+func (rc *RetryConfig) Validate() {
+	if rc.MaxAttempts <= 0 {
+		rc.MaxAttempts = defaultMaxAttempts
+	}
+	if rc.InitialBackoff <= 0 {
+		rc.InitialBackoff = defaultInitialBackoff
+	}
+	if rc.MaxBackoff < rc.InitialBackoff {
+		rc.MaxBackoff = max(defaultMaxBackoff, rc.InitialBackoff)
+	}
+}
+
+// sinkConfigs 返回所有启用的输出目标 exporter.traces / exporter.roundtrips 视为同名的输出目标
+func (c *Config) sinkConfigs() ([]SinkConfig, error) {
+	var sinks []SinkConfig
+	if c.Traces.Enabled {
+		sinks = append(sinks, SinkConfig{
+			Name:   string(common.RecordTraces),
+			Type:   string(common.RecordTraces),
+			Filter: c.Traces.Filter,
+			Traces: c.Traces,
+		})
+	}
+	if c.RoundTrips.Enabled {
+		sinks = append(sinks, SinkConfig{
+			Name:       string(common.RecordRoundTrips),
+			Type:       string(common.RecordRoundTrips),
+			Filter:     c.RoundTrips.Filter,
+			RoundTrips: c.RoundTrips,
+		})
+	}
+	sinks = append(sinks, c.Sinks...)
+
+	names := make(map[string]struct{})
+	for i := range sinks {
+		if err := sinks[i].Validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := names[sinks[i].Name]; ok {
+			return nil, errors.Errorf("duplicated sink name '%s'", sinks[i].Name)
+		}
+		names[sinks[i].Name] = struct{}{}
+	}
+	return sinks, nil
 }
 
 type TracesConfig struct {
@@ -33,6 +162,15 @@ type TracesConfig struct {
 	Header   map[string]string `config:"header"`
 	Interval time.Duration     `config:"interval"`
 	Timeout  time.Duration     `config:"timeout"`
+
+	// Filter 过滤表达式 仅导出命中的数据 为空时导出全部
+	Filter string `config:"filter"`
+
+	// Compression 请求体压缩算法 可选值为 none / gzip / zstd / snappy
+	Compression string `config:"compression"`
+
+	// Encoding 批量数据序列化格式 可选值为 protobuf / json
+	Encoding string `config:"encoding"`
 }
 
 func (tc *TracesConfig) Validate() error {
@@ -41,6 +179,19 @@ func (tc *TracesConfig) Validate() error {
 		return err
 	}
 
+	if tc.Compression == "" {
+		tc.Compression = string(CompressionNone)
+	}
+	if err := Compression(tc.Compression).validate(); err != nil {
+		return err
+	}
+	if tc.Encoding == "" {
+		tc.Encoding = string(EncodingProtobuf)
+	}
+	if err := Encoding(tc.Encoding).validate(); err != nil {
+		return err
+	}
+
 	if tc.Batch <= 0 {
 		tc.Batch = 100
 	}
@@ -53,12 +204,24 @@ func (tc *TracesConfig) Validate() error {
 	return nil
 }
 
+// Codecs 返回按照优先级排列的 Codec 服务端不支持时依次退化为不压缩以及 protobuf 格式
+func (tc *TracesConfig) Codecs() []Codec {
+	return []Codec{
+		{Encoding: Encoding(tc.Encoding), Compression: Compression(tc.Compression)},
+		{Encoding: Encoding(tc.Encoding), Compression: CompressionNone},
+		{Encoding: EncodingProtobuf, Compression: CompressionNone},
+	}
+}
+
 type MetricsConfig struct {
 	Enabled  bool              `config:"enabled"`
 	Endpoint string            `config:"endpoint"`
 	Header   map[string]string `config:"header"`
 	Interval time.Duration     `config:"interval"`
 	Timeout  time.Duration     `config:"timeout"`
+
+	// Compression 请求体压缩算法 RemoteWrite 协议要求服务端支持 snappy 其余算法需服务端额外支持
+	Compression string `config:"compression"`
 }
 
 func (mc *MetricsConfig) Validate() error {
@@ -67,6 +230,13 @@ func (mc *MetricsConfig) Validate() error {
 		return err
 	}
 
+	if mc.Compression == "" {
+		mc.Compression = string(CompressionSnappy)
+	}
+	if err := Compression(mc.Compression).validate(); err != nil {
+		return err
+	}
+
 	if mc.Timeout <= 0 {
 		mc.Timeout = defaultTimeout
 	}
@@ -76,6 +246,14 @@ func (mc *MetricsConfig) Validate() error {
 	return nil
 }
 
+// Codecs 返回按照优先级排列的 Codec 服务端不支持时退化为协议规定的 snappy
+func (mc *MetricsConfig) Codecs() []Codec {
+	return []Codec{
+		{Encoding: EncodingProtobuf, Compression: Compression(mc.Compression)},
+		{Encoding: EncodingProtobuf, Compression: CompressionSnappy},
+	}
+}
+
 type RoundTripsConfig struct {
 	Enabled    bool   `config:"enabled"`
 	Console    bool   `config:"console"`
@@ -83,6 +261,9 @@ type RoundTripsConfig struct {
 	MaxSize    int    `config:"maxSize"`
 	MaxBackups int    `config:"maxBackups"`
 	MaxAge     int    `config:"maxAge"`
+
+	// Filter 过滤表达式 语法同 TracesConfig.Filter
+	Filter string `config:"filter"`
 }
 
 func (rc *RoundTripsConfig) Validate() {
@@ -99,3 +280,156 @@ func (rc *RoundTripsConfig) Validate() {
 		rc.MaxBackups = 10
 	}
 }
+
+// EventsConfig 结构化事件输出配置 输出格式同 roundtrips 为 JSON 行
+type EventsConfig struct {
+	Enabled    bool   `config:"enabled"`
+	Console    bool   `config:"console"`
+	Filename   string `config:"filename"`
+	MaxSize    int    `config:"maxSize"`
+	MaxBackups int    `config:"maxBackups"`
+	MaxAge     int    `config:"maxAge"`
+	QueueSize  int    `config:"queueSize"`
+}
+
+func (ec *EventsConfig) Validate() {
+	if ec.Filename == "" {
+		ec.Filename = "events.log"
+	}
+	if ec.MaxSize <= 0 {
+		ec.MaxSize = 100
+	}
+	if ec.MaxAge <= 0 {
+		ec.MaxAge = 7
+	}
+	if ec.MaxBackups <= 0 {
+		ec.MaxBackups = 10
+	}
+	if ec.QueueSize <= 0 {
+		ec.QueueSize = defaultQueueSize
+	}
+}
+
+// CollectorConfig collector 转发配置
+//
+// 开启后 agent 通常无需再配置 exporter.metrics / exporter.traces 直连存储
+type CollectorConfig struct {
+	Enabled  bool   `config:"enabled"`
+	Endpoint string `config:"endpoint"` // collector 的 gRPC 地址 host:port
+
+	// TLS 与 collector 之间的传输层加密配置
+	TLS relay.TLSConfig `config:"tls"`
+
+	// Token 推送时携带的 token 对应 collector.pushTokens
+	Token string `config:"token"`
+
+	// Agent 当前 agent 的标识 用于 collector 跨 agent 去重 默认为主机名
+	Agent string `config:"agent"`
+
+	Batch     int           `config:"batch"`
+	Interval  time.Duration `config:"interval"`
+	Timeout   time.Duration `config:"timeout"`
+	QueueSize int           `config:"queueSize"`
+
+	// RoundTrips 是否同时转发 roundtrips 供 collector 按照权限范围查询
+	RoundTrips bool `config:"roundTrips"`
+
+	// Labels 附加在转发的 roundtrips 上的标签 如 namespace / service collector 依此限制各 token 的可见范围
+	Labels map[string]string `config:"labels"`
+
+	// LabelRules 按照协议字段为 roundtrips 追加标签 首个命中的规则生效 同名标签覆盖 Labels
+	LabelRules []CollectorLabelRule `config:"labelRules"`
+}
+
+// CollectorLabelRule 单条标签规则 Filter 语法同 TracesConfig.Filter
+type CollectorLabelRule struct {
+	Filter string            `config:"filter"`
+	Labels map[string]string `config:"labels"`
+}
+
+// Validate 未启用时跳过校验 ucfg 解析时会对所有嵌套结构自动调用 Validate
+func (cc *CollectorConfig) Validate() error {
+	if !cc.Enabled {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(cc.Endpoint); err != nil {
+		return errors.Wrapf(err, "invalid collector endpoint '%s'", cc.Endpoint)
+	}
+	if cc.Agent == "" {
+		cc.Agent, _ = os.Hostname()
+	}
+	if cc.Batch <= 0 {
+		cc.Batch = 500
+	}
+	if cc.Interval <= 0 {
+		cc.Interval = 3 * time.Second
+	}
+	if cc.Timeout <= 0 {
+		cc.Timeout = defaultTimeout
+	}
+	if cc.QueueSize <= 0 {
+		cc.QueueSize = defaultQueueSize
+	}
+	return nil
+}
+
+// FlowVersion 流记录的导出格式
+type FlowVersion string
+
+const (
+	FlowVersionIPFIX    FlowVersion = "ipfix"
+	FlowVersionNetFlow9 FlowVersion = "netflow9"
+)
+
+// DefaultFlowsEnterpriseID 未配置企业号时使用 RFC 5612 保留用于文档示例的企业号
+const DefaultFlowsEnterpriseID = 32473
+
+type FlowsConfig struct {
+	Enabled  bool   `config:"enabled"`
+	Endpoint string `config:"endpoint"` // collector 的 UDP 地址 host:port
+	Version  string `config:"version"`  // 可选值为 ipfix / netflow9
+
+	// Interval 活跃链接的导出周期 即 NetFlow 中的 active timeout
+	Interval time.Duration `config:"interval"`
+
+	// TemplateRefresh 模板重发周期 UDP 传输下 collector 重启后需要重新获取模板
+	TemplateRefresh time.Duration `config:"templateRefresh"`
+
+	ObservationDomainID uint32 `config:"observationDomainId"`
+
+	// EnterpriseID 重传次数没有标准的信息元素 IPFIX 以该企业号的私有字段导出
+	EnterpriseID uint32 `config:"enterpriseId"`
+
+	QueueSize int `config:"queueSize"`
+}
+
+// Validate 未启用时跳过校验 ucfg 解析时会对所有嵌套结构自动调用 Validate
+func (fc *FlowsConfig) Validate() error {
+	if !fc.Enabled {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(fc.Endpoint); err != nil {
+		return errors.Wrapf(err, "invalid flows endpoint '%s'", fc.Endpoint)
+	}
+
+	switch FlowVersion(fc.Version) {
+	case "":
+		fc.Version = string(FlowVersionIPFIX)
+	case FlowVersionIPFIX, FlowVersionNetFlow9:
+	default:
+		return errors.Errorf("unsupported flows version '%s'", fc.Version)
+	}
+	if fc.Interval <= 0 {
+		fc.Interval = time.Minute
+	}
+	if fc.TemplateRefresh <= 0 {
+		fc.TemplateRefresh = 5 * time.Minute
+	}
+	if fc.EnterpriseID == 0 {
+		fc.EnterpriseID = DefaultFlowsEnterpriseID
+	}
+	if fc.QueueSize <= 0 {
+		fc.QueueSize = defaultQueueSize
+	}
+	return nil
+}
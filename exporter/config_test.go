@@ -0,0 +1,137 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/confengine"
+)
+
+// referenceConfig 随二进制发布的完整配置
+const referenceConfig = "../cmd/static/packetd.reference.yaml"
+
+func unpackContent(t *testing.T, content string, to any) error {
+	conf, err := confengine.LoadContent([]byte(content))
+	require.NoError(t, err)
+	return conf.Unpack(to)
+}
+
+func TestFlowsConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		version string
+		wantErr bool
+	}{
+		{
+			name:    "Disabled",
+			content: "enabled: false",
+		},
+		{
+			name:    "DefaultVersion",
+			content: "enabled: true\nendpoint: localhost:4739",
+			version: string(FlowVersionIPFIX),
+		},
+		{
+			name:    "NetFlow9",
+			content: "enabled: true\nendpoint: localhost:4739\nversion: netflow9",
+			version: string(FlowVersionNetFlow9),
+		},
+		{
+			name:    "InvalidVersion",
+			content: "enabled: true\nendpoint: localhost:4739\nversion: netflow5",
+			wantErr: true,
+		},
+		{
+			name:    "InvalidEndpoint",
+			content: "enabled: true",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fc FlowsConfig
+			err := unpackContent(t, tt.content, &fc)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, fc.Version)
+		})
+	}
+}
+
+func TestCollectorConfig(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		var cfg Config
+		assert.NoError(t, unpackContent(t, "{}", &cfg))
+	})
+
+	t.Run("Minimal", func(t *testing.T) {
+		var cfg Config
+		err := unpackContent(t, "roundtrips:\n  enabled: true\n  console: true", &cfg)
+		require.NoError(t, err)
+		assert.False(t, cfg.Collector.Enabled)
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		var cc CollectorConfig
+		require.NoError(t, unpackContent(t, "enabled: true\nendpoint: localhost:9093", &cc))
+		assert.Equal(t, 500, cc.Batch)
+	})
+
+	t.Run("InvalidEndpoint", func(t *testing.T) {
+		var cc CollectorConfig
+		assert.Error(t, unpackContent(t, "enabled: true", &cc))
+	})
+}
+
+func TestReferenceConfig(t *testing.T) {
+	conf, err := confengine.LoadConfigPath(referenceConfig)
+	require.NoError(t, err)
+
+	exp, err := New(conf, nil)
+	require.NoError(t, err)
+	exp.Close()
+}
+
+func TestCodecConfig(t *testing.T) {
+	var tc TracesConfig
+	require.NoError(t, unpackContent(t, "compression: gzip\nencoding: json", &tc))
+	assert.Equal(t, Codec{Encoding: EncodingJSON, Compression: CompressionGzip}, tc.Codecs()[0])
+
+	var mc MetricsConfig
+	require.NoError(t, unpackContent(t, "compression: zstd", &mc))
+	assert.Equal(t, CompressionZstd, mc.Codecs()[0].Compression)
+
+	assert.Error(t, unpackContent(t, "compression: lz4", &tc))
+	assert.Error(t, unpackContent(t, "encoding: xml", &tc))
+}
+
+func TestSinksConfig(t *testing.T) {
+	var cfg Config
+	err := unpackContent(t, "sinks:\n  - name: archive\n    type: roundtrips", &cfg)
+	require.NoError(t, err)
+	require.Len(t, cfg.Sinks, 1)
+	assert.Equal(t, string(common.RecordRoundTrips), cfg.Sinks[0].Type)
+
+	assert.Error(t, unpackContent(t, "sinks:\n  - type: metrics", &cfg))
+}
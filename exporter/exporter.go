@@ -15,13 +15,14 @@ package exporter
 
 import (
 	"context"
+	"sync"
 	"time"
 
+	"github.com/pkg/errors"
+
 	"github.com/packetd/packetd/common"
-	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/confengine"
 	"github.com/packetd/packetd/internal/metricstorage"
-	"github.com/packetd/packetd/internal/tracestroage"
 	"github.com/packetd/packetd/logger"
 )
 
@@ -31,11 +32,22 @@ type Exporter struct {
 	conf   Config
 
 	metricsStorage *metricstorage.Storage
-	tracesStorage  *tracestroage.Storage
+	metricsSinker  Sinker
+
+	flowsSinker Sinker
+	flows       chan *common.FlowsData
+
+	eventsSinker Sinker
+	events       chan *common.EventsData
+
+	// 每个输出目标拥有独立的队列以及写入协程 互不阻塞
+	pipes  []*sinkPipe
+	router *router
+
+	forwarder *forwarder
 
-	metricsSinker    Sinker
-	tracesSinker     Sinker
-	roundTripsSinker Sinker
+	started bool
+	wg      sync.WaitGroup
 }
 
 func New(conf *confengine.Config, metricsStorage *metricstorage.Storage) (*Exporter, error) {
@@ -53,93 +65,192 @@ func New(conf *confengine.Config, metricsStorage *metricstorage.Storage) (*Expor
 		}
 	}
 
-	var tracesSinker Sinker
-	if cfg.Traces.Enabled {
-		f := Get(common.RecordTraces)
-		if tracesSinker, err = f(cfg); err != nil {
+	var flowsSinker Sinker
+	if cfg.Flows.Enabled {
+		if err := cfg.Flows.Validate(); err != nil {
+			return nil, err
+		}
+		f := Get(common.RecordFlows)
+		if flowsSinker, err = f(cfg); err != nil {
 			return nil, err
 		}
 	}
 
-	var roundTripsSinker Sinker
-	if cfg.RoundTrips.Enabled {
-		f := Get(common.RecordRoundTrips)
-		if roundTripsSinker, err = f(cfg); err != nil {
+	var eventsSinker Sinker
+	if cfg.Events.Enabled {
+		cfg.Events.Validate()
+		f := Get(common.RecordEvents)
+		if eventsSinker, err = f(cfg); err != nil {
 			return nil, err
 		}
 	}
 
+	sinks, err := cfg.sinkConfigs()
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
+	pipes := make([]*sinkPipe, 0, len(sinks))
+	for _, sc := range sinks {
+		pipe, err := newSinkPipe(ctx, sc)
+		if err != nil {
+			for _, p := range pipes {
+				p.close()
+			}
+			cancel()
+			return nil, errors.Wrapf(err, "create sink (%s) failed", sc.Name)
+		}
+		pipes = append(pipes, pipe)
+	}
+
+	router, err := newRouter(cfg.Routes, pipes)
+	if err != nil {
+		for _, p := range pipes {
+			p.close()
+		}
+		cancel()
+		return nil, err
+	}
+
+	var fwd *forwarder
+	if cfg.Collector.Enabled {
+		if fwd, err = newForwarder(ctx, cfg.Collector); err != nil {
+			for _, p := range pipes {
+				p.close()
+			}
+			cancel()
+			return nil, err
+		}
+	}
+
 	exp := &Exporter{
-		ctx:              ctx,
-		cancel:           cancel,
-		conf:             cfg,
-		metricsStorage:   metricsStorage,
-		tracesStorage:    tracestroage.New(cfg.Traces.Batch, cfg.Traces.Interval),
-		metricsSinker:    metricsSinker,
-		tracesSinker:     tracesSinker,
-		roundTripsSinker: roundTripsSinker,
+		ctx:            ctx,
+		cancel:         cancel,
+		conf:           cfg,
+		metricsStorage: metricsStorage,
+		metricsSinker:  metricsSinker,
+		flowsSinker:    flowsSinker,
+		eventsSinker:   eventsSinker,
+		pipes:          pipes,
+		router:         router,
+		forwarder:      fwd,
+	}
+	if cfg.Flows.Enabled {
+		exp.flows = make(chan *common.FlowsData, cfg.Flows.QueueSize)
+	}
+	if cfg.Events.Enabled {
+		exp.events = make(chan *common.EventsData, cfg.Events.QueueSize)
 	}
 	return exp, nil
 }
 
 func (e *Exporter) Start() {
-	if e.conf.Traces.Enabled {
-		go e.loopExportTraces()
+	e.started = true
+	for _, p := range e.pipes {
+		go p.loop()
 	}
 	if e.conf.Metrics.Enabled {
-		go e.loopExportMetrics()
+		e.goLoop(e.loopExportMetrics)
+	}
+	if e.conf.Flows.Enabled {
+		e.goLoop(e.loopExportFlows)
+	}
+	if e.conf.Events.Enabled {
+		e.goLoop(e.loopExportEvents)
 	}
+	if e.forwarder != nil {
+		e.goLoop(e.forwarder.loop)
+	}
+}
+
+func (e *Exporter) goLoop(f func()) {
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		f()
+	}()
+}
+
+// FlowsEnabled 返回是否开启流记录导出 以及活跃链接的导出周期
+func (e *Exporter) FlowsEnabled() (time.Duration, bool) {
+	return e.conf.Flows.Interval, e.conf.Flows.Enabled
 }
 
+// Close 关闭 Exporter
+//
+// 先停止各输出目标的接收并等待队列中剩余的数据写入 随后等待各 loop 退出 最后关闭 Sinker
 func (e *Exporter) Close() {
+	if e.started {
+		for _, p := range e.pipes {
+			p.drain()
+		}
+	}
 	e.cancel()
+	e.wg.Wait()
 
 	if e.conf.Metrics.Enabled {
 		e.metricsSinker.Close()
 	}
-	if e.conf.Traces.Enabled {
-		e.tracesSinker.Close()
+	if e.conf.Flows.Enabled {
+		e.flowsSinker.Close()
 	}
-	if e.conf.RoundTrips.Enabled {
-		e.roundTripsSinker.Close()
+	if e.conf.Events.Enabled {
+		e.eventsSinker.Close()
+	}
+	for _, p := range e.pipes {
+		p.close()
+	}
+	if e.forwarder != nil {
+		e.forwarder.close()
 	}
 }
 
 func (e *Exporter) Export(record *common.Record) {
 	switch record.RecordType {
 	case common.RecordMetrics:
-		if e.metricsStorage == nil {
-			return
-		}
-
 		data, ok := record.Data.(*common.MetricsData)
 		if !ok {
 			return
 		}
-		e.metricsStorage.Update(data.Data...)
-
-	case common.RecordTraces:
-		if !e.conf.Traces.Enabled {
-			return
+		if e.forwarder != nil {
+			e.forwarder.push(record)
+		}
+		if e.metricsStorage != nil {
+			e.metricsStorage.Update(data.Data...)
 		}
 
-		data, ok := record.Data.(*common.TracesData)
-		if !ok {
+	case common.RecordFlows:
+		data, ok := record.Data.(*common.FlowsData)
+		if !ok || e.flows == nil {
 			return
 		}
-		e.tracesStorage.Push(data.Data)
+		select {
+		case e.flows <- data:
+		default:
+			sinkDroppedTotal.WithLabelValues(string(common.RecordFlows)).Inc()
+		}
 
-	case common.RecordRoundTrips:
-		if !e.conf.RoundTrips.Enabled {
+	case common.RecordEvents:
+		data, ok := record.Data.(*common.EventsData)
+		if !ok || e.events == nil {
 			return
 		}
+		select {
+		case e.events <- data:
+		default:
+			sinkDroppedTotal.WithLabelValues(string(common.RecordEvents)).Inc()
+		}
 
-		data, ok := record.Data.(socket.RoundTrip)
-		if !ok {
-			return
+	case common.RecordTraces, common.RecordRoundTrips:
+		if e.forwarder != nil && (record.RecordType == common.RecordTraces || e.conf.Collector.RoundTrips) {
+			e.forwarder.push(record)
+		}
+		for _, p := range e.router.pipes(record) {
+			if common.RecordType(p.conf.Type) == record.RecordType {
+				p.push(record)
+			}
 		}
-		e.roundTripsSinker.Sink(data)
 	}
 }
 
@@ -164,20 +275,58 @@ func (e *Exporter) loopExportMetrics() {
 	}
 }
 
-func (e *Exporter) loopExportTraces() {
-	if !e.conf.Traces.Enabled {
-		return
+// loopExportFlows 流记录基于 UDP 发送 不做重试
+//
+// 退出前写入队列中剩余的流记录
+func (e *Exporter) loopExportFlows() {
+	for {
+		select {
+		case <-e.ctx.Done():
+			for {
+				select {
+				case data := <-e.flows:
+					e.sinkFlows(data)
+				default:
+					return
+				}
+			}
+
+		case data := <-e.flows:
+			e.sinkFlows(data)
+		}
 	}
+}
 
+func (e *Exporter) sinkFlows(data *common.FlowsData) {
+	if err := e.flowsSinker.Sink(data); err != nil {
+		sinkFailedTotal.WithLabelValues(string(common.RecordFlows)).Inc()
+		logger.Errorf("sink flows failed: %v", err)
+	}
+}
+
+// loopExportEvents 退出前写入队列中剩余的事件
+func (e *Exporter) loopExportEvents() {
 	for {
 		select {
 		case <-e.ctx.Done():
-			return
-
-		case traces := <-e.tracesStorage.Pop():
-			if err := e.tracesSinker.Sink(traces); err != nil {
-				logger.Errorf("sink traces failed: %v", err)
+			for {
+				select {
+				case data := <-e.events:
+					e.sinkEvents(data)
+				default:
+					return
+				}
 			}
+
+		case data := <-e.events:
+			e.sinkEvents(data)
 		}
 	}
 }
+
+func (e *Exporter) sinkEvents(data *common.EventsData) {
+	if err := e.eventsSinker.Sink(data); err != nil {
+		sinkFailedTotal.WithLabelValues(string(common.RecordEvents)).Inc()
+		logger.Errorf("sink events failed: %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/eventfilter"
+	"github.com/packetd/packetd/internal/relay"
+	"github.com/packetd/packetd/logger"
+)
+
+const sinkCollector = "collector"
+
+// forwarder 将指标以及 Span 批量转发至 collector
+//
+// 转发不做重试 collector 不可用期间的数据直接丢弃 避免 agent 内存无限增长
+type forwarder struct {
+	ctx    context.Context
+	conf   CollectorConfig
+	cli    *relay.Client
+	ch     chan *common.Record
+	labels *labeler
+}
+
+func newForwarder(ctx context.Context, conf CollectorConfig) (*forwarder, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	lb, err := newLabeler(conf.Labels, conf.LabelRules)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := relay.NewClient(conf.Endpoint, relay.ClientConfig{TLS: conf.TLS, Token: conf.Token})
+	if err != nil {
+		return nil, err
+	}
+	return &forwarder{
+		ctx:    ctx,
+		conf:   conf,
+		cli:    cli,
+		ch:     make(chan *common.Record, conf.QueueSize),
+		labels: lb,
+	}, nil
+}
+
+func (f *forwarder) push(record *common.Record) {
+	select {
+	case f.ch <- record:
+	default:
+		sinkDroppedTotal.WithLabelValues(sinkCollector).Inc()
+	}
+}
+
+func (f *forwarder) loop() {
+	ticker := time.NewTicker(f.conf.Interval)
+	defer ticker.Stop()
+
+	var b pendingBatch
+	for {
+		select {
+		case <-f.ctx.Done():
+			f.drain(&b)
+			return
+
+		case record := <-f.ch:
+			b.add(record, f.labels)
+			if b.size() >= f.conf.Batch {
+				f.flush(f.ctx, &b)
+			}
+
+		case <-ticker.C:
+			f.flush(f.ctx, &b)
+		}
+	}
+}
+
+// drain 退出前推送队列中剩余的记录 f.ctx 此时已经取消 单独计算超时
+func (f *forwarder) drain(b *pendingBatch) {
+	for {
+		select {
+		case record := <-f.ch:
+			b.add(record, f.labels)
+			if b.size() >= f.conf.Batch {
+				f.flush(context.Background(), b)
+			}
+		default:
+			f.flush(context.Background(), b)
+			return
+		}
+	}
+}
+
+func (f *forwarder) flush(parent context.Context, b *pendingBatch) {
+	if b.size() == 0 {
+		return
+	}
+	defer b.reset()
+
+	batch, err := b.build(f.conf.Agent)
+	if err != nil {
+		logger.Errorf("build collector batch failed: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(parent, f.conf.Timeout)
+	defer cancel()
+
+	if _, err := f.cli.Push(ctx, batch); err != nil {
+		sinkFailedTotal.WithLabelValues(sinkCollector).Inc()
+		logger.Errorf("push to collector (%s) failed: %v", f.conf.Endpoint, err)
+	}
+}
+
+func (f *forwarder) close() {
+	f.cli.Close()
+}
+
+type pendingBatch struct {
+	metrics  []relay.Observation
+	spans    []ptrace.Span
+	spanKeys []relay.Key
+
+	roundTrips []relay.RoundTripRecord
+}
+
+func (b *pendingBatch) add(record *common.Record, lb *labeler) {
+	switch data := record.Data.(type) {
+	case *common.MetricsData:
+		b.metrics = append(b.metrics, relay.Observation{
+			Key:  relay.RoundTripKey(data.RoundTrip),
+			Data: data.Data,
+		})
+	case *common.TracesData:
+		b.spans = append(b.spans, data.Data)
+		b.spanKeys = append(b.spanKeys, relay.RoundTripKey(data.RoundTrip))
+	case socket.RoundTrip:
+		raw, err := socket.JSONMarshalRoundTrip(data)
+		if err != nil {
+			return
+		}
+		b.roundTrips = append(b.roundTrips, relay.RoundTripRecord{
+			Labels: lb.labels(data),
+			Data:   raw,
+		})
+	}
+}
+
+func (b *pendingBatch) size() int {
+	return len(b.metrics) + len(b.spans) + len(b.roundTrips)
+}
+
+func (b *pendingBatch) reset() {
+	b.metrics = nil
+	b.spans = nil
+	b.spanKeys = nil
+	b.roundTrips = nil
+}
+
+func (b *pendingBatch) build(agent string) (*relay.Batch, error) {
+	batch := &relay.Batch{
+		Agent:      agent,
+		Metrics:    b.metrics,
+		SpanKeys:   b.spanKeys,
+		RoundTrips: b.roundTrips,
+	}
+	if len(b.spans) == 0 {
+		return batch, nil
+	}
+
+	traces := ptrace.NewTraces()
+	spans := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	for i := range b.spans {
+		b.spans[i].CopyTo(spans.AppendEmpty())
+	}
+
+	var marshaler ptrace.ProtoMarshaler
+	data, err := marshaler.MarshalTraces(traces)
+	if err != nil {
+		return nil, err
+	}
+	batch.Traces = data
+	return batch, nil
+}
+
+type labelRule struct {
+	filter *eventfilter.Filter
+	labels map[string]string
+}
+
+// labeler 计算 roundtrip 转发时附加的标签
+type labeler struct {
+	base  map[string]string
+	rules []labelRule
+}
+
+func newLabeler(base map[string]string, rules []CollectorLabelRule) (*labeler, error) {
+	lb := &labeler{base: base}
+	for _, rule := range rules {
+		filter, err := eventfilter.Compile(rule.Filter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "compile collector label rule '%s' failed", rule.Filter)
+		}
+		lb.rules = append(lb.rules, labelRule{filter: filter, labels: rule.Labels})
+	}
+	return lb, nil
+}
+
+func (lb *labeler) labels(rt socket.RoundTrip) map[string]string {
+	var matched map[string]string
+	for _, rule := range lb.rules {
+		if rule.filter.Match(rt) {
+			matched = rule.labels
+			break
+		}
+	}
+	if len(matched) == 0 {
+		return lb.base
+	}
+
+	lbs := make(map[string]string, len(lb.base)+len(matched))
+	for k, v := range lb.base {
+		lbs[k] = v
+	}
+	for k, v := range matched {
+		lbs[k] = v
+	}
+	return lbs
+}
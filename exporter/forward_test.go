@@ -0,0 +1,41 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+func TestLabeler(t *testing.T) {
+	lb, err := newLabeler(
+		map[string]string{"namespace": "default", "cluster": "c1"},
+		[]CollectorLabelRule{
+			{Filter: `proto == "mysql"`, Labels: map[string]string{"namespace": "db", "service": "mysql"}},
+			{Filter: `proto == "mysql" || proto == "redis"`, Labels: map[string]string{"service": "cache"}},
+		},
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"namespace": "default", "cluster": "c1"}, lb.labels(fakeRoundTrip{proto: socket.L7ProtoHTTP}))
+	assert.Equal(t, map[string]string{"namespace": "db", "cluster": "c1", "service": "mysql"}, lb.labels(fakeRoundTrip{proto: socket.L7ProtoMySQL}))
+	assert.Equal(t, map[string]string{"namespace": "default", "cluster": "c1", "service": "cache"}, lb.labels(fakeRoundTrip{proto: socket.L7ProtoRedis}))
+
+	_, err = newLabeler(nil, []CollectorLabelRule{{Filter: `proto ==`}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,50 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/packetd/packetd/common"
+)
+
+var (
+	sinkDroppedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: common.App,
+			Name:      "exporter_sink_dropped_total",
+			Help:      "Exporter sink dropped records total",
+		},
+		[]string{"sink"},
+	)
+
+	sinkRetriedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: common.App,
+			Name:      "exporter_sink_retried_total",
+			Help:      "Exporter sink retried writes total",
+		},
+		[]string{"sink"},
+	)
+
+	sinkFailedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: common.App,
+			Name:      "exporter_sink_failed_total",
+			Help:      "Exporter sink failed writes total after all retries",
+		},
+		[]string{"sink"},
+	)
+)
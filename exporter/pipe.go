@@ -0,0 +1,194 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/eventfilter"
+	"github.com/packetd/packetd/internal/tracestroage"
+	"github.com/packetd/packetd/logger"
+)
+
+// sinkPipe 单个输出目标的处理管道
+//
+// 数据先写入独立的队列 再由独立的 goroutine 写入 Sinker 写入失败时按照退避策略重试
+// 队列已满时直接丢弃 保证慢速的输出目标不会阻塞上游以及其他输出目标
+type sinkPipe struct {
+	ctx    context.Context
+	conf   SinkConfig
+	filter *eventfilter.Filter
+	sinker Sinker
+
+	traces     *tracestroage.Storage
+	roundTrips chan socket.RoundTrip
+
+	stop chan struct{} // 停止接收 roundtrip 队列中剩余的数据写入后 loop 退出
+	done chan struct{}
+}
+
+func newSinkPipe(ctx context.Context, conf SinkConfig) (*sinkPipe, error) {
+	filter, err := eventfilter.Compile(conf.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	f := Get(common.RecordType(conf.Type))
+	if f == nil {
+		return nil, errors.Errorf("sink (%s) got unregistered type '%s'", conf.Name, conf.Type)
+	}
+
+	// Sinker 仍以完整的 Config 构建 仅填充当前输出目标对应的部分
+	sinker, err := f(Config{
+		Traces:     conf.Traces,
+		RoundTrips: conf.RoundTrips,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p := &sinkPipe{
+		ctx:    ctx,
+		conf:   conf,
+		filter: filter,
+		sinker: sinker,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	switch common.RecordType(conf.Type) {
+	case common.RecordTraces:
+		// Sinker 持有的是配置副本 批量参数的默认值需要单独填充
+		traces := conf.Traces
+		if err := traces.Validate(); err != nil {
+			return nil, err
+		}
+		p.traces = tracestroage.New(traces.Batch, traces.Interval, conf.Queue.Size)
+	case common.RecordRoundTrips:
+		p.roundTrips = make(chan socket.RoundTrip, conf.Queue.Size)
+	}
+	return p, nil
+}
+
+// push 非阻塞写入 仅接收与输出目标类型一致的数据
+func (p *sinkPipe) push(record *common.Record) {
+	var ok bool
+	switch common.RecordType(p.conf.Type) {
+	case common.RecordTraces:
+		data, isTraces := record.Data.(*common.TracesData)
+		if !isTraces {
+			return
+		}
+		if data.RoundTrip != nil && !p.filter.Match(data.RoundTrip) {
+			return
+		}
+		ok = p.traces.TryPush(data.Data)
+
+	case common.RecordRoundTrips:
+		rt, isRoundTrip := record.Data.(socket.RoundTrip)
+		if !isRoundTrip || !p.filter.Match(rt) {
+			return
+		}
+		select {
+		case p.roundTrips <- rt:
+			ok = true
+		default:
+		}
+	}
+
+	if !ok {
+		sinkDroppedTotal.WithLabelValues(p.conf.Name).Inc()
+	}
+}
+
+func (p *sinkPipe) loop() {
+	defer close(p.done)
+
+	switch common.RecordType(p.conf.Type) {
+	case common.RecordTraces:
+		for traces := range p.traces.Pop() {
+			p.sink(traces)
+		}
+
+	case common.RecordRoundTrips:
+		for {
+			select {
+			case <-p.stop:
+				p.drainRoundTrips()
+				return
+			case rt := <-p.roundTrips:
+				p.sink(rt)
+			}
+		}
+	}
+}
+
+// drainRoundTrips 写入队列中剩余的 roundtrip
+func (p *sinkPipe) drainRoundTrips() {
+	for {
+		select {
+		case rt := <-p.roundTrips:
+			p.sink(rt)
+		default:
+			return
+		}
+	}
+}
+
+// drain 停止接收数据 等待队列中剩余的数据写入 Sinker 后返回
+//
+// 仅在 loop 已经启动后调用
+func (p *sinkPipe) drain() {
+	if p.traces != nil {
+		p.traces.Close()
+	} else {
+		close(p.stop)
+	}
+	<-p.done
+}
+
+// sink 写入数据 失败时按照指数退避重试
+func (p *sinkPipe) sink(data any) {
+	backoff := p.conf.Retry.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := p.sinker.Sink(data)
+		if err == nil {
+			return
+		}
+		if attempt >= p.conf.Retry.MaxAttempts {
+			sinkFailedTotal.WithLabelValues(p.conf.Name).Inc()
+			logger.Errorf("sink (%s) failed after %d attempts: %v", p.conf.Name, attempt, err)
+			return
+		}
+
+		sinkRetriedTotal.WithLabelValues(p.conf.Name).Inc()
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, p.conf.Retry.MaxBackoff)
+	}
+}
+
+func (p *sinkPipe) close() {
+	if p.traces != nil {
+		p.traces.Close()
+	}
+	p.sinker.Close()
+}
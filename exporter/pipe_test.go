@@ -0,0 +1,140 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+)
+
+type fakeRoundTrip struct {
+	proto socket.L7Proto
+}
+
+func (rt fakeRoundTrip) Proto() socket.L7Proto   { return rt.proto }
+func (rt fakeRoundTrip) Request() any            { return nil }
+func (rt fakeRoundTrip) Response() any           { return nil }
+func (rt fakeRoundTrip) Duration() time.Duration { return 0 }
+func (rt fakeRoundTrip) Validate() bool          { return true }
+
+// fakeSinker 前 failures 次写入返回错误
+type fakeSinker struct {
+	mut      sync.Mutex
+	failures int
+	attempts int
+	sunk     []any
+}
+
+func (s *fakeSinker) Name() common.RecordType { return common.RecordRoundTrips }
+func (s *fakeSinker) Close()                  {}
+
+func (s *fakeSinker) Sink(data any) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.attempts++
+	if s.attempts <= s.failures {
+		return errors.New("sink failed")
+	}
+	s.sunk = append(s.sunk, data)
+	return nil
+}
+
+func newTestPipe(t *testing.T, sinker *fakeSinker, conf SinkConfig) *sinkPipe {
+	Register(common.RecordRoundTrips, func(Config) (Sinker, error) { return sinker, nil })
+	conf.Type = string(common.RecordRoundTrips)
+	require.NoError(t, conf.Validate())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	pipe, err := newSinkPipe(ctx, conf)
+	require.NoError(t, err)
+	return pipe
+}
+
+func TestSinkPipeRetry(t *testing.T) {
+	sinker := &fakeSinker{failures: 2}
+	pipe := newTestPipe(t, sinker, SinkConfig{
+		Retry: RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+	})
+
+	pipe.sink(fakeRoundTrip{})
+	assert.Equal(t, 3, sinker.attempts)
+	assert.Len(t, sinker.sunk, 1)
+
+	sinker.attempts, sinker.failures = 0, 5
+	pipe.sink(fakeRoundTrip{})
+	assert.Equal(t, 3, sinker.attempts)
+	assert.Len(t, sinker.sunk, 1)
+}
+
+func TestSinkPipePush(t *testing.T) {
+	sinker := &fakeSinker{}
+	pipe := newTestPipe(t, sinker, SinkConfig{
+		Filter: `proto == "mysql"`,
+		Queue:  QueueConfig{Size: 1},
+	})
+
+	pipe.push(common.NewRecord(common.RecordRoundTrips, fakeRoundTrip{proto: socket.L7ProtoHTTP}))
+	assert.Len(t, pipe.roundTrips, 0)
+
+	// 队列已满时丢弃 不阻塞调用方
+	pipe.push(common.NewRecord(common.RecordRoundTrips, fakeRoundTrip{proto: socket.L7ProtoMySQL}))
+	pipe.push(common.NewRecord(common.RecordRoundTrips, fakeRoundTrip{proto: socket.L7ProtoMySQL}))
+	assert.Len(t, pipe.roundTrips, 1)
+}
+
+func TestSinkConfigs(t *testing.T) {
+	cfg := Config{
+		RoundTrips: RoundTripsConfig{Enabled: true},
+		Sinks: []SinkConfig{
+			{Name: "archive", Type: string(common.RecordRoundTrips)},
+			{Type: string(common.RecordTraces)},
+		},
+	}
+	sinks, err := cfg.sinkConfigs()
+	require.NoError(t, err)
+	assert.Len(t, sinks, 3)
+	assert.Equal(t, "traces", sinks[2].Name)
+	assert.Equal(t, defaultQueueSize, sinks[1].Queue.Size)
+
+	cfg.Sinks = append(cfg.Sinks, SinkConfig{Name: "archive", Type: string(common.RecordRoundTrips)})
+	_, err = cfg.sinkConfigs()
+	assert.Error(t, err)
+
+	cfg.Sinks = []SinkConfig{{Type: string(common.RecordMetrics)}}
+	_, err = cfg.sinkConfigs()
+	assert.Error(t, err)
+}
+
+func TestSinkPipeDrain(t *testing.T) {
+	sinker := &fakeSinker{}
+	pipe := newTestPipe(t, sinker, SinkConfig{Queue: QueueConfig{Size: 8}})
+
+	for i := 0; i < 5; i++ {
+		pipe.push(common.NewRecord(common.RecordRoundTrips, fakeRoundTrip{}))
+	}
+	go pipe.loop()
+	pipe.drain()
+	assert.Len(t, sinker.sunk, 5)
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/eventfilter"
+)
+
+type route struct {
+	name   string
+	filter *eventfilter.Filter
+	pipes  []*sinkPipe
+	types  map[common.RecordType]struct{}
+}
+
+// router 根据路由规则为每条数据选择输出目标
+//
+// 规则按照声明顺序匹配 首个命中的规则生效 数据仅发送至该规则引用的输出目标
+// 规则只接管其引用的输出目标所对应的数据类型 如仅引用 traces 输出目标的规则不影响 roundtrips 数据
+// 未命中任何规则（或无法获取 RoundTrip）的数据发送至未被任何规则引用的输出目标
+// 未配置任何规则时即为全部输出目标 与此前的行为一致
+type router struct {
+	routes   []route
+	fallback []*sinkPipe
+}
+
+func newRouter(confs []RouteConfig, pipes []*sinkPipe) (*router, error) {
+	byName := make(map[string]*sinkPipe, len(pipes))
+	for _, p := range pipes {
+		byName[p.conf.Name] = p
+	}
+
+	referred := make(map[string]struct{})
+	routes := make([]route, 0, len(confs))
+	for i, rc := range confs {
+		name := rc.Name
+		if name == "" {
+			name = rc.Filter
+		}
+		if len(rc.Sinks) == 0 {
+			return nil, errors.Errorf("route[%d] (%s) got empty sinks", i, name)
+		}
+
+		filter, err := eventfilter.Compile(rc.Filter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "route[%d] (%s) got invalid filter", i, name)
+		}
+
+		r := route{name: name, filter: filter, types: make(map[common.RecordType]struct{})}
+		for _, sink := range rc.Sinks {
+			p, ok := byName[sink]
+			if !ok {
+				return nil, errors.Errorf("route[%d] (%s) refers to unknown sink '%s'", i, name, sink)
+			}
+			r.pipes = append(r.pipes, p)
+			r.types[common.RecordType(p.conf.Type)] = struct{}{}
+			referred[sink] = struct{}{}
+		}
+		routes = append(routes, r)
+	}
+
+	var fallback []*sinkPipe
+	for _, p := range pipes {
+		if _, ok := referred[p.conf.Name]; !ok {
+			fallback = append(fallback, p)
+		}
+	}
+	return &router{routes: routes, fallback: fallback}, nil
+}
+
+// pipes 返回 record 需要发送的输出目标
+func (r *router) pipes(record *common.Record) []*sinkPipe {
+	if len(r.routes) == 0 {
+		return r.fallback
+	}
+
+	var rt socket.RoundTrip
+	switch data := record.Data.(type) {
+	case *common.TracesData:
+		rt = data.RoundTrip
+	case socket.RoundTrip:
+		rt = data
+	}
+	if rt == nil {
+		return r.fallback
+	}
+
+	for _, route := range r.routes {
+		if _, ok := route.types[record.RecordType]; !ok {
+			continue
+		}
+		if route.filter.Match(rt) {
+			return route.pipes
+		}
+	}
+	return r.fallback
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+)
+
+func pipeNames(pipes []*sinkPipe) []string {
+	var names []string
+	for _, p := range pipes {
+		names = append(names, p.conf.Name)
+	}
+	return names
+}
+
+func TestRouter(t *testing.T) {
+	pipes := []*sinkPipe{
+		newTestPipe(t, &fakeSinker{}, SinkConfig{Name: "team-db"}),
+		newTestPipe(t, &fakeSinker{}, SinkConfig{Name: "team-web"}),
+		newTestPipe(t, &fakeSinker{}, SinkConfig{Name: "default"}),
+		{conf: SinkConfig{Name: "traces", Type: string(common.RecordTraces)}},
+	}
+
+	t.Run("NoRoutes", func(t *testing.T) {
+		r, err := newRouter(nil, pipes)
+		require.NoError(t, err)
+		record := common.NewRecord(common.RecordRoundTrips, fakeRoundTrip{proto: socket.L7ProtoHTTP})
+		assert.Len(t, r.pipes(record), 4)
+	})
+
+	r, err := newRouter([]RouteConfig{
+		{Name: "db", Filter: `proto == "mysql" || proto == "redis"`, Sinks: []string{"team-db"}},
+		{Name: "web", Filter: `proto == "http"`, Sinks: []string{"team-web", "default"}},
+	}, pipes)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		record *common.Record
+		want   []string
+	}{
+		{
+			name:   "FirstMatch",
+			record: common.NewRecord(common.RecordRoundTrips, fakeRoundTrip{proto: socket.L7ProtoMySQL}),
+			want:   []string{"team-db"},
+		},
+		{
+			name:   "MultipleSinks",
+			record: common.NewRecord(common.RecordRoundTrips, fakeRoundTrip{proto: socket.L7ProtoHTTP}),
+			want:   []string{"team-web", "default"},
+		},
+		{
+			name:   "Fallback",
+			record: common.NewRecord(common.RecordRoundTrips, fakeRoundTrip{proto: socket.L7ProtoDNS}),
+			want:   []string{"traces"},
+		},
+		{
+			name: "UnclaimedType",
+			record: common.NewRecord(common.RecordTraces, &common.TracesData{
+				RoundTrip: fakeRoundTrip{proto: socket.L7ProtoMySQL},
+			}),
+			want: []string{"traces"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, pipeNames(r.pipes(tt.record)))
+		})
+	}
+}
+
+func TestRouterInvalid(t *testing.T) {
+	pipes := []*sinkPipe{newTestPipe(t, &fakeSinker{}, SinkConfig{Name: "archive"})}
+
+	tests := []struct {
+		name   string
+		routes []RouteConfig
+	}{
+		{name: "EmptySinks", routes: []RouteConfig{{Filter: `proto == "http"`}}},
+		{name: "UnknownSink", routes: []RouteConfig{{Filter: `proto == "http"`, Sinks: []string{"missing"}}}},
+		{name: "InvalidFilter", routes: []RouteConfig{{Filter: `proto ==`, Sinks: []string{"archive"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newRouter(tt.routes, pipes)
+			assert.Error(t, err)
+		})
+	}
+}
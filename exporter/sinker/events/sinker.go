@@ -0,0 +1,103 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"hash/fnv"
+	"io"
+	"os"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/exporter"
+)
+
+func init() {
+	exporter.Register(common.RecordEvents, New)
+}
+
+type Sinker struct {
+	wc io.WriteCloser
+}
+
+func New(conf exporter.Config) (exporter.Sinker, error) {
+	cfg := &conf.Events
+	cfg.Validate()
+
+	var wr io.WriteCloser
+	switch {
+	case cfg.Console:
+		wr = os.Stdout
+	default:
+		wr = &lumberjack.Logger{
+			Filename:   cfg.Filename,
+			MaxSize:    cfg.MaxSize,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAge,
+			LocalTime:  true,
+		}
+	}
+	return &Sinker{wc: wr}, nil
+}
+
+func (s *Sinker) Name() common.RecordType {
+	return common.RecordEvents
+}
+
+func (s *Sinker) Sink(data any) error {
+	events, ok := data.(*common.EventsData)
+	if !ok {
+		return nil
+	}
+
+	for _, event := range events.Data {
+		b, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		s.wc.Write(withEventID(b))
+		s.wc.Write([]byte{'\n'})
+	}
+	return nil
+}
+
+// withEventID 在事件 JSON 对象的首部插入 EventID 字段 取值为事件内容的哈希
+//
+// 事件生成后内容即不再变化 重复投递的同一事件具有相同的 EventID 非 JSON 对象原样返回
+func withEventID(b []byte) []byte {
+	if len(b) < 2 || b[0] != '{' {
+		return b
+	}
+	h := fnv.New128a()
+	h.Write(b)
+
+	var buf bytes.Buffer
+	buf.Grow(len(b) + 48)
+	buf.WriteString(`{"EventID":"`)
+	buf.WriteString(hex.EncodeToString(h.Sum(nil)))
+	buf.WriteByte('"')
+	if len(b) > 2 {
+		buf.WriteByte(',')
+	}
+	buf.Write(b[1:])
+	return buf.Bytes()
+}
+
+func (s *Sinker) Close() {
+	s.wc.Close()
+}
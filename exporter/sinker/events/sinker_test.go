@@ -0,0 +1,38 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithEventID(t *testing.T) {
+	b := withEventID([]byte(`{"Event":"progress","Rows":10}`))
+
+	var v map[string]any
+	require.NoError(t, json.Unmarshal(b, &v))
+	assert.Equal(t, "progress", v["Event"])
+	assert.Len(t, v["EventID"], 32)
+
+	assert.Equal(t, b, withEventID([]byte(`{"Event":"progress","Rows":10}`)))
+	assert.NotEqual(t, b, withEventID([]byte(`{"Event":"progress","Rows":11}`)))
+
+	b = withEventID([]byte(`{}`))
+	require.NoError(t, json.Unmarshal(b, &v))
+	assert.Equal(t, []byte(`["a"]`), withEventID([]byte(`["a"]`)))
+}
@@ -0,0 +1,315 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flows
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/exporter"
+)
+
+const (
+	// maxMessageSize 单个 UDP 报文的上限 避免在常见 MTU 下产生 IP 分片
+	maxMessageSize = 1400
+
+	ipfixHeaderLength = 16
+	v9HeaderLength    = 20
+	setHeaderLength   = 4
+
+	// ipfixTemplateSetID / v9TemplateSetID 模板集合的 Set ID
+	ipfixTemplateSetID = 2
+	v9TemplateSetID    = 0
+
+	templateIPv4 = 256
+	templateIPv6 = 257
+
+	// applicationNameLength applicationName 按照定长字符串导出 NetFlow v9 不支持变长字段
+	applicationNameLength = 16
+
+	// elementRetransmits 重传次数的企业私有信息元素 ID
+	elementRetransmits = 1
+
+	// v9FieldRetransmits NetFlow v9 没有企业字段 以最高位置 1 的字段类型导出重传次数 避免与标准字段冲突
+	v9FieldRetransmits = 0x8000 | elementRetransmits
+)
+
+// field 模板中的字段定义
+type field struct {
+	id         uint16
+	length     uint16
+	enterprise uint32
+	put        func(b []byte, f *common.Flow)
+}
+
+func putUint64(v func(f *common.Flow) uint64) func(b []byte, f *common.Flow) {
+	return func(b []byte, f *common.Flow) {
+		binary.BigEndian.PutUint64(b, v(f))
+	}
+}
+
+func protocolIdentifier(proto socket.L4Proto) uint8 {
+	switch proto {
+	case socket.L4ProtoTCP:
+		return 6
+	case socket.L4ProtoUDP:
+		return 17
+	}
+	return 0
+}
+
+// encoder 将流记录编码为 IPFIX（RFC 7011）或者 NetFlow v9（RFC 3954）报文
+//
+// 每种地址族对应一个模板 模板随首个报文发送 此后按照 templateRefresh 周期重发
+type encoder struct {
+	version         exporter.FlowVersion
+	domainID        uint32
+	templateRefresh time.Duration
+	templates       map[uint16][]field
+
+	started      time.Time // NetFlow v9 的 sysUptime 基准
+	lastTemplate time.Time
+	sequence     uint32 // IPFIX 为已发送的数据记录数 NetFlow v9 为已发送的报文数
+}
+
+func newEncoder(cfg exporter.FlowsConfig, started time.Time) *encoder {
+	e := &encoder{
+		version:         exporter.FlowVersion(cfg.Version),
+		domainID:        cfg.ObservationDomainID,
+		templateRefresh: cfg.TemplateRefresh,
+		started:         started,
+	}
+	e.templates = map[uint16][]field{
+		templateIPv4: e.fields(cfg.EnterpriseID, 8, 12, 4),
+		templateIPv6: e.fields(cfg.EnterpriseID, 27, 28, 16),
+	}
+	return e
+}
+
+func (e *encoder) headerLength() int {
+	if e.version == exporter.FlowVersionNetFlow9 {
+		return v9HeaderLength
+	}
+	return ipfixHeaderLength
+}
+
+// uptime 返回 t 相对于 started 的毫秒数 用于 NetFlow v9 的 FIRST/LAST_SWITCHED
+func (e *encoder) uptime(t time.Time) uint32 {
+	d := t.Sub(e.started)
+	if d < 0 {
+		return 0
+	}
+	return uint32(d.Milliseconds())
+}
+
+func (e *encoder) fields(enterpriseID uint32, srcAddr, dstAddr, addrLength uint16) []field {
+	fields := []field{
+		{id: srcAddr, length: addrLength, put: func(b []byte, f *common.Flow) {
+			copy(b, f.Tuple.SrcIP.NetIP())
+		}},
+		{id: dstAddr, length: addrLength, put: func(b []byte, f *common.Flow) {
+			copy(b, f.Tuple.DstIP.NetIP())
+		}},
+		{id: 7, length: 2, put: func(b []byte, f *common.Flow) {
+			binary.BigEndian.PutUint16(b, uint16(f.Tuple.SrcPort))
+		}},
+		{id: 11, length: 2, put: func(b []byte, f *common.Flow) {
+			binary.BigEndian.PutUint16(b, uint16(f.Tuple.DstPort))
+		}},
+		{id: 4, length: 1, put: func(b []byte, f *common.Flow) {
+			b[0] = protocolIdentifier(f.L4Proto)
+		}},
+		{id: 1, length: 8, put: putUint64(func(f *common.Flow) uint64 { return f.Bytes })},
+		{id: 2, length: 8, put: putUint64(func(f *common.Flow) uint64 { return f.Packets })},
+	}
+
+	if e.version == exporter.FlowVersionNetFlow9 {
+		fields = append(fields,
+			field{id: 22, length: 4, put: func(b []byte, f *common.Flow) {
+				binary.BigEndian.PutUint32(b, e.uptime(f.Start))
+			}},
+			field{id: 21, length: 4, put: func(b []byte, f *common.Flow) {
+				binary.BigEndian.PutUint32(b, e.uptime(f.End))
+			}},
+		)
+	} else {
+		fields = append(fields,
+			field{id: 152, length: 8, put: putUint64(func(f *common.Flow) uint64 { return uint64(f.Start.UnixMilli()) })},
+			field{id: 153, length: 8, put: putUint64(func(f *common.Flow) uint64 { return uint64(f.End.UnixMilli()) })},
+		)
+	}
+
+	fields = append(fields, field{id: 96, length: applicationNameLength, put: func(b []byte, f *common.Flow) {
+		copy(b, f.L7Proto)
+	}})
+	retransmits := field{
+		id:         elementRetransmits,
+		length:     8,
+		enterprise: enterpriseID,
+		put:        putUint64(func(f *common.Flow) uint64 { return f.Retransmits }),
+	}
+	if e.version == exporter.FlowVersionNetFlow9 {
+		retransmits.id, retransmits.enterprise = v9FieldRetransmits, 0
+	}
+	return append(fields, retransmits)
+}
+
+func recordLength(fields []field) int {
+	var n int
+	for _, f := range fields {
+		n += int(f.length)
+	}
+	return n
+}
+
+// message 单个待发送的报文
+type message struct {
+	b       []byte
+	records int // 报文中的记录数 包含模板记录
+	data    int // 报文中的数据记录数
+	setAt   int // 当前 Set 的起始位置
+	setID   uint16
+	pad     bool // NetFlow v9 要求 FlowSet 按照 4 字节对齐
+}
+
+func (m *message) openSet(id uint16) {
+	m.closeSet()
+	m.setAt = len(m.b)
+	m.setID = id
+	m.b = append(m.b, make([]byte, setHeaderLength)...)
+	binary.BigEndian.PutUint16(m.b[m.setAt:], id)
+}
+
+// closeSet 回填 Set 长度
+func (m *message) closeSet() {
+	if m.setAt == 0 {
+		return
+	}
+	if m.pad {
+		for (len(m.b)-m.setAt)%4 != 0 {
+			m.b = append(m.b, 0)
+		}
+	}
+	binary.BigEndian.PutUint16(m.b[m.setAt+2:], uint16(len(m.b)-m.setAt))
+	m.setAt = 0
+}
+
+// templateSet 返回包含所有模板的 Set
+func (e *encoder) templateSet(m *message) {
+	setID := uint16(ipfixTemplateSetID)
+	if e.version == exporter.FlowVersionNetFlow9 {
+		setID = v9TemplateSetID
+	}
+
+	m.openSet(setID)
+	for _, id := range []uint16{templateIPv4, templateIPv6} {
+		fields := e.templates[id]
+		m.b = binary.BigEndian.AppendUint16(m.b, id)
+		m.b = binary.BigEndian.AppendUint16(m.b, uint16(len(fields)))
+		for _, f := range fields {
+			if f.enterprise != 0 {
+				m.b = binary.BigEndian.AppendUint16(m.b, f.id|0x8000)
+				m.b = binary.BigEndian.AppendUint16(m.b, f.length)
+				m.b = binary.BigEndian.AppendUint32(m.b, f.enterprise)
+				continue
+			}
+			m.b = binary.BigEndian.AppendUint16(m.b, f.id)
+			m.b = binary.BigEndian.AppendUint16(m.b, f.length)
+		}
+		m.records++
+	}
+	m.closeSet()
+}
+
+// Encode 将流记录编码为一个或多个报文
+func (e *encoder) Encode(now time.Time, flows []common.Flow) [][]byte {
+	var msgs [][]byte
+	var m *message
+
+	newMessage := func() {
+		m = &message{
+			b:   make([]byte, e.headerLength(), maxMessageSize),
+			pad: e.version == exporter.FlowVersionNetFlow9,
+		}
+		if e.lastTemplate.IsZero() || now.Sub(e.lastTemplate) >= e.templateRefresh {
+			e.templateSet(m)
+			e.lastTemplate = now
+		}
+	}
+	flush := func() {
+		m.closeSet()
+		msgs = append(msgs, e.finish(now, m))
+		m = nil
+	}
+
+	for i := range flows {
+		f := &flows[i]
+		id := uint16(templateIPv4)
+		if f.Tuple.SrcIP.Version == socket.V6 {
+			id = templateIPv6
+		}
+		fields := e.templates[id]
+		size := recordLength(fields)
+
+		if m != nil && len(m.b)+size+setHeaderLength+3 > maxMessageSize {
+			flush()
+		}
+		if m == nil {
+			newMessage()
+		}
+		if m.setAt == 0 || m.setID != id {
+			m.openSet(id)
+		}
+
+		start := len(m.b)
+		m.b = append(m.b, make([]byte, size)...)
+		offset := start
+		for _, fd := range fields {
+			fd.put(m.b[offset:offset+int(fd.length)], f)
+			offset += int(fd.length)
+		}
+		m.records++
+		m.data++
+	}
+	if m != nil {
+		flush()
+	}
+	return msgs
+}
+
+// finish 填充报文头部
+func (e *encoder) finish(now time.Time, m *message) []byte {
+	b := m.b
+	if e.version == exporter.FlowVersionNetFlow9 {
+		e.sequence++
+		binary.BigEndian.PutUint16(b[0:], 9)
+		binary.BigEndian.PutUint16(b[2:], uint16(m.records))
+		binary.BigEndian.PutUint32(b[4:], e.uptime(now))
+		binary.BigEndian.PutUint32(b[8:], uint32(now.Unix()))
+		binary.BigEndian.PutUint32(b[12:], e.sequence)
+		binary.BigEndian.PutUint32(b[16:], e.domainID)
+		return b
+	}
+
+	// IPFIX 的序号为此前已发送的数据记录总数
+	binary.BigEndian.PutUint16(b[0:], 10)
+	binary.BigEndian.PutUint16(b[2:], uint16(len(b)))
+	binary.BigEndian.PutUint32(b[4:], uint32(now.Unix()))
+	binary.BigEndian.PutUint32(b[8:], e.sequence)
+	binary.BigEndian.PutUint32(b[12:], e.domainID)
+	e.sequence += uint32(m.data)
+	return b
+}
@@ -0,0 +1,169 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flows
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/exporter"
+)
+
+func newFlow(srcIP string, port socket.Port) common.Flow {
+	t0 := time.UnixMilli(1751356800123)
+	tuple := socket.Tuple{SrcPort: port, DstPort: 3306}
+	if ip := net.ParseIP(srcIP); ip.To4() != nil {
+		tuple.SrcIP = socket.ToIPV4(ip.To4())
+		tuple.DstIP = socket.ToIPV4(net.ParseIP("10.0.0.2").To4())
+	} else {
+		tuple.SrcIP = socket.ToIPV6(ip)
+		tuple.DstIP = socket.ToIPV6(net.ParseIP("fd00::2"))
+	}
+	return common.Flow{
+		Tuple:       tuple,
+		L4Proto:     socket.L4ProtoTCP,
+		L7Proto:     socket.L7ProtoMySQL,
+		Packets:     12,
+		Bytes:       3456,
+		Retransmits: 2,
+		Start:       t0,
+		End:         t0.Add(1500 * time.Millisecond),
+	}
+}
+
+// parsedSet 解析后的 Set
+type parsedSet struct {
+	id   uint16
+	body []byte
+}
+
+func parseSets(t *testing.T, b []byte) []parsedSet {
+	var sets []parsedSet
+	for len(b) > 0 {
+		require.GreaterOrEqual(t, len(b), setHeaderLength)
+		n := int(binary.BigEndian.Uint16(b[2:]))
+		require.GreaterOrEqual(t, len(b), n)
+		sets = append(sets, parsedSet{id: binary.BigEndian.Uint16(b), body: b[setHeaderLength:n]})
+		b = b[n:]
+	}
+	return sets
+}
+
+func TestEncodeIPFIX(t *testing.T) {
+	now := time.Unix(1751356860, 0)
+	enc := newEncoder(exporter.FlowsConfig{
+		Version:             string(exporter.FlowVersionIPFIX),
+		TemplateRefresh:     time.Minute,
+		ObservationDomainID: 7,
+		EnterpriseID:        32473,
+	}, now)
+
+	msgs := enc.Encode(now, []common.Flow{newFlow("10.0.0.1", 50001), newFlow("fd00::1", 50002)})
+	require.Len(t, msgs, 1)
+	b := msgs[0]
+	assert.Equal(t, uint16(10), binary.BigEndian.Uint16(b[0:]))
+	assert.Equal(t, uint16(len(b)), binary.BigEndian.Uint16(b[2:]))
+	assert.Equal(t, uint32(now.Unix()), binary.BigEndian.Uint32(b[4:]))
+	assert.Equal(t, uint32(0), binary.BigEndian.Uint32(b[8:]))
+	assert.Equal(t, uint32(7), binary.BigEndian.Uint32(b[12:]))
+
+	sets := parseSets(t, b[ipfixHeaderLength:])
+	require.Len(t, sets, 3)
+	assert.Equal(t, uint16(ipfixTemplateSetID), sets[0].id)
+	assert.Equal(t, uint16(templateIPv4), sets[1].id)
+	assert.Equal(t, uint16(templateIPv6), sets[2].id)
+
+	// 模板首个记录 IPv4 共 11 个字段 最后一个为企业私有字段
+	tmpl := sets[0].body
+	assert.Equal(t, uint16(templateIPv4), binary.BigEndian.Uint16(tmpl[0:]))
+	assert.Equal(t, uint16(11), binary.BigEndian.Uint16(tmpl[2:]))
+	last := tmpl[4+10*4:]
+	assert.Equal(t, uint16(elementRetransmits|0x8000), binary.BigEndian.Uint16(last[0:]))
+	assert.Equal(t, uint32(32473), binary.BigEndian.Uint32(last[4:]))
+
+	rec := sets[1].body
+	require.Len(t, rec, recordLength(enc.templates[templateIPv4]))
+	assert.Equal(t, net.ParseIP("10.0.0.1").To4(), net.IP(rec[0:4]))
+	assert.Equal(t, net.ParseIP("10.0.0.2").To4(), net.IP(rec[4:8]))
+	assert.Equal(t, uint16(50001), binary.BigEndian.Uint16(rec[8:]))
+	assert.Equal(t, uint16(3306), binary.BigEndian.Uint16(rec[10:]))
+	assert.Equal(t, uint8(6), rec[12])
+	assert.Equal(t, uint64(3456), binary.BigEndian.Uint64(rec[13:]))
+	assert.Equal(t, uint64(12), binary.BigEndian.Uint64(rec[21:]))
+	assert.Equal(t, uint64(1751356800123), binary.BigEndian.Uint64(rec[29:]))
+	assert.Equal(t, uint64(1751356801623), binary.BigEndian.Uint64(rec[37:]))
+	assert.Equal(t, "mysql", string(rec[45:50]))
+	assert.Equal(t, uint64(2), binary.BigEndian.Uint64(rec[61:]))
+
+	// 模板未到重发周期 序号为此前发送的数据记录数
+	msgs = enc.Encode(now.Add(time.Second), []common.Flow{newFlow("10.0.0.1", 50001)})
+	require.Len(t, msgs, 1)
+	assert.Equal(t, uint32(2), binary.BigEndian.Uint32(msgs[0][8:]))
+	sets = parseSets(t, msgs[0][ipfixHeaderLength:])
+	require.Len(t, sets, 1)
+	assert.Equal(t, uint16(templateIPv4), sets[0].id)
+
+	msgs = enc.Encode(now.Add(time.Minute), []common.Flow{newFlow("10.0.0.1", 50001)})
+	assert.Len(t, parseSets(t, msgs[0][ipfixHeaderLength:]), 2)
+}
+
+func TestEncodeNetFlow9(t *testing.T) {
+	started := time.UnixMilli(1751356800000)
+	now := started.Add(time.Minute)
+	enc := newEncoder(exporter.FlowsConfig{
+		Version:         string(exporter.FlowVersionNetFlow9),
+		TemplateRefresh: time.Minute,
+		EnterpriseID:    32473,
+	}, started)
+
+	flows := make([]common.Flow, 40)
+	for i := range flows {
+		flows[i] = newFlow("10.0.0.1", socket.Port(50000+i))
+	}
+	msgs := enc.Encode(now, flows)
+	require.Len(t, msgs, 2)
+
+	var records int
+	for i, b := range msgs {
+		assert.LessOrEqual(t, len(b), maxMessageSize)
+		assert.Equal(t, uint16(9), binary.BigEndian.Uint16(b[0:]))
+		assert.Equal(t, uint32(60000), binary.BigEndian.Uint32(b[4:]))
+		assert.Equal(t, uint32(i+1), binary.BigEndian.Uint32(b[12:]))
+
+		for _, set := range parseSets(t, b[v9HeaderLength:]) {
+			assert.Zero(t, (len(set.body)+setHeaderLength)%4)
+			if set.id == templateIPv4 {
+				records += len(set.body) / recordLength(enc.templates[templateIPv4])
+			}
+		}
+	}
+	assert.Equal(t, 40, records)
+
+	// NetFlow v9 没有企业字段 重传次数以私有字段类型导出 时间为相对于启动时刻的毫秒数
+	fields := enc.templates[templateIPv4]
+	require.Len(t, fields, 11)
+	assert.Equal(t, uint16(v9FieldRetransmits), fields[10].id)
+	assert.Zero(t, fields[10].enterprise)
+	rec := parseSets(t, msgs[0][v9HeaderLength:])[1].body
+	assert.Equal(t, uint32(123), binary.BigEndian.Uint32(rec[29:]))
+	assert.Equal(t, uint32(1623), binary.BigEndian.Uint32(rec[33:]))
+	assert.Equal(t, uint64(2), binary.BigEndian.Uint64(rec[53:]))
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flows
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/exporter"
+)
+
+func init() {
+	exporter.Register(common.RecordFlows, New)
+}
+
+// Sinker 以 IPFIX / NetFlow v9 格式通过 UDP 发送流记录
+type Sinker struct {
+	mut  sync.Mutex
+	conn net.Conn
+	enc  *encoder
+}
+
+func New(conf exporter.Config) (exporter.Sinker, error) {
+	cfg := conf.Flows
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial("udp", cfg.Endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "dial flows collector (%s) failed", cfg.Endpoint)
+	}
+	return &Sinker{
+		conn: conn,
+		enc:  newEncoder(cfg, time.Now()),
+	}, nil
+}
+
+func (s *Sinker) Name() common.RecordType {
+	return common.RecordFlows
+}
+
+func (s *Sinker) Sink(data any) error {
+	flows, ok := data.(*common.FlowsData)
+	if !ok || len(flows.Data) == 0 {
+		return nil
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	for _, b := range s.enc.Encode(time.Now(), flows.Data) {
+		if _, err := s.conn.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Sinker) Close() {
+	s.conn.Close()
+}
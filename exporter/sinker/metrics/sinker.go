@@ -20,7 +20,6 @@ import (
 	"net/http"
 
 	"github.com/gogo/protobuf/proto"
-	"github.com/golang/snappy"
 
 	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/exporter"
@@ -37,6 +36,7 @@ type Sinker struct {
 
 	cli *http.Client
 	cfg *exporter.MetricsConfig
+	neg *exporter.Negotiator
 }
 
 func New(conf exporter.Config) (exporter.Sinker, error) {
@@ -58,6 +58,7 @@ func New(conf exporter.Config) (exporter.Sinker, error) {
 		cancel: cancel,
 		cfg:    cfg,
 		cli:    cli,
+		neg:    exporter.NewNegotiator(cfg.Codecs()...),
 	}, nil
 }
 
@@ -76,15 +77,41 @@ func (s *Sinker) Sink(data any) error {
 		return err
 	}
 
+	for {
+		codec := s.neg.Codec()
+		rejected, err := s.send(b, codec.Compression)
+		if err != nil {
+			return err
+		}
+		if !rejected {
+			return nil
+		}
+		// 服务端不支持当前的压缩算法 降级后重新发送
+		if !s.neg.Fallback(codec) {
+			logger.Warnf("failed to sink metrics, status_code: %d", http.StatusUnsupportedMediaType)
+			return nil
+		}
+		logger.Warnf("metrics endpoint rejected compression=%s, fallback to %s", codec.Compression, s.neg.Codec().Compression)
+	}
+}
+
+// send 以 compression 压缩并发送数据 返回服务端是否以 415 拒绝了该压缩算法
+func (s *Sinker) send(b []byte, compression exporter.Compression) (bool, error) {
+	compressed, err := compression.Compress(b)
+	if err != nil {
+		return false, err
+	}
+
 	ctx, cancel := context.WithTimeout(s.ctx, s.cfg.Timeout)
 	defer cancel()
 
-	compressed := snappy.Encode(nil, b)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.Endpoint, bytes.NewBuffer(compressed))
 	if err != nil {
-		return err
+		return false, err
+	}
+	if ce := compression.ContentEncoding(); ce != "" {
+		req.Header.Add("Content-Encoding", ce)
 	}
-	req.Header.Add("Content-Encoding", "snappy")
 	req.Header.Set("Content-Type", "application/x-protobuf")
 	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
 
@@ -94,16 +121,18 @@ func (s *Sinker) Sink(data any) error {
 
 	rsp, err := s.cli.Do(req)
 	if err != nil {
-		return err
+		return false, err
 	}
+	defer rsp.Body.Close()
+	io.Copy(io.Discard, rsp.Body)
 
+	if rsp.StatusCode == http.StatusUnsupportedMediaType {
+		return true, nil
+	}
 	if rsp.StatusCode >= 400 && rsp.StatusCode < 500 {
 		logger.Warnf("failed to sink metrics, status_code: %d", rsp.StatusCode)
 	}
-
-	io.Copy(io.Discard, rsp.Body)
-	defer rsp.Body.Close()
-	return nil
+	return false, nil
 }
 
 func (s *Sinker) Close() {
@@ -37,6 +37,7 @@ type Sinker struct {
 
 	cli *http.Client
 	cfg *exporter.TracesConfig
+	neg *exporter.Negotiator
 }
 
 func New(conf exporter.Config) (exporter.Sinker, error) {
@@ -58,6 +59,7 @@ func New(conf exporter.Config) (exporter.Sinker, error) {
 		cancel: cancel,
 		cfg:    cfg,
 		cli:    cli,
+		neg:    exporter.NewNegotiator(cfg.Codecs()...),
 	}, nil
 }
 
@@ -72,9 +74,39 @@ func (s *Sinker) Sink(data any) error {
 	}
 
 	tr := ptraceotlp.NewExportRequestFromTraces(traces)
-	b, err := tr.MarshalProto()
+	for {
+		codec := s.neg.Codec()
+		rejected, err := s.send(tr, codec)
+		if err != nil {
+			return err
+		}
+		if !rejected {
+			return nil
+		}
+		// 服务端不支持当前的序列化格式或者压缩算法 降级后重新发送
+		if !s.neg.Fallback(codec) {
+			logger.Warnf("failed to sink traces, status_code: %d", http.StatusUnsupportedMediaType)
+			return nil
+		}
+		logger.Warnf("traces endpoint rejected encoding=%s compression=%s, fallback to %+v", codec.Encoding, codec.Compression, s.neg.Codec())
+	}
+}
+
+// send 以 codec 编码并发送数据 返回服务端是否以 415 拒绝了该 codec
+func (s *Sinker) send(tr ptraceotlp.ExportRequest, codec exporter.Codec) (bool, error) {
+	var b []byte
+	var err error
+	switch codec.Encoding {
+	case exporter.EncodingJSON:
+		b, err = tr.MarshalJSON()
+	default:
+		b, err = tr.MarshalProto()
+	}
 	if err != nil {
-		return err
+		return false, err
+	}
+	if b, err = codec.Compression.Compress(b); err != nil {
+		return false, err
 	}
 
 	ctx, cancel := context.WithTimeout(s.ctx, s.cfg.Timeout)
@@ -82,9 +114,12 @@ func (s *Sinker) Sink(data any) error {
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.Endpoint, bytes.NewBuffer(b))
 	if err != nil {
-		return err
+		return false, err
+	}
+	req.Header.Set("Content-Type", codec.Encoding.ContentType())
+	if ce := codec.Compression.ContentEncoding(); ce != "" {
+		req.Header.Set("Content-Encoding", ce)
 	}
-	req.Header.Set("Content-Type", "application/x-protobuf")
 
 	for k, v := range s.cfg.Header {
 		req.Header.Add(k, v)
@@ -92,15 +127,18 @@ func (s *Sinker) Sink(data any) error {
 
 	rsp, err := s.cli.Do(req)
 	if err != nil {
-		return err
+		return false, err
 	}
+	defer rsp.Body.Close()
+	io.Copy(io.Discard, rsp.Body)
 
+	if rsp.StatusCode == http.StatusUnsupportedMediaType {
+		return true, nil
+	}
 	if rsp.StatusCode >= 400 && rsp.StatusCode < 500 {
 		logger.Warnf("failed to sink traces, status_code: %d", rsp.StatusCode)
 	}
-
-	io.Copy(io.Discard, rsp.Body)
-	return rsp.Body.Close()
+	return false, nil
 }
 
 func (s *Sinker) Close() {
@@ -0,0 +1,22 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package integration 端到端集成测试
+//
+// 使用容器启动真实的服务端 并使用官方客户端产生流量 在 loopback 网卡上抓包解析后校验输出的 RoundTrip
+// 手工构造字节流的单元测试无法覆盖各版本协议实现的差异 集成测试用于补充这部分覆盖
+//
+// 测试依赖 docker 以及 root 权限 需使用 integration 构建标签运行
+//
+//	make integration
+package integration
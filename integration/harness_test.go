@@ -0,0 +1,342 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build integration
+
+package integration
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/confengine"
+	"github.com/packetd/packetd/controller"
+)
+
+const (
+	containerReadyTimeout = 2 * time.Minute
+	roundTripTimeout      = 30 * time.Second
+)
+
+// Rule 协议解析规则
+type Rule struct {
+	Protocol string
+	Port     int
+}
+
+// RoundTrip 为 /watch 接口输出的 RoundTrip 仅保留校验所需字段
+type RoundTrip struct {
+	Request  map[string]any
+	Response map[string]any
+	Duration string
+}
+
+// Proto 返回请求的协议名称
+func (rt RoundTrip) Proto() string {
+	s, _ := rt.Request["Proto"].(string)
+	return s
+}
+
+// Field 按路径读取请求或响应中的字段 如 Request.Packet.Statement
+func (rt RoundTrip) Field(path string) any {
+	parts := strings.Split(path, ".")
+	var curr any
+	switch parts[0] {
+	case "Request":
+		curr = rt.Request
+	case "Response":
+		curr = rt.Response
+	default:
+		return nil
+	}
+	for _, part := range parts[1:] {
+		m, ok := curr.(map[string]any)
+		if !ok {
+			return nil
+		}
+		curr = m[part]
+	}
+	return curr
+}
+
+// rules 所有测试用例使用的协议解析规则
+//
+// 监控指标等状态为进程级别 因此所有用例共享同一个 packetd 实例
+var rules = []Rule{
+	{Protocol: "mysql", Port: 3306},
+	{Protocol: "postgresql", Port: 5432},
+	{Protocol: "redis", Port: 6379},
+	{Protocol: "kafka", Port: 9092},
+	{Protocol: "mongodb", Port: 27017},
+}
+
+// Harness 在进程内启动 packetd 并收集 RoundTrip
+type Harness struct {
+	ctr    *controller.Controller
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mut sync.Mutex
+	rts []RoundTrip
+	ch  chan struct{}
+}
+
+var (
+	harnessOnce sync.Once
+	harness     *Harness
+	harnessErr  error
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if harness != nil {
+		harness.stop()
+	}
+	os.Exit(code)
+}
+
+func requireEnv(t *testing.T) {
+	t.Helper()
+	if os.Geteuid() != 0 {
+		t.Skip("integration test requires root privilege to capture packets")
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("integration test requires docker")
+	}
+}
+
+// GetHarness 返回共享的 packetd 实例 首次调用时启动
+func GetHarness(t *testing.T) *Harness {
+	t.Helper()
+	requireEnv(t)
+
+	harnessOnce.Do(func() {
+		harness, harnessErr = startHarness(rules)
+	})
+	require.NoError(t, harnessErr)
+	return harness
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func startHarness(rules []Rule) (*Harness, error) {
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+
+	var sb strings.Builder
+	sb.WriteString("logger.stdout: true\n")
+	sb.WriteString("logger.level: warn\n")
+	sb.WriteString("sniffer.ifaces: 'lo'\n")
+	sb.WriteString("sniffer.protocols:\n  rules:\n")
+	for _, rule := range rules {
+		fmt.Fprintf(&sb, "    - name: %q\n      protocol: %q\n      ports: [%d]\n", rule.Protocol, rule.Protocol, rule.Port)
+	}
+	sb.WriteString("server.enabled: true\n")
+	fmt.Fprintf(&sb, "server.address: %q\n", addr)
+
+	conf, err := confengine.LoadContent([]byte(sb.String()))
+	if err != nil {
+		return nil, err
+	}
+	ctr, err := controller.New(conf, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := ctr.Start(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Harness{
+		ctr:    ctr,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		ch:     make(chan struct{}, 1),
+	}
+
+	ready := make(chan struct{})
+	go func() {
+		defer close(h.done)
+		h.watch(ctx, addr, ready)
+	}()
+
+	select {
+	case <-ready:
+		return h, nil
+	case <-time.After(10 * time.Second):
+		h.stop()
+		return nil, errors.Errorf("watch server %s not ready", addr)
+	}
+}
+
+func (h *Harness) stop() {
+	h.cancel()
+	<-h.done
+	h.ctr.Stop()
+}
+
+// watch 持续订阅 /watch 接口 断开后自动重连直至 ctx 取消
+func (h *Harness) watch(ctx context.Context, addr string, ready chan struct{}) {
+	var once sync.Once
+	url := fmt.Sprintf("http://%s/watch?max_message=1000000&timeout=1h", addr)
+	for ctx.Err() == nil {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		rsp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		once.Do(func() { close(ready) })
+
+		scanner := bufio.NewScanner(rsp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var rt RoundTrip
+			if err := json.Unmarshal(scanner.Bytes(), &rt); err != nil {
+				continue
+			}
+			h.mut.Lock()
+			h.rts = append(h.rts, rt)
+			h.mut.Unlock()
+			select {
+			case h.ch <- struct{}{}:
+			default:
+			}
+		}
+		rsp.Body.Close()
+	}
+}
+
+// WaitRoundTrip 等待直至出现满足 match 的 RoundTrip
+func (h *Harness) WaitRoundTrip(t *testing.T, match func(rt RoundTrip) bool) RoundTrip {
+	t.Helper()
+
+	deadline := time.After(roundTripTimeout)
+	for {
+		h.mut.Lock()
+		for _, rt := range h.rts {
+			if match(rt) {
+				h.mut.Unlock()
+				return rt
+			}
+		}
+		n := len(h.rts)
+		h.mut.Unlock()
+
+		select {
+		case <-h.ch:
+		case <-deadline:
+			t.Fatalf("no matched roundtrip within %s (%d collected)", roundTripTimeout, n)
+		}
+	}
+}
+
+// Container 测试使用的容器
+//
+// 容器使用 host 网络 客户端流量经由 loopback 网卡 可被 packetd 直接抓取
+type Container struct {
+	t    *testing.T
+	name string
+}
+
+// RunContainer 启动容器并等待端口可连接 测试结束时自动删除
+func RunContainer(t *testing.T, image string, port int, env map[string]string, args ...string) *Container {
+	t.Helper()
+
+	name := fmt.Sprintf("packetd-it-%s-%d", strings.ToLower(t.Name()), time.Now().UnixNano())
+	name = strings.NewReplacer("/", "-", "_", "-").Replace(name)
+
+	cmdArgs := []string{"run", "-d", "--rm", "--network", "host", "--name", name}
+	for k, v := range env {
+		cmdArgs = append(cmdArgs, "-e", k+"="+v)
+	}
+	cmdArgs = append(cmdArgs, image)
+	cmdArgs = append(cmdArgs, args...)
+
+	out, err := exec.Command("docker", cmdArgs...).CombinedOutput()
+	require.NoError(t, err, "docker run %s: %s", image, out)
+
+	c := &Container{t: t, name: name}
+	t.Cleanup(func() {
+		exec.Command("docker", "rm", "-f", name).Run()
+	})
+
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	deadline := time.Now().Add(containerReadyTimeout)
+	for time.Now().Before(deadline) {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			conn.Close()
+			return c
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	t.Fatalf("container %s (%s) not ready on %s", name, image, addr)
+	return nil
+}
+
+// Exec 在容器内执行命令 用于运行镜像内置的官方客户端
+func (c *Container) Exec(args ...string) string {
+	c.t.Helper()
+	out, err := c.exec(args...)
+	require.NoError(c.t, err, "docker exec %v: %s", args, out)
+	return out
+}
+
+// ExecRetry 重复执行命令直至成功 用于等待服务端完成初始化
+//
+// 端口可连接时部分服务端仍在初始化 此时客户端请求会失败
+func (c *Container) ExecRetry(args ...string) string {
+	c.t.Helper()
+
+	var out string
+	var err error
+	deadline := time.Now().Add(containerReadyTimeout)
+	for time.Now().Before(deadline) {
+		if out, err = c.exec(args...); err == nil {
+			return out
+		}
+		time.Sleep(time.Second)
+	}
+	c.t.Fatalf("docker exec %v: %v: %s", args, err, out)
+	return ""
+}
+
+func (c *Container) exec(args ...string) (string, error) {
+	cmdArgs := append([]string{"exec", c.name}, args...)
+	out, err := exec.Command("docker", cmdArgs...).CombinedOutput()
+	return string(out), err
+}
@@ -0,0 +1,41 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build integration
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKafka(t *testing.T) {
+	h := GetHarness(t)
+	c := RunContainer(t, "apache/kafka:3.7.0", 9092, nil)
+
+	bin := "/opt/kafka/bin/"
+	c.ExecRetry(bin+"kafka-topics.sh", "--bootstrap-server", "127.0.0.1:9092", "--create", "--if-not-exists", "--topic", "packetd")
+	c.Exec("bash", "-c", "echo packetd-integration | "+bin+"kafka-console-producer.sh --bootstrap-server 127.0.0.1:9092 --topic packetd")
+
+	tests := []string{"Metadata", "Produce"}
+	for _, api := range tests {
+		t.Run(api, func(t *testing.T) {
+			rt := h.WaitRoundTrip(t, func(rt RoundTrip) bool {
+				return rt.Proto() == "Kafka" && rt.Field("Request.Packet.API") == api
+			})
+			assert.Equal(t, "NoError", rt.Field("Response.ErrorCode"))
+		})
+	}
+}
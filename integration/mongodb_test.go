@@ -0,0 +1,37 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build integration
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMongoDB(t *testing.T) {
+	h := GetHarness(t)
+	c := RunContainer(t, "mongo:7", 27017, nil)
+
+	mongosh := []string{"mongosh", "--quiet", "mongodb://127.0.0.1:27017/packetd", "--eval"}
+	c.ExecRetry(append(mongosh, "db.runCommand({ping: 1})")...)
+	c.Exec(append(mongosh, "db.integration.insertOne({name: 'packetd'})")...)
+
+	rt := h.WaitRoundTrip(t, func(rt RoundTrip) bool {
+		return rt.Proto() == "MongoDB" && rt.Field("Request.CmdName") == "insert"
+	})
+	assert.Equal(t, "integration", rt.Field("Request.CmdValue"))
+	assert.Equal(t, "MSG", rt.Field("Response.OpCode"))
+}
@@ -0,0 +1,40 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build integration
+
+package integration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMySQL(t *testing.T) {
+	h := GetHarness(t)
+	c := RunContainer(t, "mysql:8.0", 3306, map[string]string{"MYSQL_ROOT_PASSWORD": "packetd"})
+
+	// 使用 TCP 连接 避免客户端默认通过 unix socket 访问
+	mysql := []string{"mysql", "--protocol=TCP", "-h127.0.0.1", "-P3306", "-uroot", "-ppacketd", "-e"}
+	c.ExecRetry(append(mysql, "SELECT 1")...)
+	c.Exec(append(mysql, "SELECT 'packetd-integration'")...)
+
+	rt := h.WaitRoundTrip(t, func(rt RoundTrip) bool {
+		stmt, _ := rt.Field("Request.Statement").(string)
+		return rt.Proto() == "MySQL" && strings.Contains(stmt, "packetd-integration")
+	})
+	assert.Equal(t, "QUERY", rt.Field("Request.Command"))
+	assert.EqualValues(t, 1, rt.Field("Response.Packet.Rows"))
+}
@@ -0,0 +1,39 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build integration
+
+package integration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgreSQL(t *testing.T) {
+	h := GetHarness(t)
+	c := RunContainer(t, "postgres:16", 5432, map[string]string{"POSTGRES_PASSWORD": "packetd"})
+
+	psql := []string{"psql", "-h", "127.0.0.1", "-U", "postgres", "-c"}
+	c.ExecRetry(append(psql, "SELECT 1")...)
+	c.Exec(append(psql, "SELECT 'packetd-integration'")...)
+
+	rt := h.WaitRoundTrip(t, func(rt RoundTrip) bool {
+		stmt, _ := rt.Field("Request.Packet.Statement").(string)
+		return rt.Proto() == "PostgreSQL" && strings.Contains(stmt, "packetd-integration")
+	})
+	assert.Equal(t, "SELECT", rt.Field("Response.Packet.Command"))
+	assert.EqualValues(t, 1, rt.Field("Response.Packet.Rows"))
+}
@@ -0,0 +1,47 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build integration
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedis(t *testing.T) {
+	h := GetHarness(t)
+	c := RunContainer(t, "redis:7", 6379, nil)
+
+	cli := []string{"redis-cli", "-h", "127.0.0.1", "-p", "6379"}
+	c.Exec(append(cli, "SET", "packetd", "integration")...)
+	c.Exec(append(cli, "GET", "packetd")...)
+
+	tests := []struct {
+		command  string
+		dataType string
+	}{
+		{command: "SET", dataType: "SimpleStrings"},
+		{command: "GET", dataType: "BulkStrings"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			rt := h.WaitRoundTrip(t, func(rt RoundTrip) bool {
+				return rt.Proto() == "Redis" && rt.Field("Request.Command") == tt.command
+			})
+			assert.Equal(t, tt.dataType, rt.Field("Response.DataType"))
+		})
+	}
+}
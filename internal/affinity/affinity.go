@@ -0,0 +1,120 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package affinity
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// specNUMA 使用网卡所在 NUMA 节点的 CPU
+	specNUMA = "numa"
+
+	// specNUMAPrefix 使用指定 NUMA 节点的 CPU 如 numa:1
+	specNUMAPrefix = "numa:"
+)
+
+// sysfsRoot sysfs 挂载路径 单测时替换
+var sysfsRoot = "/sys"
+
+// Resolve 解析 CPU 绑定声明 返回需要绑定的 CPU 列表 返回空列表表示不绑定
+//
+// spec 支持以下格式
+// - 空值: 不绑定
+// - numa: 网卡 iface 所在 NUMA 节点的 CPU 无法获取网卡所在节点时不绑定
+// - numa:N: NUMA 节点 N 的 CPU
+// - cpulist: 与 taskset -c 格式一致 如 0-3,8,10-11
+func Resolve(spec, iface string) ([]int, error) {
+	spec = strings.TrimSpace(spec)
+	switch {
+	case spec == "":
+		return nil, nil
+
+	case spec == specNUMA:
+		if iface == "" {
+			return nil, nil
+		}
+		node, err := IfaceNode(iface)
+		if err != nil || node < 0 {
+			return nil, nil
+		}
+		return NodeCPUs(node)
+
+	case strings.HasPrefix(spec, specNUMAPrefix):
+		node, err := strconv.Atoi(strings.TrimPrefix(spec, specNUMAPrefix))
+		if err != nil || node < 0 {
+			return nil, errors.Errorf("invalid numa node (%s)", spec)
+		}
+		return NodeCPUs(node)
+	}
+	return ParseCPUList(spec)
+}
+
+// IfaceNode 返回网卡所在的 NUMA 节点 未知时返回 -1
+func IfaceNode(iface string) (int, error) {
+	b, err := os.ReadFile(filepath.Join(sysfsRoot, "class/net", iface, "device/numa_node"))
+	if err != nil {
+		return -1, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(b)))
+}
+
+// NodeCPUs 返回 NUMA 节点上的 CPU 列表
+func NodeCPUs(node int) ([]int, error) {
+	b, err := os.ReadFile(filepath.Join(sysfsRoot, "devices/system/node", "node"+strconv.Itoa(node), "cpulist"))
+	if err != nil {
+		return nil, err
+	}
+	return ParseCPUList(string(b))
+}
+
+// ParseCPUList 解析 cpulist 格式的 CPU 列表 返回结果去重且有序
+func ParseCPUList(s string) ([]int, error) {
+	set := make(map[int]struct{})
+	for _, part := range strings.Split(strings.TrimSpace(s), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		lo, hi, found := strings.Cut(part, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil || start < 0 {
+			return nil, errors.Errorf("invalid cpulist (%s)", s)
+		}
+		end := start
+		if found {
+			end, err = strconv.Atoi(hi)
+			if err != nil || end < start {
+				return nil, errors.Errorf("invalid cpulist (%s)", s)
+			}
+		}
+		for i := start; i <= end; i++ {
+			set[i] = struct{}{}
+		}
+	}
+
+	cpus := make([]int, 0, len(set))
+	for cpu := range set {
+		cpus = append(cpus, cpu)
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
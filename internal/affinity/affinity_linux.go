@@ -0,0 +1,43 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package affinity
+
+import (
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// Pin 将当前 goroutine 固定在系统线程上 并将该线程绑定至 cpus
+//
+// 调用方需保证在 goroutine 结束前不再切换 cpus 为空时不做任何处理
+func Pin(cpus []int) error {
+	if len(cpus) == 0 {
+		return nil
+	}
+
+	var set unix.CPUSet
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+
+	runtime.LockOSThread()
+	if err := unix.SchedSetaffinity(0, &set); err != nil {
+		runtime.UnlockOSThread()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+
+package affinity
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Pin 非 linux 平台不支持绑定 CPU
+func Pin(cpus []int) error {
+	if len(cpus) == 0 {
+		return nil
+	}
+	return errors.New("cpu affinity not supported on this platform")
+}
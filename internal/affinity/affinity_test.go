@@ -0,0 +1,91 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package affinity
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []int
+		err   bool
+	}{
+		{input: "0", want: []int{0}},
+		{input: "0-3", want: []int{0, 1, 2, 3}},
+		{input: "8,0-1,1\n", want: []int{0, 1, 8}},
+		{input: "3-1", err: true},
+		{input: "a", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			cpus, err := ParseCPUList(tt.input)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, cpus)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	root := t.TempDir()
+	write := func(path, content string) {
+		path = filepath.Join(root, path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	write("class/net/eth0/device/numa_node", "1\n")
+	write("class/net/eth1/device/numa_node", "-1\n")
+	write("devices/system/node/node1/cpulist", "8-11\n")
+
+	prev := sysfsRoot
+	sysfsRoot = root
+	defer func() { sysfsRoot = prev }()
+
+	cpus, err := Resolve("", "eth0")
+	assert.NoError(t, err)
+	assert.Empty(t, cpus)
+
+	cpus, err = Resolve("numa", "eth0")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{8, 9, 10, 11}, cpus)
+
+	// 网卡未关联 NUMA 节点或者网卡不存在时不绑定
+	cpus, err = Resolve("numa", "eth1")
+	assert.NoError(t, err)
+	assert.Empty(t, cpus)
+	cpus, err = Resolve("numa", "any")
+	assert.NoError(t, err)
+	assert.Empty(t, cpus)
+
+	cpus, err = Resolve("numa:1", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{8, 9, 10, 11}, cpus)
+
+	_, err = Resolve("numa:x", "")
+	assert.Error(t, err)
+
+	cpus, err = Resolve("2,4", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 4}, cpus)
+}
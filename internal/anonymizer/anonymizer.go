@@ -0,0 +1,320 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package anonymizer 在数据导出前对客户端 IP 进行匿名化处理
+//
+// 支持三种模式：truncate 将低位清零 hash 使用加盐的 HMAC-SHA256 计算假名 drop 直接置空
+// 同一个 IP 在所有数据类型中的处理结果一致 指标维度 Span 属性以及 roundtrips 之间仍可相互关联
+package anonymizer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/prober"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/pamqp"
+	"github.com/packetd/packetd/protocol/pdns"
+	"github.com/packetd/packetd/protocol/pgrpc"
+	"github.com/packetd/packetd/protocol/phttp"
+	"github.com/packetd/packetd/protocol/phttp2"
+	"github.com/packetd/packetd/protocol/pkafka"
+	"github.com/packetd/packetd/protocol/pmongodb"
+	"github.com/packetd/packetd/protocol/pmysql"
+	"github.com/packetd/packetd/protocol/pntp"
+	"github.com/packetd/packetd/protocol/ppostgresql"
+	"github.com/packetd/packetd/protocol/predis"
+	"github.com/packetd/packetd/protocol/ptls"
+	"github.com/packetd/packetd/protocol/pudprpc"
+)
+
+const (
+	ModeNone     = ""
+	ModeTruncate = "truncate"
+	ModeHash     = "hash"
+	ModeDrop     = "drop"
+)
+
+const (
+	defaultIPv4PrefixBits = 24
+	defaultIPv6PrefixBits = 48
+)
+
+// defaultHeaders 默认处理的 HTTP Header 均为代理层写入的真实客户端地址
+var defaultHeaders = []string{"X-Forwarded-For", "X-Real-Ip", "Forwarded", "True-Client-Ip", "Cf-Connecting-Ip"}
+
+// Config 客户端 IP 匿名化配置
+type Config struct {
+	// Mode 匿名化模式 为空时不处理
+	Mode string `config:"mode"`
+
+	// IPv4PrefixBits / IPv6PrefixBits truncate 模式下保留的前缀长度
+	IPv4PrefixBits int `config:"ipv4PrefixBits"`
+	IPv6PrefixBits int `config:"ipv6PrefixBits"`
+
+	// Salt hash 模式下的密钥 IPv4 地址空间有限 不加盐的哈希可以被穷举还原
+	Salt string `config:"salt"`
+
+	// Headers 同样需要处理的 HTTP Header 为空时使用 defaultHeaders
+	Headers []string `config:"headers"`
+}
+
+// Validate 校验并填充默认值
+func (c *Config) Validate() error {
+	switch c.Mode {
+	case ModeNone, ModeDrop:
+	case ModeTruncate:
+		if c.IPv4PrefixBits <= 0 {
+			c.IPv4PrefixBits = defaultIPv4PrefixBits
+		}
+		if c.IPv6PrefixBits <= 0 {
+			c.IPv6PrefixBits = defaultIPv6PrefixBits
+		}
+		if c.IPv4PrefixBits > 32 || c.IPv6PrefixBits > 128 {
+			return errors.Errorf("invalid prefix bits ipv4=%d ipv6=%d", c.IPv4PrefixBits, c.IPv6PrefixBits)
+		}
+	case ModeHash:
+		if c.Salt == "" {
+			return errors.New("hash mode requires salt")
+		}
+	default:
+		return errors.Errorf("unsupported anonymize mode '%s'", c.Mode)
+	}
+
+	if len(c.Headers) == 0 {
+		c.Headers = defaultHeaders
+	}
+	return nil
+}
+
+// Anonymizer 客户端 IP 匿名化处理器 nil 值代表未开启 所有方法均可安全调用
+type Anonymizer struct {
+	mode    string
+	v4Bits  int
+	v6Bits  int
+	salt    []byte
+	headers []string
+}
+
+// New 创建 Anonymizer 实例 未开启时返回 nil
+func New(conf Config) (*Anonymizer, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	if conf.Mode == ModeNone {
+		return nil, nil
+	}
+
+	headers := make([]string, 0, len(conf.Headers))
+	for _, h := range conf.Headers {
+		headers = append(headers, http.CanonicalHeaderKey(h))
+	}
+	return &Anonymizer{
+		mode:    conf.Mode,
+		v4Bits:  conf.IPv4PrefixBits,
+		v6Bits:  conf.IPv6PrefixBits,
+		salt:    []byte(conf.Salt),
+		headers: headers,
+	}, nil
+}
+
+// IP 返回 s 匿名化后的结果 truncate 模式下无法解析的地址原样返回
+func (a *Anonymizer) IP(s string) string {
+	if a == nil || s == "" {
+		return s
+	}
+
+	if a.mode == ModeDrop {
+		return ""
+	}
+
+	// 统一地址的文本表示 避免同一地址因写法不同（如 IPv4-mapped IPv6）得到不同的结果
+	addr, err := netip.ParseAddr(s)
+	if err == nil {
+		addr = addr.Unmap()
+		s = addr.String()
+	}
+
+	if a.mode == ModeHash {
+		return hex.EncodeToString(a.sum(s)[:8])
+	}
+	if err != nil {
+		return s
+	}
+
+	bits := a.v6Bits
+	if addr.Is4() {
+		bits = a.v4Bits
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return s
+	}
+	return prefix.Addr().String()
+}
+
+// Addr 返回 ip 匿名化后的结果 用于流统计等以二进制形式导出地址的场景
+//
+// 结果与 IP 保持同一地址族：truncate 模式与 IP 一致 hash 模式取 HMAC 的前 4 / 16 字节作为地址 drop 模式为全零地址
+func (a *Anonymizer) Addr(ip socket.IPV) socket.IPV {
+	if a == nil {
+		return ip
+	}
+
+	dst := socket.IPV{Version: ip.Version}
+	n := net.IPv6len
+	if ip.Version == socket.V4 {
+		n = net.IPv4len
+	}
+
+	switch a.mode {
+	case ModeHash:
+		copy(dst.IP[:n], a.sum(ip.String()))
+	case ModeTruncate:
+		addr, ok := netip.AddrFromSlice(ip.NetIP())
+		if !ok {
+			return dst
+		}
+		bits := a.v6Bits
+		if addr.Is4() {
+			bits = a.v4Bits
+		}
+		if prefix, err := addr.Prefix(bits); err == nil {
+			copy(dst.IP[:n], prefix.Addr().AsSlice())
+		}
+	}
+	return dst
+}
+
+func (a *Anonymizer) sum(s string) []byte {
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(s))
+	return mac.Sum(nil)
+}
+
+// RoundTrip 原地处理 roundtrip 中的客户端地址
+//
+// 所有协议的 Request.Host 即客户端地址 Response.Host 以及单向事件的 ServerHost 为服务端地址不做处理
+// HTTP 请求解析出的 ClientIP 字段同样属于客户端地址
+// HTTP/HTTP2 的 Header 以及 gRPC 的 Metadata 同时处理代理写入的客户端地址 Header
+func (a *Anonymizer) RoundTrip(rt socket.RoundTrip) {
+	if a == nil || rt == nil {
+		return
+	}
+
+	switch req := rt.Request().(type) {
+	case *phttp.Request:
+		req.Host = a.IP(req.Host)
+		req.ClientIP = a.IP(req.ClientIP)
+		a.Header(req.Header)
+	case *phttp2.Request:
+		req.Host = a.IP(req.Host)
+		a.Header(req.Header)
+	case *pgrpc.Request:
+		req.Host = a.IP(req.Host)
+		a.Header(req.Metadata)
+	case *pmysql.Request:
+		req.Host = a.IP(req.Host)
+	case *ppostgresql.Request:
+		req.Host = a.IP(req.Host)
+	case *predis.Request:
+		req.Host = a.IP(req.Host)
+	case *pkafka.Request:
+		req.Host = a.IP(req.Host)
+	case *pmongodb.Request:
+		req.Host = a.IP(req.Host)
+	case *pamqp.Request:
+		req.Host = a.IP(req.Host)
+	case *pdns.Request:
+		req.Host = a.IP(req.Host)
+	case *pntp.Request:
+		req.Host = a.IP(req.Host)
+	case *ptls.Request:
+		req.Host = a.IP(req.Host)
+	case *pudprpc.Request:
+		req.Host = a.IP(req.Host)
+	case *protocol.TruncatedMessage:
+		req.Host = a.IP(req.Host)
+	case *prober.Request:
+		req.Host = a.IP(req.Host)
+	}
+}
+
+// Header 原地处理 Header 中的客户端地址
+func (a *Anonymizer) Header(h http.Header) {
+	if a == nil || h == nil {
+		return
+	}
+	for _, k := range a.headers {
+		if a.mode == ModeDrop {
+			h.Del(k)
+			continue
+		}
+		values := h[k]
+		for i, v := range values {
+			if k == "Forwarded" {
+				values[i] = a.forwarded(v)
+			} else {
+				values[i] = a.ipList(v)
+			}
+		}
+	}
+}
+
+// ipList 处理以逗号分隔的地址列表 如 X-Forwarded-For: 203.0.113.7, 10.0.0.1
+func (a *Anonymizer) ipList(v string) string {
+	items := strings.Split(v, ",")
+	for i, item := range items {
+		items[i] = a.node(strings.TrimSpace(item))
+	}
+	return strings.Join(items, ", ")
+}
+
+// forwarded 处理 RFC 7239 Forwarded Header 中的 for 参数 如 for="[2001:db8::1]:4711";proto=https
+func (a *Anonymizer) forwarded(v string) string {
+	elems := strings.Split(v, ",")
+	for i, elem := range elems {
+		pairs := strings.Split(strings.TrimSpace(elem), ";")
+		for j, pair := range pairs {
+			k, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || !strings.EqualFold(k, "for") {
+				continue
+			}
+			node := a.node(strings.Trim(val, `"`))
+			pairs[j] = k + `="` + node + `"`
+		}
+		elems[i] = strings.Join(pairs, ";")
+	}
+	return strings.Join(elems, ", ")
+}
+
+// node 处理单个地址 支持携带端口的形式 端口信息直接丢弃
+func (a *Anonymizer) node(s string) string {
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		s = ap.Addr().String()
+	} else {
+		s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	}
+	if _, err := netip.ParseAddr(s); err != nil {
+		return s // unknown / 混淆标识（如 _hidden）等非地址内容
+	}
+	return a.IP(s)
+}
@@ -0,0 +1,182 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anonymizer
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol/pgrpc"
+	"github.com/packetd/packetd/protocol/phttp"
+	"github.com/packetd/packetd/protocol/pkafka"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		conf Config
+		err  bool
+	}{
+		{name: "None", conf: Config{}},
+		{name: "Truncate", conf: Config{Mode: ModeTruncate}},
+		{name: "TruncateInvalidBits", conf: Config{Mode: ModeTruncate, IPv4PrefixBits: 33}, err: true},
+		{name: "HashWithoutSalt", conf: Config{Mode: ModeHash}, err: true},
+		{name: "Hash", conf: Config{Mode: ModeHash, Salt: "s"}},
+		{name: "Drop", conf: Config{Mode: ModeDrop}},
+		{name: "Unknown", conf: Config{Mode: "mask"}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.conf.Validate()
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotEmpty(t, tt.conf.Headers)
+		})
+	}
+}
+
+func TestNewDisabled(t *testing.T) {
+	a, err := New(Config{})
+	require.NoError(t, err)
+	assert.Nil(t, a)
+	assert.Equal(t, "10.0.0.1", a.IP("10.0.0.1"))
+}
+
+func TestIP(t *testing.T) {
+	truncate, err := New(Config{Mode: ModeTruncate})
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.1.0", truncate.IP("192.168.1.77"))
+	assert.Equal(t, "192.168.1.0", truncate.IP("::ffff:192.168.1.77"))
+	assert.Equal(t, "2001:db8:1::", truncate.IP("2001:db8:1:2::1"))
+	assert.Equal(t, "not-an-ip", truncate.IP("not-an-ip"))
+
+	truncate16, err := New(Config{Mode: ModeTruncate, IPv4PrefixBits: 16})
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.0.0", truncate16.IP("192.168.1.77"))
+
+	hash, err := New(Config{Mode: ModeHash, Salt: "salt"})
+	require.NoError(t, err)
+	h := hash.IP("10.0.0.1")
+	assert.Len(t, h, 16)
+	assert.Equal(t, h, hash.IP("10.0.0.1"))
+	assert.Equal(t, h, hash.IP("::ffff:10.0.0.1"))
+	assert.NotEqual(t, h, hash.IP("10.0.0.2"))
+
+	other, err := New(Config{Mode: ModeHash, Salt: "pepper"})
+	require.NoError(t, err)
+	assert.NotEqual(t, h, other.IP("10.0.0.1"))
+
+	drop, err := New(Config{Mode: ModeDrop})
+	require.NoError(t, err)
+	assert.Equal(t, "", drop.IP("10.0.0.1"))
+}
+
+func TestHeader(t *testing.T) {
+	a, err := New(Config{Mode: ModeTruncate})
+	require.NoError(t, err)
+
+	h := http.Header{
+		"X-Forwarded-For": {"203.0.113.7, 10.0.0.1:8080"},
+		"Forwarded":       {`for="[2001:db8:cafe::17]:4711";proto=https, for=unknown`},
+		"User-Agent":      {"curl/8.0"},
+	}
+	a.Header(h)
+	assert.Equal(t, "203.0.113.0, 10.0.0.0", h.Get("X-Forwarded-For"))
+	assert.Equal(t, `for="2001:db8:cafe::";proto=https, for="unknown"`, h.Get("Forwarded"))
+	assert.Equal(t, "curl/8.0", h.Get("User-Agent"))
+
+	drop, err := New(Config{Mode: ModeDrop})
+	require.NoError(t, err)
+	drop.Header(h)
+	assert.Empty(t, h.Get("X-Forwarded-For"))
+	assert.Empty(t, h.Get("Forwarded"))
+	assert.Equal(t, "curl/8.0", h.Get("User-Agent"))
+}
+
+type testRoundTrip struct {
+	req any
+	rsp any
+}
+
+func (rt testRoundTrip) Proto() socket.L7Proto   { return socket.L7ProtoHTTP }
+func (rt testRoundTrip) Request() any            { return rt.req }
+func (rt testRoundTrip) Response() any           { return rt.rsp }
+func (rt testRoundTrip) Duration() time.Duration { return 0 }
+func (rt testRoundTrip) Validate() bool          { return true }
+
+func TestRoundTrip(t *testing.T) {
+	a, err := New(Config{Mode: ModeTruncate})
+	require.NoError(t, err)
+
+	t.Run("HTTP", func(t *testing.T) {
+		req := &phttp.Request{Host: "10.1.2.3", Port: 50001, ClientIP: "198.51.100.9", Header: http.Header{"X-Real-Ip": {"198.51.100.9"}}}
+		rsp := &phttp.Response{Host: "10.9.9.9", Port: 80}
+		a.RoundTrip(testRoundTrip{req: req, rsp: rsp})
+		assert.Equal(t, "10.1.2.0", req.Host)
+		assert.Equal(t, uint16(50001), req.Port)
+		assert.Equal(t, "198.51.100.0", req.ClientIP)
+		assert.Equal(t, "198.51.100.0", req.Header.Get("X-Real-Ip"))
+		assert.Equal(t, "10.9.9.9", rsp.Host)
+	})
+
+	t.Run("GRPC", func(t *testing.T) {
+		req := &pgrpc.Request{Host: "10.1.2.3", Metadata: http.Header{"X-Forwarded-For": {"198.51.100.9"}}}
+		a.RoundTrip(testRoundTrip{req: req})
+		assert.Equal(t, "10.1.2.0", req.Host)
+		assert.Equal(t, "198.51.100.0", req.Metadata.Get("X-Forwarded-For"))
+	})
+
+	t.Run("Kafka", func(t *testing.T) {
+		req := &pkafka.Request{Host: "10.1.2.3", ServerHost: "10.9.9.9"}
+		a.RoundTrip(testRoundTrip{req: req})
+		assert.Equal(t, "10.1.2.0", req.Host)
+		assert.Equal(t, "10.9.9.9", req.ServerHost)
+	})
+
+	// 单向事件 / 未知类型
+	a.RoundTrip(testRoundTrip{})
+	a.RoundTrip(nil)
+}
+
+func TestAddr(t *testing.T) {
+	v4 := socket.ToIPV4(net.ParseIP("10.1.2.3").To4())
+	v6 := socket.ToIPV6(net.ParseIP("2001:db8::1"))
+
+	truncate, err := New(Config{Mode: ModeTruncate})
+	require.NoError(t, err)
+	assert.Equal(t, "10.1.2.0", truncate.Addr(v4).String())
+	assert.Equal(t, "2001:db8::", truncate.Addr(v6).String())
+
+	hash, err := New(Config{Mode: ModeHash, Salt: "s"})
+	require.NoError(t, err)
+	assert.Equal(t, hash.Addr(v4), hash.Addr(v4))
+	assert.NotEqual(t, v4, hash.Addr(v4))
+	assert.Equal(t, socket.V6, hash.Addr(v6).Version)
+
+	drop, err := New(Config{Mode: ModeDrop})
+	require.NoError(t, err)
+	assert.Equal(t, "0.0.0.0", drop.Addr(v4).String())
+
+	var disabled *Anonymizer
+	assert.Equal(t, v4, disabled.Addr(v4))
+}
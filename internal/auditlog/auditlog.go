@@ -0,0 +1,98 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auditlog
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ResultSuccess = "success"
+	ResultFailed  = "failed"
+)
+
+// Entry 单条审计记录
+//
+// Actor 为操作发起方 管理接口为客户端地址（附带 operator 标识） 内部触发时为触发源名称
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor"`
+	Action string    `json:"action"`
+	Detail string    `json:"detail,omitempty"`
+	Result string    `json:"result"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Logger 只追加写入的审计日志 每行一条 JSON 记录
+//
+// 审计日志不做轮转与清理 归档由外部系统负责 每次写入后立即刷盘 避免进程异常退出时丢失记录
+type Logger struct {
+	mut sync.Mutex
+	f   *os.File
+	now func() time.Time
+}
+
+// New 打开审计日志文件 不存在时创建
+func New(filename string) (*Logger, error) {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "open audit log (%s) failed", filename)
+	}
+	return &Logger{f: f, now: time.Now}, nil
+}
+
+// Record 写入一条审计记录 err 不为空时记录为失败
+//
+// nil Logger 直接忽略 调用方无需判断是否开启审计
+func (l *Logger) Record(actor, action, detail string, err error) error {
+	if l == nil {
+		return nil
+	}
+
+	entry := Entry{
+		Actor:  actor,
+		Action: action,
+		Detail: detail,
+		Result: ResultSuccess,
+	}
+	if err != nil {
+		entry.Result = ResultFailed
+		entry.Error = err.Error()
+	}
+
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	entry.Time = l.now()
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err = l.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return l.f.Sync()
+}
+
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.f.Close()
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auditlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readEntries(t *testing.T, filename string) []Entry {
+	f, err := os.Open(filename)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry Entry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLoggerRecord(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+	t0 := time.Date(2025, 7, 1, 8, 0, 0, 0, time.UTC)
+
+	l, err := New(filename)
+	require.NoError(t, err)
+	l.now = func() time.Time { return t0 }
+	assert.NoError(t, l.Record("127.0.0.1:50000", "logger.level", "level=debug", nil))
+	assert.NoError(t, l.Close())
+
+	// 重新打开后追加写入 不覆盖已有记录
+	l, err = New(filename)
+	require.NoError(t, err)
+	l.now = func() time.Time { return t0.Add(time.Second) }
+	assert.NoError(t, l.Record("sighup", "config.reload", "", errors.New("invalid rule")))
+	assert.NoError(t, l.Close())
+
+	assert.Equal(t, []Entry{
+		{Time: t0, Actor: "127.0.0.1:50000", Action: "logger.level", Detail: "level=debug", Result: ResultSuccess},
+		{Time: t0.Add(time.Second), Actor: "sighup", Action: "config.reload", Result: ResultFailed, Error: "invalid rule"},
+	}, readEntries(t, filename))
+
+	info, err := os.Stat(filename)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestNilLogger(t *testing.T) {
+	var l *Logger
+	assert.NoError(t, l.Record("actor", "action", "", nil))
+	assert.NoError(t, l.Close())
+}
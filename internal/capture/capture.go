@@ -0,0 +1,142 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Mode 采集模式
+type Mode string
+
+const (
+	// ModeLite 按照原始配置解析 默认模式
+	ModeLite Mode = "lite"
+
+	// ModeFull 叠加完整采集配置（如 HTTP Body 捕获）解析 仅在排查问题期间开启
+	ModeFull Mode = "full"
+)
+
+// Window 每日的时间窗口 [Start, End) 以当日零点起的偏移表示
+//
+// Start 大于 End 时代表跨越零点 如 22:00-06:00
+type Window struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseWindow 解析 HH:MM-HH:MM 格式的时间窗口
+func ParseWindow(s string) (Window, error) {
+	start, end, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return Window{}, errors.Errorf("invalid capture window (%s)", s)
+	}
+
+	parse := func(v string) (time.Duration, error) {
+		t, err := time.Parse("15:04", strings.TrimSpace(v))
+		if err != nil {
+			return 0, errors.Wrapf(err, "invalid capture window (%s)", s)
+		}
+		return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+	}
+
+	var w Window
+	var err error
+	if w.Start, err = parse(start); err != nil {
+		return Window{}, err
+	}
+	if w.End, err = parse(end); err != nil {
+		return Window{}, err
+	}
+	if w.Start == w.End {
+		return Window{}, errors.Errorf("empty capture window (%s)", s)
+	}
+	return w, nil
+}
+
+// Contains 判断 t 是否位于窗口内 按照 t 所在时区计算
+func (w Window) Contains(t time.Time) bool {
+	y, m, d := t.Date()
+	offset := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// Status 采集模式状态
+//
+// Until 为按需触发的完整采集截止时间 未触发时为空
+type Status struct {
+	Mode   Mode       `json:"mode"`
+	Reason string     `json:"reason,omitempty"`
+	Until  *time.Time `json:"until,omitempty"`
+}
+
+// Scheduler 采集模式调度
+//
+// 处于任一时间窗口内或者按需触发的完整采集尚未到期时为 ModeFull 否则回退至 ModeLite
+type Scheduler struct {
+	mut     sync.Mutex
+	windows []Window
+	until   time.Time
+}
+
+// NewScheduler 创建并返回 Scheduler 实例
+func NewScheduler(windows []string) (*Scheduler, error) {
+	s := &Scheduler{}
+	for _, window := range windows {
+		w, err := ParseWindow(window)
+		if err != nil {
+			return nil, err
+		}
+		s.windows = append(s.windows, w)
+	}
+	return s, nil
+}
+
+// Trigger 从 now 开始完整采集 d 时长 d 小于等于 0 时取消按需触发
+//
+// 重复触发以最后一次为准
+func (s *Scheduler) Trigger(now time.Time, d time.Duration) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if d <= 0 {
+		s.until = time.Time{}
+		return
+	}
+	s.until = now.Add(d)
+}
+
+// Status 返回 now 时刻的采集模式
+func (s *Scheduler) Status(now time.Time) Status {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if now.Before(s.until) {
+		until := s.until
+		return Status{Mode: ModeFull, Reason: "triggered", Until: &until}
+	}
+	for _, w := range s.windows {
+		if w.Contains(now) {
+			return Status{Mode: ModeFull, Reason: "scheduled"}
+		}
+	}
+	return Status{Mode: ModeLite}
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capture
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Window
+		err   bool
+	}{
+		{input: "09:00-18:00", want: Window{Start: 9 * time.Hour, End: 18 * time.Hour}},
+		{input: " 22:30 - 06:00 ", want: Window{Start: 22*time.Hour + 30*time.Minute, End: 6 * time.Hour}},
+		{input: "09:00", err: true},
+		{input: "09:00-25:00", err: true},
+		{input: "09:00-09:00", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			w, err := ParseWindow(tt.input)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, w)
+		})
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	day := time.Date(2025, 7, 6, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		window string
+		at     time.Duration
+		want   bool
+	}{
+		{window: "09:00-18:00", at: 9 * time.Hour, want: true},
+		{window: "09:00-18:00", at: 17*time.Hour + 59*time.Minute, want: true},
+		{window: "09:00-18:00", at: 18 * time.Hour, want: false},
+		{window: "09:00-18:00", at: 8 * time.Hour, want: false},
+		{window: "22:00-06:00", at: 23 * time.Hour, want: true},
+		{window: "22:00-06:00", at: 5 * time.Hour, want: true},
+		{window: "22:00-06:00", at: 12 * time.Hour, want: false},
+	}
+
+	for _, tt := range tests {
+		w, err := ParseWindow(tt.window)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, w.Contains(day.Add(tt.at)), "%s at %s", tt.window, tt.at)
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	s, err := NewScheduler([]string{"09:00-18:00"})
+	require.NoError(t, err)
+
+	night := time.Date(2025, 7, 6, 20, 0, 0, 0, time.UTC)
+	assert.Equal(t, Status{Mode: ModeLite}, s.Status(night))
+	assert.Equal(t, Status{Mode: ModeFull, Reason: "scheduled"}, s.Status(night.Add(-10*time.Hour)))
+
+	s.Trigger(night, 10*time.Minute)
+	status := s.Status(night.Add(5 * time.Minute))
+	assert.Equal(t, ModeFull, status.Mode)
+	assert.Equal(t, "triggered", status.Reason)
+	assert.Equal(t, night.Add(10*time.Minute), *status.Until)
+
+	// 到期后自动回退
+	assert.Equal(t, ModeLite, s.Status(night.Add(10*time.Minute)).Mode)
+
+	s.Trigger(night, 10*time.Minute)
+	s.Trigger(night, 0)
+	assert.Equal(t, ModeLite, s.Status(night.Add(time.Minute)).Mode)
+
+	_, err = NewScheduler([]string{"invalid"})
+	assert.Error(t, err)
+}
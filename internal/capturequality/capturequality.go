@@ -0,0 +1,105 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capturequality
+
+import (
+	"sync"
+	"time"
+)
+
+// Config 采集质量评估配置
+type Config struct {
+	Enabled  bool          `config:"enabled"`
+	Interval time.Duration `config:"interval"`
+}
+
+// GetInterval 返回丢包计数的采样周期 默认为 1s
+func (c Config) GetInterval() time.Duration {
+	if c.Interval <= 0 {
+		return time.Second
+	}
+	return c.Interval
+}
+
+// maxWindows 保留的丢包窗口数量 需覆盖绝大多数请求的耗时
+const maxWindows = 600
+
+// window 出现丢包的采样窗口 Quality 为窗口内成功捕获的数据包占比
+type window struct {
+	start   time.Time
+	end     time.Time
+	quality float64
+}
+
+// Tracker 根据内核丢包计数评估各时间段的采集质量
+//
+// 丢包计数只能周期性读取 无法精确到单个数据包 因此以采样窗口为粒度
+// 窗口尚未结束时到达的 RoundTrip 无法感知窗口内的丢包 标记是尽力而为的
+type Tracker struct {
+	mut     sync.RWMutex
+	last    time.Time
+	packets uint
+	drops   uint
+	windows []window
+}
+
+// New 创建 Tracker 实例
+func New() *Tracker {
+	return &Tracker{}
+}
+
+// Observe 记录 now 时刻累计的收包以及丢包数量 计数回退（如 sniffer 重载）时重新开始计算
+func (t *Tracker) Observe(now time.Time, packets, drops uint) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	prev, prevPackets, prevDrops := t.last, t.packets, t.drops
+	t.last, t.packets, t.drops = now, packets, drops
+	if prev.IsZero() || packets < prevPackets || drops < prevDrops {
+		return
+	}
+
+	dropped := drops - prevDrops
+	if dropped == 0 {
+		return
+	}
+	received := packets - prevPackets
+	if len(t.windows) >= maxWindows {
+		t.windows = t.windows[1:]
+	}
+	t.windows = append(t.windows, window{
+		start:   prev,
+		end:     now,
+		quality: float64(received) / float64(received+dropped),
+	})
+}
+
+// Quality 返回 [start, end] 期间的采集质量 取值 (0, 1] 与多个丢包窗口重叠时取最低值 未发生丢包时为 1
+func (t *Tracker) Quality(start, end time.Time) float64 {
+	t.mut.RLock()
+	defer t.mut.RUnlock()
+
+	quality := 1.0
+	for i := len(t.windows) - 1; i >= 0; i-- {
+		w := t.windows[i]
+		if w.end.Before(start) {
+			break // 窗口按时间递增 更早的窗口不再重叠
+		}
+		if w.start.After(end) {
+			continue
+		}
+		quality = min(quality, w.quality)
+	}
+	return quality
+}
@@ -0,0 +1,53 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package capturequality
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker(t *testing.T) {
+	t0 := time.Unix(1751356800, 0)
+	sec := func(n int) time.Time { return t0.Add(time.Duration(n) * time.Second) }
+
+	tracker := New()
+	tracker.Observe(sec(0), 100, 5) // 首次采样仅记录基准
+	tracker.Observe(sec(1), 200, 5)
+	tracker.Observe(sec(2), 290, 15) // 90 / 100
+	tracker.Observe(sec(3), 390, 15)
+	tracker.Observe(sec(4), 420, 85) // 30 / 100
+	tracker.Observe(sec(5), 10, 0)   // 计数回退
+	tracker.Observe(sec(6), 20, 0)
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       float64
+	}{
+		{name: "BeforeLoss", start: sec(0), end: t0.Add(900 * time.Millisecond), want: 1},
+		{name: "Overlap", start: t0.Add(1500 * time.Millisecond), end: t0.Add(2500 * time.Millisecond), want: 0.9},
+		{name: "Lowest", start: sec(1), end: sec(5), want: 0.3},
+		{name: "BetweenLoss", start: t0.Add(2100 * time.Millisecond), end: t0.Add(2900 * time.Millisecond), want: 1},
+		{name: "AfterReset", start: sec(5), end: sec(6), want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tracker.Quality(tt.start, tt.end))
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Source 时间来源
+type Source string
+
+const (
+	// SourcePacket 使用内核生成的数据包抓取时间戳
+	SourcePacket Source = "packet"
+
+	// SourceHardware 使用网卡生成的硬件时间戳 仅取与主机时钟同步的时间戳 设备不支持时等同于 SourcePacket
+	SourceHardware Source = "hardware"
+
+	// SourceSystem 使用数据包被处理时的本地时钟
+	SourceSystem Source = "system"
+)
+
+// maxClockDrift 抓取时间戳与单调时钟之间允许的最大偏差 超过即认为主机时钟发生跳变
+const maxClockDrift = time.Second
+
+// Clock 为数据包统一生成时间戳
+//
+// 所有 Decoder 均以 Clock 生成的时间作为 t0 计算耗时
+type Clock interface {
+	// Stamp 根据数据包抓取时间戳 captured 返回统一的时间
+	Stamp(captured time.Time) time.Time
+}
+
+// New 创建并返回实时抓包场景下的 Clock 实例 SourceHardware 以及非法的 Source 按照 SourcePacket 处理
+func New(source Source) Clock {
+	if source == SourceSystem {
+		return systemClock{}
+	}
+	return &liveClock{now: time.Now}
+}
+
+// NewReplay 创建并返回回放（读取 pcap 文件）场景下的 Clock 实例
+//
+// 回放时本地时钟没有任何意义 时间戳完全取自抓包文件
+func NewReplay() Clock {
+	return &replayClock{}
+}
+
+type systemClock struct{}
+
+// Stamp 返回的时间携带单调时钟读数 不受主机时钟跳变影响
+func (systemClock) Stamp(time.Time) time.Time {
+	return time.Now()
+}
+
+// liveClock 以抓取时间戳之间的差值推进单调时钟
+//
+// 抓取时间戳由内核（或网卡）基于墙上时钟生成 主机时钟跳变（NTP 校时等）时会导致耗时出现负值或者异常大值
+// 因此以首个数据包到达时的单调时钟为锚点 后续时间为锚点加上抓取时间戳的差值
+// 当抓取时间戳回退 或者抓取间隔超过 maxClockDrift 的空闲之后与单调时钟的偏差超过 maxClockDrift 时重新锚定
+//
+// 持续有流量时不重新锚定 数据包在队列中积压时单调时钟会领先抓取时间戳 此时重新锚定会把排队耗时计入时间戳
+// 主机时钟向前跳变时相邻抓取时间戳之间同样会出现超过 maxClockDrift 的间隔
+type liveClock struct {
+	mut      sync.Mutex
+	now      func() time.Time
+	anchor   time.Time // 锚定时的本地时间 携带单调时钟读数
+	captured time.Time // 锚定时的抓取时间戳
+	last     time.Time // 上一个抓取时间戳
+	stamped  time.Time // 上一个返回的时间
+}
+
+func (c *liveClock) Stamp(captured time.Time) time.Time {
+	now := c.now()
+	if captured.IsZero() {
+		return now
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.anchor.IsZero() || captured.Before(c.last) {
+		return c.reanchor(now, captured)
+	}
+
+	elapsed := captured.Sub(c.captured)
+	drift := elapsed - now.Sub(c.anchor)
+	idle := captured.Sub(c.last) >= maxClockDrift
+	if idle && (drift > maxClockDrift || drift < -maxClockDrift) {
+		return c.reanchor(now, captured)
+	}
+
+	c.last = captured
+	c.stamped = c.anchor.Add(elapsed)
+	return c.stamped
+}
+
+// reanchor 重新锚定 新的锚点不早于上一个返回的时间 保证时间不会回退
+func (c *liveClock) reanchor(now, captured time.Time) time.Time {
+	if now.Before(c.stamped) {
+		now = c.stamped
+	}
+	c.anchor = now
+	c.captured = captured
+	c.last = captured
+	c.stamped = now
+	return now
+}
+
+// replayClock 直接使用抓包文件中的时间戳 但保证时间不会回退
+type replayClock struct {
+	mut  sync.Mutex
+	last time.Time
+}
+
+func (c *replayClock) Stamp(captured time.Time) time.Time {
+	if captured.IsZero() {
+		return time.Now()
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if captured.Before(c.last) {
+		return c.last
+	}
+	c.last = captured
+	return captured
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayClock(t *testing.T) {
+	c := NewReplay()
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, t0, c.Stamp(t0))
+	assert.Equal(t, t0.Add(time.Second), c.Stamp(t0.Add(time.Second)))
+
+	// 时间戳回退时保持上一个时间
+	assert.Equal(t, t0.Add(time.Second), c.Stamp(t0))
+}
+
+func TestLiveClock(t *testing.T) {
+	c := New(SourcePacket)
+	t0 := time.Now()
+
+	s0 := c.Stamp(t0)
+	s1 := c.Stamp(t0.Add(10 * time.Millisecond))
+	assert.Equal(t, 10*time.Millisecond, s1.Sub(s0))
+
+	// 主机时钟向前跳变 1 小时 耗时不受影响
+	s2 := c.Stamp(t0.Add(time.Hour))
+	assert.True(t, s2.Sub(s1) < maxClockDrift)
+
+	s3 := c.Stamp(t0.Add(time.Hour + 5*time.Millisecond))
+	assert.Equal(t, 5*time.Millisecond, s3.Sub(s2))
+
+	// 主机时钟回退 耗时不会出现负值
+	s4 := c.Stamp(t0)
+	assert.False(t, s4.Before(s3))
+}
+
+func TestLiveClockBacklog(t *testing.T) {
+	t0 := time.Now()
+	now := t0
+	c := &liveClock{now: func() time.Time { return now }}
+	s0 := c.Stamp(t0)
+
+	// 数据包持续积压 处理时间领先抓取时间戳 2s 仍以抓取时间戳的差值为准
+	now = t0.Add(2 * time.Second)
+	s1 := c.Stamp(t0.Add(time.Millisecond))
+	assert.Equal(t, time.Millisecond, s1.Sub(s0))
+
+	// 空闲之后重新锚定
+	now = t0.Add(10 * time.Second)
+	s2 := c.Stamp(t0.Add(5 * time.Second))
+	assert.Equal(t, now, s2)
+}
+
+func TestSystemClock(t *testing.T) {
+	c := New(SourceSystem)
+	s0 := c.Stamp(time.Time{})
+	s1 := c.Stamp(time.Time{})
+	assert.False(t, s1.Before(s0))
+}
+
+func TestHardwareClock(t *testing.T) {
+	// 硬件时间戳与内核抓取时间戳共用同一套校准逻辑
+	_, ok := New(SourceHardware).(*liveClock)
+	assert.True(t, ok)
+}
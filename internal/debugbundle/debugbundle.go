@@ -0,0 +1,297 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package debugbundle 收集运行中 packetd 的诊断信息并打包为 tar.gz 用于附加至问题反馈
+package debugbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/packetd/packetd/common"
+)
+
+// Config 收集配置
+type Config struct {
+	// Addr packetd HTTP 服务地址 如 localhost:9091
+	Addr string
+
+	// ConfigPath 本地配置文件路径 为空时不收集
+	ConfigPath string
+
+	// Timeout 单个数据源的请求超时
+	Timeout time.Duration
+}
+
+// source 通过 HTTP 服务获取的数据源
+type source struct {
+	file string
+	path string
+}
+
+var sources = []source{
+	{file: "capabilities.json", path: "/capabilities"},
+	{file: "capture.json", path: "/capture"},
+	{file: "metrics.txt", path: "/metrics"},
+	{file: "connections.json", path: "/connections?limit=1000"},
+	{file: "errors.json", path: "/errors"},
+}
+
+// Manifest 记录打包时间 版本以及各数据源的收集结果
+type Manifest struct {
+	Time      time.Time
+	BuildInfo common.BuildInfo
+	Addr      string
+	Files     []string
+	Failures  map[string]string `json:",omitempty"`
+}
+
+// Collect 收集诊断信息写入 w
+//
+// 单个数据源不可用（如未开启 recentErrors）不会中断收集 失败原因记录在 manifest.json 中
+func Collect(ctx context.Context, cfg Config, w io.Writer) (*Manifest, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	manifest := &Manifest{
+		Time:      time.Now(),
+		BuildInfo: common.GetBuildInfo(),
+		Addr:      cfg.Addr,
+		Failures:  make(map[string]string),
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	add := func(name string, b []byte) error {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0o644,
+			Size:    int64(len(b)),
+			ModTime: manifest.Time,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(b); err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, name)
+		return nil
+	}
+
+	if cfg.ConfigPath != "" {
+		b, err := os.ReadFile(cfg.ConfigPath)
+		if err != nil {
+			manifest.Failures["config.yaml"] = err.Error()
+		} else if b, err = ScrubConfig(b); err != nil {
+			manifest.Failures["config.yaml"] = err.Error()
+		} else if err := add("config.yaml", b); err != nil {
+			return nil, err
+		}
+	}
+
+	client := &http.Client{Timeout: cfg.Timeout}
+	for _, src := range sources {
+		b, err := fetch(ctx, client, cfg.Addr, src.path)
+		if err == nil && src.file == "errors.json" {
+			b, err = ScrubErrors(b)
+		}
+		if err != nil {
+			manifest.Failures[src.file] = err.Error()
+			continue
+		}
+		if err := add(src.file, b); err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := add("manifest.json", b); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func fetch(ctx context.Context, client *http.Client, addr, path string) ([]byte, error) {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(addr, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	b, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status %d: %s", rsp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return b, nil
+}
+
+const scrubbed = "<scrubbed>"
+
+// secretKeyRegex 匹配可能为凭证的配置项 key 的任一路径片段命中即隐去其下所有取值
+var secretKeyRegex = regexp.MustCompile(`(?i)(password|passwd|secret|token|apikey|api_key|credential|authorization|salt)`)
+
+// urlUserinfoRegex 匹配 URL 中的 userinfo 部分
+var urlUserinfoRegex = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://)[^/@\s]+@`)
+
+// ScrubConfig 解析 yaml 后按 key 路径隐去配置文件中的凭证
+//
+// key 路径中任一片段（包括 `a.b.c` 形式的点分 key）命中 secretKeyRegex 时 其下所有标量（含列表元素）均被替换
+// 其余标量中 URL 的 userinfo 同样会被隐去
+func ScrubConfig(b []byte) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		return nil, errors.Wrap(err, "decode config")
+	}
+	scrubNode(&root, false)
+
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return nil, errors.Wrap(err, "encode config")
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func scrubNode(node *yaml.Node, secret bool) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			scrubNode(child, secret)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			scrubNode(node.Content[i+1], secret || isSecretKey(node.Content[i].Value))
+		}
+	case yaml.ScalarNode:
+		if secret {
+			node.Value = scrubbed
+			node.Tag = "!!str"
+			node.Style = 0
+			return
+		}
+		node.Value = urlUserinfoRegex.ReplaceAllString(node.Value, "${1}"+scrubbed+"@")
+	}
+}
+
+func isSecretKey(key string) bool {
+	for _, seg := range strings.Split(key, ".") {
+		if secretKeyRegex.MatchString(seg) {
+			return true
+		}
+	}
+	return false
+}
+
+// payloadKeys RoundTrip 中承载业务数据的字段 解析器的诊断并不依赖其内容
+var payloadKeys = map[string]struct{}{
+	"Body":      {},
+	"Header":    {},
+	"Trailer":   {},
+	"Statement": {},
+	"Params":    {},
+	"Message":   {},
+	"Comment":   {},
+	"Args":      {},
+	"ClientIP":  {},
+}
+
+// queryKeys RoundTrip 中携带查询参数的字段 仅保留路径部分
+var queryKeys = map[string]struct{}{
+	"URL": {},
+}
+
+// ScrubErrors 隐去 /errors 返回的 RoundTrip 中的业务数据
+func ScrubErrors(b []byte) ([]byte, error) {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, errors.Wrap(err, "decode errors")
+	}
+	return json.MarshalIndent(scrubPayload(v), "", "  ")
+}
+
+func scrubPayload(v any) any {
+	switch obj := v.(type) {
+	case map[string]any:
+		for k, val := range obj {
+			if _, ok := payloadKeys[k]; ok && val != nil {
+				obj[k] = scrubbed
+				continue
+			}
+			if _, ok := queryKeys[k]; ok {
+				if str, ok := val.(string); ok {
+					obj[k] = scrubURL(str)
+					continue
+				}
+			}
+			obj[k] = scrubPayload(val)
+		}
+	case []any:
+		for i := range obj {
+			obj[i] = scrubPayload(obj[i])
+		}
+	}
+	return v
+}
+
+// scrubURL 去除 URL 中的 userinfo query 以及 fragment
+func scrubURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return scrubbed
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
@@ -0,0 +1,403 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/affinity"
+	"github.com/packetd/packetd/logger"
+)
+
+const (
+	defaultQueueSize = 4096
+	defaultBatchSize = 64
+	defaultCPUBudget = 0.8
+
+	// utilizationWindow worker CPU 占用的统计周期
+	utilizationWindow = 100 * time.Millisecond
+
+	// shedFlowExpired 链接丢弃决策的保留时长 超过该时长未收到数据包的链接会重新决策
+	shedFlowExpired = time.Minute
+
+	maxWeight = 100
+)
+
+// Config 数据包分发配置
+type Config struct {
+	// Workers 解析 worker 数量 小于等于 0 时在抓包协程内直接处理
+	Workers int `config:"workers"`
+
+	// QueueSize 每个 worker 的队列长度
+	QueueSize int `config:"queueSize"`
+
+	// BatchSize 单个 worker 每次最多批量处理的数据包数量
+	// 仅在 Workers 大于 0 时生效 inline 模式下逐个处理
+	BatchSize int `config:"batchSize"`
+
+	// CPUs 指定 worker 绑定的 CPU 格式参见 affinity.Resolve
+	// 每个 worker 依次绑定至其中一个 CPU 且使用独立的队列 避免跨 NUMA 节点访问内存
+	CPUs string `config:"cpus"`
+
+	// Weights 过载时各协议的保留权重 取值 0-100 未配置的协议始终完整处理
+	// worker 的 CPU 占用超过 CPUBudget 后 按照超出程度抽样丢弃低权重协议的链接 CPU 占满时仅保留 weight% 的链接
+	Weights map[string]int `config:"weights"`
+
+	// CPUBudget 每个 worker 的 CPU 预算 即解析耗时占墙上时间的比例 取值 (0, 1)
+	CPUBudget float64 `config:"cpuBudget"`
+}
+
+// ClassifyFunc 返回数据包所属的协议 未匹配时返回空值
+type ClassifyFunc func(st socket.Tuple) socket.L7Proto
+
+// HandleFunc 数据包批量处理函数
+//
+// pkts 仅在函数调用期间有效 调用结束后会被复用
+type HandleFunc func(pkts []socket.L4Packet)
+
+// Stats worker 统计数据
+type Stats struct {
+	Worker  int           // worker 序号
+	Packets uint64        // 已处理的数据包数量
+	Batches uint64        // 已处理的批次数量
+	Busy    time.Duration // 处理数据包累计耗时
+	Queued  int           // 队列中等待处理的数据包数量
+	CPU     int           // 绑定的 CPU 为 -1 时不绑定
+}
+
+type worker struct {
+	cpu     int // 绑定的 CPU 为 -1 时不绑定
+	ch      chan socket.L4Packet
+	packets atomic.Uint64
+	batches atomic.Uint64
+	busy    atomic.Int64
+	running atomic.Int64 // 正在处理的批次的开始时间 UnixNano 空闲时为 0
+
+	// single inline 模式下复用的单元素切片 多个抓包协程可能并发分发 因此使用 sync.Pool
+	single sync.Pool
+
+	mut   sync.Mutex
+	flows map[socket.Tuple]*shedFlow // 链接的丢弃决策 仅记录配置了权重的协议
+	swept time.Time
+
+	// 最近一个统计周期的 CPU 占用 需持有 mut
+	util        float64
+	sampled     time.Time
+	sampledBusy int64
+}
+
+// shedFlow 链接首次出现时做出的丢弃决策 后续数据包沿用该决策
+type shedFlow struct {
+	shed bool
+	seen time.Time
+}
+
+// Dispatcher 将数据包分发至解析 worker
+//
+// 使用链接的对称哈希选择 worker 保证同一条链接两个方向的数据包始终由同一个 worker 处理
+// 避免链接在多个 worker 之间竞争锁 同时保证单链接内数据包的处理顺序
+type Dispatcher struct {
+	workers   []*worker
+	handle    HandleFunc
+	batchSize int
+	wg        sync.WaitGroup
+	inline    worker
+
+	classify  ClassifyFunc
+	weights   map[socket.L7Proto]int
+	cpuBudget float64
+	shed      sync.Map // socket.L7Proto -> *atomic.Uint64
+}
+
+// New 创建并返回 Dispatcher 实例
+//
+// iface 为抓包网卡 用于 CPUs 声明为 numa 时确认网卡所在的 NUMA 节点
+func New(conf Config, iface string, handle HandleFunc) (*Dispatcher, error) {
+	d := &Dispatcher{handle: handle, inline: worker{cpu: -1}}
+	if conf.Workers <= 0 {
+		return d, nil
+	}
+
+	cpus, err := affinity.Resolve(conf.CPUs, iface)
+	if err != nil {
+		return nil, err
+	}
+
+	queueSize := conf.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	d.batchSize = conf.BatchSize
+	if d.batchSize <= 0 {
+		d.batchSize = defaultBatchSize
+	}
+	d.cpuBudget = conf.CPUBudget
+	if d.cpuBudget <= 0 || d.cpuBudget >= 1 {
+		d.cpuBudget = defaultCPUBudget
+	}
+	if len(conf.Weights) > 0 {
+		d.weights = make(map[socket.L7Proto]int, len(conf.Weights))
+		for proto, weight := range conf.Weights {
+			d.weights[socket.L7Proto(proto)] = min(max(weight, 0), maxWeight)
+		}
+	}
+
+	d.workers = make([]*worker, conf.Workers)
+	for i := range d.workers {
+		w := &worker{
+			cpu:     -1,
+			ch:      make(chan socket.L4Packet, queueSize),
+			flows:   make(map[socket.Tuple]*shedFlow),
+			sampled: time.Now(),
+		}
+		if len(cpus) > 0 {
+			w.cpu = cpus[i%len(cpus)]
+		}
+		d.workers[i] = w
+		d.wg.Add(1)
+		go d.run(i, w)
+	}
+	return d, nil
+}
+
+func (d *Dispatcher) run(idx int, w *worker) {
+	defer d.wg.Done()
+
+	if w.cpu >= 0 {
+		if err := affinity.Pin([]int{w.cpu}); err != nil {
+			logger.Warnf("pin worker (%d) to cpu %d failed: %v", idx, w.cpu, err)
+		}
+	}
+
+	// 阻塞等待首个数据包 随后非阻塞地读取队列中已有的数据包 凑满一批或者队列为空时即处理
+	// 低负载时退化为逐个处理 不会因为等待凑批而引入额外延迟
+	batch := make([]socket.L4Packet, 0, d.batchSize)
+	for pkt := range w.ch {
+		batch = append(batch[:0], pkt)
+	drain:
+		for len(batch) < d.batchSize {
+			select {
+			case p, ok := <-w.ch:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, p)
+			default:
+				break drain
+			}
+		}
+		d.process(w, batch)
+		clear(batch)
+	}
+}
+
+func (d *Dispatcher) process(w *worker, pkts []socket.L4Packet) {
+	start := time.Now()
+	w.running.Store(start.UnixNano())
+	d.handle(pkts)
+	elapsed := time.Since(start)
+	w.running.Store(0) // 先于累加 busy 并发读取时仅会短暂少计 不会重复计入
+	w.busy.Add(int64(elapsed))
+	w.packets.Add(uint64(len(pkts)))
+	w.batches.Add(1)
+}
+
+// Dispatch 分发数据包 队列已满时阻塞等待
+//
+// 抓包引擎复用 payload 内存 交由 worker 异步处理前需要拷贝一份
+func (d *Dispatcher) Dispatch(pkt socket.L4Packet) {
+	if len(d.workers) == 0 {
+		single, _ := d.inline.single.Get().(*[1]socket.L4Packet)
+		if single == nil {
+			single = new([1]socket.L4Packet)
+		}
+		single[0] = pkt
+		d.process(&d.inline, single[:])
+		single[0] = nil
+		d.inline.single.Put(single)
+		return
+	}
+
+	st := pkt.SocketTuple()
+	hash := st.SymmetricHash()
+	w := d.workers[hash%uint64(len(d.workers))]
+	if d.shouldShed(w, pkt, st, hash) {
+		return
+	}
+	w.ch <- clonePacket(pkt)
+}
+
+// SetClassifier 设置协议识别函数 未设置时 Weights 不生效
+//
+// 需在开始分发数据包之前调用
+func (d *Dispatcher) SetClassifier(f ClassifyFunc) {
+	d.classify = f
+}
+
+// shouldShed 判断过载时是否丢弃数据包
+//
+// 链接首次出现（或 TCP 链接重新握手）时由 decideShed 根据 worker 的 CPU 占用以及协议权重做出决策 后续数据包沿用该决策
+// 同一条链接的数据包要么全部保留 要么全部丢弃 不会因为 CPU 占用的波动而残缺不全
+func (d *Dispatcher) shouldShed(w *worker, pkt socket.L4Packet, st socket.Tuple, hash uint64) bool {
+	if len(d.weights) == 0 || d.classify == nil {
+		return false
+	}
+
+	proto := d.classify(st)
+	weight, ok := d.weights[proto]
+	if !ok {
+		return false
+	}
+
+	now := time.Now()
+	w.mut.Lock()
+	if now.Sub(w.swept) > shedFlowExpired {
+		w.sweep(now)
+	}
+	flow, ok := w.flows[st]
+	if !ok {
+		flow, ok = w.flows[st.Mirror()]
+	}
+	if !ok || isSYN(pkt) {
+		flow = &shedFlow{shed: d.decideShed(w, now, hash, weight)}
+		w.flows[st] = flow
+		delete(w.flows, st.Mirror())
+	}
+	flow.seen = now
+	shed := flow.shed
+	w.mut.Unlock()
+
+	if !shed {
+		return false
+	}
+	v, _ := d.shed.LoadOrStore(proto, &atomic.Uint64{})
+	v.(*atomic.Uint64).Add(1)
+	return true
+}
+
+// decideShed 根据 worker 的 CPU 占用决定新链接的去留 调用方需持有 mut
+//
+// 保留比例随 CPU 占用从 CPUBudget 时的 100% 线性下降至 CPU 占满时的 weight%
+func (d *Dispatcher) decideShed(w *worker, now time.Time, hash uint64, weight int) bool {
+	util := w.utilization(now)
+	if util <= d.cpuBudget {
+		return false
+	}
+
+	pressure := min((util-d.cpuBudget)/(1-d.cpuBudget), 1)
+	keep := maxWeight - pressure*float64(maxWeight-weight)
+	// 低位已用于选择 worker 使用高位避免与 worker 序号相关
+	return float64((hash>>32)%maxWeight) >= keep
+}
+
+// busyAt 返回截至 now 的累计处理耗时 包含正在处理的批次
+func (w *worker) busyAt(now time.Time) int64 {
+	busy := w.busy.Load()
+	if running := w.running.Load(); running > 0 {
+		busy += max(now.UnixNano()-running, 0)
+	}
+	return busy
+}
+
+// utilization 返回最近一个统计周期内处理耗时占墙上时间的比例 调用方需持有 mut
+//
+// 距离上次统计不足一个周期时沿用上次的结果
+func (w *worker) utilization(now time.Time) float64 {
+	elapsed := now.Sub(w.sampled)
+	if elapsed < utilizationWindow {
+		return w.util
+	}
+
+	busy := w.busyAt(now)
+	w.util = min(max(float64(busy-w.sampledBusy)/float64(elapsed), 0), 1)
+	w.sampled = now
+	w.sampledBusy = busy
+	return w.util
+}
+
+// sweep 清理过期的链接决策 调用方需持有 mut
+func (w *worker) sweep(now time.Time) {
+	for st, flow := range w.flows {
+		if now.Sub(flow.seen) > shedFlowExpired {
+			delete(w.flows, st)
+		}
+	}
+	w.swept = now
+}
+
+func isSYN(pkt socket.L4Packet) bool {
+	seg, ok := pkt.(*socket.TCPSegment)
+	return ok && seg.SYN && !seg.ACK
+}
+
+// Shed 返回各协议过载时被丢弃的数据包数量
+//
+// 读取即重置 返回的是距离上次调用的增量
+func (d *Dispatcher) Shed() map[socket.L7Proto]uint64 {
+	ret := make(map[socket.L7Proto]uint64)
+	d.shed.Range(func(k, v any) bool {
+		ret[k.(socket.L7Proto)] = v.(*atomic.Uint64).Swap(0)
+		return true
+	})
+	return ret
+}
+
+// Stats 返回所有 worker 的统计数据
+func (d *Dispatcher) Stats() []Stats {
+	if len(d.workers) == 0 {
+		return []Stats{d.inline.stats(0)}
+	}
+
+	lst := make([]Stats, 0, len(d.workers))
+	for i, w := range d.workers {
+		lst = append(lst, w.stats(i))
+	}
+	return lst
+}
+
+func (w *worker) stats(idx int) Stats {
+	return Stats{
+		Worker:  idx,
+		Packets: w.packets.Load(),
+		Batches: w.batches.Load(),
+		Busy:    time.Duration(w.busy.Load()),
+		Queued:  len(w.ch),
+		CPU:     w.cpu,
+	}
+}
+
+// Close 关闭所有 worker 并等待队列中的数据包处理完毕
+func (d *Dispatcher) Close() {
+	for _, w := range d.workers {
+		close(w.ch)
+	}
+	d.wg.Wait()
+}
+
+func clonePacket(pkt socket.L4Packet) socket.L4Packet {
+	switch p := pkt.(type) {
+	case *socket.TCPSegment:
+		cloned := *p
+		cloned.Payload = append([]byte(nil), p.Payload...)
+		return &cloned
+	case *socket.UDPDatagram:
+		cloned := *p
+		cloned.Payload = append([]byte(nil), p.Payload...)
+		return &cloned
+	}
+	return pkt
+}
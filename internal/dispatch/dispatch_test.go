@@ -0,0 +1,236 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+func TestDispatch(t *testing.T) {
+	var mut sync.Mutex
+	seen := make(map[socket.Tuple][]byte)
+	d, err := New(Config{Workers: 4, QueueSize: 8}, "", func(pkts []socket.L4Packet) {
+		mut.Lock()
+		defer mut.Unlock()
+		for _, pkt := range pkts {
+			seen[pkt.SocketTuple()] = pkt.(*socket.TCPSegment).Payload
+		}
+	})
+	assert.NoError(t, err)
+
+	st := socket.Tuple{
+		SrcIP:   socket.ToIPV4(net.ParseIP("10.0.0.1").To4()),
+		DstIP:   socket.ToIPV4(net.ParseIP("10.0.0.2").To4()),
+		SrcPort: 52000,
+		DstPort: 80,
+	}
+	assert.Equal(t, st.SymmetricHash(), st.Mirror().SymmetricHash())
+
+	payload := []byte("ping")
+	d.Dispatch(&socket.TCPSegment{Tuple: st, Payload: payload})
+	d.Dispatch(&socket.TCPSegment{Tuple: st.Mirror(), Payload: []byte("pong")})
+	payload[0] = 'x' // 抓包引擎复用内存 不应影响已分发的数据包
+	d.Close()
+
+	assert.Equal(t, []byte("ping"), seen[st])
+	assert.Equal(t, []byte("pong"), seen[st.Mirror()])
+
+	// 两个方向由同一个 worker 处理
+	var handled int
+	for _, s := range d.Stats() {
+		if s.Packets > 0 {
+			handled++
+			assert.Equal(t, uint64(2), s.Packets)
+		}
+	}
+	assert.Equal(t, 1, handled)
+}
+
+func TestDispatchInline(t *testing.T) {
+	var n int
+	d, err := New(Config{}, "", func(pkts []socket.L4Packet) { n += len(pkts) })
+	assert.NoError(t, err)
+	d.Dispatch(&socket.UDPDatagram{})
+	d.Close()
+
+	assert.Equal(t, 1, n)
+	stats := d.Stats()
+	assert.Len(t, stats, 1)
+	assert.Equal(t, uint64(1), stats[0].Packets)
+
+	// inline 模式复用单元素切片 逐个处理不产生额外的内存分配
+	pkt := &socket.UDPDatagram{}
+	allocs := testing.AllocsPerRun(100, func() { d.Dispatch(pkt) })
+	assert.Zero(t, allocs)
+}
+
+func TestDispatchInvalidCPUs(t *testing.T) {
+	_, err := New(Config{Workers: 1, CPUs: "3-1"}, "", func([]socket.L4Packet) {})
+	assert.Error(t, err)
+}
+
+func TestDispatchBatch(t *testing.T) {
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var sizes []int
+	d, err := New(Config{Workers: 1, BatchSize: 4}, "", func(pkts []socket.L4Packet) {
+		select {
+		case entered <- struct{}{}:
+		default:
+		}
+		<-release
+		sizes = append(sizes, len(pkts))
+	})
+	assert.NoError(t, err)
+
+	// 首个数据包处理阻塞期间 后续数据包在队列中堆积 随后按照 batchSize 批量处理
+	d.Dispatch(&socket.UDPDatagram{})
+	<-entered
+	for i := 0; i < 6; i++ {
+		d.Dispatch(&socket.UDPDatagram{})
+	}
+	close(release)
+	d.Close()
+
+	assert.Equal(t, []int{1, 4, 2}, sizes)
+	stats := d.Stats()
+	assert.Equal(t, uint64(7), stats[0].Packets)
+	assert.Equal(t, uint64(3), stats[0].Batches)
+}
+
+func TestDispatchShed(t *testing.T) {
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	handled := make(map[socket.L7Proto]int)
+	d, err := New(Config{
+		Workers:   1,
+		QueueSize: 1000,
+		Weights:   map[string]int{"dns": 0},
+		CPUBudget: 0.5,
+	}, "", func(pkts []socket.L4Packet) {
+		select {
+		case entered <- struct{}{}:
+		default:
+		}
+		<-release
+		for _, pkt := range pkts {
+			handled[socket.L7Proto(pkt.(*socket.UDPDatagram).Payload)]++
+		}
+	})
+	assert.NoError(t, err)
+	d.SetClassifier(func(st socket.Tuple) socket.L7Proto {
+		if st.DstPort == 53 {
+			return socket.L7ProtoDNS
+		}
+		return socket.L7ProtoMySQL
+	})
+
+	newPacket := func(i int, proto socket.L7Proto, port socket.Port) socket.L4Packet {
+		return &socket.UDPDatagram{
+			Tuple: socket.Tuple{
+				SrcIP:   socket.ToIPV4(net.IPv4(10, 0, byte(i>>8), byte(i)).To4()),
+				DstIP:   socket.ToIPV4(net.ParseIP("10.0.0.2").To4()),
+				SrcPort: socket.Port(40000 + i),
+				DstPort: port,
+			},
+			Payload: []byte(proto),
+		}
+	}
+
+	d.Dispatch(newPacket(0, socket.L7ProtoMySQL, 3306))
+	<-entered
+	time.Sleep(utilizationWindow) // worker 持续处理超过一个统计周期 CPU 占满
+
+	// CPU 超出预算期间 未配置权重的 mysql 始终完整处理 dns 的新链接几乎全部丢弃
+	for i := 1; i <= 400; i++ {
+		d.Dispatch(newPacket(i, socket.L7ProtoMySQL, 3306))
+		d.Dispatch(newPacket(i, socket.L7ProtoDNS, 53))
+	}
+	close(release)
+	d.Close()
+
+	assert.Equal(t, 401, handled[socket.L7ProtoMySQL])
+	shed := d.Shed()[socket.L7ProtoDNS]
+	assert.Greater(t, shed, uint64(300))
+	assert.Equal(t, 400, handled[socket.L7ProtoDNS]+int(shed))
+	assert.Zero(t, d.Shed()[socket.L7ProtoMySQL])
+}
+
+func TestDispatchShedSticky(t *testing.T) {
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var handled int
+	d, err := New(Config{
+		Workers:   1,
+		QueueSize: 100,
+		Weights:   map[string]int{"dns": 0},
+		CPUBudget: 0.5,
+	}, "", func(pkts []socket.L4Packet) {
+		select {
+		case entered <- struct{}{}:
+		default:
+		}
+		<-release
+		for _, pkt := range pkts {
+			if pkt.SocketTuple().DstPort == 53 {
+				handled++
+			}
+		}
+	})
+	assert.NoError(t, err)
+	d.SetClassifier(func(st socket.Tuple) socket.L7Proto {
+		if st.DstPort == 53 || st.SrcPort == 53 {
+			return socket.L7ProtoDNS
+		}
+		return socket.L7ProtoMySQL
+	})
+
+	st := socket.Tuple{
+		SrcIP:   socket.ToIPV4(net.ParseIP("10.0.0.1").To4()),
+		DstIP:   socket.ToIPV4(net.ParseIP("10.0.0.2").To4()),
+		SrcPort: 40000,
+		DstPort: 53,
+	}
+	other := st
+	other.DstPort = 3306
+
+	// 链接在 worker 空闲时出现 此后即便 CPU 超出预算也完整保留
+	d.Dispatch(&socket.UDPDatagram{Tuple: st})
+	<-entered
+	time.Sleep(utilizationWindow)
+	for i := 0; i < 90; i++ {
+		d.Dispatch(&socket.UDPDatagram{Tuple: other})
+	}
+	for i := 0; i < 5; i++ {
+		d.Dispatch(&socket.UDPDatagram{Tuple: st})
+		d.Dispatch(&socket.UDPDatagram{Tuple: st.Mirror()})
+	}
+
+	// 超出预算后出现的新链接被丢弃
+	late := st
+	late.SrcPort = 40001
+	d.Dispatch(&socket.UDPDatagram{Tuple: late})
+	close(release)
+	d.Close()
+
+	assert.Equal(t, 6, handled)
+	assert.Equal(t, uint64(1), d.Shed()[socket.L7ProtoDNS])
+}
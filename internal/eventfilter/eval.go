@@ -0,0 +1,321 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventfilter
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+const (
+	fieldProto   = "proto"
+	fieldLatency = "latency"
+
+	prefixRequest  = "request."
+	prefixResponse = "response."
+
+	// maxSearchDepth 按照名称查找字段时的最大深度
+	maxSearchDepth = 4
+)
+
+type node interface {
+	eval(rt socket.RoundTrip) bool
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n *andNode) eval(rt socket.RoundTrip) bool {
+	return n.left.eval(rt) && n.right.eval(rt)
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n *orNode) eval(rt socket.RoundTrip) bool {
+	return n.left.eval(rt) || n.right.eval(rt)
+}
+
+type notNode struct {
+	node node
+}
+
+func (n *notNode) eval(rt socket.RoundTrip) bool {
+	return !n.node.eval(rt)
+}
+
+type literalKind uint8
+
+const (
+	literalString literalKind = iota
+	literalNumber
+	literalDuration
+	literalBool
+)
+
+type literal struct {
+	kind literalKind
+	s    string
+	f    float64
+	d    time.Duration
+	b    bool
+}
+
+type compareNode struct {
+	field string
+	op    string
+	lit   literal
+	re    *regexp.Regexp
+}
+
+func (n *compareNode) eval(rt socket.RoundTrip) bool {
+	v, ok := lookup(rt, n.field)
+	if !ok {
+		return false
+	}
+
+	if n.re != nil {
+		matched := n.re.MatchString(stringify(v))
+		if n.op == "!~" {
+			return !matched
+		}
+		return matched
+	}
+
+	switch n.lit.kind {
+	case literalString:
+		s := stringify(v)
+		switch n.op {
+		case "==":
+			return strings.EqualFold(s, n.lit.s)
+		case "!=":
+			return !strings.EqualFold(s, n.lit.s)
+		}
+		return ordered(strings.Compare(s, n.lit.s), n.op)
+
+	case literalNumber:
+		f, ok := toNumber(v)
+		if !ok {
+			return false
+		}
+		return ordered(cmp.Compare(f, n.lit.f), n.op)
+
+	case literalDuration:
+		d, ok := v.(time.Duration)
+		if !ok {
+			return false
+		}
+		return ordered(cmp.Compare(d, n.lit.d), n.op)
+
+	case literalBool:
+		b, ok := v.(bool)
+		if !ok {
+			return false
+		}
+		switch n.op {
+		case "==":
+			return b == n.lit.b
+		case "!=":
+			return b != n.lit.b
+		}
+	}
+	return false
+}
+
+// ordered 将比较结果转换为运算符语义
+func ordered(c int, op string) bool {
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return false
+}
+
+func stringify(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func toNumber(v any) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case string:
+		// 部分协议的状态码以字符串形式存储 如 HTTP/2 的 Status
+		f, err := strconv.ParseFloat(val, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// lookup 按照字段名称解析 RoundTrip 中的值
+func lookup(rt socket.RoundTrip, field string) (any, bool) {
+	switch {
+	case field == fieldProto:
+		return string(rt.Proto()), true
+	case field == fieldLatency:
+		return rt.Duration(), true
+	case strings.HasPrefix(field, prefixRequest):
+		return walk(reflect.ValueOf(rt.Request()), strings.Split(field[len(prefixRequest):], "."))
+	case strings.HasPrefix(field, prefixResponse):
+		return walk(reflect.ValueOf(rt.Response()), strings.Split(field[len(prefixResponse):], "."))
+	}
+
+	if v, ok := search(reflect.ValueOf(rt.Request()), field); ok {
+		return v, true
+	}
+	return search(reflect.ValueOf(rt.Response()), field)
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// walk 按照路径逐级查找字段 支持结构体以及 key 为字符串的 map
+func walk(v reflect.Value, path []string) (any, bool) {
+	for _, name := range path {
+		v = indirect(v)
+		if !v.IsValid() {
+			return nil, false
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			v = fieldByName(v, name)
+
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return nil, false
+			}
+			var found reflect.Value
+			iter := v.MapRange()
+			for iter.Next() {
+				if strings.EqualFold(iter.Key().String(), name) {
+					found = iter.Value()
+					break
+				}
+			}
+			v = found
+
+		default:
+			return nil, false
+		}
+	}
+	return toValue(v)
+}
+
+// search 广度优先查找名称为 name 的字段 仅遍历结构体
+func search(root reflect.Value, name string) (any, bool) {
+	queue := []reflect.Value{root}
+	for depth := 0; depth < maxSearchDepth && len(queue) > 0; depth++ {
+		var next []reflect.Value
+		for _, v := range queue {
+			v = indirect(v)
+			if !v.IsValid() || v.Kind() != reflect.Struct {
+				continue
+			}
+			if f := fieldByName(v, name); f.IsValid() {
+				return toValue(f)
+			}
+			for i := 0; i < v.NumField(); i++ {
+				if v.Type().Field(i).IsExported() {
+					next = append(next, v.Field(i))
+				}
+			}
+		}
+		queue = next
+	}
+	return nil, false
+}
+
+func fieldByName(v reflect.Value, name string) reflect.Value {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.IsExported() && strings.EqualFold(f.Name, name) {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// toValue 将字段值归一化为 string / float64 / bool / time.Duration
+func toValue(v reflect.Value) (any, bool) {
+	v = indirect(v)
+	if !v.IsValid() {
+		return nil, false
+	}
+	if v.Type() == durationType {
+		return time.Duration(v.Int()), true
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Bool:
+		return v.Bool(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), true
+		}
+		// 如 http.Header 的取值 仅取首个元素
+		if v.Len() == 0 {
+			return nil, false
+		}
+		return toValue(v.Index(0))
+	}
+
+	if v.CanInterface() {
+		return fmt.Sprint(v.Interface()), true
+	}
+	return nil, false
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package eventfilter 实现了一个基于协议字段的 RoundTrip 过滤表达式
+//
+// 语法样例
+//
+//	proto == "mysql" && latency > 50ms && statement =~ "orders"
+//
+// 支持的运算符
+//   - 逻辑运算: `&&` `||` `!` 以及 `()` 分组
+//   - 比较运算: `==` `!=` `>` `>=` `<` `<=`
+//   - 正则匹配: `=~` `!~`
+//
+// 字面量支持字符串（双引号）数字 时长（如 50ms / 1.5s）以及 true / false
+//
+// 字段解析规则
+//   - proto: 协议名称 如 http / mysql
+//   - latency: 请求耗时
+//   - request.<Field>.<Field> / response.<Field>.<Field>: 按照路径逐级查找 大小写不敏感
+//   - 其余名称: 依次在 request / response 中按照广度优先查找同名字段 如 statement / method / status
+//
+// 字段不存在时比较结果均为 false 字符串相等比较大小写不敏感
+package eventfilter
+
+import (
+	"github.com/packetd/packetd/common/socket"
+)
+
+// Filter 编译后的过滤表达式 并发安全
+type Filter struct {
+	expr string
+	root node
+}
+
+// Compile 编译过滤表达式 空表达式返回 nil 表示不过滤
+func Compile(expr string) (*Filter, error) {
+	p, err := newParser(expr)
+	if err != nil {
+		return nil, err
+	}
+	if p.eof() {
+		return nil, nil
+	}
+
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{expr: expr, root: root}, nil
+}
+
+// String 返回原始表达式
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.expr
+}
+
+// Match 判断 RoundTrip 是否命中表达式 nil Filter 匹配所有数据
+func (f *Filter) Match(rt socket.RoundTrip) bool {
+	if f == nil {
+		return true
+	}
+	return f.root.eval(rt)
+}
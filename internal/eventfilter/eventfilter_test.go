@@ -0,0 +1,114 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventfilter
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol/phttp"
+	"github.com/packetd/packetd/protocol/pmysql"
+)
+
+type fakeRoundTrip struct {
+	proto    socket.L7Proto
+	req      any
+	rsp      any
+	duration time.Duration
+}
+
+func (rt fakeRoundTrip) Proto() socket.L7Proto   { return rt.proto }
+func (rt fakeRoundTrip) Request() any            { return rt.req }
+func (rt fakeRoundTrip) Response() any           { return rt.rsp }
+func (rt fakeRoundTrip) Duration() time.Duration { return rt.duration }
+func (rt fakeRoundTrip) Validate() bool          { return true }
+
+func TestFilterMatch(t *testing.T) {
+	mysqlRT := fakeRoundTrip{
+		proto:    socket.L7ProtoMySQL,
+		req:      &pmysql.Request{Command: "COM_QUERY", Statement: "SELECT * FROM orders", Size: 20},
+		rsp:      &pmysql.Response{Packet: &pmysql.ErrorPacket{ErrCode: 1146}},
+		duration: 80 * time.Millisecond,
+	}
+	httpRT := fakeRoundTrip{
+		proto: socket.L7ProtoHTTP,
+		req: &phttp.Request{
+			Method: http.MethodGet,
+			Path:   "/api/orders",
+			Header: http.Header{"X-Request-Id": []string{"abc"}},
+		},
+		rsp:      &phttp.Response{StatusCode: 503},
+		duration: 10 * time.Millisecond,
+	}
+
+	tests := []struct {
+		expr  string
+		rt    socket.RoundTrip
+		match bool
+	}{
+		{expr: `proto == "MySQL" && latency > 50ms && statement =~ "orders"`, rt: mysqlRT, match: true},
+		{expr: `proto == "mysql" && latency > 100ms`, rt: mysqlRT, match: false},
+		{expr: `statement !~ "^SELECT"`, rt: mysqlRT, match: false},
+		{expr: `response.packet.errcode == 1146`, rt: mysqlRT, match: true},
+		{expr: `errcode >= 1000 && request.size < 100`, rt: mysqlRT, match: true},
+		{expr: `proto == "http" && (status >= 500 || method == "POST")`, rt: httpRT, match: false},
+		{expr: `proto == "http" && (statuscode >= 500 || method == "POST")`, rt: httpRT, match: true},
+		{expr: `request.header.x-request-id == "abc"`, rt: httpRT, match: true},
+		{expr: `!(path =~ "^/api")`, rt: httpRT, match: false},
+		{expr: `statement == "x"`, rt: httpRT, match: false},
+		{expr: `statement != "x"`, rt: httpRT, match: false},
+		{expr: `latency <= 10ms`, rt: httpRT, match: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := Compile(tt.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.match, f.Match(tt.rt))
+		})
+	}
+}
+
+func TestCompileEmpty(t *testing.T) {
+	f, err := Compile("  ")
+	assert.NoError(t, err)
+	assert.Nil(t, f)
+	assert.True(t, f.Match(fakeRoundTrip{}))
+}
+
+func TestCompileFailed(t *testing.T) {
+	tests := []string{
+		`proto ==`,
+		`proto "mysql"`,
+		`(proto == "mysql"`,
+		`proto == "mysql" &&`,
+		`statement =~ 1`,
+		`statement =~ "("`,
+		`latency > 5xs`,
+		`proto == "mysql`,
+		`proto == mysql`,
+		`proto $ "mysql"`,
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			_, err := Compile(expr)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventfilter
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+type tokenKind uint8
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators 按照长度降序排列 保证优先匹配较长的运算符
+var operators = []string{"&&", "||", "==", "!=", ">=", "<=", "=~", "!~", ">", "<", "!"}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(s) {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return nil, errors.Errorf("unterminated string at %d", i)
+			}
+			text, err := strconv.Unquote(s[i : j+1])
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid string at %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i})
+			i = j + 1
+
+		case c >= '0' && c <= '9':
+			// 数字后可以紧跟时间单位 如 50ms / 1.5s
+			j := i
+			for j < len(s) && (isDigit(s[j]) || s[j] == '.' || isLetter(s[j])) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: s[i:j], pos: i})
+			i = j
+
+		case isLetter(s[i]) || c == '_':
+			// 允许 `-` 以便引用 HTTP Header 如 request.header.x-request-id
+			j := i
+			for j < len(s) && (isLetter(s[j]) || isDigit(s[j]) || s[j] == '_' || s[j] == '.' || s[j] == '-') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: s[i:j], pos: i})
+			i = j
+
+		default:
+			var matched string
+			for _, op := range operators {
+				if strings.HasPrefix(s[i:], op) {
+					matched = op
+					break
+				}
+			}
+			if matched == "" {
+				return nil, errors.Errorf("unexpected character %q at %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: matched, pos: i})
+			i += len(matched)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(s)}), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// parser 递归下降解析器
+//
+// expr    := and ( '||' and )*
+// and     := unary ( '&&' unary )*
+// unary   := '!' unary | '(' expr ')' | compare
+// compare := ident op literal
+type parser struct {
+	tokens []token
+	idx    int
+}
+
+func newParser(expr string) (*parser, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &parser{tokens: tokens}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.idx]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.idx]
+	if tok.kind != tokenEOF {
+		p.idx++
+	}
+	return tok
+}
+
+func (p *parser) eof() bool {
+	return p.peek().kind == tokenEOF
+}
+
+func (p *parser) isOp(text string) bool {
+	tok := p.peek()
+	return tok.kind == tokenOp && tok.text == text
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.eof() {
+		tok := p.peek()
+		return nil, errors.Errorf("unexpected token %q at %d", tok.text, tok.pos)
+	}
+	return n, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&&") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("!") {
+		p.next()
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{node: n}, nil
+	}
+
+	if p.peek().kind == tokenLParen {
+		p.next()
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokenRParen {
+			return nil, errors.Errorf("expected ')' at %d", tok.pos)
+		}
+		return n, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	field := p.next()
+	if field.kind != tokenIdent {
+		return nil, errors.Errorf("expected field at %d", field.pos)
+	}
+
+	op := p.next()
+	if op.kind != tokenOp || op.text == "&&" || op.text == "||" || op.text == "!" {
+		return nil, errors.Errorf("expected comparison operator after %q at %d", field.text, op.pos)
+	}
+
+	lit, err := p.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+
+	n := &compareNode{field: field.text, op: op.text, lit: lit}
+	if op.text == "=~" || op.text == "!~" {
+		if lit.kind != literalString {
+			return nil, errors.Errorf("operator %s requires string pattern at %d", op.text, op.pos)
+		}
+		if n.re, err = regexp.Compile(lit.s); err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern %q", lit.s)
+		}
+	}
+	return n, nil
+}
+
+func (p *parser) parseLiteral() (literal, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return literal{kind: literalString, s: tok.text}, nil
+
+	case tokenNumber:
+		if f, err := strconv.ParseFloat(tok.text, 64); err == nil {
+			return literal{kind: literalNumber, f: f}, nil
+		}
+		d, err := time.ParseDuration(tok.text)
+		if err != nil {
+			return literal{}, errors.Errorf("invalid number or duration %q at %d", tok.text, tok.pos)
+		}
+		return literal{kind: literalDuration, d: d}, nil
+
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return literal{kind: literalBool, b: true}, nil
+		case "false":
+			return literal{kind: literalBool, b: false}, nil
+		}
+	}
+	return literal{}, errors.Errorf("expected literal at %d", tok.pos)
+}
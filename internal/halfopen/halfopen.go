@@ -0,0 +1,121 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package halfopen
+
+import (
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/connstream"
+)
+
+// Reason 判定为半开链接的依据
+type Reason string
+
+const (
+	// ReasonRetransmit 数据持续重传但对端 ACK 没有推进
+	ReasonRetransmit Reason = "retransmit"
+
+	// ReasonKeepalive 连续多次 keepalive 探测未得到回应
+	ReasonKeepalive Reason = "keepalive"
+)
+
+// Conn 链接快照
+type Conn struct {
+	Proto   socket.L7Proto
+	Tuple   socket.Tuple
+	Closed  bool
+	Pending int // 尚未收到响应的请求数量 即受影响的 RoundTrip
+	States  []connstream.AckState
+}
+
+// Event 链接进入半开状态事件
+type Event struct {
+	Conn
+	State   connstream.AckState // 触发判定的方向
+	Reason  Reason
+	Stalled time.Duration
+}
+
+// Options 判定阈值
+type Options struct {
+	Stalled     time.Duration // 数据未被确认的最短时长
+	Retransmits int           // 停滞期间的最少重传次数
+	Keepalives  int           // 未得到回应的最少 keepalive 次数
+}
+
+// Detector 半开链接检测
+//
+// 对端失联后本端仍在发送数据或者探活 应用层请求会一直挂起直到超时
+// 同一方向持续处于半开状态时仅触发一次事件 恢复后再次进入会再次触发
+type Detector struct {
+	opts     Options
+	reported map[socket.Tuple]Reason
+}
+
+// New 创建并返回 Detector 实例
+func New(opts Options) *Detector {
+	return &Detector{
+		opts:     opts,
+		reported: make(map[socket.Tuple]Reason),
+	}
+}
+
+func (d *Detector) judge(now time.Time, state connstream.AckState) (Reason, bool) {
+	if state.Retransmits >= d.opts.Retransmits && state.Stalled(now) >= d.opts.Stalled {
+		return ReasonRetransmit, true
+	}
+	if state.Keepalives >= d.opts.Keepalives {
+		return ReasonKeepalive, true
+	}
+	return "", false
+}
+
+// Detect 返回本轮新进入半开状态的链接 以及各协议当前半开链接的数量
+func (d *Detector) Detect(now time.Time, conns []Conn) ([]Event, map[socket.L7Proto]int) {
+	var events []Event
+	halfOpen := make(map[socket.L7Proto]int)
+	reported := make(map[socket.Tuple]Reason, len(d.reported))
+	for _, conn := range conns {
+		if conn.Closed {
+			continue
+		}
+
+		var found bool
+		for _, state := range conn.States {
+			reason, ok := d.judge(now, state)
+			if !ok {
+				continue
+			}
+
+			found = true
+			reported[state.Tuple] = reason
+			if d.reported[state.Tuple] == reason {
+				continue
+			}
+			events = append(events, Event{
+				Conn:    conn,
+				State:   state,
+				Reason:  reason,
+				Stalled: state.Stalled(now),
+			})
+		}
+		if found {
+			halfOpen[conn.Proto]++
+		}
+	}
+
+	d.reported = reported
+	return events, halfOpen
+}
@@ -0,0 +1,70 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package halfopen
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/connstream"
+)
+
+func newTuple(port socket.Port) socket.Tuple {
+	return socket.Tuple{
+		SrcIP:   socket.ToIPV4(net.ParseIP("10.0.0.1").To4()),
+		DstIP:   socket.ToIPV4(net.ParseIP("10.0.0.2").To4()),
+		SrcPort: port,
+		DstPort: 6379,
+	}
+}
+
+func TestDetector(t *testing.T) {
+	t0 := time.Unix(1751356800, 0)
+	d := New(Options{Stalled: 10 * time.Second, Retransmits: 3, Keepalives: 2})
+
+	healthy := Conn{Proto: "redis", Tuple: newTuple(50001), States: []connstream.AckState{
+		{Tuple: newTuple(50001), Retransmits: 1, UnackedAt: t0.Add(-time.Minute)},
+	}}
+	dead := Conn{Proto: "redis", Tuple: newTuple(50002), Pending: 2, States: []connstream.AckState{
+		{Tuple: newTuple(50002), Retransmits: 4, UnackedAt: t0.Add(-30 * time.Second)},
+	}}
+	probing := Conn{Proto: "redis", Tuple: newTuple(50003), States: []connstream.AckState{
+		{Tuple: newTuple(50003).Mirror(), Keepalives: 2},
+	}}
+	closed := Conn{Proto: "redis", Tuple: newTuple(50004), Closed: true, States: []connstream.AckState{
+		{Tuple: newTuple(50004), Keepalives: 9},
+	}}
+
+	events, n := d.Detect(t0, []Conn{healthy, dead, probing, closed})
+	assert.Equal(t, []Event{
+		{Conn: dead, State: dead.States[0], Reason: ReasonRetransmit, Stalled: 30 * time.Second},
+		{Conn: probing, State: probing.States[0], Reason: ReasonKeepalive},
+	}, events)
+	assert.Equal(t, map[socket.L7Proto]int{"redis": 2}, n)
+
+	// 持续半开不再重复触发
+	events, n = d.Detect(t0.Add(time.Second), []Conn{healthy, dead, probing})
+	assert.Empty(t, events)
+	assert.Equal(t, map[socket.L7Proto]int{"redis": 2}, n)
+
+	// 恢复后再次进入半开状态会再次触发
+	events, _ = d.Detect(t0.Add(2*time.Second), []Conn{dead})
+	assert.Empty(t, events)
+	events, _ = d.Detect(t0.Add(3*time.Second), []Conn{probing})
+	assert.Len(t, events, 1)
+}
@@ -0,0 +1,230 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostnames
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTTL         = 10 * time.Minute
+	defaultMaxEntries  = 4096
+	defaultTimeout     = 2 * time.Second
+	defaultConcurrency = 4
+)
+
+// Config 主机名解析配置
+type Config struct {
+	// HostsFile 静态映射文件路径 格式同 /etc/hosts 取每行第一个主机名
+	HostsFile string `config:"hostsFile" mapstructure:"hostsFile"`
+
+	// EnablePTR 是否通过 PTR 反向解析未命中静态映射的地址
+	EnablePTR bool `config:"enablePTR" mapstructure:"enablePTR"`
+
+	// TTL PTR 解析结果（包括解析失败）的缓存时长
+	TTL time.Duration `config:"ttl" mapstructure:"ttl"`
+
+	// MaxEntries PTR 缓存的最大条目数
+	MaxEntries int `config:"maxEntries" mapstructure:"maxEntries"`
+
+	// Timeout 单次 PTR 查询的超时时间
+	Timeout time.Duration `config:"timeout" mapstructure:"timeout"`
+}
+
+// Enabled 返回是否配置了任一解析来源
+func (c Config) Enabled() bool {
+	return c.HostsFile != "" || c.EnablePTR
+}
+
+type entry struct {
+	name     string
+	expireAt time.Time
+}
+
+// Resolver 地址至主机名的解析缓存
+//
+// 静态映射优先 PTR 查询在后台异步进行 Lookup 永远不会阻塞调用方
+// 首次遇到的地址在查询完成前返回空 由调用方决定回退策略
+type Resolver struct {
+	static map[string]string
+
+	enablePTR  bool
+	ttl        time.Duration
+	maxEntries int
+	timeout    time.Duration
+	lookupAddr func(ctx context.Context, addr string) ([]string, error)
+	now        func() time.Time
+
+	mut     sync.Mutex
+	cache   map[string]entry
+	pending map[string]struct{}
+	sem     chan struct{}
+}
+
+// New 创建并返回 Resolver 实例
+func New(conf Config) (*Resolver, error) {
+	r := &Resolver{
+		static:     make(map[string]string),
+		enablePTR:  conf.EnablePTR,
+		ttl:        conf.TTL,
+		maxEntries: conf.MaxEntries,
+		timeout:    conf.Timeout,
+		lookupAddr: net.DefaultResolver.LookupAddr,
+		now:        time.Now,
+		cache:      make(map[string]entry),
+		pending:    make(map[string]struct{}),
+		sem:        make(chan struct{}, defaultConcurrency),
+	}
+	if r.ttl <= 0 {
+		r.ttl = defaultTTL
+	}
+	if r.maxEntries <= 0 {
+		r.maxEntries = defaultMaxEntries
+	}
+	if r.timeout <= 0 {
+		r.timeout = defaultTimeout
+	}
+
+	if conf.HostsFile != "" {
+		f, err := os.Open(conf.HostsFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "open hosts file")
+		}
+		defer f.Close()
+
+		static, err := parseHosts(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse hosts file (%s)", conf.HostsFile)
+		}
+		r.static = static
+	}
+	return r, nil
+}
+
+// parseHosts 解析 hosts 格式内容 同一地址重复出现时以第一次为准
+func parseHosts(r io.Reader) (map[string]string, error) {
+	hosts := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		ip := net.ParseIP(fields[0])
+		if ip == nil {
+			continue
+		}
+		addr := ip.String()
+		if _, ok := hosts[addr]; !ok {
+			hosts[addr] = fields[1]
+		}
+	}
+	return hosts, scanner.Err()
+}
+
+// Lookup 返回 addr 对应的主机名 未知时返回空
+//
+// 缓存过期的条目仍返回旧值 同时触发后台刷新
+func (r *Resolver) Lookup(addr string) string {
+	if name, ok := r.static[addr]; ok {
+		return name
+	}
+	if !r.enablePTR {
+		return ""
+	}
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	e, ok := r.cache[addr]
+	if ok && r.now().Before(e.expireAt) {
+		return e.name
+	}
+	r.refresh(addr)
+	return e.name
+}
+
+// refresh 发起后台 PTR 查询 并发已满时放弃本次查询 等待下次 Lookup 再次尝试
+//
+// 调用方需持有锁
+func (r *Resolver) refresh(addr string) {
+	if _, ok := r.pending[addr]; ok {
+		return
+	}
+	select {
+	case r.sem <- struct{}{}:
+	default:
+		return
+	}
+
+	r.pending[addr] = struct{}{}
+	go func() {
+		defer func() { <-r.sem }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+		names, err := r.lookupAddr(ctx, addr)
+		cancel()
+
+		// 解析失败同样缓存空值 避免对无 PTR 记录的地址反复查询
+		var name string
+		if err == nil && len(names) > 0 {
+			name = strings.TrimSuffix(names[0], ".")
+		}
+		r.store(addr, name)
+	}()
+}
+
+func (r *Resolver) store(addr, name string) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	delete(r.pending, addr)
+	now := r.now()
+	if _, ok := r.cache[addr]; !ok && len(r.cache) >= r.maxEntries {
+		r.evict(now)
+	}
+	r.cache[addr] = entry{name: name, expireAt: now.Add(r.ttl)}
+}
+
+// evict 腾出缓存空间 优先清理过期条目 均未过期时随机淘汰一条
+func (r *Resolver) evict(now time.Time) {
+	var evicted bool
+	for k, e := range r.cache {
+		if !now.Before(e.expireAt) {
+			delete(r.cache, k)
+			evicted = true
+		}
+	}
+	if evicted {
+		return
+	}
+	for k := range r.cache {
+		delete(r.cache, k)
+		return
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostnames
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHosts(t *testing.T) {
+	content := `
+# comment
+127.0.0.1 localhost
+10.0.0.1   db-primary db # alias
+10.0.0.1   db-replica
+::1        ip6-localhost
+not-an-ip  foo
+10.0.0.2
+`
+	hosts, err := parseHosts(strings.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"127.0.0.1": "localhost",
+		"10.0.0.1":  "db-primary",
+		"::1":       "ip6-localhost",
+	}, hosts)
+}
+
+func TestResolverStatic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	require.NoError(t, os.WriteFile(path, []byte("10.0.0.1 db-primary\n"), 0o644))
+
+	r, err := New(Config{HostsFile: path})
+	require.NoError(t, err)
+	assert.Equal(t, "db-primary", r.Lookup("10.0.0.1"))
+	assert.Empty(t, r.Lookup("10.0.0.2"))
+
+	_, err = New(Config{HostsFile: filepath.Join(t.TempDir(), "missing")})
+	assert.Error(t, err)
+}
+
+func TestResolverPTR(t *testing.T) {
+	r, err := New(Config{EnablePTR: true, TTL: time.Minute, MaxEntries: 2})
+	require.NoError(t, err)
+
+	now := time.Unix(1751356800, 0)
+	r.now = func() time.Time { return now }
+
+	calls := make(chan string, 8)
+	r.lookupAddr = func(_ context.Context, addr string) ([]string, error) {
+		calls <- addr
+		if addr == "10.0.0.9" {
+			return nil, errors.New("no such host")
+		}
+		return []string{"host-" + addr + "."}, nil
+	}
+	wait := func(addr string) {
+		assert.Equal(t, addr, <-calls)
+		assert.Eventually(t, func() bool {
+			r.mut.Lock()
+			defer r.mut.Unlock()
+			_, ok := r.pending[addr]
+			return !ok
+		}, time.Second, time.Millisecond)
+	}
+
+	// 首次查询异步进行 返回空
+	assert.Empty(t, r.Lookup("10.0.0.1"))
+	wait("10.0.0.1")
+	assert.Equal(t, "host-10.0.0.1", r.Lookup("10.0.0.1"))
+
+	// 解析失败同样缓存 TTL 内不再查询
+	assert.Empty(t, r.Lookup("10.0.0.9"))
+	wait("10.0.0.9")
+	assert.Empty(t, r.Lookup("10.0.0.9"))
+	assert.Len(t, calls, 0)
+
+	// 过期后返回旧值并后台刷新
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, "host-10.0.0.1", r.Lookup("10.0.0.1"))
+	wait("10.0.0.1")
+
+	// 超出容量时优先淘汰过期条目
+	assert.Empty(t, r.Lookup("10.0.0.3"))
+	wait("10.0.0.3")
+	r.mut.Lock()
+	assert.Len(t, r.cache, 2)
+	assert.NotContains(t, r.cache, "10.0.0.9")
+	r.mut.Unlock()
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idleconn
+
+import (
+	"sort"
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+// Conn 链接快照
+type Conn struct {
+	Proto    socket.L7Proto
+	Tuple    socket.Tuple // 客户端至服务端方向的四元组
+	ActiveAt time.Time
+	Closed   bool
+}
+
+// IdleFor 返回链接截至 now 的空闲时长
+func (c Conn) IdleFor(now time.Time) time.Duration {
+	if d := now.Sub(c.ActiveAt); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Event 链接进入空闲状态事件
+type Event struct {
+	Conn
+	Idle time.Duration
+}
+
+// Detector 空闲链接检测
+//
+// 长时间没有任何数据包的链接通常是连接池泄漏或者配置不当的长链接 持续占用服务端资源
+// 链接进入空闲状态时仅触发一次事件 重新活跃后再次空闲会再次触发
+type Detector struct {
+	threshold time.Duration
+	reported  map[socket.Tuple]time.Time // 已触发事件的链接及其触发时的最后活跃时间
+}
+
+// New 创建并返回 Detector 实例
+func New(threshold time.Duration) *Detector {
+	return &Detector{
+		threshold: threshold,
+		reported:  make(map[socket.Tuple]time.Time),
+	}
+}
+
+// Detect 返回本轮新进入空闲状态的链接 以及各协议当前空闲链接的数量
+//
+// 已关闭的链接即将被回收 不参与检测
+func (d *Detector) Detect(now time.Time, conns []Conn) ([]Event, map[socket.L7Proto]int) {
+	var events []Event
+	idle := make(map[socket.L7Proto]int)
+	reported := make(map[socket.Tuple]time.Time, len(d.reported))
+	for _, conn := range conns {
+		if conn.Closed {
+			continue
+		}
+		dur := conn.IdleFor(now)
+		if dur < d.threshold {
+			continue
+		}
+
+		idle[conn.Proto]++
+		reported[conn.Tuple] = conn.ActiveAt
+		if activeAt, ok := d.reported[conn.Tuple]; ok && activeAt.Equal(conn.ActiveAt) {
+			continue
+		}
+		events = append(events, Event{Conn: conn, Idle: dur})
+	}
+
+	// 重建记录 已恢复活跃或者已经被删除的链接不再保留
+	d.reported = reported
+	return events, idle
+}
+
+// SortByIdle 按照空闲时长降序排列
+func SortByIdle(conns []Conn) {
+	sort.SliceStable(conns, func(i, j int) bool {
+		return conns[i].ActiveAt.Before(conns[j].ActiveAt)
+	})
+}
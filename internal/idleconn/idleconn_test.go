@@ -0,0 +1,78 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idleconn
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+func newTuple(port socket.Port) socket.Tuple {
+	return socket.Tuple{
+		SrcIP:   socket.ToIPV4(net.ParseIP("10.0.0.1").To4()),
+		DstIP:   socket.ToIPV4(net.ParseIP("10.0.0.2").To4()),
+		SrcPort: port,
+		DstPort: 3306,
+	}
+}
+
+func TestDetector(t *testing.T) {
+	t0 := time.Unix(1751356800, 0)
+	d := New(time.Minute)
+
+	active := Conn{Proto: "mysql", Tuple: newTuple(50001), ActiveAt: t0}
+	leaked := Conn{Proto: "mysql", Tuple: newTuple(50002), ActiveAt: t0.Add(-2 * time.Minute)}
+	closed := Conn{Proto: "mysql", Tuple: newTuple(50003), ActiveAt: t0.Add(-time.Hour), Closed: true}
+
+	events, idle := d.Detect(t0, []Conn{active, leaked, closed})
+	assert.Equal(t, []Event{{Conn: leaked, Idle: 2 * time.Minute}}, events)
+	assert.Equal(t, map[socket.L7Proto]int{"mysql": 1}, idle)
+
+	// 持续空闲不再重复触发
+	events, idle = d.Detect(t0.Add(time.Minute), []Conn{active, leaked, closed})
+	assert.Equal(t, []Event{{Conn: active, Idle: time.Minute}}, events)
+	assert.Equal(t, map[socket.L7Proto]int{"mysql": 2}, idle)
+
+	// 重新活跃后再次空闲会再次触发
+	leaked.ActiveAt = t0.Add(time.Minute)
+	events, idle = d.Detect(t0.Add(90*time.Second), []Conn{active, leaked})
+	assert.Empty(t, events)
+	assert.Equal(t, map[socket.L7Proto]int{"mysql": 1}, idle)
+
+	events, _ = d.Detect(t0.Add(3*time.Minute), []Conn{leaked})
+	assert.Equal(t, []Event{{Conn: leaked, Idle: 2 * time.Minute}}, events)
+}
+
+func TestSortByIdle(t *testing.T) {
+	t0 := time.Unix(1751356800, 0)
+	conns := []Conn{
+		{Tuple: newTuple(1), ActiveAt: t0},
+		{Tuple: newTuple(2), ActiveAt: t0.Add(-time.Hour)},
+		{Tuple: newTuple(3), ActiveAt: t0.Add(-time.Minute)},
+	}
+	SortByIdle(conns)
+
+	var ports []socket.Port
+	for _, conn := range conns {
+		ports = append(ports, conn.Tuple.SrcPort)
+	}
+	assert.Equal(t, []socket.Port{2, 3, 1}, ports)
+	assert.Equal(t, time.Hour, conns[0].IdleFor(t0))
+	assert.Zero(t, conns[2].IdleFor(t0.Add(-time.Second)))
+}
@@ -0,0 +1,409 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jsonscan 容忍截断的 JSON 扫描器
+//
+// 捕获的 body 受 maxBodySize 限制 大文档只能拿到前缀 json.Valid 会直接判定为非法
+// Scanner 单次遍历已读取的内容 校验语法的同时按照路径提取标量字段 遇到数据结尾时视为截断而非错误
+package jsonscan
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/packetd/packetd/internal/json"
+)
+
+const (
+	// maxDepth 最大嵌套深度 超出视为非法文档
+	maxDepth = 256
+
+	// maxValueSize 提取的字段值最大长度 超出部分截断
+	maxValueSize = 256
+)
+
+// Result 扫描结果
+type Result struct {
+	// Valid 已读取的内容符合 JSON 语法
+	Valid bool
+
+	// Complete 读取到了完整的 JSON 文档 为 true 时 Valid 必然为 true
+	Complete bool
+
+	// Fields 命中路径的标量字段 字符串为解码后的内容 其余类型为原始文本
+	Fields map[string]string
+}
+
+// Scanner 按照配置的路径提取字段 可并发使用
+//
+// 路径以 . 分隔 数组元素使用下标 如 error.code / errors.0.message
+type Scanner struct {
+	paths    map[string]struct{}
+	prefixes map[string]struct{} // 所有路径的祖先节点 用于跳过无关的子树
+}
+
+// New 创建 Scanner 实例 paths 为空时仅校验语法
+func New(paths []string) *Scanner {
+	s := &Scanner{
+		paths:    make(map[string]struct{}, len(paths)),
+		prefixes: make(map[string]struct{}),
+	}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		s.paths[path] = struct{}{}
+		for i := range path {
+			if path[i] == '.' {
+				s.prefixes[path[:i]] = struct{}{}
+			}
+		}
+	}
+	return s
+}
+
+// Scan 扫描 b 并提取字段 截断前已经完整读取的字段均会被提取
+func (s *Scanner) Scan(b []byte) Result {
+	p := &parser{s: s, b: b}
+	p.skipSpace()
+	if p.i < len(p.b) {
+		p.value("", len(s.paths) > 0, 0)
+	} else {
+		p.eof = true
+	}
+	if !p.eof && !p.err {
+		p.skipSpace()
+		if p.i < len(p.b) {
+			p.err = true // 文档之后存在多余内容
+		}
+	}
+	return Result{
+		Valid:    !p.err,
+		Complete: !p.err && !p.eof,
+		Fields:   p.fields,
+	}
+}
+
+type parser struct {
+	s      *Scanner
+	b      []byte
+	i      int
+	eof    bool // 读取到数据结尾
+	err    bool // 出现语法错误
+	fields map[string]string
+}
+
+func (p *parser) done() bool {
+	return p.eof || p.err
+}
+
+func (p *parser) skipSpace() {
+	for p.i < len(p.b) {
+		switch p.b[p.i] {
+		case ' ', '\t', '\r', '\n':
+			p.i++
+		default:
+			return
+		}
+	}
+}
+
+// next 跳过空白后返回下一个字符 数据结尾时标记 eof
+func (p *parser) next() (byte, bool) {
+	p.skipSpace()
+	if p.i >= len(p.b) {
+		p.eof = true
+		return 0, false
+	}
+	return p.b[p.i], true
+}
+
+// child 返回子节点的路径 以及是否需要继续跟踪
+func (p *parser) child(path string, tracked bool, key string) (string, bool) {
+	if !tracked {
+		return "", false
+	}
+	if path != "" {
+		key = path + "." + key
+	}
+	if _, ok := p.s.paths[key]; ok {
+		return key, true
+	}
+	_, ok := p.s.prefixes[key]
+	return key, ok
+}
+
+func (p *parser) record(path string, tracked bool, raw []byte, quoted bool) {
+	if !tracked {
+		return
+	}
+	if _, ok := p.s.paths[path]; !ok {
+		return
+	}
+
+	v := string(raw)
+	if quoted {
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return
+		}
+	}
+	if len(v) > maxValueSize {
+		v = v[:maxValueSize]
+	}
+	if p.fields == nil {
+		p.fields = make(map[string]string)
+	}
+	p.fields[path] = v
+}
+
+func (p *parser) value(path string, tracked bool, depth int) {
+	if depth > maxDepth {
+		p.err = true
+		return
+	}
+	c, ok := p.next()
+	if !ok {
+		return
+	}
+
+	start := p.i
+	switch {
+	case c == '{':
+		p.object(path, tracked, depth)
+	case c == '[':
+		p.array(path, tracked, depth)
+	case c == '"':
+		if p.str() {
+			p.record(path, tracked, p.b[start:p.i], true)
+		}
+	case c == '-' || (c >= '0' && c <= '9'):
+		// 数字位于数据结尾时可能并不完整 不做提取
+		if p.number() && p.i < len(p.b) {
+			p.record(path, tracked, p.b[start:p.i], false)
+		}
+	case c == 't':
+		p.literal("true", path, tracked)
+	case c == 'f':
+		p.literal("false", path, tracked)
+	case c == 'n':
+		p.literal("null", path, tracked)
+	default:
+		p.err = true
+	}
+}
+
+func (p *parser) object(path string, tracked bool, depth int) {
+	p.i++ // {
+	c, ok := p.next()
+	if !ok {
+		return
+	}
+	if c == '}' {
+		p.i++
+		return
+	}
+
+	for {
+		if c != '"' {
+			p.err = true
+			return
+		}
+		start := p.i
+		if !p.str() {
+			return
+		}
+		var key string
+		if tracked {
+			if err := json.Unmarshal(p.b[start:p.i], &key); err != nil {
+				p.err = true
+				return
+			}
+		}
+
+		if c, ok = p.next(); !ok {
+			return
+		}
+		if c != ':' {
+			p.err = true
+			return
+		}
+		p.i++
+
+		childPath, childTracked := p.child(path, tracked, key)
+		if p.value(childPath, childTracked, depth+1); p.done() {
+			return
+		}
+
+		if c, ok = p.next(); !ok {
+			return
+		}
+		p.i++
+		switch c {
+		case '}':
+			return
+		case ',':
+			if c, ok = p.next(); !ok {
+				return
+			}
+		default:
+			p.err = true
+			return
+		}
+	}
+}
+
+func (p *parser) array(path string, tracked bool, depth int) {
+	p.i++ // [
+	c, ok := p.next()
+	if !ok {
+		return
+	}
+	if c == ']' {
+		p.i++
+		return
+	}
+
+	for idx := 0; ; idx++ {
+		childPath, childTracked := p.child(path, tracked, strconv.Itoa(idx))
+		if p.value(childPath, childTracked, depth+1); p.done() {
+			return
+		}
+
+		if c, ok = p.next(); !ok {
+			return
+		}
+		p.i++
+		switch c {
+		case ']':
+			return
+		case ',':
+		default:
+			p.err = true
+			return
+		}
+	}
+}
+
+// str 读取字符串 返回是否完整读取
+func (p *parser) str() bool {
+	p.i++ // "
+	for p.i < len(p.b) {
+		c := p.b[p.i]
+		switch {
+		case c == '"':
+			p.i++
+			return true
+		case c == '\\':
+			if p.i+1 >= len(p.b) {
+				p.i = len(p.b)
+				p.eof = true
+				return false
+			}
+			switch p.b[p.i+1] {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				p.i += 2
+			case 'u':
+				for j := p.i + 2; j < p.i+6; j++ {
+					if j >= len(p.b) {
+						p.i = len(p.b)
+						p.eof = true
+						return false
+					}
+					if !isHex(p.b[j]) {
+						p.err = true
+						return false
+					}
+				}
+				p.i += 6
+			default:
+				p.err = true
+				return false
+			}
+		case c < 0x20:
+			p.err = true
+			return false
+		default:
+			p.i++
+		}
+	}
+	p.eof = true
+	return false
+}
+
+// number 读取数字 返回已读取的部分是否合法
+func (p *parser) number() bool {
+	if p.b[p.i] == '-' {
+		p.i++
+	}
+	if !p.digits(true) {
+		return false
+	}
+	if p.i < len(p.b) && p.b[p.i] == '.' {
+		p.i++
+		if !p.digits(false) {
+			return false
+		}
+	}
+	if p.i < len(p.b) && (p.b[p.i] == 'e' || p.b[p.i] == 'E') {
+		p.i++
+		if p.i < len(p.b) && (p.b[p.i] == '+' || p.b[p.i] == '-') {
+			p.i++
+		}
+		if !p.digits(false) {
+			return false
+		}
+	}
+	if p.i >= len(p.b) {
+		p.eof = true
+	}
+	return true
+}
+
+// digits 读取至少一位数字 integer 为 true 时不允许前导零
+func (p *parser) digits(integer bool) bool {
+	start := p.i
+	for p.i < len(p.b) && p.b[p.i] >= '0' && p.b[p.i] <= '9' {
+		p.i++
+	}
+	switch {
+	case p.i == start && p.i >= len(p.b):
+		p.eof = true
+		return false
+	case p.i == start:
+		p.err = true
+		return false
+	case integer && p.b[start] == '0' && p.i-start > 1:
+		p.err = true
+		return false
+	}
+	return true
+}
+
+func (p *parser) literal(lit string, path string, tracked bool) {
+	rest := p.b[p.i:]
+	n := min(len(rest), len(lit))
+	if !strings.HasPrefix(lit, string(rest[:n])) {
+		p.err = true
+		return
+	}
+	p.i += n
+	if n < len(lit) {
+		p.eof = true
+		return
+	}
+	p.record(path, tracked, rest[:n], false)
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
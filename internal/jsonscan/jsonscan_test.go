@@ -0,0 +1,104 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonscan
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanSyntax(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		valid    bool
+		complete bool
+	}{
+		{name: "Object", input: `{"a": [1, -2.5e3, true, null, "xé"]}`, valid: true, complete: true},
+		{name: "Array", input: ` [ {}, [], "" ] `, valid: true, complete: true},
+		{name: "TruncatedObject", input: `{"a": {"b": [1, 2`, valid: true},
+		{name: "TruncatedKey", input: `{"abc`, valid: true},
+		{name: "TruncatedEscape", input: `{"a": "x\u00`, valid: true},
+		{name: "TruncatedLiteral", input: `[tr`, valid: true},
+		{name: "TruncatedAfterComma", input: `[1,`, valid: true},
+		{name: "Empty", input: ``, valid: true},
+		{name: "TrailingComma", input: `[1,]`},
+		{name: "BadLiteral", input: `[trye]`},
+		{name: "LeadingZero", input: `[01]`},
+		{name: "MissingColon", input: `{"a" 1}`},
+		{name: "Trailing", input: `{} {}`},
+		{name: "Text", input: `hello world`},
+		{name: "ControlChar", input: "[\"a\x01\"]"},
+		{name: "TooDeep", input: strings.Repeat("[", maxDepth+2)},
+	}
+
+	s := New(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := s.Scan([]byte(tt.input))
+			assert.Equal(t, tt.valid, res.Valid)
+			assert.Equal(t, tt.complete, res.Complete)
+			if tt.complete {
+				assert.True(t, json.Valid([]byte(tt.input)))
+			}
+		})
+	}
+}
+
+func TestScanFields(t *testing.T) {
+	s := New([]string{"code", "error.message", "items.1.id", "ok", "count", "missing", "items.1"})
+
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]string
+	}{
+		{
+			name:  "Complete",
+			input: `{"code": 500, "ok": false, "error": {"message": "a\"b", "stack": ["x"]}, "items": [{"id": 1}, {"id": "x2"}]}`,
+			want:  map[string]string{"code": "500", "ok": "false", "error.message": `a"b`, "items.1.id": "x2"},
+		},
+		{
+			name:  "Truncated",
+			input: `{"code": 404, "error": {"message": "not found"}, "items": [{"id": 1}, {"id": "x2`,
+			want:  map[string]string{"code": "404", "error.message": "not found"},
+		},
+		{
+			name:  "TruncatedNumber",
+			input: `{"ok": true, "count": 12`,
+			want:  map[string]string{"ok": "true"},
+		},
+		{
+			name:  "NestedIgnored",
+			input: `{"other": {"code": 1}, "error": "plain"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := s.Scan([]byte(tt.input))
+			assert.True(t, res.Valid)
+			assert.Equal(t, tt.want, res.Fields)
+		})
+	}
+}
+
+func TestScanValueSize(t *testing.T) {
+	s := New([]string{"v"})
+	res := s.Scan([]byte(`{"v": "` + strings.Repeat("a", maxValueSize*2) + `"}`))
+	assert.Len(t, res.Fields["v"], maxValueSize)
+}
@@ -0,0 +1,476 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadgen
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+// Generator 生成单个协议一次请求来回的字节流
+//
+// 生成的数据需保证能被对应协议的 decoder 完整解析并配对
+type Generator interface {
+	// Proto 返回 Layer7 协议
+	Proto() socket.L7Proto
+
+	// Port 返回服务端端口
+	Port() socket.Port
+
+	// Next 返回第 n 次请求来回的请求和响应数据
+	Next(n uint32) (req, rsp []byte)
+}
+
+// SingleUseGenerator 每条链接仅产生一次请求来回的协议（如 TLS 握手）
+//
+// 每次请求来回结束后都会删除链接并使用新的客户端端口重新建链
+type SingleUseGenerator interface {
+	SingleUse() bool
+}
+
+var generators = map[socket.L7Proto]func() Generator{
+	socket.L7ProtoHTTP:       func() Generator { return httpGenerator{} },
+	socket.L7ProtoHTTP2:      func() Generator { return http2Generator{} },
+	socket.L7ProtoGRPC:       func() Generator { return grpcGenerator{} },
+	socket.L7ProtoMySQL:      func() Generator { return mysqlGenerator{} },
+	socket.L7ProtoPostgreSQL: func() Generator { return postgresqlGenerator{} },
+	socket.L7ProtoRedis:      func() Generator { return redisGenerator{} },
+	socket.L7ProtoMongoDB:    func() Generator { return mongodbGenerator{} },
+	socket.L7ProtoKafka:      func() Generator { return kafkaGenerator{} },
+	socket.L7ProtoAMQP:       func() Generator { return amqpGenerator{} },
+	socket.L7ProtoTLS:        func() Generator { return tlsGenerator{} },
+	socket.L7ProtoDNS:        func() Generator { return dnsGenerator{} },
+	socket.L7ProtoNTP:        func() Generator { return ntpGenerator{} },
+}
+
+// NewGenerator 返回协议对应的 Generator 不支持的协议返回 false
+func NewGenerator(proto socket.L7Proto) (Generator, bool) {
+	newGen, ok := generators[proto]
+	if !ok {
+		return nil, false
+	}
+	return newGen(), true
+}
+
+// Protocols 返回支持生成流量的协议列表
+func Protocols() []string {
+	lst := make([]string, 0, len(generators))
+	for proto := range generators {
+		lst = append(lst, string(proto))
+	}
+	sort.Strings(lst)
+	return lst
+}
+
+type httpGenerator struct{}
+
+func (httpGenerator) Proto() socket.L7Proto { return socket.L7ProtoHTTP }
+
+func (httpGenerator) Port() socket.Port { return 80 }
+
+func (httpGenerator) Next(n uint32) ([]byte, []byte) {
+	req := fmt.Sprintf("GET /api/v1/items/%d HTTP/1.1\r\n"+
+		"Host: loadgen.packetd.local\r\n"+
+		"User-Agent: packetd-loadgen/1.0\r\n"+
+		"Accept: application/json\r\n"+
+		"\r\n", n)
+
+	body := fmt.Sprintf(`{"id":%d,"name":"item-%d","tags":["a","b","c"],"price":%d.99}`, n, n, n%1000)
+	rsp := fmt.Sprintf("HTTP/1.1 200 OK\r\n"+
+		"Content-Type: application/json\r\n"+
+		"Content-Length: %d\r\n"+
+		"\r\n%s", len(body), body)
+	return []byte(req), []byte(rsp)
+}
+
+type mysqlGenerator struct{}
+
+func (mysqlGenerator) Proto() socket.L7Proto { return socket.L7ProtoMySQL }
+
+func (mysqlGenerator) Port() socket.Port { return 3306 }
+
+// mysqlPacket 按照 3 字节长度 + 1 字节序号的格式封装 MySQL 数据包
+func mysqlPacket(seq byte, payload []byte) []byte {
+	b := make([]byte, 4, 4+len(payload))
+	b[0] = byte(len(payload))
+	b[1] = byte(len(payload) >> 8)
+	b[2] = byte(len(payload) >> 16)
+	b[3] = seq
+	return append(b, payload...)
+}
+
+func (mysqlGenerator) Next(n uint32) ([]byte, []byte) {
+	query := fmt.Sprintf("SELECT id, name, email FROM users WHERE id = %d", n)
+	req := mysqlPacket(0, append([]byte{0x03}, query...)) // COM_QUERY
+
+	// OK Packet: header / affected_rows / last_insert_id / status_flags / warnings
+	rsp := mysqlPacket(1, []byte{0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00})
+	return req, rsp
+}
+
+type kafkaGenerator struct{}
+
+func (kafkaGenerator) Proto() socket.L7Proto { return socket.L7ProtoKafka }
+
+func (kafkaGenerator) Port() socket.Port { return 9092 }
+
+func (kafkaGenerator) Next(n uint32) ([]byte, []byte) {
+	const clientID = "loadgen"
+	const topic = "orders"
+
+	// Metadata v0 请求: api_key / api_version / correlation_id / client_id / [topics]
+	req := make([]byte, 4, 64)
+	req = binary.BigEndian.AppendUint16(req, 3)
+	req = binary.BigEndian.AppendUint16(req, 0)
+	req = binary.BigEndian.AppendUint32(req, n)
+	req = binary.BigEndian.AppendUint16(req, uint16(len(clientID)))
+	req = append(req, clientID...)
+	req = binary.BigEndian.AppendUint32(req, 1)
+	req = binary.BigEndian.AppendUint16(req, uint16(len(topic)))
+	req = append(req, topic...)
+	binary.BigEndian.PutUint32(req[:4], uint32(len(req)-4))
+
+	// Metadata v0 响应: correlation_id / [brokers] / [topics] 均为空数组
+	rsp := make([]byte, 4, 16)
+	rsp = binary.BigEndian.AppendUint32(rsp, n)
+	rsp = binary.BigEndian.AppendUint32(rsp, 0)
+	rsp = binary.BigEndian.AppendUint32(rsp, 0)
+	binary.BigEndian.PutUint32(rsp[:4], uint32(len(rsp)-4))
+	return req, rsp
+}
+
+// http2Frame 按照 length(3) / type(1) / flags(1) / streamID(4) 的格式封装 HTTP/2 帧
+func http2Frame(streamID uint32, typ, flags byte, payload []byte) []byte {
+	b := []byte{byte(len(payload) >> 16), byte(len(payload) >> 8), byte(len(payload)), typ, flags}
+	b = binary.BigEndian.AppendUint32(b, streamID)
+	return append(b, payload...)
+}
+
+// hpackLiteral 使用 Literal Header Field without Indexing 编码头部 避免动态表随请求增长
+func hpackLiteral(fields ...string) []byte {
+	var b []byte
+	for i := 0; i+1 < len(fields); i += 2 {
+		b = append(b, 0x00, byte(len(fields[i])))
+		b = append(b, fields[i]...)
+		b = append(b, byte(len(fields[i+1])))
+		b = append(b, fields[i+1]...)
+	}
+	return b
+}
+
+const (
+	http2FrameData    = 0x0
+	http2FrameHeaders = 0x1
+
+	http2FlagEndStream  = 0x1
+	http2FlagEndHeaders = 0x4
+)
+
+type http2Generator struct{}
+
+func (http2Generator) Proto() socket.L7Proto { return socket.L7ProtoHTTP2 }
+
+func (http2Generator) Port() socket.Port { return 8080 }
+
+func (http2Generator) Next(n uint32) ([]byte, []byte) {
+	stream := 2*n - 1 // 客户端发起的 stream 为奇数
+	body := fmt.Sprintf(`{"id":%d}`, n)
+	req := http2Frame(stream, http2FrameHeaders, http2FlagEndHeaders, hpackLiteral(
+		":method", "POST",
+		":scheme", "http",
+		":path", "/api/v1/items",
+		":authority", "loadgen.packetd.local",
+		"content-type", "application/json",
+	))
+	req = append(req, http2Frame(stream, http2FrameData, http2FlagEndStream, []byte(body))...)
+
+	rsp := http2Frame(stream, http2FrameHeaders, http2FlagEndHeaders, hpackLiteral(
+		":status", "200",
+		"content-type", "application/json",
+	))
+	rsp = append(rsp, http2Frame(stream, http2FrameData, http2FlagEndStream, []byte(body))...)
+	return req, rsp
+}
+
+type grpcGenerator struct{}
+
+func (grpcGenerator) Proto() socket.L7Proto { return socket.L7ProtoGRPC }
+
+func (grpcGenerator) Port() socket.Port { return 9090 }
+
+// grpcMessage 按照 compressed(1) / length(4) 的格式封装 gRPC 消息
+func grpcMessage(payload []byte) []byte {
+	b := binary.BigEndian.AppendUint32([]byte{0}, uint32(len(payload)))
+	return append(b, payload...)
+}
+
+func (grpcGenerator) Next(n uint32) ([]byte, []byte) {
+	stream := 2*n - 1
+	req := http2Frame(stream, http2FrameHeaders, http2FlagEndHeaders, hpackLiteral(
+		":method", "POST",
+		":scheme", "http",
+		":path", "/packetd.loadgen.Items/Get",
+		":authority", "loadgen.packetd.local",
+		"content-type", "application/grpc",
+	))
+	req = append(req, http2Frame(stream, http2FrameData, http2FlagEndStream, grpcMessage(binary.AppendUvarint([]byte{0x08}, uint64(n))))...)
+
+	rsp := http2Frame(stream, http2FrameHeaders, http2FlagEndHeaders, hpackLiteral(
+		":status", "200",
+		"content-type", "application/grpc",
+	))
+	rsp = append(rsp, http2Frame(stream, http2FrameData, 0, grpcMessage([]byte("\x0a\x04item")))...)
+	rsp = append(rsp, http2Frame(stream, http2FrameHeaders, http2FlagEndHeaders|http2FlagEndStream, hpackLiteral(
+		"grpc-status", "0",
+		"grpc-message", "OK",
+	))...)
+	return req, rsp
+}
+
+type postgresqlGenerator struct{}
+
+func (postgresqlGenerator) Proto() socket.L7Proto { return socket.L7ProtoPostgreSQL }
+
+func (postgresqlGenerator) Port() socket.Port { return 5432 }
+
+// postgresqlMessage 按照 type(1) / length(4) 的格式封装 PostgreSQL 消息 length 包含自身
+func postgresqlMessage(typ byte, payload []byte) []byte {
+	b := binary.BigEndian.AppendUint32([]byte{typ}, uint32(len(payload)+4))
+	return append(b, payload...)
+}
+
+func (postgresqlGenerator) Next(n uint32) ([]byte, []byte) {
+	query := fmt.Sprintf("SELECT id, name FROM users WHERE id = %d", n)
+	req := postgresqlMessage('Q', append([]byte(query), 0))
+
+	rsp := postgresqlMessage('C', append([]byte("SELECT 1"), 0))
+	rsp = append(rsp, postgresqlMessage('Z', []byte{'I'})...)
+	return req, rsp
+}
+
+type redisGenerator struct{}
+
+func (redisGenerator) Proto() socket.L7Proto { return socket.L7ProtoRedis }
+
+func (redisGenerator) Port() socket.Port { return 6379 }
+
+func (redisGenerator) Next(n uint32) ([]byte, []byte) {
+	key := "item:" + strconv.Itoa(int(n))
+	value := fmt.Sprintf(`{"id":%d}`, n)
+	req := fmt.Sprintf("*2\r\n$3\r\nGET\r\n$%d\r\n%s\r\n", len(key), key)
+	rsp := fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	return []byte(req), []byte(rsp)
+}
+
+type mongodbGenerator struct{}
+
+func (mongodbGenerator) Proto() socket.L7Proto { return socket.L7ProtoMongoDB }
+
+func (mongodbGenerator) Port() socket.Port { return 27017 }
+
+// mongodbMessage 按照 OP_MSG 格式封装单个 body section
+func mongodbMessage(requestID, responseTo uint32, doc bson.D) []byte {
+	payload, _ := bson.Marshal(doc)
+
+	const opMsg = 2013
+	b := make([]byte, 4, 21+len(payload))
+	b = binary.LittleEndian.AppendUint32(b, requestID)
+	b = binary.LittleEndian.AppendUint32(b, responseTo)
+	b = binary.LittleEndian.AppendUint32(b, opMsg)
+	b = binary.LittleEndian.AppendUint32(b, 0) // flagBits
+	b = append(b, 0)                           // section kind: body
+	b = append(b, payload...)
+	binary.LittleEndian.PutUint32(b[:4], uint32(len(b)))
+	return b
+}
+
+func (mongodbGenerator) Next(n uint32) ([]byte, []byte) {
+	req := mongodbMessage(n, 0, bson.D{
+		{Key: "find", Value: "items"},
+		{Key: "filter", Value: bson.D{{Key: "id", Value: int64(n)}}},
+		{Key: "$db", Value: "loadgen"},
+	})
+	rsp := mongodbMessage(1<<30|n, n, bson.D{
+		{Key: "cursor", Value: bson.D{
+			{Key: "firstBatch", Value: bson.A{bson.D{{Key: "id", Value: int64(n)}}}},
+			{Key: "id", Value: int64(0)},
+			{Key: "ns", Value: "loadgen.items"},
+		}},
+		{Key: "ok", Value: 1.0},
+	})
+	return req, rsp
+}
+
+type amqpGenerator struct{}
+
+func (amqpGenerator) Proto() socket.L7Proto { return socket.L7ProtoAMQP }
+
+func (amqpGenerator) Port() socket.Port { return 5672 }
+
+// amqpMethodFrame 按照 type(1) / channel(2) / size(4) / payload / frame-end(1) 的格式封装 Method 帧
+func amqpMethodFrame(channel, class, method uint16, args []byte) []byte {
+	payload := binary.BigEndian.AppendUint16(nil, class)
+	payload = binary.BigEndian.AppendUint16(payload, method)
+	payload = append(payload, args...)
+
+	b := binary.BigEndian.AppendUint16([]byte{0x01}, channel)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(payload)))
+	b = append(b, payload...)
+	return append(b, 0xce)
+}
+
+func (amqpGenerator) Next(n uint32) ([]byte, []byte) {
+	// Basic.Qos: prefetch-size(4) / prefetch-count(2) / global(1)
+	args := binary.BigEndian.AppendUint32(nil, 0)
+	args = binary.BigEndian.AppendUint16(args, uint16(n%1000+1))
+	args = append(args, 0)
+
+	req := amqpMethodFrame(1, 60, 10, args)
+	rsp := amqpMethodFrame(1, 60, 11, nil) // Basic.Qos-Ok
+	return req, rsp
+}
+
+type tlsGenerator struct{}
+
+func (tlsGenerator) Proto() socket.L7Proto { return socket.L7ProtoTLS }
+
+func (tlsGenerator) Port() socket.Port { return 443 }
+
+// SingleUse TLS 仅解析建链阶段的明文握手
+func (tlsGenerator) SingleUse() bool { return true }
+
+func tlsVector8(b []byte) []byte {
+	return append([]byte{byte(len(b))}, b...)
+}
+
+func tlsVector16(b []byte) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(b))), b...)
+}
+
+func tlsExtension(typ uint16, data []byte) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, typ), tlsVector16(data)...)
+}
+
+// tlsHandshakeRecord 将单个握手消息封装为 Handshake Record
+func tlsHandshakeRecord(msgType byte, body []byte) []byte {
+	msg := append([]byte{msgType, byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body))}, body...)
+	return append([]byte{0x16, 0x03, 0x01}, tlsVector16(msg)...)
+}
+
+func (tlsGenerator) Next(n uint32) ([]byte, []byte) {
+	const (
+		extServerName        = 0
+		extALPN              = 16
+		extSupportedVersions = 43
+	)
+
+	serverName := "api-" + strconv.Itoa(int(n%100)) + ".loadgen.packetd.local"
+	exts := tlsExtension(extServerName, tlsVector16(append([]byte{0}, tlsVector16([]byte(serverName))...)))
+	exts = append(exts, tlsExtension(extALPN, tlsVector16(tlsVector8([]byte("h2"))))...)
+	exts = append(exts, tlsExtension(extSupportedVersions, tlsVector8([]byte{0x03, 0x04, 0x03, 0x03}))...)
+
+	hello := []byte{0x03, 0x03}
+	hello = append(hello, make([]byte, 32)...) // random
+	hello = append(hello, tlsVector8(nil)...)  // session id
+	hello = append(hello, tlsVector16([]byte{0x13, 0x01, 0x13, 0x02})...)
+	hello = append(hello, tlsVector8([]byte{0})...)
+	hello = append(hello, tlsVector16(exts)...)
+	req := tlsHandshakeRecord(0x01, hello)
+
+	// TLS 1.3 ServerHello 之后的消息均被加密 decoder 在 ServerHello 后即完成解析
+	hello = []byte{0x03, 0x03}
+	hello = append(hello, make([]byte, 32)...)
+	hello = append(hello, tlsVector8(nil)...)
+	hello = append(hello, 0x13, 0x01, 0x00)
+	hello = append(hello, tlsVector16(tlsExtension(extSupportedVersions, []byte{0x03, 0x04}))...)
+	rsp := tlsHandshakeRecord(0x02, hello)
+	return req, rsp
+}
+
+type dnsGenerator struct{}
+
+func (dnsGenerator) Proto() socket.L7Proto { return socket.L7ProtoDNS }
+
+func (dnsGenerator) Port() socket.Port { return 53 }
+
+func serializeDNS(msg *layers.DNS) []byte {
+	buf := gopacket.NewSerializeBuffer()
+	_ = msg.SerializeTo(buf, gopacket.SerializeOptions{FixLengths: true})
+	return buf.Bytes()
+}
+
+func (dnsGenerator) Next(n uint32) ([]byte, []byte) {
+	name := []byte("api-" + strconv.Itoa(int(n%100)) + ".loadgen.packetd.local")
+	question := layers.DNSQuestion{Name: name, Type: layers.DNSTypeA, Class: layers.DNSClassIN}
+
+	req := serializeDNS(&layers.DNS{
+		ID:        uint16(n),
+		RD:        true,
+		OpCode:    layers.DNSOpCodeQuery,
+		QDCount:   1,
+		Questions: []layers.DNSQuestion{question},
+	})
+	rsp := serializeDNS(&layers.DNS{
+		ID:        uint16(n),
+		QR:        true,
+		RD:        true,
+		RA:        true,
+		OpCode:    layers.DNSOpCodeQuery,
+		QDCount:   1,
+		ANCount:   1,
+		Questions: []layers.DNSQuestion{question},
+		Answers: []layers.DNSResourceRecord{{
+			Name:  name,
+			Type:  layers.DNSTypeA,
+			Class: layers.DNSClassIN,
+			TTL:   300,
+			IP:    net.IPv4(10, 1, byte(n>>8), byte(n)).To4(),
+		}},
+	})
+	return req, rsp
+}
+
+type ntpGenerator struct{}
+
+func (ntpGenerator) Proto() socket.L7Proto { return socket.L7ProtoNTP }
+
+func (ntpGenerator) Port() socket.Port { return 123 }
+
+func (ntpGenerator) Next(n uint32) ([]byte, []byte) {
+	const packetSize = 48
+
+	// Transmit Timestamp 作为事务 ID 服务端原样回填至 Origin Timestamp
+	transmit := binary.BigEndian.AppendUint64(nil, uint64(n)<<32|uint64(n))
+
+	req := make([]byte, packetSize)
+	req[0] = 4<<3 | 3 // VN=4 Mode=client
+	copy(req[40:48], transmit)
+
+	rsp := make([]byte, packetSize)
+	rsp[0] = 4<<3 | 4 // VN=4 Mode=server
+	rsp[1] = 2        // stratum
+	copy(rsp[24:32], transmit)
+	copy(rsp[32:40], transmit)
+	copy(rsp[40:48], transmit)
+	return req, rsp
+}
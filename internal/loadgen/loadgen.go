@@ -0,0 +1,295 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol"
+)
+
+const (
+	// segmentSize 单个 TCP 分段的最大 payload 与常见 MSS 保持一致
+	segmentSize = 1448
+
+	// latency 合成请求来回的耗时
+	latency = time.Millisecond
+)
+
+// Config 压测配置
+type Config struct {
+	// Protocols 参与压测的协议 为空时使用全部支持的协议
+	Protocols []string
+
+	// Conns 每个协议的并发链接数
+	Conns int
+
+	// Rate 每秒生成的请求来回上限 0 为不限速
+	Rate int
+
+	// Duration 压测时长
+	Duration time.Duration
+
+	// Workers 并发 worker 数量 默认为 common.Concurrency()
+	Workers int
+}
+
+// Result 压测结果
+type Result struct {
+	Elapsed    time.Duration
+	Packets    uint64
+	Bytes      uint64
+	RoundTrips map[socket.L7Proto]uint64
+
+	// Generated 合成的请求来回数量 与 RoundTrips 之和的差值即为未被解析或配对的数量
+	Generated uint64
+}
+
+// Total 返回所有协议的请求来回总数
+func (r *Result) Total() uint64 {
+	var n uint64
+	for _, v := range r.RoundTrips {
+		n += v
+	}
+	return n
+}
+
+func (r *Result) String() string {
+	secs := r.Elapsed.Seconds()
+	if secs <= 0 {
+		secs = 1
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "elapsed:    %s\n", r.Elapsed.Truncate(time.Millisecond))
+	fmt.Fprintf(&sb, "packets:    %d (%.0f/s)\n", r.Packets, float64(r.Packets)/secs)
+	fmt.Fprintf(&sb, "throughput: %.2f MB/s\n", float64(r.Bytes)/secs/1024/1024)
+	fmt.Fprintf(&sb, "roundtrips: %d (%.0f/s)\n", r.Total(), float64(r.Total())/secs)
+
+	protos := make([]string, 0, len(r.RoundTrips))
+	for proto := range r.RoundTrips {
+		protos = append(protos, string(proto))
+	}
+	sort.Strings(protos)
+	for _, proto := range protos {
+		n := r.RoundTrips[socket.L7Proto(proto)]
+		fmt.Fprintf(&sb, "  - %-8s %d (%.0f/s)\n", proto, n, float64(n)/secs)
+	}
+	return sb.String()
+}
+
+// conn 合成的 TCP 链接 记录双向的 seq
+type conn struct {
+	gen    Generator
+	pool   protocol.ConnPool
+	st     socket.Tuple
+	n      uint32
+	reqSeq uint32
+	rspSeq uint32
+}
+
+// Run 按照配置合成流量并送入各协议的解析链路 返回可达到的吞吐
+//
+// 数据包不经过网卡以及抓包引擎 直接从 connstream 重组开始 覆盖 decoder 以及请求配对的全部开销
+// 可在上线前评估当前主机的解析能力
+func Run(ctx context.Context, cfg Config) (*Result, error) {
+	if len(cfg.Protocols) == 0 {
+		cfg.Protocols = Protocols()
+	}
+	if cfg.Conns <= 0 {
+		cfg.Conns = 1
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = common.Concurrency()
+	}
+
+	var conns []*conn
+	var pools []protocol.ConnPool
+	for idx, name := range cfg.Protocols {
+		newGen, ok := generators[socket.L7Proto(name)]
+		if !ok {
+			return nil, errors.Errorf("unsupported protocol (%s), available: %v", name, Protocols())
+		}
+		create, err := protocol.Get(socket.L7Proto(name))
+		if err != nil {
+			return nil, err
+		}
+
+		gen := newGen()
+		pool := create(common.NewOptions())
+		pools = append(pools, pool)
+		for i := 0; i < cfg.Conns; i++ {
+			conns = append(conns, &conn{
+				gen:  gen,
+				pool: pool,
+				st: socket.Tuple{
+					SrcIP:   socket.ToIPV4(net.IPv4(10, 0, byte(idx), byte(i%250+1)).To4()),
+					SrcPort: socket.Port(10000 + i),
+					DstIP:   socket.ToIPV4(net.IPv4(10, 1, 0, 1).To4()),
+					DstPort: gen.Port(),
+				},
+			})
+		}
+	}
+	defer func() {
+		for _, pool := range pools {
+			pool.Clean()
+		}
+	}()
+
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	result := &Result{RoundTrips: make(map[socket.L7Proto]uint64)}
+	ch := make(chan socket.RoundTrip, common.Concurrency())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for rt := range ch {
+			result.RoundTrips[rt.Proto()]++
+		}
+	}()
+
+	var packets, bytes, generated atomic.Uint64
+	var interval time.Duration
+	if cfg.Rate > 0 {
+		interval = time.Duration(cfg.Workers) * time.Second / time.Duration(cfg.Rate)
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for w := 0; w < cfg.Workers && w < len(conns); w++ {
+		// 链接按照 worker 分组 与 dispatch 按四元组分发的方式保持一致 避免链接锁竞争
+		var owned []*conn
+		for i := w; i < len(conns); i += cfg.Workers {
+			owned = append(owned, conns[i])
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var p, b, g uint64
+			defer func() {
+				packets.Add(p)
+				bytes.Add(b)
+				generated.Add(g)
+			}()
+
+			t := start
+			next := time.Now()
+			for i := 0; ; i++ {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+
+				if interval > 0 {
+					if d := time.Until(next); d > 0 {
+						time.Sleep(d)
+					}
+					next = next.Add(interval)
+				}
+
+				c := owned[i%len(owned)]
+				np, nb := c.roundTrip(t, ch)
+				p += np
+				b += nb
+				g++
+				t = t.Add(latency)
+			}
+		}()
+	}
+
+	wg.Wait()
+	result.Elapsed = time.Since(start)
+	close(ch)
+	<-done
+
+	result.Packets = packets.Load()
+	result.Bytes = bytes.Load()
+	result.Generated = generated.Load()
+	return result, nil
+}
+
+// roundTrip 生成一次请求来回并写入链接 返回写入的数据包数量以及字节数
+func (c *conn) roundTrip(t time.Time, ch chan<- socket.RoundTrip) (uint64, uint64) {
+	c.n++
+	req, rsp := c.gen.Next(c.n)
+
+	l7conn := c.pool.GetOrCreate(c.st, c.gen.Port())
+	if c.pool.L4Proto() == socket.L4ProtoUDP {
+		writeDatagram(l7conn, c.st, req, t, ch)
+		writeDatagram(l7conn, c.st.Mirror(), rsp, t.Add(latency), ch)
+		return 2, uint64(len(req) + len(rsp))
+	}
+
+	p1 := writeSegments(l7conn, c.st, &c.reqSeq, req, t, ch)
+	p2 := writeSegments(l7conn, c.st.Mirror(), &c.rspSeq, rsp, t.Add(latency), ch)
+	if g, ok := c.gen.(SingleUseGenerator); ok && g.SingleUse() {
+		c.reconnect()
+	}
+	return p1 + p2, uint64(len(req) + len(rsp))
+}
+
+// reconnect 删除当前链接并切换至新的客户端端口
+func (c *conn) reconnect() {
+	c.pool.Delete(c.st)
+	c.st.SrcPort++
+	if c.st.SrcPort < 10000 {
+		c.st.SrcPort = 10000
+	}
+	c.reqSeq, c.rspSeq = 0, 0
+}
+
+func writeDatagram(l7conn protocol.Conn, st socket.Tuple, b []byte, t time.Time, ch chan<- socket.RoundTrip) {
+	_ = l7conn.OnL4Packet(&socket.UDPDatagram{
+		Tuple:   st,
+		Time:    t,
+		Payload: b,
+	}, ch)
+}
+
+func writeSegments(l7conn protocol.Conn, st socket.Tuple, seq *uint32, b []byte, t time.Time, ch chan<- socket.RoundTrip) uint64 {
+	var n uint64
+	for len(b) > 0 {
+		size := min(len(b), segmentSize)
+		seg := &socket.TCPSegment{
+			Tuple:   st,
+			Time:    t,
+			ACK:     true,
+			Seq:     *seq,
+			Payload: b[:size],
+		}
+		_ = l7conn.OnL4Packet(seg, ch)
+		*seq += uint32(size)
+		b = b[size:]
+		n++
+	}
+	return n
+}
@@ -0,0 +1,70 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loadgen
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common/socket"
+	_ "github.com/packetd/packetd/protocol/pamqp"
+	_ "github.com/packetd/packetd/protocol/pdns"
+	_ "github.com/packetd/packetd/protocol/pgrpc"
+	_ "github.com/packetd/packetd/protocol/phttp"
+	_ "github.com/packetd/packetd/protocol/phttp2"
+	_ "github.com/packetd/packetd/protocol/pkafka"
+	_ "github.com/packetd/packetd/protocol/pmongodb"
+	_ "github.com/packetd/packetd/protocol/pmysql"
+	_ "github.com/packetd/packetd/protocol/pntp"
+	_ "github.com/packetd/packetd/protocol/ppostgresql"
+	_ "github.com/packetd/packetd/protocol/predis"
+	_ "github.com/packetd/packetd/protocol/ptls"
+)
+
+func TestRun(t *testing.T) {
+	for _, proto := range Protocols() {
+		t.Run(proto, func(t *testing.T) {
+			result, err := Run(context.Background(), Config{
+				Protocols: []string{proto},
+				Conns:     4,
+				Workers:   2,
+				Duration:  100 * time.Millisecond,
+			})
+			assert.NoError(t, err)
+			assert.Greater(t, result.RoundTrips[socket.L7Proto(proto)], uint64(0))
+			assert.GreaterOrEqual(t, result.Packets, 2*result.Total())
+			assert.Equal(t, result.Generated, result.Total())
+			assert.Contains(t, result.String(), proto)
+		})
+	}
+}
+
+func TestRunRate(t *testing.T) {
+	result, err := Run(context.Background(), Config{
+		Protocols: []string{"http"},
+		Rate:      100,
+		Workers:   1,
+		Duration:  200 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, result.Total(), uint64(25))
+}
+
+func TestRunUnsupported(t *testing.T) {
+	_, err := Run(context.Background(), Config{Protocols: []string{"smtp"}})
+	assert.Error(t, err)
+}
@@ -27,11 +27,21 @@ import (
 )
 
 type histogram struct {
-	vals    []float64
-	sum     float64
-	count   float64
-	lbs     labels.Labels
-	updated int64
+	vals      []float64
+	sum       float64
+	count     float64
+	lbs       labels.Labels
+	updated   int64
+	exemplars []*exemplar // 按需分配 与 bucket 一一对应
+}
+
+// exemplar 关联到具体 bucket 的代表性样本
+//
+// 每个 bucket 仅保留最近一次观测到的样本 其 labels 通常为 trace_id/span_id
+type exemplar struct {
+	lbs   labels.Labels
+	value float64
+	ts    int64 // 毫秒
 }
 
 type Histogram struct {
@@ -52,6 +62,11 @@ func NewHistogram(name string, expired time.Duration, bucket []float64) *Histogr
 }
 
 func (h *Histogram) Observe(v float64, lbs labels.Labels) {
+	h.ObserveExemplar(v, lbs, nil)
+}
+
+// ObserveExemplar 观测样本并记录 exemplar 到样本所属的 bucket 中 exemplar 为空时等同于 Observe
+func (h *Histogram) ObserveExemplar(v float64, lbs labels.Labels, exemplarLbs labels.Labels) {
 	hash := lbs.Hash()
 
 	h.mut.Lock()
@@ -63,10 +78,20 @@ func (h *Histogram) Observe(v float64, lbs labels.Labels) {
 	}
 
 	obj := h.histograms[hash]
+	owner := -1 // 样本所属的（最小的）bucket
 	for i := 0; i < len(h.bucket); i++ {
 		if h.bucket[i] >= v {
 			obj.vals[i]++
+			if owner < 0 {
+				owner = i
+			}
+		}
+	}
+	if len(exemplarLbs) > 0 && owner >= 0 {
+		if obj.exemplars == nil {
+			obj.exemplars = make([]*exemplar, len(h.bucket))
 		}
+		obj.exemplars[owner] = &exemplar{lbs: exemplarLbs, value: v, ts: time.Now().UnixMilli()}
 	}
 	obj.count++
 	obj.sum += v
@@ -88,17 +113,30 @@ func (h *Histogram) RemoveExpired() {
 }
 
 func (h *Histogram) WritePrometheus(w io.Writer) {
+	h.writeText(w, false)
+}
+
+// WriteOpenMetrics 以 OpenMetrics 格式输出 bucket 会携带 exemplar
+func (h *Histogram) WriteOpenMetrics(w io.Writer) {
+	h.writeText(w, true)
+}
+
+func (h *Histogram) writeText(w io.Writer, withExemplar bool) {
 	h.mut.RLock()
 	defer h.mut.RUnlock()
 
 	for _, inst := range h.histograms {
 		for i, bucket := range h.bucket {
 			le := strconv.FormatFloat(bucket, 'f', -1, 64)
-			WritePrometheus(w, ConstMetric{
+			var ex *exemplar
+			if withExemplar && inst.exemplars != nil {
+				ex = inst.exemplars[i]
+			}
+			writeMetric(w, ConstMetric{
 				Name:   h.name + "_bucket",
 				Labels: append(inst.lbs, labels.Label{Name: "le", Value: le}),
 				Value:  inst.vals[i],
-			})
+			}, ex)
 		}
 
 		WritePrometheus(w,
@@ -121,6 +159,9 @@ func (h *Histogram) PrompbSeriess() []prompb.TimeSeries {
 				Labels: append(inst.lbs, labels.Label{Name: "le", Value: le}),
 				Value:  inst.vals[i],
 			})
+			if inst.exemplars != nil && inst.exemplars[i] != nil {
+				tss[0].Exemplars = []prompb.Exemplar{inst.exemplars[i].prompb()}
+			}
 			seriess = append(seriess, tss...)
 		}
 
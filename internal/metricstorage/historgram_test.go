@@ -0,0 +1,73 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricstorage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/internal/labels"
+)
+
+func TestHistogramExemplar(t *testing.T) {
+	h := NewHistogram("request_duration_seconds", time.Minute, []float64{0.1, 1})
+	lbs := labels.Labels{{Name: "proto", Value: "http"}}
+
+	h.Observe(0.05, lbs)
+	h.ObserveExemplar(0.5, lbs, labels.Labels{{Name: "trace_id", Value: "t1"}})
+	h.ObserveExemplar(0.6, lbs, labels.Labels{{Name: "trace_id", Value: "t2"}})
+
+	var buf bytes.Buffer
+	h.WritePrometheus(&buf)
+	assert.NotContains(t, buf.String(), "#")
+
+	buf.Reset()
+	h.WriteOpenMetrics(&buf)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 5)
+	assert.Equal(t, `request_duration_seconds_bucket{proto="http",le="0.1"} 1.000000`, lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], `request_duration_seconds_bucket{proto="http",le="1"} 3.000000 # {trace_id="t2"} 0.600000 `))
+	assert.Equal(t, `request_duration_seconds_bucket{proto="http",le="+Inf"} 3.000000`, lines[2])
+
+	seriess := h.PrompbSeriess()
+	var exemplars int
+	for _, series := range seriess {
+		for _, ex := range series.Exemplars {
+			exemplars++
+			assert.Equal(t, "t2", ex.Labels[0].Value)
+			assert.Equal(t, 0.6, ex.Value)
+		}
+	}
+	assert.Equal(t, 1, exemplars)
+}
+
+func TestSetWriteOpenMetrics(t *testing.T) {
+	s := newSet(time.Minute)
+	s.GetOrCreateCounter("requests_total").Inc(nil)
+
+	var buf bytes.Buffer
+	s.WriteOpenMetrics(&buf)
+	assert.Equal(t, "# TYPE requests counter\nrequests_total{} 1.000000\n# EOF\n", buf.String())
+
+	s = newSet(time.Minute)
+	s.GetOrCreateHistogram("request_duration_seconds", []float64{1}).Observe(0.5, nil)
+
+	buf.Reset()
+	s.WriteOpenMetrics(&buf)
+	assert.True(t, strings.HasPrefix(buf.String(), "# TYPE request_duration_seconds histogram\nrequest_duration_seconds_bucket{"))
+}
@@ -16,6 +16,7 @@ package metricstorage
 import (
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"time"
 
@@ -31,6 +32,7 @@ const (
 	UnitNone Unit = iota
 	UnitBytes
 	UnitSeconds
+	UnitCount
 )
 
 func KB(n int) float64 {
@@ -53,6 +55,11 @@ var (
 	DefObserveDuration = []float64{
 		0.001, 0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 20, 30, 60, 120, 300, 600,
 	}
+
+	// DefCountDistribution 默认的数量桶分布 如并发流数量
+	DefCountDistribution = []float64{
+		1, 2, 5, 10, 20, 50, 100, 128, 200, 256, 500, 1000,
+	}
 )
 
 func DefBuckets(u Unit) []float64 {
@@ -61,6 +68,8 @@ func DefBuckets(u Unit) []float64 {
 		return DefSizeDistribution
 	case UnitSeconds:
 		return DefObserveDuration
+	case UnitCount:
+		return DefCountDistribution
 	default:
 		return nil
 	}
@@ -80,6 +89,9 @@ type ConstMetric struct {
 	Name   string
 	Labels labels.Labels
 	Value  float64
+
+	// Exemplar 仅对 Histogram 生效 如 trace_id/span_id
+	Exemplar labels.Labels
 }
 
 func NewCounterConstMetric(name string, val float64, lbs labels.Labels) ConstMetric {
@@ -223,6 +235,38 @@ func (s *Set) WritePrometheus(w io.Writer) {
 	}
 }
 
+// WriteOpenMetrics 以 OpenMetrics 格式输出所有指标
+//
+// 与 WritePrometheus 的区别在于 Histogram bucket 会携带 exemplar 并且以 `# EOF` 结尾
+// 每个指标之前输出 `# TYPE` 元数据 未声明类型的指标无法携带 exemplar 指标没有描述信息 因此不输出 `# HELP`
+// VmHistogram 不支持 exemplar 其 vmrange bucket 也不符合 OpenMetrics 的 histogram 定义 按原样输出
+func (s *Set) WriteOpenMetrics(w io.Writer) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	for name, inst := range s.counters {
+		// OpenMetrics 的 counter 以去掉 _total 后缀的名称声明
+		if family, ok := strings.CutSuffix(name, "_total"); ok {
+			writeType(w, family, "counter")
+		} else {
+			writeType(w, name, "unknown")
+		}
+		inst.WritePrometheus(w)
+	}
+	for name, inst := range s.gauges {
+		writeType(w, name, "gauge")
+		inst.WritePrometheus(w)
+	}
+	for name, inst := range s.histograms {
+		writeType(w, name, "histogram")
+		inst.WriteOpenMetrics(w)
+	}
+	for _, inst := range s.vmHistograms {
+		inst.WritePrometheus(w)
+	}
+	w.Write([]byte("# EOF\n"))
+}
+
 func (s *Set) RemoveExpired() {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
@@ -263,28 +307,44 @@ func (s *Set) WriteRequest() *prompb.WriteRequest {
 	}
 }
 
+func writeType(w io.Writer, name, typ string) {
+	w.Write([]byte("# TYPE " + name + " " + typ + "\n"))
+}
+
 func WritePrometheus(w io.Writer, metrics ...ConstMetric) {
 	for i := 0; i < len(metrics); i++ {
-		metric := metrics[i]
-		w.Write([]byte(metric.Name))
-		w.Write([]byte(`{`))
+		writeMetric(w, metrics[i], nil)
+	}
+}
 
-		var n int
-		for _, label := range metric.Labels {
-			if n > 0 {
-				w.Write([]byte(`,`))
-			}
-			n++
-			w.Write([]byte(label.Name))
-			w.Write([]byte(`="`))
-			w.Write([]byte(label.Value))
-			w.Write([]byte(`"`))
+func writeLabels(w io.Writer, lbs labels.Labels) {
+	w.Write([]byte(`{`))
+	for i, label := range lbs {
+		if i > 0 {
+			w.Write([]byte(`,`))
 		}
+		w.Write([]byte(label.Name))
+		w.Write([]byte(`="`))
+		w.Write([]byte(label.Value))
+		w.Write([]byte(`"`))
+	}
+	w.Write([]byte(`}`))
+}
 
-		w.Write([]byte("} "))
-		w.Write([]byte(fmt.Sprintf("%f", metric.Value)))
-		w.Write([]byte("\n"))
+// writeMetric 输出单行指标 ex 不为空时按照 OpenMetrics 格式在行尾追加 exemplar
+//
+// name{labels} value # {trace_id="..."} value timestamp
+func writeMetric(w io.Writer, metric ConstMetric, ex *exemplar) {
+	w.Write([]byte(metric.Name))
+	writeLabels(w, metric.Labels)
+	w.Write([]byte(" "))
+	w.Write([]byte(fmt.Sprintf("%f", metric.Value)))
+	if ex != nil {
+		w.Write([]byte(" # "))
+		writeLabels(w, ex.lbs)
+		w.Write([]byte(fmt.Sprintf(" %f %.3f", ex.value, float64(ex.ts)/1000)))
 	}
+	w.Write([]byte("\n"))
 }
 
 func ToPrompbTimeSeries(metrics ...ConstMetric) []prompb.TimeSeries {
@@ -312,3 +372,18 @@ func ToPrompbTimeSeries(metrics ...ConstMetric) []prompb.TimeSeries {
 	}
 	return seriess
 }
+
+func (e *exemplar) prompb() prompb.Exemplar {
+	lbs := make([]prompb.Label, 0, len(e.lbs))
+	for _, label := range e.lbs {
+		lbs = append(lbs, prompb.Label{
+			Name:  label.Name,
+			Value: label.Value,
+		})
+	}
+	return prompb.Exemplar{
+		Labels:    lbs,
+		Value:     e.value,
+		Timestamp: e.ts,
+	}
+}
@@ -25,6 +25,7 @@ import (
 type Config struct {
 	Expired     time.Duration `config:"expired"`
 	VmHistogram bool          `config:"vmHistogram"`
+	Exemplars   bool          `config:"exemplars"`
 }
 
 type Storage struct {
@@ -71,6 +72,10 @@ func (s *Storage) Update(cms ...ConstMetric) {
 				continue
 			}
 			inst := s.set.GetOrCreateHistogram(cm.Name, DefBuckets(cm.Unit))
+			if s.cfg.Exemplars {
+				inst.ObserveExemplar(cm.Value, cm.Labels, cm.Exemplar)
+				continue
+			}
 			inst.Observe(cm.Value, cm.Labels)
 		}
 	}
@@ -94,6 +99,16 @@ func (s *Storage) WritePrometheus(w io.Writer) {
 	s.set.WritePrometheus(w)
 }
 
+// ExemplarsEnabled 返回是否启用了 exemplar
+func (s *Storage) ExemplarsEnabled() bool {
+	return s.cfg.Exemplars
+}
+
+// WriteOpenMetrics 以 OpenMetrics 格式输出指标 Histogram 会携带 exemplar
+func (s *Storage) WriteOpenMetrics(w io.Writer) {
+	s.set.WriteOpenMetrics(w)
+}
+
 func (s *Storage) WriteRequest() *prompb.WriteRequest {
 	return s.set.WriteRequest()
 }
@@ -0,0 +1,234 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/packetd/packetd/protocol"
+)
+
+// Config OpenAPI 路由匹配配置
+type Config struct {
+	// Specs OpenAPI 3.x 或 Swagger 2.0 描述文件 支持 yaml 以及 json 格式
+	Specs []string `config:"specs"`
+}
+
+// document 仅解析路由匹配所需的字段
+type document struct {
+	BasePath string `yaml:"basePath"`
+	Servers  []struct {
+		URL string `yaml:"url"`
+	} `yaml:"servers"`
+	Paths map[string]map[string]yaml.Node `yaml:"paths"`
+}
+
+type operation struct {
+	OperationID string `yaml:"operationId"`
+}
+
+var methods = map[string]string{
+	"get":     "GET",
+	"put":     "PUT",
+	"post":    "POST",
+	"delete":  "DELETE",
+	"options": "OPTIONS",
+	"head":    "HEAD",
+	"patch":   "PATCH",
+	"trace":   "TRACE",
+}
+
+// segment 路由模板中的单个路径段
+//
+// 参数段允许携带固定的前后缀 如 {name}.json
+type segment struct {
+	literal string
+	param   bool
+	prefix  string
+	suffix  string
+}
+
+func (s segment) match(v string) bool {
+	if !s.param {
+		return s.literal == v
+	}
+	if len(v) <= len(s.prefix)+len(s.suffix) {
+		return false
+	}
+	return strings.HasPrefix(v, s.prefix) && strings.HasSuffix(v, s.suffix)
+}
+
+func parseSegment(s string) segment {
+	start := strings.IndexByte(s, '{')
+	end := strings.LastIndexByte(s, '}')
+	if start < 0 || end < start {
+		return segment{literal: s}
+	}
+	return segment{param: true, prefix: s[:start], suffix: s[end+1:]}
+}
+
+type route struct {
+	op       protocol.Operation
+	segments []segment
+	params   int
+}
+
+func (r route) match(parts []string) bool {
+	if len(parts) != len(r.segments) {
+		return false
+	}
+	for i, seg := range r.segments {
+		if !seg.match(parts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Router 根据请求方法以及路径匹配 OpenAPI 中声明的接口
+type Router struct {
+	routes map[string][]route
+}
+
+// New 加载 conf.Specs 并创建 Router 未配置描述文件时返回 nil
+func New(conf Config) (*Router, error) {
+	if len(conf.Specs) == 0 {
+		return nil, nil
+	}
+
+	r := &Router{routes: make(map[string][]route)}
+	for _, file := range conf.Specs {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrap(err, "read openapi spec")
+		}
+		if err := r.load(b); err != nil {
+			return nil, errors.Wrapf(err, "load openapi spec '%s'", file)
+		}
+	}
+
+	// 字面量路径段优先于参数段 如 /users/me 优先于 /users/{id} 参数数量相同时保持声明顺序
+	for method := range r.routes {
+		lst := r.routes[method]
+		sort.SliceStable(lst, func(i, j int) bool {
+			return lst[i].params < lst[j].params
+		})
+	}
+	return r, nil
+}
+
+// basePaths 返回描述中声明的路径前缀 未声明时为空字符串
+func (doc *document) basePaths() []string {
+	var lst []string
+	if doc.BasePath != "" {
+		lst = append(lst, strings.TrimSuffix(doc.BasePath, "/"))
+	}
+	for _, server := range doc.Servers {
+		u, err := url.Parse(server.URL)
+		if err != nil {
+			continue
+		}
+		lst = append(lst, strings.TrimSuffix(u.Path, "/"))
+	}
+	if len(lst) == 0 {
+		lst = append(lst, "")
+	}
+	return lst
+}
+
+// load 解析单个描述文件 json 是 yaml 的子集 因此统一按照 yaml 解析
+func (r *Router) load(b []byte) error {
+	var doc document
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	if len(doc.Paths) == 0 {
+		return errors.New("no paths defined")
+	}
+
+	// map 遍历顺序不固定 按照路径排序保证匹配结果稳定
+	paths := make([]string, 0, len(doc.Paths))
+	for p := range doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	bases := doc.basePaths()
+	for _, p := range paths {
+		for key, node := range doc.Paths[p] {
+			method, ok := methods[strings.ToLower(key)]
+			if !ok {
+				continue // parameters / summary 等非操作字段
+			}
+			var op operation
+			if err := node.Decode(&op); err != nil {
+				return errors.Wrapf(err, "decode operation %s %s", method, p)
+			}
+			for _, base := range bases {
+				r.routes[method] = append(r.routes[method], newRoute(base+p, op.OperationID))
+			}
+		}
+	}
+	return nil
+}
+
+func newRoute(template, id string) route {
+	rt := route{op: protocol.Operation{ID: id, Route: template}}
+	for _, s := range splitPath(template) {
+		seg := parseSegment(s)
+		if seg.param {
+			rt.params++
+		}
+		rt.segments = append(rt.segments, seg)
+	}
+	return rt
+}
+
+// splitPath 去除 query 参数以及首尾的 `/` 后按照 `/` 切分
+func splitPath(p string) []string {
+	if idx := strings.IndexAny(p, "?#"); idx >= 0 {
+		p = p[:idx]
+	}
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+// Match 返回请求匹配的接口 r 为 nil 或者未匹配时返回 nil
+func (r *Router) Match(method, path string) *protocol.Operation {
+	if r == nil {
+		return nil
+	}
+	routes := r.routes[method]
+	if len(routes) == 0 {
+		return nil
+	}
+
+	parts := splitPath(path)
+	for i := range routes {
+		if routes[i].match(parts) {
+			op := routes[i].op
+			return &op
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openapi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/protocol"
+)
+
+const specV3 = `
+openapi: 3.0.0
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /users:
+    get:
+      operationId: listUsers
+    post:
+      operationId: createUser
+  /users/{id}:
+    parameters:
+      - name: id
+        in: path
+    get:
+      operationId: getUser
+  /users/me:
+    get:
+      operationId: getCurrentUser
+  /files/{name}.json:
+    get:
+      operationId: getFile
+  /health:
+    get: {}
+`
+
+const specV2 = `{
+  "swagger": "2.0",
+  "basePath": "/legacy",
+  "paths": {
+    "/orders/{id}": {"delete": {"operationId": "deleteOrder"}}
+  }
+}`
+
+func writeSpec(t *testing.T, name, content string) string {
+	p := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	return p
+}
+
+func TestRouterMatch(t *testing.T) {
+	r, err := New(Config{Specs: []string{
+		writeSpec(t, "v3.yaml", specV3),
+		writeSpec(t, "v2.json", specV2),
+	}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   *protocol.Operation
+	}{
+		{
+			name:   "Literal",
+			method: "GET",
+			path:   "/v1/users?page=2",
+			want:   &protocol.Operation{ID: "listUsers", Route: "/v1/users"},
+		},
+		{
+			name:   "Method",
+			method: "POST",
+			path:   "/v1/users/",
+			want:   &protocol.Operation{ID: "createUser", Route: "/v1/users"},
+		},
+		{
+			name:   "Param",
+			method: "GET",
+			path:   "/v1/users/42",
+			want:   &protocol.Operation{ID: "getUser", Route: "/v1/users/{id}"},
+		},
+		{
+			name:   "LiteralFirst",
+			method: "GET",
+			path:   "/v1/users/me",
+			want:   &protocol.Operation{ID: "getCurrentUser", Route: "/v1/users/me"},
+		},
+		{
+			name:   "ParamSuffix",
+			method: "GET",
+			path:   "/v1/files/report.json",
+			want:   &protocol.Operation{ID: "getFile", Route: "/v1/files/{name}.json"},
+		},
+		{
+			name:   "NoOperationID",
+			method: "GET",
+			path:   "/v1/health",
+			want:   &protocol.Operation{Route: "/v1/health"},
+		},
+		{
+			name:   "BasePath",
+			method: "DELETE",
+			path:   "/legacy/orders/7",
+			want:   &protocol.Operation{ID: "deleteOrder", Route: "/legacy/orders/{id}"},
+		},
+		{
+			name:   "UnknownMethod",
+			method: "PUT",
+			path:   "/v1/users/42",
+		},
+		{
+			name:   "UnknownPath",
+			method: "GET",
+			path:   "/v1/users/42/posts",
+		},
+		{
+			name:   "MissingBasePath",
+			method: "GET",
+			path:   "/users",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, r.Match(tt.method, tt.path))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	r, err := New(Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, r)
+	assert.Nil(t, r.Match("GET", "/"))
+
+	_, err = New(Config{Specs: []string{filepath.Join(t.TempDir(), "missing.yaml")}})
+	assert.Error(t, err)
+
+	_, err = New(Config{Specs: []string{writeSpec(t, "empty.yaml", "openapi: 3.0.0\n")}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,399 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pcapng 实现 pcapng 文件的只读解析
+//
+// 相比 gopacket/pcapgo 额外暴露了数据包注释以及接口描述信息
+// 支持同一文件内多个 Section 多个接口以及不同的链路类型和时间戳精度
+package pcapng
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/bits"
+	"strconv"
+	"time"
+
+	"github.com/gopacket/gopacket/layers"
+	"github.com/pkg/errors"
+)
+
+// Magic pcapng 文件以 Section Header Block 开头 可用于判断文件格式
+var Magic = []byte{0x0a, 0x0d, 0x0d, 0x0a}
+
+const (
+	blockSectionHeader  uint32 = 0x0a0d0d0a
+	blockInterface      uint32 = 0x00000001
+	blockPacket         uint32 = 0x00000002
+	blockSimplePacket   uint32 = 0x00000003
+	blockEnhancedPacket uint32 = 0x00000006
+
+	byteOrderMagic uint32 = 0x1a2b3c4d
+
+	optEndOfOpt uint16 = 0
+	optComment  uint16 = 1
+
+	optIfName        uint16 = 2
+	optIfDescription uint16 = 3
+	optIfTsresol     uint16 = 9
+	optIfTsoffset    uint16 = 14
+
+	// maxBlockSize 单个 block 的最大长度 避免异常文件导致过量分配
+	maxBlockSize = 64 << 20
+
+	// defaultTsresol 未声明 if_tsresol 时默认精度为微秒
+	defaultTsresol = 6
+)
+
+// Interface 接口描述信息 对应 Interface Description Block
+type Interface struct {
+	Name        string
+	Description string
+	Comment     string
+	LinkType    layers.LinkType
+	SnapLen     uint32
+
+	// units 每秒包含的时间戳单位数量
+	units  uint64
+	offset int64
+}
+
+// Label 返回接口的可读名称 未声明名称时使用描述或者序号
+func (i Interface) Label(idx int) string {
+	if i.Name != "" {
+		return i.Name
+	}
+	if i.Description != "" {
+		return i.Description
+	}
+	return "if" + strconv.Itoa(idx)
+}
+
+func (i Interface) timestamp(ts uint64) time.Time {
+	sec := ts / i.units
+	frac := ts % i.units
+	// frac < units 因此商一定小于 1e9 不会溢出
+	hi, lo := bits.Mul64(frac, uint64(time.Second))
+	nsec, _ := bits.Div64(hi, lo, i.units)
+	return time.Unix(int64(sec)+i.offset, int64(nsec)).UTC()
+}
+
+// Packet 数据包 Interface 为所属接口在当前 Section 中的序号
+type Packet struct {
+	Interface int
+	Timestamp time.Time
+	Length    int
+	Data      []byte
+	Comments  []string
+}
+
+// Reader pcapng 文件读取器
+type Reader struct {
+	r      *bufio.Reader
+	order  binary.ByteOrder
+	ifaces []Interface
+	buf    []byte
+}
+
+// NewReader 创建 Reader 文件必须以 Section Header Block 开头
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(Magic))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic, Magic) {
+		return nil, errors.New("not a pcapng file")
+	}
+
+	reader := &Reader{r: br}
+	typ, _, err := reader.readBlock()
+	if err != nil {
+		return nil, err
+	}
+	if typ != blockSectionHeader {
+		return nil, errors.Errorf("unexpected first block type (0x%x)", typ)
+	}
+	return reader, nil
+}
+
+// Interfaces 返回当前 Section 已声明的接口
+func (r *Reader) Interfaces() []Interface {
+	return r.ifaces
+}
+
+// Next 返回下一个数据包 文件结束时返回 io.EOF
+//
+// 返回的 Packet.Data 在下一次调用前有效
+func (r *Reader) Next() (*Packet, error) {
+	for {
+		typ, body, err := r.readBlock()
+		if err != nil {
+			return nil, err
+		}
+
+		switch typ {
+		case blockInterface:
+			if err := r.parseInterface(body); err != nil {
+				return nil, err
+			}
+		case blockEnhancedPacket:
+			return r.parseEnhancedPacket(body)
+		case blockSimplePacket:
+			return r.parseSimplePacket(body)
+		case blockPacket:
+			return r.parseObsoletePacket(body)
+		}
+	}
+}
+
+// readBlock 读取完整的 block 并返回 body 部分
+//
+// 遇到 Section Header Block 时重新确定字节序并清空接口列表
+func (r *Reader) readBlock() (uint32, []byte, error) {
+	var hdr [12]byte
+	if _, err := io.ReadFull(r.r, hdr[:8]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, nil, errors.New("truncated block header")
+		}
+		return 0, nil, err
+	}
+
+	if bytes.Equal(hdr[:4], Magic) {
+		// Section Header Block 的字节序由 body 中的 magic 决定
+		if _, err := io.ReadFull(r.r, hdr[8:12]); err != nil {
+			return 0, nil, errors.Wrap(err, "read section header")
+		}
+		switch binary.LittleEndian.Uint32(hdr[8:12]) {
+		case byteOrderMagic:
+			r.order = binary.LittleEndian
+		case bits32Swap(byteOrderMagic):
+			r.order = binary.BigEndian
+		default:
+			return 0, nil, errors.New("invalid byte-order magic")
+		}
+		r.ifaces = r.ifaces[:0]
+
+		total := r.order.Uint32(hdr[4:8])
+		body, err := r.readBody(total, 12)
+		if err != nil {
+			return 0, nil, err
+		}
+		return blockSectionHeader, body, nil
+	}
+
+	if r.order == nil {
+		return 0, nil, errors.New("missing section header")
+	}
+	typ := r.order.Uint32(hdr[:4])
+	total := r.order.Uint32(hdr[4:8])
+	body, err := r.readBody(total, 8)
+	if err != nil {
+		return 0, nil, err
+	}
+	return typ, body, nil
+}
+
+// readBody 读取 block 剩余内容 consumed 为已读取的字节数 返回值不包含末尾的 length 字段
+func (r *Reader) readBody(total uint32, consumed int) ([]byte, error) {
+	if total%4 != 0 || int(total) < consumed+4 || total > maxBlockSize {
+		return nil, errors.Errorf("invalid block length (%d)", total)
+	}
+	n := int(total) - consumed
+	if cap(r.buf) < n {
+		r.buf = make([]byte, n)
+	}
+	buf := r.buf[:n]
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		return nil, errors.Wrap(err, "truncated block")
+	}
+	if r.order.Uint32(buf[n-4:]) != total {
+		return nil, errors.New("block length mismatch")
+	}
+	return buf[:n-4], nil
+}
+
+func (r *Reader) parseInterface(body []byte) error {
+	if len(body) < 8 {
+		return errors.New("truncated interface description block")
+	}
+	iface := Interface{
+		LinkType: layers.LinkType(r.order.Uint16(body[0:2])),
+		SnapLen:  r.order.Uint32(body[4:8]),
+		units:    pow10(defaultTsresol),
+	}
+
+	err := r.rangeOptions(body[8:], func(code uint16, val []byte) error {
+		switch code {
+		case optComment:
+			iface.Comment = string(val)
+		case optIfName:
+			iface.Name = string(val)
+		case optIfDescription:
+			iface.Description = string(val)
+		case optIfTsresol:
+			if len(val) < 1 {
+				return errors.New("invalid if_tsresol")
+			}
+			units, ok := tsresolUnits(val[0])
+			if !ok {
+				return errors.Errorf("unsupported if_tsresol (0x%x)", val[0])
+			}
+			iface.units = units
+		case optIfTsoffset:
+			if len(val) < 8 {
+				return errors.New("invalid if_tsoffset")
+			}
+			iface.offset = int64(r.order.Uint64(val))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.ifaces = append(r.ifaces, iface)
+	return nil
+}
+
+func (r *Reader) iface(idx int) (Interface, error) {
+	if idx < 0 || idx >= len(r.ifaces) {
+		return Interface{}, errors.Errorf("interface id %d not present in section", idx)
+	}
+	return r.ifaces[idx], nil
+}
+
+func (r *Reader) parseEnhancedPacket(body []byte) (*Packet, error) {
+	if len(body) < 20 {
+		return nil, errors.New("truncated enhanced packet block")
+	}
+	idx := int(r.order.Uint32(body[0:4]))
+	ts := uint64(r.order.Uint32(body[4:8]))<<32 | uint64(r.order.Uint32(body[8:12]))
+	return r.parsePacketBody(idx, ts, body[12:])
+}
+
+func (r *Reader) parseObsoletePacket(body []byte) (*Packet, error) {
+	if len(body) < 20 {
+		return nil, errors.New("truncated packet block")
+	}
+	idx := int(r.order.Uint16(body[0:2]))
+	ts := uint64(r.order.Uint32(body[4:8]))<<32 | uint64(r.order.Uint32(body[8:12]))
+	return r.parsePacketBody(idx, ts, body[12:])
+}
+
+// parsePacketBody 解析 captured length 之后的数据以及 options 两种 packet block 布局一致
+func (r *Reader) parsePacketBody(idx int, ts uint64, body []byte) (*Packet, error) {
+	iface, err := r.iface(idx)
+	if err != nil {
+		return nil, err
+	}
+
+	caplen := int(r.order.Uint32(body[0:4]))
+	length := int(r.order.Uint32(body[4:8]))
+	body = body[8:]
+	padded := align4(caplen)
+	if caplen < 0 || padded > len(body) {
+		return nil, errors.Errorf("invalid captured length (%d)", caplen)
+	}
+
+	pkt := &Packet{
+		Interface: idx,
+		Timestamp: iface.timestamp(ts),
+		Length:    length,
+		Data:      body[:caplen],
+	}
+	err = r.rangeOptions(body[padded:], func(code uint16, val []byte) error {
+		if code == optComment {
+			pkt.Comments = append(pkt.Comments, string(val))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pkt, nil
+}
+
+// parseSimplePacket Simple Packet Block 没有时间戳且固定属于第一个接口
+func (r *Reader) parseSimplePacket(body []byte) (*Packet, error) {
+	iface, err := r.iface(0)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < 4 {
+		return nil, errors.New("truncated simple packet block")
+	}
+
+	length := int(r.order.Uint32(body[0:4]))
+	caplen := min(length, len(body)-4)
+	if iface.SnapLen > 0 && caplen > int(iface.SnapLen) {
+		caplen = int(iface.SnapLen)
+	}
+	return &Packet{
+		Length: length,
+		Data:   body[4 : 4+caplen],
+	}, nil
+}
+
+// rangeOptions 遍历 options 遇到 opt_endofopt 或者数据结束时停止
+func (r *Reader) rangeOptions(b []byte, f func(code uint16, val []byte) error) error {
+	for len(b) >= 4 {
+		code := r.order.Uint16(b[0:2])
+		n := int(r.order.Uint16(b[2:4]))
+		if code == optEndOfOpt {
+			return nil
+		}
+		b = b[4:]
+		if align4(n) > len(b) {
+			return errors.Errorf("invalid option length (%d)", n)
+		}
+		if err := f(code, b[:n]); err != nil {
+			return err
+		}
+		b = b[align4(n):]
+	}
+	return nil
+}
+
+// tsresolUnits 解析 if_tsresol 最高位为 0 时表示 10 的负幂 为 1 时表示 2 的负幂
+func tsresolUnits(v byte) (uint64, bool) {
+	exp := v & 0x7f
+	if v&0x80 != 0 {
+		if exp > 63 {
+			return 0, false
+		}
+		return 1 << exp, true
+	}
+	if exp > 19 {
+		return 0, false
+	}
+	return pow10(exp), true
+}
+
+func pow10(n uint8) uint64 {
+	v := uint64(1)
+	for i := uint8(0); i < n; i++ {
+		v *= 10
+	}
+	return v
+}
+
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
+
+func bits32Swap(v uint32) uint32 {
+	return v>>24 | (v>>8)&0xff00 | (v<<8)&0xff0000 | v<<24
+}
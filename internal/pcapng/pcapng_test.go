@@ -0,0 +1,230 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pcapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket/layers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type option struct {
+	code uint16
+	val  []byte
+}
+
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+type builder struct {
+	order byteOrder
+	buf   bytes.Buffer
+}
+
+func (b *builder) block(typ uint32, body []byte, opts ...option) {
+	var ob []byte
+	for _, opt := range opts {
+		ob = b.order.AppendUint16(ob, opt.code)
+		ob = b.order.AppendUint16(ob, uint16(len(opt.val)))
+		ob = append(ob, opt.val...)
+		ob = append(ob, make([]byte, align4(len(opt.val))-len(opt.val))...)
+	}
+	if len(ob) > 0 {
+		ob = append(ob, 0, 0, 0, 0)
+	}
+
+	total := uint32(12 + len(body) + len(ob))
+	var out []byte
+	out = b.order.AppendUint32(out, typ)
+	out = b.order.AppendUint32(out, total)
+	out = append(out, body...)
+	out = append(out, ob...)
+	out = b.order.AppendUint32(out, total)
+	b.buf.Write(out)
+}
+
+func (b *builder) section() {
+	var body []byte
+	body = b.order.AppendUint32(body, byteOrderMagic)
+	body = b.order.AppendUint16(body, 1)
+	body = b.order.AppendUint16(body, 0)
+	body = b.order.AppendUint64(body, ^uint64(0))
+	b.block(blockSectionHeader, body)
+}
+
+func (b *builder) iface(lt layers.LinkType, opts ...option) {
+	var body []byte
+	body = b.order.AppendUint16(body, uint16(lt))
+	body = b.order.AppendUint16(body, 0)
+	body = b.order.AppendUint32(body, 0)
+	b.block(blockInterface, body, opts...)
+}
+
+func (b *builder) packet(idx uint32, ts uint64, data []byte, opts ...option) {
+	var body []byte
+	body = b.order.AppendUint32(body, idx)
+	body = b.order.AppendUint32(body, uint32(ts>>32))
+	body = b.order.AppendUint32(body, uint32(ts))
+	body = b.order.AppendUint32(body, uint32(len(data)))
+	body = b.order.AppendUint32(body, uint32(len(data)))
+	body = append(body, data...)
+	body = append(body, make([]byte, align4(len(data))-len(data))...)
+	b.block(blockEnhancedPacket, body, opts...)
+}
+
+func TestReader(t *testing.T) {
+	for _, order := range []byteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			b := &builder{order: order}
+			b.section()
+			b.iface(layers.LinkTypeEthernet,
+				option{code: optIfName, val: []byte("eth0")},
+				option{code: optIfTsresol, val: []byte{9}},
+			)
+			b.iface(layers.LinkTypeRaw,
+				option{code: optIfDescription, val: []byte("tunnel")},
+			)
+			b.block(0x00000bad, []byte{1, 2, 3, 4})
+			b.packet(0, 1700000000_123456789, []byte("hello"), option{code: optComment, val: []byte("retransmit")})
+			b.packet(1, 1700000000_654321, []byte("world!"))
+
+			r, err := NewReader(&b.buf)
+			require.NoError(t, err)
+
+			pkt, err := r.Next()
+			require.NoError(t, err)
+			assert.Equal(t, 0, pkt.Interface)
+			assert.Equal(t, []byte("hello"), pkt.Data)
+			assert.Equal(t, []string{"retransmit"}, pkt.Comments)
+			assert.Equal(t, time.Unix(1700000000, 123456789).UTC(), pkt.Timestamp)
+
+			pkt, err = r.Next()
+			require.NoError(t, err)
+			assert.Equal(t, 1, pkt.Interface)
+			assert.Equal(t, []byte("world!"), pkt.Data)
+			assert.Nil(t, pkt.Comments)
+			assert.Equal(t, time.Unix(1700000000, 654321000).UTC(), pkt.Timestamp)
+
+			ifaces := r.Interfaces()
+			require.Len(t, ifaces, 2)
+			assert.Equal(t, "eth0", ifaces[0].Label(0))
+			assert.Equal(t, layers.LinkTypeEthernet, ifaces[0].LinkType)
+			assert.Equal(t, "tunnel", ifaces[1].Label(1))
+			assert.Equal(t, layers.LinkTypeRaw, ifaces[1].LinkType)
+
+			_, err = r.Next()
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestReaderMultiSection(t *testing.T) {
+	b := &builder{order: binary.LittleEndian}
+	b.section()
+	b.iface(layers.LinkTypeEthernet, option{code: optIfName, val: []byte("eth0")})
+	b.packet(0, 1, []byte("a"))
+
+	// 新的 Section 使用不同的字节序 且接口序号重新从 0 开始
+	b.order = binary.BigEndian
+	b.section()
+	b.iface(layers.LinkTypeLinuxSLL, option{code: optIfName, val: []byte("any")})
+	b.packet(0, 2, []byte("b"))
+
+	r, err := NewReader(&b.buf)
+	require.NoError(t, err)
+
+	_, err = r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "eth0", r.Interfaces()[0].Name)
+
+	pkt, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("b"), pkt.Data)
+	require.Len(t, r.Interfaces(), 1)
+	assert.Equal(t, "any", r.Interfaces()[0].Name)
+	assert.Equal(t, layers.LinkTypeLinuxSLL, r.Interfaces()[0].LinkType)
+}
+
+func TestInterfaceTimestamp(t *testing.T) {
+	tests := []struct {
+		name    string
+		tsresol byte
+		ts      uint64
+		want    time.Time
+	}{
+		{
+			name:    "micro",
+			tsresol: 6,
+			ts:      1_500000,
+			want:    time.Unix(1, 500000000),
+		},
+		{
+			name:    "nano",
+			tsresol: 9,
+			ts:      1_000000001,
+			want:    time.Unix(1, 1),
+		},
+		{
+			name:    "pico",
+			tsresol: 12,
+			ts:      2_000000001000,
+			want:    time.Unix(2, 1),
+		},
+		{
+			name:    "power of 2",
+			tsresol: 0x80 | 10,
+			ts:      3<<10 | 512,
+			want:    time.Unix(3, 500000000),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			units, ok := tsresolUnits(tt.tsresol)
+			require.True(t, ok)
+			iface := Interface{units: units}
+			assert.Equal(t, tt.want.UTC(), iface.timestamp(tt.ts))
+		})
+	}
+}
+
+func TestReaderFailed(t *testing.T) {
+	_, err := NewReader(bytes.NewReader([]byte{0xd4, 0xc3, 0xb2, 0xa1}))
+	assert.Error(t, err)
+
+	b := &builder{order: binary.LittleEndian}
+	b.section()
+	b.packet(3, 1, []byte("a"))
+	r, err := NewReader(&b.buf)
+	require.NoError(t, err)
+	_, err = r.Next()
+	assert.Error(t, err)
+
+	b = &builder{order: binary.LittleEndian}
+	b.section()
+	b.iface(layers.LinkTypeEthernet)
+	b.packet(0, 1, []byte("truncated"))
+	r, err = NewReader(bytes.NewReader(b.buf.Bytes()[:b.buf.Len()-4]))
+	require.NoError(t, err)
+	_, err = r.Next()
+	assert.Error(t, err)
+}
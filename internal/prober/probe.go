@@ -0,0 +1,259 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Type 探测类型
+type Type string
+
+const (
+	TypeHTTP Type = "http"
+	TypeTCP  Type = "tcp"
+	TypeDNS  Type = "dns"
+)
+
+// Target 探测目标
+//
+// Address 的格式取决于探测类型
+// - http: 完整的 URL 如 http://10.0.0.1:8080/healthz
+// - tcp: host:port
+// - dns: 解析服务器地址 host:port 解析的域名由 Query 指定
+type Target struct {
+	Name     string        `config:"name"`
+	Type     string        `config:"type"` // 可选值为 http / tcp / dns
+	Address  string        `config:"address"`
+	Query    string        `config:"query"`
+	Interval time.Duration `config:"interval"`
+	Timeout  time.Duration `config:"timeout"`
+}
+
+func (t *Target) Validate() error {
+	switch Type(t.Type) {
+	case TypeHTTP, TypeTCP:
+	case TypeDNS:
+		if t.Query == "" {
+			return errors.Errorf("probe (%s) got empty dns query", t.Name)
+		}
+	default:
+		return errors.Errorf("probe (%s) got unsupported type '%s'", t.Name, t.Type)
+	}
+	if t.Address == "" {
+		return errors.Errorf("probe (%s) got empty address", t.Name)
+	}
+	if t.Name == "" {
+		t.Name = t.Address
+	}
+	if t.Interval <= 0 {
+		t.Interval = 30 * time.Second
+	}
+	if t.Timeout <= 0 || t.Timeout > t.Interval {
+		t.Timeout = min(5*time.Second, t.Interval)
+	}
+	return nil
+}
+
+// Result 单次探测结果
+//
+// Connect 为建立 TCP 链接的耗时 DNS 探测基于 UDP 不存在建连阶段
+// Server 为实际探测到的服务端地址 用于与被动采集的数据关联 Local 为探测发起方的本地地址
+type Result struct {
+	Target     Target
+	Time       time.Time
+	Server     string
+	Local      string
+	Connected  bool
+	Success    bool
+	Error      string
+	Connect    time.Duration
+	Duration   time.Duration
+	StatusCode int
+}
+
+// connFunc 探测建立链接后回调 传入链接的本地地址以及远端地址
+type connFunc func(local, remote net.Addr)
+
+func probe(ctx context.Context, t Target, onConn connFunc) Result {
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	// 超时返回后拨号协程仍可能回调 因此本地地址单独加锁记录
+	var mut sync.Mutex
+	var local string
+	track := func(l, r net.Addr) {
+		mut.Lock()
+		local = l.String()
+		mut.Unlock()
+		if onConn != nil {
+			onConn(l, r)
+		}
+	}
+
+	res := Result{Target: t, Time: time.Now()}
+	var err error
+	switch Type(t.Type) {
+	case TypeHTTP:
+		err = probeHTTP(ctx, t, &res, track)
+	case TypeTCP:
+		err = probeTCP(ctx, t, &res, track)
+	case TypeDNS:
+		err = probeDNS(ctx, t, &res, track)
+	}
+	res.Duration = time.Since(res.Time)
+	mut.Lock()
+	res.Local = local
+	mut.Unlock()
+	res.Success = err == nil
+	if err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+func probeTCP(ctx context.Context, t Target, res *Result, onConn connFunc) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", t.Address)
+	res.Connect = time.Since(res.Time)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	onConn(conn.LocalAddr(), conn.RemoteAddr())
+	res.Connected = true
+	res.Server = conn.RemoteAddr().String()
+	return nil
+}
+
+func probeHTTP(ctx context.Context, t Target, res *Result, onConn connFunc) error {
+	// 超时返回后拨号协程仍可能回调 ConnectDone 因此建连结果单独加锁记录
+	var mut sync.Mutex
+	var connectStart time.Time
+	var connected Result
+	defer func() {
+		mut.Lock()
+		defer mut.Unlock()
+		res.Connected, res.Server, res.Connect = connected.Connected, connected.Server, connected.Connect
+	}()
+
+	trace := &httptrace.ClientTrace{
+		ConnectStart: func(string, string) {
+			mut.Lock()
+			defer mut.Unlock()
+			connectStart = time.Now()
+		},
+		ConnectDone: func(_, addr string, err error) {
+			mut.Lock()
+			defer mut.Unlock()
+			if err == nil {
+				connected.Connected = true
+				connected.Server = addr
+				connected.Connect = time.Since(connectStart)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			onConn(info.Conn.LocalAddr(), info.Conn.RemoteAddr())
+		},
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, t.Address, nil)
+	if err != nil {
+		return err
+	}
+
+	// 每次探测使用独立的链接 保证建连耗时可以反映当前的网络状况
+	cli := &http.Client{
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	rsp, err := cli.Do(req)
+	if err != nil {
+		return err
+	}
+	rsp.Body.Close()
+
+	res.StatusCode = rsp.StatusCode
+	if rsp.StatusCode >= http.StatusInternalServerError {
+		return errors.Errorf("unexpected status code %d", rsp.StatusCode)
+	}
+	return nil
+}
+
+func probeDNS(ctx context.Context, t Target, res *Result, onConn connFunc) error {
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, network, t.Address)
+			if err != nil {
+				return nil, err
+			}
+			onConn(conn.LocalAddr(), conn.RemoteAddr())
+			return conn, nil
+		},
+	}
+
+	host, port, err := net.SplitHostPort(t.Address)
+	if err != nil {
+		return err
+	}
+	res.Server = net.JoinHostPort(host, port)
+
+	_, err = resolver.LookupHost(ctx, t.Query)
+	if err == nil {
+		res.Connected = true
+		return nil
+	}
+
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || dnsErr.IsTimeout {
+		return err
+	}
+	// 收到了解析服务器的响应 NXDOMAIN 同样说明解析服务器可用
+	res.Connected = true
+	if dnsErr.IsNotFound {
+		return nil
+	}
+	return err
+}
+
+// splitServer 拆分 host:port 端口非法时返回 0
+func splitServer(addr string) (string, uint16) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, 0
+	}
+	n, _ := strconv.ParseUint(port, 10, 16)
+	return host, uint16(n)
+}
+
+func serverKey(host string, port uint16) string {
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
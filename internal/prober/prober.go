@@ -0,0 +1,237 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package prober 主动探测 与被动采集的数据相互印证
+//
+// 被动采集只能看到已经发生的流量 目标无流量或者链接无法建立时没有数据
+// 主动探测周期性地访问指定目标 同时汇总同一服务端在探测周期内的被动观测结果 用于区分网络问题与应用问题
+package prober
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+type Config struct {
+	Enabled bool `config:"enabled"`
+
+	// SlowConnect 建连耗时超过该值时视为网络异常
+	SlowConnect time.Duration `config:"slowConnect"`
+
+	// SlowResponse 被动观测的平均耗时超过该值时视为应用异常
+	SlowResponse time.Duration `config:"slowResponse"`
+
+	Targets []Target `config:"targets"`
+}
+
+func (c *Config) Validate() error {
+	if c.SlowConnect <= 0 {
+		c.SlowConnect = 200 * time.Millisecond
+	}
+	if c.SlowResponse <= 0 {
+		c.SlowResponse = time.Second
+	}
+	for i := range c.Targets {
+		if err := c.Targets[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verdict 探测结论
+type Verdict string
+
+const (
+	VerdictOK      Verdict = "ok"
+	VerdictNetwork Verdict = "network"
+	VerdictApp     Verdict = "app"
+)
+
+// Passive 探测周期内同一服务端的被动观测汇总
+type Passive struct {
+	Requests int
+	Avg      time.Duration
+	Max      time.Duration
+}
+
+// Report 单次探测结果以及同期的被动观测
+type Report struct {
+	Result
+	ServerAddress string
+	ServerPort    uint16
+	Passive       Passive
+	Verdict       Verdict
+}
+
+// judge 给出探测结论
+//
+// 无法建连或者建连缓慢说明问题位于网络层 建连正常但探测失败或者被动观测耗时过高说明问题位于应用层
+func judge(conf Config, res Result, passive Passive) Verdict {
+	switch {
+	case !res.Connected, res.Connect > conf.SlowConnect:
+		return VerdictNetwork
+	case !res.Success:
+		return VerdictApp
+	case passive.Requests > 0 && passive.Avg > conf.SlowResponse:
+		return VerdictApp
+	}
+	return VerdictOK
+}
+
+// probeConn 探测自身发起的链接 以服务端地址以及本地端口标识
+type probeConn struct {
+	server string
+	port   uint16
+}
+
+type window struct {
+	requests int
+	total    time.Duration
+	max      time.Duration
+}
+
+// Prober 按照各目标的周期执行探测
+type Prober struct {
+	conf Config
+
+	mut     sync.Mutex
+	windows map[string]*window // key 为 host:port 仅记录探测过的服务端
+	conns   map[probeConn]time.Time
+}
+
+func New(conf Config) (*Prober, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	return &Prober{
+		conf:    conf,
+		windows: make(map[string]*window),
+		conns:   make(map[probeConn]time.Time),
+	}, nil
+}
+
+// track 登记探测自身发起的链接
+//
+// 探测流量同样会被抓包 需要从被动观测中剔除 否则探测本身会影响结论
+// 客户端地址可能已被匿名化 因此仅以本地端口区分 链接关闭后端口在 TIME-WAIT 结束前不会被复用
+func (p *Prober) track(local, remote net.Addr) {
+	_, port := splitServer(local.String())
+	host, serverPort := splitServer(remote.String())
+	now := time.Now()
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	for k, t := range p.conns {
+		if now.Sub(t) > socket.TCPMsl {
+			delete(p.conns, k)
+		}
+	}
+	p.conns[probeConn{server: serverKey(host, serverPort), port: port}] = now
+}
+
+// Observe 记录被动采集的 roundtrip 仅统计探测目标对应的服务端 探测自身的流量不计入
+func (p *Prober) Observe(rt socket.RoundTrip) {
+	if socket.IsOneWay(rt) {
+		return
+	}
+	req, ok := socket.PeerOf(rt.Request())
+	if !ok {
+		return
+	}
+	rsp, ok := socket.PeerOf(rt.Response())
+	if !ok {
+		return
+	}
+	key := serverKey(rsp.Host, rsp.Port)
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if _, ok := p.conns[probeConn{server: key, port: req.Port}]; ok {
+		return
+	}
+	w, ok := p.windows[key]
+	if !ok {
+		return
+	}
+	d := rt.Duration()
+	w.requests++
+	w.total += d
+	w.max = max(w.max, d)
+}
+
+// collect 返回服务端自上次探测以来的被动观测 并开启新的统计周期
+func (p *Prober) collect(key string) Passive {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	w, ok := p.windows[key]
+	if !ok {
+		p.windows[key] = &window{}
+		return Passive{}
+	}
+
+	passive := Passive{Requests: w.requests, Max: w.max}
+	if w.requests > 0 {
+		passive.Avg = w.total / time.Duration(w.requests)
+	}
+	*w = window{}
+	return passive
+}
+
+// Run 启动探测 阻塞直至 ctx 结束 每次探测完成后回调 fn
+func (p *Prober) Run(ctx context.Context, fn func(Report)) {
+	var wg sync.WaitGroup
+	for _, t := range p.conf.Targets {
+		wg.Add(1)
+		go func(t Target) {
+			defer wg.Done()
+			p.loop(ctx, t, fn)
+		}(t)
+	}
+	wg.Wait()
+}
+
+func (p *Prober) loop(ctx context.Context, t Target, fn func(Report)) {
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
+
+	for {
+		fn(p.report(probe(ctx, t, p.track)))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Prober) report(res Result) Report {
+	r := Report{Result: res}
+	if res.Server == "" {
+		r.Verdict = judge(p.conf, res, Passive{})
+		return r
+	}
+
+	r.ServerAddress, r.ServerPort = splitServer(res.Server)
+	r.Passive = p.collect(serverKey(r.ServerAddress, r.ServerPort))
+	r.Verdict = judge(p.conf, res, r.Passive)
+	return r
+}
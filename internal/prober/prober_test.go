@@ -0,0 +1,202 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prober
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/confengine"
+)
+
+type message struct {
+	Host string
+	Port uint16
+	Time time.Time
+}
+
+type roundTrip struct {
+	request  *message
+	response *message
+	duration time.Duration
+}
+
+func (rt *roundTrip) Proto() socket.L7Proto   { return socket.L7ProtoHTTP }
+func (rt *roundTrip) Request() any            { return rt.request }
+func (rt *roundTrip) Response() any           { return rt.response }
+func (rt *roundTrip) Duration() time.Duration { return rt.duration }
+func (rt *roundTrip) Validate() bool          { return true }
+
+func newTarget(t *testing.T, typ Type, addr string) Target {
+	target := Target{Type: string(typ), Address: addr, Timeout: time.Second}
+	require.NoError(t, target.Validate())
+	return target
+}
+
+func TestTargetValidate(t *testing.T) {
+	target := Target{Type: string(TypeTCP), Address: "127.0.0.1:80"}
+	assert.NoError(t, target.Validate())
+	assert.Equal(t, "127.0.0.1:80", target.Name)
+	assert.Equal(t, 30*time.Second, target.Interval)
+	assert.Equal(t, 5*time.Second, target.Timeout)
+
+	assert.Error(t, (&Target{Type: "icmp", Address: "127.0.0.1"}).Validate())
+	assert.Error(t, (&Target{Type: string(TypeDNS), Address: "127.0.0.1:53"}).Validate())
+	assert.Error(t, (&Target{Type: string(TypeHTTP)}).Validate())
+}
+
+func TestConfigUnpack(t *testing.T) {
+	conf, err := confengine.LoadContent([]byte(`
+enabled: true
+targets:
+  - type: tcp
+    address: 127.0.0.1:80
+`))
+	require.NoError(t, err)
+
+	var c Config
+	require.NoError(t, conf.Unpack(&c))
+	require.Len(t, c.Targets, 1)
+	assert.Equal(t, string(TypeTCP), c.Targets[0].Type)
+}
+
+func TestProbeTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+
+	res := probe(context.Background(), newTarget(t, TypeTCP, addr), nil)
+	assert.True(t, res.Success)
+	assert.True(t, res.Connected)
+	assert.Equal(t, addr, res.Server)
+
+	l.Close()
+	res = probe(context.Background(), newTarget(t, TypeTCP, addr), nil)
+	assert.False(t, res.Success)
+	assert.False(t, res.Connected)
+	assert.NotEmpty(t, res.Error)
+}
+
+func TestProbeHTTP(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer svr.Close()
+
+	res := probe(context.Background(), newTarget(t, TypeHTTP, svr.URL+"/ok"), nil)
+	assert.True(t, res.Success)
+	assert.True(t, res.Connected)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, svr.Listener.Addr().String(), res.Server)
+
+	res = probe(context.Background(), newTarget(t, TypeHTTP, svr.URL+"/fail"), nil)
+	assert.False(t, res.Success)
+	assert.True(t, res.Connected)
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+}
+
+func TestJudge(t *testing.T) {
+	conf := Config{}
+	require.NoError(t, conf.Validate())
+
+	tests := []struct {
+		name    string
+		res     Result
+		passive Passive
+		want    Verdict
+	}{
+		{name: "Refused", res: Result{}, want: VerdictNetwork},
+		{name: "SlowConnect", res: Result{Connected: true, Success: true, Connect: time.Second}, want: VerdictNetwork},
+		{name: "ServerError", res: Result{Connected: true, StatusCode: 503}, want: VerdictApp},
+		{name: "PassiveSlow", res: Result{Connected: true, Success: true}, passive: Passive{Requests: 3, Avg: 2 * time.Second}, want: VerdictApp},
+		{name: "Healthy", res: Result{Connected: true, Success: true}, passive: Passive{Requests: 3, Avg: time.Millisecond}, want: VerdictOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, judge(conf, tt.res, tt.passive))
+		})
+	}
+}
+
+func TestProberPassive(t *testing.T) {
+	p, err := New(Config{})
+	require.NoError(t, err)
+
+	newRoundTrip := func(port uint16, d time.Duration) *roundTrip {
+		return &roundTrip{request: &message{Host: "10.0.0.2", Port: 40000}, response: &message{Host: "10.0.0.1", Port: port}, duration: d}
+	}
+
+	// 首次探测前服务端未登记 被动观测不计入
+	p.Observe(newRoundTrip(80, time.Second))
+	r := p.report(Result{Connected: true, Success: true, Server: "10.0.0.1:80"})
+	assert.Equal(t, Passive{}, r.Passive)
+	assert.Equal(t, "10.0.0.1", r.ServerAddress)
+	assert.Equal(t, uint16(80), r.ServerPort)
+
+	p.Observe(newRoundTrip(80, 10*time.Millisecond))
+	p.Observe(newRoundTrip(80, 30*time.Millisecond))
+	p.Observe(newRoundTrip(8080, time.Second))
+
+	r = p.report(Result{Connected: true, Success: true, Server: "10.0.0.1:80"})
+	assert.Equal(t, Passive{Requests: 2, Avg: 20 * time.Millisecond, Max: 30 * time.Millisecond}, r.Passive)
+	assert.Equal(t, VerdictOK, r.Verdict)
+
+	r = p.report(Result{Connected: true, Success: true, Server: "10.0.0.1:80"})
+	assert.Equal(t, Passive{}, r.Passive)
+}
+
+func TestProberExcludeOwnConn(t *testing.T) {
+	p, err := New(Config{})
+	require.NoError(t, err)
+
+	r := p.report(Result{Connected: true, Success: true, Server: "10.0.0.1:80"})
+	assert.Equal(t, Passive{}, r.Passive)
+
+	// 探测自身的链接不计入被动观测
+	p.track(&net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 40001}, &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 80})
+	p.Observe(&roundTrip{request: &message{Host: "10.0.0.2", Port: 40001}, response: &message{Host: "10.0.0.1", Port: 80}, duration: time.Second})
+	p.Observe(&roundTrip{request: &message{Host: "10.0.0.2", Port: 40002}, response: &message{Host: "10.0.0.1", Port: 80}, duration: time.Millisecond})
+
+	r = p.report(Result{Connected: true, Success: true, Server: "10.0.0.1:80"})
+	assert.Equal(t, Passive{Requests: 1, Avg: time.Millisecond, Max: time.Millisecond}, r.Passive)
+}
+
+func TestProbeTrackConn(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer svr.Close()
+
+	var local, remote net.Addr
+	res := probe(context.Background(), newTarget(t, TypeHTTP, svr.URL), func(l, r net.Addr) {
+		local, remote = l, r
+	})
+	assert.True(t, res.Success)
+	require.NotNil(t, local)
+	assert.Equal(t, local.String(), res.Local)
+	assert.Equal(t, svr.Listener.Addr().String(), remote.String())
+
+	rt := NewRoundTrip(Report{Result: res, ServerAddress: "127.0.0.1"})
+	assert.Equal(t, socket.L7ProtoProbe, rt.Proto())
+	assert.Equal(t, res.Duration, rt.Duration())
+	assert.NotZero(t, rt.Request().(*Request).Port)
+}
@@ -0,0 +1,104 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package prober
+
+import (
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+// Request 探测请求 Host/Port 为探测发起方的本地地址 未建连时为空
+type Request struct {
+	Host    string
+	Port    uint16
+	Time    time.Time
+	Name    string
+	Type    Type
+	Address string
+	Query   string `json:",omitempty"`
+}
+
+// Response 探测结果 Host/Port 为实际探测到的服务端地址
+//
+// Passive 为同一服务端自上次探测以来被动采集到的 roundtrips 汇总
+type Response struct {
+	Host       string
+	Port       uint16
+	Time       time.Time
+	Connected  bool
+	Success    bool
+	Error      string `json:",omitempty"`
+	StatusCode int    `json:",omitempty"`
+	Connect    time.Duration
+	Passive    Passive
+	Verdict    Verdict
+}
+
+var _ socket.RoundTrip = (*RoundTrip)(nil)
+
+// RoundTrip 单次探测 与被动采集的 roundtrips 使用相同的输出链路
+//
+// 实现了 socket.RoundTrip 接口
+type RoundTrip struct {
+	request  *Request
+	response *Response
+}
+
+// NewRoundTrip 将探测结果转换为 RoundTrip
+func NewRoundTrip(r Report) *RoundTrip {
+	host, port := splitServer(r.Local)
+	return &RoundTrip{
+		request: &Request{
+			Host:    host,
+			Port:    port,
+			Time:    r.Time,
+			Name:    r.Target.Name,
+			Type:    Type(r.Target.Type),
+			Address: r.Target.Address,
+			Query:   r.Target.Query,
+		},
+		response: &Response{
+			Host:       r.ServerAddress,
+			Port:       r.ServerPort,
+			Time:       r.Time.Add(r.Duration),
+			Connected:  r.Connected,
+			Success:    r.Success,
+			Error:      r.Error,
+			StatusCode: r.StatusCode,
+			Connect:    r.Connect,
+			Passive:    r.Passive,
+			Verdict:    r.Verdict,
+		},
+	}
+}
+
+func (rt RoundTrip) Proto() socket.L7Proto {
+	return socket.L7ProtoProbe
+}
+
+func (rt RoundTrip) Request() any {
+	return rt.request
+}
+
+func (rt RoundTrip) Response() any {
+	return rt.response
+}
+
+func (rt RoundTrip) Duration() time.Duration {
+	return rt.response.Time.Sub(rt.request.Time)
+}
+
+func (rt RoundTrip) Validate() bool {
+	return !rt.response.Time.Before(rt.request.Time)
+}
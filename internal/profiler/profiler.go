@@ -0,0 +1,141 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profiler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"runtime/trace"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/packetd/packetd/logger"
+)
+
+const (
+	// KindCPU CPU profile 使用 go tool pprof 分析
+	KindCPU = "cpu"
+
+	// KindTrace 执行轨迹 使用 go tool trace 分析
+	KindTrace = "trace"
+)
+
+const (
+	defaultMaxDuration = 5 * time.Minute
+)
+
+var (
+	ErrBusy            = errors.New("another profile capture is in progress")
+	ErrInvalidDuration = errors.New("duration out of range")
+)
+
+// Capture 单次采集信息
+type Capture struct {
+	Kind  string    `json:"kind"`
+	File  string    `json:"file"`
+	Until time.Time `json:"until"`
+}
+
+// Profiler 按需采集 CPU profile 以及执行轨迹并写入指定目录
+//
+// 生产环境的抓包主机往往无法直接暴露 pprof 端口给开发人员 也难以在问题发生时及时手动采集
+// 采集完成后文件保留在本地 由运维人员拷贝分析 同一时刻仅允许一个采集任务
+type Profiler struct {
+	dir         string
+	maxDuration time.Duration
+	running     atomic.Bool
+}
+
+// New 创建 Profiler 实例 dir 为空时使用系统临时目录
+func New(dir string, maxDuration time.Duration) *Profiler {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "packetd-profiles")
+	}
+	if maxDuration <= 0 {
+		maxDuration = defaultMaxDuration
+	}
+	return &Profiler{
+		dir:         dir,
+		maxDuration: maxDuration,
+	}
+}
+
+// Start 开始采集 d 时长后自动停止并关闭文件
+func (p *Profiler) Start(kind string, d time.Duration) (*Capture, error) {
+	if d <= 0 || d > p.maxDuration {
+		return nil, errors.Wrapf(ErrInvalidDuration, "want (0, %s]", p.maxDuration)
+	}
+
+	var start func(f *os.File) error
+	var stop func()
+	var ext string
+	switch kind {
+	case KindCPU:
+		start, stop, ext = func(f *os.File) error { return pprof.StartCPUProfile(f) }, pprof.StopCPUProfile, "pprof"
+	case KindTrace:
+		start, stop, ext = func(f *os.File) error { return trace.Start(f) }, trace.Stop, "trace"
+	default:
+		return nil, errors.Errorf("unsupported profile kind (%s)", kind)
+	}
+
+	if !p.running.CompareAndSwap(false, true) {
+		return nil, ErrBusy
+	}
+
+	f, err := p.createFile(kind, ext)
+	if err != nil {
+		p.running.Store(false)
+		return nil, err
+	}
+	if err := start(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		p.running.Store(false)
+		return nil, errors.Wrapf(err, "start %s profile", kind)
+	}
+
+	now := time.Now()
+	logger.Infof("profiler: start %s capture for %s, output=%s", kind, d, f.Name())
+	time.AfterFunc(d, func() {
+		stop()
+		if err := f.Close(); err != nil {
+			logger.Warnf("profiler: failed to close %s: %v", f.Name(), err)
+		}
+		p.running.Store(false)
+		logger.Infof("profiler: %s capture finished, output=%s", kind, f.Name())
+	})
+
+	return &Capture{
+		Kind:  kind,
+		File:  f.Name(),
+		Until: now.Add(d),
+	}, nil
+}
+
+// Running 返回是否存在进行中的采集任务
+func (p *Profiler) Running() bool {
+	return p.running.Load()
+}
+
+func (p *Profiler) createFile(kind, ext string) (*os.File, error) {
+	if err := os.MkdirAll(p.dir, 0o755); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("packetd.%s.%s.%s", kind, time.Now().Format("20060102-150405.000"), ext)
+	return os.Create(filepath.Join(p.dir, name))
+}
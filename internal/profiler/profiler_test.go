@@ -0,0 +1,47 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profiler
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfiler(t *testing.T) {
+	p := New(t.TempDir(), time.Second)
+
+	_, err := p.Start(KindCPU, 2*time.Second)
+	assert.ErrorIs(t, err, ErrInvalidDuration)
+
+	_, err = p.Start("heap", 100*time.Millisecond)
+	assert.Error(t, err)
+
+	for _, kind := range []string{KindCPU, KindTrace} {
+		c, err := p.Start(kind, 50*time.Millisecond)
+		assert.NoError(t, err)
+		assert.Equal(t, kind, c.Kind)
+		assert.True(t, p.Running())
+
+		_, err = p.Start(kind, 50*time.Millisecond)
+		assert.ErrorIs(t, err, ErrBusy)
+
+		assert.Eventually(t, func() bool { return !p.Running() }, time.Second, 10*time.Millisecond)
+		info, err := os.Stat(c.File)
+		assert.NoError(t, err)
+		assert.Greater(t, info.Size(), int64(0))
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recenterrors
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultSize 每种协议默认保留的错误 RoundTrip 数量
+const defaultSize = 50
+
+// Config 最近错误缓存配置
+type Config struct {
+	Enabled bool `config:"enabled"`
+	Size    int  `config:"size"`
+}
+
+// GetSize 返回每种协议保留的数量 默认为 50
+func (c Config) GetSize() int {
+	if c.Size <= 0 {
+		return defaultSize
+	}
+	return c.Size
+}
+
+// Entry 单条错误记录
+//
+// RoundTrip 为 socket.JSONMarshalRoundTrip 序列化后的完整内容 写入后不再修改
+type Entry struct {
+	Time      time.Time
+	Code      string
+	RoundTrip json.RawMessage
+}
+
+// ring 定长环形队列 写满后覆盖最旧的记录
+type ring struct {
+	entries []Entry
+	next    int
+	full    bool
+}
+
+func (r *ring) push(e Entry) {
+	r.entries[r.next] = e
+	r.next++
+	if r.next == len(r.entries) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+func (r *ring) len() int {
+	if r.full {
+		return len(r.entries)
+	}
+	return r.next
+}
+
+// latest 按写入时间倒序返回至多 limit 条记录
+func (r *ring) latest(limit int) []Entry {
+	n := r.len()
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	lst := make([]Entry, 0, n)
+	idx := r.next
+	for i := 0; i < n; i++ {
+		idx--
+		if idx < 0 {
+			idx = len(r.entries) - 1
+		}
+		lst = append(lst, r.entries[idx])
+	}
+	return lst
+}
+
+// Store 按协议分别保留最近的错误 RoundTrip
+//
+// 仅驻留内存 重启后清空 用于在单机上快速查看现场 不替代中心化存储
+type Store struct {
+	mut   sync.RWMutex
+	size  int
+	rings map[string]*ring
+}
+
+// New 创建 Store 实例 size 为每种协议保留的数量
+func New(size int) *Store {
+	if size <= 0 {
+		size = defaultSize
+	}
+	return &Store{
+		size:  size,
+		rings: make(map[string]*ring),
+	}
+}
+
+// Add 追加 proto 协议的错误记录
+func (s *Store) Add(proto string, e Entry) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	r, ok := s.rings[proto]
+	if !ok {
+		r = &ring{entries: make([]Entry, s.size)}
+		s.rings[proto] = r
+	}
+	r.push(e)
+}
+
+// List 返回 proto 协议最近的至多 limit 条记录 按时间倒序排列 limit <= 0 时返回全部
+func (s *Store) List(proto string, limit int) []Entry {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	r, ok := s.rings[proto]
+	if !ok {
+		return nil
+	}
+	return r.latest(limit)
+}
+
+// Protos 返回已有错误记录的协议 按字典序排列
+func (s *Store) Protos() []string {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	protos := make([]string, 0, len(s.rings))
+	for proto := range s.rings {
+		protos = append(protos, proto)
+	}
+	sort.Strings(protos)
+	return protos
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recenterrors
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func codes(entries []Entry) []string {
+	var lst []string
+	for _, e := range entries {
+		lst = append(lst, e.Code)
+	}
+	return lst
+}
+
+func TestStore(t *testing.T) {
+	s := New(3)
+	for i := 0; i < 5; i++ {
+		s.Add("mysql", Entry{Code: strconv.Itoa(i)})
+	}
+	s.Add("http", Entry{Code: "500"})
+
+	tests := []struct {
+		name  string
+		proto string
+		limit int
+		want  []string
+	}{
+		{name: "Overwritten", proto: "mysql", want: []string{"4", "3", "2"}},
+		{name: "Limit", proto: "mysql", limit: 2, want: []string{"4", "3"}},
+		{name: "NotFull", proto: "http", limit: 10, want: []string{"500"}},
+		{name: "Missing", proto: "redis"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, codes(s.List(tt.proto, tt.limit)))
+		})
+	}
+	assert.Equal(t, []string{"http", "mysql"}, s.Protos())
+}
+
+func TestConfigGetSize(t *testing.T) {
+	assert.Equal(t, 50, Config{}.GetSize())
+	assert.Equal(t, 10, Config{Size: 10}.GetSize())
+}
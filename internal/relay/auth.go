@@ -0,0 +1,167 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package relay
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// TLSConfig agent 与 collector 之间的传输层加密配置
+type TLSConfig struct {
+	Enabled bool `config:"enabled"`
+
+	// CertFile / KeyFile 证书以及私钥 collector 必填 agent 配置后作为客户端证书
+	CertFile string `config:"certFile"`
+	KeyFile  string `config:"keyFile"`
+
+	// CAFile agent 用于校验 collector 证书 为空时使用系统 CA
+	// collector 配置后要求 agent 出示由该 CA 签发的客户端证书
+	CAFile string `config:"caFile"`
+
+	// ServerName agent 校验证书时使用的域名 为空时使用 endpoint 的主机名
+	ServerName string `config:"serverName"`
+
+	// InsecureSkipVerify agent 不校验 collector 证书 仅用于测试
+	InsecureSkipVerify bool `config:"insecureSkipVerify"`
+}
+
+// ServerConfig collector 侧的 Relay 服务配置
+type ServerConfig struct {
+	TLS TLSConfig
+
+	// Tokens 允许推送的 token 列表 为空时不做校验
+	Tokens []string
+}
+
+// ClientConfig agent 侧的 Relay 客户端配置
+type ClientConfig struct {
+	TLS TLSConfig
+
+	// Token 推送时携带的 token
+	Token string
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read ca file '%s' failed", file)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, errors.Errorf("no certificates found in ca file '%s'", file)
+	}
+	return pool, nil
+}
+
+func (c TLSConfig) serverCredentials() (credentials.TransportCredentials, error) {
+	if !c.Enabled {
+		return insecure.NewCredentials(), nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "load collector certificate failed")
+	}
+
+	conf := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if c.CAFile != "" {
+		if conf.ClientCAs, err = loadCertPool(c.CAFile); err != nil {
+			return nil, err
+		}
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return credentials.NewTLS(conf), nil
+}
+
+func (c TLSConfig) clientCredentials() (credentials.TransportCredentials, error) {
+	if !c.Enabled {
+		return insecure.NewCredentials(), nil
+	}
+
+	conf := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if c.CAFile != "" {
+		pool, err := loadCertPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = pool
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "load agent certificate failed")
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(conf), nil
+}
+
+const authorizationKey = "authorization"
+
+// tokenCredentials 推送时以 `authorization: Bearer <token>` 携带 token
+type tokenCredentials struct {
+	token  string
+	secure bool
+}
+
+func (c tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{authorizationKey: "Bearer " + c.token}, nil
+}
+
+func (c tokenCredentials) RequireTransportSecurity() bool {
+	return c.secure
+}
+
+// authorized 校验请求携带的 token
+func authorized(ctx context.Context, tokens []string) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	vals := md.Get(authorizationKey)
+	if len(vals) == 0 {
+		return false
+	}
+	token, ok := strings.CutPrefix(vals[0], "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	return MatchToken(token, tokens) >= 0
+}
+
+// MatchToken 返回 token 在 tokens 中首个匹配项的下标 未匹配时返回 -1
+//
+// 逐个比较且不提前返回 避免通过响应耗时猜测 token
+func MatchToken(token string, tokens []string) int {
+	idx := -1
+	for i, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 && idx < 0 {
+			idx = i
+		}
+	}
+	return idx
+}
@@ -0,0 +1,201 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package relay 定义了 agent 与 collector 之间的 gRPC 传输协议
+//
+// 协议未使用 protoc 生成代码 消息以 JSON 编码 Span 以 OTLP protobuf 编码后内嵌其中
+package relay
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/json"
+	"github.com/packetd/packetd/internal/metricstorage"
+)
+
+const (
+	serviceName = "packetd.relay.Relay"
+	methodPush  = "/" + serviceName + "/Push"
+)
+
+// Key roundtrip 的去重标识
+//
+// Hash 由协议以及客户端/服务端地址计算得出 Time 为请求时间
+// 同一条链接上的两端 agent 观测到的 roundtrip 具有相同的 Hash 且请求时间相近
+type Key struct {
+	Hash uint64    `json:",omitempty"`
+	Time time.Time `json:",omitempty"`
+
+	// TSVal 请求首个数据段的 TSval 两端 agent 均开启 controller.tcpTimestamps 时可精确关联同一个请求
+	TSVal uint32 `json:",omitempty"`
+
+	// Duration 该 agent 观测到的 roundtrip 耗时 客户端一侧的耗时包含网络传输
+	Duration time.Duration `json:",omitempty"`
+}
+
+// Valid 返回 Key 是否可用于去重
+func (k Key) Valid() bool {
+	return k.Hash != 0
+}
+
+// Observation 单个 roundtrip 生成的指标
+type Observation struct {
+	Key  Key
+	Data []metricstorage.ConstMetric
+}
+
+// Batch agent 单次推送的数据
+type Batch struct {
+	Agent   string
+	Metrics []Observation
+
+	// Traces 为 OTLP protobuf 编码的 ptrace.Traces SpanKeys 与其中的 Span 按顺序一一对应
+	Traces   []byte
+	SpanKeys []Key
+
+	RoundTrips []RoundTripRecord
+}
+
+// RoundTripRecord JSON 编码的 roundtrip 以及 agent 为其附加的标签
+type RoundTripRecord struct {
+	Labels map[string]string
+	Data   []byte
+}
+
+// Ack collector 对 Batch 的确认
+type Ack struct {
+	Accepted   int
+	Duplicated int
+}
+
+// RoundTripKey 计算 roundtrip 的去重标识
+//
+// 所有协议的 Request/Response 均包含 Host/Port/Time 字段 无响应的单向事件无法确定服务端 不参与去重
+func RoundTripKey(rt socket.RoundTrip) Key {
+	if rt == nil || socket.IsOneWay(rt) {
+		return Key{}
+	}
+
+	req, ok1 := socket.PeerOf(rt.Request())
+	rsp, ok2 := socket.PeerOf(rt.Response())
+	if !ok1 || !ok2 {
+		return Key{}
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(rt.Proto()))
+	h.Write([]byte{0})
+	h.Write([]byte(req.Host + ":" + strconv.Itoa(int(req.Port))))
+	h.Write([]byte{0})
+	h.Write([]byte(rsp.Host + ":" + strconv.Itoa(int(rsp.Port))))
+	return Key{Hash: h.Sum64(), Time: req.Time, TSVal: socket.TCPTimestamp(rt), Duration: rt.Duration()}
+}
+
+type codec struct{}
+
+func (codec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return "json"
+}
+
+// Handler 处理 agent 推送的 Batch
+type Handler func(ctx context.Context, b *Batch) (*Ack, error)
+
+// NewServer 创建注册了 Relay 服务的 gRPC Server
+//
+// 配置了 Tokens 时 未携带有效 token 的推送返回 Unauthenticated
+func NewServer(h Handler, conf ServerConfig) (*grpc.Server, error) {
+	creds, err := conf.TLS.serverCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	srv := grpc.NewServer(grpc.ForceServerCodec(codec{}), grpc.Creds(creds))
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: serviceName,
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "Push",
+				Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+					if !authorized(ctx, conf.Tokens) {
+						return nil, status.Error(codes.Unauthenticated, "invalid push token")
+					}
+					b := &Batch{}
+					if err := dec(b); err != nil {
+						return nil, err
+					}
+					return h(ctx, b)
+				},
+			},
+		},
+	}, nil)
+	return srv, nil
+}
+
+// Client collector 客户端
+type Client struct {
+	conn *grpc.ClientConn
+}
+
+// NewClient 创建 collector 客户端 链接在首次推送时建立
+//
+// 请求体使用 gzip 压缩 agent 数量较多时可显著降低 collector 的入口带宽
+func NewClient(endpoint string, conf ClientConfig) (*Client, error) {
+	creds, err := conf.TLS.clientCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(codec{}), grpc.UseCompressor(gzip.Name)),
+	}
+	if conf.Token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials{token: conf.Token, secure: conf.TLS.Enabled}))
+	}
+	conn, err := grpc.NewClient(endpoint, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn}, nil
+}
+
+// Push 推送 Batch
+func (c *Client) Push(ctx context.Context, b *Batch) (*Ack, error) {
+	ack := &Ack{}
+	if err := c.conn.Invoke(ctx, methodPush, b, ack); err != nil {
+		return nil, err
+	}
+	return ack, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,198 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relay
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/labels"
+	"github.com/packetd/packetd/internal/metricstorage"
+)
+
+type message struct {
+	Host string
+	Port uint16
+	Time time.Time
+}
+
+type roundTrip struct {
+	request  *message
+	response *message
+}
+
+func (rt *roundTrip) Proto() socket.L7Proto   { return socket.L7ProtoHTTP }
+func (rt *roundTrip) Request() any            { return rt.request }
+func (rt *roundTrip) Response() any           { return rt.response }
+func (rt *roundTrip) Duration() time.Duration { return 0 }
+func (rt *roundTrip) Validate() bool          { return true }
+
+func TestRoundTripKey(t *testing.T) {
+	now := time.Now()
+	newRoundTrip := func(clientPort uint16, t time.Time) *roundTrip {
+		return &roundTrip{
+			request:  &message{Host: "10.0.0.1", Port: clientPort, Time: t},
+			response: &message{Host: "10.0.0.2", Port: 80, Time: t.Add(time.Millisecond)},
+		}
+	}
+
+	k1 := RoundTripKey(newRoundTrip(50000, now))
+	k2 := RoundTripKey(newRoundTrip(50000, now.Add(time.Millisecond)))
+	k3 := RoundTripKey(newRoundTrip(50001, now))
+
+	assert.True(t, k1.Valid())
+	assert.Equal(t, k1.Hash, k2.Hash)
+	assert.Equal(t, now, k1.Time)
+	assert.NotEqual(t, k1.Hash, k3.Hash)
+	assert.Zero(t, k1.TSVal)
+
+	k4 := RoundTripKey(socket.WithTCPTimestamp(newRoundTrip(50000, now), 12345))
+	assert.Equal(t, k1.Hash, k4.Hash)
+	assert.Equal(t, uint32(12345), k4.TSVal)
+
+	assert.False(t, RoundTripKey(nil).Valid())
+	assert.False(t, RoundTripKey(&roundTrip{request: &message{}}).Valid())
+}
+
+func TestPush(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var got *Batch
+	srv, err := NewServer(func(_ context.Context, b *Batch) (*Ack, error) {
+		got = b
+		return &Ack{Accepted: len(b.Metrics)}, nil
+	}, ServerConfig{})
+	require.NoError(t, err)
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	cli, err := NewClient(l.Addr().String(), ClientConfig{})
+	require.NoError(t, err)
+	defer cli.Close()
+
+	batch := &Batch{
+		Agent: "agent-1",
+		Metrics: []Observation{
+			{
+				Key: Key{Hash: 1, Time: time.Unix(1, 0).UTC()},
+				Data: []metricstorage.ConstMetric{
+					metricstorage.NewCounterConstMetric("http_requests_total", 1, labels.Labels{{Name: "method", Value: "GET"}}),
+				},
+			},
+		},
+		Traces:   []byte{0x01, 0x02},
+		SpanKeys: []Key{{Hash: 2}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ack, err := cli.Push(ctx, batch)
+	require.NoError(t, err)
+	assert.Equal(t, 1, ack.Accepted)
+	assert.Equal(t, batch, got)
+}
+
+// writeCert 生成自签名证书 返回证书以及私钥路径
+func writeCert(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "collector"},
+		DNSNames:              []string{"collector.local"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+	return certFile, keyFile
+}
+
+func TestPushAuth(t *testing.T) {
+	certFile, keyFile := writeCert(t)
+	serverTLS := TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile}
+	clientTLS := TLSConfig{Enabled: true, CAFile: certFile, ServerName: "collector.local"}
+
+	tests := []struct {
+		name   string
+		client ClientConfig
+		code   codes.Code
+	}{
+		{name: "Valid", client: ClientConfig{TLS: clientTLS, Token: "secret"}, code: codes.OK},
+		{name: "InvalidToken", client: ClientConfig{TLS: clientTLS, Token: "guess"}, code: codes.Unauthenticated},
+		{name: "MissingToken", client: ClientConfig{TLS: clientTLS}, code: codes.Unauthenticated},
+		{name: "Plaintext", client: ClientConfig{Token: "secret"}, code: codes.Unavailable},
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv, err := NewServer(func(context.Context, *Batch) (*Ack, error) {
+		return &Ack{}, nil
+	}, ServerConfig{TLS: serverTLS, Tokens: []string{"secret"}})
+	require.NoError(t, err)
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cli, err := NewClient(l.Addr().String(), tt.client)
+			require.NoError(t, err)
+			defer cli.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err = cli.Push(ctx, &Batch{Agent: "agent-1"})
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestMatchToken(t *testing.T) {
+	tokens := []string{"alpha", "beta", "alpha"}
+	assert.Equal(t, 0, MatchToken("alpha", tokens))
+	assert.Equal(t, 1, MatchToken("beta", tokens))
+	assert.Equal(t, -1, MatchToken("gamma", tokens))
+	assert.Equal(t, -1, MatchToken("alp", tokens))
+	assert.Equal(t, -1, MatchToken("alpha", nil))
+}
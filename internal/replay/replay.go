@@ -0,0 +1,233 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/pcapgo"
+	"github.com/pkg/errors"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/pcapng"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/sniffer"
+)
+
+// packetReader 返回数据包内容 时间戳以及所属接口的链路类型和名称
+type packetReader interface {
+	next() ([]byte, time.Time, layers.LinkType, string, error)
+}
+
+type pcapReader struct {
+	r *pcapgo.Reader
+}
+
+func (pr pcapReader) next() ([]byte, time.Time, layers.LinkType, string, error) {
+	data, ci, err := pr.r.ReadPacketData()
+	return data, ci.Timestamp, pr.r.LinkType(), "", err
+}
+
+// ngReader pcapng 文件每个接口可以声明不同的链路类型
+type ngReader struct {
+	r *pcapng.Reader
+}
+
+func (nr ngReader) next() ([]byte, time.Time, layers.LinkType, string, error) {
+	pkt, err := nr.r.Next()
+	if err != nil {
+		return nil, time.Time{}, 0, "", err
+	}
+	iface := nr.r.Interfaces()[pkt.Interface]
+	return pkt.Data, pkt.Timestamp, iface.LinkType, iface.Label(pkt.Interface), nil
+}
+
+func newPacketReader(r io.Reader) (packetReader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(pcapng.Magic))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(magic, pcapng.Magic) {
+		nr, err := pcapng.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return ngReader{r: nr}, nil
+	}
+
+	pr, err := pcapgo.NewReader(br)
+	if err != nil {
+		return nil, err
+	}
+	return pcapReader{r: pr}, nil
+}
+
+// Replay 读取 pcap/pcapng 文件 将数据包按照 ports 声明的协议解析并返回所有 RoundTrip
+//
+// 与抓包引擎的文件模式不同 Replay 不依赖 libpcap 且不经过 BPF 过滤 仅用于回归测试
+// 数据包时间统一转换为 UTC 保证输出结果与运行环境的时区无关
+func Replay(path string, ports []socket.L7Ports) ([]socket.RoundTrip, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader, err := newPacketReader(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s", path)
+	}
+	pools, err := newPools(ports)
+	if err != nil {
+		return nil, err
+	}
+	defer pools.clean()
+
+	var rts []socket.RoundTrip
+	var wg sync.WaitGroup
+	ch := make(chan socket.RoundTrip, common.Concurrency())
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for rt := range ch {
+			rts = append(rts, rt)
+		}
+	}()
+
+	for {
+		data, ts, lt, iface, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			close(ch)
+			wg.Wait()
+			return nil, errors.Wrapf(err, "read %s", path)
+		}
+		if pkt := decodePacket(data, lt, iface, ts.UTC()); pkt != nil {
+			pools.onL4Packet(pkt, ch)
+		}
+	}
+
+	close(ch)
+	wg.Wait()
+	return rts, nil
+}
+
+func decodePacket(data []byte, lt layers.LinkType, iface string, ts time.Time) socket.L4Packet {
+	payload, lyr, next, err := sniffer.DecodeLinkIPLayer(data, lt, "")
+	if err != nil {
+		return nil
+	}
+
+	switch next {
+	case layers.LayerTypeTCP:
+		var tcpPkt layers.TCP
+		if err := tcpPkt.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err != nil {
+			return nil
+		}
+		if pkt := sniffer.ParseTCPPacket(ts, lyr, &tcpPkt); pkt != nil {
+			pkt.Iface = iface
+			return pkt
+		}
+
+	case layers.LayerTypeUDP:
+		var udpPkt layers.UDP
+		if err := udpPkt.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err != nil {
+			return nil
+		}
+		if pkt := sniffer.ParseUDPDatagram(ts, lyr, &udpPkt); pkt != nil {
+			pkt.Iface = iface
+			return pkt
+		}
+	}
+	return nil
+}
+
+type pools struct {
+	ports map[socket.Port]protocol.ConnPool
+	all   []protocol.ConnPool
+}
+
+func newPools(l7ports []socket.L7Ports) (*pools, error) {
+	ps := &pools{ports: make(map[socket.Port]protocol.ConnPool)}
+	created := make(map[socket.L7Proto]protocol.ConnPool)
+	for _, pp := range l7ports {
+		pool, ok := created[pp.Proto]
+		if !ok {
+			f, err := protocol.Get(pp.Proto)
+			if err != nil {
+				return nil, err
+			}
+			pool = f(common.NewOptions())
+			created[pp.Proto] = pool
+			ps.all = append(ps.all, pool)
+		}
+		for _, port := range pp.Ports {
+			ps.ports[port] = pool
+		}
+	}
+	return ps, nil
+}
+
+func (ps *pools) onL4Packet(pkt socket.L4Packet, ch chan<- socket.RoundTrip) {
+	st := pkt.SocketTuple()
+	port := st.SrcPort
+	pool, ok := ps.ports[port]
+	if !ok {
+		port = st.DstPort
+		if pool, ok = ps.ports[port]; !ok {
+			return
+		}
+	}
+
+	conn := pool.GetOrCreate(st, port)
+	if err := conn.OnL4Packet(pkt, ch); errors.Is(err, protocol.ErrConnClosed) {
+		pool.Delete(st)
+	}
+}
+
+func (ps *pools) clean() {
+	for _, pool := range ps.all {
+		pool.Clean()
+	}
+}
+
+// MarshalGolden 将 RoundTrip 列表序列化为 golden 文件内容
+func MarshalGolden(rts []socket.RoundTrip) ([]byte, error) {
+	lst := make([]json.RawMessage, 0, len(rts))
+	for _, rt := range rts {
+		b, err := socket.JSONMarshalRoundTrip(rt)
+		if err != nil {
+			return nil, err
+		}
+		lst = append(lst, b)
+	}
+
+	b, err := json.MarshalIndent(lst, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common/socket"
+	_ "github.com/packetd/packetd/protocol/pamqp"
+	_ "github.com/packetd/packetd/protocol/pdns"
+	_ "github.com/packetd/packetd/protocol/pgrpc"
+	_ "github.com/packetd/packetd/protocol/phttp"
+	_ "github.com/packetd/packetd/protocol/phttp2"
+	_ "github.com/packetd/packetd/protocol/pkafka"
+	_ "github.com/packetd/packetd/protocol/pmongodb"
+	_ "github.com/packetd/packetd/protocol/pmysql"
+	_ "github.com/packetd/packetd/protocol/pntp"
+	_ "github.com/packetd/packetd/protocol/ppostgresql"
+	_ "github.com/packetd/packetd/protocol/predis"
+	_ "github.com/packetd/packetd/protocol/ptls"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// corpusDir pcap 语料目录 子目录名称即为协议名称
+const corpusDir = "../../testdata/pcaps"
+
+// corpusPorts 各协议语料使用的服务端端口
+var corpusPorts = map[socket.L7Proto][]socket.Port{
+	socket.L7ProtoHTTP:       {80, 8080},
+	socket.L7ProtoHTTP2:      {50051},
+	socket.L7ProtoGRPC:       {9090},
+	socket.L7ProtoDNS:        {53},
+	socket.L7ProtoRedis:      {6379},
+	socket.L7ProtoMySQL:      {3306},
+	socket.L7ProtoPostgreSQL: {5432},
+	socket.L7ProtoKafka:      {9092},
+	socket.L7ProtoMongoDB:    {27017},
+	socket.L7ProtoAMQP:       {5672},
+	socket.L7ProtoNTP:        {123},
+	socket.L7ProtoTLS:        {443},
+}
+
+func TestCorpus(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(corpusDir, "*", "*.pcap*"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		proto := socket.L7Proto(filepath.Base(filepath.Dir(file)))
+		name := strings.TrimSuffix(file, filepath.Ext(file))
+		t.Run(string(proto)+"/"+filepath.Base(name), func(t *testing.T) {
+			ports, ok := corpusPorts[proto]
+			require.True(t, ok, "unknown protocol directory (%s)", proto)
+
+			rts, err := Replay(file, []socket.L7Ports{{Proto: proto, Ports: ports}})
+			require.NoError(t, err)
+			assert.NotEmpty(t, rts)
+
+			got, err := MarshalGolden(rts)
+			require.NoError(t, err)
+
+			golden := name + ".json"
+			if *update {
+				require.NoError(t, os.WriteFile(golden, got, 0o644))
+				return
+			}
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err, "run with -update to create golden file")
+			assert.JSONEq(t, string(want), string(got))
+		})
+	}
+}
+
+func TestReplayFailed(t *testing.T) {
+	_, err := Replay(filepath.Join(corpusDir, "not-exist.pcap"), nil)
+	assert.Error(t, err)
+
+	_, err = Replay(filepath.Join(corpusDir, "README.md"), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package selftest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/loadgen"
+	"github.com/packetd/packetd/protocol"
+)
+
+// Result 单个 decoder 的自检结果
+type Result struct {
+	Proto      socket.L7Proto
+	Passed     bool
+	Reason     string
+	Generated  uint64
+	RoundTrips uint64
+	Elapsed    time.Duration
+	Bytes      uint64
+}
+
+// Rate 每秒解析的请求来回数量
+func (r Result) Rate() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.RoundTrips) / r.Elapsed.Seconds()
+}
+
+// Throughput 每秒解析的字节数 单位为 MB
+func (r Result) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / r.Elapsed.Seconds() / 1024 / 1024
+}
+
+// Run 使用内置样本依次驱动所有已注册的 decoder
+//
+// 样本即 loadgen 为各协议合成的请求来回 单链接单 worker 运行 duration 时长
+// 所有合成的请求来回均被解析并配对才视为通过 没有样本的协议直接判定为失败
+func Run(ctx context.Context, protos []socket.L7Proto, duration time.Duration) []Result {
+	if len(protos) == 0 {
+		protos = protocol.Protocols()
+	}
+
+	results := make([]Result, 0, len(protos))
+	for _, proto := range protos {
+		if ctx.Err() != nil {
+			break
+		}
+		results = append(results, runOne(ctx, proto, duration))
+	}
+	return results
+}
+
+func runOne(ctx context.Context, proto socket.L7Proto, duration time.Duration) Result {
+	result := Result{Proto: proto}
+	if _, err := protocol.Get(proto); err != nil {
+		result.Reason = "decoder not registered"
+		return result
+	}
+	if _, ok := loadgen.NewGenerator(proto); !ok {
+		result.Reason = "no sample corpus"
+		return result
+	}
+
+	lr, err := loadgen.Run(ctx, loadgen.Config{
+		Protocols: []string{string(proto)},
+		Conns:     1,
+		Workers:   1,
+		Duration:  duration,
+	})
+	if err != nil {
+		result.Reason = err.Error()
+		return result
+	}
+
+	result.Generated = lr.Generated
+	result.RoundTrips = lr.RoundTrips[proto]
+	result.Elapsed = lr.Elapsed
+	result.Bytes = lr.Bytes
+
+	switch {
+	case result.Generated == 0:
+		result.Reason = "no roundtrips generated"
+	case result.RoundTrips != result.Generated:
+		result.Reason = fmt.Sprintf("decoded %d of %d roundtrips", result.RoundTrips, result.Generated)
+	default:
+		result.Passed = true
+	}
+	return result
+}
+
+// Passed 判断是否所有 decoder 均通过自检
+func Passed(results []Result) bool {
+	for _, r := range results {
+		if !r.Passed {
+			return false
+		}
+	}
+	return len(results) > 0
+}
+
+// Print 以表格形式输出自检结果
+func Print(w io.Writer, results []Result) {
+	fmt.Fprintf(w, "platform: %s/%s, go: %s\n\n", runtime.GOOS, runtime.GOARCH, runtime.Version())
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DECODER\tRESULT\tROUNDTRIPS\tRATE\tTHROUGHPUT\tREASON")
+	for _, r := range results {
+		status := "PASS"
+		if !r.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%.0f/s\t%.2f MB/s\t%s\n", r.Proto, status, r.RoundTrips, r.Rate(), r.Throughput(), r.Reason)
+	}
+	tw.Flush()
+}
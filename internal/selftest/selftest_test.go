@@ -0,0 +1,62 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common/socket"
+	_ "github.com/packetd/packetd/protocol/phttp"
+	_ "github.com/packetd/packetd/protocol/pntp"
+	_ "github.com/packetd/packetd/protocol/ptls"
+)
+
+func TestRun(t *testing.T) {
+	results := Run(context.Background(), []socket.L7Proto{
+		socket.L7ProtoHTTP,
+		socket.L7ProtoNTP,
+		socket.L7ProtoTLS,
+	}, 50*time.Millisecond)
+
+	require.Len(t, results, 3)
+	for _, r := range results {
+		assert.True(t, r.Passed, "%s: %s", r.Proto, r.Reason)
+		assert.Greater(t, r.RoundTrips, uint64(0))
+		assert.Greater(t, r.Rate(), float64(0))
+	}
+	assert.True(t, Passed(results))
+
+	var buf bytes.Buffer
+	Print(&buf, results)
+	assert.Contains(t, buf.String(), "PASS")
+	assert.Contains(t, buf.String(), "tls")
+}
+
+func TestRunFailed(t *testing.T) {
+	results := Run(context.Background(), []socket.L7Proto{"smtp"}, time.Millisecond)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Passed)
+	assert.Equal(t, "decoder not registered", results[0].Reason)
+	assert.False(t, Passed(results))
+
+	var buf bytes.Buffer
+	Print(&buf, results)
+	assert.Contains(t, buf.String(), "FAIL")
+}
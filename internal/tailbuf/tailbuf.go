@@ -0,0 +1,99 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tailbuf
+
+import (
+	"sync"
+)
+
+// sizeClasses 底层数组按容量分级复用 超出最大分级的数组直接分配且不回收
+var sizeClasses = [...]int{64, 512, 4096, 32768}
+
+var slabs [len(sizeClasses)]sync.Pool
+
+func alloc(n int) []byte {
+	for i, size := range sizeClasses {
+		if n > size {
+			continue
+		}
+		if p, ok := slabs[i].Get().(*[]byte); ok {
+			return (*p)[:0]
+		}
+		return make([]byte, 0, size)
+	}
+	return make([]byte, 0, n)
+}
+
+func release(b []byte) {
+	for i, size := range sizeClasses {
+		if cap(b) == size {
+			b = b[:0]
+			slabs[i].Put(&b)
+			return
+		}
+	}
+}
+
+// ensure 保证 b 的容量不小于 n 扩容时归还原有数组 返回长度为 0 的切片
+func ensure(b []byte, n int) []byte {
+	if cap(b) >= n {
+		return b[:0]
+	}
+	release(b)
+	return alloc(n)
+}
+
+// Buffer 用于 decoder 跨数据包拼接的尾部数据
+//
+// 数据包末尾不完整的片段需要拷贝保存 并在下一个数据包到达时拼接在其头部
+// tail 以及 join 分别持有独立的底层数组 并在链接的生命周期内复用 稳定状态下不产生堆内存分配
+// 两者相互独立 因此 Set 传入的数据允许指向 Join 返回的内存
+type Buffer struct {
+	tail []byte
+	join []byte
+}
+
+// Set 拷贝并记录尾部数据
+func (buf *Buffer) Set(b []byte) {
+	buf.tail = ensure(buf.tail, len(b))
+	buf.tail = append(buf.tail, b...)
+}
+
+// Bytes 返回尾部数据
+func (buf *Buffer) Bytes() []byte {
+	return buf.tail
+}
+
+// Join 返回尾部数据与 b 拼接后的内容 尾部数据保持不变
+//
+// 返回值在下一次调用 Join 或者 Free 之前有效
+func (buf *Buffer) Join(b []byte) []byte {
+	buf.join = ensure(buf.join, len(buf.tail)+len(b))
+	buf.join = append(buf.join, buf.tail...)
+	buf.join = append(buf.join, b...)
+	return buf.join
+}
+
+// Reset 清空尾部数据 保留底层数组
+func (buf *Buffer) Reset() {
+	buf.tail = buf.tail[:0]
+}
+
+// Free 归还底层数组
+func (buf *Buffer) Free() {
+	release(buf.tail)
+	release(buf.join)
+	buf.tail = nil
+	buf.join = nil
+}
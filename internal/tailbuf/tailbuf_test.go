@@ -0,0 +1,68 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tailbuf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuffer(t *testing.T) {
+	var buf Buffer
+	assert.Empty(t, buf.Bytes())
+
+	src := []byte("head")
+	buf.Set(src)
+	src[0] = 'x' // 必须拷贝内存
+	assert.Equal(t, []byte("head"), buf.Bytes())
+
+	joined := buf.Join([]byte("-body"))
+	assert.Equal(t, []byte("head-body"), joined)
+	assert.Equal(t, []byte("head"), buf.Bytes())
+
+	// 允许使用 Join 返回的内存设置尾部数据
+	buf.Set(joined[5:])
+	assert.Equal(t, []byte("body"), buf.Bytes())
+	assert.Equal(t, []byte("body!"), buf.Join([]byte("!")))
+
+	// 超出分级容量时扩容
+	large := bytes.Repeat([]byte{'a'}, 1000)
+	buf.Set(large)
+	assert.Equal(t, large, buf.Bytes())
+	joined = buf.Join(large[:24])
+	assert.Len(t, joined, 1024)
+	assert.Equal(t, 4096, cap(joined))
+
+	buf.Reset()
+	assert.Empty(t, buf.Bytes())
+	buf.Free()
+	assert.Nil(t, buf.Bytes())
+}
+
+func TestBufferAllocs(t *testing.T) {
+	var buf Buffer
+	tail := []byte("partial header")
+	payload := bytes.Repeat([]byte{'b'}, 256)
+
+	buf.Set(tail)
+	buf.Join(payload)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf.Set(tail)
+		buf.Join(payload)
+		buf.Reset()
+	})
+	assert.Zero(t, allocs)
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tcpstamp 记录各链接数据段携带的 TCP Timestamps 选项
+//
+// 同一个数据段在客户端与服务端主机上观测到的 TSval 相同 且与两端时钟是否同步无关
+// 以 TSval 关联两台主机上的同一个请求 即可在无 trace header 的环境下拆分网络传输耗时与服务端耗时
+package tcpstamp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+// maxStamps 每个方向保留的数据段数量 需覆盖 roundtrip 自解析完成至被消费期间新到达的数据段
+const maxStamps = 16
+
+type stamp struct {
+	time  time.Time
+	tsval uint32
+}
+
+type flow struct {
+	stamps [maxStamps]stamp
+	next   int
+	seen   time.Time
+}
+
+// Index 按照链接方向记录最近的数据段到达时间以及 TSval
+type Index struct {
+	mut   sync.Mutex
+	flows map[socket.Tuple]*flow
+}
+
+// New 创建 Index 实例
+func New() *Index {
+	return &Index{flows: make(map[socket.Tuple]*flow)}
+}
+
+// Observe 记录携带 payload 的数据段 未携带 Timestamps 选项的数据段直接忽略
+func (idx *Index) Observe(seg *socket.TCPSegment) {
+	if seg.TSVal == 0 || len(seg.Payload) == 0 {
+		return
+	}
+
+	idx.mut.Lock()
+	defer idx.mut.Unlock()
+
+	f, ok := idx.flows[seg.Tuple]
+	if !ok {
+		f = &flow{}
+		idx.flows[seg.Tuple] = f
+	}
+	f.stamps[f.next] = stamp{time: seg.Time, tsval: seg.TSVal}
+	f.next = (f.next + 1) % maxStamps
+	f.seen = time.Now()
+}
+
+// Lookup 返回 st 方向上 t 时刻（含）之前最后到达的数据段的 TSval
+//
+// 请求时间即为请求首个数据段的到达时间 正常情况下总能精确命中
+func (idx *Index) Lookup(st socket.Tuple, t time.Time) (uint32, bool) {
+	idx.mut.Lock()
+	defer idx.mut.Unlock()
+
+	f, ok := idx.flows[st]
+	if !ok {
+		return 0, false
+	}
+
+	var found stamp
+	for _, s := range f.stamps {
+		if s.tsval == 0 || s.time.After(t) || s.time.Before(found.time) {
+			continue
+		}
+		found = s
+	}
+	return found.tsval, found.tsval != 0
+}
+
+// RemoveExpired 删除 before 之前再无数据段到达的链接 返回删除的数量
+//
+// 链接关闭时最后一个 roundtrip 可能尚未被消费 因此不随链接删除 仅按照过期时间清理
+func (idx *Index) RemoveExpired(before time.Time) int {
+	idx.mut.Lock()
+	defer idx.mut.Unlock()
+
+	var n int
+	for st, f := range idx.flows {
+		if f.seen.Before(before) {
+			delete(idx.flows, st)
+			n++
+		}
+	}
+	return n
+}
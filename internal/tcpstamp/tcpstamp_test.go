@@ -0,0 +1,86 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcpstamp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+func TestIndex(t *testing.T) {
+	st := socket.Tuple{
+		SrcIP:   socket.ToIPV4(net.ParseIP("10.0.0.1").To4()),
+		DstIP:   socket.ToIPV4(net.ParseIP("10.0.0.2").To4()),
+		SrcPort: 50001,
+		DstPort: 6379,
+	}
+	t0 := time.Unix(1700000000, 0)
+
+	idx := New()
+	idx.Observe(&socket.TCPSegment{Tuple: st, Time: t0, TSVal: 100, Payload: []byte("a")})
+	idx.Observe(&socket.TCPSegment{Tuple: st, Time: t0.Add(time.Millisecond), TSVal: 0, Payload: []byte("b")})
+	idx.Observe(&socket.TCPSegment{Tuple: st, Time: t0.Add(2 * time.Millisecond), TSVal: 102})
+	idx.Observe(&socket.TCPSegment{Tuple: st, Time: t0.Add(3 * time.Millisecond), TSVal: 103, Payload: []byte("c")})
+	idx.Observe(&socket.TCPSegment{Tuple: st.Mirror(), Time: t0.Add(4 * time.Millisecond), TSVal: 900, Payload: []byte("d")})
+
+	tests := []struct {
+		name  string
+		st    socket.Tuple
+		t     time.Time
+		tsval uint32
+		ok    bool
+	}{
+		{name: "exact", st: st, t: t0, tsval: 100, ok: true},
+		{name: "exact later", st: st, t: t0.Add(3 * time.Millisecond), tsval: 103, ok: true},
+		{name: "without option or payload", st: st, t: t0.Add(2 * time.Millisecond), tsval: 100, ok: true},
+		{name: "before first", st: st, t: t0.Add(-time.Millisecond)},
+		{name: "mirror", st: st.Mirror(), t: t0.Add(5 * time.Millisecond), tsval: 900, ok: true},
+		{name: "unknown", st: socket.Tuple{SrcPort: 1}, t: t0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tsval, ok := idx.Lookup(tt.st, tt.t)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.tsval, tsval)
+		})
+	}
+
+	assert.Equal(t, 0, idx.RemoveExpired(time.Now().Add(-time.Minute)))
+	assert.Equal(t, 2, idx.RemoveExpired(time.Now().Add(time.Second)))
+	_, ok := idx.Lookup(st, t0)
+	assert.False(t, ok)
+}
+
+func TestIndexWrap(t *testing.T) {
+	st := socket.Tuple{SrcPort: 50001, DstPort: 80}
+	t0 := time.Unix(1700000000, 0)
+
+	idx := New()
+	for i := 0; i < maxStamps*2; i++ {
+		idx.Observe(&socket.TCPSegment{Tuple: st, Time: t0.Add(time.Duration(i) * time.Millisecond), TSVal: uint32(i + 1), Payload: []byte("a")})
+	}
+
+	// 被覆盖的数据段无法再精确命中
+	_, ok := idx.Lookup(st, t0)
+	assert.False(t, ok)
+
+	tsval, ok := idx.Lookup(st, t0.Add(time.Duration(maxStamps*2-1)*time.Millisecond))
+	assert.True(t, ok)
+	assert.Equal(t, uint32(maxStamps*2), tsval)
+}
@@ -24,20 +24,34 @@ import (
 
 const (
 	headerTraceParent = "traceparent"
+	headerTraceState  = "tracestate"
 )
 
 type TraceContext struct {
 	TraceID pcommon.TraceID
 	SpanID  pcommon.SpanID
+	State   string // tracestate 厂商自定义的传播字段 原样透传
 }
 
 // TraceIDFromHTTPHeader 从 HTTP header 中提取 TraceID
 //
 // 格式样例
 // traceparent: 00-{trace-id}-{parent-id}-{trace-flags}
+// tracestate: vendor1=value1,vendor2=value2
 func TraceIDFromHTTPHeader(h http.Header) (TraceContext, bool) {
+	tc, ok := TraceContextFromTraceparent(h.Get(headerTraceParent))
+	if !ok {
+		return tc, false
+	}
+
+	// tracestate 允许拆分为多个 Header 按照出现顺序以逗号拼接
+	tc.State = strings.Join(h.Values(headerTraceState), ",")
+	return tc, true
+}
+
+// TraceContextFromTraceparent 解析 W3C traceparent 格式的字符串
+func TraceContextFromTraceparent(s string) (TraceContext, bool) {
 	var empty TraceContext
-	s := h.Get(headerTraceParent)
 	if s == "" {
 		return empty, false
 	}
@@ -32,9 +32,13 @@ func TestTraceIDFromHTTPHeader(t *testing.T) {
 		}
 	}
 
+	withState := genTc("0af7651916cd43dd8448eb211c80319c", "b7ad6b7169203331")
+	withState.State = "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7"
+
 	tests := []struct {
 		name        string
 		traceParent string
+		traceState  []string
 		tc          TraceContext
 	}{
 		{
@@ -42,6 +46,17 @@ func TestTraceIDFromHTTPHeader(t *testing.T) {
 			traceParent: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
 			tc:          genTc("0af7651916cd43dd8448eb211c80319c", "b7ad6b7169203331"),
 		},
+		{
+			name:        "with tracestate",
+			traceParent: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			traceState:  []string{"congo=t61rcWkgMzE", "rojo=00f067aa0ba902b7"},
+			tc:          withState,
+		},
+		{
+			name:       "tracestate only",
+			traceState: []string{"congo=t61rcWkgMzE"},
+			tc:         TraceContext{},
+		},
 		{
 			name:        "invalid traceid",
 			traceParent: "00-0af7651916cd43dd8448eb211c80319!-b7ad6b7169203331-01",
@@ -58,6 +73,9 @@ func TestTraceIDFromHTTPHeader(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			header := make(http.Header)
 			header.Set(headerTraceParent, tt.traceParent)
+			for _, s := range tt.traceState {
+				header.Add(headerTraceState, s)
+			}
 
 			got, _ := TraceIDFromHTTPHeader(header)
 			assert.Equal(t, tt.tc, got)
@@ -15,6 +15,7 @@ package tracestroage
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"go.opentelemetry.io/collector/pdata/ptrace"
@@ -25,6 +26,7 @@ import (
 type Storage struct {
 	ctx    context.Context
 	cancel context.CancelFunc
+	once   sync.Once
 
 	out      chan ptrace.Traces
 	in       chan ptrace.Span
@@ -32,32 +34,45 @@ type Storage struct {
 	interval time.Duration
 }
 
-func New(batch int, interval time.Duration) *Storage {
+// New 创建 Storage queueSize 为待打包 span 的队列长度 小于等于 0 时使用默认值
+func New(batch int, interval time.Duration, queueSize int) *Storage {
+	if queueSize <= 0 {
+		queueSize = common.Concurrency()
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	s := &Storage{
 		ctx:      ctx,
 		cancel:   cancel,
 		batch:    batch,
 		interval: interval,
-		in:       make(chan ptrace.Span, common.Concurrency()),
+		in:       make(chan ptrace.Span, queueSize),
 		out:      make(chan ptrace.Traces, 1),
 	}
 	go s.pack()
 	return s
 }
 
-func (s *Storage) Push(span ptrace.Span) {
+// TryPush 非阻塞写入 span 队列已满时返回 false
+func (s *Storage) TryPush(span ptrace.Span) bool {
 	select {
 	case <-s.ctx.Done():
-		return
+		return false
 	case s.in <- span:
+		return true
+	default:
+		return false
 	}
 }
 
+// Close 停止接收 span 队列中剩余的 span 打包输出后关闭 Pop 返回的 channel
+//
+// 调用方需持续读取 Pop 直至 channel 关闭
 func (s *Storage) Close() {
-	s.cancel()
+	s.once.Do(s.cancel)
 }
 
+// Pop 返回打包完成的 Traces Close 后剩余数据输出完毕即关闭
 func (s *Storage) Pop() <-chan ptrace.Traces {
 	return s.out
 }
@@ -77,6 +92,7 @@ func (s *Storage) sendOut(data []ptrace.Span) {
 		span := spans.AppendEmpty()
 		data[i].CopyTo(span)
 	}
+
 	s.out <- traces
 }
 
@@ -88,6 +104,7 @@ func (s *Storage) pack() {
 	for {
 		select {
 		case <-s.ctx.Done():
+			s.drain(data)
 			return
 
 		case span := <-s.in:
@@ -105,3 +122,23 @@ func (s *Storage) pack() {
 		}
 	}
 }
+
+// drain 将队列中剩余的 span 打包输出 随后关闭 out
+func (s *Storage) drain(data []ptrace.Span) {
+	defer close(s.out)
+	for {
+		select {
+		case span := <-s.in:
+			data = append(data, span)
+			if len(data) >= s.batch {
+				s.sendOut(data)
+				data = make([]ptrace.Span, 0, s.batch)
+			}
+		default:
+			if len(data) > 0 {
+				s.sendOut(data)
+			}
+			return
+		}
+	}
+}
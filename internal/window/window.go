@@ -0,0 +1,127 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Package window 按照时间窗口汇总 roundtrip 的处理器骨架
+//
+// 负责窗口的起止 并发保护以及输出事件的封装 各处理器仅需实现窗口内的统计逻辑
+// 窗口既会在新数据到达时结束 也会由 pipeline 定时调用 Flush 结束 没有流量期间同样能够输出
+package window
+
+import (
+	"sync"
+	"time"
+
+	"github.com/packetd/packetd/common"
+)
+
+// State 窗口内的统计状态
+type State interface {
+	// Flush 结束 [start, end) 窗口 返回窗口内的事件并重置统计状态
+	Flush(start, end time.Time) []any
+
+	// Reset 丢弃所有统计状态
+	Reset()
+}
+
+// Aggregator 窗口汇总骨架
+type Aggregator struct {
+	mut    sync.Mutex
+	window time.Duration
+	start  time.Time
+	state  State
+	now    func() time.Time
+}
+
+// New 创建并返回 Aggregator 实例
+func New(window time.Duration, state State) *Aggregator {
+	return &Aggregator{
+		window: window,
+		state:  state,
+		now:    time.Now,
+	}
+}
+
+// SetClock 替换时钟 仅用于测试
+func (a *Aggregator) SetClock(now func() time.Time) {
+	a.now = now
+}
+
+// Observe 在锁内调用 f 记录数据
+//
+// 当前窗口已经结束时先输出窗口事件 f 返回的即时事件追加在窗口事件之后
+func (a *Aggregator) Observe(f func(now time.Time) []any) *common.Record {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	now := a.now()
+	events := a.rotate(now)
+	events = append(events, f(now)...)
+	return newRecord(events)
+}
+
+// Flush 输出已经结束的窗口 force 为 true 时无论窗口是否结束均输出当前窗口
+func (a *Aggregator) Flush(force bool) *common.Record {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	now := a.now()
+	if force && !a.start.IsZero() {
+		events := a.state.Flush(a.start, now)
+		a.start = now
+		return newRecord(events)
+	}
+	return newRecord(a.rotate(now))
+}
+
+// Clean 丢弃当前窗口
+func (a *Aggregator) Clean() {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	a.start = time.Time{}
+	a.state.Reset()
+}
+
+func (a *Aggregator) rotate(now time.Time) []any {
+	if a.start.IsZero() {
+		a.start = now
+		return nil
+	}
+	if now.Sub(a.start) < a.window {
+		return nil
+	}
+	events := a.state.Flush(a.start, now)
+	a.start = now
+	return events
+}
+
+func newRecord(events []any) *common.Record {
+	if len(events) == 0 {
+		return nil
+	}
+	return &common.Record{
+		RecordType: common.RecordEvents,
+		Data:       &common.EventsData{Data: events},
+	}
+}
+
+// Events 将事件列表转换为 []any
+func Events[T any](lst []T) []any {
+	if len(lst) == 0 {
+		return nil
+	}
+	events := make([]any, 0, len(lst))
+	for _, event := range lst {
+		events = append(events, event)
+	}
+	return events
+}
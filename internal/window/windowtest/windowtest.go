@@ -0,0 +1,89 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Package windowtest 窗口汇总类处理器共用的测试工具
+package windowtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/processor"
+)
+
+// RoundTrip 测试用的 roundtrip
+type RoundTrip struct {
+	Protocol socket.L7Proto
+	Req      any
+	Rsp      any
+	Latency  time.Duration
+}
+
+func (rt RoundTrip) Proto() socket.L7Proto   { return rt.Protocol }
+func (rt RoundTrip) Request() any            { return rt.Req }
+func (rt RoundTrip) Response() any           { return rt.Rsp }
+func (rt RoundTrip) Duration() time.Duration { return rt.Latency }
+func (rt RoundTrip) Validate() bool          { return true }
+
+// NewRecord 返回 roundtrip 类型的 Record
+func NewRecord(proto socket.L7Proto, req, rsp any, latency time.Duration) *common.Record {
+	return common.NewRecord(common.RecordRoundTrips, RoundTrip{Protocol: proto, Req: req, Rsp: rsp, Latency: latency})
+}
+
+// Clock 手动推进的时钟
+type Clock struct {
+	Start time.Time
+	now   time.Time
+}
+
+// NewClock 创建并返回 Clock 实例
+func NewClock() *Clock {
+	start := time.Unix(1751356800, 0)
+	return &Clock{Start: start, now: start}
+}
+
+func (c *Clock) Now() time.Time {
+	return c.now
+}
+
+// Advance 推进时钟并返回推进后的时间
+func (c *Clock) Advance(d time.Duration) time.Time {
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+// Process 重复处理 n 次 r 返回首个非空的输出
+func Process(t *testing.T, p processor.Processor, r *common.Record, n int) *common.Record {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		ret, err := p.Process(r)
+		require.NoError(t, err)
+		if ret != nil {
+			return ret
+		}
+	}
+	return nil
+}
+
+// Events 返回输出的事件列表 r 为 nil 时返回 nil
+func Events(t *testing.T, r *common.Record) []any {
+	t.Helper()
+	if r == nil {
+		return nil
+	}
+	require.Equal(t, common.RecordEvents, r.RecordType)
+	return r.Data.(*common.EventsData).Data
+}
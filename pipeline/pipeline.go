@@ -47,14 +47,18 @@ func New(conf *confengine.Config) (*Pipeline, error) {
 	}, nil
 }
 
+// Range 依次交由各 pipeline 的 processor 处理
+//
+// 每个 pipeline 处理的是 src 的副本 processor 回填的 TraceID 等字段仅在同一 pipeline 内可见 也不会影响已导出的 src
 func (p *Pipeline) Range(src *common.Record, f func(dst *common.Record)) {
 	for i := 0; i < len(p.configs); i++ {
+		record := *src
 		for _, name := range p.configs[i].Processors {
 			ps, ok := p.psmgr.Get(name)
 			if !ok {
 				continue
 			}
-			r, err := ps.Process(src)
+			r, err := ps.Process(&record)
 			if err != nil {
 				continue
 			}
@@ -65,6 +69,27 @@ func (p *Pipeline) Range(src *common.Record, f func(dst *common.Record)) {
 	}
 }
 
+// Flush 输出各 processor.Flusher 已经结束的窗口 force 为 true 时输出当前窗口
+func (p *Pipeline) Flush(force bool, f func(dst *common.Record)) {
+	for _, ps := range p.psmgr.Processors() {
+		flusher, ok := ps.(processor.Flusher)
+		if !ok {
+			continue
+		}
+		if r := flusher.Flush(force); r != nil {
+			f(r)
+		}
+	}
+}
+
+// Clean 输出各 Processor 缓存的数据后清理资源
+func (p *Pipeline) Clean(f func(dst *common.Record)) {
+	p.Flush(true, f)
+	for _, ps := range p.psmgr.Processors() {
+		ps.Clean()
+	}
+}
+
 func loadPipeline(conf *confengine.Config) (Configs, error) {
 	var configs Configs
 	if err := conf.UnpackChild("pipeline", &configs); err != nil {
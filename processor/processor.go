@@ -14,6 +14,8 @@
 package processor
 
 import (
+	"slices"
+
 	"github.com/pkg/errors"
 
 	"github.com/packetd/packetd/common"
@@ -41,6 +43,14 @@ type Processor interface {
 	Clean()
 }
 
+// Flusher 按照时间窗口输出数据的 Processor 可选实现
+//
+// 窗口可能在没有新数据到达时结束 由 pipeline 定时调用 Flush 输出
+type Flusher interface {
+	// Flush 输出已经结束的窗口 force 为 true 时输出当前窗口 退出前调用
+	Flush(force bool) *common.Record
+}
+
 type CreateFunc func(conf map[string]any) (Processor, error)
 
 var processorFactory = map[string]CreateFunc{}
@@ -49,6 +59,16 @@ func Register(name string, f CreateFunc) {
 	processorFactory[name] = f
 }
 
+// Names 返回已注册的处理器名称 按名称排序
+func Names() []string {
+	lst := make([]string, 0, len(processorFactory))
+	for name := range processorFactory {
+		lst = append(lst, name)
+	}
+	slices.Sort(lst)
+	return lst
+}
+
 func Get(name string) (CreateFunc, error) {
 	f, ok := processorFactory[name]
 	if !ok {
@@ -94,6 +114,11 @@ func NewManager(conf *confengine.Config) (*Manager, error) {
 	}, nil
 }
 
+// Processors 返回所有 Processor
+func (mgr *Manager) Processors() []Processor {
+	return mgr.processors
+}
+
 func (mgr *Manager) Get(name string) (Processor, bool) {
 	for _, p := range mgr.processors {
 		if p.Name() == name {
@@ -0,0 +1,93 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstoclientmetrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/mapstructure"
+	"github.com/packetd/packetd/processor"
+)
+
+const Name = "roundtripstoclientmetrics"
+
+const (
+	defaultTopN   = 100
+	defaultWindow = time.Minute
+)
+
+func init() {
+	processor.Register(Name, New)
+}
+
+type Config struct {
+	// TopN 独立上报的客户端数量上限 其余客户端汇总为 other
+	TopN int `config:"topN" mapstructure:"topN"`
+
+	// Window 排名以及并发度统计窗口
+	Window time.Duration `config:"window" mapstructure:"window"`
+}
+
+// Factory 按照客户端 IP 聚合 RoundTrip 生成请求量 错误量以及并发度指标
+//
+// 客户端 IP 基数不可控 因此仅对请求量排名前 N 的客户端单独上报 避免指标维度膨胀
+type Factory struct {
+	mut     sync.Mutex
+	tracker *tracker
+	now     func() time.Time
+}
+
+func New(conf map[string]any) (processor.Processor, error) {
+	cfg := &Config{}
+	if err := mapstructure.Decode(conf, cfg); err != nil {
+		return nil, err
+	}
+	if cfg.TopN <= 0 {
+		cfg.TopN = defaultTopN
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultWindow
+	}
+
+	return &Factory{
+		tracker: newTracker(cfg.TopN, cfg.Window),
+		now:     time.Now,
+	}, nil
+}
+
+func (f *Factory) Name() string {
+	return Name
+}
+
+func (f *Factory) Process(record *common.Record) (*common.Record, error) {
+	rt := record.Data.(socket.RoundTrip)
+	client, failed, ok := inspect(rt)
+	if !ok {
+		return nil, nil
+	}
+
+	f.mut.Lock()
+	data := f.tracker.observe(f.now(), clientKey{proto: rt.Proto(), address: client}, rt.Duration(), failed)
+	f.mut.Unlock()
+
+	return &common.Record{
+		RecordType: common.RecordMetrics,
+		Data:       &common.MetricsData{Data: data},
+	}, nil
+}
+
+func (f *Factory) Clean() {}
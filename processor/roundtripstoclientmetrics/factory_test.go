@@ -0,0 +1,122 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstoclientmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/metricstorage"
+	"github.com/packetd/packetd/protocol/predis"
+)
+
+type redisRoundTrip struct {
+	req *predis.Request
+	rsp *predis.Response
+}
+
+func (rt redisRoundTrip) Proto() socket.L7Proto   { return socket.L7ProtoRedis }
+func (rt redisRoundTrip) Request() any            { return rt.req }
+func (rt redisRoundTrip) Response() any           { return rt.rsp }
+func (rt redisRoundTrip) Duration() time.Duration { return rt.rsp.Time.Sub(rt.req.Time) }
+func (rt redisRoundTrip) Validate() bool          { return true }
+
+func newRecord(client string, duration time.Duration, dataType predis.DataType) *common.Record {
+	t0 := time.Unix(1751356800, 0)
+	return common.NewRecord(common.RecordRoundTrips, redisRoundTrip{
+		req: &predis.Request{Host: client, Time: t0},
+		rsp: &predis.Response{DataType: string(dataType), Time: t0.Add(duration)},
+	})
+}
+
+// findMetric 返回指定名称以及客户端的指标
+func findMetric(data []metricstorage.ConstMetric, name, client string) (metricstorage.ConstMetric, bool) {
+	for _, cm := range data {
+		if cm.Name == name && cm.Labels[1].Value == client {
+			return cm, true
+		}
+	}
+	return metricstorage.ConstMetric{}, false
+}
+
+func TestFactoryProcess(t *testing.T) {
+	p, err := New(map[string]any{"topN": 2, "window": "10s"})
+	require.NoError(t, err)
+
+	f := p.(*Factory)
+	now := time.Unix(1751356800, 0)
+	f.now = func() time.Time { return now }
+
+	process := func(client string, duration time.Duration, dataType predis.DataType) []metricstorage.ConstMetric {
+		r, err := f.Process(newRecord(client, duration, dataType))
+		require.NoError(t, err)
+		return r.Data.(*common.MetricsData).Data
+	}
+
+	// 首个窗口内按照到达顺序进入排名
+	data := process("10.0.0.1", time.Second, predis.SimpleStrings)
+	cm, ok := findMetric(data, clientRequestsTotal, "10.0.0.1")
+	assert.True(t, ok)
+	assert.Equal(t, "redis", cm.Labels[0].Value)
+
+	cm, ok = findMetric(data, clientErrorsTotal, "10.0.0.1")
+	assert.True(t, ok)
+	assert.Zero(t, cm.Value)
+
+	process("10.0.0.2", time.Second, predis.Errors)
+	data = process("10.0.0.3", time.Second, predis.Errors)
+	_, ok = findMetric(data, clientRequestsTotal, otherClients)
+	assert.True(t, ok)
+	cm, ok = findMetric(data, clientErrorsTotal, otherClients)
+	assert.True(t, ok)
+	assert.Equal(t, float64(1), cm.Value)
+
+	for i := 0; i < 4; i++ {
+		process("10.0.0.3", 5*time.Second, predis.SimpleStrings)
+	}
+
+	// 窗口结束后重新排名 并输出排名内客户端的并发度
+	now = now.Add(10 * time.Second)
+	data = process("10.0.0.1", time.Second, predis.SimpleStrings)
+
+	cm, ok = findMetric(data, clientConcurrency, "10.0.0.3")
+	assert.True(t, ok)
+	assert.Equal(t, 2.1, cm.Value) // (1s + 4*5s) / 10s
+
+	cm, ok = findMetric(data, clientConcurrency, "10.0.0.1")
+	assert.True(t, ok)
+	assert.Equal(t, 0.1, cm.Value)
+
+	_, ok = findMetric(data, clientConcurrency, "10.0.0.2")
+	assert.False(t, ok)
+
+	data = process("10.0.0.2", time.Second, predis.SimpleStrings)
+	_, ok = findMetric(data, clientRequestsTotal, otherClients)
+	assert.True(t, ok)
+}
+
+func TestTrackerCandidates(t *testing.T) {
+	tr := newTracker(1, time.Second)
+	now := time.Unix(1751356800, 0)
+	for _, client := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		tr.observe(now, clientKey{proto: socket.L7ProtoHTTP, address: client}, 0, false)
+	}
+	assert.Len(t, tr.curr, tr.maxCandidates)
+	assert.Len(t, tr.top, 1)
+}
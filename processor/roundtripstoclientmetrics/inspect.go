@@ -0,0 +1,93 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstoclientmetrics
+
+import (
+	"strconv"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol/pamqp"
+	"github.com/packetd/packetd/protocol/pdns"
+	"github.com/packetd/packetd/protocol/pgrpc"
+	"github.com/packetd/packetd/protocol/phttp"
+	"github.com/packetd/packetd/protocol/phttp2"
+	"github.com/packetd/packetd/protocol/pkafka"
+	"github.com/packetd/packetd/protocol/pmongodb"
+	"github.com/packetd/packetd/protocol/pmysql"
+	"github.com/packetd/packetd/protocol/pntp"
+	"github.com/packetd/packetd/protocol/ppostgresql"
+	"github.com/packetd/packetd/protocol/predis"
+	"github.com/packetd/packetd/protocol/pudprpc"
+)
+
+// inspect 返回 RoundTrip 的客户端地址以及请求是否失败
+//
+// 失败的判定依据各协议的响应状态 HTTP 类协议以 4xx/5xx 作为失败 便于发现鉴权失败或者被限流的客户端
+// 单向事件没有响应 一律视为成功
+func inspect(rt socket.RoundTrip) (string, bool, bool) {
+	switch req := rt.Request().(type) {
+	case *phttp.Request:
+		rsp := rt.Response().(*phttp.Response)
+		return req.Host, rsp.StatusCode >= 400, true
+
+	case *phttp2.Request:
+		rsp := rt.Response().(*phttp2.Response)
+		code, _ := strconv.Atoi(rsp.Status)
+		return req.Host, code >= 400, true
+
+	case *pgrpc.Request:
+		if req.Progress != nil {
+			return "", false, false // 增量消息统计并非一次完整的调用
+		}
+		rsp := rt.Response().(*pgrpc.Response)
+		status := rsp.Metadata.Get("grpc-status")
+		return req.Host, rsp.Status != "200" || (status != "" && status != "0"), true
+
+	case *pmysql.Request:
+		_, failed := rt.Response().(*pmysql.Response).Packet.(*pmysql.ErrorPacket)
+		return req.Host, failed, true
+
+	case *ppostgresql.Request:
+		_, failed := rt.Response().(*ppostgresql.Response).Packet.(*ppostgresql.ErrorPacket)
+		return req.Host, failed, true
+
+	case *predis.Request:
+		rsp := rt.Response().(*predis.Response)
+		return req.Host, rsp != nil && rsp.DataType == string(predis.Errors), true
+
+	case *pkafka.Request:
+		rsp := rt.Response().(*pkafka.Response)
+		return req.Host, rsp != nil && rsp.ErrorCode != "" && rsp.ErrorCode != "NoError", true
+
+	case *pmongodb.Request:
+		rsp := rt.Response().(*pmongodb.Response)
+		return req.Host, rsp.Code != 0, true
+
+	case *pdns.Request:
+		rsp := rt.Response().(*pdns.Response)
+		return req.Host, rsp.Message.Header.Status != "Success", true
+
+	case *pamqp.Request:
+		rsp := rt.Response().(*pamqp.Response)
+		return req.Host, rsp != nil && rsp.ErrCode != "" && rsp.ErrCode != "OK", true
+
+	case *pntp.Request:
+		rsp := rt.Response().(*pntp.Response)
+		return req.Host, rsp.KissCode != "", true
+
+	case *pudprpc.Request: // 协议内容未知 无法判断是否失败
+		return req.Host, false, true
+	}
+	return "", false, false
+}
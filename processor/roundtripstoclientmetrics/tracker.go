@@ -0,0 +1,151 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstoclientmetrics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/labels"
+	"github.com/packetd/packetd/internal/metricstorage"
+)
+
+const (
+	clientRequestsTotal = "client_requests_total"
+	clientErrorsTotal   = "client_errors_total"
+	clientConcurrency   = "client_concurrency"
+
+	// otherClients 未进入排名的客户端统一使用的维度值
+	otherClients = "other"
+
+	// candidatesFactor 每个窗口参与排名的候选客户端数量为 TopN 的倍数
+	candidatesFactor = 8
+)
+
+type clientKey struct {
+	proto   socket.L7Proto
+	address string
+}
+
+func (k clientKey) labels() labels.Labels {
+	return labels.Labels{
+		{Name: "proto", Value: string(k.proto)},
+		{Name: "client_address", Value: k.address},
+	}
+}
+
+type clientStat struct {
+	requests int
+	busy     time.Duration // 窗口内请求耗时累计
+}
+
+// tracker 维护请求量排名前 N 的客户端
+//
+// 每个窗口结束时按照窗口内的请求量重新排名 候选客户端数量有上限 超出后新出现的客户端不再参与本轮排名
+// 首个窗口内尚未有排名结果 先到达的客户端直接进入排名直至名额用尽
+//
+// 并发度按照 Little's Law 计算 即窗口内请求耗时之和除以窗口时长 代表平均同时处理中的请求数
+type tracker struct {
+	topN          int
+	maxCandidates int
+	window        time.Duration
+	rotated       time.Time
+	top           map[clientKey]struct{}
+	curr          map[clientKey]*clientStat
+}
+
+func newTracker(topN int, window time.Duration) *tracker {
+	return &tracker{
+		topN:          topN,
+		maxCandidates: topN * candidatesFactor,
+		window:        window,
+		top:           make(map[clientKey]struct{}),
+		curr:          make(map[clientKey]*clientStat),
+	}
+}
+
+func (t *tracker) observe(now time.Time, key clientKey, duration time.Duration, failed bool) []metricstorage.ConstMetric {
+	var data []metricstorage.ConstMetric
+	if t.rotated.IsZero() {
+		t.rotated = now
+	}
+	if elapsed := now.Sub(t.rotated); elapsed >= t.window {
+		data = t.rotate(elapsed)
+		t.rotated = now
+	}
+
+	stat, ok := t.curr[key]
+	if !ok {
+		if len(t.curr) < t.maxCandidates {
+			stat = &clientStat{}
+			t.curr[key] = stat
+		}
+	}
+	if stat != nil {
+		stat.requests++
+		stat.busy += duration
+	}
+
+	if _, ok := t.top[key]; !ok {
+		if len(t.top) < t.topN {
+			t.top[key] = struct{}{}
+		} else {
+			key.address = otherClients
+		}
+	}
+
+	var errors float64
+	if failed {
+		errors = 1
+	}
+	lbs := key.labels()
+	return append(data,
+		metricstorage.NewCounterConstMetric(clientRequestsTotal, 1, lbs),
+		metricstorage.NewCounterConstMetric(clientErrorsTotal, errors, lbs),
+	)
+}
+
+// rotate 结束当前窗口 重新排名并返回排名内客户端的并发度
+func (t *tracker) rotate(elapsed time.Duration) []metricstorage.ConstMetric {
+	keys := make([]clientKey, 0, len(t.curr))
+	for key := range t.curr {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := t.curr[keys[i]], t.curr[keys[j]]
+		if a.requests != b.requests {
+			return a.requests > b.requests
+		}
+		if keys[i].proto != keys[j].proto {
+			return keys[i].proto < keys[j].proto
+		}
+		return keys[i].address < keys[j].address
+	})
+
+	data := make([]metricstorage.ConstMetric, 0, min(len(keys), t.topN))
+	top := make(map[clientKey]struct{}, t.topN)
+	for i, key := range keys {
+		if i >= t.topN {
+			break
+		}
+		top[key] = struct{}{}
+		concurrency := t.curr[key].busy.Seconds() / elapsed.Seconds()
+		data = append(data, metricstorage.NewGaugeConstMetric(clientConcurrency, concurrency, key.labels()))
+	}
+
+	t.top = top
+	t.curr = make(map[clientKey]*clientStat, len(t.curr))
+	return data
+}
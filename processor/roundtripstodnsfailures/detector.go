@@ -0,0 +1,167 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstodnsfailures
+
+import (
+	"sort"
+	"time"
+
+	"github.com/packetd/packetd/internal/window"
+)
+
+const (
+	// eventName 突增事件名称 便于与其他结构化事件区分
+	eventName = "dns_failure_burst"
+
+	// baselineAlpha 基线失败率的指数移动平均系数
+	baselineAlpha = 0.3
+
+	statusNXDomain = "NameError"
+	statusServFail = "ServerFailure"
+
+	scopeResolver = "resolver"
+	scopeDomain   = "domain"
+)
+
+// failureStatuses 参与检测的响应状态 取值同 pdns Header.Status
+var failureStatuses = []string{statusNXDomain, statusServFail}
+
+type scopeKey struct {
+	scope string
+	key   string
+}
+
+type counter struct {
+	total    int
+	failures map[string]int // status -> 失败次数
+}
+
+// Burst 单个窗口内某个解析服务器或者域名的失败率突增
+type Burst struct {
+	Event        string
+	Start        time.Time
+	End          time.Time
+	Scope        string // resolver / domain
+	Key          string // 解析服务器地址 host:port 或者域名
+	Status       string // NameError（NXDOMAIN）/ ServerFailure（SERVFAIL）
+	Total        int
+	Failures     int
+	Rate         float64
+	BaselineRate float64
+}
+
+// detector 按照窗口统计失败率并与历史基线比较
+//
+// 基线为此前各窗口失败率的指数移动平均 首个窗口仅用于建立基线 不做检测
+// 此后新出现的对象基线为 0 因此新域名的大量 NXDOMAIN 同样会被检测到
+type detector struct {
+	conf      Config
+	warmed    bool
+	curr      map[scopeKey]*counter
+	baselines map[scopeKey]map[string]float64
+}
+
+func newDetector(conf Config) *detector {
+	return &detector{
+		conf:      conf,
+		curr:      make(map[scopeKey]*counter),
+		baselines: make(map[scopeKey]map[string]float64),
+	}
+}
+
+// observe 记录一次 DNS 响应
+func (d *detector) observe(resolver, domain, status string) {
+	d.count(scopeKey{scope: scopeResolver, key: resolver}, status)
+	d.count(scopeKey{scope: scopeDomain, key: domain}, status)
+}
+
+func (d *detector) count(key scopeKey, status string) {
+	c, ok := d.curr[key]
+	if !ok {
+		if len(d.curr) >= d.conf.MaxKeys {
+			return
+		}
+		c = &counter{failures: make(map[string]int)}
+		d.curr[key] = c
+	}
+
+	c.total++
+	if status == statusNXDomain || status == statusServFail {
+		c.failures[status]++
+	}
+}
+
+// Flush 结束当前窗口 更新基线并按照 Scope / Key / Status 排序返回突增事件
+func (d *detector) Flush(start, end time.Time) []any {
+	return window.Events(d.flush(start, end))
+}
+
+func (d *detector) Reset() {
+	d.warmed = false
+	d.curr = make(map[scopeKey]*counter)
+	d.baselines = make(map[scopeKey]map[string]float64)
+}
+
+func (d *detector) flush(start, end time.Time) []*Burst {
+	var bursts []*Burst
+	for key, c := range d.curr {
+		baseline, ok := d.baselines[key]
+		if !ok {
+			if len(d.baselines) >= d.conf.MaxKeys {
+				d.baselines = make(map[scopeKey]map[string]float64)
+			}
+			baseline = make(map[string]float64)
+			d.baselines[key] = baseline
+		}
+
+		for _, status := range failureStatuses {
+			rate := float64(c.failures[status]) / float64(c.total)
+			prev := baseline[status]
+			if d.warmed && c.total >= d.conf.MinRequests && rate >= d.conf.MinRate && rate >= prev*d.conf.Threshold {
+				bursts = append(bursts, &Burst{
+					Event:        eventName,
+					Start:        start,
+					End:          end,
+					Scope:        key.scope,
+					Key:          key.key,
+					Status:       status,
+					Total:        c.total,
+					Failures:     c.failures[status],
+					Rate:         rate,
+					BaselineRate: prev,
+				})
+			}
+
+			if !ok {
+				baseline[status] = rate
+				continue
+			}
+			baseline[status] = prev + baselineAlpha*(rate-prev)
+		}
+	}
+
+	sort.Slice(bursts, func(i, j int) bool {
+		if bursts[i].Scope != bursts[j].Scope {
+			return bursts[i].Scope < bursts[j].Scope
+		}
+		if bursts[i].Key != bursts[j].Key {
+			return bursts[i].Key < bursts[j].Key
+		}
+		return bursts[i].Status < bursts[j].Status
+	})
+
+	d.warmed = true
+	d.curr = make(map[scopeKey]*counter, len(d.curr))
+	return bursts
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstodnsfailures
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/mapstructure"
+	"github.com/packetd/packetd/internal/window"
+	"github.com/packetd/packetd/processor"
+	"github.com/packetd/packetd/protocol/pdns"
+)
+
+const Name = "roundtripstodnsfailures"
+
+const (
+	defaultWindow       = time.Minute
+	defaultMinRequests  = 20
+	defaultMinRate      = 0.1
+	defaultThreshold    = 3.0
+	defaultDomainLabels = 2
+	defaultMaxKeys      = 1000
+)
+
+func init() {
+	processor.Register(Name, New)
+}
+
+type Config struct {
+	// Window 检测窗口 每个窗口结束时计算各解析服务器以及域名的失败率
+	Window time.Duration `config:"window" mapstructure:"window"`
+
+	// MinRequests 窗口内请求数不低于该值时才参与检测 避免低流量下的抖动
+	MinRequests int `config:"minRequests" mapstructure:"minRequests"`
+
+	// MinRate 失败率不低于该值时才视为突增
+	MinRate float64 `config:"minRate" mapstructure:"minRate"`
+
+	// Threshold 失败率达到基线的倍数时视为突增
+	Threshold float64 `config:"threshold" mapstructure:"threshold"`
+
+	// DomainLabels 按照域名统计时保留的末尾 label 数量 如 2 时 a.b.example.com 归入 example.com
+	DomainLabels int `config:"domainLabels" mapstructure:"domainLabels"`
+
+	// MaxKeys 单独统计的解析服务器以及域名数量上限 超出后不再统计新出现的对象
+	MaxKeys int `config:"maxKeys" mapstructure:"maxKeys"`
+}
+
+// Factory 跟踪 DNS NXDOMAIN / SERVFAIL 的失败率 在失败率相对基线突增时输出结构化事件
+//
+// 分别以解析服务器（host:port）以及域名两个维度统计 DNS 配置错误时无需再人工翻查日志
+type Factory struct {
+	*window.Aggregator
+	detector     *detector
+	domainLabels int
+}
+
+func New(conf map[string]any) (processor.Processor, error) {
+	cfg := &Config{}
+	if err := mapstructure.Decode(conf, cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultWindow
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = defaultMinRequests
+	}
+	if cfg.MinRate <= 0 {
+		cfg.MinRate = defaultMinRate
+	}
+	if cfg.Threshold <= 1 {
+		cfg.Threshold = defaultThreshold
+	}
+	if cfg.DomainLabels <= 0 {
+		cfg.DomainLabels = defaultDomainLabels
+	}
+	if cfg.MaxKeys <= 0 {
+		cfg.MaxKeys = defaultMaxKeys
+	}
+
+	d := newDetector(*cfg)
+	return &Factory{
+		Aggregator:   window.New(cfg.Window, d),
+		detector:     d,
+		domainLabels: cfg.DomainLabels,
+	}, nil
+}
+
+func (f *Factory) Name() string {
+	return Name
+}
+
+// Process 记录 DNS 响应状态 窗口结束时返回上一个窗口检测到的突增事件
+func (f *Factory) Process(record *common.Record) (*common.Record, error) {
+	rt, ok := record.Data.(socket.RoundTrip)
+	if !ok || rt.Proto() != socket.L7ProtoDNS {
+		return nil, nil
+	}
+	req, ok := rt.Request().(*pdns.Request)
+	if !ok {
+		return nil, nil
+	}
+	rsp, ok := rt.Response().(*pdns.Response)
+	if !ok {
+		return nil, nil
+	}
+
+	resolver := net.JoinHostPort(rsp.Host, strconv.Itoa(int(rsp.Port)))
+	domain := pdns.Zone(req.Message.QuestionSec.Name, f.domainLabels)
+
+	return f.Observe(func(time.Time) []any {
+		f.detector.observe(resolver, domain, rsp.Message.Header.Status)
+		return nil
+	}), nil
+}
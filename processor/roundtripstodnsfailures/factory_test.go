@@ -0,0 +1,116 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstodnsfailures
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/window/windowtest"
+	"github.com/packetd/packetd/protocol/pdns"
+)
+
+func newRecord(resolver, name, status string) *common.Record {
+	return windowtest.NewRecord(socket.L7ProtoDNS,
+		&pdns.Request{Message: pdns.Message{QuestionSec: pdns.Question{Name: name}}},
+		&pdns.Response{Host: resolver, Port: 53, Message: pdns.Message{Header: pdns.Header{Status: status}}},
+		0,
+	)
+}
+
+func TestFactoryProcess(t *testing.T) {
+	p, err := New(map[string]any{"window": "10s", "minRequests": 10})
+	require.NoError(t, err)
+
+	f := p.(*Factory)
+	clock := windowtest.NewClock()
+	start := clock.Start
+	f.SetClock(clock.Now)
+
+	process := func(n int, resolver, name, status string) *common.Record {
+		return windowtest.Process(t, f, newRecord(resolver, name, status), n)
+	}
+
+	// 首个窗口仅建立基线 resolver 常态下有 5% 的 NXDOMAIN
+	assert.Nil(t, process(19, "10.0.0.53", "api.example.com.", "Success"))
+	assert.Nil(t, process(1, "10.0.0.53", "typo.example.org.", "NameError"))
+
+	// 第二个窗口 search 域拼接错误导致大量 NXDOMAIN
+	clock.Advance(10 * time.Second)
+	assert.Nil(t, process(10, "10.0.0.53", "api.example.com.", "Success"))
+	assert.Nil(t, process(10, "10.0.0.53", "api.example.com.svc.cluster.local.", "NameError"))
+	// 10.0.0.54 请求量不足 minRequests 不参与检测 但 example.com 维度的 SERVFAIL 同样突增
+	assert.Nil(t, process(5, "10.0.0.54", "db.example.com.", "ServerFailure"))
+
+	now := clock.Advance(10 * time.Second)
+	assert.Equal(t, []any{
+		&Burst{
+			Event:    eventName,
+			Start:    start.Add(10 * time.Second),
+			End:      now,
+			Scope:    scopeDomain,
+			Key:      "cluster.local",
+			Status:   statusNXDomain,
+			Total:    10,
+			Failures: 10,
+			Rate:     1,
+		},
+		&Burst{
+			Event:    eventName,
+			Start:    start.Add(10 * time.Second),
+			End:      now,
+			Scope:    scopeDomain,
+			Key:      "example.com",
+			Status:   statusServFail,
+			Total:    15,
+			Failures: 5,
+			Rate:     float64(5) / 15,
+		},
+		&Burst{
+			Event:        eventName,
+			Start:        start.Add(10 * time.Second),
+			End:          now,
+			Scope:        scopeResolver,
+			Key:          "10.0.0.53:53",
+			Status:       statusNXDomain,
+			Total:        20,
+			Failures:     10,
+			Rate:         0.5,
+			BaselineRate: 0.05,
+		},
+	}, windowtest.Events(t, process(1, "10.0.0.53", "api.example.com.", "Success")))
+}
+
+func TestDetectorSteadyFailures(t *testing.T) {
+	d := newDetector(Config{Window: time.Minute, MinRequests: 10, MinRate: 0.1, Threshold: 3, MaxKeys: 100})
+	now := time.Unix(1751356800, 0)
+
+	// 持续存在的失败率不会重复告警
+	for w := 0; w < 5; w++ {
+		for i := 0; i < 20; i++ {
+			status := "Success"
+			if i%2 == 0 {
+				status = statusServFail
+			}
+			d.observe("10.0.0.53:53", "example.com", status)
+		}
+		assert.Empty(t, d.flush(now, now.Add(time.Minute)))
+		now = now.Add(time.Minute)
+	}
+}
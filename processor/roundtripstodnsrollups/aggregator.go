@@ -0,0 +1,154 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstodnsrollups
+
+import (
+	"sort"
+	"time"
+
+	"github.com/packetd/packetd/internal/window"
+)
+
+const (
+	// eventName 汇总事件名称 便于与其他结构化事件区分
+	eventName = "dns_rollup"
+
+	// otherValue 超出上限的组合统一使用的 Zone
+	otherValue = "other"
+
+	// maxClients 单个组合单个窗口内单独计数的客户端数量上限 超出后新出现的客户端仅计入 Count
+	maxClients = 1024
+)
+
+type rollupKey struct {
+	zone  string
+	qtype string
+	rcode string
+}
+
+// Talker 查询量最高的客户端
+type Talker struct {
+	Client string
+	Count  int
+}
+
+// Rollup 单个窗口内某个 zone/qtype/rcode 组合的查询量
+type Rollup struct {
+	Event   string
+	Start   time.Time
+	End     time.Time
+	Zone    string
+	QType   string
+	RCode   string
+	Count   int
+	Talkers []Talker `json:",omitempty"`
+
+	clients map[string]int
+}
+
+// aggregator 汇总窗口内的 DNS 查询
+//
+// 组合数量以及单个组合的客户端数量均有上限 避免随机子域名或者扫描流量导致内存膨胀
+type aggregator struct {
+	conf Config
+	curr map[rollupKey]*Rollup
+}
+
+func newAggregator(conf Config) *aggregator {
+	return &aggregator{
+		conf: conf,
+		curr: make(map[rollupKey]*Rollup),
+	}
+}
+
+// observe 记录一次查询
+func (a *aggregator) observe(key rollupKey, client string) {
+	rollup, ok := a.curr[key]
+	if !ok {
+		if len(a.curr) >= a.conf.MaxKeys {
+			key.zone = otherValue
+		}
+		if rollup, ok = a.curr[key]; !ok {
+			rollup = &Rollup{
+				Event:   eventName,
+				Zone:    key.zone,
+				QType:   key.qtype,
+				RCode:   key.rcode,
+				clients: make(map[string]int),
+			}
+			a.curr[key] = rollup
+		}
+	}
+
+	rollup.Count++
+	if a.conf.TopTalkers <= 0 {
+		return
+	}
+	if _, ok := rollup.clients[client]; ok || len(rollup.clients) < maxClients {
+		rollup.clients[client]++
+	}
+}
+
+// topTalkers 按照查询量降序返回前 n 个客户端 查询量相同时按照地址排序
+func topTalkers(clients map[string]int, n int) []Talker {
+	talkers := make([]Talker, 0, len(clients))
+	for client, count := range clients {
+		talkers = append(talkers, Talker{Client: client, Count: count})
+	}
+	sort.Slice(talkers, func(i, j int) bool {
+		if talkers[i].Count != talkers[j].Count {
+			return talkers[i].Count > talkers[j].Count
+		}
+		return talkers[i].Client < talkers[j].Client
+	})
+	if len(talkers) > n {
+		talkers = talkers[:n]
+	}
+	return talkers
+}
+
+// Flush 结束当前窗口 按照 zone/qtype/rcode 排序返回汇总结果
+func (a *aggregator) Flush(start, end time.Time) []any {
+	return window.Events(a.flush(start, end))
+}
+
+func (a *aggregator) Reset() {
+	a.curr = make(map[rollupKey]*Rollup)
+}
+
+func (a *aggregator) flush(start, end time.Time) []*Rollup {
+	rollups := make([]*Rollup, 0, len(a.curr))
+	for _, rollup := range a.curr {
+		rollup.Start = start
+		rollup.End = end
+		if a.conf.TopTalkers > 0 {
+			rollup.Talkers = topTalkers(rollup.clients, a.conf.TopTalkers)
+		}
+		rollup.clients = nil
+		rollups = append(rollups, rollup)
+	}
+	sort.Slice(rollups, func(i, j int) bool {
+		a, b := rollups[i], rollups[j]
+		if a.Zone != b.Zone {
+			return a.Zone < b.Zone
+		}
+		if a.QType != b.QType {
+			return a.QType < b.QType
+		}
+		return a.RCode < b.RCode
+	})
+
+	a.curr = make(map[rollupKey]*Rollup, len(a.curr))
+	return rollups
+}
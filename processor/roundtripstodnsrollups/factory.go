@@ -0,0 +1,118 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstodnsrollups
+
+import (
+	"time"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/mapstructure"
+	"github.com/packetd/packetd/internal/window"
+	"github.com/packetd/packetd/processor"
+	"github.com/packetd/packetd/protocol/pdns"
+)
+
+const Name = "roundtripstodnsrollups"
+
+const (
+	defaultWindow     = time.Minute
+	defaultZoneLabels = 2
+	defaultTopTalkers = 5
+	defaultMaxKeys    = 1000
+)
+
+func init() {
+	processor.Register(Name, New)
+}
+
+type Config struct {
+	// Window 汇总窗口 每个窗口结束时按照 zone/qtype/rcode 输出一次查询量
+	Window time.Duration `config:"window" mapstructure:"window"`
+
+	// ZoneLabels 归入同一 zone 时保留的末尾 label 数量 如 2 时 a.b.example.com 归入 example.com
+	ZoneLabels int `config:"zoneLabels" mapstructure:"zoneLabels"`
+
+	// TopTalkers 每条汇总附带的查询量最高的客户端数量 为负数时不附带
+	TopTalkers int `config:"topTalkers" mapstructure:"topTalkers"`
+
+	// MaxKeys 单个窗口内单独统计的 zone/qtype/rcode 组合数量上限 其余组合的 Zone 为 other
+	MaxKeys int `config:"maxKeys" mapstructure:"maxKeys"`
+}
+
+// Factory 按照时间窗口汇总 DNS 查询 以结构化事件的形式输出
+//
+// DNS 的报文量往往超过其余流量之和 逐条导出的成本很高 汇总结果的体积只与 zone 的数量相关
+type Factory struct {
+	*window.Aggregator
+	aggregator *aggregator
+	zoneLabels int
+}
+
+func New(conf map[string]any) (processor.Processor, error) {
+	cfg := &Config{}
+	if err := mapstructure.Decode(conf, cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultWindow
+	}
+	if cfg.ZoneLabels <= 0 {
+		cfg.ZoneLabels = defaultZoneLabels
+	}
+	if cfg.TopTalkers == 0 {
+		cfg.TopTalkers = defaultTopTalkers
+	}
+	if cfg.MaxKeys <= 0 {
+		cfg.MaxKeys = defaultMaxKeys
+	}
+
+	a := newAggregator(*cfg)
+	return &Factory{
+		Aggregator: window.New(cfg.Window, a),
+		aggregator: a,
+		zoneLabels: cfg.ZoneLabels,
+	}, nil
+}
+
+func (f *Factory) Name() string {
+	return Name
+}
+
+// Process 记录 DNS 查询 窗口结束时返回上一个窗口的汇总事件
+func (f *Factory) Process(record *common.Record) (*common.Record, error) {
+	rt, ok := record.Data.(socket.RoundTrip)
+	if !ok || rt.Proto() != socket.L7ProtoDNS {
+		return nil, nil
+	}
+	req, ok := rt.Request().(*pdns.Request)
+	if !ok {
+		return nil, nil
+	}
+	rsp, ok := rt.Response().(*pdns.Response)
+	if !ok {
+		return nil, nil
+	}
+
+	key := rollupKey{
+		zone:  pdns.Zone(req.Message.QuestionSec.Name, f.zoneLabels),
+		qtype: req.Message.QuestionSec.Type,
+		rcode: rsp.Message.Header.Status,
+	}
+
+	return f.Observe(func(time.Time) []any {
+		f.aggregator.observe(key, req.Host)
+		return nil
+	}), nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstodnsrollups
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/window/windowtest"
+	"github.com/packetd/packetd/protocol/pdns"
+)
+
+func newRecord(client, name, qtype, status string) *common.Record {
+	return windowtest.NewRecord(socket.L7ProtoDNS,
+		&pdns.Request{Host: client, Message: pdns.Message{QuestionSec: pdns.Question{Name: name, Type: qtype}}},
+		&pdns.Response{Host: "10.0.0.53", Port: 53, Message: pdns.Message{Header: pdns.Header{Status: status}}},
+		0,
+	)
+}
+
+func TestFactoryProcess(t *testing.T) {
+	p, err := New(map[string]any{"window": "10s", "topTalkers": 2})
+	require.NoError(t, err)
+
+	f := p.(*Factory)
+	clock := windowtest.NewClock()
+	start := clock.Start
+	f.SetClock(clock.Now)
+
+	process := func(n int, client, name, qtype, status string) *common.Record {
+		return windowtest.Process(t, f, newRecord(client, name, qtype, status), n)
+	}
+
+	assert.Nil(t, process(5, "10.0.0.1", "api.example.com.", "A", "Success"))
+	assert.Nil(t, process(3, "10.0.0.2", "db.Example.com.", "A", "Success"))
+	assert.Nil(t, process(1, "10.0.0.3", "web.example.com.", "A", "Success"))
+	assert.Nil(t, process(2, "10.0.0.1", "api.example.com.", "AAAA", "Success"))
+	assert.Nil(t, process(4, "10.0.0.9", "x1.example.com.svc.cluster.local.", "A", "NameError"))
+
+	now := clock.Advance(10 * time.Second)
+	assert.Equal(t, []any{
+		&Rollup{
+			Event: eventName,
+			Start: start,
+			End:   now,
+			Zone:  "cluster.local",
+			QType: "A",
+			RCode: "NameError",
+			Count: 4,
+			Talkers: []Talker{
+				{Client: "10.0.0.9", Count: 4},
+			},
+		},
+		&Rollup{
+			Event: eventName,
+			Start: start,
+			End:   now,
+			Zone:  "example.com",
+			QType: "A",
+			RCode: "Success",
+			Count: 9,
+			Talkers: []Talker{
+				{Client: "10.0.0.1", Count: 5},
+				{Client: "10.0.0.2", Count: 3},
+			},
+		},
+		&Rollup{
+			Event: eventName,
+			Start: start,
+			End:   now,
+			Zone:  "example.com",
+			QType: "AAAA",
+			RCode: "Success",
+			Count: 2,
+			Talkers: []Talker{
+				{Client: "10.0.0.1", Count: 2},
+			},
+		},
+	}, windowtest.Events(t, process(1, "10.0.0.1", "api.example.com.", "A", "Success")))
+}
+
+func TestAggregatorLimits(t *testing.T) {
+	a := newAggregator(Config{Window: time.Minute, TopTalkers: -1, MaxKeys: 2})
+	now := time.Unix(1751356800, 0)
+
+	for i := 0; i < 4; i++ {
+		key := rollupKey{zone: fmt.Sprintf("z%d.example", i), qtype: "A", rcode: "Success"}
+		a.observe(key, "10.0.0.1")
+	}
+
+	rollups := a.flush(now, now.Add(time.Minute))
+	require.Len(t, rollups, 3)
+	assert.Equal(t, otherValue, rollups[0].Zone)
+	assert.Equal(t, 2, rollups[0].Count)
+	assert.Nil(t, rollups[0].Talkers)
+}
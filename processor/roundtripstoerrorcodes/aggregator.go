@@ -0,0 +1,116 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstoerrorcodes
+
+import (
+	"sort"
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/window"
+)
+
+const (
+	// eventName 汇总事件名称 便于与其他结构化事件区分
+	eventName = "error_codes"
+
+	// otherValue 超出上限的服务端或者响应码统一使用的值
+	otherValue = "other"
+
+	// maxCodes 单个服务端单个窗口内单独统计的响应码数量上限
+	maxCodes = 64
+)
+
+type endpointKey struct {
+	proto    socket.L7Proto
+	endpoint string
+}
+
+// Rollup 单个窗口内某个服务端的响应码分布
+type Rollup struct {
+	Event    string
+	Start    time.Time
+	End      time.Time
+	Proto    socket.L7Proto
+	Endpoint string // 服务端地址 host:port
+	Total    int
+	Codes    map[string]int
+}
+
+// aggregator 汇总窗口内的响应码
+//
+// 服务端数量以及响应码数量均有上限 超出后计入 other 避免异常流量导致内存膨胀
+type aggregator struct {
+	maxEndpoints int
+	curr         map[endpointKey]*Rollup
+}
+
+func newAggregator(maxEndpoints int) *aggregator {
+	return &aggregator{
+		maxEndpoints: maxEndpoints,
+		curr:         make(map[endpointKey]*Rollup),
+	}
+}
+
+// observe 记录一次响应码
+func (a *aggregator) observe(key endpointKey, code string) {
+	rollup, ok := a.curr[key]
+	if !ok {
+		if len(a.curr) >= a.maxEndpoints {
+			key.endpoint = otherValue
+		}
+		if rollup, ok = a.curr[key]; !ok {
+			rollup = &Rollup{
+				Event:    eventName,
+				Proto:    key.proto,
+				Endpoint: key.endpoint,
+				Codes:    make(map[string]int),
+			}
+			a.curr[key] = rollup
+		}
+	}
+
+	if _, ok := rollup.Codes[code]; !ok && len(rollup.Codes) >= maxCodes {
+		code = otherValue
+	}
+	rollup.Codes[code]++
+	rollup.Total++
+}
+
+// Flush 结束当前窗口 按照协议以及服务端排序返回汇总结果
+func (a *aggregator) Flush(start, end time.Time) []any {
+	return window.Events(a.flush(start, end))
+}
+
+func (a *aggregator) Reset() {
+	a.curr = make(map[endpointKey]*Rollup)
+}
+
+func (a *aggregator) flush(start, end time.Time) []*Rollup {
+	rollups := make([]*Rollup, 0, len(a.curr))
+	for _, rollup := range a.curr {
+		rollup.Start = start
+		rollup.End = end
+		rollups = append(rollups, rollup)
+	}
+	sort.Slice(rollups, func(i, j int) bool {
+		if rollups[i].Proto != rollups[j].Proto {
+			return rollups[i].Proto < rollups[j].Proto
+		}
+		return rollups[i].Endpoint < rollups[j].Endpoint
+	})
+
+	a.curr = make(map[endpointKey]*Rollup, len(a.curr))
+	return rollups
+}
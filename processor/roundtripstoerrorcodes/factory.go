@@ -0,0 +1,93 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstoerrorcodes
+
+import (
+	"time"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/mapstructure"
+	"github.com/packetd/packetd/internal/window"
+	"github.com/packetd/packetd/processor"
+)
+
+const Name = "roundtripstoerrorcodes"
+
+const (
+	defaultWindow       = time.Minute
+	defaultMaxEndpoints = 1000
+)
+
+func init() {
+	processor.Register(Name, New)
+}
+
+type Config struct {
+	// Window 汇总窗口 每个窗口结束时输出一次各服务端的响应码分布
+	Window time.Duration `config:"window" mapstructure:"window"`
+
+	// MaxEndpoints 单个窗口内单独统计的服务端数量上限 其余服务端汇总为 other
+	MaxEndpoints int `config:"maxEndpoints" mapstructure:"maxEndpoints"`
+}
+
+// Factory 按照时间窗口汇总每个服务端的响应码分布 以结构化事件的形式输出
+//
+// 相比保留原始 roundtrip 汇总结果的体积与请求量无关 足以回答 `哪个错误码突增` 这类问题
+type Factory struct {
+	*window.Aggregator
+	aggregator *aggregator
+}
+
+func New(conf map[string]any) (processor.Processor, error) {
+	cfg := &Config{}
+	if err := mapstructure.Decode(conf, cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultWindow
+	}
+	if cfg.MaxEndpoints <= 0 {
+		cfg.MaxEndpoints = defaultMaxEndpoints
+	}
+
+	a := newAggregator(cfg.MaxEndpoints)
+	return &Factory{
+		Aggregator: window.New(cfg.Window, a),
+		aggregator: a,
+	}, nil
+}
+
+func (f *Factory) Name() string {
+	return Name
+}
+
+// Process 记录 RoundTrip 的响应码 窗口结束时返回上一个窗口的汇总事件
+//
+// 没有新数据到达时窗口由 Flush 结束 空窗口不输出
+func (f *Factory) Process(record *common.Record) (*common.Record, error) {
+	rt, ok := record.Data.(socket.RoundTrip)
+	if !ok {
+		return nil, nil
+	}
+	endpoint, code, ok := inspect(rt)
+	if !ok {
+		return nil, nil
+	}
+
+	return f.Observe(func(time.Time) []any {
+		f.aggregator.observe(endpointKey{proto: rt.Proto(), endpoint: endpoint}, code)
+		return nil
+	}), nil
+}
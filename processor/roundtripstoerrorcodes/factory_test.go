@@ -0,0 +1,102 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstoerrorcodes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/window/windowtest"
+	"github.com/packetd/packetd/protocol/phttp"
+)
+
+func newRecord(server string, statusCode int) *common.Record {
+	return windowtest.NewRecord(socket.L7ProtoHTTP, &phttp.Request{}, &phttp.Response{Host: server, Port: 80, StatusCode: statusCode}, 0)
+}
+
+func TestFactoryProcess(t *testing.T) {
+	p, err := New(map[string]any{"window": "10s"})
+	require.NoError(t, err)
+
+	f := p.(*Factory)
+	clock := windowtest.NewClock()
+	f.SetClock(clock.Now)
+
+	for _, code := range []int{200, 200, 500, 503} {
+		assert.Nil(t, windowtest.Process(t, f, newRecord("10.0.0.1", code), 1))
+	}
+	assert.Nil(t, windowtest.Process(t, f, newRecord("10.0.0.2", 404), 1))
+
+	// 窗口结束后由下一条数据触发输出
+	now := clock.Advance(10 * time.Second)
+	want := []any{
+		&Rollup{
+			Event:    eventName,
+			Start:    clock.Start,
+			End:      now,
+			Proto:    socket.L7ProtoHTTP,
+			Endpoint: "10.0.0.1:80",
+			Total:    4,
+			Codes:    map[string]int{"200": 2, "500": 1, "503": 1},
+		},
+		&Rollup{
+			Event:    eventName,
+			Start:    clock.Start,
+			End:      now,
+			Proto:    socket.L7ProtoHTTP,
+			Endpoint: "10.0.0.2:80",
+			Total:    1,
+			Codes:    map[string]int{"404": 1},
+		},
+	}
+	assert.Equal(t, want, windowtest.Events(t, windowtest.Process(t, f, newRecord("10.0.0.1", 200), 1)))
+
+	// 没有新数据时由 Flush 结束窗口
+	assert.Nil(t, f.Flush(false))
+	end := clock.Advance(10 * time.Second)
+	events := windowtest.Events(t, f.Flush(false))
+	require.Len(t, events, 1)
+	assert.Equal(t, now, events[0].(*Rollup).Start)
+	assert.Equal(t, end, events[0].(*Rollup).End)
+	assert.Nil(t, f.Flush(false))
+
+	// 退出前强制输出当前窗口
+	assert.Nil(t, windowtest.Process(t, f, newRecord("10.0.0.1", 200), 1))
+	assert.Len(t, windowtest.Events(t, f.Flush(true)), 1)
+}
+
+func TestAggregatorLimits(t *testing.T) {
+	a := newAggregator(2)
+	now := time.Unix(1751356800, 0)
+	for _, ep := range []string{"a", "b", "c", "d"} {
+		a.observe(endpointKey{proto: socket.L7ProtoMySQL, endpoint: ep}, "0")
+	}
+	for i := 0; i < maxCodes+3; i++ {
+		a.observe(endpointKey{proto: socket.L7ProtoMySQL, endpoint: "a"}, string(rune('A'+i)))
+	}
+
+	rollups := a.flush(now, now)
+	assert.Len(t, rollups, 3)
+	assert.Equal(t, otherValue, rollups[2].Endpoint)
+	assert.Equal(t, 2, rollups[2].Total)
+
+	assert.Len(t, rollups[0].Codes, maxCodes+1) // 含 other
+	assert.Equal(t, 4, rollups[0].Codes[otherValue])
+	assert.Empty(t, a.curr)
+}
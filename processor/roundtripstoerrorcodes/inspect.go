@@ -0,0 +1,69 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstoerrorcodes
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol/phttp"
+	"github.com/packetd/packetd/protocol/phttp2"
+	"github.com/packetd/packetd/protocol/pkafka"
+	"github.com/packetd/packetd/protocol/pmongodb"
+	"github.com/packetd/packetd/protocol/pmysql"
+	"github.com/packetd/packetd/protocol/ppostgresql"
+)
+
+func endpoint(host string, port uint16) string {
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+// inspect 返回 RoundTrip 的服务端地址以及响应码
+//
+// 成功的响应同样计入分布（如 HTTP 200 / MySQL 0 / Kafka NoError）便于计算各错误码的占比
+// 单向事件没有响应 不参与统计
+func inspect(rt socket.RoundTrip) (string, string, bool) {
+	switch rsp := rt.Response().(type) {
+	case *phttp.Response:
+		return endpoint(rsp.Host, rsp.Port), strconv.Itoa(rsp.StatusCode), true
+
+	case *phttp2.Response:
+		return endpoint(rsp.Host, rsp.Port), rsp.Status, true
+
+	case *pmysql.Response:
+		code := "0"
+		if p, ok := rsp.Packet.(*pmysql.ErrorPacket); ok {
+			code = strconv.Itoa(p.ErrCode)
+		}
+		return endpoint(rsp.Host, rsp.Port), code, true
+
+	case *ppostgresql.Response:
+		code := "00000" // SQLSTATE successful_completion
+		if p, ok := rsp.Packet.(*ppostgresql.ErrorPacket); ok {
+			code = p.SQLStateCode
+		}
+		return endpoint(rsp.Host, rsp.Port), code, true
+
+	case *pkafka.Response:
+		if rsp == nil {
+			return "", "", false
+		}
+		return endpoint(rsp.Host, rsp.Port), rsp.ErrorCode, true
+
+	case *pmongodb.Response:
+		return endpoint(rsp.Host, rsp.Port), strconv.Itoa(int(rsp.Code)), true
+	}
+	return "", "", false
+}
@@ -60,12 +60,19 @@ var amqpCommMetrics = commonMetrics{
 	requestDurationSeconds: "amqp_request_duration_seconds",
 	requestBodySizeBytes:   "amqp_request_body_bytes",
 	responseBodySizeBytes:  "amqp_response_body_bytes",
+	oneWayTotal:            "amqp_oneway_requests_total",
 }
 
 func (c *amqpConverter) Convert(rt socket.RoundTrip) []metricstorage.ConstMetric {
 	req := rt.Request().(*pamqp.Request)
 	rsp := rt.Response().(*pamqp.Response)
 
+	// 未开启 Confirm 模式的 Publish 没有响应 服务端地址由请求携带
+	if rsp == nil {
+		lbs := c.matchLabels(req, &pamqp.Response{Host: req.ServerHost, Port: req.ServerPort})
+		return generateOneWayMetrics(amqpCommMetrics, lbs, req.Size)
+	}
+
 	lbs := c.matchLabels(req, rsp)
 	return generateCommonMetrics(amqpCommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
 }
@@ -0,0 +1,101 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstometrics
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/packetd/packetd/internal/labels"
+	"github.com/packetd/packetd/internal/metricstorage"
+)
+
+// AuxiliaryConfig 辅助请求的识别配置
+//
+// CORS 预检以及健康检查请求耗时极短且频率固定 混入常规指标会拉低延迟分位数 默认单独计数
+type AuxiliaryConfig struct {
+	// HealthPaths 健康检查路径 支持 glob 为空时使用 defaultHealthPaths
+	HealthPaths []string `config:"healthPaths" mapstructure:"healthPaths"`
+
+	// Include 为 true 时辅助请求仍计入常规指标 可配合 request.class 维度自行过滤
+	Include bool `config:"include" mapstructure:"include"`
+}
+
+var defaultHealthPaths = []string{
+	"/healthz",
+	"/livez",
+	"/readyz",
+	"/health",
+	"/actuator/health",
+	"/actuator/health/*",
+}
+
+func (ac *AuxiliaryConfig) Validate() error {
+	if len(ac.HealthPaths) == 0 {
+		ac.HealthPaths = defaultHealthPaths
+	}
+	for _, pattern := range ac.HealthPaths {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.Wrapf(err, "invalid health path '%s'", pattern)
+		}
+	}
+	return nil
+}
+
+const (
+	requestClassNormal    = "normal"
+	requestClassPreflight = "preflight"
+	requestClassHealth    = "health"
+)
+
+// classifyRequest 返回请求类别
+//
+// 预检请求需同时满足 OPTIONS 方法以及携带 Access-Control-Request-Method 头 普通的 OPTIONS 请求不受影响
+func classifyRequest(healthPaths []string, method, p string, header http.Header) string {
+	if method == http.MethodOptions && header.Get("Access-Control-Request-Method") != "" {
+		return requestClassPreflight
+	}
+
+	if idx := strings.IndexByte(p, '?'); idx >= 0 {
+		p = p[:idx]
+	}
+	for _, pattern := range healthPaths {
+		if ok, _ := path.Match(pattern, p); ok {
+			return requestClassHealth
+		}
+	}
+	return requestClassNormal
+}
+
+// generateAuxiliaryMetrics 辅助请求仅记录请求数 不进入耗时以及大小分布
+//
+// 未配置 request.class 维度时同样追加 class 以区分预检 / 健康检查 / 保活语句
+func generateAuxiliaryMetrics(name string, lbs labels.Labels, class string) []metricstorage.ConstMetric {
+	var found bool
+	for _, label := range lbs {
+		if label.Name == "class" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		lbs = append(lbs[:len(lbs):len(lbs)], labels.Label{Name: "class", Value: class})
+	}
+	return []metricstorage.ConstMetric{
+		metricstorage.NewCounterConstMetric(name, 1, lbs),
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstometrics
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/internal/labels"
+)
+
+func TestClassifyRequest(t *testing.T) {
+	var conf AuxiliaryConfig
+	assert.NoError(t, conf.Validate())
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header http.Header
+		want   string
+	}{
+		{
+			name:   "Preflight",
+			method: http.MethodOptions,
+			path:   "/api/orders",
+			header: http.Header{"Access-Control-Request-Method": []string{"POST"}},
+			want:   requestClassPreflight,
+		},
+		{
+			name:   "PlainOptions",
+			method: http.MethodOptions,
+			path:   "/api/orders",
+			want:   requestClassNormal,
+		},
+		{
+			name:   "Health",
+			method: http.MethodGet,
+			path:   "/healthz",
+			want:   requestClassHealth,
+		},
+		{
+			name:   "HealthGlobWithQuery",
+			method: http.MethodGet,
+			path:   "/actuator/health/liveness?verbose=1",
+			want:   requestClassHealth,
+		},
+		{
+			name:   "Normal",
+			method: http.MethodGet,
+			path:   "/api/healthz/history",
+			want:   requestClassNormal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, classifyRequest(conf.HealthPaths, tt.method, tt.path, tt.header))
+		})
+	}
+}
+
+func TestAuxiliaryConfigValidate(t *testing.T) {
+	conf := AuxiliaryConfig{HealthPaths: []string{"/ping"}}
+	assert.NoError(t, conf.Validate())
+	assert.Equal(t, []string{"/ping"}, conf.HealthPaths)
+
+	conf = AuxiliaryConfig{HealthPaths: []string{"/health["}}
+	assert.Error(t, conf.Validate())
+}
+
+func TestGenerateAuxiliaryMetrics(t *testing.T) {
+	lbs := labels.Labels{{Name: "server_port", Value: "80"}}
+	metrics := generateAuxiliaryMetrics("http_auxiliary_requests_total", lbs, requestClassHealth)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, labels.Labels{{Name: "server_port", Value: "80"}, {Name: "class", Value: "health"}}, metrics[0].Labels)
+	assert.Len(t, lbs, 1)
+
+	lbs = labels.Labels{{Name: "class", Value: "preflight"}}
+	metrics = generateAuxiliaryMetrics("http_auxiliary_requests_total", lbs, requestClassPreflight)
+	assert.Equal(t, lbs, metrics[0].Labels)
+}
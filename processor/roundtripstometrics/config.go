@@ -17,6 +17,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/packetd/packetd/internal/hostnames"
 	"github.com/packetd/packetd/internal/labels"
 	"github.com/packetd/packetd/internal/metricstorage"
 )
@@ -25,20 +26,61 @@ type CommonConfig struct {
 	RequireLabels []string `config:"requireLabels" mapstructure:"requireLabels"`
 }
 
+// ExtractRule 从 HTTP 请求中提取自定义维度
+//
+// Header 与 PathSegment 二选一 Regex 可选 若指定则取第一个捕获分组（无分组时取整个匹配）
+type ExtractRule struct {
+	// Label 输出的维度名称
+	Label string `config:"label" mapstructure:"label"`
+
+	// Header 取值的请求头名称 如 X-Tenant-Id
+	Header string `config:"header" mapstructure:"header"`
+
+	// PathSegment 取值的路径段序号 从 1 开始 如 /api/v1/users 的第 1 段为 api
+	PathSegment int `config:"pathSegment" mapstructure:"pathSegment"`
+
+	// Regex 对取到的值做二次提取
+	Regex string `config:"regex" mapstructure:"regex"`
+}
+
+type HTTPConfig struct {
+	RequireLabels []string        `config:"requireLabels" mapstructure:"requireLabels"`
+	Extract       []ExtractRule   `config:"extract" mapstructure:"extract"`
+	Auxiliary     AuxiliaryConfig `config:"auxiliary" mapstructure:"auxiliary"`
+}
+
+// StatementConfig 语句类协议的配置
+type StatementConfig struct {
+	RequireLabels []string        `config:"requireLabels" mapstructure:"requireLabels"`
+	Keepalive     KeepaliveConfig `config:"keepalive" mapstructure:"keepalive"`
+}
+
 type Config struct {
-	Expired    time.Duration `config:"expired" mapstructure:"expired"`
-	HTTP       CommonConfig  `config:"http" mapstructure:"http"`
-	Redis      CommonConfig  `config:"redis" mapstructure:"redis"`
-	MySQL      CommonConfig  `config:"mysql" mapstructure:"mysql"`
-	HTTP2      CommonConfig  `config:"http2" mapstructure:"http2"`
-	GRPC       CommonConfig  `config:"grpc" mapstructure:"grpc"`
-	DNS        CommonConfig  `config:"dns" mapstructure:"dns"`
-	MongoDB    CommonConfig  `config:"mongodb" mapstructure:"mongodb"`
-	PostgreSQL CommonConfig  `config:"postgresql" mapstructure:"postgresql"`
-	Kafka      CommonConfig  `config:"kafka" mapstructure:"kafka"`
-	AMQP       CommonConfig  `config:"amqp" mapstructure:"amqp"`
+	Expired    time.Duration   `config:"expired" mapstructure:"expired"`
+	HTTP       HTTPConfig      `config:"http" mapstructure:"http"`
+	Redis      StatementConfig `config:"redis" mapstructure:"redis"`
+	MySQL      StatementConfig `config:"mysql" mapstructure:"mysql"`
+	HTTP2      HTTPConfig      `config:"http2" mapstructure:"http2"`
+	GRPC       CommonConfig    `config:"grpc" mapstructure:"grpc"`
+	DNS        CommonConfig    `config:"dns" mapstructure:"dns"`
+	MongoDB    CommonConfig    `config:"mongodb" mapstructure:"mongodb"`
+	PostgreSQL StatementConfig `config:"postgresql" mapstructure:"postgresql"`
+	Kafka      CommonConfig    `config:"kafka" mapstructure:"kafka"`
+	AMQP       CommonConfig    `config:"amqp" mapstructure:"amqp"`
+	NTP        CommonConfig    `config:"ntp" mapstructure:"ntp"`
+	TLS        CommonConfig    `config:"tls" mapstructure:"tls"`
+	Probe      CommonConfig    `config:"probe" mapstructure:"probe"`
+	UDPRPC     CommonConfig    `config:"udprpc" mapstructure:"udprpc"`
+
+	// Hostnames 为 peer.hostname 维度提供地址至主机名的解析
+	Hostnames hostnames.Config `config:"hostnames" mapstructure:"hostnames"`
+
+	// IncludeDegradedLatency 为 true 时采集丢包期间的 RoundTrip 仍计入耗时分布 默认仅计入请求数以及大小
+	IncludeDegradedLatency bool `config:"includeDegradedLatency" mapstructure:"includeDegradedLatency"`
 }
 
+const peerHostnameLabel = "peer_hostname"
+
 func matchCommonLabels(required []string, src, dst string, sport, dport uint16) labels.Labels {
 	var lbs labels.Labels
 	for _, label := range required {
@@ -51,6 +93,9 @@ func matchCommonLabels(required []string, src, dst string, sport, dport uint16)
 			lbs = append(lbs, labels.Label{Name: "server_address", Value: dst})
 		case "server.port":
 			lbs = append(lbs, labels.Label{Name: "server_port", Value: strconv.Itoa(int(dport))})
+		case "peer.hostname":
+			// 先以服务端地址占位 由 Factory 统一替换为解析后的主机名
+			lbs = append(lbs, labels.Label{Name: peerHostnameLabel, Value: dst})
 		}
 	}
 	return lbs
@@ -61,6 +106,17 @@ type commonMetrics struct {
 	requestDurationSeconds string
 	requestBodySizeBytes   string
 	responseBodySizeBytes  string
+	oneWayTotal            string // 仅存在单向事件的协议需要设置
+}
+
+// generateOneWayMetrics 生成单向事件的指标
+//
+// 单向事件没有响应 不记录耗时以及响应大小 避免 0 值污染耗时分布
+func generateOneWayMetrics(cm commonMetrics, lbs labels.Labels, reqSize int) []metricstorage.ConstMetric {
+	return []metricstorage.ConstMetric{
+		metricstorage.NewCounterConstMetric(cm.oneWayTotal, 1, lbs),
+		metricstorage.NewHistogramConstMetric(cm.requestBodySizeBytes, float64(reqSize), metricstorage.UnitBytes, lbs),
+	}
 }
 
 func generateCommonMetrics(cm commonMetrics, lbs labels.Labels, secs float64, reqSize, rspSize int) []metricstorage.ConstMetric {
@@ -71,3 +127,22 @@ func generateCommonMetrics(cm commonMetrics, lbs labels.Labels, secs float64, re
 		metricstorage.NewHistogramConstMetric(cm.responseBodySizeBytes, float64(rspSize), metricstorage.UnitBytes, lbs),
 	}
 }
+
+type streamMetrics struct {
+	concurrentStreams string
+	limitReachedTotal string
+}
+
+// generateStreamMetrics 生成多路复用协议的并发流指标
+//
+// 请求发出时并发流已达到服务端声明的上限则计入 limitReachedTotal 用于解释排队引起的延迟
+func generateStreamMetrics(sm streamMetrics, lbs labels.Labels, concurrent int, reached bool) []metricstorage.ConstMetric {
+	var n float64
+	if reached {
+		n = 1
+	}
+	return []metricstorage.ConstMetric{
+		metricstorage.NewHistogramConstMetric(sm.concurrentStreams, float64(concurrent), metricstorage.UnitCount, lbs),
+		metricstorage.NewCounterConstMetric(sm.limitReachedTotal, n, lbs),
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstometrics
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/packetd/packetd/internal/labels"
+)
+
+// fieldExtractor 编译后的 ExtractRule
+type fieldExtractor struct {
+	label   string
+	header  string
+	segment int
+	re      *regexp.Regexp
+}
+
+// newFieldExtractors 校验并编译提取规则
+func newFieldExtractors(rules []ExtractRule) ([]fieldExtractor, error) {
+	extractors := make([]fieldExtractor, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Label == "" {
+			return nil, errors.Errorf("extract rule #%d: label required", i)
+		}
+		if (rule.Header == "") == (rule.PathSegment <= 0) {
+			return nil, errors.Errorf("extract rule %q: exactly one of header/pathSegment required", rule.Label)
+		}
+
+		fe := fieldExtractor{
+			label:   rule.Label,
+			header:  rule.Header,
+			segment: rule.PathSegment,
+		}
+		if rule.Regex != "" {
+			re, err := regexp.Compile(rule.Regex)
+			if err != nil {
+				return nil, errors.Wrapf(err, "extract rule %q: compile regex", rule.Label)
+			}
+			fe.re = re
+		}
+		extractors = append(extractors, fe)
+	}
+	return extractors, nil
+}
+
+func (fe fieldExtractor) extract(header http.Header, path string) string {
+	var val string
+	if fe.header != "" {
+		val = header.Get(fe.header)
+	} else {
+		val = pathSegment(path, fe.segment)
+	}
+	if val == "" || fe.re == nil {
+		return val
+	}
+
+	m := fe.re.FindStringSubmatch(val)
+	switch len(m) {
+	case 0:
+		return ""
+	case 1:
+		return m[0]
+	}
+	return m[1]
+}
+
+// pathSegment 返回 path 中第 n 段（从 1 开始）忽略 query 以及首尾的 '/'
+func pathSegment(path string, n int) string {
+	if idx := strings.IndexByte(path, '?'); idx >= 0 {
+		path = path[:idx]
+	}
+	path = strings.Trim(path, "/")
+	for i := 1; path != ""; i++ {
+		seg, rest, _ := strings.Cut(path, "/")
+		if i == n {
+			return seg
+		}
+		path = rest
+	}
+	return ""
+}
+
+// appendExtractLabels 追加提取出的维度 未取到值时维度仍保留为空字符串 保证同一指标的维度集合一致
+func appendExtractLabels(lbs labels.Labels, extractors []fieldExtractor, header http.Header, path string) labels.Labels {
+	for _, fe := range extractors {
+		lbs = append(lbs, labels.Label{Name: fe.label, Value: fe.extract(header, path)})
+	}
+	return lbs
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstometrics
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/internal/labels"
+)
+
+func TestNewFieldExtractors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []ExtractRule
+		err   bool
+	}{
+		{
+			name:  "Header",
+			rules: []ExtractRule{{Label: "tenant", Header: "X-Tenant-Id"}},
+		},
+		{
+			name:  "MissingLabel",
+			rules: []ExtractRule{{Header: "X-Tenant-Id"}},
+			err:   true,
+		},
+		{
+			name:  "NoSource",
+			rules: []ExtractRule{{Label: "tenant"}},
+			err:   true,
+		},
+		{
+			name:  "BothSource",
+			rules: []ExtractRule{{Label: "tenant", Header: "X-Tenant-Id", PathSegment: 1}},
+			err:   true,
+		},
+		{
+			name:  "InvalidRegex",
+			rules: []ExtractRule{{Label: "version", PathSegment: 2, Regex: "v("}},
+			err:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newFieldExtractors(tt.rules)
+			assert.Equal(t, tt.err, err != nil)
+		})
+	}
+}
+
+func TestAppendExtractLabels(t *testing.T) {
+	extractors, err := newFieldExtractors([]ExtractRule{
+		{Label: "tenant", Header: "X-Tenant-Id"},
+		{Label: "api_group", PathSegment: 1},
+		{Label: "api_version", PathSegment: 2, Regex: `^v(\d+)$`},
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		path   string
+		want   labels.Labels
+	}{
+		{
+			name:   "Matched",
+			header: http.Header{"X-Tenant-Id": []string{"t1"}},
+			path:   "/orders/v2/items?limit=10",
+			want: labels.Labels{
+				{Name: "tenant", Value: "t1"},
+				{Name: "api_group", Value: "orders"},
+				{Name: "api_version", Value: "2"},
+			},
+		},
+		{
+			name: "Missing",
+			path: "/",
+			want: labels.Labels{
+				{Name: "tenant", Value: ""},
+				{Name: "api_group", Value: ""},
+				{Name: "api_version", Value: ""},
+			},
+		},
+		{
+			name: "RegexNotMatched",
+			path: "/users/latest",
+			want: labels.Labels{
+				{Name: "tenant", Value: ""},
+				{Name: "api_group", Value: "users"},
+				{Name: "api_version", Value: ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, appendExtractLabels(nil, extractors, tt.header, tt.path))
+		})
+	}
+}
@@ -14,8 +14,14 @@
 package roundtripstometrics
 
 import (
+	"net"
+
+	"github.com/pkg/errors"
+
 	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/hostnames"
+	"github.com/packetd/packetd/internal/labels"
 	"github.com/packetd/packetd/internal/mapstructure"
 	"github.com/packetd/packetd/internal/metricstorage"
 	"github.com/packetd/packetd/processor"
@@ -39,7 +45,9 @@ func register(proto socket.L7Proto, converter func(config Config) converter) {
 }
 
 type Factory struct {
-	converters map[socket.L7Proto]converter
+	converters      map[socket.L7Proto]converter
+	hostnames       *hostnames.Resolver
+	includeDegraded bool
 }
 
 func New(conf map[string]any) (processor.Processor, error) {
@@ -47,13 +55,42 @@ func New(conf map[string]any) (processor.Processor, error) {
 	if err := mapstructure.Decode(conf, cfg); err != nil {
 		return nil, err
 	}
+	if _, err := newFieldExtractors(cfg.HTTP.Extract); err != nil {
+		return nil, errors.Wrap(err, "http")
+	}
+	if _, err := newFieldExtractors(cfg.HTTP2.Extract); err != nil {
+		return nil, errors.Wrap(err, "http2")
+	}
+	if err := cfg.HTTP.Auxiliary.Validate(); err != nil {
+		return nil, errors.Wrap(err, "http")
+	}
+	if err := cfg.HTTP2.Auxiliary.Validate(); err != nil {
+		return nil, errors.Wrap(err, "http2")
+	}
+	if err := cfg.MySQL.Keepalive.Validate(defaultMySQLKeepalives); err != nil {
+		return nil, errors.Wrap(err, "mysql")
+	}
+	if err := cfg.PostgreSQL.Keepalive.Validate(defaultPostgreSQLKeepalives); err != nil {
+		return nil, errors.Wrap(err, "postgresql")
+	}
+	if err := cfg.Redis.Keepalive.Validate(defaultRedisKeepalives); err != nil {
+		return nil, errors.Wrap(err, "redis")
+	}
 
 	impl := make(map[socket.L7Proto]converter)
 	for k, f := range converters {
 		impl[k] = f(*cfg)
 	}
 	factory := &Factory{
-		converters: impl,
+		converters:      impl,
+		includeDegraded: cfg.IncludeDegradedLatency,
+	}
+	if cfg.Hostnames.Enabled() {
+		r, err := hostnames.New(cfg.Hostnames)
+		if err != nil {
+			return nil, errors.Wrap(err, "hostnames")
+		}
+		factory.hostnames = r
 	}
 	return factory, nil
 }
@@ -69,11 +106,91 @@ func (f *Factory) Process(record *common.Record) (*common.Record, error) {
 		return nil, nil
 	}
 
-	data := impl.Convert(rt)
+	var data []metricstorage.ConstMetric
+	if socket.IsTruncatedCapture(rt) {
+		data = convertTruncated(rt)
+	} else {
+		data = impl.Convert(rt)
+	}
+	if !f.includeDegraded && socket.CaptureQuality(rt) < 1 {
+		data = excludeLatency(data)
+	}
+	f.resolvePeerHostname(data)
+	if socket.IsInjected(rt) {
+		markInjected(data)
+	}
+	attachExemplar(record, data)
 	return &common.Record{
 		RecordType: common.RecordMetrics,
-		Data:       &common.MetricsData{Data: data},
+		Data:       &common.MetricsData{Data: data, RoundTrip: rt},
 	}, nil
 }
 
 func (f *Factory) Clean() {}
+
+// resolvePeerHostname 将 peer_hostname 维度的地址占位替换为主机名 无法解析时保留原始地址
+//
+// 同一 roundtrip 生成的指标通常共享 labels 已替换过的值不再是 IP 地址 直接跳过
+func (f *Factory) resolvePeerHostname(metrics []metricstorage.ConstMetric) {
+	if f.hostnames == nil || len(metrics) == 0 {
+		return
+	}
+	for i := 0; i < len(metrics); i++ {
+		lbs := metrics[i].Labels
+		for j := 0; j < len(lbs); j++ {
+			if lbs[j].Name != peerHostnameLabel || net.ParseIP(lbs[j].Value) == nil {
+				continue
+			}
+			if name := f.hostnames.Lookup(lbs[j].Value); name != "" {
+				lbs[j].Value = name
+			}
+		}
+	}
+}
+
+// markInjected 为注入的合成数据附加 injected="true" 维度 避免与真实流量的指标混在一起
+//
+// 同一 roundtrip 生成的指标可能共享 labels 需复制后再追加
+func markInjected(metrics []metricstorage.ConstMetric) {
+	for i := 0; i < len(metrics); i++ {
+		lbs := metrics[i].Labels
+		metrics[i].Labels = append(lbs[:len(lbs):len(lbs)], labels.Label{Name: "injected", Value: "true"})
+	}
+}
+
+// excludeLatency 移除耗时类 Histogram
+//
+// 丢包期间重传的数据包可能未被捕获 测得的耗时并不可信 计入分位数容易在突发流量时引起误告警
+func excludeLatency(metrics []metricstorage.ConstMetric) []metricstorage.ConstMetric {
+	dst := metrics[:0]
+	for _, m := range metrics {
+		if m.Model == metricstorage.ModelHistogram && m.Unit == metricstorage.UnitSeconds {
+			continue
+		}
+		dst = append(dst, m)
+	}
+	return dst
+}
+
+// attachExemplar 为耗时类 Histogram 附加 exemplar 以便从热力图跳转至具体的 Trace
+//
+// 仅当 roundtripstotraces 在同一 pipeline 中先行处理过该 roundtrip 时才存在 TraceID
+func attachExemplar(record *common.Record, metrics []metricstorage.ConstMetric) {
+	if record.TraceID.IsEmpty() {
+		return
+	}
+
+	var exemplar labels.Labels
+	for i := 0; i < len(metrics); i++ {
+		if metrics[i].Model != metricstorage.ModelHistogram || metrics[i].Unit != metricstorage.UnitSeconds {
+			continue
+		}
+		if exemplar == nil {
+			exemplar = labels.Labels{
+				{Name: "trace_id", Value: record.TraceID.String()},
+				{Name: "span_id", Value: record.SpanID.String()},
+			}
+		}
+		metrics[i].Exemplar = exemplar
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstometrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/internal/labels"
+	"github.com/packetd/packetd/internal/metricstorage"
+)
+
+func TestMarkInjected(t *testing.T) {
+	// 同一 roundtrip 生成的指标共享 labels
+	lbs := make(labels.Labels, 1, 4)
+	lbs[0] = labels.Label{Name: "server_port", Value: "6379"}
+	metrics := []metricstorage.ConstMetric{
+		metricstorage.NewCounterConstMetric("redis_requests_total", 1, lbs),
+		metricstorage.NewCounterConstMetric("redis_request_bytes_total", 10, lbs),
+	}
+
+	markInjected(metrics)
+	want := labels.Labels{
+		{Name: "server_port", Value: "6379"},
+		{Name: "injected", Value: "true"},
+	}
+	for _, m := range metrics {
+		assert.Equal(t, want, m.Labels)
+	}
+	assert.Len(t, lbs, 1)
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/packetd/packetd/internal/labels"
 	"github.com/packetd/packetd/internal/metricstorage"
 	"github.com/packetd/packetd/protocol/pgrpc"
+	"github.com/packetd/packetd/protocol/phttp2"
 )
 
 func init() {
@@ -58,10 +59,64 @@ var grpcCommMetrics = commonMetrics{
 	responseBodySizeBytes:  "grpc_response_body_bytes",
 }
 
+var grpcStreamMetrics = streamMetrics{
+	concurrentStreams: "grpc_concurrent_streams",
+	limitReachedTotal: "grpc_stream_limit_reached_total",
+}
+
 func (c *grpcConverter) Convert(rt socket.RoundTrip) []metricstorage.ConstMetric {
 	req := rt.Request().(*pgrpc.Request)
 	rsp := rt.Response().(*pgrpc.Response)
 
+	// 流式调用尚未结束时上报的增量消息统计 仅生成消息指标 此时还没有响应状态
+	if p := req.Progress; p != nil {
+		lbs := c.matchLabels(req, &pgrpc.Response{Host: p.ServerHost, Port: p.ServerPort})
+		return generateMessageMetrics(lbs, p.Direction, p.Messages, p.MessageSizes)
+	}
+
 	lbs := c.matchLabels(req, rsp)
-	return generateCommonMetrics(grpcCommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
+	metrics := generateCommonMetrics(grpcCommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
+	reached := phttp2.StreamLimitReached(req.ConcurrentStreams, rsp.MaxConcurrentStreams)
+	metrics = append(metrics, generateStreamMetrics(grpcStreamMetrics, lbs, req.ConcurrentStreams, reached)...)
+	metrics = append(metrics, generateMessageMetrics(lbs, "request", req.Messages, req.MessageSizes)...)
+	metrics = append(metrics, generateDeadlineMetrics(lbs, req.Timeout.Seconds(), rsp.PastDeadline)...)
+	return append(metrics, generateMessageMetrics(lbs, "response", rsp.Messages, rsp.MessageSizes)...)
+}
+
+// generateDeadlineMetrics 生成声明了 grpc-timeout 的 RPC 的超时指标
+//
+// 与 grpc_request_duration_seconds 对比可以看出超时设置是否合理 超时后才完成的 RPC 计入 past_deadline
+func generateDeadlineMetrics(lbs labels.Labels, timeout float64, pastDeadline bool) []metricstorage.ConstMetric {
+	if timeout <= 0 {
+		return nil
+	}
+
+	var n float64
+	if pastDeadline {
+		n = 1
+	}
+	return []metricstorage.ConstMetric{
+		metricstorage.NewHistogramConstMetric("grpc_request_timeout_seconds", timeout, metricstorage.UnitSeconds, lbs),
+		metricstorage.NewCounterConstMetric("grpc_requests_past_deadline_total", n, lbs),
+	}
+}
+
+// generateMessageMetrics 生成单个方向的 gRPC 消息指标
+//
+// 流式 RPC 的整体耗时无法反映消息的吞吐情况 因此按方向统计消息数量以及每条消息的大小
+func generateMessageMetrics(lbs labels.Labels, direction string, count int, sizes []int) []metricstorage.ConstMetric {
+	if count == 0 {
+		return nil
+	}
+
+	dlbs := make(labels.Labels, 0, len(lbs)+1)
+	dlbs = append(dlbs, lbs...)
+	dlbs = append(dlbs, labels.Label{Name: "direction", Value: direction})
+
+	metrics := make([]metricstorage.ConstMetric, 0, len(sizes)+1)
+	metrics = append(metrics, metricstorage.NewCounterConstMetric("grpc_messages_total", float64(count), dlbs))
+	for _, size := range sizes {
+		metrics = append(metrics, metricstorage.NewHistogramConstMetric("grpc_message_size_bytes", float64(size), metricstorage.UnitBytes, dlbs))
+	}
+	return metrics
 }
@@ -14,11 +14,14 @@
 package roundtripstometrics
 
 import (
+	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/labels"
 	"github.com/packetd/packetd/internal/metricstorage"
+	"github.com/packetd/packetd/protocol"
 	"github.com/packetd/packetd/protocol/phttp"
 )
 
@@ -27,12 +30,15 @@ func init() {
 }
 
 type httpConverter struct {
-	config CommonConfig
+	config     HTTPConfig
+	extractors []fieldExtractor
 }
 
 func newHTTPConverter(config Config) converter {
+	extractors, _ := newFieldExtractors(config.HTTP.Extract) // 规则已在 New 中校验
 	return &httpConverter{
-		config: config.HTTP,
+		config:     config.HTTP,
+		extractors: extractors,
 	}
 }
 
@@ -40,7 +46,7 @@ func (c *httpConverter) Proto() socket.L7Proto {
 	return socket.L7ProtoHTTP
 }
 
-func (c *httpConverter) matchLabels(req *phttp.Request, rsp *phttp.Response) labels.Labels {
+func (c *httpConverter) matchLabels(req *phttp.Request, rsp *phttp.Response, class string) labels.Labels {
 	lbs := matchCommonLabels(c.config.RequireLabels, req.Host, rsp.Host, req.Port, rsp.Port)
 	for _, label := range c.config.RequireLabels {
 		switch label {
@@ -52,9 +58,17 @@ func (c *httpConverter) matchLabels(req *phttp.Request, rsp *phttp.Response) lab
 			lbs = append(lbs, labels.Label{Name: "remote_host", Value: req.RemoteHost})
 		case "response.status_code":
 			lbs = append(lbs, labels.Label{Name: "status_code", Value: strconv.Itoa(rsp.StatusCode)})
+		case "response.outcome":
+			lbs = append(lbs, labels.Label{Name: "outcome", Value: httpOutcome(rsp)})
+		case "request.class":
+			lbs = append(lbs, labels.Label{Name: "class", Value: class})
+		case "request.route":
+			lbs = append(lbs, labels.Label{Name: "route", Value: operationRoute(req.Operation)})
+		case "request.operation_id":
+			lbs = append(lbs, labels.Label{Name: "operation_id", Value: operationID(req.Operation)})
 		}
 	}
-	return lbs
+	return appendExtractLabels(lbs, c.extractors, req.Header, req.Path)
 }
 
 var httpCommMetrics = commonMetrics{
@@ -68,6 +82,52 @@ func (c *httpConverter) Convert(rt socket.RoundTrip) []metricstorage.ConstMetric
 	req := rt.Request().(*phttp.Request)
 	rsp := rt.Response().(*phttp.Response)
 
-	lbs := c.matchLabels(req, rsp)
-	return generateCommonMetrics(httpCommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
+	class := classifyRequest(c.config.Auxiliary.HealthPaths, req.Method, req.Path, req.Header)
+	lbs := c.matchLabels(req, rsp, class)
+	if class != requestClassNormal && !c.config.Auxiliary.Include {
+		return generateAuxiliaryMetrics("http_auxiliary_requests_total", lbs, class)
+	}
+
+	metrics := generateCommonMetrics(httpCommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
+	if rsp.Outcome == phttp.OutcomeClientAborted {
+		metrics = append(metrics, metricstorage.NewCounterConstMetric("http_client_aborted_total", 1, lbs))
+	}
+	return append(metrics, generateQueueMetrics("http_request_queue_seconds", lbs, req.Header, req.Time)...)
+}
+
+// operationRoute 返回 OpenAPI 匹配出的路由模板 未匹配时为空 避免原始路径导致维度基数膨胀
+func operationRoute(op *protocol.Operation) string {
+	if op == nil {
+		return ""
+	}
+	return op.Route
+}
+
+// operationID 返回 OpenAPI 匹配出的 operationId 未匹配时为空
+func operationID(op *protocol.Operation) string {
+	if op == nil {
+		return ""
+	}
+	return op.ID
+}
+
+// httpOutcome 返回响应的传输结果 正常结束的响应记为 completed
+func httpOutcome(rsp *phttp.Response) string {
+	if rsp.Outcome == "" {
+		return "completed"
+	}
+	return rsp.Outcome
+}
+
+// generateQueueMetrics 根据上游代理写入的请求到达时间生成排队耗时指标
+//
+// 与 *_request_duration_seconds（服务端处理耗时）组合即可区分延迟来自代理层还是服务本身
+func generateQueueMetrics(name string, lbs labels.Labels, header http.Header, received time.Time) []metricstorage.ConstMetric {
+	d, ok := phttp.QueueTime(header, received)
+	if !ok {
+		return nil
+	}
+	return []metricstorage.ConstMetric{
+		metricstorage.NewHistogramConstMetric(name, d.Seconds(), metricstorage.UnitSeconds, lbs),
+	}
 }
@@ -25,12 +25,15 @@ func init() {
 }
 
 type http2Converter struct {
-	config CommonConfig
+	config     HTTPConfig
+	extractors []fieldExtractor
 }
 
 func newHTTP2Converter(config Config) converter {
+	extractors, _ := newFieldExtractors(config.HTTP2.Extract) // 规则已在 New 中校验
 	return &http2Converter{
-		config: config.HTTP2,
+		config:     config.HTTP2,
+		extractors: extractors,
 	}
 }
 
@@ -38,7 +41,7 @@ func (c *http2Converter) Proto() socket.L7Proto {
 	return socket.L7ProtoHTTP2
 }
 
-func (c *http2Converter) matchLabels(req *phttp2.Request, rsp *phttp2.Response) labels.Labels {
+func (c *http2Converter) matchLabels(req *phttp2.Request, rsp *phttp2.Response, class string) labels.Labels {
 	lbs := matchCommonLabels(c.config.RequireLabels, req.Host, rsp.Host, req.Port, rsp.Port)
 	for _, label := range c.config.RequireLabels {
 		switch label {
@@ -48,9 +51,15 @@ func (c *http2Converter) matchLabels(req *phttp2.Request, rsp *phttp2.Response)
 			lbs = append(lbs, labels.Label{Name: "path", Value: req.Path})
 		case "response.status_code":
 			lbs = append(lbs, labels.Label{Name: "status_code", Value: rsp.Status})
+		case "request.class":
+			lbs = append(lbs, labels.Label{Name: "class", Value: class})
+		case "request.route":
+			lbs = append(lbs, labels.Label{Name: "route", Value: operationRoute(req.Operation)})
+		case "request.operation_id":
+			lbs = append(lbs, labels.Label{Name: "operation_id", Value: operationID(req.Operation)})
 		}
 	}
-	return lbs
+	return appendExtractLabels(lbs, c.extractors, req.Header, req.Path)
 }
 
 var http2CommMetrics = commonMetrics{
@@ -60,10 +69,23 @@ var http2CommMetrics = commonMetrics{
 	responseBodySizeBytes:  "http2_response_body_bytes",
 }
 
+var http2StreamMetrics = streamMetrics{
+	concurrentStreams: "http2_concurrent_streams",
+	limitReachedTotal: "http2_stream_limit_reached_total",
+}
+
 func (c *http2Converter) Convert(rt socket.RoundTrip) []metricstorage.ConstMetric {
 	req := rt.Request().(*phttp2.Request)
 	rsp := rt.Response().(*phttp2.Response)
 
-	lbs := c.matchLabels(req, rsp)
-	return generateCommonMetrics(http2CommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
+	class := classifyRequest(c.config.Auxiliary.HealthPaths, req.Method, req.Path, req.Header)
+	lbs := c.matchLabels(req, rsp, class)
+	if class != requestClassNormal && !c.config.Auxiliary.Include {
+		return generateAuxiliaryMetrics("http2_auxiliary_requests_total", lbs, class)
+	}
+
+	metrics := generateCommonMetrics(http2CommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
+	reached := phttp2.StreamLimitReached(req.ConcurrentStreams, rsp.MaxConcurrentStreams)
+	metrics = append(metrics, generateStreamMetrics(http2StreamMetrics, lbs, req.ConcurrentStreams, reached)...)
+	return append(metrics, generateQueueMetrics("http2_request_queue_seconds", lbs, req.Header, req.Time)...)
 }
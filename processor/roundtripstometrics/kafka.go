@@ -14,6 +14,7 @@
 package roundtripstometrics
 
 import (
+	"slices"
 	"strconv"
 
 	"github.com/packetd/packetd/common/socket"
@@ -59,12 +60,127 @@ var kafkaCommMetrics = commonMetrics{
 	requestDurationSeconds: "kafka_request_duration_seconds",
 	requestBodySizeBytes:   "kafka_request_body_bytes",
 	responseBodySizeBytes:  "kafka_response_body_bytes",
+	oneWayTotal:            "kafka_oneway_requests_total",
 }
 
 func (c *kafkaConverter) Convert(rt socket.RoundTrip) []metricstorage.ConstMetric {
 	req := rt.Request().(*pkafka.Request)
 	rsp := rt.Response().(*pkafka.Response)
 
+	// SASL 认证阶段的请求单独统计 避免影响正常请求的耗时分布
+	if req.Packet.IsAuthentication() {
+		return c.convertAuthentication(rt, req, rsp)
+	}
+
+	// acks=0 的 Produce 没有响应 服务端地址由请求携带
+	if rsp == nil {
+		rsp = &pkafka.Response{Host: req.ServerHost, Port: req.ServerPort}
+		metrics := generateOneWayMetrics(kafkaCommMetrics, c.matchLabels(req, rsp), req.Size)
+		if stats := req.Packet.Produce; stats != nil {
+			metrics = append(metrics, c.convertProduce(rt, req, rsp, stats)...)
+		}
+		return metrics
+	}
+
 	lbs := c.matchLabels(req, rsp)
-	return generateCommonMetrics(kafkaCommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
+	metrics := generateCommonMetrics(kafkaCommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
+
+	// 使用远低于 Broker 支持版本的客户端 通常意味着需要升级
+	if req.Packet.Legacy {
+		legacyLbs := matchCommonLabels(c.config.RequireLabels, req.Host, rsp.Host, req.Port, rsp.Port)
+		legacyLbs = append(legacyLbs,
+			labels.Label{Name: "api", Value: req.Packet.API},
+			labels.Label{Name: "version", Value: strconv.Itoa(int(req.Packet.APIVersion))},
+		)
+		metrics = append(metrics, metricstorage.NewCounterConstMetric("kafka_legacy_version_requests_total", 1, legacyLbs))
+	}
+
+	if stats := req.Packet.Produce; stats != nil {
+		metrics = append(metrics, c.convertProduce(rt, req, rsp, stats)...)
+	}
+	if stats := rsp.Fetch; stats != nil {
+		metrics = append(metrics, c.convertFetch(req, rsp, stats)...)
+	}
+	return metrics
+}
+
+// convertProduce 生成 Produce 请求的批量写入指标
+//
+// 过小的 batch 或者过高的请求频率通常意味着 linger.ms / batch.size 配置不合理
+// acks 标签则用于区分 acks=all 带来的额外延迟
+func (c *kafkaConverter) convertProduce(rt socket.RoundTrip, req *pkafka.Request, rsp *pkafka.Response, stats *pkafka.ProduceStats) []metricstorage.ConstMetric {
+	acks := strconv.Itoa(int(stats.Acks))
+	if stats.Acks == -1 {
+		acks = "all"
+	}
+
+	lbs := matchCommonLabels(c.config.RequireLabels, req.Host, rsp.Host, req.Port, rsp.Port)
+	lbs = append(lbs, labels.Label{Name: "acks", Value: acks})
+
+	// client_id 由客户端自行设置 基数不可控 需显式开启
+	clientLbs := lbs
+	if slices.Contains(c.config.RequireLabels, "request.client_id") {
+		clientLbs = make(labels.Labels, 0, len(lbs)+1)
+		clientLbs = append(clientLbs, lbs...)
+		clientLbs = append(clientLbs, labels.Label{Name: "client_id", Value: req.Packet.ClientID})
+	}
+
+	metrics := make([]metricstorage.ConstMetric, 0, len(stats.BatchSizes)+5)
+	metrics = append(metrics,
+		metricstorage.NewCounterConstMetric("kafka_produce_requests_total", 1, clientLbs),
+		metricstorage.NewHistogramConstMetric("kafka_produce_partitions", float64(stats.Partitions), metricstorage.UnitCount, lbs),
+		metricstorage.NewHistogramConstMetric("kafka_produce_records", float64(stats.Records), metricstorage.UnitCount, lbs),
+	)
+	if !socket.IsOneWay(rt) {
+		metrics = append(metrics, metricstorage.NewHistogramConstMetric("kafka_produce_duration_seconds", rt.Duration().Seconds(), metricstorage.UnitSeconds, lbs))
+	}
+	for _, size := range stats.BatchSizes {
+		metrics = append(metrics, metricstorage.NewHistogramConstMetric("kafka_produce_batch_size_bytes", float64(size), metricstorage.UnitBytes, lbs))
+	}
+	return append(metrics, c.convertRecordBatches("produce", req, rsp, stats.Batches)...)
+}
+
+// convertFetch 生成 Fetch 响应的拉取指标 仅在开启 decodeRecordBatches 时存在
+func (c *kafkaConverter) convertFetch(req *pkafka.Request, rsp *pkafka.Response, stats *pkafka.FetchStats) []metricstorage.ConstMetric {
+	lbs := matchCommonLabels(c.config.RequireLabels, req.Host, rsp.Host, req.Port, rsp.Port)
+	metrics := []metricstorage.ConstMetric{
+		metricstorage.NewHistogramConstMetric("kafka_fetch_partitions", float64(stats.Partitions), metricstorage.UnitCount, lbs),
+		metricstorage.NewHistogramConstMetric("kafka_fetch_records", float64(stats.Records), metricstorage.UnitCount, lbs),
+		metricstorage.NewHistogramConstMetric("kafka_fetch_bytes", float64(stats.Bytes), metricstorage.UnitBytes, lbs),
+	}
+	return append(metrics, c.convertRecordBatches("fetch", req, rsp, stats.Batches)...)
+}
+
+// convertRecordBatches 按照压缩算法统计每个 RecordBatch 压缩前后的大小 两者之比即为压缩率
+func (c *kafkaConverter) convertRecordBatches(direction string, req *pkafka.Request, rsp *pkafka.Response, batches []pkafka.RecordBatch) []metricstorage.ConstMetric {
+	metrics := make([]metricstorage.ConstMetric, 0, len(batches)*2)
+	for _, batch := range batches {
+		lbs := matchCommonLabels(c.config.RequireLabels, req.Host, rsp.Host, req.Port, rsp.Port)
+		lbs = append(lbs,
+			labels.Label{Name: "direction", Value: direction},
+			labels.Label{Name: "compression", Value: batch.Compression},
+		)
+		metrics = append(metrics, metricstorage.NewHistogramConstMetric("kafka_record_batch_compressed_bytes", float64(batch.CompressedSize), metricstorage.UnitBytes, lbs))
+		if batch.UncompressedSize > 0 {
+			metrics = append(metrics, metricstorage.NewHistogramConstMetric("kafka_record_batch_uncompressed_bytes", float64(batch.UncompressedSize), metricstorage.UnitBytes, lbs))
+		}
+	}
+	return metrics
+}
+
+func (c *kafkaConverter) convertAuthentication(rt socket.RoundTrip, req *pkafka.Request, rsp *pkafka.Response) []metricstorage.ConstMetric {
+	lbs := matchCommonLabels(c.config.RequireLabels, req.Host, rsp.Host, req.Port, rsp.Port)
+	lbs = append(lbs,
+		labels.Label{Name: "api", Value: req.Packet.API},
+		labels.Label{Name: "error_code", Value: rsp.ErrorCode},
+	)
+
+	metrics := []metricstorage.ConstMetric{
+		metricstorage.NewCounterConstMetric("kafka_auth_requests_total", 1, lbs),
+		metricstorage.NewHistogramConstMetric("kafka_auth_duration_seconds", rt.Duration().Seconds(), metricstorage.UnitSeconds, lbs),
+	}
+	if rsp.ErrorCode != "NoError" {
+		metrics = append(metrics, metricstorage.NewCounterConstMetric("kafka_auth_failed_total", 1, lbs))
+	}
+	return metrics
 }
@@ -0,0 +1,85 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstometrics
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// KeepaliveConfig 保活语句的识别配置
+//
+// ORM 以及连接池会周期性地发送 `SELECT 1` / `SET autocommit` / `PING` 探测链接 这类语句数量多且耗时稳定
+// 混入常规指标后真实查询的分布会被淹没 默认单独计数
+type KeepaliveConfig struct {
+	// Patterns 保活语句的正则 匹配前会去除首尾空白以及末尾分号 为空时使用协议内置的规则
+	Patterns []string `config:"patterns" mapstructure:"patterns"`
+
+	// Include 为 true 时保活语句仍计入常规指标 可配合 request.class 维度自行过滤
+	Include bool `config:"include" mapstructure:"include"`
+
+	regexps []*regexp.Regexp
+}
+
+var (
+	defaultMySQLKeepalives = []string{
+		`^PING$`, // COM_PING 没有语句 以命令名匹配
+		`(?i)^select\s+1$`,
+		`(?i)^/\*\s*ping\s*\*/`, // Connector/J 的轻量 ping
+		`(?i)^set\s+(session\s+)?autocommit\s*=\s*\w+$`,
+	}
+
+	defaultPostgreSQLKeepalives = []string{
+		`^$`, // database/sql 驱动常以空语句探测
+		`(?i)^select\s+1$`,
+		`^--\s*ping$`, // pgx
+	}
+
+	defaultRedisKeepalives = []string{
+		`(?i)^ping$`,
+	}
+)
+
+// Validate 编译保活规则 未配置时使用 defaults
+func (kc *KeepaliveConfig) Validate(defaults []string) error {
+	if len(kc.Patterns) == 0 {
+		kc.Patterns = defaults
+	}
+
+	kc.regexps = kc.regexps[:0]
+	for _, pattern := range kc.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return errors.Wrapf(err, "invalid keepalive pattern '%s'", pattern)
+		}
+		kc.regexps = append(kc.regexps, re)
+	}
+	return nil
+}
+
+const requestClassKeepalive = "keepalive"
+
+// classify 返回语句类别 命中任一保活规则时为 keepalive
+func (kc *KeepaliveConfig) classify(statement string) string {
+	statement = strings.TrimSpace(statement)
+	statement = strings.TrimSpace(strings.TrimRight(statement, ";"))
+	for _, re := range kc.regexps {
+		if re.MatchString(statement) {
+			return requestClassKeepalive
+		}
+	}
+	return requestClassNormal
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstometrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeepaliveClassify(t *testing.T) {
+	tests := []struct {
+		name      string
+		defaults  []string
+		statement string
+		want      string
+	}{
+		{
+			name:      "MySQLSelectOne",
+			defaults:  defaultMySQLKeepalives,
+			statement: " select 1; ",
+			want:      requestClassKeepalive,
+		},
+		{
+			name:      "MySQLPing",
+			defaults:  defaultMySQLKeepalives,
+			statement: "PING",
+			want:      requestClassKeepalive,
+		},
+		{
+			name:      "MySQLConnectorJPing",
+			defaults:  defaultMySQLKeepalives,
+			statement: "/* ping */ SELECT 1",
+			want:      requestClassKeepalive,
+		},
+		{
+			name:      "MySQLAutocommit",
+			defaults:  defaultMySQLKeepalives,
+			statement: "SET autocommit=1",
+			want:      requestClassKeepalive,
+		},
+		{
+			name:      "MySQLQuery",
+			defaults:  defaultMySQLKeepalives,
+			statement: "SELECT 1 FROM orders",
+			want:      requestClassNormal,
+		},
+		{
+			name:      "PostgreSQLEmpty",
+			defaults:  defaultPostgreSQLKeepalives,
+			statement: ";",
+			want:      requestClassKeepalive,
+		},
+		{
+			name:      "PostgreSQLPgxPing",
+			defaults:  defaultPostgreSQLKeepalives,
+			statement: "-- ping",
+			want:      requestClassKeepalive,
+		},
+		{
+			name:      "RedisPing",
+			defaults:  defaultRedisKeepalives,
+			statement: "ping",
+			want:      requestClassKeepalive,
+		},
+		{
+			name:      "RedisGet",
+			defaults:  defaultRedisKeepalives,
+			statement: "GET",
+			want:      requestClassNormal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conf KeepaliveConfig
+			assert.NoError(t, conf.Validate(tt.defaults))
+			assert.Equal(t, tt.want, conf.classify(tt.statement))
+		})
+	}
+}
+
+func TestKeepaliveConfigValidate(t *testing.T) {
+	conf := KeepaliveConfig{Patterns: []string{`(?i)^select\s+now\(\)$`}}
+	assert.NoError(t, conf.Validate(defaultMySQLKeepalives))
+	assert.Equal(t, requestClassKeepalive, conf.classify("SELECT NOW()"))
+	assert.Equal(t, requestClassNormal, conf.classify("SELECT 1"))
+
+	conf = KeepaliveConfig{Patterns: []string{"("}}
+	assert.Error(t, conf.Validate(nil))
+}
@@ -48,6 +48,12 @@ func (c *mongodbConverter) matchLabels(req *pmongodb.Request, rsp *pmongodb.Resp
 			lbs = append(lbs, labels.Label{Name: "service", Value: req.CmdName})
 		case "request.source":
 			lbs = append(lbs, labels.Label{Name: "source", Value: req.Source})
+		case "request.read_concern":
+			lbs = append(lbs, labels.Label{Name: "read_concern", Value: req.ReadConcern})
+		case "request.write_concern":
+			lbs = append(lbs, labels.Label{Name: "write_concern", Value: req.WriteConcern})
+		case "request.query_shape":
+			lbs = append(lbs, labels.Label{Name: "query_shape", Value: req.QueryShape})
 		case "response.ok":
 			lbs = append(lbs, labels.Label{Name: "ok", Value: fmt.Sprintf("%.0f", rsp.Ok)})
 		}
@@ -67,5 +73,35 @@ func (c *mongodbConverter) Convert(rt socket.RoundTrip) []metricstorage.ConstMet
 	rsp := rt.Response().(*pmongodb.Response)
 
 	lbs := c.matchLabels(req, rsp)
-	return generateCommonMetrics(mangodbCommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
+	metrics := generateCommonMetrics(mangodbCommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
+	if len(req.Sequences) > 0 {
+		metrics = append(metrics, c.convertSequences(req, rsp)...)
+	}
+	if req.Txn != nil {
+		metrics = append(metrics, c.convertTransaction(req, rsp)...)
+	}
+	return metrics
+}
+
+// convertSequences 按照 identifier 统计文档序列中的文档数量 可区分单条写入与批量写入
+func (c *mongodbConverter) convertSequences(req *pmongodb.Request, rsp *pmongodb.Response) []metricstorage.ConstMetric {
+	metrics := make([]metricstorage.ConstMetric, 0, len(req.Sequences))
+	for _, seq := range req.Sequences {
+		lbs := c.matchLabels(req, rsp)
+		lbs = append(lbs, labels.Label{Name: "identifier", Value: seq.Identifier})
+		metrics = append(metrics, metricstorage.NewHistogramConstMetric("mongodb_request_documents", float64(seq.Documents), metricstorage.UnitCount, lbs))
+	}
+	return metrics
+}
+
+// convertTransaction 生成事务维度的指标 耗时从事务内首个请求开始计算
+func (c *mongodbConverter) convertTransaction(req *pmongodb.Request, rsp *pmongodb.Response) []metricstorage.ConstMetric {
+	lbs := matchCommonLabels(c.config.RequireLabels, req.Host, rsp.Host, req.Port, rsp.Port)
+	lbs = append(lbs, labels.Label{Name: "outcome", Value: req.Txn.Outcome})
+
+	return []metricstorage.ConstMetric{
+		metricstorage.NewCounterConstMetric("mongodb_transactions_total", 1, lbs),
+		metricstorage.NewCounterConstMetric("mongodb_transaction_operations_total", float64(req.Txn.Operations), lbs),
+		metricstorage.NewHistogramConstMetric("mongodb_transaction_duration_seconds", rsp.Time.Sub(req.Txn.StartTime).Seconds(), metricstorage.UnitSeconds, lbs),
+	}
 }
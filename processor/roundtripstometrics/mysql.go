@@ -25,7 +25,7 @@ func init() {
 }
 
 type mysqlConverter struct {
-	config CommonConfig
+	config StatementConfig
 }
 
 func newMySQLConverter(config Config) converter {
@@ -38,12 +38,16 @@ func (c *mysqlConverter) Proto() socket.L7Proto {
 	return socket.L7ProtoMySQL
 }
 
-func (c *mysqlConverter) matchLabels(req *pmysql.Request, rsp *pmysql.Response) labels.Labels {
+func (c *mysqlConverter) matchLabels(req *pmysql.Request, rsp *pmysql.Response, class string) labels.Labels {
 	lbs := matchCommonLabels(c.config.RequireLabels, req.Host, rsp.Host, req.Port, rsp.Port)
 	for _, label := range c.config.RequireLabels {
 		switch label {
 		case "request.command":
 			lbs = append(lbs, labels.Label{Name: "command", Value: req.Command})
+		case "request.database":
+			lbs = append(lbs, labels.Label{Name: "database", Value: req.Database})
+		case "request.class":
+			lbs = append(lbs, labels.Label{Name: "class", Value: class})
 		}
 	}
 	return lbs
@@ -60,7 +64,15 @@ func (c *mysqlConverter) Convert(rt socket.RoundTrip) []metricstorage.ConstMetri
 	req := rt.Request().(*pmysql.Request)
 	rsp := rt.Response().(*pmysql.Response)
 
-	lbs := c.matchLabels(req, rsp)
+	statement := req.Statement
+	if statement == "" {
+		statement = req.Command
+	}
+	class := c.config.Keepalive.classify(statement)
+	lbs := c.matchLabels(req, rsp, class)
+	if class != requestClassNormal && !c.config.Keepalive.Include {
+		return generateAuxiliaryMetrics("mysql_auxiliary_requests_total", lbs, class)
+	}
 	metrics := generateCommonMetrics(mysqlCommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
 
 	switch packet := rsp.Packet.(type) {
@@ -80,6 +92,13 @@ func (c *mysqlConverter) Convert(rt socket.RoundTrip) []metricstorage.ConstMetri
 			Unit:   metricstorage.UnitBytes,
 			Value:  float64(packet.Rows),
 		})
+		metrics = append(metrics, metricstorage.NewHistogramConstMetric("mysql_response_resultset_bytes", float64(packet.Bytes), metricstorage.UnitBytes, lbs))
+
+	case *pmysql.BinlogStreamPacket:
+		metrics = append(metrics, metricstorage.NewCounterConstMetric("mysql_binlog_events_total", float64(packet.Events), lbs))
+		if lag, ok := packet.Lag(rsp.Time); ok {
+			metrics = append(metrics, metricstorage.NewGaugeConstMetric("mysql_binlog_lag_seconds", lag.Seconds(), lbs))
+		}
 	}
 
 	return metrics
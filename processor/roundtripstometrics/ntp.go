@@ -0,0 +1,69 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstometrics
+
+import (
+	"strconv"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/labels"
+	"github.com/packetd/packetd/internal/metricstorage"
+	"github.com/packetd/packetd/protocol/pntp"
+)
+
+func init() {
+	register(socket.L7ProtoNTP, newNTPConverter)
+}
+
+type ntpConverter struct {
+	config CommonConfig
+}
+
+func newNTPConverter(config Config) converter {
+	return &ntpConverter{
+		config: config.NTP,
+	}
+}
+
+func (c *ntpConverter) Proto() socket.L7Proto {
+	return socket.L7ProtoNTP
+}
+
+func (c *ntpConverter) matchLabels(req *pntp.Request, rsp *pntp.Response) labels.Labels {
+	lbs := matchCommonLabels(c.config.RequireLabels, req.Host, rsp.Host, req.Port, rsp.Port)
+	for _, label := range c.config.RequireLabels {
+		switch label {
+		case "response.stratum":
+			lbs = append(lbs, labels.Label{Name: "stratum", Value: strconv.Itoa(int(rsp.Stratum))})
+		case "response.kiss_code":
+			lbs = append(lbs, labels.Label{Name: "kiss_code", Value: rsp.KissCode})
+		}
+	}
+	return lbs
+}
+
+var ntpCommMetrics = commonMetrics{
+	requestTotal:           "ntp_requests_total",
+	requestDurationSeconds: "ntp_request_duration_seconds",
+	requestBodySizeBytes:   "ntp_request_body_bytes",
+	responseBodySizeBytes:  "ntp_response_body_bytes",
+}
+
+func (c *ntpConverter) Convert(rt socket.RoundTrip) []metricstorage.ConstMetric {
+	req := rt.Request().(*pntp.Request)
+	rsp := rt.Response().(*pntp.Response)
+
+	lbs := c.matchLabels(req, rsp)
+	return generateCommonMetrics(ntpCommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
+}
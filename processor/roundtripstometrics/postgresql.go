@@ -25,7 +25,7 @@ func init() {
 }
 
 type postgresqlConverter struct {
-	config CommonConfig
+	config StatementConfig
 }
 
 func newPostgreSQLConverter(config Config) converter {
@@ -38,7 +38,7 @@ func (c *postgresqlConverter) Proto() socket.L7Proto {
 	return socket.L7ProtoPostgreSQL
 }
 
-func (c *postgresqlConverter) matchLabels(req *ppostgresql.Request, rsp *ppostgresql.Response) labels.Labels {
+func (c *postgresqlConverter) matchLabels(req *ppostgresql.Request, rsp *ppostgresql.Response, class string) labels.Labels {
 	var name string
 	namer, ok := req.Packet.(interface{ Name() string })
 	if ok {
@@ -50,6 +50,10 @@ func (c *postgresqlConverter) matchLabels(req *ppostgresql.Request, rsp *ppostgr
 		switch label {
 		case "request.command":
 			lbs = append(lbs, labels.Label{Name: "command", Value: name})
+		case "request.database":
+			lbs = append(lbs, labels.Label{Name: "database", Value: req.Database})
+		case "request.class":
+			lbs = append(lbs, labels.Label{Name: "class", Value: class})
 		}
 	}
 	return lbs
@@ -66,7 +70,15 @@ func (c *postgresqlConverter) Convert(rt socket.RoundTrip) []metricstorage.Const
 	req := rt.Request().(*ppostgresql.Request)
 	rsp := rt.Response().(*ppostgresql.Response)
 
-	lbs := c.matchLabels(req, rsp)
+	// 仅简单查询携带完整语句 扩展协议的 Parse / Bind / Execute 均视为常规请求
+	class := requestClassNormal
+	if query, ok := req.Packet.(*ppostgresql.QueryPacket); ok {
+		class = c.config.Keepalive.classify(query.Statement)
+	}
+	lbs := c.matchLabels(req, rsp, class)
+	if class != requestClassNormal && !c.config.Keepalive.Include {
+		return generateAuxiliaryMetrics("postgresql_auxiliary_requests_total", lbs, class)
+	}
 	metrics := generateCommonMetrics(postgresqlCommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
 
 	switch packet := rsp.Packet.(type) {
@@ -77,7 +89,26 @@ func (c *postgresqlConverter) Convert(rt socket.RoundTrip) []metricstorage.Const
 			Labels: lbs,
 			Value:  float64(packet.Rows),
 		})
+
+	case *ppostgresql.ReplicationStreamPacket:
+		if status, ok := req.Packet.(*ppostgresql.StandbyStatusPacket); ok {
+			metrics = append(metrics, generateReplicationMetrics(lbs, status, packet)...)
+		}
 	}
 
 	return metrics
 }
+
+// generateReplicationMetrics 生成流复制链接的 WAL 吞吐以及复制延迟指标
+//
+// 延迟为主库 WAL 末尾与备库已落盘位置之差 以字节计
+func generateReplicationMetrics(lbs labels.Labels, status *ppostgresql.StandbyStatusPacket, packet *ppostgresql.ReplicationStreamPacket) []metricstorage.ConstMetric {
+	slbs := make(labels.Labels, 0, len(lbs)+1)
+	slbs = append(slbs, lbs...)
+	slbs = append(slbs, labels.Label{Name: "slot", Value: status.Slot})
+
+	return []metricstorage.ConstMetric{
+		metricstorage.NewCounterConstMetric("postgresql_replication_wal_bytes_total", float64(packet.WALBytes), slbs),
+		metricstorage.NewGaugeConstMetric("postgresql_replication_lag_bytes", float64(packet.LagBytes(status)), slbs),
+	}
+}
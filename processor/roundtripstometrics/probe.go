@@ -0,0 +1,64 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package roundtripstometrics
+
+import (
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/labels"
+	"github.com/packetd/packetd/internal/metricstorage"
+	"github.com/packetd/packetd/internal/prober"
+)
+
+func init() {
+	register(socket.L7ProtoProbe, newProbeConverter)
+}
+
+type probeConverter struct {
+	config CommonConfig
+}
+
+func newProbeConverter(config Config) converter {
+	return &probeConverter{
+		config: config.Probe,
+	}
+}
+
+func (c *probeConverter) Proto() socket.L7Proto {
+	return socket.L7ProtoProbe
+}
+
+// matchLabels 探测名称以及类型为固定维度 其余维度与被动采集的指标保持一致 便于按照服务端关联对比
+func (c *probeConverter) matchLabels(req *prober.Request, rsp *prober.Response) labels.Labels {
+	lbs := labels.Labels{
+		{Name: "probe", Value: req.Name},
+		{Name: "type", Value: string(req.Type)},
+	}
+	return append(lbs, matchCommonLabels(c.config.RequireLabels, req.Host, rsp.Host, req.Port, rsp.Port)...)
+}
+
+func (c *probeConverter) Convert(rt socket.RoundTrip) []metricstorage.ConstMetric {
+	req := rt.Request().(*prober.Request)
+	rsp := rt.Response().(*prober.Response)
+
+	lbs := c.matchLabels(req, rsp)
+	verdictLbs := append(lbs[:len(lbs):len(lbs)], labels.Label{Name: "verdict", Value: string(rsp.Verdict)})
+
+	metrics := []metricstorage.ConstMetric{
+		metricstorage.NewCounterConstMetric("probe_requests_total", 1, verdictLbs),
+		metricstorage.NewHistogramConstMetric("probe_duration_seconds", rt.Duration().Seconds(), metricstorage.UnitSeconds, lbs),
+	}
+	if rsp.Connected && req.Type != prober.TypeDNS {
+		metrics = append(metrics, metricstorage.NewHistogramConstMetric("probe_connect_duration_seconds", rsp.Connect.Seconds(), metricstorage.UnitSeconds, lbs))
+	}
+	return metrics
+}
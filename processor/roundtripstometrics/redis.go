@@ -25,7 +25,7 @@ func init() {
 }
 
 type redisConverter struct {
-	config CommonConfig
+	config StatementConfig
 }
 
 func newRedisConverter(config Config) converter {
@@ -38,12 +38,14 @@ func (c *redisConverter) Proto() socket.L7Proto {
 	return socket.L7ProtoRedis
 }
 
-func (c *redisConverter) matchLabels(req *predis.Request, rsp *predis.Response) labels.Labels {
+func (c *redisConverter) matchLabels(req *predis.Request, rsp *predis.Response, class string) labels.Labels {
 	lbs := matchCommonLabels(c.config.RequireLabels, req.Host, rsp.Host, req.Port, rsp.Port)
 	for _, label := range c.config.RequireLabels {
 		switch label {
 		case "request.command":
 			lbs = append(lbs, labels.Label{Name: "command", Value: req.Command})
+		case "request.class":
+			lbs = append(lbs, labels.Label{Name: "class", Value: class})
 		}
 	}
 	return lbs
@@ -60,6 +62,15 @@ func (c *redisConverter) Convert(rt socket.RoundTrip) []metricstorage.ConstMetri
 	req := rt.Request().(*predis.Request)
 	rsp := rt.Response().(*predis.Response)
 
-	lbs := c.matchLabels(req, rsp)
+	// Sentinel 推送的事件没有请求耗时 交由 roundtripstoredistopology 处理
+	if rsp == nil {
+		return nil
+	}
+
+	class := c.config.Keepalive.classify(req.Command)
+	lbs := c.matchLabels(req, rsp, class)
+	if class != requestClassNormal && !c.config.Keepalive.Include {
+		return generateAuxiliaryMetrics("redis_auxiliary_requests_total", lbs, class)
+	}
 	return generateCommonMetrics(redisCommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
 }
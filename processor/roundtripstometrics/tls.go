@@ -0,0 +1,81 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstometrics
+
+import (
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/labels"
+	"github.com/packetd/packetd/internal/metricstorage"
+	"github.com/packetd/packetd/protocol/ptls"
+)
+
+func init() {
+	register(socket.L7ProtoTLS, newTLSConverter)
+}
+
+type tlsConverter struct {
+	config CommonConfig
+}
+
+func newTLSConverter(config Config) converter {
+	return &tlsConverter{
+		config: config.TLS,
+	}
+}
+
+func (c *tlsConverter) Proto() socket.L7Proto {
+	return socket.L7ProtoTLS
+}
+
+func (c *tlsConverter) matchLabels(req *ptls.Request, rsp *ptls.Response) labels.Labels {
+	lbs := matchCommonLabels(c.config.RequireLabels, req.Host, rsp.Host, req.Port, rsp.Port)
+	for _, label := range c.config.RequireLabels {
+		switch label {
+		case "request.server_name":
+			lbs = append(lbs, labels.Label{Name: "server_name", Value: req.ServerName})
+		case "request.ja3":
+			lbs = append(lbs, labels.Label{Name: "ja3", Value: req.JA3})
+		case "request.ja4":
+			lbs = append(lbs, labels.Label{Name: "ja4", Value: req.JA4})
+		case "response.version":
+			lbs = append(lbs, labels.Label{Name: "version", Value: rsp.Version})
+		case "response.cipher_suite":
+			lbs = append(lbs, labels.Label{Name: "cipher_suite", Value: rsp.CipherSuite})
+		}
+	}
+	return lbs
+}
+
+var tlsCommMetrics = commonMetrics{
+	requestTotal:           "tls_handshakes_total",
+	requestDurationSeconds: "tls_handshake_duration_seconds",
+	requestBodySizeBytes:   "tls_request_body_bytes",
+	responseBodySizeBytes:  "tls_response_body_bytes",
+}
+
+func (c *tlsConverter) Convert(rt socket.RoundTrip) []metricstorage.ConstMetric {
+	req := rt.Request().(*ptls.Request)
+	rsp := rt.Response().(*ptls.Response)
+
+	// 重协商事件没有响应 服务端地址由请求携带 与握手无关的维度均为空
+	if rsp == nil {
+		lbs := c.matchLabels(req, &ptls.Response{Host: req.ServerHost, Port: req.ServerPort})
+		return []metricstorage.ConstMetric{
+			metricstorage.NewCounterConstMetric("tls_renegotiations_total", 1, lbs),
+		}
+	}
+
+	lbs := c.matchLabels(req, rsp)
+	return generateCommonMetrics(tlsCommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
+}
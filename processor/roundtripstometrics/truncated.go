@@ -0,0 +1,45 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstometrics
+
+import (
+	"strconv"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/labels"
+	"github.com/packetd/packetd/internal/metricstorage"
+	"github.com/packetd/packetd/protocol"
+)
+
+var truncatedCommMetrics = commonMetrics{
+	requestTotal:           "truncated_capture_requests_total",
+	requestDurationSeconds: "truncated_capture_request_duration_seconds",
+	requestBodySizeBytes:   "truncated_capture_request_body_bytes",
+	responseBodySizeBytes:  "truncated_capture_response_body_bytes",
+}
+
+// convertTruncated 转换截断抓包产生的 RoundTrip
+//
+// 此类 RoundTrip 没有协议字段 各协议的 requireLabels 无法生效 固定以协议以及服务端地址作为维度
+func convertTruncated(rt socket.RoundTrip) []metricstorage.ConstMetric {
+	req := rt.Request().(*protocol.TruncatedMessage)
+	rsp := rt.Response().(*protocol.TruncatedMessage)
+
+	lbs := labels.Labels{
+		{Name: "proto", Value: string(rt.Proto())},
+		{Name: "server_address", Value: rsp.Host},
+		{Name: "server_port", Value: strconv.Itoa(int(rsp.Port))},
+	}
+	return generateCommonMetrics(truncatedCommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
+}
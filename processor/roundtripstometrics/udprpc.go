@@ -0,0 +1,52 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package roundtripstometrics
+
+import (
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/metricstorage"
+	"github.com/packetd/packetd/protocol/pudprpc"
+)
+
+func init() {
+	register(socket.L7ProtoUDPRPC, newUDPRPCConverter)
+}
+
+type udprpcConverter struct {
+	config CommonConfig
+}
+
+func newUDPRPCConverter(config Config) converter {
+	return &udprpcConverter{
+		config: config.UDPRPC,
+	}
+}
+
+func (c *udprpcConverter) Proto() socket.L7Proto {
+	return socket.L7ProtoUDPRPC
+}
+
+var udprpcCommMetrics = commonMetrics{
+	requestTotal:           "udprpc_requests_total",
+	requestDurationSeconds: "udprpc_request_duration_seconds",
+	requestBodySizeBytes:   "udprpc_request_body_bytes",
+	responseBodySizeBytes:  "udprpc_response_body_bytes",
+}
+
+func (c *udprpcConverter) Convert(rt socket.RoundTrip) []metricstorage.ConstMetric {
+	req := rt.Request().(*pudprpc.Request)
+	rsp := rt.Response().(*pudprpc.Response)
+
+	lbs := matchCommonLabels(c.config.RequireLabels, req.Host, rsp.Host, req.Port, rsp.Port)
+	return generateCommonMetrics(udprpcCommMetrics, lbs, rt.Duration().Seconds(), req.Size, rsp.Size)
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstoredistopology
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/mapstructure"
+	"github.com/packetd/packetd/internal/window"
+	"github.com/packetd/packetd/processor"
+	"github.com/packetd/packetd/protocol/predis"
+)
+
+const Name = "roundtripstoredistopology"
+
+const (
+	defaultWindow  = time.Minute
+	defaultMaxKeys = 1000
+)
+
+func init() {
+	processor.Register(Name, New)
+}
+
+type Config struct {
+	// Window 重定向的聚合窗口 同一窗口内重复的 Sentinel 事件仅输出一次
+	Window time.Duration `config:"window" mapstructure:"window"`
+
+	// MaxKeys 单独跟踪的节点数量上限 超出后不再跟踪新出现的节点
+	MaxKeys int `config:"maxKeys" mapstructure:"maxKeys"`
+}
+
+// Factory 从 Redis roundtrips 中识别拓扑变化并输出结构化事件
+//
+// 依赖 redis.trackTopology 解析出的 Sentinel 事件 / MOVED ASK 重定向 / CLUSTER SLOTS 摘要
+type Factory struct {
+	*window.Aggregator
+	tracker *tracker
+}
+
+func New(conf map[string]any) (processor.Processor, error) {
+	cfg := &Config{}
+	if err := mapstructure.Decode(conf, cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultWindow
+	}
+	if cfg.MaxKeys <= 0 {
+		cfg.MaxKeys = defaultMaxKeys
+	}
+
+	t := newTracker(*cfg)
+	return &Factory{
+		Aggregator: window.New(cfg.Window, t),
+		tracker:    t,
+	}, nil
+}
+
+func (f *Factory) Name() string {
+	return Name
+}
+
+// Process Sentinel 事件以及 slot 分布变化即时输出 重定向在窗口结束时汇总输出
+func (f *Factory) Process(record *common.Record) (*common.Record, error) {
+	rt, ok := record.Data.(socket.RoundTrip)
+	if !ok || rt.Proto() != socket.L7ProtoRedis {
+		return nil, nil
+	}
+	req, ok := rt.Request().(*predis.Request)
+	if !ok {
+		return nil, nil
+	}
+	rsp, _ := rt.Response().(*predis.Response)
+	if rsp == nil && req.Channel == "" {
+		return nil, nil
+	}
+	if rsp != nil && rsp.Redirect == nil && rsp.Digest == "" {
+		return nil, nil
+	}
+
+	return f.Observe(func(now time.Time) []any {
+		switch {
+		case rsp == nil:
+			return f.tracker.observeSentinel(now, endpoint(req.ServerHost, req.ServerPort), req.Channel, req.Message)
+		case rsp.Redirect != nil:
+			f.tracker.observeRedirect(endpoint(rsp.Host, rsp.Port), rsp.Redirect)
+			return nil
+		default:
+			return f.tracker.observeSlots(now, endpoint(rsp.Host, rsp.Port), rsp.Digest)
+		}
+	}), nil
+}
+
+func endpoint(host string, port uint16) string {
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
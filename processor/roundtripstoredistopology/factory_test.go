@@ -0,0 +1,116 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstoredistopology
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/window/windowtest"
+	"github.com/packetd/packetd/protocol/predis"
+)
+
+func newSentinel(channel, message string) *common.Record {
+	req := &predis.Request{Command: "MESSAGE", Channel: channel, Message: message, ServerHost: "10.0.0.5", ServerPort: 26379}
+	return windowtest.NewRecord(socket.L7ProtoRedis, req, nil, 0)
+}
+
+func newResponse(server string, rsp *predis.Response) *common.Record {
+	rsp.Host, rsp.Port = server, 6379
+	return windowtest.NewRecord(socket.L7ProtoRedis, &predis.Request{}, rsp, 0)
+}
+
+func TestFactoryProcess(t *testing.T) {
+	p, err := New(map[string]any{"window": "10s"})
+	require.NoError(t, err)
+
+	f := p.(*Factory)
+	clock := windowtest.NewClock()
+	start, now := clock.Start, clock.Start
+	f.SetClock(clock.Now)
+
+	process := func(r *common.Record) []any {
+		return windowtest.Events(t, windowtest.Process(t, f, r, 1))
+	}
+
+	// 普通请求不参与处理
+	assert.Nil(t, process(newResponse("10.0.0.1", &predis.Response{DataType: string(predis.SimpleStrings)})))
+
+	// 同一窗口内多个客户端收到的相同事件仅输出一次
+	const message = "mymaster 10.0.0.1 6379 10.0.0.2 6379"
+	assert.Equal(t, []any{
+		&SentinelEvent{
+			Event:    eventSentinel,
+			Time:     now,
+			Sentinel: "10.0.0.5:26379",
+			Name:     "+switch-master",
+			Message:  message,
+			Master:   "mymaster",
+			From:     "10.0.0.1:6379",
+			To:       "10.0.0.2:6379",
+		},
+	}, process(newSentinel("+switch-master", message)))
+	assert.Nil(t, process(newSentinel("+switch-master", message)))
+
+	// 节点首次出现时仅记录摘要
+	assert.Nil(t, process(newResponse("10.0.0.1", &predis.Response{Digest: "aaaa"})))
+	assert.Nil(t, process(newResponse("10.0.0.1", &predis.Response{Digest: "aaaa"})))
+	assert.Equal(t, []any{
+		&SlotsChanged{
+			Event:    eventSlotsChanged,
+			Time:     now,
+			Server:   "10.0.0.1:6379",
+			Previous: "aaaa",
+			Current:  "bbbb",
+		},
+	}, process(newResponse("10.0.0.1", &predis.Response{Digest: "bbbb"})))
+
+	for _, slot := range []int{3999, 4000, 3999} {
+		redirect := &predis.Redirect{Type: "MOVED", Slot: slot, Address: "10.0.0.3:6379"}
+		assert.Nil(t, process(newResponse("10.0.0.1", &predis.Response{Redirect: redirect})))
+	}
+	redirect := &predis.Redirect{Type: "ASK", Slot: 12, Address: "10.0.0.3:6379"}
+	assert.Nil(t, process(newResponse("10.0.0.1", &predis.Response{Redirect: redirect})))
+
+	// 窗口结束后汇总输出重定向 去重记录同时清空
+	now = clock.Advance(10 * time.Second)
+	events := process(newSentinel("+switch-master", message))
+	require.Len(t, events, 3)
+	assert.Equal(t, &Redirected{
+		Event:  eventRedirected,
+		Start:  start,
+		End:    now,
+		Server: "10.0.0.1:6379",
+		Type:   "ASK",
+		Target: "10.0.0.3:6379",
+		Count:  1,
+		Slots:  []int{12},
+	}, events[0])
+	assert.Equal(t, &Redirected{
+		Event:  eventRedirected,
+		Start:  start,
+		End:    now,
+		Server: "10.0.0.1:6379",
+		Type:   "MOVED",
+		Target: "10.0.0.3:6379",
+		Count:  3,
+		Slots:  []int{3999, 4000},
+	}, events[1])
+	assert.IsType(t, &SentinelEvent{}, events[2])
+}
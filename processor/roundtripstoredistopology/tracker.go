@@ -0,0 +1,208 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstoredistopology
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/packetd/packetd/internal/window"
+	"github.com/packetd/packetd/protocol/predis"
+)
+
+const (
+	eventSentinel     = "redis_sentinel_event"
+	eventSlotsChanged = "redis_cluster_slots_changed"
+	eventRedirected   = "redis_cluster_redirected"
+
+	// switchMaster Sentinel 完成主从切换后发布的事件
+	switchMaster = "+switch-master"
+
+	// maxSlots 单条重定向事件中列出的 slot 数量上限
+	maxSlots = 16
+)
+
+// SentinelEvent Sentinel 发布的事件 如 +sdown / +odown / +switch-master
+//
+// 仅 +switch-master 填充 Master / From / To 即切换前后的主节点地址
+type SentinelEvent struct {
+	Event    string
+	Time     time.Time
+	Sentinel string // Sentinel 地址 host:port
+	Name     string
+	Message  string
+	Master   string `json:",omitempty"`
+	From     string `json:",omitempty"`
+	To       string `json:",omitempty"`
+}
+
+// SlotsChanged 节点返回的 CLUSTER SLOTS 内容相对上一次发生了变化
+type SlotsChanged struct {
+	Event    string
+	Time     time.Time
+	Server   string
+	Previous string
+	Current  string
+}
+
+// Redirected 窗口内某个节点返回的 MOVED / ASK 重定向汇总
+type Redirected struct {
+	Event  string
+	Start  time.Time
+	End    time.Time
+	Server string // 返回重定向的节点
+	Type   string // MOVED / ASK
+	Target string // 重定向的目标节点
+	Count  int
+	Slots  []int // 涉及的 slot 最多列出 maxSlots 个
+}
+
+type redirectKey struct {
+	server string
+	typ    string
+	target string
+}
+
+type redirects struct {
+	count int
+	slots map[int]struct{}
+}
+
+// tracker 记录各节点的 slot 分布摘要以及窗口内的重定向
+//
+// 同一事件会推送给所有订阅了 Sentinel 的客户端 多个 Sentinel 也会各自发布 因此窗口内按照事件内容去重
+type tracker struct {
+	conf      Config
+	digests   map[string]string
+	redirects map[redirectKey]*redirects
+	seen      map[string]struct{}
+}
+
+func newTracker(conf Config) *tracker {
+	return &tracker{
+		conf:      conf,
+		digests:   make(map[string]string),
+		redirects: make(map[redirectKey]*redirects),
+		seen:      make(map[string]struct{}),
+	}
+}
+
+// observeSentinel 记录 Sentinel 事件 窗口内首次出现时返回
+func (t *tracker) observeSentinel(now time.Time, sentinel, name, message string) []any {
+	key := name + " " + message
+	if _, ok := t.seen[key]; ok || len(t.seen) >= t.conf.MaxKeys {
+		return nil
+	}
+	t.seen[key] = struct{}{}
+
+	event := &SentinelEvent{
+		Event:    eventSentinel,
+		Time:     now,
+		Sentinel: sentinel,
+		Name:     name,
+		Message:  message,
+	}
+
+	// +switch-master <master name> <oldip> <oldport> <newip> <newport>
+	if fields := strings.Fields(message); name == switchMaster && len(fields) == 5 {
+		event.Master = fields[0]
+		event.From = net.JoinHostPort(fields[1], fields[2])
+		event.To = net.JoinHostPort(fields[3], fields[4])
+	}
+	return []any{event}
+}
+
+// observeSlots 记录节点的 slot 分布摘要 节点首次出现时仅记录 不输出事件
+func (t *tracker) observeSlots(now time.Time, server, digest string) []any {
+	prev, ok := t.digests[server]
+	if !ok {
+		if len(t.digests) < t.conf.MaxKeys {
+			t.digests[server] = digest
+		}
+		return nil
+	}
+	if prev == digest {
+		return nil
+	}
+
+	t.digests[server] = digest
+	return []any{&SlotsChanged{
+		Event:    eventSlotsChanged,
+		Time:     now,
+		Server:   server,
+		Previous: prev,
+		Current:  digest,
+	}}
+}
+
+// observeRedirect 记录一次重定向 在窗口结束时汇总输出
+func (t *tracker) observeRedirect(server string, r *predis.Redirect) {
+	key := redirectKey{server: server, typ: r.Type, target: r.Address}
+	rs, ok := t.redirects[key]
+	if !ok {
+		if len(t.redirects) >= t.conf.MaxKeys {
+			return
+		}
+		rs = &redirects{slots: make(map[int]struct{})}
+		t.redirects[key] = rs
+	}
+	rs.count++
+	if len(rs.slots) < maxSlots {
+		rs.slots[r.Slot] = struct{}{}
+	}
+}
+
+// Flush 结束当前窗口 返回窗口内的重定向汇总并清空去重记录
+func (t *tracker) Flush(start, end time.Time) []any {
+	events := make([]*Redirected, 0, len(t.redirects))
+	for key, rs := range t.redirects {
+		slots := make([]int, 0, len(rs.slots))
+		for slot := range rs.slots {
+			slots = append(slots, slot)
+		}
+		sort.Ints(slots)
+		events = append(events, &Redirected{
+			Event:  eventRedirected,
+			Start:  start,
+			End:    end,
+			Server: key.server,
+			Type:   key.typ,
+			Target: key.target,
+			Count:  rs.count,
+			Slots:  slots,
+		})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if a.Server != b.Server {
+			return a.Server < b.Server
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Target < b.Target
+	})
+
+	t.redirects = make(map[redirectKey]*redirects)
+	t.seen = make(map[string]struct{})
+	return window.Events(events)
+}
+
+func (t *tracker) Reset() {
+	t.digests = make(map[string]string)
+	t.redirects = make(map[redirectKey]*redirects)
+	t.seen = make(map[string]struct{})
+}
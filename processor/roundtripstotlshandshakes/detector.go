@@ -0,0 +1,170 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstotlshandshakes
+
+import (
+	"sort"
+	"time"
+
+	"github.com/packetd/packetd/internal/window"
+)
+
+const (
+	// eventName 握手耗时劣化事件名称
+	eventName = "tls_handshake_degraded"
+
+	// baselineAlpha 基线 p99 的指数移动平均系数
+	baselineAlpha = 0.3
+
+	// quantile 参与比较的分位数
+	quantile = 0.99
+
+	// maxSamples 单个目的端在单个窗口内保留的耗时样本上限 超出后不再记录耗时 仅计数
+	maxSamples = 4096
+)
+
+type handshakeStats struct {
+	total         int
+	renegotiation int
+	samples       []time.Duration
+}
+
+// Degradation 单个窗口内某个目的端的 TLS 握手 p99 相对基线劣化
+type Degradation struct {
+	Event          string
+	Start          time.Time
+	End            time.Time
+	Destination    string // 服务端地址 host:port
+	Handshakes     int
+	Renegotiations int
+	P99            string
+	BaselineP99    string
+	Ratio          float64
+}
+
+// detector 按照窗口计算各目的端握手耗时的 p99 并与历史基线比较
+//
+// 与失败率不同 耗时没有天然的零值基线 因此目的端首次出现的窗口仅建立基线 不做检测
+type detector struct {
+	conf      Config
+	curr      map[string]*handshakeStats
+	baselines map[string]time.Duration
+}
+
+func newDetector(conf Config) *detector {
+	return &detector{
+		conf:      conf,
+		curr:      make(map[string]*handshakeStats),
+		baselines: make(map[string]time.Duration),
+	}
+}
+
+func (d *detector) stats(dst string) *handshakeStats {
+	w, ok := d.curr[dst]
+	if !ok {
+		if len(d.curr) >= d.conf.MaxKeys {
+			return nil
+		}
+		w = &handshakeStats{}
+		d.curr[dst] = w
+	}
+	return w
+}
+
+// observeHandshake 记录一次握手耗时
+func (d *detector) observeHandshake(dst string, duration time.Duration) {
+	if w := d.stats(dst); w != nil {
+		w.total++
+		if len(w.samples) < maxSamples {
+			w.samples = append(w.samples, duration)
+		}
+	}
+}
+
+// observeRenegotiation 记录一次重协商 仅作为劣化事件的上下文输出
+func (d *detector) observeRenegotiation(dst string) {
+	if w := d.stats(dst); w != nil {
+		w.renegotiation++
+	}
+}
+
+// percentile 返回样本的 q 分位数 samples 会被原地排序
+func percentile(samples []time.Duration, q float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	idx := int(float64(len(samples))*q+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(samples) {
+		idx = len(samples) - 1
+	}
+	return samples[idx]
+}
+
+// Flush 结束当前窗口 更新基线并按照 Destination 排序返回劣化事件
+func (d *detector) Flush(start, end time.Time) []any {
+	return window.Events(d.flush(start, end))
+}
+
+func (d *detector) Reset() {
+	d.curr = make(map[string]*handshakeStats)
+	d.baselines = make(map[string]time.Duration)
+}
+
+func (d *detector) flush(start, end time.Time) []*Degradation {
+	var degradations []*Degradation
+	for dst, w := range d.curr {
+		if w.total < d.conf.MinHandshakes {
+			continue // 样本不足时 p99 不可靠 同样不更新基线
+		}
+
+		p99 := percentile(w.samples, quantile)
+		prev, ok := d.baselines[dst]
+		if !ok {
+			if len(d.baselines) >= d.conf.MaxKeys {
+				d.baselines = make(map[string]time.Duration)
+			}
+			d.baselines[dst] = p99
+			continue
+		}
+
+		if p99 >= d.conf.MinDuration && float64(p99) >= float64(prev)*d.conf.Threshold {
+			var ratio float64
+			if prev > 0 {
+				ratio = float64(p99) / float64(prev)
+			}
+			degradations = append(degradations, &Degradation{
+				Event:          eventName,
+				Start:          start,
+				End:            end,
+				Destination:    dst,
+				Handshakes:     w.total,
+				Renegotiations: w.renegotiation,
+				P99:            p99.String(),
+				BaselineP99:    prev.String(),
+				Ratio:          ratio,
+			})
+		}
+		d.baselines[dst] = prev + time.Duration(baselineAlpha*float64(p99-prev))
+	}
+
+	sort.Slice(degradations, func(i, j int) bool {
+		return degradations[i].Destination < degradations[j].Destination
+	})
+	d.curr = make(map[string]*handshakeStats, len(d.curr))
+	return degradations
+}
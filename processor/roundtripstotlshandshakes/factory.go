@@ -0,0 +1,126 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstotlshandshakes
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/mapstructure"
+	"github.com/packetd/packetd/internal/window"
+	"github.com/packetd/packetd/processor"
+	"github.com/packetd/packetd/protocol/ptls"
+)
+
+const Name = "roundtripstotlshandshakes"
+
+const (
+	defaultWindow        = time.Minute
+	defaultMinHandshakes = 20
+	defaultMinDuration   = 100 * time.Millisecond
+	defaultThreshold     = 2.0
+	defaultMaxKeys       = 1000
+)
+
+func init() {
+	processor.Register(Name, New)
+}
+
+type Config struct {
+	// Window 检测窗口 每个窗口结束时计算各目的端握手耗时的 p99
+	Window time.Duration `config:"window" mapstructure:"window"`
+
+	// MinHandshakes 窗口内握手次数不低于该值时才参与检测
+	MinHandshakes int `config:"minHandshakes" mapstructure:"minHandshakes"`
+
+	// MinDuration p99 不低于该值时才视为劣化 避免毫秒级的波动触发告警
+	MinDuration time.Duration `config:"minDuration" mapstructure:"minDuration"`
+
+	// Threshold p99 达到基线的倍数时视为劣化
+	Threshold float64 `config:"threshold" mapstructure:"threshold"`
+
+	// MaxKeys 单独统计的目的端数量上限 超出后不再统计新出现的目的端
+	MaxKeys int `config:"maxKeys" mapstructure:"maxKeys"`
+}
+
+// Factory 跟踪各目的端的 TLS 握手耗时 在 p99 相对基线劣化时输出结构化事件
+//
+// TLS 卸载代理过载时首先表现为握手变慢 而应用侧指标从请求到达之后才开始计时 无法体现这部分延迟
+type Factory struct {
+	*window.Aggregator
+	detector *detector
+}
+
+func New(conf map[string]any) (processor.Processor, error) {
+	cfg := &Config{}
+	if err := mapstructure.Decode(conf, cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultWindow
+	}
+	if cfg.MinHandshakes <= 0 {
+		cfg.MinHandshakes = defaultMinHandshakes
+	}
+	if cfg.MinDuration <= 0 {
+		cfg.MinDuration = defaultMinDuration
+	}
+	if cfg.Threshold <= 1 {
+		cfg.Threshold = defaultThreshold
+	}
+	if cfg.MaxKeys <= 0 {
+		cfg.MaxKeys = defaultMaxKeys
+	}
+
+	d := newDetector(*cfg)
+	return &Factory{
+		Aggregator: window.New(cfg.Window, d),
+		detector:   d,
+	}, nil
+}
+
+func (f *Factory) Name() string {
+	return Name
+}
+
+// Process 记录握手耗时以及重协商 窗口结束时返回上一个窗口检测到的劣化事件
+func (f *Factory) Process(record *common.Record) (*common.Record, error) {
+	rt, ok := record.Data.(socket.RoundTrip)
+	if !ok || rt.Proto() != socket.L7ProtoTLS {
+		return nil, nil
+	}
+	req, ok := rt.Request().(*ptls.Request)
+	if !ok {
+		return nil, nil
+	}
+
+	rsp, _ := rt.Response().(*ptls.Response)
+	return f.Observe(func(time.Time) []any {
+		if rsp == nil {
+			if req.Renegotiation {
+				f.detector.observeRenegotiation(endpoint(req.ServerHost, req.ServerPort))
+			}
+			return nil
+		}
+		f.detector.observeHandshake(endpoint(rsp.Host, rsp.Port), rt.Duration())
+		return nil
+	}), nil
+}
+
+func endpoint(host string, port uint16) string {
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstotlshandshakes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/window/windowtest"
+	"github.com/packetd/packetd/protocol/ptls"
+)
+
+func newHandshake(server string, d time.Duration) *common.Record {
+	return windowtest.NewRecord(socket.L7ProtoTLS, &ptls.Request{}, &ptls.Response{Host: server, Port: 443}, d)
+}
+
+func newRenegotiation(server string) *common.Record {
+	return windowtest.NewRecord(socket.L7ProtoTLS, &ptls.Request{Renegotiation: true, ServerHost: server, ServerPort: 443}, nil, 0)
+}
+
+func TestFactoryProcess(t *testing.T) {
+	p, err := New(map[string]any{"window": "10s", "minHandshakes": 10})
+	require.NoError(t, err)
+
+	f := p.(*Factory)
+	clock := windowtest.NewClock()
+	start := clock.Start
+	f.SetClock(clock.Now)
+
+	process := func(r *common.Record, n int) *common.Record {
+		return windowtest.Process(t, f, r, n)
+	}
+
+	// 首个窗口仅建立基线
+	assert.Nil(t, process(newHandshake("10.0.0.1", 20*time.Millisecond), 20))
+	assert.Nil(t, process(newHandshake("10.0.0.2", 20*time.Millisecond), 20))
+
+	// 第二个窗口 10.0.0.1 的卸载代理过载 10.0.0.2 握手次数不足 minHandshakes 不参与检测
+	clock.Advance(10 * time.Second)
+	assert.Nil(t, process(newHandshake("10.0.0.1", 400*time.Millisecond), 20))
+	assert.Nil(t, process(newRenegotiation("10.0.0.1"), 3))
+	assert.Nil(t, process(newHandshake("10.0.0.2", time.Second), 5))
+
+	now := clock.Advance(10 * time.Second)
+	assert.Equal(t, []any{
+		&Degradation{
+			Event:          eventName,
+			Start:          start.Add(10 * time.Second),
+			End:            now,
+			Destination:    "10.0.0.1:443",
+			Handshakes:     20,
+			Renegotiations: 3,
+			P99:            "400ms",
+			BaselineP99:    "20ms",
+			Ratio:          20,
+		},
+	}, windowtest.Events(t, process(newHandshake("10.0.0.1", 20*time.Millisecond), 1)))
+}
+
+func TestDetectorSteadyLatency(t *testing.T) {
+	d := newDetector(Config{Window: time.Minute, MinHandshakes: 10, MinDuration: 100 * time.Millisecond, Threshold: 2, MaxKeys: 100})
+	now := time.Unix(1751356800, 0)
+
+	// 持续偏高的耗时逐步计入基线 不会重复告警 未超过 minDuration 的翻倍同样不告警
+	for w := 0; w < 5; w++ {
+		for i := 0; i < 20; i++ {
+			d.observeHandshake("10.0.0.1:443", 300*time.Millisecond)
+			d.observeHandshake("10.0.0.2:443", time.Duration(w+1)*10*time.Millisecond)
+		}
+		assert.Empty(t, d.flush(now, now.Add(time.Minute)))
+		now = now.Add(time.Minute)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var samples []time.Duration
+	for i := 100; i > 0; i-- {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, 99*time.Millisecond, percentile(samples, 0.99))
+	assert.Equal(t, 50*time.Millisecond, percentile(samples, 0.5))
+	assert.Equal(t, time.Duration(0), percentile(nil, 0.99))
+}
@@ -42,6 +42,12 @@ func (c *amqpConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 	req := rt.Request().(*pamqp.Request)
 	rsp := rt.Response().(*pamqp.Response)
 
+	// 单向事件没有响应 Span 的起止时间相同 服务端地址由请求携带
+	oneWay := rsp == nil
+	if oneWay {
+		rsp = &pamqp.Response{Host: req.ServerHost, Port: req.ServerPort, Time: req.Time, Size: req.Size}
+	}
+
 	name := req.ClassMethod.Class + "." + req.ClassMethod.Method
 	span := ptrace.NewSpan()
 	span.SetName(name)
@@ -63,10 +69,20 @@ func (c *amqpConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 	attr.PutStr("messaging.amqp.destination.exchange_name", packet.ExchangeName)
 	attr.PutStr("messaging.amqp.destination.queue_name", packet.QueueName)
 
+	// 发布时目的地为交换机 消费时则为队列
+	destination := packet.ExchangeName
+	if destination == "" {
+		destination = packet.QueueName
+	}
+	putMessagingDestination(attr, "rabbitmq", destination)
+
 	attr.PutStr("server.address", rsp.Host)
 	attr.PutInt("server.port", int64(rsp.Port))
 	attr.PutStr("network.peer.address", req.Host)
 	attr.PutInt("network.peer.port", int64(req.Port))
+	if oneWay {
+		attr.PutBool("packetd.oneway", true)
+	}
 
 	return span
 }
@@ -0,0 +1,84 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstotraces
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol/pgrpc"
+	"github.com/packetd/packetd/protocol/phttp"
+	"github.com/packetd/packetd/protocol/phttp2"
+)
+
+const correlationAttrPrefix = "packetd.correlation."
+
+// correlator 将关联头的值以统一的属性名写入 Span 便于与应用日志 join
+//
+// 请求头优先 请求中不存在时再从响应头中查找 适配由服务端生成 x-request-id 的场景
+type correlator struct {
+	headers []string
+	attrs   []string
+}
+
+func newCorrelator(headers []string) *correlator {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	c := &correlator{}
+	for _, h := range headers {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		c.headers = append(c.headers, h)
+		c.attrs = append(c.attrs, correlationAttrPrefix+strings.ToLower(h))
+	}
+	return c
+}
+
+func (c *correlator) attach(rt socket.RoundTrip, span ptrace.Span) {
+	req, rsp, ok := httpHeaders(rt)
+	if !ok {
+		return
+	}
+
+	attr := span.Attributes()
+	for i, h := range c.headers {
+		v := req.Get(h)
+		if v == "" {
+			v = rsp.Get(h)
+		}
+		if v != "" {
+			attr.PutStr(c.attrs[i], v)
+		}
+	}
+}
+
+// httpHeaders 返回 HTTP 族协议的请求头以及响应头
+func httpHeaders(rt socket.RoundTrip) (http.Header, http.Header, bool) {
+	switch req := rt.Request().(type) {
+	case *phttp.Request:
+		return req.Header, rt.Response().(*phttp.Response).Header, true
+	case *phttp2.Request:
+		return req.Header, rt.Response().(*phttp2.Response).Header, true
+	case *pgrpc.Request:
+		return req.Metadata, rt.Response().(*pgrpc.Response).Metadata, true
+	}
+	return nil, nil, false
+}
@@ -0,0 +1,50 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstotraces
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/protocol/phttp"
+)
+
+func TestCorrelationHeaders(t *testing.T) {
+	p, err := New(map[string]any{"correlationHeaders": []string{"X-Request-Id", "x-b3-traceid", "X-Tenant-Id", " "}})
+	require.NoError(t, err)
+
+	t0 := time.Unix(1751356800, 0)
+	req := http.Header{}
+	req.Set("X-Request-Id", "req-1")
+	req.Set("X-B3-TraceId", "80f198ee56343ba864fe8b2a57d3eff7")
+	rsp := http.Header{}
+	rsp.Set("X-Request-Id", "ignored")
+	rsp.Set("X-Tenant-Id", "tenant-a")
+
+	span := processSpan(t, p, common.NewRecord(common.RecordRoundTrips, httpRoundTrip{
+		req: &phttp.Request{Method: http.MethodGet, Header: req, Time: t0},
+		rsp: &phttp.Response{Header: rsp, StatusCode: http.StatusOK, Time: t0.Add(time.Millisecond)},
+	}))
+
+	attrs := span.Attributes().AsRaw()
+	assert.Equal(t, "req-1", attrs["packetd.correlation.x-request-id"])
+	assert.Equal(t, "80f198ee56343ba864fe8b2a57d3eff7", attrs["packetd.correlation.x-b3-traceid"])
+	assert.Equal(t, "tenant-a", attrs["packetd.correlation.x-tenant-id"])
+	assert.NotContains(t, attrs, "packetd.correlation.")
+}
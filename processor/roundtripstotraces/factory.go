@@ -14,15 +14,25 @@
 package roundtripstotraces
 
 import (
+	"sync"
+	"time"
+
 	"go.opentelemetry.io/collector/pdata/ptrace"
 
 	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/mapstructure"
 	"github.com/packetd/packetd/processor"
+	"github.com/packetd/packetd/protocol/pgrpc"
 )
 
 const Name = "roundtripstotraces"
 
+const (
+	defaultProxyLinkWindow  = 5 * time.Second
+	defaultPoolerLinkWindow = 10 * time.Second
+)
+
 func init() {
 	processor.Register(Name, New)
 }
@@ -38,10 +48,62 @@ func register(proto socket.L7Proto, converter converter) {
 	converters[proto] = converter
 }
 
-type Factory struct{}
+type Config struct {
+	ProxyLink ProxyLinkConfig `config:"proxyLink" mapstructure:"proxyLink"`
+
+	PoolerLink PoolerLinkConfig `config:"poolerLink" mapstructure:"poolerLink"`
+
+	// CorrelationHeaders 需要复制到 Span 属性中的关联头 仅对 HTTP / HTTP2 / gRPC 生效
+	CorrelationHeaders []string `config:"correlationHeaders" mapstructure:"correlationHeaders"`
+}
+
+type ProxyLinkConfig struct {
+	// Enabled 是否关联同一主机上代理前后两跳的 HTTP 请求
+	Enabled bool `config:"enabled" mapstructure:"enabled"`
+
+	// Window 入站请求与出站请求开始时间的最大间隔
+	Window time.Duration `config:"window" mapstructure:"window"`
+}
+
+type PoolerLinkConfig struct {
+	// Enabled 是否关联同一主机上连接池前后两段的 MySQL / PostgreSQL 请求
+	Enabled bool `config:"enabled" mapstructure:"enabled"`
+
+	// Window 前端请求与后端请求开始时间的最大间隔 包含了等待空闲后端链接的排队时间
+	Window time.Duration `config:"window" mapstructure:"window"`
+}
+
+type Factory struct {
+	mut          sync.Mutex
+	linker       *proxyLinker
+	poolerLinker *poolerLinker
+	correlator   *correlator
+}
+
+func New(conf map[string]any) (processor.Processor, error) {
+	cfg := &Config{}
+	if err := mapstructure.Decode(conf, cfg); err != nil {
+		return nil, err
+	}
 
-func New(_ map[string]any) (processor.Processor, error) {
-	return &Factory{}, nil
+	f := &Factory{
+		correlator: newCorrelator(cfg.CorrelationHeaders),
+	}
+	if cfg.ProxyLink.Enabled {
+		window := cfg.ProxyLink.Window
+		if window <= 0 {
+			window = defaultProxyLinkWindow
+		}
+		f.linker = newProxyLinker(window)
+	}
+	if cfg.PoolerLink.Enabled {
+		window := cfg.PoolerLink.Window
+		if window <= 0 {
+			window = defaultPoolerLinkWindow
+		}
+		f.poolerLinker = newPoolerLinker(window)
+	}
+	return f, nil
 }
 
 func (f *Factory) Name() string {
@@ -54,11 +116,53 @@ func (f *Factory) Process(record *common.Record) (*common.Record, error) {
 	if !ok {
 		return nil, nil
 	}
+	// 流式调用的增量消息统计并非一次完整的调用 不生成 Span
+	if req, ok := rt.Request().(*pgrpc.Request); ok && req.Progress != nil {
+		return nil, nil
+	}
+
+	var data ptrace.Span
+	if socket.IsTruncatedCapture(rt) {
+		data = convertTruncated(rt)
+	} else {
+		data = impl.Convert(rt)
+	}
+	data.Attributes().PutStr("packetd.event.id", socket.EventID(rt))
+	if q := socket.CaptureQuality(rt); q < 1 {
+		data.Attributes().PutDouble("packetd.capture.quality", q)
+	}
+	if socket.IsHalfOpen(rt) {
+		data.Attributes().PutBool("packetd.half_open", true)
+	}
+	if iface := socket.Iface(rt); iface != "" {
+		data.Attributes().PutStr("network.interface.name", iface)
+	}
+	if socket.IsInjected(rt) {
+		data.Attributes().PutBool("packetd.injected", true)
+	}
+	if f.correlator != nil {
+		f.correlator.attach(rt, data)
+	}
+	if f.linker != nil {
+		if h, ok := httpHop(rt); ok {
+			f.mut.Lock()
+			f.linker.link(h, data)
+			f.mut.Unlock()
+		}
+	}
+	if f.poolerLinker != nil {
+		if h, ok := databaseHop(rt); ok {
+			f.mut.Lock()
+			f.poolerLinker.link(h, data)
+			f.mut.Unlock()
+		}
+	}
 
-	data := impl.Convert(rt)
+	record.TraceID = data.TraceID()
+	record.SpanID = data.SpanID()
 	return &common.Record{
 		RecordType: common.RecordTraces,
-		Data:       &common.TracesData{Data: data},
+		Data:       &common.TracesData{Data: data, RoundTrip: rt},
 	}, nil
 }
 
@@ -44,12 +44,13 @@ func (c *grpcConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 	req := rt.Request().(*pgrpc.Request)
 	rsp := rt.Response().(*pgrpc.Response)
 
-	tc := extractTraceContext(req.Metadata, rsp.Metadata)
+	tc := extractTraceContext(req.Trace, rsp.Metadata)
 
 	span := ptrace.NewSpan()
 	span.SetName(req.Service)
 	span.SetTraceID(tc.TraceID)
 	span.SetParentSpanID(tc.SpanID)
+	span.TraceState().FromRaw(tc.State)
 	span.SetSpanID(tracekit.RandomSpanID())
 	span.SetStartTimestamp(pcommon.NewTimestampFromTime(req.Time))
 	span.SetEndTimestamp(pcommon.NewTimestampFromTime(rsp.Time))
@@ -61,6 +62,10 @@ func (c *grpcConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 	attr.PutStr("rpc.grpc.status_code", rsp.Status)
 	attr.PutInt("rpc.request.size", int64(req.Size))
 	attr.PutInt("rpc.response.size", int64(rsp.Size))
+	if req.Timeout > 0 {
+		attr.PutInt("packetd.grpc.timeout_ms", req.Timeout.Milliseconds())
+		attr.PutBool("packetd.grpc.past_deadline", rsp.PastDeadline)
+	}
 
 	attr.PutStr("server.address", rsp.Host)
 	attr.PutInt("server.port", int64(rsp.Port))
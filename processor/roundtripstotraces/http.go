@@ -22,6 +22,7 @@ import (
 
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/tracekit"
+	"github.com/packetd/packetd/protocol"
 	"github.com/packetd/packetd/protocol/phttp"
 )
 
@@ -41,8 +42,9 @@ func (c *httpConverter) Proto() socket.L7Proto {
 	return socket.L7ProtoHTTP
 }
 
-func extractTraceContext(req, rsp http.Header) tracekit.TraceContext {
-	if tc, ok := tracekit.TraceIDFromHTTPHeader(req); ok {
+// extractTraceContext 优先使用请求中解析出的 Trace 上下文 其次为响应 Header 均未携带时随机生成
+func extractTraceContext(req *protocol.TraceContext, rsp http.Header) tracekit.TraceContext {
+	if tc, ok := req.Context(); ok {
 		return tc
 	}
 	if tc, ok := tracekit.TraceIDFromHTTPHeader(rsp); ok {
@@ -55,12 +57,18 @@ func (c *httpConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 	req := rt.Request().(*phttp.Request)
 	rsp := rt.Response().(*phttp.Response)
 
-	tc := extractTraceContext(req.Header, rsp.Header)
+	tc := extractTraceContext(req.Trace, rsp.Header)
+
+	name := req.Method
+	if name == "" {
+		name = req.Proto // 不支持的版本仅保留了 Proto
+	}
 
 	span := ptrace.NewSpan()
-	span.SetName(req.Method)
+	span.SetName(name)
 	span.SetTraceID(tc.TraceID)
 	span.SetParentSpanID(tc.SpanID)
+	span.TraceState().FromRaw(tc.State)
 	span.SetSpanID(tracekit.RandomSpanID())
 	span.SetStartTimestamp(pcommon.NewTimestampFromTime(req.Time))
 	span.SetEndTimestamp(pcommon.NewTimestampFromTime(rsp.Time))
@@ -71,6 +79,7 @@ func (c *httpConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 	attr.PutStr("http.request.method", req.Method)
 	attr.PutInt("http.response.status_code", int64(rsp.StatusCode))
 
+	putRouteAttrs(attr, req.Operation)
 	attr.PutStr("url.full", req.URL)
 	attr.PutStr("url.scheme", req.Scheme)
 	attr.PutStr("server.address", rsp.Host)
@@ -96,5 +105,23 @@ func (c *httpConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 		}
 	}
 
+	if d, ok := phttp.QueueTime(req.Header, req.Time); ok {
+		attr.PutInt("packetd.http.queue_time_us", d.Microseconds())
+	}
+	putRPCAttrs(span, req.RPC)
+
+	// 客户端提前断开时 Body 并未传输完成
+	last := eventBodyComplete
+	if rsp.Outcome != "" {
+		last = eventClientAborted
+		attr.PutStr("packetd.http.outcome", rsp.Outcome)
+		attr.PutInt("packetd.http.expected_response_size", int64(rsp.ExpectedSize))
+	}
+
+	appendPhaseEvents(span, req.Time,
+		phase{name: eventFirstByte, t: rsp.FirstByteTime},
+		phase{name: eventHeadersComplete, t: rsp.HeaderTime},
+		phase{name: last, t: rsp.Time},
+	)
 	return span
 }
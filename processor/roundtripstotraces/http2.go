@@ -21,6 +21,7 @@ import (
 
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/tracekit"
+	"github.com/packetd/packetd/protocol/phttp"
 	"github.com/packetd/packetd/protocol/phttp2"
 )
 
@@ -44,12 +45,13 @@ func (c *http2Converter) Convert(rt socket.RoundTrip) ptrace.Span {
 	req := rt.Request().(*phttp2.Request)
 	rsp := rt.Response().(*phttp2.Response)
 
-	tc := extractTraceContext(req.Header, rsp.Header)
+	tc := extractTraceContext(req.Trace, rsp.Header)
 
 	span := ptrace.NewSpan()
 	span.SetName(req.Method)
 	span.SetTraceID(tc.TraceID)
 	span.SetParentSpanID(tc.SpanID)
+	span.TraceState().FromRaw(tc.State)
 	span.SetSpanID(tracekit.RandomSpanID())
 	span.SetStartTimestamp(pcommon.NewTimestampFromTime(req.Time))
 	span.SetEndTimestamp(pcommon.NewTimestampFromTime(rsp.Time))
@@ -60,6 +62,7 @@ func (c *http2Converter) Convert(rt socket.RoundTrip) ptrace.Span {
 	attr.PutStr("http.request.method", req.Method)
 	attr.PutStr("http.response.status_code", rsp.Status)
 
+	putRouteAttrs(attr, req.Operation)
 	attr.PutStr("url.full", req.Path)
 	attr.PutStr("server.address", rsp.Host)
 	attr.PutInt("server.port", int64(rsp.Port))
@@ -82,5 +85,14 @@ func (c *http2Converter) Convert(rt socket.RoundTrip) ptrace.Span {
 		}
 	}
 
+	if d, ok := phttp.QueueTime(req.Header, req.Time); ok {
+		attr.PutInt("packetd.http.queue_time_us", d.Microseconds())
+	}
+	putRPCAttrs(span, req.RPC)
+
+	// 流未正常结束即被回收
+	if req.Outcome != "" || rsp.Outcome != "" {
+		attr.PutStr("packetd.http.outcome", phttp2.OutcomeIncomplete)
+	}
 	return span
 }
@@ -44,6 +44,12 @@ func (c *kafkaConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 	req := rt.Request().(*pkafka.Request)
 	rsp := rt.Response().(*pkafka.Response)
 
+	// acks=0 的 Produce 没有响应 Span 的起止时间相同 服务端地址由请求携带
+	oneWay := rsp == nil
+	if oneWay {
+		rsp = &pkafka.Response{Host: req.ServerHost, Port: req.ServerPort, Time: req.Time, Size: req.Size}
+	}
+
 	packet := req.Packet
 
 	span := ptrace.NewSpan()
@@ -59,6 +65,13 @@ func (c *kafkaConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 	attr.PutStr("messaging.operation.version", strconv.Itoa(int(packet.APIVersion)))
 	attr.PutStr("messaging.client.id", packet.ClientID)
 	attr.PutStr("messaging.consumer.group.name", packet.GroupID)
+	putMessagingDestination(attr, "kafka", packet.Topic)
+	if packet.LastTopic != "" {
+		attr.PutStr("packetd.kafka.last_topic", packet.LastTopic)
+	}
+	if packet.MaxVersion > 0 {
+		attr.PutInt("packetd.kafka.max_version", int64(packet.MaxVersion))
+	}
 	attr.PutInt("messaging.message.body.size", int64(rsp.Size))
 
 	attr.PutStr("error.type", rsp.ErrorCode)
@@ -66,6 +79,40 @@ func (c *kafkaConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 	attr.PutInt("server.port", int64(rsp.Port))
 	attr.PutStr("network.peer.address", req.Host)
 	attr.PutInt("network.peer.port", int64(req.Port))
+	if oneWay {
+		attr.PutBool("packetd.oneway", true)
+	}
+	if stats := packet.Produce; stats != nil && len(stats.Batches) > 0 {
+		putRecordBatches(attr, stats.Records, stats.Batches)
+	}
+	if stats := rsp.Fetch; stats != nil {
+		putRecordBatches(attr, stats.Records, stats.Batches)
+	}
 
 	return span
 }
+
+// putRecordBatches 记录涉及的分区 压缩算法以及压缩前后的总大小
+func putRecordBatches(attr pcommon.Map, records int, batches []pkafka.RecordBatch) {
+	attr.PutInt("messaging.batch.message_count", int64(records))
+
+	var compressed, uncompressed int
+	partitions := attr.PutEmptySlice("packetd.kafka.partitions")
+	compressions := attr.PutEmptySlice("packetd.kafka.compressions")
+	seenPartitions := make(map[int32]struct{})
+	seenCompressions := make(map[string]struct{})
+	for _, batch := range batches {
+		compressed += batch.CompressedSize
+		uncompressed += batch.UncompressedSize
+		if _, ok := seenPartitions[batch.Partition]; !ok {
+			seenPartitions[batch.Partition] = struct{}{}
+			partitions.AppendEmpty().SetInt(int64(batch.Partition))
+		}
+		if _, ok := seenCompressions[batch.Compression]; !ok {
+			seenCompressions[batch.Compression] = struct{}{}
+			compressions.AppendEmpty().SetStr(batch.Compression)
+		}
+	}
+	attr.PutInt("packetd.kafka.compressed_bytes", int64(compressed))
+	attr.PutInt("packetd.kafka.uncompressed_bytes", int64(uncompressed))
+}
@@ -0,0 +1,234 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstotraces
+
+import (
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/tracekit"
+	"github.com/packetd/packetd/protocol/phttp"
+	"github.com/packetd/packetd/protocol/phttp2"
+)
+
+const (
+	headerRequestID = "X-Request-Id"
+
+	// proxyHostTTL 主机被识别为 HTTP 服务端后的有效期
+	proxyHostTTL = 5 * time.Minute
+
+	// maxProxyLinks 单个入站 Span 最多携带的 Span Link 数量
+	maxProxyLinks = 16
+)
+
+// hop 描述一次 HTTP 请求在本机上的一跳
+type hop struct {
+	key    string // 关联标识 优先使用 x-request-id 其次为 traceparent 中的 trace-id 与 parent-id
+	client string
+	server string
+	start  time.Time
+	end    time.Time
+
+	// propagated 请求携带了 traceparent 对应的 Span 已经以其 parent-id 作为父 Span
+	propagated bool
+}
+
+// httpHop 从 HTTP / HTTP2 RoundTrip 中提取 hop 无关联标识时返回 false
+func httpHop(rt socket.RoundTrip) (hop, bool) {
+	var h hop
+	var header http.Header
+	switch req := rt.Request().(type) {
+	case *phttp.Request:
+		header, h.client, h.start = req.Header, req.Host, req.Time
+	case *phttp2.Request:
+		header, h.client, h.start = req.Header, req.Host, req.Time
+	default:
+		return h, false
+	}
+
+	switch rsp := rt.Response().(type) {
+	case *phttp.Response:
+		h.server, h.end = rsp.Host, rsp.Time
+	case *phttp2.Response:
+		h.server, h.end = rsp.Host, rsp.Time
+	default:
+		return h, false
+	}
+
+	// 同一 trace 内可能同时存在多个经过该主机的请求 仅使用 trace-id 会相互冲突
+	// 未植入探针的代理原样透传 traceparent 入站请求与出站请求的 parent-id 相同
+	tc, ok := tracekit.TraceIDFromHTTPHeader(header)
+	h.propagated = ok
+	h.key = header.Get(headerRequestID)
+	if h.key == "" && ok {
+		h.key = tc.TraceID.String() + "-" + tc.SpanID.String()
+	}
+	return h, h.key != ""
+}
+
+type reservationKey struct {
+	host string
+	key  string
+}
+
+// reservation 为尚未完成的入站请求预留的 Span 身份
+//
+// 出站请求总是先于入站请求完成 因此由出站请求预先生成父 Span 的 SpanID
+// 待入站请求完成时认领该 SpanID 从而形成父子关系
+//
+// 出站请求已经携带父 Span（traceparent）时不能再覆盖 仅记录其 Span 由入站 Span 以 Span Link 关联
+type reservation struct {
+	traceID pcommon.TraceID
+	spanID  pcommon.SpanID // 为空表示没有需要认领的父 Span
+	links   []spanRef
+	start   time.Time // 子请求中最早的开始时间
+	end     time.Time // 子请求中最晚的结束时间
+}
+
+type spanRef struct {
+	traceID pcommon.TraceID
+	spanID  pcommon.SpanID
+}
+
+// proxyLinker 关联同一主机上的入站请求和出站请求
+//
+// 当主机 A 作为服务端接收到请求 并在 window 内作为客户端发出携带相同关联标识的请求时
+// 将后者作为前者的子 Span 适用于 nginx / envoy 等无法植入探针的代理节点
+type proxyLinker struct {
+	window       time.Duration
+	proxies      map[string]time.Time // 近期作为 HTTP 服务端出现过的主机
+	reservations map[reservationKey][]*reservation
+	lastGC       time.Time
+}
+
+func newProxyLinker(window time.Duration) *proxyLinker {
+	return &proxyLinker{
+		window:       window,
+		proxies:      make(map[string]time.Time),
+		reservations: make(map[reservationKey][]*reservation),
+	}
+}
+
+// link 按需调整 span 的 TraceID / SpanID / ParentSpanID 或者追加 Span Link
+//
+// 时间均以数据包时间为准 而非处理时的系统时间
+func (l *proxyLinker) link(h hop, span ptrace.Span) {
+	l.gc(h.end)
+
+	// 作为入站请求 认领在时间上被其包含的子请求
+	if r := l.claim(reservationKey{host: h.server, key: h.key}, h); r != nil {
+		if !r.spanID.IsEmpty() {
+			span.SetTraceID(r.traceID)
+			span.SetSpanID(r.spanID)
+		}
+		for _, ref := range r.links {
+			link := span.Links().AppendEmpty()
+			link.SetTraceID(ref.traceID)
+			link.SetSpanID(ref.spanID)
+		}
+	}
+	l.proxies[h.server] = h.end
+
+	// 作为出站请求 仅当客户端主机近期作为服务端出现过才预留父 Span
+	if _, ok := l.proxies[h.client]; !ok {
+		return
+	}
+	r := l.reserve(reservationKey{host: h.client, key: h.key}, h)
+	if h.propagated {
+		if len(r.links) < maxProxyLinks {
+			r.links = append(r.links, spanRef{traceID: span.TraceID(), spanID: span.SpanID()})
+		}
+		return
+	}
+	if r.spanID.IsEmpty() {
+		r.traceID = span.TraceID()
+		r.spanID = tracekit.RandomSpanID()
+	}
+	span.SetTraceID(r.traceID)
+	span.SetParentSpanID(r.spanID)
+}
+
+// reserve 返回出站请求所属的预留 开始时间相距超过 window 的同名请求视为不同的入站请求
+func (l *proxyLinker) reserve(rk reservationKey, h hop) *reservation {
+	for _, r := range l.reservations[rk] {
+		if d := h.start.Sub(r.start); d <= l.window && d >= -l.window {
+			if h.start.Before(r.start) {
+				r.start = h.start
+			}
+			if h.end.After(r.end) {
+				r.end = h.end
+			}
+			return r
+		}
+	}
+
+	r := &reservation{start: h.start, end: h.end}
+	l.reservations[rk] = append(l.reservations[rk], r)
+	return r
+}
+
+// claim 取出被入站请求在时间上包含的预留 不存在时返回 nil
+func (l *proxyLinker) claim(rk reservationKey, h hop) *reservation {
+	rs := l.reservations[rk]
+	for i, r := range rs {
+		if !l.encloses(h, r) {
+			continue
+		}
+		if len(rs) == 1 {
+			delete(l.reservations, rk)
+		} else {
+			l.reservations[rk] = append(rs[:i:i], rs[i+1:]...)
+		}
+		return r
+	}
+	return nil
+}
+
+// encloses 判断入站请求是否在时间上包含了所有子请求
+func (l *proxyLinker) encloses(h hop, r *reservation) bool {
+	if r.start.Before(h.start) || r.end.After(h.end) {
+		return false
+	}
+	return r.start.Sub(h.start) <= l.window
+}
+
+func (l *proxyLinker) gc(now time.Time) {
+	if now.Sub(l.lastGC) < l.window {
+		return
+	}
+	l.lastGC = now
+
+	for k, rs := range l.reservations {
+		live := rs[:0]
+		for _, r := range rs {
+			if now.Sub(r.end) <= l.window {
+				live = append(live, r)
+			}
+		}
+		if len(live) == 0 {
+			delete(l.reservations, k)
+			continue
+		}
+		l.reservations[k] = live
+	}
+	for host, t := range l.proxies {
+		if now.Sub(t) > proxyHostTTL {
+			delete(l.proxies, host)
+		}
+	}
+}
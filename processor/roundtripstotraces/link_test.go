@@ -0,0 +1,125 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstotraces
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/processor"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/phttp"
+)
+
+type httpRoundTrip struct {
+	req *phttp.Request
+	rsp *phttp.Response
+}
+
+func (rt httpRoundTrip) Proto() socket.L7Proto   { return socket.L7ProtoHTTP }
+func (rt httpRoundTrip) Request() any            { return rt.req }
+func (rt httpRoundTrip) Response() any           { return rt.rsp }
+func (rt httpRoundTrip) Duration() time.Duration { return rt.rsp.Time.Sub(rt.req.Time) }
+func (rt httpRoundTrip) Validate() bool          { return true }
+
+func newHTTPRecord(client, server, requestID string, start, end time.Time) *common.Record {
+	header := http.Header{}
+	header.Set("X-Request-Id", requestID)
+	return common.NewRecord(common.RecordRoundTrips, httpRoundTrip{
+		req: &phttp.Request{Host: client, Method: http.MethodGet, Header: header, Time: start},
+		rsp: &phttp.Response{Host: server, Header: http.Header{}, StatusCode: http.StatusOK, Time: end},
+	})
+}
+
+func newTracedRecord(client, server, traceparent string, start, end time.Time) *common.Record {
+	header := http.Header{}
+	header.Set("traceparent", traceparent)
+	return common.NewRecord(common.RecordRoundTrips, httpRoundTrip{
+		req: &phttp.Request{Host: client, Method: http.MethodGet, Header: header, Trace: protocol.ParseTraceContext(header), Time: start},
+		rsp: &phttp.Response{Host: server, Header: http.Header{}, StatusCode: http.StatusOK, Time: end},
+	})
+}
+
+func processSpan(t *testing.T, p processor.Processor, r *common.Record) ptrace.Span {
+	ret, err := p.Process(r)
+	require.NoError(t, err)
+	return ret.Data.(*common.TracesData).Data
+}
+
+func TestProxyLink(t *testing.T) {
+	p, err := New(map[string]any{"proxyLink": map[string]any{"enabled": true, "window": "1s"}})
+	require.NoError(t, err)
+
+	t0 := time.Unix(1751356800, 0)
+	ms := func(n int) time.Time { return t0.Add(time.Duration(n) * time.Millisecond) }
+
+	// 先完成一次请求使 10.0.0.2 被识别为服务端
+	processSpan(t, p, newHTTPRecord("10.0.0.1", "10.0.0.2", "warmup", ms(0), ms(5)))
+
+	// 10.0.0.2 作为代理转发请求至 10.0.0.3 出站请求先于入站请求完成
+	child := processSpan(t, p, newHTTPRecord("10.0.0.2", "10.0.0.3", "req-1", ms(12), ms(20)))
+	parent := processSpan(t, p, newHTTPRecord("10.0.0.1", "10.0.0.2", "req-1", ms(10), ms(22)))
+
+	assert.Equal(t, parent.TraceID(), child.TraceID())
+	assert.Equal(t, parent.SpanID(), child.ParentSpanID())
+
+	// 关联标识不同的请求互不影响
+	other := processSpan(t, p, newHTTPRecord("10.0.0.1", "10.0.0.2", "req-2", ms(30), ms(40)))
+	assert.NotEqual(t, parent.TraceID(), other.TraceID())
+
+	// 关联标识相同但时间上不被包含的入站请求不会认领
+	processSpan(t, p, newHTTPRecord("10.0.0.2", "10.0.0.3", "req-3", ms(52), ms(60)))
+	stale := processSpan(t, p, newHTTPRecord("10.0.0.1", "10.0.0.2", "req-3", ms(61), ms(70)))
+	later := processSpan(t, p, newHTTPRecord("10.0.0.2", "10.0.0.3", "req-3", ms(2000), ms(2010)))
+	assert.NotEqual(t, stale.SpanID(), later.ParentSpanID())
+}
+
+func TestProxyLinkPropagated(t *testing.T) {
+	p, err := New(map[string]any{"proxyLink": map[string]any{"enabled": true, "window": "1s"}})
+	require.NoError(t, err)
+
+	t0 := time.Unix(1751356800, 0)
+	ms := func(n int) time.Time { return t0.Add(time.Duration(n) * time.Millisecond) }
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	processSpan(t, p, newHTTPRecord("10.0.0.1", "10.0.0.2", "warmup", ms(0), ms(5)))
+
+	// 出站请求沿用 traceparent 中的父 Span 不被覆盖 入站 Span 以 Span Link 关联
+	child := processSpan(t, p, newTracedRecord("10.0.0.2", "10.0.0.3", traceparent, ms(12), ms(20)))
+	assert.Equal(t, "00f067aa0ba902b7", child.ParentSpanID().String())
+
+	parent := processSpan(t, p, newTracedRecord("10.0.0.1", "10.0.0.2", traceparent, ms(10), ms(22)))
+	assert.Equal(t, "00f067aa0ba902b7", parent.ParentSpanID().String())
+	require.Equal(t, 1, parent.Links().Len())
+	assert.Equal(t, child.SpanID(), parent.Links().At(0).SpanID())
+	assert.Equal(t, child.TraceID(), parent.Links().At(0).TraceID())
+}
+
+func TestProxyLinkDisabled(t *testing.T) {
+	p, err := New(nil)
+	require.NoError(t, err)
+
+	t0 := time.Unix(1751356800, 0)
+	processSpan(t, p, newHTTPRecord("10.0.0.1", "10.0.0.2", "req-1", t0, t0.Add(time.Millisecond)))
+	child := processSpan(t, p, newHTTPRecord("10.0.0.2", "10.0.0.3", "req-1", t0.Add(2*time.Millisecond), t0.Add(3*time.Millisecond)))
+	parent := processSpan(t, p, newHTTPRecord("10.0.0.1", "10.0.0.2", "req-1", t0.Add(time.Millisecond), t0.Add(4*time.Millisecond)))
+	assert.NotEqual(t, parent.SpanID(), child.ParentSpanID())
+}
@@ -53,11 +53,24 @@ func (c *mongodbConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 	attr.PutStr("db.system.name", "mongodb")
 	attr.PutStr("db.query.text", req.CmdValue)
 	attr.PutStr("db.operation.name", req.CmdName)
+	putDBLegacyAttrs(attr, "mongodb", req.CmdValue)
 	attr.PutInt("db.request.size", int64(req.Size))
 	attr.PutInt("db.response.size", int64(rsp.Size))
 	attr.PutStr("db.namespace", req.Source)
 	attr.PutInt("db.response.status_code", int64(rsp.Code))
 	attr.PutDouble("db.response.ok", rsp.Ok)
+	if req.ReadConcern != "" {
+		attr.PutStr("db.mongodb.read_concern", req.ReadConcern)
+	}
+	if req.WriteConcern != "" {
+		attr.PutStr("db.mongodb.write_concern", req.WriteConcern)
+	}
+	if req.QueryShape != "" {
+		attr.PutStr("db.query.summary", req.CmdName+" "+req.QueryShape)
+	}
+	if len(req.Sequences) > 0 {
+		putDocumentSequences(attr, req.Sequences)
+	}
 
 	attr.PutStr("error.type", rsp.Message)
 	attr.PutStr("server.address", rsp.Host)
@@ -67,3 +80,18 @@ func (c *mongodbConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 
 	return span
 }
+
+// putDocumentSequences 记录文档序列的 identifier 以及文档总数
+//
+// 按照语义规范 仅包含两个及以上操作时才视为批量操作 输出 db.operation.batch.size
+func putDocumentSequences(attr pcommon.Map, seqs []pmongodb.DocumentSequence) {
+	var documents int
+	lst := attr.PutEmptySlice("db.mongodb.document_sequences")
+	for _, seq := range seqs {
+		lst.AppendEmpty().SetStr(seq.Identifier)
+		documents += seq.Documents
+	}
+	if documents >= 2 {
+		attr.PutInt("db.operation.batch.size", int64(documents))
+	}
+}
@@ -53,6 +53,11 @@ func (c *mysqlConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 	attr.PutStr("db.system.name", "mysql")
 	attr.PutStr("db.query.text", req.Statement)
 	attr.PutStr("db.operation.name", req.Command)
+	if req.Database != "" {
+		attr.PutStr("db.namespace", req.Database)
+	}
+	putDBLegacyAttrs(attr, "mysql", req.Statement)
+	applySQLComment(span, req.Comment)
 	attr.PutInt("db.request.size", int64(req.Size))
 	attr.PutInt("db.response.size", int64(rsp.Size))
 
@@ -64,6 +69,8 @@ func (c *mysqlConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 	switch packet := rsp.Packet.(type) {
 	case *pmysql.ResultSetPacket:
 		attr.PutInt("db.response.returned_rows", int64(packet.Rows))
+		attr.PutInt("db.response.returned_columns", int64(packet.Columns))
+		attr.PutInt("db.response.returned_bytes", int64(packet.Bytes))
 
 	case *pmysql.ErrorPacket:
 		attr.PutStr("error.type", packet.ErrMsg)
@@ -75,6 +82,14 @@ func (c *mysqlConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 		attr.PutInt("db.response.last_insert_id", int64(packet.LastInsertID))
 		attr.PutInt("db.response.warnings", int64(packet.Warnings))
 		attr.PutInt("db.response.info", int64(packet.Status))
+
+	case *pmysql.BinlogStreamPacket:
+		attr.PutInt("db.mysql.binlog.events", int64(packet.Events))
+		attr.PutInt("db.mysql.binlog.heartbeats", int64(packet.Heartbeats))
+		attr.PutInt("db.mysql.binlog.log_pos", int64(packet.LogPos))
+		if lag, ok := packet.Lag(rsp.Time); ok {
+			attr.PutInt("db.mysql.binlog.lag_ms", lag.Milliseconds())
+		}
 	}
 
 	return span
@@ -0,0 +1,65 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstotraces
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/tracekit"
+	"github.com/packetd/packetd/protocol/pntp"
+)
+
+func init() {
+	register(socket.L7ProtoNTP, newNTPConverter())
+}
+
+type ntpConverter struct{}
+
+func newNTPConverter() converter {
+	return &ntpConverter{}
+}
+
+func (c *ntpConverter) Proto() socket.L7Proto {
+	return socket.L7ProtoNTP
+}
+
+func (c *ntpConverter) Convert(rt socket.RoundTrip) ptrace.Span {
+	req := rt.Request().(*pntp.Request)
+	rsp := rt.Response().(*pntp.Response)
+
+	span := ptrace.NewSpan()
+	span.SetName(req.Mode)
+	span.SetTraceID(tracekit.RandomTraceID())
+	span.SetSpanID(tracekit.RandomSpanID())
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(req.Time))
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(rsp.Time))
+
+	attr := span.Attributes()
+	attr.PutInt("ntp.version", int64(req.Version))
+	attr.PutInt("ntp.stratum", int64(rsp.Stratum))
+	attr.PutInt("ntp.leap", int64(rsp.Leap))
+	attr.PutStr("ntp.reference_id", rsp.ReferenceID)
+	if rsp.KissCode != "" {
+		attr.PutStr("ntp.kiss_code", rsp.KissCode)
+	}
+
+	attr.PutStr("server.address", rsp.Host)
+	attr.PutInt("server.port", int64(rsp.Port))
+	attr.PutStr("network.peer.address", req.Host)
+	attr.PutInt("network.peer.port", int64(req.Port))
+
+	return span
+}
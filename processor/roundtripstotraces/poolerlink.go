@@ -0,0 +1,209 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstotraces
+
+import (
+	"hash/fnv"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/tracekit"
+	"github.com/packetd/packetd/protocol/pmysql"
+	"github.com/packetd/packetd/protocol/ppostgresql"
+)
+
+// maxPoolerPending 同一语句指纹最多等待认领的后端请求数量
+const maxPoolerPending = 64
+
+// dbHop 描述一次数据库请求在本机上的一跳
+type dbHop struct {
+	system      socket.L7Proto
+	fingerprint uint64
+	client      string
+	server      string
+	upstream    string // 服务端地址:端口
+	start       time.Time
+	end         time.Time
+}
+
+// databaseHop 从 MySQL / PostgreSQL RoundTrip 中提取 dbHop 无语句时返回 false
+func databaseHop(rt socket.RoundTrip) (dbHop, bool) {
+	h := dbHop{system: rt.Proto()}
+
+	var statement string
+	var port uint16
+	switch req := rt.Request().(type) {
+	case *pmysql.Request:
+		rsp := rt.Response().(*pmysql.Response)
+		statement = req.Statement
+		h.client, h.start = req.Host, req.Time
+		h.server, h.end, port = rsp.Host, rsp.Time, rsp.Port
+
+	case *ppostgresql.Request:
+		switch packet := req.Packet.(type) {
+		case *ppostgresql.QueryPacket:
+			statement = packet.Statement
+		case *ppostgresql.CommandCompletePacket:
+			statement = packet.Command
+		}
+		rsp := rt.Response().(*ppostgresql.Response)
+		h.client, h.start = req.Host, req.Time
+		h.server, h.end, port = rsp.Host, rsp.Time, rsp.Port
+
+	default:
+		return h, false
+	}
+
+	if statement == "" {
+		return h, false
+	}
+	h.fingerprint = statementFingerprint(statement)
+	h.upstream = net.JoinHostPort(h.server, strconv.Itoa(int(port)))
+	return h, true
+}
+
+// statementFingerprint 计算语句指纹
+//
+// 连接池会原样转发语句 仅需忽略大小写以及空白字符的差异 保留字面量以降低不同请求之间的碰撞
+func statementFingerprint(s string) uint64 {
+	h := fnv.New64a()
+	for i, field := range strings.Fields(s) {
+		if i > 0 {
+			_, _ = h.Write([]byte{' '})
+		}
+		_, _ = h.Write([]byte(strings.ToLower(field)))
+	}
+	return h.Sum64()
+}
+
+type poolerKey struct {
+	host        string
+	system      socket.L7Proto
+	fingerprint uint64
+}
+
+// poolerReservation 后端请求为尚未完成的前端请求预留的 Span 身份
+type poolerReservation struct {
+	traceID  pcommon.TraceID
+	spanID   pcommon.SpanID
+	upstream string
+	start    time.Time
+	end      time.Time
+}
+
+// poolerLinker 关联同一主机上连接池前后两段的数据库请求
+//
+// pgbouncer / ProxySQL 等连接池作为服务端接收应用的请求 随后作为客户端将相同语句转发给数据库
+// 两段请求按照语句指纹以及时间包含关系进行配对 后端请求作为前端请求的子 Span
+// 前端请求的耗时减去后端请求的耗时即为连接池引入的延迟（排队等待后端链接以及转发开销）
+type poolerLinker struct {
+	window  time.Duration
+	poolers map[string]time.Time // 近期作为数据库服务端出现过的主机
+	pending map[poolerKey][]*poolerReservation
+	lastGC  time.Time
+}
+
+func newPoolerLinker(window time.Duration) *poolerLinker {
+	return &poolerLinker{
+		window:  window,
+		poolers: make(map[string]time.Time),
+		pending: make(map[poolerKey][]*poolerReservation),
+	}
+}
+
+// link 按需调整 span 的 TraceID / SpanID / ParentSpanID 并为前端请求写入连接池延迟
+func (l *poolerLinker) link(h dbHop, span ptrace.Span) {
+	l.gc(h.end)
+
+	// 作为前端请求 认领时间上被包含的最早一次后端请求
+	pk := poolerKey{host: h.server, system: h.system, fingerprint: h.fingerprint}
+	if r, ok := l.claim(pk, h); ok {
+		span.SetTraceID(r.traceID)
+		span.SetSpanID(r.spanID)
+
+		added := h.end.Sub(h.start) - r.end.Sub(r.start)
+		attr := span.Attributes()
+		attr.PutStr("packetd.pooler.upstream", r.upstream)
+		attr.PutInt("packetd.pooler.added_latency_ms", added.Milliseconds())
+	}
+	l.poolers[h.server] = h.end
+
+	// 作为后端请求 仅当客户端主机近期作为数据库服务端出现过才预留父 Span
+	if _, ok := l.poolers[h.client]; !ok {
+		return
+	}
+	pk = poolerKey{host: h.client, system: h.system, fingerprint: h.fingerprint}
+	if len(l.pending[pk]) >= maxPoolerPending {
+		return
+	}
+	r := &poolerReservation{
+		traceID:  span.TraceID(),
+		spanID:   tracekit.RandomSpanID(),
+		upstream: h.upstream,
+		start:    h.start,
+		end:      h.end,
+	}
+	l.pending[pk] = append(l.pending[pk], r)
+	span.SetParentSpanID(r.spanID)
+}
+
+// claim 查找并移除被前端请求 h 在时间上包含的预留
+func (l *poolerLinker) claim(pk poolerKey, h dbHop) (*poolerReservation, bool) {
+	lst := l.pending[pk]
+	for i, r := range lst {
+		if r.start.Before(h.start) || r.end.After(h.end) || r.start.Sub(h.start) > l.window {
+			continue
+		}
+		if len(lst) == 1 {
+			delete(l.pending, pk)
+		} else {
+			l.pending[pk] = append(lst[:i:i], lst[i+1:]...)
+		}
+		return r, true
+	}
+	return nil, false
+}
+
+func (l *poolerLinker) gc(now time.Time) {
+	if now.Sub(l.lastGC) < l.window {
+		return
+	}
+	l.lastGC = now
+
+	for k, lst := range l.pending {
+		n := 0
+		for _, r := range lst {
+			if now.Sub(r.end) <= l.window {
+				lst[n] = r
+				n++
+			}
+		}
+		if n == 0 {
+			delete(l.pending, k)
+			continue
+		}
+		l.pending[k] = lst[:n]
+	}
+	for host, t := range l.poolers {
+		if now.Sub(t) > proxyHostTTL {
+			delete(l.poolers, host)
+		}
+	}
+}
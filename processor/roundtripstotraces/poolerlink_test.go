@@ -0,0 +1,85 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstotraces
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol/pmysql"
+)
+
+type mysqlRoundTrip struct {
+	req *pmysql.Request
+	rsp *pmysql.Response
+}
+
+func (rt mysqlRoundTrip) Proto() socket.L7Proto   { return socket.L7ProtoMySQL }
+func (rt mysqlRoundTrip) Request() any            { return rt.req }
+func (rt mysqlRoundTrip) Response() any           { return rt.rsp }
+func (rt mysqlRoundTrip) Duration() time.Duration { return rt.rsp.Time.Sub(rt.req.Time) }
+func (rt mysqlRoundTrip) Validate() bool          { return true }
+
+func newMySQLRecord(client, server string, port uint16, statement string, start, end time.Time) *common.Record {
+	return common.NewRecord(common.RecordRoundTrips, mysqlRoundTrip{
+		req: &pmysql.Request{Host: client, Command: "COM_QUERY", Statement: statement, Time: start},
+		rsp: &pmysql.Response{Host: server, Port: port, Packet: &pmysql.OKPacket{}, Time: end},
+	})
+}
+
+func TestStatementFingerprint(t *testing.T) {
+	assert.Equal(t, statementFingerprint("SELECT *  FROM t\nWHERE id = 1"), statementFingerprint("select * from t where id = 1"))
+	assert.NotEqual(t, statementFingerprint("select * from t where id = 1"), statementFingerprint("select * from t where id = 2"))
+}
+
+func TestPoolerLink(t *testing.T) {
+	p, err := New(map[string]any{"poolerLink": map[string]any{"enabled": true, "window": "1s"}})
+	require.NoError(t, err)
+
+	t0 := time.Unix(1751356800, 0)
+	ms := func(n int) time.Time { return t0.Add(time.Duration(n) * time.Millisecond) }
+	const query = "SELECT * FROM users WHERE id = 1"
+
+	// 先完成一次请求使 10.0.0.2 被识别为数据库服务端（连接池）
+	processSpan(t, p, newMySQLRecord("10.0.0.1", "10.0.0.2", 6033, "SELECT 1", ms(0), ms(5)))
+
+	// 连接池 10.0.0.2 将语句转发至数据库 10.0.0.3 后端请求先于前端请求完成
+	backend := processSpan(t, p, newMySQLRecord("10.0.0.2", "10.0.0.3", 3306, query, ms(15), ms(20)))
+	frontend := processSpan(t, p, newMySQLRecord("10.0.0.1", "10.0.0.2", 6033, query, ms(10), ms(22)))
+
+	assert.Equal(t, frontend.TraceID(), backend.TraceID())
+	assert.Equal(t, frontend.SpanID(), backend.ParentSpanID())
+
+	attr := frontend.Attributes()
+	upstream, ok := attr.Get("packetd.pooler.upstream")
+	require.True(t, ok)
+	assert.Equal(t, "10.0.0.3:3306", upstream.Str())
+	added, ok := attr.Get("packetd.pooler.added_latency_ms")
+	require.True(t, ok)
+	assert.Equal(t, int64(7), added.Int())
+
+	// 语句不同或者时间上不包含的请求不会被关联
+	processSpan(t, p, newMySQLRecord("10.0.0.2", "10.0.0.3", 3306, query, ms(35), ms(45)))
+	other := processSpan(t, p, newMySQLRecord("10.0.0.1", "10.0.0.2", 6033, "SELECT 2", ms(30), ms(50)))
+	late := processSpan(t, p, newMySQLRecord("10.0.0.1", "10.0.0.2", 6033, query, ms(40), ms(50)))
+	_, ok = other.Attributes().Get("packetd.pooler.upstream")
+	assert.False(t, ok)
+	_, ok = late.Attributes().Get("packetd.pooler.upstream")
+	assert.False(t, ok)
+}
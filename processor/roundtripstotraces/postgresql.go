@@ -14,6 +14,8 @@
 package roundtripstotraces
 
 import (
+	"strconv"
+
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 
@@ -58,6 +60,9 @@ func (c *postgresqlConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 	attr := span.Attributes()
 	attr.PutStr("db.system.name", "postgresql")
 	attr.PutStr("db.operation.name", name)
+	if req.Database != "" {
+		attr.PutStr("db.namespace", req.Database)
+	}
 	attr.PutInt("db.request.size", int64(req.Size))
 	attr.PutInt("db.response.size", int64(rsp.Size))
 
@@ -65,12 +70,23 @@ func (c *postgresqlConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 	attr.PutInt("server.port", int64(rsp.Port))
 	attr.PutStr("network.peer.address", req.Host)
 	attr.PutInt("network.peer.port", int64(req.Port))
+	applySQLComment(span, req.Comment)
 
+	var statement string
 	switch packet := req.Packet.(type) {
 	case *ppostgresql.QueryPacket:
+		statement = packet.Statement
 		attr.PutStr("db.query.text", packet.Statement)
+		for i, param := range packet.Params {
+			// 已脱敏的参数仅保留长度 NULL 参数不输出
+			if param.Size < 0 || (param.Value == "" && param.Size > 0) {
+				continue
+			}
+			attr.PutStr("db.query.parameter."+strconv.Itoa(i), param.Value)
+		}
 
 	case *ppostgresql.CommandCompletePacket:
+		statement = packet.Command
 		attr.PutStr("db.query.text", packet.Command)
 		attr.PutInt("db.response.returned_rows", int64(packet.Rows))
 
@@ -81,7 +97,21 @@ func (c *postgresqlConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 
 	case *ppostgresql.FlagPacket:
 		attr.PutStr("db.packet.flag", packet.Flag)
+
+	case *ppostgresql.StartReplicationPacket:
+		attr.PutStr("db.postgresql.replication.slot", packet.Slot)
+		attr.PutStr("db.postgresql.replication.start_lsn", packet.StartLSN.String())
+
+	case *ppostgresql.StandbyStatusPacket:
+		attr.PutStr("db.postgresql.replication.slot", packet.Slot)
+		attr.PutStr("db.postgresql.replication.flush_lsn", packet.FlushLSN.String())
+		if stream, ok := rsp.Packet.(*ppostgresql.ReplicationStreamPacket); ok {
+			attr.PutStr("db.postgresql.replication.wal_end", stream.WALEnd.String())
+			attr.PutInt("db.postgresql.replication.wal_bytes", int64(stream.WALBytes))
+			attr.PutInt("db.postgresql.replication.lag_bytes", stream.LagBytes(packet))
+		}
 	}
+	putDBLegacyAttrs(attr, "postgresql", statement)
 
 	return span
 }
@@ -41,6 +41,9 @@ func (c *redisConverter) Proto() socket.L7Proto {
 func (c *redisConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 	req := rt.Request().(*predis.Request)
 	rsp := rt.Response().(*predis.Response)
+	if rsp == nil {
+		return c.convertSentinelEvent(req)
+	}
 
 	span := ptrace.NewSpan()
 	span.SetName(req.Command)
@@ -52,6 +55,7 @@ func (c *redisConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 	attr := span.Attributes()
 	attr.PutStr("db.system.name", "redis")
 	attr.PutStr("db.operation.name", req.Command)
+	putDBLegacyAttrs(attr, "redis", req.Command)
 	attr.PutInt("db.request.size", int64(req.Size))
 	attr.PutInt("db.response.size", int64(rsp.Size))
 
@@ -60,5 +64,34 @@ func (c *redisConverter) Convert(rt socket.RoundTrip) ptrace.Span {
 	attr.PutStr("network.peer.address", req.Host)
 	attr.PutInt("network.peer.port", int64(req.Port))
 	attr.PutStr("response.data_type", rsp.DataType)
+	if r := rsp.Redirect; r != nil {
+		attr.PutStr("packetd.redis.redirect.type", r.Type)
+		attr.PutInt("packetd.redis.redirect.slot", int64(r.Slot))
+		attr.PutStr("packetd.redis.redirect.address", r.Address)
+	}
+	if rsp.Digest != "" {
+		attr.PutStr("packetd.redis.slots_digest", rsp.Digest)
+	}
+	return span
+}
+
+// convertSentinelEvent Sentinel 推送的事件没有请求耗时 以零时长的 span 输出
+func (c *redisConverter) convertSentinelEvent(req *predis.Request) ptrace.Span {
+	span := ptrace.NewSpan()
+	span.SetName(req.Channel)
+	span.SetTraceID(tracekit.RandomTraceID())
+	span.SetSpanID(tracekit.RandomSpanID())
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(req.Time))
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(req.Time))
+
+	attr := span.Attributes()
+	attr.PutStr("db.system.name", "redis")
+	attr.PutStr("db.operation.name", req.Command)
+	attr.PutStr("packetd.redis.sentinel.event", req.Channel)
+	attr.PutStr("packetd.redis.sentinel.message", req.Message)
+	attr.PutStr("server.address", req.ServerHost)
+	attr.PutInt("server.port", int64(req.ServerPort))
+	attr.PutStr("network.peer.address", req.Host)
+	attr.PutInt("network.peer.port", int64(req.Port))
 	return span
 }
@@ -0,0 +1,122 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstotraces
+
+import (
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/packetd/packetd/protocol"
+)
+
+// 各阶段对应的 span event 名称
+const (
+	eventFirstByte       = "first_byte"
+	eventHeadersComplete = "headers_complete"
+	eventBodyComplete    = "body_complete"
+	eventClientAborted   = "client_aborted"
+)
+
+type phase struct {
+	name string
+	t    time.Time
+}
+
+// appendPhaseEvents 将请求的子阶段以 span event 的形式追加到 span 上
+//
+// 未记录的阶段（零值时间）直接跳过 event 中附带距离 span 开始时间的偏移量
+func appendPhaseEvents(span ptrace.Span, start time.Time, phases ...phase) {
+	for _, p := range phases {
+		if p.t.IsZero() {
+			continue
+		}
+		event := span.Events().AppendEmpty()
+		event.SetName(p.name)
+		event.SetTimestamp(pcommon.NewTimestampFromTime(p.t))
+		event.Attributes().PutInt("packetd.phase.offset_us", p.t.Sub(start).Microseconds())
+	}
+}
+
+// putDBLegacyAttrs 写入旧版本语义规范中的数据库字段
+//
+// 部分后端仍然依赖 db.system / db.statement 识别数据库调用
+func putDBLegacyAttrs(attr pcommon.Map, system, statement string) {
+	attr.PutStr("db.system", system)
+	if statement != "" {
+		attr.PutStr("db.statement", statement)
+	}
+}
+
+// putMessagingDestination 写入消息目的地字段 destination 为空时不写入
+func putMessagingDestination(attr pcommon.Map, system, destination string) {
+	attr.PutStr("messaging.system", system)
+	if destination == "" {
+		return
+	}
+	attr.PutStr("messaging.destination.name", destination)
+	attr.PutStr("messaging.destination", destination)
+}
+
+// putRouteAttrs 写入 http.route
+//
+// 语义约定要求 http.route 为低基数的路由模板 仅在匹配到 OpenAPI 接口时写入 原始路径已记录在 url.full 中
+func putRouteAttrs(attr pcommon.Map, op *protocol.Operation) {
+	if op == nil {
+		return
+	}
+	attr.PutStr("http.route", op.Route)
+	if op.ID != "" {
+		attr.PutStr("packetd.http.operation_id", op.ID)
+	}
+}
+
+// putRPCAttrs 写入基于 HTTP 的 RPC 调用字段 并以 Service/Method 作为 span 名称（JSON-RPC 不区分 Service 仅为 Method）
+//
+// Connect 的错误码按照语义规范写入 rpc.connect_rpc.error_code Twirp 未定义规范字段 统一使用 packetd.rpc.outcome
+func putRPCAttrs(span ptrace.Span, rpc *protocol.RPC) {
+	if rpc == nil {
+		return
+	}
+
+	attr := span.Attributes()
+	attr.PutStr("rpc.system", rpc.System)
+	attr.PutStr("rpc.method", rpc.Method)
+	attr.PutStr("packetd.rpc.outcome", rpc.Outcome)
+
+	switch rpc.System {
+	case protocol.RPCSystemJSONRPC:
+		span.SetName(rpc.Method)
+		attr.PutStr("rpc.jsonrpc.version", "2.0")
+		if rpc.RequestID != "" {
+			attr.PutStr("rpc.jsonrpc.request_id", rpc.RequestID)
+		}
+		if rpc.Batch > 0 {
+			attr.PutInt("packetd.rpc.jsonrpc.batch_size", int64(rpc.Batch))
+		}
+		if rpc.Outcome == protocol.RPCOutcomeError {
+			attr.PutInt("rpc.jsonrpc.error_code", int64(rpc.ErrorCode))
+			attr.PutStr("rpc.jsonrpc.error_message", rpc.ErrorMessage)
+		}
+		return
+
+	case protocol.RPCSystemConnect:
+		if rpc.Outcome != protocol.RPCOutcomeOK {
+			attr.PutStr("rpc.connect_rpc.error_code", rpc.Outcome)
+		}
+	}
+	span.SetName(rpc.Service + "/" + rpc.Method)
+	attr.PutStr("rpc.service", rpc.Service)
+}
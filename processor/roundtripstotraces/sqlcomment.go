@@ -0,0 +1,51 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstotraces
+
+import (
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/packetd/packetd/protocol"
+)
+
+// applySQLComment 将 span 挂载至 sqlcommenter 注释中的应用 Trace 并写入其余注释字段
+//
+// 应用侧的数据库客户端 span 即为父 span 无需解密 TLS 或者修改应用即可完成关联
+func applySQLComment(span ptrace.Span, comment *protocol.SQLComment) {
+	if comment == nil {
+		return
+	}
+	if tc, ok := comment.TraceContext(); ok {
+		span.SetTraceID(tc.TraceID)
+		span.SetParentSpanID(tc.SpanID)
+	}
+
+	attr := span.Attributes()
+	if comment.Route != "" {
+		attr.PutStr("db.sqlcommenter.route", comment.Route)
+	}
+	for k, v := range comment.Tags {
+		switch k {
+		case "traceparent", "tracestate", "route":
+			continue
+		}
+		attr.PutStr("db.sqlcommenter."+k, v)
+	}
+	if len(comment.Hints) > 0 {
+		hints := attr.PutEmptySlice("db.query.hints")
+		for _, hint := range comment.Hints {
+			hints.AppendEmpty().SetStr(hint)
+		}
+	}
+}
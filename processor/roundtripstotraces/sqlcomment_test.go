@@ -0,0 +1,49 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstotraces
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/pmysql"
+)
+
+func TestSQLCommentLink(t *testing.T) {
+	p, err := New(map[string]any{})
+	require.NoError(t, err)
+
+	const statement = "SELECT /*+ INDEX(users idx_id) */ * FROM users /*traceparent='00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01',route='%2Fusers',db_driver='pgx'*/"
+	t0 := time.Unix(1751356800, 0)
+	record := common.NewRecord(common.RecordRoundTrips, mysqlRoundTrip{
+		req: &pmysql.Request{Host: "10.0.0.1", Command: "COM_QUERY", Statement: statement, Comment: protocol.ParseSQLComment(statement), Time: t0},
+		rsp: &pmysql.Response{Host: "10.0.0.2", Port: 3306, Packet: &pmysql.OKPacket{}, Time: t0.Add(time.Millisecond)},
+	})
+
+	span := processSpan(t, p, record)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", span.TraceID().String())
+	assert.Equal(t, "b7ad6b7169203331", span.ParentSpanID().String())
+	assert.Equal(t, span.TraceID(), record.TraceID)
+
+	attrs := span.Attributes().AsRaw()
+	assert.Equal(t, "/users", attrs["db.sqlcommenter.route"])
+	assert.Equal(t, "pgx", attrs["db.sqlcommenter.db_driver"])
+	assert.Equal(t, []any{"INDEX(users idx_id)"}, attrs["db.query.hints"])
+	assert.NotContains(t, attrs, "db.sqlcommenter.traceparent")
+}
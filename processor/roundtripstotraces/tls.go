@@ -0,0 +1,102 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstotraces
+
+import (
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/tracekit"
+	"github.com/packetd/packetd/protocol/ptls"
+)
+
+func init() {
+	register(socket.L7ProtoTLS, newTLSConverter())
+}
+
+type tlsConverter struct{}
+
+func newTLSConverter() converter {
+	return &tlsConverter{}
+}
+
+func (c *tlsConverter) Proto() socket.L7Proto {
+	return socket.L7ProtoTLS
+}
+
+func (c *tlsConverter) Convert(rt socket.RoundTrip) ptrace.Span {
+	req := rt.Request().(*ptls.Request)
+	rsp := rt.Response().(*ptls.Response)
+	if rsp == nil {
+		return c.convertRenegotiation(req)
+	}
+
+	span := ptrace.NewSpan()
+	span.SetName("TLS handshake")
+	span.SetTraceID(tracekit.RandomTraceID())
+	span.SetSpanID(tracekit.RandomSpanID())
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(req.Time))
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(rsp.Time))
+
+	attr := span.Attributes()
+	attr.PutStr("tls.protocol.version", rsp.Version)
+	attr.PutStr("tls.cipher", rsp.CipherSuite)
+	if req.ServerName != "" {
+		attr.PutStr("tls.client.server_name", req.ServerName)
+	}
+	attr.PutStr("tls.client.ja3", req.JA3)
+	attr.PutStr("tls.client.ja4", req.JA4)
+	if rsp.ALPN != "" {
+		attr.PutStr("tls.next_protocol", rsp.ALPN)
+	}
+	if cert := rsp.Certificate; cert != nil {
+		attr.PutStr("tls.server.subject", cert.Subject)
+		attr.PutStr("tls.server.issuer", cert.Issuer)
+		attr.PutStr("tls.server.not_after", cert.NotAfter.Format(time.RFC3339))
+		attr.PutStr("tls.server.hash.sha256", cert.ChainHash)
+	}
+	if len(rsp.Warnings) > 0 {
+		attr.PutStr("tls.warnings", strings.Join(rsp.Warnings, ","))
+	}
+
+	attr.PutStr("server.address", rsp.Host)
+	attr.PutInt("server.port", int64(rsp.Port))
+	attr.PutStr("network.peer.address", req.Host)
+	attr.PutInt("network.peer.port", int64(req.Port))
+
+	return span
+}
+
+// convertRenegotiation 重协商事件没有可度量的耗时 以零时长的 span 输出
+func (c *tlsConverter) convertRenegotiation(req *ptls.Request) ptrace.Span {
+	span := ptrace.NewSpan()
+	span.SetName("TLS renegotiation")
+	span.SetTraceID(tracekit.RandomTraceID())
+	span.SetSpanID(tracekit.RandomSpanID())
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(req.Time))
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(req.Time))
+
+	attr := span.Attributes()
+	attr.PutStr("packetd.tls.renegotiation.initiator", req.Initiator)
+	attr.PutStr("server.address", req.ServerHost)
+	attr.PutInt("server.port", int64(req.ServerPort))
+	attr.PutStr("network.peer.address", req.Host)
+	attr.PutInt("network.peer.port", int64(req.Port))
+
+	return span
+}
@@ -0,0 +1,50 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtripstotraces
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/tracekit"
+	"github.com/packetd/packetd/protocol"
+)
+
+// convertTruncated 转换截断抓包产生的 RoundTrip
+//
+// Payload 不完整 Span 仅包含请求响应的字节数以及服务端地址 以协议名称作为 Span 名称
+func convertTruncated(rt socket.RoundTrip) ptrace.Span {
+	req := rt.Request().(*protocol.TruncatedMessage)
+	rsp := rt.Response().(*protocol.TruncatedMessage)
+
+	span := ptrace.NewSpan()
+	span.SetName(string(rt.Proto()))
+	span.SetTraceID(tracekit.RandomTraceID())
+	span.SetSpanID(tracekit.RandomSpanID())
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(req.Time))
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(rsp.Time))
+
+	attr := span.Attributes()
+	attr.PutStr("network.protocol.name", string(rt.Proto()))
+	attr.PutBool("packetd.truncated_capture", true)
+	attr.PutInt("packetd.request.size", int64(req.Size))
+	attr.PutInt("packetd.response.size", int64(rsp.Size))
+
+	attr.PutStr("server.address", rsp.Host)
+	attr.PutInt("server.port", int64(rsp.Port))
+	attr.PutStr("network.peer.address", req.Host)
+	attr.PutInt("network.peer.port", int64(req.Port))
+	return span
+}
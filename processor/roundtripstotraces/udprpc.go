@@ -0,0 +1,58 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package roundtripstotraces
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/tracekit"
+	"github.com/packetd/packetd/protocol/pudprpc"
+)
+
+func init() {
+	register(socket.L7ProtoUDPRPC, newUDPRPCConverter())
+}
+
+type udprpcConverter struct{}
+
+func newUDPRPCConverter() converter {
+	return &udprpcConverter{}
+}
+
+func (c *udprpcConverter) Proto() socket.L7Proto {
+	return socket.L7ProtoUDPRPC
+}
+
+func (c *udprpcConverter) Convert(rt socket.RoundTrip) ptrace.Span {
+	req := rt.Request().(*pudprpc.Request)
+	rsp := rt.Response().(*pudprpc.Response)
+
+	span := ptrace.NewSpan()
+	span.SetName(string(socket.L7ProtoUDPRPC))
+	span.SetTraceID(tracekit.RandomTraceID())
+	span.SetSpanID(tracekit.RandomSpanID())
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(req.Time))
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(rsp.Time))
+
+	attr := span.Attributes()
+	attr.PutStr("udprpc.transaction_id", req.TransactionID)
+
+	attr.PutStr("server.address", rsp.Host)
+	attr.PutInt("server.port", int64(rsp.Port))
+	attr.PutStr("network.peer.address", req.Host)
+	attr.PutInt("network.peer.port", int64(req.Port))
+
+	return span
+}
@@ -0,0 +1,38 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"github.com/packetd/packetd/common/socket"
+)
+
+// Capability 描述协议解析器支持的协议版本以及可用的解析选项
+//
+// 用于对外声明当前构建的能力 调用方在下发配置之前可据此确认 agent 是否支持
+type Capability struct {
+	Versions []string `json:"versions"`
+	Options  []string `json:"options"`
+}
+
+var capabilities = map[socket.L7Proto]Capability{}
+
+// Describe 注册协议能力描述 与 Register 一同在协议包初始化时调用
+func Describe(name socket.L7Proto, c Capability) {
+	capabilities[name] = c
+}
+
+// GetCapability 返回协议能力描述 未注册时返回空值
+func GetCapability(name socket.L7Proto) Capability {
+	return capabilities[name]
+}
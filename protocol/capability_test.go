@@ -0,0 +1,34 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+func TestCapability(t *testing.T) {
+	const name socket.L7Proto = "capability-test"
+	assert.Empty(t, GetCapability(name).Versions)
+
+	Describe(name, Capability{Versions: []string{"1.0"}, Options: []string{"foo"}})
+	defer delete(capabilities, name)
+
+	c := GetCapability(name)
+	assert.Equal(t, []string{"1.0"}, c.Versions)
+	assert.Equal(t, []string{"foo"}, c.Options)
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"strings"
+)
+
+// maxClientFieldLength 客户端名称以及版本的最大长度 避免异常数据撑大事件
+const maxClientFieldLength = 64
+
+// Client 客户端库指纹
+//
+// 由各协议从握手信息或者请求头中提取（如 MySQL connect attrs / MongoDB hello / User-Agent）
+// 用于在排查问题时关联驱动版本
+type Client struct {
+	Name    string
+	Version string
+}
+
+// NewClient 创建归一化后的 *Client name 为空时返回 nil
+//
+// 名称统一为小写并以 `-` 替代空白字符 版本去除前缀 `v`
+func NewClient(name, version string) *Client {
+	name = normalizeClientField(strings.ToLower(name))
+	if name == "" {
+		return nil
+	}
+	name = strings.Join(strings.Fields(name), "-")
+
+	version = normalizeClientField(version)
+	if len(version) > 1 && (version[0] == 'v' || version[0] == 'V') && isDigit(version[1]) {
+		version = version[1:]
+	}
+	return &Client{Name: name, Version: version}
+}
+
+// ParseUserAgent 从 User-Agent 中提取客户端指纹
+//
+// 仅解析首个 product token 如 `Go-http-client/1.1` `grpc-go/1.60.0` `python-requests/2.31.0`
+func ParseUserAgent(ua string) *Client {
+	ua = strings.TrimSpace(ua)
+	if ua == "" {
+		return nil
+	}
+
+	token := ua
+	if idx := strings.IndexAny(ua, " \t("); idx > 0 {
+		token = ua[:idx]
+	}
+
+	name, version, _ := strings.Cut(token, "/")
+	return NewClient(name, version)
+}
+
+func normalizeClientField(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > maxClientFieldLength {
+		s = s[:maxClientFieldLength]
+	}
+	return strings.ToValidUTF8(s, "")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,58 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		want    *Client
+	}{
+		{name: "", version: "1.0", want: nil},
+		{name: "  ", version: "1.0", want: nil},
+		{name: "MySQL Connector/J", version: "8.0.33", want: &Client{Name: "mysql-connector/j", Version: "8.0.33"}},
+		{name: "nodejs", version: "v6.3.0", want: &Client{Name: "nodejs", Version: "6.3.0"}},
+		{name: "sarama", version: "", want: &Client{Name: "sarama"}},
+		{name: "version", version: "v", want: &Client{Name: "version", Version: "v"}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, NewClient(tt.name, tt.version))
+	}
+}
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want *Client
+	}{
+		{ua: "", want: nil},
+		{ua: "Go-http-client/1.1", want: &Client{Name: "go-http-client", Version: "1.1"}},
+		{ua: "grpc-go/1.60.0", want: &Client{Name: "grpc-go", Version: "1.60.0"}},
+		{ua: "curl/8.4.0", want: &Client{Name: "curl", Version: "8.4.0"}},
+		{ua: "python-requests/2.31.0 extra/1.0", want: &Client{Name: "python-requests", Version: "2.31.0"}},
+		{ua: "Mozilla/5.0 (X11; Linux x86_64)", want: &Client{Name: "mozilla", Version: "5.0"}},
+		{ua: "okhttp", want: &Client{Name: "okhttp"}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ParseUserAgent(tt.ua), tt.ua)
+	}
+}
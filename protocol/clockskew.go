@@ -0,0 +1,116 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+)
+
+const defaultClockSkewMaxOffset = 5 * time.Second
+
+var clockSkewCorrectionsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: common.App,
+		Name:      "clock_skew_corrections_total",
+		Help:      "Per-connection clock offset adjustments caused by negative roundtrip latency",
+	},
+	[]string{"proto"},
+)
+
+var clockSkewDroppedTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: common.App,
+		Name:      "clock_skew_dropped_roundtrips_total",
+		Help:      "Roundtrips dropped because of negative latency before the clock offset is corrected",
+	},
+	[]string{"proto"},
+)
+
+var clockSkewOutOfRangeTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: common.App,
+		Name:      "clock_skew_out_of_range_total",
+		Help:      "Negative roundtrips whose skew exceeds the configured max offset",
+	},
+	[]string{"proto"},
+)
+
+// ClockSkew 镜像采集下的时钟偏移修正
+//
+// 请求与响应两个方向由不同的采集点（TAP/交换机镜像口）上送时 两端时钟不同步会导致耗时为负数
+// 开启后每个链接独立估算偏移量 后续服务端方向的数据包时间统一加上偏移量后再解析
+type ClockSkew struct {
+	maxOffset time.Duration
+}
+
+// NewClockSkew 创建 ClockSkew 实例 maxOffset 为允许修正的最大偏移量 超出则视为异常数据不做修正
+func NewClockSkew(maxOffset time.Duration) *ClockSkew {
+	if maxOffset <= 0 {
+		maxOffset = defaultClockSkewMaxOffset
+	}
+	return &ClockSkew{maxOffset: maxOffset}
+}
+
+var globalClockSkew atomic.Pointer[ClockSkew]
+
+// SetClockSkew 设置全局 ClockSkew 实例 传入 nil 则关闭修正
+func SetClockSkew(cs *ClockSkew) {
+	globalClockSkew.Store(cs)
+}
+
+// skewOffset 单个链接估算出的时钟偏移
+//
+// 真实耗时不可能为负 观测到的最小耗时即为偏移量的下界 偏移量只增不减
+// 修正后最快的请求耗时趋近于 0 对于其余请求耗时的误差不超过最快请求的真实耗时
+// observe 观测到的负耗时已经经过当前偏移量的修正 为剩余的偏差 需要累加至偏移量
+type skewOffset struct {
+	d time.Duration
+}
+
+// adjust 返回修正后的数据包时间 仅作用于服务端方向
+func (s *skewOffset) adjust(st socket.Tuple, serverPort socket.Port, t time.Time) time.Time {
+	if s.d == 0 || st.SrcPort != serverPort || st.SrcPort == st.DstPort {
+		return t
+	}
+	return t.Add(s.d)
+}
+
+// observe 根据未通过校验的 roundtrip 更新偏移量
+func (s *skewOffset) observe(rt socket.RoundTrip) {
+	cs := globalClockSkew.Load()
+	if cs == nil {
+		return
+	}
+
+	skew := -rt.Duration()
+	if skew <= 0 {
+		return
+	}
+	clockSkewDroppedTotal.WithLabelValues(string(rt.Proto())).Inc()
+	if s.d+skew > cs.maxOffset {
+		clockSkewOutOfRangeTotal.WithLabelValues(string(rt.Proto())).Inc()
+		return
+	}
+
+	// 额外增加 1us 保证修正后响应时间严格晚于请求
+	s.d += skew + time.Microsecond
+	clockSkewCorrectionsTotal.WithLabelValues(string(rt.Proto())).Inc()
+}
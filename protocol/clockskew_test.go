@@ -0,0 +1,62 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+type mockRoundTrip struct {
+	d time.Duration
+}
+
+func (mockRoundTrip) Proto() socket.L7Proto      { return socket.L7ProtoHTTP }
+func (mockRoundTrip) Request() any               { return nil }
+func (mockRoundTrip) Response() any              { return nil }
+func (rt mockRoundTrip) Duration() time.Duration { return rt.d }
+func (rt mockRoundTrip) Validate() bool          { return rt.d > 0 }
+
+func TestSkewOffset(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	client := socket.Tuple{SrcPort: 50001, DstPort: 80}
+	server := client.Mirror()
+
+	var s skewOffset
+	s.observe(mockRoundTrip{d: -3 * time.Millisecond})
+	assert.Equal(t, time.Duration(0), s.d, "disabled")
+
+	SetClockSkew(NewClockSkew(time.Second))
+	defer SetClockSkew(nil)
+
+	s.observe(mockRoundTrip{d: -3 * time.Millisecond})
+	assert.Equal(t, 3*time.Millisecond+time.Microsecond, s.d)
+
+	// 修正后仍为负数的耗时为剩余偏差 累加至偏移量
+	s.observe(mockRoundTrip{d: -time.Millisecond})
+	assert.Equal(t, 4*time.Millisecond+2*time.Microsecond, s.d)
+
+	s.observe(mockRoundTrip{d: time.Millisecond})
+	assert.Equal(t, 4*time.Millisecond+2*time.Microsecond, s.d, "offset only grows")
+
+	s.observe(mockRoundTrip{d: -time.Second})
+	assert.Equal(t, 4*time.Millisecond+2*time.Microsecond, s.d, "out of range")
+
+	assert.Equal(t, t0, s.adjust(client, 80, t0))
+	assert.Equal(t, t0.Add(s.d), s.adjust(server, 80, t0))
+}
@@ -37,4 +37,16 @@ type Conn interface {
 
 	// ActiveAt 返回链接最后活跃时间
 	ActiveAt() time.Time
+
+	// AckStates 返回链接中存在未确认数据的方向 用于识别半开链接
+	AckStates() []connstream.AckState
+
+	// Pending 返回尚未收到响应的请求数量
+	Pending() int
+
+	// MarkHalfOpen 标记链接于 t 时刻被判定为半开 此前发出的请求生成的 RoundTrip 均会附加半开标记
+	MarkHalfOpen(t time.Time)
+
+	// Flows 返回两个方向自上次调用以来的流统计 读取即重置
+	Flows() []connstream.FlowStats
 }
@@ -0,0 +1,213 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+const (
+	// CtxDatabase 链接当前所在的数据库 如 MySQL 的 USE / COM_INIT_DB 以及 PostgreSQL StartupMessage 中的 database
+	CtxDatabase = "database"
+
+	// CtxTopic 链接最近一次请求的 topic 如 Kafka Produce/Fetch 中的首个 topic
+	CtxTopic = "topic"
+
+	// defaultMaxContextEntries 单链接最多保存的上下文条目数量
+	defaultMaxContextEntries = 32
+
+	// maxContextValueSize 单个上下文取值的最大长度 超出部分截断 与各协议的语句缓冲区大小保持一致
+	maxContextValueSize = 1024
+)
+
+type contextEntry struct {
+	key   string
+	value string
+}
+
+// ConnContext 链接级别的上下文存储
+//
+// 部分字段仅在链接建立或者切换时出现一次（如当前数据库 预处理语句）而后续请求依赖其取值
+// 同一链接两个方向的 decoder 共享同一个 ConnContext 条目数超过上限时淘汰最久未访问的条目
+// 协议自身的链接级状态（如 Kafka 的版本协商结果 PostgreSQL 的 pipeline）通过 Value 挂载 不参与淘汰
+type ConnContext struct {
+	mut     sync.Mutex
+	max     int
+	l       *list.List
+	entries map[string]*list.Element
+	values  map[string]any
+}
+
+// NewConnContext 创建 ConnContext 实例 max 小于等于 0 时使用默认上限
+func NewConnContext(max int) *ConnContext {
+	if max <= 0 {
+		max = defaultMaxContextEntries
+	}
+	return &ConnContext{
+		max:     max,
+		l:       list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Set 设置上下文 value 为空时删除该条目
+func (c *ConnContext) Set(key, value string) {
+	if c == nil {
+		return
+	}
+	if len(value) > maxContextValueSize {
+		value = value[:maxContextValueSize]
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		if value == "" {
+			c.l.Remove(e)
+			delete(c.entries, key)
+			return
+		}
+		e.Value.(*contextEntry).value = value
+		c.l.MoveToBack(e)
+		return
+	}
+	if value == "" {
+		return
+	}
+
+	if c.l.Len() >= c.max {
+		front := c.l.Front()
+		c.l.Remove(front)
+		delete(c.entries, front.Value.(*contextEntry).key)
+	}
+	c.entries[key] = c.l.PushBack(&contextEntry{key: key, value: value})
+}
+
+// Get 获取上下文 不存在时返回空
+func (c *ConnContext) Get(key string) string {
+	if c == nil {
+		return ""
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return ""
+	}
+	c.l.MoveToBack(e)
+	return e.Value.(*contextEntry).value
+}
+
+// Value 获取 key 挂载的链接级对象 不存在时使用 newFn 创建
+func (c *ConnContext) Value(key string, newFn func() any) any {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if v, ok := c.values[key]; ok {
+		return v
+	}
+	if c.values == nil {
+		c.values = make(map[string]any)
+	}
+	v := newFn()
+	c.values[key] = v
+	return v
+}
+
+// Len 返回当前条目数量
+func (c *ConnContext) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	return c.l.Len()
+}
+
+// ConnContexts 管理连接池内所有链接的 ConnContext
+//
+// 同一条链接的两个方向使用 client 端的 socket.Tuple 作为标识 引用计数归零时删除
+// 需要在两个方向之间共享状态的协议均应通过 ConnContexts 获取 而非各自维护一份引用计数
+type ConnContexts struct {
+	mut  sync.Mutex
+	max  int
+	refs map[socket.Tuple]*connContextRef
+}
+
+type connContextRef struct {
+	c    *ConnContext
+	refs int
+}
+
+// NewConnContexts 创建 ConnContexts 实例 max 为单链接的条目上限
+func NewConnContexts(max int) *ConnContexts {
+	return &ConnContexts{
+		max:  max,
+		refs: make(map[socket.Tuple]*connContextRef),
+	}
+}
+
+func connContextKey(st socket.Tuple, serverPort socket.Port) socket.Tuple {
+	if st.DstPort == serverPort {
+		return st
+	}
+	return st.Mirror()
+}
+
+// Acquire 获取（或创建）链接关联的 ConnContext
+func (cs *ConnContexts) Acquire(st socket.Tuple, serverPort socket.Port) *ConnContext {
+	cs.mut.Lock()
+	defer cs.mut.Unlock()
+
+	key := connContextKey(st, serverPort)
+	ref, ok := cs.refs[key]
+	if !ok {
+		ref = &connContextRef{c: NewConnContext(cs.max)}
+		cs.refs[key] = ref
+	}
+	ref.refs++
+	return ref.c
+}
+
+// Release 释放链接关联的 ConnContext
+func (cs *ConnContexts) Release(st socket.Tuple, serverPort socket.Port) {
+	cs.mut.Lock()
+	defer cs.mut.Unlock()
+
+	key := connContextKey(st, serverPort)
+	ref, ok := cs.refs[key]
+	if !ok {
+		return
+	}
+	ref.refs--
+	if ref.refs <= 0 {
+		delete(cs.refs, key)
+	}
+}
+
+// Len 返回当前追踪的链接数量
+func (cs *ConnContexts) Len() int {
+	cs.mut.Lock()
+	defer cs.mut.Unlock()
+
+	return len(cs.refs)
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+func TestConnContext(t *testing.T) {
+	c := NewConnContext(2)
+	c.Set(CtxDatabase, "orders")
+	c.Set("statement:s1", "SELECT 1")
+	assert.Equal(t, "orders", c.Get(CtxDatabase))
+
+	// 淘汰最久未访问的条目
+	c.Set("statement:s2", "SELECT 2")
+	assert.Equal(t, 2, c.Len())
+	assert.Empty(t, c.Get("statement:s1"))
+	assert.Equal(t, "orders", c.Get(CtxDatabase))
+
+	// 覆盖以及删除
+	c.Set(CtxDatabase, "users")
+	assert.Equal(t, "users", c.Get(CtxDatabase))
+	c.Set(CtxDatabase, "")
+	assert.Empty(t, c.Get(CtxDatabase))
+	assert.Equal(t, 1, c.Len())
+
+	c.Set("long", strings.Repeat("x", maxContextValueSize+1))
+	assert.Len(t, c.Get("long"), maxContextValueSize)
+
+	// 挂载的对象不参与淘汰
+	newFn := func() any { return new(int) }
+	v := c.Value("pipeline", newFn)
+	c.Set("statement:s3", "SELECT 3")
+	c.Set("statement:s4", "SELECT 4")
+	assert.Same(t, v, c.Value("pipeline", newFn))
+
+	var nilCtx *ConnContext
+	nilCtx.Set(CtxDatabase, "orders")
+	assert.Empty(t, nilCtx.Get(CtxDatabase))
+}
+
+func TestConnContexts(t *testing.T) {
+	st := socket.Tuple{
+		SrcIP:   socket.ToIPV4(net.ParseIP("10.0.0.1").To4()),
+		DstIP:   socket.ToIPV4(net.ParseIP("10.0.0.2").To4()),
+		SrcPort: 50001,
+		DstPort: 3306,
+	}
+
+	cs := NewConnContexts(0)
+	client := cs.Acquire(st, 3306)
+	server := cs.Acquire(st.Mirror(), 3306)
+	assert.Same(t, client, server)
+
+	client.Set(CtxDatabase, "orders")
+	assert.Equal(t, "orders", server.Get(CtxDatabase))
+
+	cs.Release(st, 3306)
+	assert.Equal(t, 1, cs.Len())
+	cs.Release(st.Mirror(), 3306)
+	assert.Equal(t, 0, cs.Len())
+
+	for i := 0; i < 3; i++ {
+		st.SrcPort = socket.Port(50002 + i)
+		cs.Acquire(st, 3306).Set(CtxDatabase, strconv.Itoa(i))
+	}
+	assert.Equal(t, 3, cs.Len())
+}
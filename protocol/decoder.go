@@ -36,3 +36,26 @@ type Decoder interface {
 	// Free 释放持有的资源
 	Free()
 }
+
+// Aborter 可选接口 客户端中断链接（FIN/RST）时由服务端方向的 Decoder 实现
+//
+// 用于响应尚未传输完成客户端就主动断开的场景 如取消下载或者拖动视频进度
+// 返回提前归档的对象 没有进行中的响应时返回 nil
+type Aborter interface {
+	Abort(t time.Time) []*role.Object
+}
+
+// ServerCloser 可选接口 服务端先于客户端关闭链接（FIN/RST）时由服务端方向的 Decoder 实现
+//
+// 用于服务端以断开链接代替响应的场景 如 Kafka SASL v0 认证失败时 Broker 不返回 Token 直接断开
+// 返回代替响应归档的对象 没有等待响应的请求时返回 nil
+type ServerCloser interface {
+	ServerClose(t time.Time) []*role.Object
+}
+
+// TCPConnectSetter 可选接口 需要链接建连耗时的 RoundTrip 实现
+//
+// 仅在观测到完整的 TCP 三次握手时调用 如 TLS 按阶段拆分首个请求的耗时
+type TCPConnectSetter interface {
+	SetTCPConnect(d time.Duration)
+}
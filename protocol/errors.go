@@ -0,0 +1,117 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+)
+
+// ErrorCode 解析错误分类
+//
+// 旁路抓包时 decoder 经常从链接中途开始解析 此类错误是预期内的 不代表协议实现有问题
+// 通过分类可以区分中途接入与真正的解析缺陷
+type ErrorCode string
+
+const (
+	// ErrCodeHeaderTooShort 剩余字节不足以解析固定长度的头部
+	ErrCodeHeaderTooShort ErrorCode = "header_too_short"
+
+	// ErrCodeLengthOverflow 报文声明的长度超出协议上限或者与实际字节数不符
+	ErrCodeLengthOverflow ErrorCode = "length_overflow"
+
+	// ErrCodeUnsupportedVersion 协议版本或者 API 未被 decoder 支持
+	ErrCodeUnsupportedVersion ErrorCode = "unsupported_version"
+
+	// ErrCodeResyncFailed 数据流失去同步且无法找到下一个报文边界 通常由中途接入或者丢包导致
+	ErrCodeResyncFailed ErrorCode = "resync_failed"
+
+	// ErrCodeMalformed 报文字段不符合协议规范 排除以上情况后应优先排查此类错误
+	ErrCodeMalformed ErrorCode = "malformed"
+
+	// ErrCodeUnknown 未分类的错误
+	ErrCodeUnknown ErrorCode = "unknown"
+)
+
+// DecodeError 带分类的解析错误
+type DecodeError struct {
+	Proto   socket.L7Proto
+	Code    ErrorCode
+	Version string // 仅 ErrCodeUnsupportedVersion 时有效
+	msg     string
+}
+
+// NewDecodeError 创建带分类的解析错误 format 规则同 fmt.Sprintf
+func NewDecodeError(proto socket.L7Proto, code ErrorCode, format string, args ...any) error {
+	return &DecodeError{
+		Proto: proto,
+		Code:  code,
+		msg:   fmt.Sprintf(format, args...),
+	}
+}
+
+// NewUnsupportedVersionError 创建协议版本不被支持的解析错误 记录时会同时累加 unsupported_versions_total
+func NewUnsupportedVersionError(proto socket.L7Proto, version string, format string, args ...any) error {
+	return &DecodeError{
+		Proto:   proto,
+		Code:    ErrCodeUnsupportedVersion,
+		Version: version,
+		msg:     fmt.Sprintf(format, args...),
+	}
+}
+
+func (e *DecodeError) Error() string {
+	return e.msg + " (" + string(e.Code) + ")"
+}
+
+// ErrorCodeOf 返回 err 的分类以及所属协议 未分类的错误返回 ErrCodeUnknown
+func ErrorCodeOf(err error) (socket.L7Proto, ErrorCode) {
+	var de *DecodeError
+	if errors.As(err, &de) {
+		return de.Proto, de.Code
+	}
+	return "unknown", ErrCodeUnknown
+}
+
+var decodeErrorsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: common.App,
+		Name:      "decode_errors_total",
+		Help:      "Decoder errors total by protocol and error code",
+	},
+	[]string{"proto", "code"},
+)
+
+var unsupportedVersionsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: common.App,
+		Name:      "unsupported_versions_total",
+		Help:      "Protocol versions observed but not supported by decoders",
+	},
+	[]string{"proto", "version"},
+)
+
+// RecordUnsupportedVersion 记录 decoder 观测到但无法完整解析的协议版本
+//
+// decoder 应尽量退化为仅包含大小以及耗时的 roundtrip 而不是静默丢弃
+// 以便了解线上存在哪些尚未支持的版本及其流量规模 version 取值需由 decoder 保证有限
+func RecordUnsupportedVersion(proto socket.L7Proto, version string) {
+	unsupportedVersionsTotal.WithLabelValues(string(proto), version).Inc()
+}
@@ -0,0 +1,70 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+func TestErrorCodeOf(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		proto socket.L7Proto
+		code  ErrorCode
+	}{
+		{
+			name:  "DecodeError",
+			err:   NewDecodeError(socket.L7ProtoKafka, ErrCodeHeaderTooShort, "header too short"),
+			proto: socket.L7ProtoKafka,
+			code:  ErrCodeHeaderTooShort,
+		},
+		{
+			name:  "Wrapped",
+			err:   errors.Wrap(NewDecodeError(socket.L7ProtoMySQL, ErrCodeResyncFailed, "boundary lost"), "decode"),
+			proto: socket.L7ProtoMySQL,
+			code:  ErrCodeResyncFailed,
+		},
+		{
+			name:  "UnsupportedVersion",
+			err:   NewUnsupportedVersionError(socket.L7ProtoAMQP, "1.0.0", "unsupported version"),
+			proto: socket.L7ProtoAMQP,
+			code:  ErrCodeUnsupportedVersion,
+		},
+		{
+			name:  "Untyped",
+			err:   errors.New("oops"),
+			proto: "unknown",
+			code:  ErrCodeUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proto, code := ErrorCodeOf(tt.err)
+			assert.Equal(t, tt.proto, proto)
+			assert.Equal(t, tt.code, code)
+		})
+	}
+}
+
+func TestDecodeErrorMessage(t *testing.T) {
+	err := NewDecodeError(socket.L7ProtoRedis, ErrCodeLengthOverflow, "redis/decoder: length %d", 10)
+	assert.Equal(t, "redis/decoder: length 10 (length_overflow)", err.Error())
+}
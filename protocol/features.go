@@ -0,0 +1,39 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+)
+
+var optionEventsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: common.App,
+		Name:      "decoder_option_events_total",
+		Help:      "Decoder optional feature events total by protocol, option and event",
+	},
+	[]string{"proto", "option", "event"},
+)
+
+// NewOptionCounter 返回 decoder 可选功能的生效次数计数器
+//
+// 用于评估每个开启的解析选项的实际收益 如 Body 捕获次数以及因超出限制被截断的次数
+// 计数器在 decoder 所在包初始化时创建 热路径上仅需 Inc 无需按照 label 查找
+func NewOptionCounter(proto socket.L7Proto, option, event string) prometheus.Counter {
+	return optionEventsTotal.WithLabelValues(string(proto), option, event)
+}
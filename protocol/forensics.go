@@ -0,0 +1,176 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+const (
+	defaultForensicsDumpBytes = 256
+	maxForensicsDumpBytes     = 4096
+	defaultForensicsPerMinute = 6
+)
+
+// StateDescriber 可选接口 Decoder 实现后可在解析错误现场中附带内部状态
+type StateDescriber interface {
+	DescribeState() string
+}
+
+// Forensics 解析错误现场采集器
+//
+// Decoder 返回错误时记录出错数据包的 payload（hex dump 且有长度上限）以及 Decoder 状态
+// 便于在没有完整 pcap 的情况下复现用户反馈的解析问题 采集按分钟窗口限流
+type Forensics struct {
+	maxDumpBytes int
+	perMinute    int
+	output       func(string)
+	redact       func(socket.Tuple) string
+
+	mut     sync.Mutex
+	window  time.Time
+	count   int
+	dropped int
+}
+
+// NewForensics 创建 Forensics 实例
+//
+// maxDumpBytes 单次最多 dump 的字节数 perMinute 每分钟最多记录的次数 output 为记录输出函数
+func NewForensics(maxDumpBytes, perMinute int, output func(string)) *Forensics {
+	if maxDumpBytes <= 0 {
+		maxDumpBytes = defaultForensicsDumpBytes
+	}
+	if maxDumpBytes > maxForensicsDumpBytes {
+		maxDumpBytes = maxForensicsDumpBytes
+	}
+	if perMinute <= 0 {
+		perMinute = defaultForensicsPerMinute
+	}
+	return &Forensics{
+		maxDumpBytes: maxDumpBytes,
+		perMinute:    perMinute,
+		output:       output,
+	}
+}
+
+// SetRedact 设置四元组的输出格式 用于隐藏客户端地址
+//
+// 设置后不再输出 payload 的 hex dump 应用层数据中同样可能携带客户端地址（如 X-Forwarded-For）
+func (f *Forensics) SetRedact(fn func(socket.Tuple) string) {
+	f.redact = fn
+}
+
+// allow 判断 t 时刻是否允许记录 返回上一窗口内被丢弃的次数
+func (f *Forensics) allow(t time.Time) (int, bool) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if t.Sub(f.window) >= time.Minute || t.Before(f.window) {
+		f.window = t
+		f.count = 0
+	}
+	if f.count >= f.perMinute {
+		f.dropped++
+		return 0, false
+	}
+	f.count++
+
+	dropped := f.dropped
+	f.dropped = 0
+	return dropped, true
+}
+
+// Record 记录一次解析错误现场
+func (f *Forensics) Record(pkt socket.L4Packet, d Decoder, err error) {
+	dropped, ok := f.allow(time.Now())
+	if !ok {
+		return
+	}
+
+	payload := packetPayload(pkt)
+	window := payload
+	if len(window) > f.maxDumpBytes {
+		window = window[:f.maxDumpBytes]
+	}
+
+	tuple := pkt.SocketTuple().String()
+	if f.redact != nil {
+		tuple = f.redact(pkt.SocketTuple())
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "decode error forensics: %s\n", tuple)
+	fmt.Fprintf(&sb, "decoder: %T\n", d)
+	fmt.Fprintf(&sb, "error: %v\n", err)
+	fmt.Fprintf(&sb, "arrived: %s\n", pkt.ArrivedTime().Format(time.RFC3339Nano))
+	if sd, ok := d.(StateDescriber); ok {
+		fmt.Fprintf(&sb, "state: %s\n", sd.DescribeState())
+	}
+	if dropped > 0 {
+		fmt.Fprintf(&sb, "suppressed: %d\n", dropped)
+	}
+	if f.redact != nil {
+		fmt.Fprintf(&sb, "payload: %d bytes (redacted)\n", len(payload))
+	} else {
+		fmt.Fprintf(&sb, "payload: %d bytes (dump %d bytes)\n", len(payload), len(window))
+		sb.WriteString(hex.Dump(window))
+	}
+
+	f.output(sb.String())
+}
+
+// packetPayload 返回 L4Packet 携带的原始数据
+func packetPayload(pkt socket.L4Packet) []byte {
+	switch p := pkt.(type) {
+	case *socket.TCPSegment:
+		return p.Payload
+	case socket.TCPSegment:
+		return p.Payload
+	case *socket.UDPDatagram:
+		return p.Payload
+	case socket.UDPDatagram:
+		return p.Payload
+	}
+	return nil
+}
+
+var globalForensics atomic.Pointer[Forensics]
+
+// SetForensics 设置全局 Forensics 实例 传入 nil 则关闭采集
+func SetForensics(f *Forensics) {
+	globalForensics.Store(f)
+}
+
+func recordDecodeError(pkt socket.L4Packet, d Decoder, err error) {
+	proto, code := ErrorCodeOf(err)
+	decodeErrorsTotal.WithLabelValues(string(proto), string(code)).Inc()
+
+	var de *DecodeError
+	if errors.As(err, &de) && de.Version != "" {
+		RecordUnsupportedVersion(de.Proto, de.Version)
+	}
+
+	if f := globalForensics.Load(); f != nil {
+		f.Record(pkt, d, err)
+	}
+}
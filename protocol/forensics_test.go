@@ -0,0 +1,86 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+type mockDecoder struct{}
+
+func (mockDecoder) Decode(zerocopy.Reader, time.Time) ([]*role.Object, error) {
+	return nil, nil
+}
+
+func (mockDecoder) Free() {}
+
+func (mockDecoder) DescribeState() string {
+	return "state=header"
+}
+
+func TestForensicsRecord(t *testing.T) {
+	var records []string
+	f := NewForensics(8, 2, func(s string) {
+		records = append(records, s)
+	})
+
+	pkt := &socket.TCPSegment{
+		Time:    time.Now(),
+		Payload: bytes.Repeat([]byte{0xAB}, 32),
+	}
+	for i := 0; i < 5; i++ {
+		f.Record(pkt, mockDecoder{}, errors.New("invalid bytes"))
+	}
+
+	assert.Len(t, records, 2)
+	assert.Contains(t, records[0], "error: invalid bytes")
+	assert.Contains(t, records[0], "state: state=header")
+	assert.Contains(t, records[0], "payload: 32 bytes (dump 8 bytes)")
+	assert.Contains(t, records[0], "ab ab ab ab ab ab ab ab")
+	assert.NotContains(t, records[0], "ab ab ab ab ab ab ab ab ab")
+
+	// 超出窗口后恢复记录 并附带被丢弃的次数
+	f.window = f.window.Add(-time.Minute)
+	f.Record(pkt, mockDecoder{}, errors.New("invalid bytes"))
+	assert.Len(t, records, 3)
+	assert.Contains(t, records[2], "suppressed: 3")
+}
+
+func TestForensicsRedact(t *testing.T) {
+	var records []string
+	f := NewForensics(8, 2, func(s string) {
+		records = append(records, s)
+	})
+	f.SetRedact(func(socket.Tuple) string { return "redacted-tuple" })
+
+	pkt := &socket.TCPSegment{
+		Time:    time.Now(),
+		Payload: bytes.Repeat([]byte{0xAB}, 32),
+	}
+	f.Record(pkt, mockDecoder{}, errors.New("invalid bytes"))
+
+	assert.Len(t, records, 1)
+	assert.Contains(t, records[0], "decode error forensics: redacted-tuple")
+	assert.Contains(t, records[0], "payload: 32 bytes (redacted)")
+	assert.NotContains(t, records[0], "ab ab")
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+const (
+	RPCSystemJSONRPC = "jsonrpc"
+
+	// RPCOutcomeError JSON-RPC 响应携带 error 对象 具体错误码记录在 RPC.ErrorCode 中
+	RPCOutcomeError = "error"
+)
+
+// JSONRPCError JSON-RPC 2.0 错误对象
+type JSONRPCError struct {
+	Code    int
+	Message string
+}
+
+// JSONRPCMessage 单条 JSON-RPC 2.0 消息
+//
+// 请求以及通知携带 Method 通知没有 id 响应携带 result 或者 error 仅在失败时保留 Error
+type JSONRPCMessage struct {
+	ID     string
+	Method string
+	Error  *JSONRPCError
+}
+
+// IsRequest 是否为请求（包括通知）
+func (m JSONRPCMessage) IsRequest() bool {
+	return m.Method != ""
+}
+
+type jsonrpcMessage struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Result  json.RawMessage `json:"result"`
+	Error   *JSONRPCError   `json:"error"`
+}
+
+// ParseJSONRPC 解析 JSON-RPC 2.0 消息 支持批量调用
+//
+// 要求每条消息均声明 "jsonrpc": "2.0" 且为请求或者响应之一 否则视为普通的 json 内容返回 nil
+// 被截断的 body 无法通过 json 校验 同样返回 nil
+func ParseJSONRPC(b []byte) []JSONRPCMessage {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return nil
+	}
+
+	var raws []jsonrpcMessage
+	switch b[0] {
+	case '{':
+		var raw jsonrpcMessage
+		if json.Unmarshal(b, &raw) != nil {
+			return nil
+		}
+		raws = append(raws, raw)
+	case '[':
+		if json.Unmarshal(b, &raws) != nil || len(raws) == 0 {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	msgs := make([]JSONRPCMessage, 0, len(raws))
+	for _, raw := range raws {
+		if raw.Version != "2.0" {
+			return nil
+		}
+		msg := JSONRPCMessage{Method: raw.Method}
+		if id := bytes.TrimSpace(raw.ID); len(id) > 0 && !bytes.Equal(id, []byte("null")) {
+			msg.ID = string(id)
+		}
+		if msg.Method == "" {
+			// 响应必须携带 id 以及 result / error 二者其一
+			if msg.ID == "" && raw.Error == nil {
+				return nil
+			}
+			if raw.Result == nil && raw.Error == nil {
+				return nil
+			}
+			msg.Error = raw.Error
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// NewJSONRPC 按照 id 关联请求与响应 calls 中不包含请求时返回 nil
+//
+// 批量调用的响应顺序与请求无关 Method 以及 RequestID 取首个请求 Batch 记录请求数量
+// 任一请求失败即记为 error 错误码取首个失败的请求 存在未收到响应的请求（通知除外）时记为 unknown
+func NewJSONRPC(calls, replies []JSONRPCMessage) *RPC {
+	var rpc *RPC
+	var requests int
+	for _, call := range calls {
+		if !call.IsRequest() {
+			continue
+		}
+		requests++
+		if rpc == nil {
+			rpc = &RPC{System: RPCSystemJSONRPC, Method: call.Method, RequestID: call.ID}
+		}
+	}
+	if rpc == nil {
+		return nil
+	}
+	if requests > 1 {
+		rpc.Batch = requests
+	}
+
+	results := make(map[string]JSONRPCMessage, len(replies))
+	for _, reply := range replies {
+		if !reply.IsRequest() && reply.ID != "" {
+			results[reply.ID] = reply
+		}
+	}
+
+	rpc.Outcome = RPCOutcomeOK
+	for _, call := range calls {
+		if !call.IsRequest() || call.ID == "" {
+			continue // 通知不存在响应
+		}
+		reply, ok := results[call.ID]
+		switch {
+		case !ok:
+			if rpc.Outcome == RPCOutcomeOK {
+				rpc.Outcome = RPCOutcomeUnknown
+			}
+		case reply.Error != nil:
+			rpc.Outcome = RPCOutcomeError
+			rpc.ErrorCode = reply.Error.Code
+			rpc.ErrorMessage = reply.Error.Message
+			return rpc
+		}
+	}
+	return rpc
+}
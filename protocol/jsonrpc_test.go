@@ -0,0 +1,123 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJSONRPC(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []JSONRPCMessage
+	}{
+		{
+			name: "Request",
+			body: `{"jsonrpc":"2.0","method":"eth_blockNumber","params":[],"id":1}`,
+			want: []JSONRPCMessage{{ID: "1", Method: "eth_blockNumber"}},
+		},
+		{
+			name: "Notification",
+			body: `{"jsonrpc":"2.0","method":"notify","params":{"a":1}}`,
+			want: []JSONRPCMessage{{Method: "notify"}},
+		},
+		{
+			name: "Result",
+			body: ` {"jsonrpc":"2.0","id":"abc","result":"0x10"} `,
+			want: []JSONRPCMessage{{ID: `"abc"`}},
+		},
+		{
+			name: "Error",
+			body: `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`,
+			want: []JSONRPCMessage{{Error: &JSONRPCError{Code: -32700, Message: "Parse error"}}},
+		},
+		{
+			name: "Batch",
+			body: `[{"jsonrpc":"2.0","method":"a","id":1},{"jsonrpc":"2.0","method":"b","id":2}]`,
+			want: []JSONRPCMessage{{ID: "1", Method: "a"}, {ID: "2", Method: "b"}},
+		},
+		{name: "Version1", body: `{"method":"a","params":[],"id":1}`},
+		{name: "PlainJSON", body: `{"jsonrpc":"2.0","name":"a"}`},
+		{name: "ResultWithoutID", body: `{"jsonrpc":"2.0","result":1}`},
+		{name: "Truncated", body: `{"jsonrpc":"2.0","method":"a","id":`},
+		{name: "EmptyBatch", body: `[]`},
+		{name: "Text", body: `hello`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseJSONRPC([]byte(tt.body)))
+		})
+	}
+}
+
+func TestNewJSONRPC(t *testing.T) {
+	tests := []struct {
+		name    string
+		calls   []JSONRPCMessage
+		replies []JSONRPCMessage
+		want    *RPC
+	}{
+		{
+			name:    "OK",
+			calls:   []JSONRPCMessage{{ID: "1", Method: "eth_call"}},
+			replies: []JSONRPCMessage{{ID: "1"}},
+			want:    &RPC{System: RPCSystemJSONRPC, Method: "eth_call", RequestID: "1", Outcome: RPCOutcomeOK},
+		},
+		{
+			name:    "Error",
+			calls:   []JSONRPCMessage{{ID: "1", Method: "eth_call"}},
+			replies: []JSONRPCMessage{{ID: "1", Error: &JSONRPCError{Code: -32000, Message: "execution reverted"}}},
+			want: &RPC{
+				System: RPCSystemJSONRPC, Method: "eth_call", RequestID: "1", Outcome: RPCOutcomeError,
+				ErrorCode: -32000, ErrorMessage: "execution reverted",
+			},
+		},
+		{
+			name:  "BatchOutOfOrder",
+			calls: []JSONRPCMessage{{ID: "1", Method: "a"}, {Method: "notify"}, {ID: "2", Method: "b"}},
+			replies: []JSONRPCMessage{
+				{ID: "2", Error: &JSONRPCError{Code: -32601, Message: "Method not found"}},
+				{ID: "1"},
+			},
+			want: &RPC{
+				System: RPCSystemJSONRPC, Method: "a", RequestID: "1", Batch: 3, Outcome: RPCOutcomeError,
+				ErrorCode: -32601, ErrorMessage: "Method not found",
+			},
+		},
+		{
+			name:  "Unmatched",
+			calls: []JSONRPCMessage{{ID: "1", Method: "a"}},
+			want:  &RPC{System: RPCSystemJSONRPC, Method: "a", RequestID: "1", Outcome: RPCOutcomeUnknown},
+		},
+		{
+			name:  "Notification",
+			calls: []JSONRPCMessage{{Method: "notify"}},
+			want:  &RPC{System: RPCSystemJSONRPC, Method: "notify", Outcome: RPCOutcomeOK},
+		},
+		{
+			name:  "NoRequest",
+			calls: []JSONRPCMessage{{ID: "1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewJSONRPC(tt.calls, tt.replies))
+		})
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+// Operation 依据 OpenAPI 描述匹配出的 HTTP 接口
+//
+// Route 为包含 base path 的路由模板 如 /v1/users/{id} ID 为 operationId 描述中未声明时为空
+type Operation struct {
+	ID    string `json:",omitempty"`
+	Route string
+}
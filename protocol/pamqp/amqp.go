@@ -24,6 +24,10 @@ import (
 
 func init() {
 	protocol.Register(socket.L7ProtoAMQP, NewConnPool)
+	protocol.Describe(socket.L7ProtoAMQP, protocol.Capability{
+		Versions: []string{"0-9-1"},
+		Options:  []string{protocol.OptMaxPayloadSize},
+	})
 }
 
 const maxRecordSize = 128
@@ -62,10 +66,11 @@ func NewConnPool(opts common.Options) protocol.ConnPool {
 			})
 		},
 		func(pair *role.Pair) socket.RoundTrip {
-			return &RoundTrip{
-				request:  pair.Request.Obj.(*Request),
-				response: pair.Response.Obj.(*Response),
+			rt := &RoundTrip{request: pair.Request.Obj.(*Request)}
+			if pair.Response != nil {
+				rt.response = pair.Response.Obj.(*Response)
 			}
+			return rt
 		},
 		func(st socket.Tuple, serverPort socket.Port) protocol.Decoder {
 			return NewDecoder(st, serverPort, opts)
@@ -85,6 +90,10 @@ type Request struct {
 	ClassMethod *NamedClassMethod
 	FrameType   string
 	ErrCode     string
+
+	// ServerHost / ServerPort 仅在单向事件中填充 此时没有 Response 可以提供 Broker 地址
+	ServerHost string `json:",omitempty"`
+	ServerPort uint16 `json:",omitempty"`
 }
 
 // Response AMQP 响应
@@ -122,9 +131,20 @@ func (rt RoundTrip) Response() any {
 }
 
 func (rt RoundTrip) Duration() time.Duration {
+	if rt.OneWay() {
+		return 0
+	}
 	return rt.response.Time.Sub(rt.request.Time)
 }
 
 func (rt RoundTrip) Validate() bool {
+	if rt.OneWay() {
+		return true
+	}
 	return rt.response.Time.After(rt.request.Time)
 }
+
+// OneWay 实现了 socket.OneWayRoundTrip 接口
+func (rt RoundTrip) OneWay() bool {
+	return rt.response == nil
+}
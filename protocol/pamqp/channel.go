@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol"
 	"github.com/packetd/packetd/protocol/role"
 )
 
@@ -27,10 +28,13 @@ const (
 )
 
 var (
-	errInvalidBytes      = newError("invalid bytes")
-	errDecodeHeader      = newError("decode header failed")
-	errDecodeString      = newError("decode string failed")
-	errDecodeClassMethod = newError("decode class method failed")
+	errResyncFailed      = newError(protocol.ErrCodeResyncFailed, "frame boundary lost")
+	errInvalidFrameType  = newError(protocol.ErrCodeResyncFailed, "invalid frame type")
+	errHeaderTooShort    = newError(protocol.ErrCodeHeaderTooShort, "header too short")
+	errFieldTooShort     = newError(protocol.ErrCodeHeaderTooShort, "frame fields too short")
+	errPayloadOverflow   = newError(protocol.ErrCodeLengthOverflow, "payload length overflow")
+	errDecodeString      = newError(protocol.ErrCodeLengthOverflow, "decode string failed")
+	errDecodeClassMethod = newError(protocol.ErrCodeMalformed, "unknown class method")
 )
 
 type channelDecoder struct {
@@ -55,6 +59,8 @@ type channelDecoder struct {
 	drainBytes        int
 	reqTime           time.Time
 	closed            bool
+	confirm           bool // channel 已开启 Confirm 模式 Publish 会收到 Basic.Ack / Basic.Nack
+	discarding        bool // 方法帧被跳过 随后的内容帧不再归档
 }
 
 func newChannelDecoder(id uint16, st socket.TupleRaw, serverPort socket.Port) *channelDecoder {
@@ -72,7 +78,7 @@ func (cd *channelDecoder) Decode(b []byte, t time.Time) (*role.Object, error) {
 	// 解析 header 获取 payload / flags 等信息
 	if cd.state == stateDecodeHeader {
 		if len(b) < headerHeadLength {
-			return nil, errDecodeHeader
+			return nil, errHeaderTooShort
 		}
 		err := cd.decodeHeader(b[:headerHeadLength])
 		if err != nil {
@@ -80,6 +86,9 @@ func (cd *channelDecoder) Decode(b []byte, t time.Time) (*role.Object, error) {
 		}
 		b = b[headerHeadLength:]              // 切割剩余数据
 		cd.payloadConsumed -= headerEndLength // header end 会在 payload 计算 consumed 被记进去 所以这里需要先减去
+		if cd.frameType == frameMethod {
+			cd.discarding = false
+		}
 	}
 
 	complete, err := cd.decodePayload(b)
@@ -88,11 +97,21 @@ func (cd *channelDecoder) Decode(b []byte, t time.Time) (*role.Object, error) {
 		return nil, err
 	}
 	if complete {
+		if cd.discarding {
+			cd.reset()
+			return nil, nil
+		}
 		return cd.archive(), nil
 	}
 	return nil, nil
 }
 
+// discard 丢弃进行中的消息 用于方法帧超长被跳过的场景
+func (cd *channelDecoder) discard() {
+	cd.reset()
+	cd.discarding = true
+}
+
 func (cd *channelDecoder) Free() {
 	cd.packet = nil
 }
@@ -106,7 +125,7 @@ func (cd *channelDecoder) decodeHeader(b []byte) error {
 	cd.drainBytes += len(b)
 	payloadLen := binary.BigEndian.Uint32(b[3:7]) // 上层已经判断其长度了 可直接取值
 	if payloadLen > maxPayloadSize {
-		return errDecodeHeader
+		return errPayloadOverflow
 	}
 
 	if cd.isClient() {
@@ -168,7 +187,7 @@ func (cd *channelDecoder) archive() *role.Object {
 	}
 
 	if cd.isClient() {
-		obj := role.NewRequestObject(&Request{
+		req := &Request{
 			ChannelID:   cd.id,
 			FrameType:   frameNames[cd.frameType],
 			Size:        cd.drainBytes,
@@ -179,7 +198,15 @@ func (cd *channelDecoder) archive() *role.Object {
 			Packet:      cd.packet,
 			ClassMethod: ncm,
 			ErrCode:     matchErrCode(cd.errCode),
-		})
+		}
+
+		// 非 Confirm 模式下 Broker 不会对 Basic.Publish 做任何应答
+		obj := role.NewRequestObject(req)
+		if cd.cm.ClassID == classBasic && cd.cm.MethodID == 40 && !cd.confirm {
+			req.ServerHost = cd.st.DstIP
+			req.ServerPort = cd.st.DstPort
+			obj = role.NewOneWayObject(req)
+		}
 		cd.reset()
 		return obj
 	}
@@ -228,7 +255,7 @@ func (cd *channelDecoder) reset() {
 // 对于其他 `非重要` 的字段 节省 CPU 不做判断
 func (cd *channelDecoder) decodeFrameMethod(b []byte) error {
 	if len(b) < 4 {
-		return errInvalidBytes
+		return errFieldTooShort
 	}
 
 	cm := classMethod{
@@ -256,6 +283,11 @@ func (cd *channelDecoder) decodeFrameMethod(b []byte) error {
 		cd.closed = true // 此状态不会重置
 	}
 
+	// Confirm.Select 之后 channel 一直处于 Confirm 模式 直至关闭
+	if cd.cm.ClassID == classConfirm && cd.cm.MethodID == 10 {
+		cd.confirm = true // 此状态不会重置
+	}
+
 	fr, ok := fieldRequestMap[cd.cm]
 	if ok && len(b) > 4 {
 		return cd.decodeFieldRequests(b[4:], fr)
@@ -278,13 +310,13 @@ func (cd *channelDecoder) decodeFrameMethod(b []byte) error {
 // Props 字段不做解析
 func (cd *channelDecoder) decodeFrameContentHeader(b []byte) error {
 	if len(b) < 12 {
-		return errInvalidBytes
+		return errFieldTooShort
 	}
 
 	classID := binary.BigEndian.Uint16(b[0:2])
 	bodySize := binary.BigEndian.Uint64(b[4:12])
 	if bodySize > maxPayloadSize {
-		return errDecodeHeader
+		return errPayloadOverflow
 	}
 
 	_, ok := classNames[classID]
@@ -343,7 +375,7 @@ func (cd *channelDecoder) decodeFieldRequests(b []byte, fr fieldRequest) error {
 	decodeString := func(p *string) error {
 		var err error
 		if len(b) <= skip {
-			return errInvalidBytes
+			return errFieldTooShort
 		}
 		*p, offset, err = decodeShortString(b[skip:])
 		if err != nil {
@@ -384,7 +416,7 @@ func (cd *channelDecoder) decodeFieldRequests(b []byte, fr fieldRequest) error {
 
 		case opErrCode:
 			if offset+2 >= len(b) {
-				return errInvalidBytes
+				return errFieldTooShort
 			}
 			cd.errCode = binary.BigEndian.Uint16(b[offset : offset+2])
 
@@ -395,7 +427,7 @@ func (cd *channelDecoder) decodeFieldRequests(b []byte, fr fieldRequest) error {
 
 	// 解析轮次要求等于 ops 长度
 	if round != len(ops) || skip > len(b) {
-		return errInvalidBytes
+		return errFieldTooShort
 	}
 
 	cd.packet = &Packet{
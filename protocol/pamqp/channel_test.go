@@ -1045,3 +1045,81 @@ func TestDecodeShortString(t *testing.T) {
 		})
 	}
 }
+
+func TestChannelDecoderOneWay(t *testing.T) {
+	publish := [][]byte{
+		{
+			0x01, // Method Frame
+			0x00, 0x01,
+			0x00, 0x00, 0x00, 0x14,
+			0x00, 0x3C, 0x00, 0x28,
+			0x00, 0x05, 'a', 'm', 'q', 'p', '.', 'd', 'i', 'r',
+			0x00, 0x03, 'k', 'e', 'y',
+			0x00,
+			0xCE,
+		},
+		{
+			0x02, // ContentHeader Frame
+			0x00, 0x01,
+			0x00, 0x00, 0x00, 0x0D,
+			0x00, 0x3C, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0B,
+			0x02,
+			0xCE,
+		},
+		{
+			0x03, // ContentBody Frame
+			0x00, 0x01,
+			0x00, 0x00, 0x00, 0x0B,
+			'h', 'e', 'l', 'l', 'o', ' ', 'w', 'o', 'r', 'l', 'd',
+			0xCE,
+		},
+	}
+	confirmSelect := []byte{
+		0x01, // Method Frame
+		0x00, 0x01,
+		0x00, 0x00, 0x00, 0x05,
+		0x00, 0x55, 0x00, 0x0A,
+		0x00,
+		0xCE,
+	}
+
+	st := socket.TupleRaw{SrcIP: "10.0.0.1", DstIP: "10.0.0.2", SrcPort: 50000, DstPort: 5672}
+	decodePublish := func(cd *channelDecoder) *role.Object {
+		var obj *role.Object
+		for _, chunk := range publish {
+			o, err := cd.Decode(chunk, time.Time{})
+			assert.NoError(t, err)
+			if o != nil {
+				obj = o
+			}
+		}
+		return obj
+	}
+
+	t.Run("WithoutConfirm", func(t *testing.T) {
+		cd := newChannelDecoder(1, st, 5672)
+		obj := decodePublish(cd)
+		assert.Equal(t, role.Role(role.OneWay), obj.Role)
+
+		req := obj.Obj.(*Request)
+		assert.Equal(t, "10.0.0.2", req.ServerHost)
+		assert.Equal(t, uint16(5672), req.ServerPort)
+
+		rt := RoundTrip{request: req}
+		assert.True(t, socket.IsOneWay(rt))
+		assert.True(t, rt.Validate())
+		assert.Zero(t, rt.Duration())
+	})
+
+	t.Run("ConfirmSelect", func(t *testing.T) {
+		cd := newChannelDecoder(1, st, 5672)
+		obj, err := cd.Decode(confirmSelect, time.Time{})
+		assert.NoError(t, err)
+		assert.Equal(t, "Select", obj.Obj.(*Request).ClassMethod.Method)
+
+		obj = decodePublish(cd)
+		assert.Equal(t, role.Role(role.Request), obj.Role)
+		assert.Empty(t, obj.Obj.(*Request).ServerHost)
+	})
+}
@@ -19,11 +19,10 @@ import (
 	"math"
 	"time"
 
-	"github.com/pkg/errors"
-
 	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/bufpool"
+	"github.com/packetd/packetd/internal/tailbuf"
 	"github.com/packetd/packetd/internal/zerocopy"
 	"github.com/packetd/packetd/protocol"
 	"github.com/packetd/packetd/protocol/role"
@@ -53,9 +52,8 @@ func validateFrameType(b byte) bool {
 	}
 }
 
-func newError(format string, args ...any) error {
-	format = "amqp/decoder: " + format
-	return errors.Errorf(format, args...)
+func newError(code protocol.ErrorCode, format string, args ...any) error {
+	return protocol.NewDecodeError(socket.L7ProtoAMQP, code, "amqp/decoder: "+format, args...)
 }
 
 // state 记录着 decoder 的处理状态
@@ -78,7 +76,7 @@ const (
 	// 0xCE
 	headerEndLength = 1
 
-	// maxPayloadSize 最大 payload 大小
+	// maxPayloadSize 最大 payload 大小 即 Connection.Tune 协商 frame-max 允许的上限
 	maxPayloadSize = 2147483647
 )
 
@@ -91,25 +89,29 @@ type decoder struct {
 	prevData *channelData
 	channels map[uint16]*channelDecoder
 
-	tail    []byte // 尾部数据拼接 仅允许拼接一次 避免上一轮切割了部分数据
-	partial uint8  // 标记上一轮的 header 是否待拼接
+	tail    tailbuf.Buffer // 尾部数据拼接 仅允许拼接一次 避免上一轮切割了部分数据
+	partial uint8          // 标记上一轮的 header 是否待拼接
+	opaque  bool           // 链接使用了不支持的协议版本 不再解析
+	guard   protocol.PayloadGuard
 }
 
-func NewDecoder(st socket.Tuple, serverPort socket.Port, _ common.Options) protocol.Decoder {
+func NewDecoder(st socket.Tuple, serverPort socket.Port, opts common.Options) protocol.Decoder {
 	return &decoder{
 		st:         st.ToRaw(),
 		serverPort: serverPort,
 		rbuf:       bufpool.Acquire(),
 		prevData:   &channelData{},
 		channels:   make(map[uint16]*channelDecoder),
+		guard:      protocol.NewPayloadGuard(socket.L7ProtoAMQP, opts, maxPayloadSize),
 	}
 }
 
 type channelData struct {
-	id    uint16
-	data  []byte
-	tail  []byte
-	lackN uint32
+	id      uint16
+	data    []byte
+	tail    []byte
+	lackN   uint32
+	skipped bool // 超长帧已被跳过 仅 tail 有效
 }
 
 // Decode 从 zerocopy.Reader 解析 AMQP 二进制帧数据流 构建完整 RoundTrip
@@ -145,22 +147,38 @@ func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error)
 	d.t0 = t
 
 	b, err := r.Read(common.ReadWriteBlockSize)
-	if err != nil {
+	if err != nil || d.opaque {
 		return nil, nil
 	}
 	defer d.rbuf.Reset()
 
+	// 协议头仅出现在链接的起始位置
+	if d.prevData.lackN == 0 && d.partial == 0 {
+		if tail, version, ok := decodeProtocolHeader(b); ok {
+			if version != "" {
+				d.opaque = true
+				return []*role.Object{d.protocolHeaderObject(version, t)}, nil
+			}
+			b = tail
+		}
+	}
+
 	var objs []*role.Object
 	for len(b) > 0 {
+		if d.guard.Skipping() {
+			_, b = d.guard.Drain(b)
+			continue
+		}
+
 		// 如果已经出现过两次拼接 返回解析错误
 		if d.partial > 1 {
-			return nil, errInvalidBytes
+			return nil, errResyncFailed
 		}
 
 		// 如果上一轮待拼接的数据 则追加在开头
 		// 使用 buffer 避免重复分配内存
 		if d.partial == 1 {
-			d.rbuf.Write(d.tail)
+			d.rbuf.Write(d.tail.Bytes())
 			d.rbuf.Write(b)
 			b = d.rbuf.Bytes()
 		}
@@ -177,6 +195,11 @@ func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error)
 				}
 				return nil, err
 			}
+			if data.skipped {
+				d.partial = 0
+				b = data.tail
+				continue
+			}
 		} else {
 			// 如果上一轮解析中数据还没读取完毕
 			data.id = d.prevData.id
@@ -224,6 +247,7 @@ func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error)
 
 func (d *decoder) Free() {
 	bufpool.Release(d.rbuf)
+	d.tail.Free()
 }
 
 func (d *decoder) getOrCreateChannel(id uint16) *channelDecoder {
@@ -298,8 +322,8 @@ func (d *decoder) deleteChannel(id uint16) {
 func (d *decoder) decodeHeader(b []byte) (*channelData, error) {
 	if len(b) < headerHeadLength {
 		d.partial++
-		d.tail = bytes.Clone(b)
-		return nil, errDecodeHeader
+		d.tail.Set(b)
+		return nil, errHeaderTooShort
 	}
 
 	var data []byte
@@ -307,7 +331,7 @@ func (d *decoder) decodeHeader(b []byte) (*channelData, error) {
 	var lackN uint32
 
 	if !validateFrameType(b[0]) {
-		return nil, errDecodeHeader
+		return nil, errInvalidFrameType
 	}
 
 	channelID := binary.BigEndian.Uint16(b[1:3])
@@ -315,7 +339,7 @@ func (d *decoder) decodeHeader(b []byte) (*channelData, error) {
 
 	if payloadLen > maxPayloadSize {
 		d.partial++ // 连续两次则上层需要判为异常
-		return nil, errDecodeHeader
+		return nil, errPayloadOverflow
 	}
 
 	total := headerHeadLength + payloadLen + headerEndLength // 计算总长度
@@ -331,6 +355,15 @@ func (d *decoder) decodeHeader(b []byte) (*channelData, error) {
 		lackN = total - uint32(len(b))
 	}
 
+	// 超长的方法帧不再解析 按照声明长度排空 所属 channel 丢弃随后的内容帧直至下一个方法帧
+	// ContentHeader/ContentBody 帧仅解析固定长度的字段 无需跳过
+	if b[0] == frameMethod && d.guard.Exceeded(int(payloadLen)) {
+		d.getOrCreateChannel(channelID).discard()
+		d.guard.Skip(int(total))
+		_, tail = d.guard.Drain(b)
+		return &channelData{tail: tail, skipped: true}, nil
+	}
+
 	return &channelData{
 		id:    channelID,
 		data:  data,
@@ -22,6 +22,7 @@ import (
 	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol"
 	"github.com/packetd/packetd/protocol/role"
 )
 
@@ -164,3 +165,95 @@ func TestDecoderDecode(t *testing.T) {
 		})
 	}
 }
+
+func TestDecoderSkipOversized(t *testing.T) {
+	var st socket.Tuple
+	opts := common.NewOptions()
+	opts.Merge(protocol.OptMaxPayloadSize, 8)
+	dec := NewDecoder(st, 0, opts)
+	defer dec.Free()
+
+	// Channel1 Connection.Start 超过长度限制
+	oversized := []byte{
+		0x01,
+		0x00, 0x01,
+		0x00, 0x00, 0x00, 0x0E,
+		0x00, 0x0A, 0x00, 0x0A,
+		0x00, 0x00, 0x00, 0x00,
+		0x05, 'P', 'L', 'A', 'I', 'N',
+		0xCE,
+	}
+	// Channel1 Channel.Open
+	open := []byte{
+		0x01,
+		0x00, 0x01,
+		0x00, 0x00, 0x00, 0x06,
+		0x00, 0x14, 0x00, 0x0A, 0x00, 0x00,
+		0xCE,
+	}
+
+	t0 := time.Now()
+	objs, err := dec.Decode(zerocopy.NewBuffer(oversized[:10]), t0)
+	assert.NoError(t, err)
+	assert.Nil(t, objs)
+
+	objs, err = dec.Decode(zerocopy.NewBuffer(append(oversized[10:], open...)), t0)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	req := objs[0].Obj.(*Request)
+	assert.Equal(t, "Channel", req.ClassMethod.Class)
+	assert.Equal(t, len(open), req.Size)
+}
+
+func TestDecodeProtocolHeader(t *testing.T) {
+	st := socket.Tuple{SrcPort: 50001, DstPort: 5672}
+	t0 := time.Unix(1700000000, 0)
+
+	// 0-9-1 的协议头直接跳过 继续解析后续的帧
+	d := NewDecoder(st, 5672, common.NewOptions())
+	objs, err := d.Decode(zerocopy.NewBuffer([]byte{
+		'A', 'M', 'Q', 'P', 0x00, 0x00, 0x09, 0x01,
+		0x01,
+		0x00, 0x01,
+		0x00, 0x00, 0x00, 0x06,
+		0x00, 0x14, 0x00, 0x0A, 0x00, 0x00,
+		0xCE,
+	}), t0)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.Equal(t, "Open", objs[0].Obj.(*Request).ClassMethod.Method)
+
+	// 1.0 仅生成协议头对象 后续数据不再解析
+	ncm := &NamedClassMethod{Class: "Connection", Method: "ProtocolHeader"}
+	d = NewDecoder(st, 5672, common.NewOptions())
+	objs, err = d.Decode(zerocopy.NewBuffer([]byte{'A', 'M', 'Q', 'P', 0x03, 0x01, 0x00, 0x00}), t0)
+	assert.NoError(t, err)
+	assert.Equal(t, []*role.Object{role.NewRequestObject(&Request{
+		Host:        "0.0.0.0",
+		Port:        50001,
+		Proto:       "AMQP/1.0.0",
+		Size:        8,
+		Time:        t0,
+		Packet:      &Packet{},
+		ClassMethod: ncm,
+		FrameType:   "ProtocolHeader",
+	})}, objs)
+
+	objs, err = d.Decode(zerocopy.NewBuffer([]byte{0x00, 0x00, 0x00, 0x1A, 0x02, 0x01, 0x00, 0x00}), t0)
+	assert.NoError(t, err)
+	assert.Nil(t, objs)
+
+	d = NewDecoder(st.Mirror(), 5672, common.NewOptions())
+	objs, err = d.Decode(zerocopy.NewBuffer([]byte{'A', 'M', 'Q', 'P', 0x03, 0x01, 0x00, 0x00}), t0)
+	assert.NoError(t, err)
+	assert.Equal(t, []*role.Object{role.NewResponseObject(&Response{
+		Host:        "0.0.0.0",
+		Port:        5672,
+		Proto:       "AMQP/1.0.0",
+		Size:        8,
+		Time:        t0,
+		Packet:      &Packet{},
+		ClassMethod: ncm,
+		FrameType:   "ProtocolHeader",
+	})}, objs)
+}
@@ -0,0 +1,85 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pamqp
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+// protocolHeaderLength 链接建立时客户端（AMQP 1.0 中双方均会）发送的协议头长度
+//
+// ┌─────┬─────┬─────┬─────┬─────────────┬───────┬───────┬──────────┐
+// │ 'A' │ 'M' │ 'Q' │ 'P' │ protocol id │ major │ minor │ revision │
+// └─────┴─────┴─────┴─────┴─────────────┴───────┴───────┴──────────┘
+//
+// * 0-9-1: 'AMQP' 0 0 9 1
+// * 1.0:   'AMQP' id 1 0 0 其中 id 0: AMQP / 2: TLS / 3: SASL
+const protocolHeaderLength = 8
+
+var (
+	charAMQP        = []byte("AMQP")
+	protocolVer091  = []byte{0, 0, 9, 1}
+	methodProtoHead = "ProtocolHeader"
+)
+
+// decodeProtocolHeader 解析协议头 ok 为 false 表示 b 不是协议头
+//
+// 返回协议头之后的数据 version 非空时代表 decoder 不支持该版本
+func decodeProtocolHeader(b []byte) (tail []byte, version string, ok bool) {
+	if len(b) < protocolHeaderLength || !bytes.HasPrefix(b, charAMQP) {
+		return nil, "", false
+	}
+	if bytes.Equal(b[4:protocolHeaderLength], protocolVer091) {
+		return b[protocolHeaderLength:], "", true
+	}
+	return b[protocolHeaderLength:], fmt.Sprintf("%d.%d.%d", b[5], b[6], b[7]), true
+}
+
+// protocolHeaderObject 为不支持的版本生成仅包含大小以及耗时的对象
+//
+// 双方的协议头可以配对成一次 roundtrip 即协议握手耗时 此后链接中的数据不再解析
+func (d *decoder) protocolHeaderObject(version string, t time.Time) *role.Object {
+	protocol.RecordUnsupportedVersion(socket.L7ProtoAMQP, version)
+
+	ncm := &NamedClassMethod{Class: classNames[classConnection], Method: methodProtoHead}
+	proto := PROTO + "/" + version
+	if d.st.DstPort == uint16(d.serverPort) {
+		return role.NewRequestObject(&Request{
+			Host:        d.st.SrcIP,
+			Port:        d.st.SrcPort,
+			Proto:       proto,
+			Size:        protocolHeaderLength,
+			Time:        t,
+			Packet:      &Packet{},
+			ClassMethod: ncm,
+			FrameType:   methodProtoHead,
+		})
+	}
+	return role.NewResponseObject(&Response{
+		Host:        d.st.SrcIP,
+		Port:        d.st.SrcPort,
+		Proto:       proto,
+		Size:        protocolHeaderLength,
+		Time:        t,
+		Packet:      &Packet{},
+		ClassMethod: ncm,
+		FrameType:   methodProtoHead,
+	})
+}
@@ -0,0 +1,76 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+)
+
+// OptMaxPayloadSize 单个消息（或帧）允许解析的最大 payload 长度 超出时按照声明长度整体跳过
+//
+// 未配置或者超出协议本身的上限时使用协议上限
+const OptMaxPayloadSize = "maxPayloadSize"
+
+// PayloadGuard 协议消息长度守卫
+//
+// 超出限制的消息不再解析 而是按照报文声明的长度排空对应字节
+// 相比于直接重置 decoder 排空可以保证后续的消息边界依然正确
+type PayloadGuard struct {
+	limit   int
+	remain  int
+	skipped prometheus.Counter
+}
+
+// NewPayloadGuard 创建长度守卫 hardLimit 为协议报文格式所能表达的最大长度
+func NewPayloadGuard(proto socket.L7Proto, opts common.Options, hardLimit int) PayloadGuard {
+	limit, err := opts.GetInt(OptMaxPayloadSize)
+	if err != nil || limit <= 0 || limit > hardLimit {
+		limit = hardLimit
+	}
+	return PayloadGuard{
+		limit:   limit,
+		skipped: NewOptionCounter(proto, OptMaxPayloadSize, "skipped"),
+	}
+}
+
+// Limit 返回生效的长度上限
+func (g *PayloadGuard) Limit() int {
+	return g.limit
+}
+
+// Exceeded 判断声明长度 n 是否超出限制
+func (g *PayloadGuard) Exceeded(n int) bool {
+	return n > g.limit
+}
+
+// Skip 标记后续 n 字节需要被排空
+func (g *PayloadGuard) Skip(n int) {
+	g.skipped.Inc()
+	g.remain = n
+}
+
+// Skipping 是否仍有待排空的字节
+func (g *PayloadGuard) Skipping() bool {
+	return g.remain > 0
+}
+
+// Drain 排空 b 中属于被跳过消息的部分 返回排空的字节数以及剩余数据
+func (g *PayloadGuard) Drain(b []byte) (int, []byte) {
+	n := min(g.remain, len(b))
+	g.remain -= n
+	return n, b[n:]
+}
@@ -0,0 +1,68 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+)
+
+func TestPayloadGuard(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  common.Options
+		limit int
+	}{
+		{
+			name:  "default",
+			opts:  common.NewOptions(),
+			limit: 1024,
+		},
+		{
+			name:  "configured",
+			opts:  common.Options{OptMaxPayloadSize: 16},
+			limit: 16,
+		},
+		{
+			name:  "beyond hard limit",
+			opts:  common.Options{OptMaxPayloadSize: 4096},
+			limit: 1024,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewPayloadGuard(socket.L7ProtoMySQL, tt.opts, 1024)
+			assert.Equal(t, tt.limit, g.Limit())
+			assert.False(t, g.Exceeded(tt.limit))
+			assert.True(t, g.Exceeded(tt.limit+1))
+		})
+	}
+
+	g := NewPayloadGuard(socket.L7ProtoMySQL, nil, 1024)
+	g.Skip(5)
+	n, tail := g.Drain([]byte("abc"))
+	assert.Equal(t, 3, n)
+	assert.Empty(t, tail)
+	assert.True(t, g.Skipping())
+
+	n, tail = g.Drain([]byte("defgh"))
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []byte("fgh"), tail)
+	assert.False(t, g.Skipping())
+}
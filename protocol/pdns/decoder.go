@@ -33,6 +33,10 @@ const (
 	PROTO = "DNS"
 )
 
+func newError(code protocol.ErrorCode, format string, args ...any) error {
+	return protocol.NewDecodeError(socket.L7ProtoDNS, code, "dns/decoder: "+format, args...)
+}
+
 // Message 报文布局
 //
 // rfc: https://www.ietf.org/rfc/rfc1035.txt 4.1. Format
@@ -104,9 +108,11 @@ func NewDecoder(st socket.Tuple, _ socket.Port, _ common.Options) protocol.Decod
 // - Authority Section (AA)
 // - Additional Section (AD)
 //
-// 因为单数据包即可完成请求 不再区分 Reqeust / Response Time 直接按 t0 归档即可 也无须重置
+// 因为单数据包即可完成请求 不再区分 Reqeust / Response Time 直接按 t0 归档即可
+// 同一个四元组可能先后发送多个数据包 字节数需按数据包重新计数
 func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error) {
 	d.t0 = t
+	d.drainBytes = 0
 
 	b, err := r.Read(common.ReadWriteBlockSize)
 	if err != nil {
@@ -115,7 +121,8 @@ func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error)
 
 	obj, err := d.decode(b)
 	if err != nil {
-		return nil, err
+		// 单个数据包即为完整报文 不存在中途接入的情况 解析失败均视为报文异常
+		return nil, newError(protocol.ErrCodeMalformed, "%v", err)
 	}
 
 	if obj == nil {
@@ -549,3 +549,44 @@ func TestDecodeFailed(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeMultiplePackets(t *testing.T) {
+	var st socket.Tuple
+	var t0 time.Time
+	d := NewDecoder(st, 0, common.NewOptions())
+
+	// 同一四元组先后发送两个数据包 字节数按数据包分别计数
+	inputs := []struct {
+		input    []byte
+		question string
+		size     int
+	}{
+		{
+			input: buildQuestionMessage(dnsmessage.Question{
+				Name:  dnsmessage.MustNewName("example.com."),
+				Type:  dnsmessage.TypeA,
+				Class: dnsmessage.ClassINET,
+			}),
+			question: "example.com.",
+			size:     29,
+		},
+		{
+			input: buildQuestionMessage(dnsmessage.Question{
+				Name:  dnsmessage.MustNewName("ipv6.example.com."),
+				Type:  dnsmessage.TypeAAAA,
+				Class: dnsmessage.ClassINET,
+			}),
+			question: "ipv6.example.com.",
+			size:     34,
+		},
+	}
+	for _, in := range inputs {
+		objs, err := d.Decode(zerocopy.NewBuffer(in.input), t0)
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+
+		req := objs[0].Obj.(*Request)
+		assert.Equal(t, in.size, req.Size)
+		assert.Equal(t, in.question, req.Message.QuestionSec.Name)
+	}
+}
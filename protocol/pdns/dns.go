@@ -14,6 +14,7 @@
 package pdns
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/packetd/packetd/common"
@@ -24,17 +25,38 @@ import (
 
 func init() {
 	protocol.Register(socket.L7ProtoDNS, NewConnPool)
+	protocol.Describe(socket.L7ProtoDNS, protocol.Capability{
+		Versions: []string{"rfc1035"},
+		Options:  []string{protocol.OptTransactionTimeout},
+	})
 }
 
-const maxRecordSize = 64
+const (
+	maxRecordSize = 64
+
+	// defaultTimeout 与 glibc resolver 默认超时保持一致
+	defaultTimeout = 5 * time.Second
+)
+
+// extractTransaction 以 Header.ID 作为事务 ID
+func extractTransaction(o *role.Object) (string, time.Time, bool) {
+	switch obj := o.Obj.(type) {
+	case *Request:
+		return strconv.Itoa(int(obj.Message.Header.ID)), obj.Time, true
+	case *Response:
+		return strconv.Itoa(int(obj.Message.Header.ID)), obj.Time, true
+	}
+	return "", time.Time{}, false
+}
 
 // NewConnPool 创建 DNS 协议连接池
 func NewConnPool(opts common.Options) protocol.ConnPool {
-	return protocol.NewL7UDPConnPool(
-		func() role.Matcher {
-			return role.NewListMatcher(maxRecordSize, func(req, rsp *role.Object) bool {
-				return req.Obj.(*Request).Message.Header.ID == rsp.Obj.(*Response).Message.Header.ID
-			})
+	return protocol.NewL7UDPTransactionConnPool(
+		opts,
+		protocol.UDPTransaction{
+			Size:    maxRecordSize,
+			Timeout: defaultTimeout,
+			Extract: extractTransaction,
 		},
 		func(pair *role.Pair) socket.RoundTrip {
 			return &RoundTrip{
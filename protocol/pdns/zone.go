@@ -0,0 +1,37 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pdns
+
+import (
+	"strings"
+)
+
+// Zone 保留域名末尾的 n 个 label 并统一为小写 如 n 为 2 时 a.b.Example.COM. 归入 example.com
+//
+// 随机子域名（如错误的 search 域拼接 DGA）按照上级域名聚合后才具备统计意义
+func Zone(name string, n int) string {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if name == "" {
+		return "."
+	}
+
+	idx := len(name)
+	for i := 0; i < n; i++ {
+		idx = strings.LastIndexByte(name[:idx], '.')
+		if idx < 0 {
+			return name
+		}
+	}
+	return name[idx+1:]
+}
@@ -0,0 +1,36 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pdns
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZone(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		want string
+	}{
+		{name: "a.b.Example.COM.", n: 2, want: "example.com"},
+		{name: "a.b.example.com", n: 3, want: "b.example.com"},
+		{name: "localhost.", n: 2, want: "localhost"},
+		{name: ".", n: 2, want: "."},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Zone(tt.name, tt.n))
+	}
+}
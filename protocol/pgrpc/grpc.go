@@ -31,8 +31,15 @@ const (
 
 func init() {
 	protocol.Register(socket.L7ProtoGRPC, NewConnPool)
+	protocol.Describe(socket.L7ProtoGRPC, protocol.Capability{
+		Versions: []string{"h2"},
+		Options:  []string{phttp2.OptTrailerKeys, phttp2.OptStreamIdleTimeout, phttp2.OptMaxStreams, phttp2.OptMessageFlushInterval, protocol.OptMaxPayloadSize},
+	})
 }
 
+// defaultMessageFlushInterval 流式调用默认的增量消息统计上报间隔
+const defaultMessageFlushInterval = 10 * time.Second
+
 const (
 	trailersGrpcMessage = "grpc-message"
 	trailersGrpcStatus  = "grpc-status"
@@ -40,21 +47,35 @@ const (
 
 // NewConnPool 创建 GRPC 协议连接池
 func NewConnPool(opts common.Options) protocol.ConnPool {
+	cs := protocol.NewConnContexts(0)
 	return protocol.NewL7TCPConnPool(
 		func() role.Matcher {
-			return role.NewListMatcher(phttp2.MaxConcurrentStreams, func(req, rsp *role.Object) bool {
-				return req.Obj.(*phttp2.Request).StreamID == rsp.Obj.(*phttp2.Response).StreamID
-			})
+			return phttp2.NewStreamMatcher()
 		},
 		func(pair *role.Pair) socket.RoundTrip {
-			return &RoundTrip{
+			// 流式调用尚未结束时上报的增量消息统计
+			if p, ok := pair.Request.Obj.(*phttp2.Progress); ok {
+				return &RoundTrip{request: fromHTTP2Progress(p)}
+			}
+			rt := &RoundTrip{
 				request:  fromHTTP2Request(pair.Request.Obj.(*phttp2.Request)),
 				response: fromHTTP2Response(pair.Response.Obj.(*phttp2.Response)),
 			}
+			// 客户端已放弃等待 服务端仍完成了处理 这部分工作是无效的
+			if rt.request.Timeout > 0 && rt.Duration() > rt.request.Timeout {
+				rt.response.PastDeadline = true
+			}
+			return rt
 		},
 		func(st socket.Tuple, serverPort socket.Port) protocol.Decoder {
 			opts.Merge(phttp2.OptTrailerKeys, []string{trailersGrpcStatus, trailersGrpcMessage})
-			return phttp2.NewDecoder(st, serverPort, opts)
+			opts.Merge(phttp2.OptCountMessages, true)
+			if v, err := opts.GetDuration(phttp2.OptMessageFlushInterval); err != nil || v == 0 {
+				opts.Merge(phttp2.OptMessageFlushInterval, defaultMessageFlushInterval)
+			}
+			return phttp2.NewConnDecoder(st, serverPort, opts, cs.Acquire(st, serverPort), func() {
+				cs.Release(st, serverPort)
+			})
 		},
 	)
 }
@@ -71,23 +92,62 @@ type Request struct {
 	Metadata http.Header
 	Size     int
 	Time     time.Time
+
+	ConcurrentStreams int
+	Messages          int
+	MessageSizes      []int
+	Client            *protocol.Client `json:",omitempty"`
+
+	// Trace metadata 中携带的 traceparent / tracestate
+	Trace *protocol.TraceContext `json:",omitempty"`
+
+	// Timeout 客户端通过 grpc-timeout 声明的超时时间 未声明或格式非法时为 0
+	Timeout time.Duration `json:",omitempty"`
+
+	// Progress 流式调用尚未结束时上报的增量消息统计 非空时 RoundTrip 为单向事件 不代表一次完整的调用
+	Progress *phttp2.Progress `json:",omitempty"`
 }
 
 func fromHTTP2Request(req *phttp2.Request) *Request {
+	timeout, _ := ParseTimeout(req.Header.Get(headerGrpcTimeout))
 	return &Request{
 		StreamID: req.StreamID,
 		Host:     req.Host,
 		Port:     req.Port,
 		Proto:    PROTO,
-		Service:  strings.ReplaceAll(strings.Trim(req.Path, "/"), "/", "."),
+		Service:  serviceOf(req.Path),
 		Scheme:   req.Scheme,
 		Target:   req.Authority,
 		Metadata: req.Header,
 		Size:     req.Size,
 		Time:     req.Time,
+
+		ConcurrentStreams: req.ConcurrentStreams,
+		Messages:          req.Messages,
+		MessageSizes:      req.MessageSizes,
+		Client:            req.Client,
+		Trace:             req.Trace,
+		Timeout:           timeout,
 	}
 }
 
+func fromHTTP2Progress(p *phttp2.Progress) *Request {
+	return &Request{
+		StreamID: p.StreamID,
+		Host:     p.Host,
+		Port:     p.Port,
+		Proto:    PROTO,
+		Service:  serviceOf(p.Path),
+		Time:     p.Time,
+		Progress: p,
+	}
+}
+
+// serviceOf 将 :path 转换为 package.Service.Method 形式
+func serviceOf(path string) string {
+	return strings.ReplaceAll(strings.Trim(path, "/"), "/", ".")
+}
+
 // Response GRPC 响应
 type Response struct {
 	StreamID uint32
@@ -98,6 +158,13 @@ type Response struct {
 	Metadata http.Header
 	Size     int
 	Time     time.Time
+
+	MaxConcurrentStreams uint32
+	Messages             int
+	MessageSizes         []int
+
+	// PastDeadline 响应晚于请求声明的 grpc-timeout 到达
+	PastDeadline bool `json:",omitempty"`
 }
 
 func fromHTTP2Response(rsp *phttp2.Response) *Response {
@@ -110,6 +177,10 @@ func fromHTTP2Response(rsp *phttp2.Response) *Response {
 		Metadata: rsp.Header,
 		Size:     rsp.Size,
 		Time:     rsp.Time,
+
+		MaxConcurrentStreams: rsp.MaxConcurrentStreams,
+		Messages:             rsp.Messages,
+		MessageSizes:         rsp.MessageSizes,
 	}
 }
 
@@ -136,9 +207,20 @@ func (rt RoundTrip) Response() any {
 }
 
 func (rt RoundTrip) Duration() time.Duration {
+	if rt.OneWay() {
+		return 0
+	}
 	return rt.response.Time.Sub(rt.request.Time)
 }
 
 func (rt RoundTrip) Validate() bool {
+	if rt.OneWay() {
+		return true
+	}
 	return rt.response.Time.After(rt.request.Time)
 }
+
+// OneWay 实现了 socket.OneWayRoundTrip 接口 仅 Progress 没有响应
+func (rt RoundTrip) OneWay() bool {
+	return rt.response == nil
+}
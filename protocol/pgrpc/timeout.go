@@ -0,0 +1,63 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgrpc
+
+import (
+	"time"
+)
+
+const headerGrpcTimeout = "grpc-timeout"
+
+// maxTimeoutDigits grpc-timeout 数值部分最多 8 位
+const maxTimeoutDigits = 8
+
+// ParseTimeout 解析 grpc-timeout 头 格式为 `TimeoutValue TimeoutUnit`
+//
+// TimeoutValue 为至多 8 位的正整数 TimeoutUnit 取值如下
+// H: 时 M: 分 S: 秒 m: 毫秒 u: 微秒 n: 纳秒
+//
+// https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-HTTP2.md
+func ParseTimeout(s string) (time.Duration, bool) {
+	if len(s) < 2 || len(s) > maxTimeoutDigits+1 {
+		return 0, false
+	}
+
+	var unit time.Duration
+	switch s[len(s)-1] {
+	case 'H':
+		unit = time.Hour
+	case 'M':
+		unit = time.Minute
+	case 'S':
+		unit = time.Second
+	case 'm':
+		unit = time.Millisecond
+	case 'u':
+		unit = time.Microsecond
+	case 'n':
+		unit = time.Nanosecond
+	default:
+		return 0, false
+	}
+
+	var n int64
+	for i := 0; i < len(s)-1; i++ {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int64(c-'0')
+	}
+	return time.Duration(n) * unit, true
+}
@@ -0,0 +1,51 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgrpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimeout(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+		ok    bool
+	}{
+		{input: "1H", want: time.Hour, ok: true},
+		{input: "2M", want: 2 * time.Minute, ok: true},
+		{input: "30S", want: 30 * time.Second, ok: true},
+		{input: "100m", want: 100 * time.Millisecond, ok: true},
+		{input: "250u", want: 250 * time.Microsecond, ok: true},
+		{input: "99999999n", want: 99999999 * time.Nanosecond, ok: true},
+		{input: "123456789m"},
+		{input: "10"},
+		{input: "m"},
+		{input: ""},
+		{input: "1.5S"},
+		{input: "-1S"},
+		{input: "10s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := ParseTimeout(tt.input)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
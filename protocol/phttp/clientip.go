@@ -0,0 +1,157 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package phttp
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/packetd/packetd/common"
+)
+
+const (
+	// OptExtractClientIPHeaders 依次从这些 Header 中解析真实客户端地址 记录在 Request.ClientIP 中
+	OptExtractClientIPHeaders = "extractClientIPHeaders"
+
+	// OptTrustedProxies 可信代理地址段 仅当链接对端位于其中时才采信 Header 中的地址
+	OptTrustedProxies = "trustedProxies"
+)
+
+// clientIPResolver 解析 L7 代理 / 负载均衡写入的客户端地址
+//
+// 未配置 trustedProxies 时采信全部 Header 取地址链最左侧的地址
+// 配置后仅在链接对端为可信代理时解析 自右向左跳过可信代理 第一个不可信的地址即为客户端
+type clientIPResolver struct {
+	headers []string
+	trusted []netip.Prefix
+}
+
+// newClientIPResolver 从 opts 中读取配置 未配置任何 Header 时返回 nil
+//
+// trustedProxies 支持 CIDR 以及单个地址 无法解析的条目直接忽略
+func newClientIPResolver(opts common.Options) *clientIPResolver {
+	headers, _ := opts.GetStringSlice(OptExtractClientIPHeaders)
+	if len(headers) == 0 {
+		return nil
+	}
+
+	r := &clientIPResolver{}
+	for _, h := range headers {
+		r.headers = append(r.headers, http.CanonicalHeaderKey(h))
+	}
+
+	proxies, _ := opts.GetStringSlice(OptTrustedProxies)
+	for _, s := range proxies {
+		if prefix, err := netip.ParsePrefix(s); err == nil {
+			r.trusted = append(r.trusted, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(s); err == nil {
+			addr = addr.Unmap()
+			r.trusted = append(r.trusted, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return r
+}
+
+// isTrusted 判断 addr 是否为可信代理
+func (r *clientIPResolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve 返回客户端地址 peer 为链接对端地址 无法解析时返回空
+func (r *clientIPResolver) resolve(h http.Header, peer string) string {
+	if r == nil || len(h) == 0 {
+		return ""
+	}
+
+	// 对端不可信时 Header 可能由客户端伪造
+	if len(r.trusted) > 0 {
+		addr, err := netip.ParseAddr(peer)
+		if err != nil || !r.isTrusted(addr.Unmap()) {
+			return ""
+		}
+	}
+
+	for _, k := range r.headers {
+		values := h[k]
+		if len(values) == 0 {
+			continue
+		}
+
+		var chain []string
+		for _, v := range values {
+			if k == "Forwarded" {
+				chain = append(chain, forwardedFor(v)...)
+			} else {
+				chain = append(chain, strings.Split(v, ",")...)
+			}
+		}
+		if addr, ok := r.pick(chain); ok {
+			return addr.String()
+		}
+	}
+	return ""
+}
+
+// pick 从代理按顺序追加的地址链中挑选客户端地址
+//
+// 遇到无法解析的地址（如 unknown / _hidden）即停止 其左侧的地址不再可信
+func (r *clientIPResolver) pick(chain []string) (netip.Addr, bool) {
+	var last netip.Addr
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, ok := parseNode(chain[i])
+		if !ok {
+			break
+		}
+		last = addr
+		if len(r.trusted) > 0 && !r.isTrusted(addr) {
+			return addr, true
+		}
+	}
+	return last, last.IsValid()
+}
+
+// forwardedFor 提取 RFC 7239 Forwarded Header 中各个元素的 for 参数
+func forwardedFor(v string) []string {
+	var nodes []string
+	for _, elem := range strings.Split(v, ",") {
+		for _, pair := range strings.Split(elem, ";") {
+			k, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && strings.EqualFold(k, "for") {
+				nodes = append(nodes, strings.Trim(val, `"`))
+			}
+		}
+	}
+	return nodes
+}
+
+// parseNode 解析单个地址 支持携带端口以及 IPv6 方括号的形式
+func parseNode(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
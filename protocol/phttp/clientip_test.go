@@ -0,0 +1,135 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package phttp
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+)
+
+func TestClientIPResolver(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		trusted []string
+		header  http.Header
+		peer    string
+		want    string
+	}{
+		{
+			name:    "Leftmost",
+			headers: []string{"x-forwarded-for"},
+			header:  http.Header{"X-Forwarded-For": {"203.0.113.7, 10.0.0.1"}},
+			peer:    "10.0.0.2",
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "SkipTrustedProxies",
+			headers: []string{"X-Forwarded-For"},
+			trusted: []string{"10.0.0.0/8"},
+			header:  http.Header{"X-Forwarded-For": {"198.51.100.1, 203.0.113.7", "10.0.0.1"}},
+			peer:    "10.0.0.2",
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "UntrustedPeer",
+			headers: []string{"X-Forwarded-For"},
+			trusted: []string{"10.0.0.0/8"},
+			header:  http.Header{"X-Forwarded-For": {"203.0.113.7"}},
+			peer:    "192.0.2.1",
+		},
+		{
+			name:    "AllTrusted",
+			headers: []string{"X-Forwarded-For"},
+			trusted: []string{"10.0.0.0/8", "invalid"},
+			header:  http.Header{"X-Forwarded-For": {"10.1.1.1, 10.0.0.1"}},
+			peer:    "10.0.0.2",
+			want:    "10.1.1.1",
+		},
+		{
+			name:    "StopAtUnknown",
+			headers: []string{"X-Forwarded-For"},
+			trusted: []string{"10.0.0.1"},
+			header:  http.Header{"X-Forwarded-For": {"203.0.113.7, unknown, 10.0.0.1"}},
+			peer:    "10.0.0.1",
+			want:    "10.0.0.1",
+		},
+		{
+			name:    "Forwarded",
+			headers: []string{"Forwarded"},
+			header:  http.Header{"Forwarded": {`for="[2001:db8:cafe::17]:4711";proto=https, for=10.0.0.1`}},
+			peer:    "10.0.0.2",
+			want:    "2001:db8:cafe::17",
+		},
+		{
+			name:    "Fallback",
+			headers: []string{"X-Forwarded-For", "X-Real-Ip"},
+			header:  http.Header{"X-Real-Ip": {"203.0.113.7:8080"}},
+			peer:    "10.0.0.2",
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "Missing",
+			headers: []string{"X-Real-Ip"},
+			header:  http.Header{"User-Agent": {"curl/8.0"}},
+			peer:    "10.0.0.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newClientIPResolver(common.Options{
+				OptExtractClientIPHeaders: tt.headers,
+				OptTrustedProxies:         tt.trusted,
+			})
+			assert.Equal(t, tt.want, r.resolve(tt.header, tt.peer))
+		})
+	}
+
+	assert.Nil(t, newClientIPResolver(common.NewOptions()))
+}
+
+func TestDecodeClientIP(t *testing.T) {
+	input := []byte("GET / HTTP/1.1\r\nHost: example.com\r\nX-Forwarded-For: 203.0.113.7, 10.0.0.1\r\n\r\n")
+
+	st := socket.Tuple{
+		SrcIP:   socket.ToIPV4(net.ParseIP("10.0.0.2").To4()),
+		DstIP:   socket.ToIPV4(net.ParseIP("10.0.0.3").To4()),
+		SrcPort: 50001,
+		DstPort: 80,
+	}
+	opts := common.NewOptions()
+	opts[OptExtractClientIPHeaders] = []string{"X-Forwarded-For"}
+	opts[OptTrustedProxies] = []string{"10.0.0.0/8"}
+	d := NewDecoder(st, 80, opts)
+
+	extracted := testutil.ToFloat64(clientIPExtractedTotal)
+	objs, err := d.Decode(zerocopy.NewBuffer(input), time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+
+	req := objs[0].Obj.(*Request)
+	assert.Equal(t, "10.0.0.2", req.Host)
+	assert.Equal(t, "203.0.113.7", req.ClientIP)
+	assert.Equal(t, extracted+1, testutil.ToFloat64(clientIPExtractedTotal))
+}
@@ -17,32 +17,48 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/pkg/errors"
-
 	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/bufpool"
+	"github.com/packetd/packetd/internal/jsonscan"
 	"github.com/packetd/packetd/internal/splitio"
 	"github.com/packetd/packetd/internal/zerocopy"
 	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/phttp2"
+	"github.com/packetd/packetd/protocol/ptls"
 	"github.com/packetd/packetd/protocol/role"
 )
 
-func newError(format string, args ...any) error {
-	format = "http/decoder: " + format
-	return errors.Errorf(format, args...)
+func newError(code protocol.ErrorCode, format string, args ...any) error {
+	return protocol.NewDecodeError(socket.L7ProtoHTTP, code, "http/decoder: "+format, args...)
 }
 
 var (
 	charHTTP11     = []byte("HTTP/1.1") // 目前仅支持 HTTP1.1 协议版本
 	charHTTP11CRLF = append(charHTTP11, splitio.CharCRLF...)
+
+	// HTTP/1.0 仅记录大小以及耗时 见 legacy
+	charHTTP10     = []byte("HTTP/1.0")
+	charHTTP10CRLF = append(charHTTP10, splitio.CharCRLF...)
 	charEndOfBody  = append([]byte("0"), splitio.CharCRLF...)
 )
 
+// 可选功能的生效次数 见 protocol.NewOptionCounter
+var (
+	bodyCapturedTotal    = protocol.NewOptionCounter(socket.L7ProtoHTTP, "enableBodyCapture", "captured")
+	bodyTruncatedTotal   = protocol.NewOptionCounter(socket.L7ProtoHTTP, "maxBodySize", "truncated")
+	bodyRateLimitedTotal = protocol.NewOptionCounter(socket.L7ProtoHTTP, "maxBodyBytesPerSecond", "rate_limited")
+	bodySniffedTotal     = protocol.NewOptionCounter(socket.L7ProtoHTTP, "enableBodySniff", "sniffed")
+	bodyFieldsTotal      = protocol.NewOptionCounter(socket.L7ProtoHTTP, OptBodyJSONFields, "extracted")
+
+	clientIPExtractedTotal = protocol.NewOptionCounter(socket.L7ProtoHTTP, OptExtractClientIPHeaders, "extracted")
+)
+
 // state 记录着 decoder 的处理状态
 type state uint8
 
@@ -58,11 +74,41 @@ const (
 	// stateDecodeBody 解析 body 状态
 	// 处于此状态时 header 已经处理完毕 开始解析 body 内容
 	stateDecodeBody
+
+	// stateDecodeTrailer 解析 trailer 状态
+	// 处于此状态时 chunked body 已经读取到 last-chunk 开始解析 trailer-section
+	stateDecodeTrailer
 )
 
+func (s state) String() string {
+	switch s {
+	case stateDecodeProtocol:
+		return "protocol"
+	case stateDecodeHeader:
+		return "header"
+	case stateDecodeBody:
+		return "body"
+	case stateDecodeTrailer:
+		return "trailer"
+	}
+	return "unknown"
+}
+
+// maxTrailerFields 单次请求最多记录的 trailer 字段数量
+const maxTrailerFields = 16
+
+// OptBodyJSONFields 从 json 响应体中提取的字段路径 以 . 分隔 数组元素使用下标
+//
+// 被 maxBodySize 截断的 body 同样可以提取截断位置之前的字段
+const OptBodyJSONFields = "bodyJSONFields"
+
+// plainJSONScanner 未配置 OptBodyJSONFields 时仅用于校验语法
+var plainJSONScanner = jsonscan.New(nil)
+
 const (
-	jsonBodyType = "json"
-	textBodyType = "text"
+	jsonBodyType     = "json"
+	textBodyType     = "text"
+	protobufBodyType = "protobuf"
 )
 
 // decoder HTTP1.1 协议解析器
@@ -80,6 +126,8 @@ type decoder struct {
 	rbuf              *bytes.Buffer
 	bodyBuf           bytes.Buffer // body 内容存储
 	reqTime           time.Time    // 请求接收到的时间
+	firstByteTime     time.Time    // 响应首行到达的时间
+	headerTime        time.Time    // 响应 Header 解析完成的时间
 	chunked           bool         // 记录当次请求是否为 chunked 模式
 	drainBytes        int          // 已经读取的 body 字节数
 	expectedBytes     int          // 期待读取的 body 字节 在 chunked 模式下位 0
@@ -87,6 +135,38 @@ type decoder struct {
 	enableBodyCapture bool         // 是否启用 body 捕获
 	maxBodySize       int          // 最大 body 捕获大小
 	captureBody       bool         // 是否捕获 body 内容, 默认不捕获
+	enableBodySniff   bool         // 是否根据 body 内容探测类型
+	connWindow        *byteWindow  // 单链接 body 捕获速率限制 两个方向共享
+	globalWindow      *byteWindow  // 全局 body 捕获速率限制
+	rateLimited       bool         // 当次 body 是否因超出速率限制被截断
+	trailer           http.Header  // chunked 模式下的 trailer 字段
+	trailerBytes      int          // trailer-section 字节数
+	legacy            bool         // 当次请求是否为 HTTP/1.0
+	aborted           bool         // 客户端已经中断链接 后续数据不再解析
+	enableJSONRPC     bool         // 是否识别 JSON-RPC 调用
+	jsonScanner       *jsonscan.Scanner
+	clientIP          *clientIPResolver
+
+	// h2c 升级相关状态 升级完成后 h2c 非空 后续数据全部交由其解析
+	h2cRequested bool   // 客户端已经发出携带 `Upgrade: h2c` 的请求
+	h2cAccepted  bool   // 服务端已经返回 101 响应 当前响应归档后即切换
+	h2cSettings  []byte // HTTP2-Settings 还原得到的 SETTINGS 帧
+	h2c          protocol.Decoder
+	createH2C    func() protocol.Decoder
+
+	// WebSocket 升级相关状态 仅在开启 JSON-RPC 识别时追踪 升级完成后 ws 非空
+	wsRequested bool   // 客户端已经发出携带 `Upgrade: websocket` 的请求
+	wsAccepted  bool   // 服务端已经返回 101 响应
+	wsPath      string // 升级请求的路由
+	ws          *wsDecoder
+
+	// CONNECT 隧道相关状态 两个方向通过 ctx 共享目标地址 隧道建立后 tunnel 非空
+	ctx              *protocol.ConnContext
+	release          func()
+	connectRequested bool // 当前请求为 CONNECT 归档后即进入隧道状态
+	tunnelAccepted   bool // 当前响应为 CONNECT 的 2xx 响应 不再解析 body
+	tunnel           *tunnel
+	createTLS        func() protocol.Decoder
 
 	state        state
 	obj          *role.Object
@@ -95,7 +175,14 @@ type decoder struct {
 
 const defaultMaxBodySize = 102400 // 100KB
 
+// NewDecoder 创建 HTTP decoder
+//
+// 独立创建的 decoder 无法与另一个方向共享链接上下文 不会识别 CONNECT 隧道 链接池内应使用 newDecoder
 func NewDecoder(st socket.Tuple, serverPort socket.Port, options common.Options) protocol.Decoder {
+	return newDecoder(st, serverPort, options, protocol.NewConnContext(0), nil)
+}
+
+func newDecoder(st socket.Tuple, serverPort socket.Port, options common.Options, ctx *protocol.ConnContext, release func()) *decoder {
 
 	// 只有开启了 body 捕获才会捕获 body
 	enableBodyCapture, _ := options.GetBool("enableBodyCapture")
@@ -106,12 +193,46 @@ func NewDecoder(st socket.Tuple, serverPort socket.Port, options common.Options)
 		maxBodySize = defaultMaxBodySize
 	}
 
+	// Content-Type 缺失或者不可信时 根据 body 内容探测类型
+	enableBodySniff, _ := options.GetBool("enableBodySniff")
+
+	// 捕获速率限制（Bytes/s）为 0 代表不限制
+	connRate, _ := options.GetInt("maxBodyBytesPerSecond")
+	globalRate, _ := options.GetInt("maxGlobalBodyBytesPerSecond")
+
+	// JSON-RPC 需要解析请求体以及响应体 与 enableBodyCapture 相互独立
+	enableJSONRPC, _ := options.GetBool(OptEnableJSONRPC)
+
+	jsonScanner := plainJSONScanner
+	if fields, _ := options.GetStringSlice(OptBodyJSONFields); len(fields) > 0 {
+		jsonScanner = jsonscan.New(fields)
+	}
+
+	var createTLS func() protocol.Decoder
+	if decodeTunnelTLS, _ := options.GetBool(OptDecodeTunnelTLS); decodeTunnelTLS {
+		createTLS = func() protocol.Decoder {
+			return ptls.NewDecoder(st, serverPort, options)
+		}
+	}
+
 	return &decoder{
 		st:                st.ToRaw(),
 		serverPort:        serverPort,
 		rbuf:              bufpool.Acquire(),
 		enableBodyCapture: enableBodyCapture,
 		maxBodySize:       maxBodySize,
+		enableBodySniff:   enableBodySniff,
+		enableJSONRPC:     enableJSONRPC,
+		jsonScanner:       jsonScanner,
+		clientIP:          newClientIPResolver(options),
+		connWindow:        connByteWindow(ctx, connRate),
+		globalWindow:      sharedByteWindow(globalRate),
+		createH2C: func() protocol.Decoder {
+			return phttp2.NewConnDecoder(st, serverPort, options, ctx, nil)
+		},
+		ctx:       ctx,
+		release:   release,
+		createTLS: createTLS,
 	}
 }
 
@@ -125,60 +246,124 @@ func (d *decoder) reset() {
 	d.rbuf.Reset()
 	d.captureBody = false
 	d.bodyBuf.Reset()
+	d.rateLimited = false
 	d.headBodyLine = nil
 	d.bodyType = ""
+	d.trailer = nil
+	d.trailerBytes = 0
+	d.legacy = false
+	d.firstByteTime = time.Time{}
+	d.headerTime = time.Time{}
+}
+
+// afterRequestHeader 在解析完 Request Header 之后调用 仅开启 JSON-RPC 识别时需要捕获请求体
+func (d *decoder) afterRequestHeader(r *http.Request) {
+	if !d.enableJSONRPC {
+		return
+	}
+	if r.Method == http.MethodPost && isJSONContentType(r.Header.Get("Content-Type")) {
+		d.captureBody = true
+	}
+	if isWebSocketUpgrade(r.Header) {
+		d.wsRequested = true
+		d.wsPath = r.URL.Path
+	}
 }
 
 // afterResponseHeader 在解析完 Response Header 之后调用
 func (d *decoder) afterResponseHeader(resp *Response) {
+	ct := resp.Header.Get("Content-Type")
+	if d.enableJSONRPC && isJSONContentType(ct) {
+		d.captureBody = true
+	}
 	if !d.enableBodyCapture {
-		d.captureBody = false
 		return
 	}
-	ct := resp.Header.Get("Content-Type")
 	d.detectAndSetBodyType(ct)
+
+	// 开启探测后无论 Content-Type 为何均需要先捕获 body 内容
+	if d.enableBodySniff {
+		d.captureBody = true
+	}
 }
 
 func (d *decoder) appendBodyChunk(p []byte) {
-	// 如果未启用 body 捕获 则直接返回
-	if !d.enableBodyCapture {
-		return
-	}
-	if !d.captureBody || d.bodyBuf.Len() >= d.maxBodySize {
+	// 仅开启了 enableBodyCapture 或者 enableJSONRPC 时 captureBody 才可能为 true
+	if !d.captureBody || d.rateLimited || d.bodyBuf.Len() >= d.maxBodySize {
 		return
 	}
 	remain := d.maxBodySize - d.bodyBuf.Len()
 	if len(p) > remain {
 		p = p[:remain]
 	}
+
+	// 超出速率限制后截断 body 剩余部分不再捕获 避免导出的 body 中间出现空洞
+	n := d.globalWindow.take(d.t0, d.connWindow.take(d.t0, len(p)))
+	if n < len(p) {
+		d.rateLimited = true
+		p = p[:n]
+	}
 	d.bodyBuf.Write(p)
 }
 
 // 归档响应时写入 JSON
 func (d *decoder) archiveResponseBody(resp *Response) {
-	if !d.enableBodyCapture {
-		return
-	}
 	// 如果不符合捕获条件 则直接返回
 	if !d.captureBody {
 		return
 	}
-	b := d.bodyBuf.Bytes()
+	if d.enableJSONRPC {
+		resp.replies = protocol.ParseJSONRPC(d.bodyBuf.Bytes())
+	}
+	if !d.enableBodyCapture {
+		return
+	}
+	resp.BodyRateLimited = d.rateLimited
+
+	raw := d.bodyBuf.Bytes()
 	// 去除尾部可能的 CRLF 与空白
-	b = bytes.TrimSpace(bytes.TrimSuffix(b, []byte("\r\n")))
+	b := bytes.TrimSpace(bytes.TrimSuffix(raw, []byte("\r\n")))
 	if len(b) == 0 {
 		return
 	}
 
+	// 探测结果优先于 Content-Type 无法识别时才沿用 Content-Type
+	if d.enableBodySniff {
+		if bodyType := sniffBodyType(raw, d.rateLimited || len(raw) >= d.maxBodySize); bodyType != "" {
+			d.bodyType = bodyType
+			bodySniffedTotal.Inc()
+		}
+	}
+	resp.BodyType = d.bodyType
+
 	switch d.bodyType {
 	case jsonBodyType:
-		if json.Valid(b) {
+		// 截断的文档无法作为 RawMessage 输出 但截断位置之前的字段仍然可用
+		res := d.jsonScanner.Scan(b)
+		if res.Complete {
 			resp.Body = json.RawMessage(append([]byte(nil), b...))
 		} else {
 			resp.Body = string(b)
 		}
+		if res.Valid && len(res.Fields) > 0 {
+			resp.BodyFields = res.Fields
+			bodyFieldsTotal.Inc()
+		}
 	case textBodyType:
 		resp.Body = string(b)
+	case protobufBodyType:
+		resp.Body = bytes.Clone(raw) // 二进制内容不做任何裁剪
+	}
+
+	if resp.Body == nil {
+		return
+	}
+	bodyCapturedTotal.Inc()
+	if len(raw) >= d.maxBodySize {
+		bodyTruncatedTotal.Inc()
+	}
+	if d.rateLimited {
+		bodyRateLimitedTotal.Inc()
 	}
 
 }
@@ -186,32 +371,103 @@ func (d *decoder) archiveResponseBody(resp *Response) {
 // archive 归档请求
 func (d *decoder) archive() error {
 	if d.obj == nil || d.obj.Obj == nil {
-		return newError("role (%s) got nil obj", d.role)
+		return newError(protocol.ErrCodeResyncFailed, "role (%s) got nil obj", d.role)
 	}
 	switch obj := d.obj.Obj.(type) {
 	case *Request:
-		obj.Size = d.decideContentLength()
+		if d.legacy {
+			d.obj.Obj = &Request{Proto: obj.Proto}
+			obj = d.obj.Obj.(*Request)
+		}
+		obj.Size = d.decideContentLength() + d.trailerBytes
 		obj.Host = d.st.SrcIP
 		obj.Port = d.st.SrcPort
 		obj.Chunked = d.chunked
+		obj.Trailer = d.trailer
 		obj.Time = d.reqTime
+		if obj.ClientIP = d.clientIP.resolve(obj.Header, obj.Host); obj.ClientIP != "" {
+			clientIPExtractedTotal.Inc()
+		}
+		if d.captureBody {
+			obj.calls = protocol.ParseJSONRPC(d.bodyBuf.Bytes())
+		}
 
 	case *Response:
-		obj.Size = d.decideContentLength()
+		if d.legacy {
+			d.obj.Obj = &Response{Proto: obj.Proto}
+			obj = d.obj.Obj.(*Response)
+		}
+		obj.Size = d.decideContentLength() + d.trailerBytes
+		obj.Trailer = d.trailer
 		obj.Time = d.t0 // response 的时间以接收到的最后一个字节为准
+		obj.FirstByteTime = d.firstByteTime
+		obj.HeaderTime = d.headerTime
 		obj.Host = d.st.SrcIP
 		obj.Port = d.st.SrcPort
 		obj.Chunked = d.chunked
-		d.archiveResponseBody(obj)
+		if !d.legacy {
+			d.archiveResponseBody(obj)
+		}
 
 	}
 	return nil
 }
 
+// Abort 客户端中断链接时提前归档正在传输 body 的响应
+//
+// 响应时间沿用最后一次收到数据的时间 Size 为中断前实际收到的字节数
+// 链接随后即会关闭 服务端在此之后发出的数据（对端已不再接收）直接丢弃
+func (d *decoder) Abort(_ time.Time) []*role.Object {
+	if d.tunnel != nil && d.role == role.Response {
+		return d.abortTunnel()
+	}
+	if d.role != role.Response || d.state != stateDecodeBody || d.obj == nil || d.legacy {
+		return nil
+	}
+
+	if err := d.archive(); err != nil {
+		return nil
+	}
+	rsp := d.obj.Obj.(*Response)
+	rsp.Size = d.drainBytes // chunked 模式下不能以首个 chunk-size 兜底
+	rsp.Outcome = OutcomeClientAborted
+	rsp.ExpectedSize = d.expectedBytes
+
+	obj := d.obj
+	d.reset()
+	d.aborted = true
+	return []*role.Object{obj}
+}
+
 // Free 释放持有的资源
 func (d *decoder) Free() {
 	d.obj = nil
 	bufpool.Release(d.rbuf)
+	if d.h2c != nil {
+		d.h2c.Free()
+	}
+	if d.tunnel != nil {
+		d.closeTunnel()
+	}
+	if d.release != nil {
+		d.release()
+		d.release = nil
+	}
+}
+
+// DescribeState 返回 decoder 当前的解析状态 用于解析错误现场采集
+func (d *decoder) DescribeState() string {
+	if d.h2c != nil {
+		return fmt.Sprintf("role=%s upgraded=h2c", d.role)
+	}
+	if d.ws != nil {
+		return fmt.Sprintf("role=%s upgraded=websocket bufferedBytes=%d", d.role, len(d.ws.buf))
+	}
+	if d.tunnel != nil {
+		return fmt.Sprintf("role=%s tunnel=%s tunnelBytes=%d", d.role, d.tunnel.target, d.tunnel.bytes)
+	}
+	return fmt.Sprintf("role=%s state=%s chunked=%v drainBytes=%d expectedBytes=%d bufferedBytes=%d",
+		d.role, d.state, d.chunked, d.drainBytes, d.expectedBytes, d.rbuf.Len())
 }
 
 // Decode 从 zerocopy.Reader 中不断解析来自 Request / Response 的数据 并判断是否能构建成 RoundTrip
@@ -251,23 +507,63 @@ func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error)
 	d.t0 = t
 
 	b, err := r.Read(common.ReadWriteBlockSize)
-	if err != nil {
+	if err != nil || d.aborted {
 		return nil, nil
 	}
 
+	// 链接已经升级为 h2c 或者客户端在收到 101 响应后发送了 Connection Preface
+	if d.h2c != nil {
+		return d.h2c.Decode(zerocopy.NewBuffer(b), t)
+	}
+	if d.h2cRequested && bytes.HasPrefix(b, h2cPreface) {
+		return d.switchH2C(b[len(h2cPreface):], t)
+	}
+
+	// 链接已经升级为 WebSocket 或者客户端在收到 101 响应后发送了首个 WebSocket 帧
+	if d.ws != nil {
+		return d.ws.decode(b, t), nil
+	}
+	if d.wsRequested && isWebSocketClientFrame(b) {
+		return d.switchWebSocket(b, t), nil
+	}
+
+	// 链接已经建立 CONNECT 隧道 后续数据不再按照 HTTP 解析
+	if d.tunnelActive() {
+		return d.decodeTunnel(b, t), nil
+	}
+
 	var objs []*role.Object
+	var consumed int
 	scan := splitio.NewScanner(b) // 按行处理数据
 	for scan.Scan() {
-		obj, err := d.decode(scan.Bytes())
+		line := scan.Bytes()
+		consumed += len(line)
+		obj, err := d.decode(line)
 		if err != nil {
 			d.reset() // 出现任何错误都应该重置链接 从头开始探测
 			return nil, err
 		}
 		if obj == nil {
+			if d.tunnel != nil { // 2xx 响应之后紧跟着的即为隧道数据
+				return d.decodeTunnel(b[consumed:], t), nil
+			}
 			continue
 		}
 
 		objs = append(objs, obj)
+
+		// 101 响应之后紧跟着的即为 HTTP/2 帧
+		if d.h2cAccepted {
+			more, err := d.switchH2C(b[consumed:], t)
+			return append(objs, more...), err
+		}
+		if d.wsAccepted {
+			return append(objs, d.switchWebSocket(b[consumed:], t)...), nil
+		}
+		if d.connectRequested {
+			d.openTunnel(obj)
+			return append(objs, d.decodeTunnel(b[consumed:], t)...), nil
+		}
 		return objs, nil
 	}
 
@@ -280,6 +576,12 @@ func (d *decoder) decode(line []byte) (*role.Object, error) {
 		return nil, err
 	}
 
+	// CONNECT 的 2xx 响应不携带 body 隧道关闭时才归档
+	if d.state == stateDecodeBody && d.tunnelAccepted {
+		d.openTunnel(d.obj)
+		return nil, nil
+	}
+
 	// fast-path:
 	// 不是所有的所有 HTTP 请求均携带了 body 因此当 decodeLine 结束后需要优先判断下请求是否已经结束
 	// 在非 chunked 模式下如果 body 本身无内容 则当 decode 已经结束
@@ -313,7 +615,12 @@ func (d *decoder) decodeLine(line []byte) (*role.Object, error) {
 		}
 	}
 
-	// 3) 处理 body
+	// 3) 处理 trailer
+	if d.state == stateDecodeTrailer {
+		return d.decodeTrailer(line)
+	}
+
+	// 4) 处理 body
 	return d.decodeBody(line)
 }
 
@@ -345,19 +652,43 @@ func (d *decoder) decodeRequestHeadLine(line []byte) bool {
 		d.role = role.Request
 		return true
 	}
+	if bytes.HasSuffix(line, charHTTP10CRLF) {
+		d.rbuf.Write(line)
+		d.role = role.Request
+		d.markLegacy()
+		return true
+	}
 	return false
 }
 
 // decodeResponseHeadLine 解析请求 Response 协议首行 如 `HTTP/1.1 200 OK\r\n`
 func (d *decoder) decodeResponseHeadLine(line []byte) bool {
-	if bytes.HasPrefix(line, charHTTP11) && bytes.HasSuffix(line, splitio.CharCRLF) {
+	if !bytes.HasSuffix(line, splitio.CharCRLF) {
+		return false
+	}
+
+	legacy := bytes.HasPrefix(line, charHTTP10)
+	if legacy || bytes.HasPrefix(line, charHTTP11) {
 		d.rbuf.Write(line)
 		d.role = role.Response
+		d.firstByteTime = d.t0
+		if legacy {
+			d.markLegacy()
+		}
 		return true
 	}
 	return false
 }
 
+// markLegacy 标记当次请求为 HTTP/1.0
+//
+// HTTP/1.0 的报文格式与 HTTP/1.1 兼容 但未携带 Content-Length 的 body 以链接关闭作为结束 无法准确划分边界
+// 因此仅保留大小以及耗时 body 按照 Content-Length 计算 未声明时记为 0
+func (d *decoder) markLegacy() {
+	d.legacy = true
+	protocol.RecordUnsupportedVersion(socket.L7ProtoHTTP, string(charHTTP10))
+}
+
 // decodeBody 逐行解析 body 内容
 func (d *decoder) decodeBody(line []byte) (*role.Object, error) {
 	complete, err := d.drainBody(line)
@@ -374,6 +705,41 @@ func (d *decoder) decodeBody(line []byte) (*role.Object, error) {
 	return obj, nil
 }
 
+// decodeTrailer 逐行解析 chunked body 的 trailer-section
+//
+// trailer-section 与 header 格式一致 以空行结束 如 grpc-web 中的 grpc-status 或者自定义的 X-Checksum
+//
+//	0\r\n
+//	X-Checksum: 5d41402abc4b2a76\r\n
+//	\r\n
+//
+// trailer 字节数同样计入 Size 中
+func (d *decoder) decodeTrailer(line []byte) (*role.Object, error) {
+	if bytes.Equal(line, splitio.CharCRLF) {
+		if err := d.archive(); err != nil {
+			return nil, err
+		}
+		obj := d.obj
+		d.reset()
+		return obj, nil
+	}
+
+	d.trailerBytes += len(line)
+	if d.trailer != nil && len(d.trailer) >= maxTrailerFields {
+		return nil, nil
+	}
+
+	k, v, ok := bytes.Cut(bytes.TrimSuffix(line, splitio.CharCRLF), []byte(":"))
+	if !ok || len(k) == 0 {
+		return nil, nil // 非法的 trailer 字段直接忽略
+	}
+	if d.trailer == nil {
+		d.trailer = make(http.Header)
+	}
+	d.trailer.Add(string(bytes.TrimSpace(k)), string(bytes.TrimSpace(v)))
+	return nil, nil
+}
+
 // decodeRequestHeader 解析 Request Header
 //
 // Header 一般以 \r\n 作为单行的换行符 并且最后一行的 len 为空
@@ -386,7 +752,7 @@ func (d *decoder) decodeRequestHeader(line []byte) error {
 	defer d.rbuf.Reset()
 	r, err := http.ReadRequest(bufio.NewReaderSize(d.rbuf, d.rbuf.Len()))
 	if err != nil {
-		return err
+		return newError(protocol.ErrCodeMalformed, "read request header: %v", err)
 	}
 
 	d.state = stateDecodeBody
@@ -397,6 +763,13 @@ func (d *decoder) decodeRequestHeader(line []byte) error {
 
 	d.reqTime = d.t0
 	d.obj = role.NewRequestObject(fromHTTPRequest(r))
+	d.afterRequestHeader(r)
+
+	if isH2CUpgrade(r.Header) {
+		d.h2cRequested = true
+		d.h2cSettings = h2cSettingsFrame(r.Header)
+	}
+	d.afterConnectRequest(r)
 	return nil
 }
 
@@ -412,16 +785,20 @@ func (d *decoder) decodeResponseHeader(line []byte) error {
 	defer d.rbuf.Reset()
 	r, err := http.ReadResponse(bufio.NewReaderSize(d.rbuf, d.rbuf.Len()), nil)
 	if err != nil {
-		return err
+		return newError(protocol.ErrCodeMalformed, "read response header: %v", err)
 	}
 
 	d.state = stateDecodeBody
+	d.headerTime = d.t0
 	d.chunked = checkChunkedEncoding(r.TransferEncoding) && r.ContentLength < 0
 	if r.ContentLength > 0 {
 		d.expectedBytes = int(r.ContentLength)
 	}
 
 	d.obj = role.NewResponseObject(fromHTTTResponse(r))
+	d.h2cAccepted = r.StatusCode == http.StatusSwitchingProtocols && isH2CUpgrade(r.Header)
+	d.wsAccepted = d.enableJSONRPC && r.StatusCode == http.StatusSwitchingProtocols && isWebSocketUpgrade(r.Header)
+	d.afterConnectResponse(r)
 
 	resp := fromHTTTResponse(r)
 	d.afterResponseHeader(resp)
@@ -508,7 +885,7 @@ func (d *decoder) drainBody(line []byte) (bool, error) {
 			return true, nil
 		}
 		if d.drainBytes > d.expectedBytes {
-			return false, newError("drainBytes %d greater than expectedBytes %d", d.drainBytes, d.expectedBytes)
+			return false, newError(protocol.ErrCodeLengthOverflow, "drainBytes %d greater than expectedBytes %d", d.drainBytes, d.expectedBytes)
 		}
 		return false, nil
 	}
@@ -517,12 +894,11 @@ func (d *decoder) drainBody(line []byte) (bool, error) {
 	//
 	// 已经读取到 body 末尾标识
 	// 5bytes `\r\n\0\r\n`
+	// 其后为可选的 trailer-section 以及结束的 CRLF
 	if bytes.Equal(line, charEndOfBody) {
 		d.drainBytes -= 5
-		if err := d.archive(); err != nil {
-			return false, err
-		}
-		return true, nil
+		d.state = stateDecodeTrailer
+		return false, nil
 	}
 
 	// 属于正常的 data 数据 block 不做调整（可能会有偏差）
@@ -570,7 +946,7 @@ func (d *decoder) decideContentLength() int {
 // detectAndSetBodyType 根据 Content-Type 探测 body 类型, 并设置 bodyType 字段
 func (d *decoder) detectAndSetBodyType(contentType string) {
 	ct := strings.ToLower(contentType)
-	if strings.Contains(ct, "application/json") || strings.Contains(ct, "text/json") {
+	if isJSONContentType(ct) {
 		d.bodyType = jsonBodyType
 		d.captureBody = true
 	}
@@ -580,10 +956,16 @@ func (d *decoder) detectAndSetBodyType(contentType string) {
 	}
 }
 
+// isJSONContentType 判断 Content-Type 是否为 json 类型
+func isJSONContentType(contentType string) bool {
+	ct := strings.ToLower(contentType)
+	return strings.Contains(ct, "application/json") || strings.Contains(ct, "text/json")
+}
+
 // parseHexUint 将 16 进制所代表的字节解析成 uint64 数据类型
 func parseHexUint(v []byte) (uint64, error) {
 	if len(v) == 0 {
-		return 0, errors.New("empty hex number for chunk length")
+		return 0, newError(protocol.ErrCodeMalformed, "empty hex number for chunk length")
 	}
 
 	var n uint64
@@ -596,10 +978,10 @@ func parseHexUint(v []byte) (uint64, error) {
 		case 'A' <= b && b <= 'F':
 			b = b - 'A' + 10
 		default:
-			return 0, errors.New("invalid byte in chunk length")
+			return 0, newError(protocol.ErrCodeMalformed, "invalid byte in chunk length")
 		}
 		if i == 16 {
-			return 0, errors.New("http chunk length too large")
+			return 0, newError(protocol.ErrCodeLengthOverflow, "chunk length too large")
 		}
 		n <<= 4
 		n |= uint64(b)
@@ -20,12 +20,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/splitio"
 	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol"
 )
 
 func normalizeProtocol(b []byte) []byte {
@@ -716,6 +718,158 @@ X-Custom: second`)),
 	}
 }
 
+func TestDecodeTrailer(t *testing.T) {
+	decode := func(input []byte) *Response {
+		var st socket.Tuple
+		d := NewDecoder(st, 0, common.NewOptions())
+		objs, err := d.Decode(zerocopy.NewBuffer(input), time.Time{})
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+		return objs[0].Obj.(*Response)
+	}
+
+	plain := decode(normalizeProtocol([]byte(`
+HTTP/1.1 200 OK
+Transfer-Encoding: chunked
+
+7
+packetd
+9
+Developer
+0`)))
+	assert.Nil(t, plain.Trailer)
+
+	rsp := decode(normalizeProtocol([]byte(`
+HTTP/1.1 200 OK
+Transfer-Encoding: chunked
+Trailer: X-Checksum, Grpc-Status
+
+7
+packetd
+9
+Developer
+0
+X-Checksum: 5d41402abc4b2a76
+grpc-status: 0`)))
+	assert.Equal(t, http.Header{
+		"X-Checksum":  []string{"5d41402abc4b2a76"},
+		"Grpc-Status": []string{"0"},
+	}, rsp.Trailer)
+	assert.Equal(t, plain.Size+len("X-Checksum: 5d41402abc4b2a76\r\n")+len("grpc-status: 0\r\n"), rsp.Size)
+}
+
+func TestDecodeResponsePhases(t *testing.T) {
+	var st socket.Tuple
+	d := NewDecoder(st, 0, common.NewOptions())
+
+	t0 := time.Unix(1700000000, 0)
+	chunks := [][]byte{
+		[]byte("HTTP/1.1 200 OK\r\n"),
+		[]byte("Content-Length: 7\r\n\r\n"),
+		[]byte("packetd"),
+	}
+
+	var rsp *Response
+	for i, chunk := range chunks {
+		objs, err := d.Decode(zerocopy.NewBuffer(chunk), t0.Add(time.Duration(i)*time.Millisecond))
+		assert.NoError(t, err)
+		for _, obj := range objs {
+			rsp = obj.Obj.(*Response)
+		}
+	}
+	assert.NotNil(t, rsp)
+	assert.Equal(t, t0, rsp.FirstByteTime)
+	assert.Equal(t, t0.Add(time.Millisecond), rsp.HeaderTime)
+	assert.Equal(t, t0.Add(2*time.Millisecond), rsp.Time)
+}
+
+func TestDecodeClientAborted(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	tests := []struct {
+		name     string
+		chunks   []string
+		size     int
+		expected int
+	}{
+		{
+			name:     "ContentLength",
+			chunks:   []string{"HTTP/1.1 200 OK\r\nContent-Length: 1024\r\n\r\n", "packetd"},
+			size:     7,
+			expected: 1024,
+		},
+		{
+			name:   "Chunked",
+			chunks: []string{"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n", "400\r\npacketd"},
+			size:   7,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var st socket.Tuple
+			d := NewDecoder(st, 0, common.NewOptions())
+			for i, chunk := range tt.chunks {
+				objs, err := d.Decode(zerocopy.NewBuffer([]byte(chunk)), t0.Add(time.Duration(i)*time.Millisecond))
+				assert.NoError(t, err)
+				assert.Nil(t, objs)
+			}
+
+			objs := d.(protocol.Aborter).Abort(t0.Add(time.Second))
+			assert.Len(t, objs, 1)
+			rsp := objs[0].Obj.(*Response)
+			assert.Equal(t, OutcomeClientAborted, rsp.Outcome)
+			assert.Equal(t, tt.size, rsp.Size)
+			assert.Equal(t, tt.expected, rsp.ExpectedSize)
+			assert.Equal(t, t0.Add(time.Millisecond), rsp.Time)
+
+			// 中断之后服务端继续发送的数据直接丢弃
+			objs, err := d.Decode(zerocopy.NewBuffer([]byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n")), t0)
+			assert.NoError(t, err)
+			assert.Nil(t, objs)
+		})
+	}
+
+	t.Run("Idle", func(t *testing.T) {
+		var st socket.Tuple
+		d := NewDecoder(st, 0, common.NewOptions())
+		assert.Nil(t, d.(protocol.Aborter).Abort(t0))
+	})
+}
+
+func TestDecodeLegacyVersion(t *testing.T) {
+	var st socket.Tuple
+	t0 := time.Unix(1700000000, 0)
+
+	d := NewDecoder(st, 0, common.NewOptions())
+	objs, err := d.Decode(zerocopy.NewBuffer(normalizeProtocol([]byte(`
+POST /submit HTTP/1.0
+Host: example.com
+Content-Length: 11
+
+packetd`))), t0)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.Equal(t, &Request{Host: "0.0.0.0", Proto: "HTTP/1.0", Size: 11, Time: t0}, objs[0].Obj)
+
+	d = NewDecoder(st, 0, common.NewOptions())
+	objs, err = d.Decode(zerocopy.NewBuffer(normalizeProtocol([]byte(`
+HTTP/1.0 200 OK
+Content-Type: text/plain
+Content-Length: 13
+
+Developer`))), t0)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.Equal(t, &Response{
+		Host:          "0.0.0.0",
+		Proto:         "HTTP/1.0",
+		Size:          13,
+		Time:          t0,
+		FirstByteTime: t0,
+		HeaderTime:    t0,
+	}, objs[0].Obj)
+}
+
 func TestDecodeFailed(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -766,3 +920,44 @@ Content-Type: text/plain`)),
 		})
 	}
 }
+
+func TestDecodeBodyRateLimited(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	input := []byte("HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nContent-Length: 8\r\n\r\nabcdefgh")
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		body    any
+		limited bool
+	}{
+		{name: "WithinLimit", body: "abcdefgh"},
+		{name: "Truncated", body: "ab", limited: true},
+		{name: "Exhausted", elapsed: 500 * time.Millisecond, limited: true},
+		{name: "Recovered", elapsed: 1100 * time.Millisecond, body: "abcdefgh"},
+	}
+
+	var st socket.Tuple
+	opts := common.NewOptions()
+	opts["enableBodyCapture"] = true
+	opts["maxBodyBytesPerSecond"] = 10
+	d := NewDecoder(st, 0, opts)
+	captured := testutil.ToFloat64(bodyCapturedTotal)
+	limited := testutil.ToFloat64(bodyRateLimitedTotal)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs, err := d.Decode(zerocopy.NewBuffer(input), t0.Add(tt.elapsed))
+			assert.NoError(t, err)
+			assert.Len(t, objs, 1)
+
+			rsp := objs[0].Obj.(*Response)
+			assert.Equal(t, tt.body, rsp.Body)
+			assert.Equal(t, tt.limited, rsp.BodyRateLimited)
+			assert.Equal(t, 8, rsp.Size)
+		})
+	}
+
+	// 完全被限速的 body 不计入捕获次数
+	assert.Equal(t, captured+3, testutil.ToFloat64(bodyCapturedTotal))
+	assert.Equal(t, limited+1, testutil.ToFloat64(bodyRateLimitedTotal))
+}
@@ -14,31 +14,48 @@
 package phttp
 
 import (
+	"encoding/json"
 	"net/http"
 	"time"
 
 	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/phttp2"
+	"github.com/packetd/packetd/protocol/ptls"
 	"github.com/packetd/packetd/protocol/role"
 )
 
 func init() {
 	protocol.Register(socket.L7ProtoHTTP, NewConnPool)
+	protocol.Describe(socket.L7ProtoHTTP, protocol.Capability{
+		Versions: []string{"1.0", "1.1"},
+		Options:  []string{"enableBodyCapture", "maxBodySize", "enableBodySniff", "maxBodyBytesPerSecond", "maxGlobalBodyBytesPerSecond", OptBodyJSONFields, OptEnableJSONRPC, OptExtractClientIPHeaders, OptTrustedProxies, OptDecodeTunnelTLS},
+	})
 }
 
 // NewConnPool 创建 HTTP 协议连接池
+//
+// 同一条链接两个方向的 decoder 共享链接上下文 用于识别 CONNECT 请求建立的隧道
 func NewConnPool(opts common.Options) protocol.ConnPool {
+	cs := protocol.NewConnContexts(0)
 	return protocol.NewL7TCPConnPool(
-		role.NewSingleMatcher,
+		newUpgradeMatcher,
 		func(pair *role.Pair) socket.RoundTrip {
-			return &RoundTrip{
-				request:  pair.Request.Obj.(*Request),
-				response: pair.Response.Obj.(*Response),
+			// 经 h2c 升级后的链接产生的是 HTTP/2 RoundTrip
+			switch pair.Request.Obj.(type) {
+			case *phttp2.Request:
+				return phttp2.NewRoundTrip(pair)
+			case *ptls.Request: // CONNECT 隧道内的 TLS 握手
+				return ptls.NewRoundTrip(pair)
 			}
+			return newRoundTrip(pair.Request.Obj.(*Request), pair.Response.Obj.(*Response))
 		},
 		func(st socket.Tuple, serverPort socket.Port) protocol.Decoder {
-			return NewDecoder(st, serverPort, opts)
+			ctx := cs.Acquire(st, serverPort)
+			return newDecoder(st, serverPort, opts, ctx, func() {
+				cs.Release(st, serverPort)
+			})
 		},
 	)
 }
@@ -59,7 +76,24 @@ type Request struct {
 	Close      bool
 	Size       int
 	Chunked    bool
+	Trailer    http.Header
 	Time       time.Time
+	Client     *protocol.Client `json:",omitempty"`
+
+	// ClientIP 从代理写入的 Header 中解析出的真实客户端地址 仅配置了 extractClientIPHeaders 时存在
+	// Host 始终为链接对端地址 位于 L7 代理 / 负载均衡之后时即为代理地址
+	ClientIP string `json:",omitempty"`
+
+	// Trace 请求携带的 traceparent / tracestate 未携带时为空
+	Trace *protocol.TraceContext `json:",omitempty"`
+
+	// RPC 识别出的 Twirp / Connect / JSON-RPC 调用 Outcome 在配对响应后填充
+	RPC *protocol.RPC `json:",omitempty"`
+
+	// Operation 依据 OpenAPI 描述匹配出的接口 仅配置了 controller.openapi 时存在
+	Operation *protocol.Operation `json:",omitempty"`
+
+	calls []protocol.JSONRPCMessage // 开启 OptEnableJSONRPC 后从请求体中解析出的 JSON-RPC 消息
 }
 
 // Response HTTP 响应
@@ -72,15 +106,59 @@ type Response struct {
 	Status     string
 	StatusCode int
 	Body       interface{}
+	BodyType   string
 	Proto      string
 	Close      bool
 	Size       int
 	Chunked    bool
+	Trailer    http.Header
 	Time       time.Time
+
+	// BodyRateLimited Body 捕获超出 maxBodyBytesPerSecond / maxGlobalBodyBytesPerSecond 限制而被截断
+	BodyRateLimited bool `json:",omitempty"`
+
+	// BodyFields 按照 bodyJSONFields 从 json Body 中提取的标量字段
+	BodyFields map[string]string `json:",omitempty"`
+
+	// FirstByteTime 响应首行到达时间 HeaderTime 响应 Header 完整到达时间
+	// 与 Time 一起可以拆分出等待首字节 / 传输 Header / 传输 Body 几个阶段的耗时
+	FirstByteTime time.Time
+	HeaderTime    time.Time
+
+	// Outcome 响应的传输结果 正常结束时为空
+	// 客户端在响应传输完成前断开链接时为 client_aborted 此时 Size 为实际传输的字节数
+	// ExpectedSize 为 Content-Length 声明的大小 chunked 模式下无法预知为 0
+	Outcome      string `json:",omitempty"`
+	ExpectedSize int    `json:",omitempty"`
+
+	// Tunnel CONNECT 隧道的目标地址（host:port）
+	// 隧道在客户端关闭链接时作为一次 RoundTrip 归档 请求以及响应的 Size 分别为隧道内两个方向传输的字节数
+	Tunnel string `json:",omitempty"`
+
+	replies []protocol.JSONRPCMessage
 }
 
+// OutcomeClientAborted 客户端在响应传输完成前主动断开链接
+const OutcomeClientAborted = "client_aborted"
+
 var _ socket.RoundTrip = (*RoundTrip)(nil)
 
+// newRoundTrip 创建 RoundTrip 并识别 JSON-RPC / Twirp / Connect 调用
+func newRoundTrip(req *Request, rsp *Response) *RoundTrip {
+	if req.calls != nil {
+		if req.RPC = protocol.NewJSONRPC(req.calls, rsp.replies); req.RPC != nil {
+			jsonrpcRecognizedTotal.Inc()
+		}
+	} else if req.RPC = protocol.DetectRPC(req.Method, req.Path, req.Header); req.RPC != nil {
+		body, _ := rsp.Body.(json.RawMessage)
+		req.RPC.Resolve(rsp.StatusCode, body)
+	}
+	return &RoundTrip{
+		request:  req,
+		response: rsp,
+	}
+}
+
 // RoundTrip HTTP 单次请求来回
 //
 // 实现了 socket.RoundTrip 接口
@@ -120,6 +198,8 @@ func fromHTTPRequest(r *http.Request) *Request {
 		RemoteHost: r.Host,
 		Close:      r.Close,
 		Size:       int(r.ContentLength),
+		Client:     protocol.ParseUserAgent(r.UserAgent()),
+		Trace:      protocol.ParseTraceContext(r.Header),
 	}
 }
 
@@ -0,0 +1,54 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package phttp
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+)
+
+func TestConnPoolSuperSegment(t *testing.T) {
+	for _, size := range []int{64 * 1024, 192 * 1024, 512 * 1024} {
+		t.Run(fmt.Sprintf("%dKB", size/1024), func(t *testing.T) {
+			t0 := time.Now()
+			st := socket.Tuple{SrcPort: 50001, DstPort: 80}
+			pool := NewConnPool(common.NewOptions())
+			defer pool.Clean()
+
+			req := []byte("GET /download HTTP/1.1\r\nHost: example.com\r\n\r\n")
+			body := bytes.Repeat([]byte("x"), size)
+			rsp := append([]byte(fmt.Sprintf("HTTP/1.1 200 OK\r\nContent-Length: %d\r\n\r\n", size)), body...)
+
+			ch := make(chan socket.RoundTrip, 1)
+			conn := pool.GetOrCreate(st, 80)
+			require.NoError(t, conn.OnL4Packet(&socket.TCPSegment{Tuple: st, Time: t0, Seq: 1, Payload: req}, ch))
+
+			// 整个响应合并为单个 GRO/BIG TCP 分段
+			require.NoError(t, conn.OnL4Packet(&socket.TCPSegment{Tuple: st.Mirror(), Time: t0.Add(time.Millisecond), Seq: 1, Payload: rsp}, ch))
+			require.Len(t, ch, 1)
+
+			rt := <-ch
+			assert.Equal(t, size, rt.Response().(*Response).Size)
+			assert.Equal(t, 200, rt.Response().(*Response).StatusCode)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package phttp
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queueStartHeaders 上游代理（nginx / haproxy / heroku router 等）记录请求到达时间的 Header 按优先级排列
+var queueStartHeaders = []string{"X-Request-Start", "X-Queue-Start"}
+
+// QueueTime 返回请求从上游代理接收到抵达服务端之间的排队耗时
+//
+// Header 值支持 `t=1700000000.123` 以及裸时间戳两种写法 整数时间戳按位数识别秒/毫秒/微秒/纳秒
+// 未携带 Header 或者代理时间晚于 received（两台机器时钟偏移）时返回 false
+func QueueTime(header http.Header, received time.Time) (time.Duration, bool) {
+	for _, name := range queueStartHeaders {
+		v := header.Get(name)
+		if v == "" {
+			continue
+		}
+		start, ok := parseQueueStart(v)
+		if !ok {
+			continue
+		}
+		d := received.Sub(start)
+		if d < 0 {
+			return 0, false
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func parseQueueStart(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "t=")
+	if s == "" {
+		return time.Time{}, false
+	}
+
+	// 带小数点的统一视为秒 如 nginx 的 $msec
+	if strings.IndexByte(s, '.') >= 0 {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || f <= 0 {
+			return time.Time{}, false
+		}
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*float64(time.Second))), true
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}, false
+	}
+	switch {
+	case len(s) <= 10:
+		return time.Unix(n, 0), true
+	case len(s) <= 13:
+		return time.UnixMilli(n), true
+	case len(s) <= 16:
+		return time.UnixMicro(n), true
+	}
+	return time.Unix(0, n), true
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package phttp
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueueTime(t *testing.T) {
+	received := time.Unix(1700000000, 0).Add(250 * time.Millisecond)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+		ok     bool
+	}{
+		{
+			name:   "Seconds",
+			header: http.Header{"X-Request-Start": []string{"t=1700000000.100"}},
+			want:   150 * time.Millisecond,
+			ok:     true,
+		},
+		{
+			name:   "Milliseconds",
+			header: http.Header{"X-Request-Start": []string{"t=1700000000200"}},
+			want:   50 * time.Millisecond,
+			ok:     true,
+		},
+		{
+			name:   "Microseconds",
+			header: http.Header{"X-Queue-Start": []string{"1700000000240000"}},
+			want:   10 * time.Millisecond,
+			ok:     true,
+		},
+		{
+			name:   "Nanoseconds",
+			header: http.Header{"X-Queue-Start": []string{"t=1700000000249000000"}},
+			want:   time.Millisecond,
+			ok:     true,
+		},
+		{
+			name: "RequestStartFirst",
+			header: http.Header{
+				"X-Request-Start": []string{"t=1700000000200"},
+				"X-Queue-Start":   []string{"t=1700000000100"},
+			},
+			want: 50 * time.Millisecond,
+			ok:   true,
+		},
+		{
+			name:   "ClockSkew",
+			header: http.Header{"X-Request-Start": []string{"t=1700000001000"}},
+		},
+		{
+			name:   "Invalid",
+			header: http.Header{"X-Request-Start": []string{"t=abc"}},
+		},
+		{
+			name: "Missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := QueueTime(tt.header, received)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.InDelta(t, tt.want, d, float64(time.Microsecond))
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package phttp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/packetd/packetd/protocol"
+)
+
+const (
+	windowBuckets  = 10
+	bucketDuration = 100 * time.Millisecond
+
+	// ctxBodyWindow 单链接窗口挂载在 protocol.ConnContext 中的 key
+	ctxBodyWindow = "http.bodyWindow"
+)
+
+// byteWindow 以 1s 为窗口的滑动计数器 窗口被划分为 10 个 100ms 的桶
+//
+// 时间以数据包的时间为准 而非墙上时间 离线回放时的限速结果与实时抓包保持一致
+type byteWindow struct {
+	mut     sync.Mutex
+	limit   int
+	buckets [windowBuckets]int
+	stamps  [windowBuckets]int64
+}
+
+// newByteWindow limit <= 0 时不限速 返回 nil
+func newByteWindow(limit int) *byteWindow {
+	if limit <= 0 {
+		return nil
+	}
+	return &byteWindow{limit: limit}
+}
+
+// take 在窗口余量内申请 n 个字节 返回实际获得的字节数
+func (w *byteWindow) take(t time.Time, n int) int {
+	if w == nil {
+		return n
+	}
+
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	cur := t.UnixNano() / int64(bucketDuration)
+	used := 0
+	for i := 0; i < windowBuckets; i++ {
+		if stamp := w.stamps[i]; stamp > cur-windowBuckets && stamp <= cur {
+			used += w.buckets[i]
+		}
+	}
+
+	remain := w.limit - used
+	if remain <= 0 {
+		return 0
+	}
+	if n > remain {
+		n = remain
+	}
+
+	idx := int(cur % windowBuckets)
+	if idx < 0 {
+		idx += windowBuckets
+	}
+	if w.stamps[idx] != cur {
+		w.stamps[idx] = cur
+		w.buckets[idx] = 0
+	}
+	w.buckets[idx] += n
+	return n
+}
+
+// connByteWindow 返回链接上下文中挂载的窗口 同一链接的两个方向共享同一个窗口
+func connByteWindow(ctx *protocol.ConnContext, limit int) *byteWindow {
+	if limit <= 0 {
+		return nil
+	}
+	return ctx.Value(ctxBodyWindow, func() any { return newByteWindow(limit) }).(*byteWindow)
+}
+
+var globalBodyWindow struct {
+	mut sync.Mutex
+	w   *byteWindow
+}
+
+// sharedByteWindow 返回所有链接共享的全局窗口 limit 变化时（配置重载）重新创建
+func sharedByteWindow(limit int) *byteWindow {
+	if limit <= 0 {
+		return nil
+	}
+
+	globalBodyWindow.mut.Lock()
+	defer globalBodyWindow.mut.Unlock()
+
+	if globalBodyWindow.w == nil || globalBodyWindow.w.limit != limit {
+		globalBodyWindow.w = newByteWindow(limit)
+	}
+	return globalBodyWindow.w
+}
@@ -0,0 +1,66 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package phttp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/protocol"
+)
+
+func TestByteWindow(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+
+	t.Run("Unlimited", func(t *testing.T) {
+		var w *byteWindow
+		assert.Nil(t, newByteWindow(0))
+		assert.Equal(t, 1<<20, w.take(t0, 1<<20))
+	})
+
+	t.Run("Sliding", func(t *testing.T) {
+		w := newByteWindow(100)
+		assert.Equal(t, 60, w.take(t0, 60))
+		assert.Equal(t, 40, w.take(t0.Add(500*time.Millisecond), 60))
+		assert.Equal(t, 0, w.take(t0.Add(900*time.Millisecond), 1))
+
+		// 首个桶滑出窗口后释放 60 字节
+		assert.Equal(t, 60, w.take(t0.Add(time.Second), 100))
+		assert.Equal(t, 0, w.take(t0.Add(1400*time.Millisecond), 1))
+
+		// 整个窗口滑出后恢复全部余量
+		assert.Equal(t, 100, w.take(t0.Add(10*time.Second), 200))
+	})
+
+	t.Run("Conn", func(t *testing.T) {
+		ctx := protocol.NewConnContext(0)
+		assert.Nil(t, connByteWindow(ctx, 0))
+
+		// 两个方向共享单链接的限额
+		w := connByteWindow(ctx, 100)
+		assert.Same(t, w, connByteWindow(ctx, 100))
+		assert.Equal(t, 60, w.take(t0, 60))
+		assert.Equal(t, 40, connByteWindow(ctx, 100).take(t0, 60))
+		assert.NotSame(t, w, connByteWindow(protocol.NewConnContext(0), 100))
+	})
+
+	t.Run("Shared", func(t *testing.T) {
+		assert.Nil(t, sharedByteWindow(0))
+		w := sharedByteWindow(100)
+		assert.Same(t, w, sharedByteWindow(100))
+		assert.NotSame(t, w, sharedByteWindow(200))
+	})
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package phttp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf8"
+)
+
+// maxSniffProtobufFields 探测 protobuf 时最多遍历的字段数量
+const maxSniffProtobufFields = 64
+
+// sniffBodyType 根据 body 内容探测其真实类型 无法识别时返回空字符串
+//
+// 探测顺序为 json -> text -> protobuf
+// truncated 表示 body 是否已经被截断 截断的 json 只需已读取的部分合法 截断的 protobuf 允许最后一个字段不完整
+func sniffBodyType(b []byte, truncated bool) string {
+	if len(b) == 0 {
+		return ""
+	}
+
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		res := plainJSONScanner.Scan(trimmed)
+		if res.Complete || (truncated && res.Valid) {
+			return jsonBodyType
+		}
+	}
+	if isPlainText(b) {
+		return textBodyType
+	}
+	if isProtobuf(b, truncated) {
+		return protobufBodyType
+	}
+	return ""
+}
+
+// isPlainText 判断是否为可打印的 UTF-8 文本
+func isPlainText(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, c := range b {
+		if c < 0x20 && c != '\t' && c != '\r' && c != '\n' {
+			return false
+		}
+		if c == 0x7F {
+			return false
+		}
+	}
+	return true
+}
+
+// isProtobuf 判断是否为合法的 protobuf wire format
+//
+// protobuf 没有 magic bytes 只能逐个字段校验 tag 以及长度是否合法
+// 每个字段以 varint 编码的 tag 开头 (field_number << 3 | wire_type)
+// wire_type 仅允许 0(varint) 1(i64) 2(len) 5(i32) 已废弃的 group(3/4) 视为非法
+func isProtobuf(b []byte, truncated bool) bool {
+	var fields int
+	for len(b) > 0 {
+		if fields >= maxSniffProtobufFields {
+			return true
+		}
+
+		tag, n := binary.Uvarint(b)
+		if n <= 0 || tag>>3 == 0 {
+			return false
+		}
+		b = b[n:]
+
+		var size int
+		switch tag & 0x07 {
+		case 0:
+			_, n = binary.Uvarint(b)
+			if n <= 0 {
+				return truncated && n == 0
+			}
+			size = n
+		case 1:
+			size = 8
+		case 2:
+			l, n := binary.Uvarint(b)
+			if n <= 0 {
+				return truncated && n == 0
+			}
+			b = b[n:]
+			size = int(l)
+			if size < 0 {
+				return false
+			}
+		case 5:
+			size = 4
+		default:
+			return false
+		}
+
+		fields++
+		if size > len(b) {
+			return truncated
+		}
+		b = b[size:]
+	}
+	return fields > 0
+}
@@ -0,0 +1,156 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package phttp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+)
+
+func TestSniffBodyType(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     []byte
+		truncated bool
+		want      string
+	}{
+		{
+			name:  "Empty",
+			input: []byte{},
+			want:  "",
+		},
+		{
+			name:  "JSONObject",
+			input: []byte(` {"status":"ok"}`),
+			want:  jsonBodyType,
+		},
+		{
+			name:  "JSONArray",
+			input: []byte(`[1,2,3]`),
+			want:  jsonBodyType,
+		},
+		{
+			name:  "InvalidJSONAsText",
+			input: []byte(`{"sta`),
+			want:  textBodyType,
+		},
+		{
+			name:      "TruncatedJSON",
+			input:     []byte(`{"status":"ok","items":[{"id":1},{"na`),
+			truncated: true,
+			want:      jsonBodyType,
+		},
+		{
+			name:      "TruncatedBrokenJSONAsText",
+			input:     []byte(`{"status" "ok","items":[`),
+			truncated: true,
+			want:      textBodyType,
+		},
+		{
+			name:  "PlainText",
+			input: []byte("hello packetd\r\n"),
+			want:  textBodyType,
+		},
+		{
+			name: "Protobuf",
+			input: []byte{
+				0x0A, 0x07, 'p', 'a', 'c', 'k', 'e', 't', 'd', // field 1 string
+				0x10, 0x96, 0x01, // field 2 varint
+				0x1D, 0x00, 0x00, 0x80, 0x3F, // field 3 fixed32
+			},
+			want: protobufBodyType,
+		},
+		{
+			name: "TruncatedProtobuf",
+			input: []byte{
+				0x0A, 0x20, 0x00, 0x01, 0x02,
+			},
+			truncated: true,
+			want:      protobufBodyType,
+		},
+		{
+			name: "IncompleteProtobuf",
+			input: []byte{
+				0x0A, 0x20, 0x00, 0x01, 0x02,
+			},
+			want: "",
+		},
+		{
+			name:  "Binary",
+			input: []byte{0x00, 0xFF, 0xFE, 0x07},
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sniffBodyType(tt.input, tt.truncated))
+		})
+	}
+}
+
+func TestDecodeSniffBody(t *testing.T) {
+	opts := common.NewOptions()
+	opts.Merge("enableBodyCapture", true)
+	opts.Merge("enableBodySniff", true)
+
+	var st socket.Tuple
+	d := NewDecoder(st, 0, opts)
+
+	// Content-Type 声明为 text/plain 但实际内容为 json
+	objs, err := d.Decode(zerocopy.NewBuffer(normalizeProtocol([]byte(`
+HTTP/1.1 200 OK
+Content-Type: text/plain
+Content-Length: 17
+
+{"status":"ok"}`))), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+
+	rsp := objs[0].Obj.(*Response)
+	assert.Equal(t, jsonBodyType, rsp.BodyType)
+	assert.Equal(t, json.RawMessage(`{"status":"ok"}`), rsp.Body)
+}
+
+func TestDecodeTruncatedJSONFields(t *testing.T) {
+	opts := common.NewOptions()
+	opts.Merge("enableBodyCapture", true)
+	opts.Merge("maxBodySize", 48)
+	opts.Merge(OptBodyJSONFields, []string{"code", "error.message", "data.1.id"})
+
+	var st socket.Tuple
+	d := NewDecoder(st, 0, opts)
+
+	body := `{"code":500,"error":{"message":"db timeout"},"data":[{"id":1},{"id":2}]}`
+	objs, err := d.Decode(zerocopy.NewBuffer(normalizeProtocol([]byte(`
+HTTP/1.1 500 Internal Server Error
+Content-Type: application/json
+Content-Length: 74
+
+`+body))), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+
+	rsp := objs[0].Obj.(*Response)
+	assert.Equal(t, jsonBodyType, rsp.BodyType)
+	assert.Equal(t, body[:48], rsp.Body)
+	assert.Equal(t, map[string]string{"code": "500", "error.message": "db timeout"}, rsp.BodyFields)
+}
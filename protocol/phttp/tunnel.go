@@ -0,0 +1,152 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package phttp
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+// OptDecodeTunnelTLS CONNECT 隧道建立后将隧道内的数据交由 TLS 解码器解析握手信息
+const OptDecodeTunnelTLS = "decodeTunnelTLS"
+
+// ctxConnectTarget 客户端 CONNECT 请求的目标地址
+//
+// 由请求方向写入 响应方向据此判断 2xx 响应是否建立了隧道 代理拒绝或者隧道关闭时清空
+const ctxConnectTarget = "connectTarget"
+
+var connectTunnelsTotal = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: common.App,
+		Name:      "http_connect_tunnels_total",
+		Help:      "HTTP/1.1 CONNECT tunnels established total",
+	},
+)
+
+// tunnel CONNECT 隧道状态
+//
+// 隧道内的数据不再按照 HTTP 解析 两个方向分别累加字节数
+// 请求方向累加至已归档的 CONNECT 请求 响应方向的 2xx 响应延迟至客户端关闭链接时归档 整个隧道作为一次 RoundTrip 输出
+type tunnel struct {
+	obj    *role.Object
+	target string
+	bytes  int
+	tls    protocol.Decoder // 开启 OptDecodeTunnelTLS 时解析隧道内的 TLS 握手
+}
+
+// afterConnectRequest 记录 CONNECT 请求的目标地址 请求归档后即进入隧道状态
+func (d *decoder) afterConnectRequest(r *http.Request) {
+	if r.Method != http.MethodConnect {
+		return
+	}
+	d.connectRequested = true
+	d.ctx.Set(ctxConnectTarget, r.Host)
+}
+
+// afterConnectResponse 判断响应是否建立了隧道 非 2xx 响应代表代理拒绝了 CONNECT 请求
+func (d *decoder) afterConnectResponse(r *http.Response) {
+	target := d.ctx.Get(ctxConnectTarget)
+	switch {
+	case target == "" || r.StatusCode < http.StatusOK:
+	case r.StatusCode < http.StatusMultipleChoices:
+		d.tunnelAccepted = true
+	default:
+		d.ctx.Set(ctxConnectTarget, "")
+	}
+}
+
+// openTunnel 切换至隧道状态 obj 为 CONNECT 请求或者尚未归档的 2xx 响应
+func (d *decoder) openTunnel(obj *role.Object) {
+	d.connectRequested = false
+	d.tunnelAccepted = false
+	d.tunnel = &tunnel{obj: obj}
+	if d.role == role.Response {
+		d.tunnel.target = d.ctx.Get(ctxConnectTarget)
+		connectTunnelsTotal.Inc()
+	}
+	if d.createTLS != nil {
+		d.tunnel.tls = d.createTLS()
+	}
+}
+
+// tunnelActive 隧道是否仍然有效
+//
+// 请求方向无法感知响应 代理拒绝了 CONNECT 请求时客户端可以在同一链接上继续发送 HTTP 请求
+func (d *decoder) tunnelActive() bool {
+	if d.tunnel == nil {
+		return false
+	}
+	if d.role == role.Response || d.ctx.Get(ctxConnectTarget) != "" {
+		return true
+	}
+	d.closeTunnel()
+	return false
+}
+
+// decodeTunnel 累加隧道内的字节数 开启 OptDecodeTunnelTLS 时同时解析 TLS 握手
+func (d *decoder) decodeTunnel(b []byte, t time.Time) []*role.Object {
+	if len(b) == 0 {
+		return nil
+	}
+	d.tunnel.bytes += len(b)
+	if req, ok := d.tunnel.obj.Obj.(*Request); ok {
+		req.Size = d.tunnel.bytes
+	}
+
+	if d.tunnel.tls == nil {
+		return nil
+	}
+	objs, err := d.tunnel.tls.Decode(zerocopy.NewBuffer(b), t)
+	if err != nil {
+		// 隧道内并非 TLS 流量 不再尝试解析
+		d.tunnel.tls.Free()
+		d.tunnel.tls = nil
+	}
+	return objs
+}
+
+// abortTunnel 客户端关闭链接时归档隧道的响应
+//
+// 响应时间为最后一次收到数据的时间 Size 为隧道内服务端发送的字节数
+func (d *decoder) abortTunnel() []*role.Object {
+	defer d.closeTunnel()
+	if err := d.archive(); err != nil {
+		return nil
+	}
+
+	rsp := d.obj.Obj.(*Response)
+	rsp.Size = d.tunnel.bytes
+	rsp.Tunnel = d.tunnel.target
+	d.ctx.Set(ctxConnectTarget, "")
+
+	obj := d.obj
+	d.reset()
+	d.aborted = true
+	return []*role.Object{obj}
+}
+
+func (d *decoder) closeTunnel() {
+	if d.tunnel.tls != nil {
+		d.tunnel.tls.Free()
+	}
+	d.tunnel = nil
+}
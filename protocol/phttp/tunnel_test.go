@@ -0,0 +1,174 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package phttp
+
+import (
+	"encoding/binary"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/ptls"
+)
+
+type tunnelConn struct {
+	t        *testing.T
+	conn     protocol.Conn
+	st       socket.Tuple
+	t0       time.Time
+	ch       chan socket.RoundTrip
+	seq      uint32
+	rseq     uint32
+	finished bool
+}
+
+func newTunnelConn(t *testing.T, opts common.Options) *tunnelConn {
+	st := socket.Tuple{SrcPort: 50002, DstPort: 3128}
+	pool := NewConnPool(opts)
+	t.Cleanup(pool.Clean)
+	return &tunnelConn{
+		t:    t,
+		conn: pool.GetOrCreate(st, 3128),
+		st:   st,
+		t0:   time.Now(),
+		ch:   make(chan socket.RoundTrip, 4),
+		seq:  1,
+		rseq: 1,
+	}
+}
+
+func (c *tunnelConn) send(d time.Duration, payload []byte) {
+	require.NoError(c.t, c.conn.OnL4Packet(&socket.TCPSegment{Tuple: c.st, Time: c.t0.Add(d), Seq: c.seq, Payload: payload}, c.ch))
+	c.seq += uint32(len(payload))
+}
+
+func (c *tunnelConn) reply(d time.Duration, payload []byte) {
+	require.NoError(c.t, c.conn.OnL4Packet(&socket.TCPSegment{Tuple: c.st.Mirror(), Time: c.t0.Add(d), Seq: c.rseq, Payload: payload}, c.ch))
+	c.rseq += uint32(len(payload))
+}
+
+// close 客户端半关闭后服务端随之关闭链接
+func (c *tunnelConn) close(d time.Duration) {
+	require.NoError(c.t, c.conn.OnL4Packet(&socket.TCPSegment{Tuple: c.st, Time: c.t0.Add(d), Seq: c.seq, FIN: true}, c.ch))
+	assert.Empty(c.t, c.ch)
+	require.NoError(c.t, c.conn.OnL4Packet(&socket.TCPSegment{Tuple: c.st.Mirror(), Time: c.t0.Add(d), Seq: c.rseq, FIN: true}, c.ch))
+}
+
+func tlsVec(n int, b []byte) []byte {
+	if n == 1 {
+		return append([]byte{byte(len(b))}, b...)
+	}
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(b))), b...)
+}
+
+// tlsHandshakeRecord 构造仅包含单条握手消息的 TLS record
+func tlsHandshakeRecord(typ uint8, body []byte) []byte {
+	msg := append([]byte{typ, byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body))}, body...)
+	return append([]byte{0x16, 0x03, 0x03}, tlsVec(2, msg)...)
+}
+
+// tlsHello 构造 ClientHello / ServerHello 共同的 version random session_id 部分
+func tlsHello() []byte {
+	b := append([]byte{0x03, 0x03}, make([]byte, 32)...)
+	return append(b, 0x00) // 空 session id
+}
+
+func TestConnPoolConnectTunnel(t *testing.T) {
+	c := newTunnelConn(t, common.NewOptions())
+
+	c.send(0, []byte("CONNECT api.example.com:443 HTTP/1.1\r\nHost: api.example.com:443\r\n\r\n"))
+	c.reply(time.Millisecond, []byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+	assert.Empty(t, c.ch)
+
+	// 隧道内的数据不再按照 HTTP 解析
+	inner := []byte("GET / HTTP/1.1\r\nHost: api.example.com\r\n\r\n")
+	c.send(2*time.Millisecond, inner)
+	c.reply(3*time.Millisecond, make([]byte, 1000))
+	c.reply(4*time.Millisecond, make([]byte, 500))
+	assert.Empty(t, c.ch)
+
+	c.close(5 * time.Millisecond)
+	require.Len(t, c.ch, 1)
+	rt := <-c.ch
+	req := rt.Request().(*Request)
+	rsp := rt.Response().(*Response)
+	assert.Equal(t, http.MethodConnect, req.Method)
+	assert.Equal(t, "api.example.com:443", req.RemoteHost)
+	assert.Equal(t, len(inner), req.Size)
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	assert.Equal(t, "api.example.com:443", rsp.Tunnel)
+	assert.Equal(t, 1500, rsp.Size)
+	assert.Equal(t, 4*time.Millisecond, rt.Duration())
+}
+
+func TestConnPoolConnectRejected(t *testing.T) {
+	c := newTunnelConn(t, common.NewOptions())
+
+	c.send(0, []byte("CONNECT api.example.com:443 HTTP/1.1\r\nHost: api.example.com:443\r\n\r\n"))
+	c.reply(time.Millisecond, []byte("HTTP/1.1 407 Proxy Authentication Required\r\nContent-Length: 0\r\n\r\n"))
+	require.Len(t, c.ch, 1)
+	rt := <-c.ch
+	assert.Equal(t, http.StatusProxyAuthRequired, rt.Response().(*Response).StatusCode)
+	assert.Empty(t, rt.Response().(*Response).Tunnel)
+
+	// 代理拒绝后客户端在同一链接上继续发送请求
+	c.send(2*time.Millisecond, []byte("GET http://example.com/ HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	c.reply(3*time.Millisecond, []byte("HTTP/1.1 204 No Content\r\n\r\n"))
+	require.Len(t, c.ch, 1)
+	rt = <-c.ch
+	assert.Equal(t, http.MethodGet, rt.Request().(*Request).Method)
+	assert.Equal(t, http.StatusNoContent, rt.Response().(*Response).StatusCode)
+}
+
+func TestConnPoolConnectTunnelTLS(t *testing.T) {
+	opts := common.NewOptions()
+	opts.Merge(OptDecodeTunnelTLS, true)
+	c := newTunnelConn(t, opts)
+
+	// ClientHello 携带 SNI ServerHello 携带 supported_versions(TLS 1.3)
+	sni := tlsVec(2, append([]byte{0x00}, tlsVec(2, []byte("api.example.com"))...))
+	clientHello := tlsHello()
+	clientHello = append(clientHello, tlsVec(2, []byte{0x13, 0x01})...)
+	clientHello = append(clientHello, tlsVec(1, []byte{0x00})...)
+	clientHello = append(clientHello, tlsVec(2, append([]byte{0x00, 0x00}, tlsVec(2, sni)...))...)
+	serverHello := tlsHello()
+	serverHello = append(serverHello, 0x13, 0x01, 0x00)
+	serverHello = append(serverHello, tlsVec(2, []byte{0x00, 0x2b, 0x00, 0x02, 0x03, 0x04})...)
+
+	c.send(0, []byte("CONNECT api.example.com:443 HTTP/1.1\r\nHost: api.example.com:443\r\n\r\n"))
+	c.reply(time.Millisecond, []byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+	hello := tlsHandshakeRecord(0x01, clientHello)
+	c.send(2*time.Millisecond, hello)
+	shello := tlsHandshakeRecord(0x02, serverHello)
+	c.reply(3*time.Millisecond, shello)
+
+	require.Len(t, c.ch, 1)
+	rt := <-c.ch
+	assert.Equal(t, socket.L7ProtoTLS, rt.Proto())
+	assert.Equal(t, "api.example.com", rt.Request().(*ptls.Request).ServerName)
+	assert.Equal(t, "TLS 1.3", rt.Response().(*ptls.Response).Version)
+
+	c.close(4 * time.Millisecond)
+	require.Len(t, c.ch, 1)
+	rt = <-c.ch
+	assert.Equal(t, socket.L7ProtoHTTP, rt.Proto())
+	assert.Equal(t, len(hello), rt.Request().(*Request).Size)
+	assert.Equal(t, len(shello), rt.Response().(*Response).Size)
+}
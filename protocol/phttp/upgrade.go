@@ -0,0 +1,155 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package phttp
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol/phttp2"
+	"github.com/packetd/packetd/protocol/ptls"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+// h2cPreface 客户端在收到 101 响应后发送的 HTTP/2 Connection Preface
+var h2cPreface = []byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n")
+
+// frameTypeSettings HTTP/2 SETTINGS 帧类型
+const frameTypeSettings = 0x4
+
+var h2cUpgradesTotal = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: common.App,
+		Name:      "http_h2c_upgrades_total",
+		Help:      "HTTP/1.1 connections upgraded to cleartext HTTP/2 total",
+	},
+)
+
+// isH2CUpgrade 判断 Header 是否声明了 h2c 升级 即 `Upgrade: h2c`
+func isH2CUpgrade(header http.Header) bool {
+	for _, v := range header.Values("Upgrade") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "h2c") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// h2cSettingsFrame 将 HTTP2-Settings 中 base64url 编码的 SETTINGS payload 还原为完整的 SETTINGS 帧
+//
+// 升级后客户端不会再重复发送这部分参数 解码失败时返回 nil
+func h2cSettingsFrame(header http.Header) []byte {
+	v := header.Get("HTTP2-Settings")
+	if v == "" {
+		return nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(v, "="))
+	if err != nil || len(payload)%6 != 0 {
+		return nil
+	}
+
+	n := len(payload)
+	frame := make([]byte, 0, 9+n)
+	frame = append(frame, byte(n>>16), byte(n>>8), byte(n), frameTypeSettings, 0, 0, 0, 0, 0)
+	return append(frame, payload...)
+}
+
+// upgradeMatcher 链接升级前后分别使用不同的 Matcher 配对
+//
+// 升级前为 HTTP/1.1 的单次来回 h2c 升级后按照 HTTP/2 StreamID 配对 WebSocket 升级后按照 JSON-RPC id 配对
+// CONNECT 隧道内的 TLS 握手单独配对 隧道本身仍与 CONNECT 请求按照 HTTP/1.1 配对
+type upgradeMatcher struct {
+	http1     role.Matcher
+	http2     role.Matcher
+	websocket role.Matcher
+	tls       role.Matcher
+}
+
+func newUpgradeMatcher() role.Matcher {
+	return &upgradeMatcher{http1: role.NewSingleMatcher()}
+}
+
+func (m *upgradeMatcher) Match(o *role.Object) *role.Pair {
+	switch obj := o.Obj.(type) {
+	case *phttp2.Request, *phttp2.Response:
+		if m.http2 == nil {
+			m.http2 = phttp2.NewStreamMatcher()
+		}
+		return m.http2.Match(o)
+	case *ptls.Request, *ptls.Response:
+		if m.tls == nil {
+			m.tls = role.NewSingleMatcher()
+		}
+		return m.tls.Match(o)
+	case *Request:
+		if obj.Proto == ProtoWebSocket {
+			return m.matchWebSocket(o)
+		}
+	case *Response:
+		if obj.Proto == ProtoWebSocket {
+			return m.matchWebSocket(o)
+		}
+	}
+	return m.http1.Match(o)
+}
+
+func (m *upgradeMatcher) matchWebSocket(o *role.Object) *role.Pair {
+	if m.websocket == nil {
+		m.websocket = role.NewTransactionMatcher(wsMaxPending, 0, wsTransaction)
+	}
+	return m.websocket.Match(o)
+}
+
+// Pending 返回尚未完成配对的请求数量
+func (m *upgradeMatcher) Pending() int {
+	var n int
+	for _, matcher := range []role.Matcher{m.http1, m.http2, m.websocket, m.tls} {
+		if p, ok := matcher.(interface{ Pending() int }); ok {
+			n += p.Pending()
+		}
+	}
+	return n
+}
+
+// switchH2C 链接完成 h2c 升级 剩余的字节流交由 HTTP/2 decoder 继续解析
+//
+// 客户端方向先回放 HTTP2-Settings 中的参数 升级请求本身已经以 HTTP/1.1 RoundTrip（101 响应）输出
+// 服务端在 stream 1 上返回的 HTTP/2 响应不存在对应的请求 配对时会被丢弃
+func (d *decoder) switchH2C(b []byte, t time.Time) ([]*role.Object, error) {
+	d.reset()
+	d.h2c = d.createH2C()
+	if d.role == role.Response {
+		h2cUpgradesTotal.Inc() // 以服务端确认升级为准 每条链接仅计数一次
+	}
+
+	if len(d.h2cSettings) > 0 {
+		if _, err := d.h2c.Decode(zerocopy.NewBuffer(d.h2cSettings), t); err != nil {
+			return nil, err
+		}
+		d.h2cSettings = nil
+	}
+	if len(b) == 0 {
+		return nil, nil
+	}
+	return d.h2c.Decode(zerocopy.NewBuffer(b), t)
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package phttp
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol/phttp2"
+)
+
+func h2Frame(streamID uint32, frameType, flags uint8, payload []byte) []byte {
+	n := len(payload)
+	b := []byte{
+		byte(n >> 16), byte(n >> 8), byte(n), frameType, flags,
+		byte(streamID >> 24), byte(streamID >> 16), byte(streamID >> 8), byte(streamID),
+	}
+	return append(b, payload...)
+}
+
+// h2Headers 使用增量索引的字面量编码 HPACK Header
+func h2Headers(kv ...string) []byte {
+	var b []byte
+	for i := 0; i+1 < len(kv); i += 2 {
+		b = append(b, 0x40, byte(len(kv[i])))
+		b = append(b, kv[i]...)
+		b = append(b, byte(len(kv[i+1])))
+		b = append(b, kv[i+1]...)
+	}
+	return b
+}
+
+func TestIsH2CUpgrade(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   bool
+	}{
+		{name: "H2C", header: http.Header{"Upgrade": {"h2c"}}, want: true},
+		{name: "TokenList", header: http.Header{"Upgrade": {"websocket, H2C"}}, want: true},
+		{name: "Websocket", header: http.Header{"Upgrade": {"websocket"}}, want: false},
+		{name: "None", header: http.Header{}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isH2CUpgrade(tt.header))
+		})
+	}
+}
+
+func TestH2CSettingsFrame(t *testing.T) {
+	// SETTINGS_MAX_CONCURRENT_STREAMS=100 SETTINGS_INITIAL_WINDOW_SIZE=1073741824
+	frame := h2cSettingsFrame(http.Header{"Http2-Settings": {"AAMAAABkAAQAAAAAAAIAAAAA"}})
+	require.Len(t, frame, 9+18)
+	assert.Equal(t, []byte{0x00, 0x00, 0x12, frameTypeSettings, 0x00, 0x00, 0x00, 0x00, 0x00}, frame[:9])
+	assert.Equal(t, []byte{0x00, 0x03, 0x00, 0x00, 0x00, 0x64}, frame[9:15])
+
+	assert.Nil(t, h2cSettingsFrame(http.Header{"Http2-Settings": {"!invalid"}}))
+	assert.Nil(t, h2cSettingsFrame(http.Header{}))
+}
+
+func TestConnPoolH2CUpgrade(t *testing.T) {
+	t0 := time.Now()
+	st := socket.Tuple{SrcPort: 50001, DstPort: 80}
+	pool := NewConnPool(common.NewOptions())
+	defer pool.Clean()
+
+	ch := make(chan socket.RoundTrip, 4)
+	conn := pool.GetOrCreate(st, 80)
+
+	var seq, mirrorSeq uint32 = 1, 1
+	send := func(mirror bool, d time.Duration, payload []byte) {
+		if mirror {
+			require.NoError(t, conn.OnL4Packet(&socket.TCPSegment{Tuple: st.Mirror(), Time: t0.Add(d), Seq: mirrorSeq, Payload: payload}, ch))
+			mirrorSeq += uint32(len(payload))
+			return
+		}
+		require.NoError(t, conn.OnL4Packet(&socket.TCPSegment{Tuple: st, Time: t0.Add(d), Seq: seq, Payload: payload}, ch))
+		seq += uint32(len(payload))
+	}
+
+	send(false, 0, []byte("GET /v1 HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade, HTTP2-Settings\r\n"+
+		"Upgrade: h2c\r\nHTTP2-Settings: AAMAAABkAAQAAAAAAAIAAAAA\r\n\r\n"))
+
+	// 101 响应与 HTTP/2 SETTINGS 以及 stream 1 的响应位于同一个数据包
+	rsp := []byte("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: h2c\r\n\r\n")
+	rsp = append(rsp, h2Frame(0, 0x4, 0, []byte{0x00, 0x03, 0x00, 0x00, 0x00, 0x0A})...)
+	rsp = append(rsp, h2Frame(1, 0x1, 0x4, h2Headers(":status", "200"))...)
+	rsp = append(rsp, h2Frame(1, 0x0, 0x1, []byte("v1"))...)
+	send(true, time.Millisecond, rsp)
+
+	require.Len(t, ch, 1)
+	rt := <-ch
+	assert.Equal(t, socket.L7ProtoHTTP, rt.Proto())
+	assert.Equal(t, http.StatusSwitchingProtocols, rt.Response().(*Response).StatusCode)
+
+	// 客户端收到 101 响应后发送 Connection Preface
+	req := append([]byte(nil), h2cPreface...)
+	req = append(req, h2Frame(0, 0x4, 0, nil)...)
+	req = append(req, h2Frame(3, 0x1, 0x4, h2Headers(":method", "POST", ":path", "/v2"))...)
+	req = append(req, h2Frame(3, 0x0, 0x1, []byte("ping"))...)
+	send(false, 2*time.Millisecond, req)
+
+	rsp = h2Frame(3, 0x1, 0x4, h2Headers(":status", "404"))
+	rsp = append(rsp, h2Frame(3, 0x0, 0x1, []byte("pong"))...)
+	send(true, 3*time.Millisecond, rsp)
+
+	require.Len(t, ch, 1)
+	rt = <-ch
+	assert.Equal(t, socket.L7ProtoHTTP2, rt.Proto())
+	assert.Equal(t, "/v2", rt.Request().(*phttp2.Request).Path)
+	assert.Equal(t, "404", rt.Response().(*phttp2.Response).Status)
+	assert.Equal(t, uint32(10), rt.Response().(*phttp2.Response).MaxConcurrentStreams)
+}
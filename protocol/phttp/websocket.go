@@ -0,0 +1,316 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package phttp
+
+import (
+	"encoding/binary"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+// OptEnableJSONRPC 识别 HTTP body 以及 WebSocket 消息中的 JSON-RPC 2.0 调用
+const OptEnableJSONRPC = "enableJSONRPC"
+
+// ProtoWebSocket WebSocket 消息中解析出的 JSON-RPC 调用使用的 Proto
+const ProtoWebSocket = "WebSocket"
+
+var (
+	jsonrpcRecognizedTotal = protocol.NewOptionCounter(socket.L7ProtoHTTP, OptEnableJSONRPC, "recognized")
+	wsUpgradesTotal        = protocol.NewOptionCounter(socket.L7ProtoHTTP, OptEnableJSONRPC, "websocket_upgraded")
+)
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPong         = 0xA
+)
+
+// wsMaxPending 单条 WebSocket 链接最多等待响应的 JSON-RPC 请求数量
+const wsMaxPending = 256
+
+// isWebSocketUpgrade 判断 Header 是否声明了 WebSocket 升级
+func isWebSocketUpgrade(header http.Header) bool {
+	for _, v := range header.Values("Upgrade") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "websocket") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isWebSocketClientFrame 判断数据是否以客户端发出的 WebSocket 帧开头
+//
+// 客户端发出的帧必须携带掩码 而 HTTP 请求行均为 ASCII 字符 第二个字节的最高位不可能为 1
+func isWebSocketClientFrame(b []byte) bool {
+	if len(b) < 2 || b[0]&0x30 != 0 || b[1]&0x80 == 0 {
+		return false
+	}
+	op := b[0] & 0x0f
+	return op <= wsOpBinary || (op >= wsOpClose && op <= wsOpPong)
+}
+
+// switchWebSocket 链接完成 WebSocket 升级 剩余的字节流交由 wsDecoder 继续解析
+func (d *decoder) switchWebSocket(b []byte, t time.Time) []*role.Object {
+	d.reset()
+	d.ws = newWSDecoder(d.st, d.wsPath, d.maxBodySize)
+	if d.role == role.Response {
+		wsUpgradesTotal.Inc()
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	return d.ws.decode(b, t)
+}
+
+// wsFrame WebSocket 帧头
+//
+//	 0                   1                   2                   3
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-------+-+-------------+-------------------------------+
+//	|F|R|R|R| opcode|M| Payload len |    Extended payload length    |
+//	|I|S|S|S|  (4)  |A|     (7)     |             (16/64)           |
+//	|N|V|V|V|       |S|             |   (if payload len==126/127)   |
+//	| |1|2|3|       |K|             |                               |
+//	+-+-+-+-+-------+-+-------------+ - - - - - - - - - - - - - - - +
+//	|     Extended payload length continued, if payload len == 127  |
+//	+ - - - - - - - - - - - - - - - +-------------------------------+
+//	|                               |Masking-key, if MASK set to 1  |
+//	+-------------------------------+-------------------------------+
+//
+// https://datatracker.ietf.org/doc/html/rfc6455#section-5.2
+type wsFrame struct {
+	fin        bool
+	compressed bool // RSV1 permessage-deflate 压缩
+	opcode     byte
+	masked     bool
+	mask       [4]byte
+	headerLen  int
+	length     int
+}
+
+// parseWSFrame 解析帧头 数据不足时返回 false
+func parseWSFrame(b []byte) (wsFrame, bool) {
+	var f wsFrame
+	if len(b) < 2 {
+		return f, false
+	}
+	f.fin = b[0]&0x80 != 0
+	f.compressed = b[0]&0x40 != 0
+	f.opcode = b[0] & 0x0f
+	f.masked = b[1]&0x80 != 0
+
+	f.headerLen = 2
+	f.length = int(b[1] & 0x7f)
+	switch f.length {
+	case 126:
+		if len(b) < 4 {
+			return f, false
+		}
+		f.length = int(binary.BigEndian.Uint16(b[2:4]))
+		f.headerLen = 4
+	case 127:
+		if len(b) < 10 {
+			return f, false
+		}
+		n := binary.BigEndian.Uint64(b[2:10])
+		if n > math.MaxInt32 {
+			n = math.MaxInt32
+		}
+		f.length = int(n)
+		f.headerLen = 10
+	}
+
+	if f.masked {
+		if len(b) < f.headerLen+4 {
+			return f, false
+		}
+		copy(f.mask[:], b[f.headerLen:f.headerLen+4])
+		f.headerLen += 4
+	}
+	return f, true
+}
+
+// wsDecoder WebSocket 消息解析器 链接完成 WebSocket 升级后接管后续的字节流
+//
+// 分片的文本 / 二进制消息重组后交由 protocol.ParseJSONRPC 解析 携带 id 的请求与响应分别提交给上层按照 id 配对
+// 控制帧 / 通知（如 eth_subscription 推送）/ permessage-deflate 压缩以及超过 maxSize 的消息均不解析
+type wsDecoder struct {
+	st      socket.TupleRaw
+	path    string // 升级请求的路由 仅客户端方向可知
+	maxSize int
+
+	buf      []byte    // 尚未构成完整帧的数据
+	skip     int       // 超长帧尚未到达的 payload 字节数
+	msg      []byte    // 分片消息重组缓存
+	msgSize  int       // 消息 payload 总字节数
+	msgStart time.Time // 消息首帧到达时间
+	dropping bool      // 当前消息不解析 等待 FIN 后丢弃
+}
+
+func newWSDecoder(st socket.TupleRaw, path string, maxSize int) *wsDecoder {
+	return &wsDecoder{
+		st:      st,
+		path:    path,
+		maxSize: maxSize,
+	}
+}
+
+func (w *wsDecoder) decode(b []byte, t time.Time) []*role.Object {
+	if w.skip > 0 {
+		n := min(w.skip, len(b))
+		w.skip -= n
+		b = b[n:]
+	}
+	w.buf = append(w.buf, b...)
+
+	var objs []*role.Object
+	for w.skip == 0 {
+		f, ok := parseWSFrame(w.buf)
+		if !ok {
+			break
+		}
+
+		// 超长的数据帧不再缓存 所属消息整体丢弃 控制帧 payload 不超过 125 字节
+		if f.opcode < wsOpClose && f.length > w.maxSize-len(w.msg) {
+			w.buf = w.buf[f.headerLen:]
+			n := min(f.length, len(w.buf))
+			w.buf = w.buf[n:]
+			w.skip = f.length - n
+			w.startFrame(f, t)
+			w.dropping = true
+			w.msgSize += f.length
+			if f.fin {
+				w.resetMessage()
+			}
+			continue
+		}
+
+		if len(w.buf) < f.headerLen+f.length {
+			break
+		}
+		payload := w.buf[f.headerLen : f.headerLen+f.length]
+		w.buf = w.buf[f.headerLen+f.length:]
+		if obj := w.onFrame(f, payload, t); len(obj) > 0 {
+			objs = append(objs, obj...)
+		}
+	}
+
+	// 剩余数据拷贝一份 避免持续引用已经消费的底层数组
+	if len(w.buf) == 0 {
+		w.buf = nil
+	} else {
+		w.buf = append([]byte(nil), w.buf...)
+	}
+	return objs
+}
+
+// startFrame 数据帧为新消息的首帧时重置重组状态
+func (w *wsDecoder) startFrame(f wsFrame, t time.Time) {
+	if f.opcode == wsOpContinuation {
+		// 缺失首帧（如链接中途开始抓包）的消息无法解析
+		if w.msgStart.IsZero() {
+			w.msgStart = t
+			w.dropping = true
+		}
+		return
+	}
+	w.resetMessage()
+	w.msgStart = t
+	w.dropping = f.compressed || f.opcode > wsOpBinary
+}
+
+func (w *wsDecoder) resetMessage() {
+	w.msg = w.msg[:0]
+	w.msgSize = 0
+	w.msgStart = time.Time{}
+	w.dropping = false
+}
+
+// onFrame 处理完整到达的帧 控制帧可以穿插在分片消息之间 直接忽略
+func (w *wsDecoder) onFrame(f wsFrame, payload []byte, t time.Time) []*role.Object {
+	if f.opcode >= wsOpClose {
+		return nil
+	}
+	w.startFrame(f, t)
+	w.msgSize += f.length
+	if !w.dropping {
+		for i, c := range payload {
+			if f.masked {
+				c ^= f.mask[i%4]
+			}
+			w.msg = append(w.msg, c)
+		}
+	}
+	if !f.fin {
+		return nil
+	}
+
+	defer w.resetMessage()
+	if w.dropping {
+		return nil
+	}
+	return w.archive(t)
+}
+
+// archive 将完整的消息转换为 JSON-RPC 请求 / 响应对象 批量调用中的每条消息单独配对
+func (w *wsDecoder) archive(t time.Time) []*role.Object {
+	var objs []*role.Object
+	for _, msg := range protocol.ParseJSONRPC(w.msg) {
+		if msg.ID == "" {
+			continue
+		}
+		if msg.IsRequest() {
+			objs = append(objs, role.NewRequestObject(&Request{
+				Host:  w.st.SrcIP,
+				Port:  w.st.SrcPort,
+				Proto: ProtoWebSocket,
+				Path:  w.path,
+				Size:  w.msgSize,
+				Time:  w.msgStart,
+				calls: []protocol.JSONRPCMessage{msg},
+			}))
+			continue
+		}
+		objs = append(objs, role.NewResponseObject(&Response{
+			Host:    w.st.SrcIP,
+			Port:    w.st.SrcPort,
+			Proto:   ProtoWebSocket,
+			Size:    w.msgSize,
+			Time:    t,
+			replies: []protocol.JSONRPCMessage{msg},
+		}))
+	}
+	return objs
+}
+
+// wsTransaction 按照 JSON-RPC id 配对 WebSocket 消息
+func wsTransaction(o *role.Object) (string, time.Time, bool) {
+	switch obj := o.Obj.(type) {
+	case *Request:
+		return obj.calls[0].ID, obj.Time, true
+	case *Response:
+		return obj.replies[0].ID, obj.Time, true
+	}
+	return "", time.Time{}, false
+}
@@ -0,0 +1,221 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package phttp
+
+import (
+	"encoding/binary"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol"
+)
+
+// wsFrameBytes 构造 WebSocket 帧 mask 非空时对 payload 进行掩码处理
+func wsFrameBytes(fin bool, opcode byte, mask []byte, payload []byte) []byte {
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	var maskBit byte
+	if mask != nil {
+		maskBit = 0x80
+	}
+
+	b := []byte{b0}
+	switch n := len(payload); {
+	case n < 126:
+		b = append(b, maskBit|byte(n))
+	case n <= 0xFFFF:
+		b = append(b, maskBit|126)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, maskBit|127)
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	if mask == nil {
+		return append(b, payload...)
+	}
+
+	b = append(b, mask...)
+	for i, c := range payload {
+		b = append(b, c^mask[i%4])
+	}
+	return b
+}
+
+func TestIsWebSocketUpgrade(t *testing.T) {
+	assert.True(t, isWebSocketUpgrade(http.Header{"Upgrade": {"WebSocket"}}))
+	assert.True(t, isWebSocketUpgrade(http.Header{"Upgrade": {"h2c, websocket"}}))
+	assert.False(t, isWebSocketUpgrade(http.Header{"Upgrade": {"h2c"}}))
+	assert.False(t, isWebSocketUpgrade(http.Header{}))
+}
+
+func TestIsWebSocketClientFrame(t *testing.T) {
+	mask := []byte{1, 2, 3, 4}
+	tests := []struct {
+		name string
+		b    []byte
+		want bool
+	}{
+		{name: "Text", b: wsFrameBytes(true, wsOpText, mask, []byte("{}")), want: true},
+		{name: "Ping", b: wsFrameBytes(true, 0x9, mask, nil), want: true},
+		{name: "Unmasked", b: wsFrameBytes(true, wsOpText, nil, []byte("{}")), want: false},
+		{name: "ReservedOpcode", b: wsFrameBytes(true, 0x3, mask, nil), want: false},
+		{name: "HTTP", b: []byte("HEAD / HTTP/1.1\r\n"), want: false},
+		{name: "Short", b: []byte{0x81}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isWebSocketClientFrame(tt.b))
+		})
+	}
+}
+
+func TestParseWSFrame(t *testing.T) {
+	payload := make([]byte, 300)
+	b := wsFrameBytes(false, wsOpBinary, []byte{1, 2, 3, 4}, payload)
+
+	f, ok := parseWSFrame(b)
+	require.True(t, ok)
+	assert.False(t, f.fin)
+	assert.Equal(t, byte(wsOpBinary), f.opcode)
+	assert.True(t, f.masked)
+	assert.Equal(t, [4]byte{1, 2, 3, 4}, f.mask)
+	assert.Equal(t, 8, f.headerLen)
+	assert.Equal(t, 300, f.length)
+
+	_, ok = parseWSFrame(b[:5])
+	assert.False(t, ok)
+
+	f, ok = parseWSFrame(wsFrameBytes(true, wsOpText, nil, make([]byte, 70000)))
+	require.True(t, ok)
+	assert.Equal(t, 10, f.headerLen)
+	assert.Equal(t, 70000, f.length)
+}
+
+func TestConnPoolJSONRPC(t *testing.T) {
+	t0 := time.Now()
+	st := socket.Tuple{SrcPort: 50001, DstPort: 8545}
+	opts := common.NewOptions()
+	opts.Merge(OptEnableJSONRPC, true)
+	pool := NewConnPool(opts)
+	defer pool.Clean()
+
+	ch := make(chan socket.RoundTrip, 4)
+	conn := pool.GetOrCreate(st, 8545)
+
+	body := `[{"jsonrpc":"2.0","method":"eth_blockNumber","id":1},{"jsonrpc":"2.0","method":"eth_call","id":2}]`
+	req := "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Type: application/json\r\nContent-Length: " +
+		strconv.Itoa(len(body)) + "\r\n\r\n" + body
+	require.NoError(t, conn.OnL4Packet(&socket.TCPSegment{Tuple: st, Time: t0, Seq: 1, Payload: []byte(req)}, ch))
+
+	body = `[{"jsonrpc":"2.0","id":2,"error":{"code":3,"message":"execution reverted"}},{"jsonrpc":"2.0","id":1,"result":"0x10"}]`
+	rsp := "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: " +
+		strconv.Itoa(len(body)) + "\r\n\r\n" + body
+	require.NoError(t, conn.OnL4Packet(&socket.TCPSegment{Tuple: st.Mirror(), Time: t0.Add(time.Millisecond), Seq: 1, Payload: []byte(rsp)}, ch))
+
+	require.Len(t, ch, 1)
+	rt := <-ch
+	assert.Equal(t, &protocol.RPC{
+		System:       protocol.RPCSystemJSONRPC,
+		Method:       "eth_blockNumber",
+		Outcome:      protocol.RPCOutcomeError,
+		RequestID:    "1",
+		Batch:        2,
+		ErrorCode:    3,
+		ErrorMessage: "execution reverted",
+	}, rt.Request().(*Request).RPC)
+	assert.Nil(t, rt.Response().(*Response).Body) // 未开启 enableBodyCapture
+}
+
+func TestConnPoolWebSocketJSONRPC(t *testing.T) {
+	t0 := time.Now()
+	st := socket.Tuple{SrcPort: 50001, DstPort: 8546}
+	opts := common.NewOptions()
+	opts.Merge(OptEnableJSONRPC, true)
+	opts.Merge("maxBodySize", 64)
+	pool := NewConnPool(opts)
+	defer pool.Clean()
+
+	ch := make(chan socket.RoundTrip, 4)
+	conn := pool.GetOrCreate(st, 8546)
+
+	var seq, mirrorSeq uint32 = 1, 1
+	send := func(mirror bool, d time.Duration, payload []byte) {
+		if mirror {
+			require.NoError(t, conn.OnL4Packet(&socket.TCPSegment{Tuple: st.Mirror(), Time: t0.Add(d), Seq: mirrorSeq, Payload: payload}, ch))
+			mirrorSeq += uint32(len(payload))
+			return
+		}
+		require.NoError(t, conn.OnL4Packet(&socket.TCPSegment{Tuple: st, Time: t0.Add(d), Seq: seq, Payload: payload}, ch))
+		seq += uint32(len(payload))
+	}
+
+	send(false, 0, []byte("GET /ws HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+
+	// 101 响应之后紧跟着一条订阅推送（通知不参与配对）
+	rsp := []byte("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+	rsp = append(rsp, wsFrameBytes(true, wsOpText, nil, []byte(`{"jsonrpc":"2.0","method":"eth_subscription"}`))...)
+	send(true, time.Millisecond, rsp)
+
+	require.Len(t, ch, 1)
+	rt := <-ch
+	assert.Equal(t, http.StatusSwitchingProtocols, rt.Response().(*Response).StatusCode)
+
+	// 两个请求 首个请求分为两帧 中间穿插 ping 控制帧
+	mask := []byte{0x11, 0x22, 0x33, 0x44}
+	call := []byte(`{"jsonrpc":"2.0","method":"eth_chainId","id":7}`)
+	req := wsFrameBytes(false, wsOpText, mask, call[:10])
+	req = append(req, wsFrameBytes(true, 0x9, mask, nil)...)
+	req = append(req, wsFrameBytes(true, wsOpContinuation, mask, call[10:])...)
+	req = append(req, wsFrameBytes(true, wsOpText, mask, []byte(`{"jsonrpc":"2.0","method":"eth_gasPrice","id":8}`))...)
+	send(false, 2*time.Millisecond, req)
+
+	// 超过 maxBodySize 的消息被跳过 且分两个数据包到达
+	large := wsFrameBytes(true, wsOpText, mask, []byte(`{"jsonrpc":"2.0","method":"eth_getLogs","params":[{"fromBlock":"0x1"}],"id":9}`))
+	send(false, 3*time.Millisecond, large[:20])
+	send(false, 4*time.Millisecond, large[20:])
+
+	// 响应乱序到达
+	send(true, 5*time.Millisecond, wsFrameBytes(true, wsOpText, nil, []byte(`{"jsonrpc":"2.0","id":8,"result":"0x1"}`)))
+	send(true, 6*time.Millisecond, wsFrameBytes(true, wsOpText, nil,
+		[]byte(`{"jsonrpc":"2.0","id":7,"error":{"code":-32601,"message":"no"}}`)))
+	send(true, 7*time.Millisecond, wsFrameBytes(true, wsOpText, nil, []byte(`{"jsonrpc":"2.0","id":9,"result":[]}`)))
+
+	require.Len(t, ch, 2)
+	rt = <-ch
+	r := rt.Request().(*Request)
+	assert.Equal(t, ProtoWebSocket, r.Proto)
+	assert.Equal(t, "/ws", r.Path)
+	assert.Equal(t, "eth_gasPrice", r.RPC.Method)
+	assert.Equal(t, protocol.RPCOutcomeOK, r.RPC.Outcome)
+	assert.Equal(t, 3*time.Millisecond, rt.Duration())
+
+	rt = <-ch
+	r = rt.Request().(*Request)
+	assert.Equal(t, "eth_chainId", r.RPC.Method)
+	assert.Equal(t, "7", r.RPC.RequestID)
+	assert.Equal(t, protocol.RPCOutcomeError, r.RPC.Outcome)
+	assert.Equal(t, -32601, r.RPC.ErrorCode)
+	assert.Equal(t, len(call), r.Size)
+	assert.Equal(t, 4*time.Millisecond, rt.Duration())
+}
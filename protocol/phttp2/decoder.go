@@ -19,31 +19,33 @@ import (
 	"math"
 	"time"
 
-	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 
 	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/bufpool"
+	"github.com/packetd/packetd/internal/tailbuf"
 	"github.com/packetd/packetd/internal/zerocopy"
 	"github.com/packetd/packetd/protocol"
 	"github.com/packetd/packetd/protocol/role"
 )
 
-func newError(format string, args ...any) error {
-	format = "http2/decoder: " + format
-	return errors.Errorf(format, args...)
+func newError(code protocol.ErrorCode, format string, args ...any) error {
+	return protocol.NewDecodeError(socket.L7ProtoHTTP2, code, "http2/decoder: "+format, args...)
 }
 
 var (
-	errInvalidBytes    = newError("invalid bytes")
-	errDecodeHeader    = newError("decode Header failed")
-	errSkipConnPreface = newError("skip connection preface")
+	errResyncFailed    = newError(protocol.ErrCodeResyncFailed, "frame boundary lost")
+	errHeaderTooShort  = newError(protocol.ErrCodeHeaderTooShort, "frame header too short")
+	errPayloadOverflow = newError(protocol.ErrCodeLengthOverflow, "frame payload length overflow")
+	errStreamIDJumped  = newError(protocol.ErrCodeResyncFailed, "streamID jumped")
 )
 
 var connPreface = []byte("HTTP/2.0\r\n\r\nSM\r\n\r\n")
 
 const (
-	// maxPayloadSize HTTP2 帧最大 payload 大小
+	// maxPayloadSize HTTP2 帧最大 payload 大小 即 SETTINGS_MAX_FRAME_SIZE 允许的上限
 	maxPayloadSize = 0xFFFFFF
 
 	// headerMask HTTP2 header 掩码
@@ -63,6 +65,42 @@ const (
 
 const (
 	OptTrailerKeys = "trailerKeys"
+
+	// OptCountMessages 按照 gRPC Length-Prefixed-Message 格式统计 DATA 帧中的消息
+	OptCountMessages = "countMessages"
+
+	// OptStreamIdleTimeout 流超过该时长未收到任何帧即被回收 为负数时不回收
+	OptStreamIdleTimeout = "streamIdleTimeout"
+
+	// OptMaxStreams 单链接单方向最多同时追踪的流数量 超出时回收 StreamID 最小的流
+	OptMaxStreams = "maxStreams"
+
+	// OptMessageFlushInterval 开启 OptCountMessages 后 未结束的流每隔该时长以 Progress 单向事件上报增量的消息统计
+	// 为 0 或者负数时仅在流结束时上报
+	OptMessageFlushInterval = "messageFlushInterval"
+)
+
+const (
+	// defaultStreamIdleTimeout 默认的流空闲超时 需要覆盖 gRPC 流式调用中消息的间隔
+	defaultStreamIdleTimeout = 5 * time.Minute
+
+	// sweepInterval 两次空闲流检查之间的最小间隔 避免每次 Decode 都遍历所有流
+	sweepInterval = time.Second
+)
+
+const (
+	reclaimIdle      = "idle"
+	reclaimLimit     = "limit"
+	reclaimOversized = "oversized"
+)
+
+var reclaimedStreamsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: common.App,
+		Name:      "http2_reclaimed_streams_total",
+		Help:      "HTTP/2 streams reclaimed before END_STREAM total",
+	},
+	[]string{"reason"},
 )
 
 // decoder HTTP/2 协议解析器
@@ -74,22 +112,37 @@ type decoder struct {
 	st         socket.TupleRaw
 	serverPort socket.Port
 
-	rbuf    *bytes.Buffer
-	hfd     *HeaderFieldDecoder
-	streams map[uint32]*streamDecoder
-
-	prevData    *streamData // 上一轮解析的状态
-	tail        []byte      // 尾部数据拼接 仅允许拼接一次 避免上一轮切割了部分数据
-	partial     uint8       // 标记上一轮的 header 是否待拼接
-	maxStreamID uint32      // 记录当前链接最大的 streamID
+	rbuf     *bytes.Buffer
+	hfd      *HeaderFieldDecoder
+	streams  map[uint32]*streamDecoder
+	settings *connSettings // 当前方向发送方声明的链接参数
+	open     *openStreams  // 两个方向共享的打开流
+	release  func()
+
+	countMessages bool
+	flushInterval time.Duration
+	idleTimeout   time.Duration
+	maxStreams    int
+	lastSweep     time.Time
+	reclaimed     []*role.Object // 回收时强制归档的对象 随下一次 Decode 的结果一并返回
+	guard         protocol.PayloadGuard
+
+	prevData    *streamData    // 上一轮解析的状态
+	tail        tailbuf.Buffer // 尾部数据拼接 仅允许拼接一次 避免上一轮切割了部分数据
+	partial     uint8          // 标记上一轮的 header 是否待拼接
+	maxStreamID uint32         // 记录当前链接最大的 streamID
 }
 
 // Free 释放持有的资源
 func (d *decoder) Free() {
 	bufpool.Release(d.rbuf)
+	d.tail.Free()
 	for _, stream := range d.streams {
 		stream.Free()
 	}
+	if d.release != nil {
+		d.release()
+	}
 }
 
 // Decode 从 zerocopy.Reader 解析 HTTP/2 二进制帧数据流 构建完整 RoundTrip
@@ -144,15 +197,20 @@ func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error)
 	var cut bool // 标识上一轮是否是待拼接数据
 	var objs []*role.Object
 	for len(b) > 0 {
+		if d.guard.Skipping() {
+			_, b = d.guard.Drain(b)
+			continue
+		}
+
 		// 如果已经出现过两次拼接 返回解析错误
 		if d.partial > 1 {
-			return nil, errInvalidBytes
+			return nil, errResyncFailed
 		}
 
 		// 如果上一轮待拼接的数据 则追加在开头
 		// 使用 buffer 避免重复分配内存
 		if d.partial == 1 {
-			d.rbuf.Write(d.tail)
+			d.rbuf.Write(d.tail.Bytes())
 			d.rbuf.Write(b)
 			b = d.rbuf.Bytes()
 		}
@@ -170,6 +228,11 @@ func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error)
 				}
 				return nil, err
 			}
+			if data.skipped {
+				d.partial = 0
+				b = data.tail
+				continue
+			}
 		} else {
 			// 如果上一轮解析中数据还没读取完毕
 			cut = true
@@ -204,6 +267,9 @@ func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error)
 		}
 
 		if obj == nil {
+			if p := sd.progress(t); p != nil {
+				objs = append(objs, p)
+			}
 			continue
 		}
 		objs = append(objs, obj)
@@ -213,18 +279,49 @@ func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error)
 		}
 	}
 
+	d.reclaimIdle(t)
+	if len(d.reclaimed) > 0 {
+		objs = append(objs, d.reclaimed...)
+		d.reclaimed = nil
+	}
 	return objs, nil
 }
 
+// NewDecoder 创建 HTTP/2 解码器
+//
+// 独立创建的 decoder 无法与另一个方向共享打开流的状态 链接池内应使用 NewConnDecoder
 func NewDecoder(st socket.Tuple, serverPort socket.Port, opts common.Options) protocol.Decoder {
+	return NewConnDecoder(st, serverPort, opts, protocol.NewConnContext(0), nil)
+}
+
+// NewConnDecoder 创建共享链接上下文的 HTTP/2 解码器 release 在 Free 时调用
+func NewConnDecoder(st socket.Tuple, serverPort socket.Port, opts common.Options, ctx *protocol.ConnContext, release func()) protocol.Decoder {
 	trailerKeys, _ := opts.GetStringSlice(OptTrailerKeys)
+	countMessages, _ := opts.GetBool(OptCountMessages)
+	flushInterval, _ := opts.GetDuration(OptMessageFlushInterval)
+	idleTimeout, err := opts.GetDuration(OptStreamIdleTimeout)
+	if err != nil || idleTimeout == 0 {
+		idleTimeout = defaultStreamIdleTimeout
+	}
+	maxStreams, err := opts.GetInt(OptMaxStreams)
+	if err != nil || maxStreams <= 0 {
+		maxStreams = MaxConcurrentStreams
+	}
 	return &decoder{
-		st:         st.ToRaw(),
-		serverPort: serverPort,
-		hfd:        NewHeaderFieldDecoder(trailerKeys...),
-		rbuf:       bufpool.Acquire(),
-		prevData:   &streamData{},
-		streams:    make(map[uint32]*streamDecoder),
+		countMessages: countMessages,
+		flushInterval: flushInterval,
+		idleTimeout:   idleTimeout,
+		maxStreams:    maxStreams,
+		st:            st.ToRaw(),
+		serverPort:    serverPort,
+		hfd:           NewHeaderFieldDecoder(trailerKeys...),
+		guard:         protocol.NewPayloadGuard(socket.L7ProtoHTTP2, opts, maxPayloadSize),
+		rbuf:          bufpool.Acquire(),
+		prevData:      &streamData{},
+		streams:       make(map[uint32]*streamDecoder),
+		settings:      &connSettings{},
+		open:          connOpenStreams(ctx, maxStreams),
+		release:       release,
 	}
 }
 
@@ -235,7 +332,7 @@ func (d *decoder) getOrCreateStream(id uint32) *streamDecoder {
 
 	// stream 清理机制
 	// 清理 id 最小的 stream 避免 streamid 未正常结束导致泄漏
-	if len(d.streams) >= MaxConcurrentStreams {
+	if len(d.streams) >= d.maxStreams {
 		minV := uint32(math.MaxUint32)
 		for sid := range d.streams {
 			if sid <= 0 {
@@ -245,14 +342,14 @@ func (d *decoder) getOrCreateStream(id uint32) *streamDecoder {
 				minV = sid
 			}
 		}
-
-		if sd, ok := d.streams[minV]; ok {
-			sd.Free() // 释放资源再删除
-			delete(d.streams, minV)
-		}
+		d.reclaimStream(minV, reclaimLimit)
 	}
 
-	sd := newStreamDecoder(id, d.st, d.serverPort, d.hfd)
+	sd := newStreamDecoder(id, d.st, d.serverPort, d.hfd, d.settings, d.open)
+	if d.countMessages {
+		sd.messages = &messageCounter{}
+		sd.flushInterval = d.flushInterval
+	}
 	d.streams[id] = sd
 	return sd
 }
@@ -264,26 +361,59 @@ func (d *decoder) deleteStream(id uint32) {
 	}
 }
 
+// reclaimIdle 回收超过 idleTimeout 未收到任何帧的流
+//
+// 客户端消失或者收到 GOAWAY 后 流可能永远等不到 END_STREAM
+func (d *decoder) reclaimIdle(t time.Time) {
+	if d.idleTimeout < 0 || t.Sub(d.lastSweep) < sweepInterval {
+		return
+	}
+	d.lastSweep = t
+
+	for id, sd := range d.streams {
+		if id == 0 || t.Sub(sd.t0) < d.idleTimeout {
+			continue // stream 0 为链接控制帧 不存在请求
+		}
+		d.reclaimStream(id, reclaimIdle)
+	}
+}
+
+// reclaimStream 回收未正常结束的流 强制归档的对象暂存在 reclaimed 中
+func (d *decoder) reclaimStream(id uint32, reason string) {
+	sd, ok := d.streams[id]
+	if !ok {
+		return
+	}
+	if obj := sd.abandon(); obj != nil {
+		d.reclaimed = append(d.reclaimed, obj)
+	}
+	reclaimedStreamsTotal.WithLabelValues(reason).Inc()
+	d.open.close(id)
+	sd.Free()
+	delete(d.streams, id)
+}
+
 type streamData struct {
-	id    uint32
-	data  []byte
-	tail  []byte
-	lackN uint32
+	id      uint32
+	data    []byte
+	tail    []byte
+	lackN   uint32
+	skipped bool // 超长帧已被跳过 仅 tail 有效
 }
 
 // decodeHeader decoder 主要负责读取 HTTP2 中的 Header 并进行 streams 的分发
 func (d *decoder) decodeHeader(b []byte) (*streamData, error) {
 	// HTTP/2 在建链的时候会先发送 Connection Preface 数据包用于确认双方都支持 HTTP/2 协议
-	// 此数据包明文传输
+	// 此数据包明文传输 属于预期内的报文 跳过即可
 	if bytes.HasSuffix(b, connPreface) {
-		return nil, errSkipConnPreface
+		return nil, nil
 	}
 
 	// header 长度不足则 Clone 传入字节 留着下一轮拼接至头部解析
 	if len(b) < headerLength {
 		d.partial++
-		d.tail = bytes.Clone(b) // 必须拷贝内存
-		return nil, errDecodeHeader
+		d.tail.Set(b) // 必须拷贝内存
+		return nil, errHeaderTooShort
 	}
 
 	// 前 3 个字节为 Header Length 即 24 位无符号整数
@@ -291,7 +421,7 @@ func (d *decoder) decodeHeader(b []byte) (*streamData, error) {
 	payloadLen := uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
 	if payloadLen > maxPayloadSize {
 		d.partial++ // 连续两次则上层需要判为异常
-		return nil, errDecodeHeader
+		return nil, errPayloadOverflow
 	}
 
 	var data []byte
@@ -318,10 +448,23 @@ func (d *decoder) decodeHeader(b []byte) (*streamData, error) {
 	// 不会突然新增一个大于之前非常多的 StreamID 此时大概率是流乱序了
 	if d.maxStreamID < streamID {
 		if streamID > d.maxStreamID+MaxConcurrentStreams*2 {
-			return nil, errDecodeHeader
+			return nil, errStreamIDJumped
 		}
 		d.maxStreamID = streamID
 	}
+
+	// 超长的帧不再提交至 streamDecoder 按照声明长度排空
+	// 头部块需要同步 HPACK 动态表 无论长度均需解析
+	if d.guard.Exceeded(int(payloadLen)) && !isHeaderBlock(b[3]) {
+		// 携带 END_STREAM 的 DATA 帧被跳过后流不会再结束 直接回收
+		if b[3] == frameData && b[4]&flagEndStream != 0 {
+			d.reclaimStream(streamID, reclaimOversized)
+		}
+		d.guard.Skip(int(total))
+		_, tail = d.guard.Drain(b)
+		return &streamData{tail: tail, skipped: true}, nil
+	}
+
 	return &streamData{
 		id:    streamID,
 		data:  data,
@@ -329,3 +472,8 @@ func (d *decoder) decodeHeader(b []byte) (*streamData, error) {
 		lackN: lackN,
 	}, nil
 }
+
+// isHeaderBlock 是否为携带 HPACK 头部块的帧
+func isHeaderBlock(frameType byte) bool {
+	return frameType == frameHeaders || frameType == frameContinuation || frameType == framePushPromise
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/splitio"
 	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol"
 	"github.com/packetd/packetd/protocol/role"
 )
 
@@ -347,9 +348,236 @@ func TestDecoderDecode(t *testing.T) {
 					req := obj.Obj.(*Request)
 					req.Time = time.Time{}
 					req.Host = ""
+					req.ConcurrentStreams = 0 // 见 TestDecoderConcurrentStreams
 					assert.Equal(t, tt.objs[idx].Obj.(*Request), req)
 				}
 			}
 		})
 	}
 }
+
+func TestDecoderSettings(t *testing.T) {
+	settings := func(ack bool, entries ...uint32) []byte {
+		var flags uint8
+		if ack {
+			flags = flagAck
+		}
+		var payload []byte
+		for i := 0; i+1 < len(entries); i += 2 {
+			payload = append(payload, byte(entries[i]>>8), byte(entries[i]))
+			payload = append(payload, byte(entries[i+1]>>24), byte(entries[i+1]>>16), byte(entries[i+1]>>8), byte(entries[i+1]))
+		}
+		return buildFrame(0, frameSettings, flags, payload)
+	}
+	response := func(streamID int) []byte {
+		b := buildFrame(streamID, frameHeaders, flagEndHeaders,
+			buildHeadersFramePayload(false, 0, map[string]string{":status": "200"}),
+		)
+		return append(b, buildFrame(streamID, frameData, flagEndStream, []byte("ok"))...)
+	}
+
+	var st socket.Tuple
+	dec := NewDecoder(st, 8080, common.NewOptions())
+	defer dec.Free()
+
+	decode := func(b []byte) []*role.Object {
+		objs, err := dec.Decode(zerocopy.NewBuffer(b), time.Now())
+		assert.NoError(t, err)
+		return objs
+	}
+
+	// 未观测到 SETTINGS 帧
+	objs := decode(response(1))
+	assert.Len(t, objs, 1)
+	assert.Equal(t, uint32(0), objs[0].Obj.(*Response).MaxConcurrentStreams)
+
+	// SETTINGS_INITIAL_WINDOW_SIZE(0x4) 与 SETTINGS_MAX_CONCURRENT_STREAMS(0x3)
+	decode(settings(false, 0x4, 65535, 0x3, 64))
+	decode(settings(true))
+	objs = decode(response(3))
+	assert.Len(t, objs, 1)
+	assert.Equal(t, uint32(64), objs[0].Obj.(*Response).MaxConcurrentStreams)
+}
+
+func TestDecoderReclaimStreams(t *testing.T) {
+	headers := func(streamID int) []byte {
+		return buildFrame(streamID, frameHeaders, flagEndHeaders,
+			buildHeadersFramePayload(false, 0, map[string]string{":status": "200"}),
+		)
+	}
+	t0 := time.Now()
+	var st socket.Tuple
+
+	t.Run("Idle", func(t *testing.T) {
+		opts := common.Options{OptStreamIdleTimeout: "10s"}
+		dec := NewDecoder(st, 8080, opts)
+		defer dec.Free()
+
+		objs, err := dec.Decode(zerocopy.NewBuffer(headers(1)), t0)
+		assert.NoError(t, err)
+		assert.Nil(t, objs)
+
+		// 未超过空闲时长
+		objs, err = dec.Decode(zerocopy.NewBuffer(buildFrame(0, framePing, 0, make([]byte, 8))), t0.Add(5*time.Second))
+		assert.NoError(t, err)
+		assert.Nil(t, objs)
+
+		objs, err = dec.Decode(zerocopy.NewBuffer(buildFrame(0, framePing, 0, make([]byte, 8))), t0.Add(11*time.Second))
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+
+		rsp := objs[0].Obj.(*Response)
+		assert.Equal(t, uint32(1), rsp.StreamID)
+		assert.Equal(t, OutcomeIncomplete, rsp.Outcome)
+		assert.Equal(t, t0, rsp.Time)
+		assert.Len(t, dec.(*decoder).streams, 1) // 仅剩 stream 0
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		opts := common.Options{OptMaxStreams: 2}
+		dec := NewDecoder(st, 8080, opts)
+		defer dec.Free()
+
+		var lst []*role.Object
+		for _, id := range []int{1, 3, 5} {
+			objs, err := dec.Decode(zerocopy.NewBuffer(headers(id)), t0)
+			assert.NoError(t, err)
+			lst = append(lst, objs...)
+		}
+		assert.Len(t, lst, 1)
+		assert.Equal(t, uint32(1), lst[0].Obj.(*Response).StreamID)
+		assert.Equal(t, OutcomeIncomplete, lst[0].Obj.(*Response).Outcome)
+	})
+
+	t.Run("HeaderNotDecoded", func(t *testing.T) {
+		opts := common.Options{OptMaxStreams: 1}
+		dec := NewDecoder(st, 8080, opts)
+		defer dec.Free()
+
+		// 仅收到 DATA 帧的流直接回收 不产生任何对象
+		objs, err := dec.Decode(zerocopy.NewBuffer(buildFrame(1, frameData, 0, []byte("x"))), t0)
+		assert.NoError(t, err)
+		assert.Nil(t, objs)
+
+		objs, err = dec.Decode(zerocopy.NewBuffer(headers(3)), t0)
+		assert.NoError(t, err)
+		assert.Nil(t, objs)
+	})
+
+	t.Run("Oversized", func(t *testing.T) {
+		opts := common.Options{protocol.OptMaxPayloadSize: 16}
+		dec := NewDecoder(st, 8080, opts)
+		defer dec.Free()
+
+		objs, err := dec.Decode(zerocopy.NewBuffer(headers(1)), t0)
+		assert.NoError(t, err)
+		assert.Nil(t, objs)
+
+		// 超长的 DATA 帧跨越两次读取 其后紧跟着正常的流
+		oversized := buildFrame(1, frameData, flagEndStream, make([]byte, 32))
+		objs, err = dec.Decode(zerocopy.NewBuffer(oversized[:20]), t0)
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+		assert.Equal(t, uint32(1), objs[0].Obj.(*Response).StreamID)
+		assert.Equal(t, OutcomeIncomplete, objs[0].Obj.(*Response).Outcome)
+
+		b := append(oversized[20:], headers(3)...)
+		b = append(b, buildFrame(3, frameData, flagEndStream, []byte("ok"))...)
+		objs, err = dec.Decode(zerocopy.NewBuffer(b), t0)
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+		assert.Equal(t, uint32(3), objs[0].Obj.(*Response).StreamID)
+		assert.Empty(t, objs[0].Obj.(*Response).Outcome)
+	})
+}
+
+func TestDecoderConcurrentStreams(t *testing.T) {
+	request := func(streamID int) []byte {
+		b := buildFrame(streamID, frameHeaders, flagEndHeaders,
+			buildHeadersFramePayload(false, 0, map[string]string{":method": "POST", ":path": "/"}),
+		)
+		return append(b, buildFrame(streamID, frameData, flagEndStream, []byte("ok"))...)
+	}
+	response := func(streamID int) []byte {
+		b := buildFrame(streamID, frameHeaders, flagEndHeaders,
+			buildHeadersFramePayload(false, 0, map[string]string{":status": "200"}),
+		)
+		return append(b, buildFrame(streamID, frameData, flagEndStream, []byte("ok"))...)
+	}
+
+	st := socket.Tuple{SrcPort: 50000, DstPort: 8080}
+	ctx := protocol.NewConnContext(0)
+	client := NewConnDecoder(st, 8080, common.NewOptions(), ctx, nil)
+	defer client.Free()
+	server := NewConnDecoder(st.Mirror(), 8080, common.NewOptions(), ctx, nil)
+	defer server.Free()
+
+	decode := func(dec protocol.Decoder, b []byte) []*role.Object {
+		objs, err := dec.Decode(zerocopy.NewBuffer(b), time.Now())
+		assert.NoError(t, err)
+		return objs
+	}
+	concurrent := func(b []byte) int {
+		objs := decode(client, b)
+		assert.Len(t, objs, 1)
+		return objs[0].Obj.(*Request).ConcurrentStreams
+	}
+
+	assert.Equal(t, 1, concurrent(request(1)))
+	assert.Equal(t, 2, concurrent(request(3)))
+	assert.Equal(t, 3, concurrent(request(5)))
+
+	// S1 正常结束 S3 被服务端重置 S5 未收到响应仍处于打开状态
+	assert.Len(t, decode(server, response(1)), 1)
+	decode(server, buildFrame(3, frameRSTStream, 0, make([]byte, 4)))
+	assert.Equal(t, 2, concurrent(request(7)))
+
+	// 客户端重置 S5
+	decode(client, buildFrame(5, frameRSTStream, 0, make([]byte, 4)))
+	assert.Equal(t, 2, concurrent(request(9)))
+
+	assert.True(t, StreamLimitReached(3, 3))
+	assert.False(t, StreamLimitReached(2, 3))
+	assert.False(t, StreamLimitReached(200, 0))
+}
+
+func TestDecoderMessageProgress(t *testing.T) {
+	st := socket.Tuple{SrcPort: 50000, DstPort: 8080}
+	opts := common.Options{OptCountMessages: true, OptMessageFlushInterval: "10s"}
+	dec := NewDecoder(st, 8080, opts)
+	defer dec.Free()
+
+	t0 := time.Now()
+	decode := func(b []byte, d time.Duration) []*role.Object {
+		objs, err := dec.Decode(zerocopy.NewBuffer(b), t0.Add(d))
+		assert.NoError(t, err)
+		return objs
+	}
+
+	headers := buildFrame(1, frameHeaders, flagEndHeaders,
+		buildHeadersFramePayload(false, 0, map[string]string{":method": "POST", ":path": "/pkg.Svc/Watch"}),
+	)
+	assert.Nil(t, decode(headers, 0))
+	assert.Nil(t, decode(buildFrame(1, frameData, 0, buildMessage(3)), time.Second))
+
+	// 消息跨越上报时刻 剩余部分仍需正确解析
+	message := buildMessage(8)
+	objs := decode(buildFrame(1, frameData, 0, message[:6]), 11*time.Second)
+	assert.Len(t, objs, 1)
+	assert.Equal(t, role.Role(role.OneWay), objs[0].Role)
+
+	p := objs[0].Obj.(*Progress)
+	assert.Equal(t, DirectionRequest, p.Direction)
+	assert.Equal(t, "/pkg.Svc/Watch", p.Path)
+	assert.Equal(t, uint16(8080), p.ServerPort)
+	assert.Equal(t, 2, p.Messages)
+	assert.Equal(t, []int{3, 8}, p.MessageSizes)
+
+	assert.Nil(t, decode(buildFrame(1, frameData, 0, message[6:]), 12*time.Second))
+	objs = decode(buildFrame(1, frameData, flagEndStream, buildMessage(1)), 13*time.Second)
+	assert.Len(t, objs, 1)
+
+	req := objs[0].Obj.(*Request)
+	assert.Equal(t, 1, req.Messages)
+	assert.Equal(t, []int{1}, req.MessageSizes)
+}
@@ -15,6 +15,7 @@ package phttp2
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/packetd/packetd/common"
@@ -25,6 +26,10 @@ import (
 
 func init() {
 	protocol.Register(socket.L7ProtoHTTP2, NewConnPool)
+	protocol.Describe(socket.L7ProtoHTTP2, protocol.Capability{
+		Versions: []string{"h2", "h2c"},
+		Options:  []string{OptTrailerKeys, OptCountMessages, OptStreamIdleTimeout, OptMaxStreams, protocol.OptMaxPayloadSize},
+	})
 }
 
 const (
@@ -42,25 +47,49 @@ const (
 )
 
 // NewConnPool 创建 HTTP2 协议连接池
+//
+// 同一条链接两个方向的 decoder 共享链接上下文 以便统计处于打开状态的流
 func NewConnPool(opts common.Options) protocol.ConnPool {
+	cs := protocol.NewConnContexts(0)
 	return protocol.NewL7TCPConnPool(
 		func() role.Matcher {
-			return role.NewListMatcher(MaxConcurrentStreams, func(req, rsp *role.Object) bool {
-				return req.Obj.(*Request).StreamID == rsp.Obj.(*Response).StreamID
-			})
-		},
-		func(pair *role.Pair) socket.RoundTrip {
-			return &RoundTrip{
-				request:  pair.Request.Obj.(*Request),
-				response: pair.Response.Obj.(*Response),
-			}
+			return NewStreamMatcher()
 		},
+		NewRoundTrip,
 		func(st socket.Tuple, serverPort socket.Port) protocol.Decoder {
-			return NewDecoder(st, serverPort, opts)
+			opts.Merge(OptMessageFlushInterval, 0) // Progress 仅由 gRPC 消费
+			return NewConnDecoder(st, serverPort, opts, cs.Acquire(st, serverPort), func() {
+				cs.Release(st, serverPort)
+			})
 		},
 	)
 }
 
+// NewRoundTrip 根据配对成功的 *role.Pair 创建 RoundTrip
+//
+// HTTP/1.1 链接经 h2c 升级后同样复用此函数
+func NewRoundTrip(pair *role.Pair) socket.RoundTrip {
+	req := pair.Request.Obj.(*Request)
+	rsp := pair.Response.Obj.(*Response)
+
+	// HTTP/2 不保留响应体 仅能根据状态码推断错误码
+	if req.RPC = protocol.DetectRPC(req.Method, req.Path, req.Header); req.RPC != nil {
+		status, _ := strconv.Atoi(rsp.Status)
+		req.RPC.Resolve(status, nil)
+	}
+	return &RoundTrip{
+		request:  req,
+		response: rsp,
+	}
+}
+
+// NewStreamMatcher 创建按照 StreamID 配对请求的 Matcher 每个链接独立一个实例
+func NewStreamMatcher() role.Matcher {
+	return role.NewListMatcher(MaxConcurrentStreams, func(req, rsp *role.Object) bool {
+		return req.Obj.(*Request).StreamID == rsp.Obj.(*Response).StreamID
+	})
+}
+
 // Request HTTP2 请求
 type Request struct {
 	StreamID  uint32
@@ -74,6 +103,28 @@ type Request struct {
 	Header    http.Header
 	Size      int
 	Time      time.Time
+
+	// ConcurrentStreams 请求发出时链接内处于打开状态的流数量（包含自身）
+	// 流由客户端 HEADERS 打开 双方均发送 END_STREAM 或者任意一方发送 RST_STREAM 后关闭
+	ConcurrentStreams int
+
+	// Messages / MessageSizes 开启 OptCountMessages 后统计的 gRPC 消息数量以及每条消息的大小
+	Messages     int
+	MessageSizes []int
+
+	Client *protocol.Client `json:",omitempty"`
+
+	// Trace 请求携带的 traceparent / tracestate 同 phttp
+	Trace *protocol.TraceContext `json:",omitempty"`
+
+	// Outcome 流的结束方式 正常结束时为空 见 OutcomeIncomplete
+	Outcome string `json:",omitempty"`
+
+	// RPC 识别出的 Connect / Twirp 调用
+	RPC *protocol.RPC `json:",omitempty"`
+
+	// Operation 依据 OpenAPI 描述匹配出的接口 同 phttp
+	Operation *protocol.Operation `json:",omitempty"`
 }
 
 // Response HTTP/2 响应
@@ -86,6 +137,48 @@ type Response struct {
 	Header   http.Header
 	Size     int
 	Time     time.Time
+
+	// MaxConcurrentStreams 服务端通过 SETTINGS 帧声明的并发流上限 0 表示未观测到
+	MaxConcurrentStreams uint32
+
+	Messages     int
+	MessageSizes []int
+
+	Outcome string `json:",omitempty"`
+}
+
+const (
+	DirectionRequest  = "request"
+	DirectionResponse = "response"
+)
+
+// Progress 流式调用尚未结束时上报的增量消息统计
+//
+// 开启 OptCountMessages 以及 OptMessageFlushInterval 后 未结束的流周期性地以单向事件提交
+// 流结束时归档的 Messages / MessageSizes 仅包含最后一次上报之后的消息 两者相加即为整个流的消息
+type Progress struct {
+	StreamID   uint32
+	Host       string // 客户端地址 与 Request 一致
+	Port       uint16
+	ServerHost string
+	ServerPort uint16
+	Path       string
+	Direction  string // 消息的发送方向 DirectionRequest / DirectionResponse
+	Time       time.Time
+
+	Messages     int
+	MessageSizes []int
+}
+
+// OutcomeIncomplete 流在收到 END_STREAM 之前即被回收（空闲超时或者超出单链接流数量上限）
+// 此时 Size 仅为回收前收到的字节数 Response.Time 为最后一次收到帧的时间
+const OutcomeIncomplete = "incomplete"
+
+// StreamLimitReached 返回请求发出时链接的并发流是否已达到服务端声明的上限
+//
+// 达到上限后新的请求需要在客户端排队等待 表现为无规律的延迟抖动
+func StreamLimitReached(concurrent int, limit uint32) bool {
+	return limit > 0 && concurrent >= int(limit)
 }
 
 // RoundTrip HTTP/2 单次请求来回
@@ -0,0 +1,73 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package phttp2
+
+import (
+	"encoding/binary"
+)
+
+const (
+	// messagePrefixLength gRPC Length-Prefixed-Message 前缀长度
+	// 1 字节 Compressed-Flag + 4 字节 Message-Length
+	messagePrefixLength = 5
+
+	// maxRecordedMessages 单个流最多记录的消息大小个数 超出后仅计数
+	maxRecordedMessages = 1024
+)
+
+// messageCounter 统计 DATA 帧中的 gRPC 消息数量以及大小
+//
+// DATA 帧的边界与消息边界无关 单条消息可能跨越多个帧 单个帧也可能包含多条消息
+// 因此需要在整个流的生命周期内持续跟踪前缀以及剩余未读取的字节
+type messageCounter struct {
+	prefix  [messagePrefixLength]byte
+	prefixN int
+	remain  uint32 // 当前消息剩余未读取的字节数
+
+	count int
+	sizes []int
+}
+
+func (c *messageCounter) feed(b []byte) {
+	for len(b) > 0 {
+		if c.remain > 0 {
+			n := min(uint32(len(b)), c.remain)
+			c.remain -= n
+			b = b[n:]
+			continue
+		}
+
+		n := copy(c.prefix[c.prefixN:], b)
+		c.prefixN += n
+		b = b[n:]
+		if c.prefixN < messagePrefixLength {
+			return
+		}
+
+		c.prefixN = 0
+		size := binary.BigEndian.Uint32(c.prefix[1:])
+		c.count++
+		if len(c.sizes) < maxRecordedMessages {
+			c.sizes = append(c.sizes, int(size))
+		}
+		c.remain = size
+	}
+}
+
+// take 返回统计结果并清空计数 尚未读取完毕的消息状态保留 流未结束时也可以调用
+func (c *messageCounter) take() (int, []int) {
+	count, sizes := c.count, c.sizes
+	c.count, c.sizes = 0, nil
+	return count, sizes
+}
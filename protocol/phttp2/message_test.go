@@ -0,0 +1,58 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package phttp2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildMessage(size int) []byte {
+	b := make([]byte, messagePrefixLength+size)
+	binary.BigEndian.PutUint32(b[1:], uint32(size))
+	return b
+}
+
+func TestMessageCounter(t *testing.T) {
+	stream := bytes.Join([][]byte{buildMessage(3), buildMessage(0), buildMessage(300), buildMessage(7)}, nil)
+
+	tests := []struct {
+		name  string
+		chunk int
+	}{
+		{name: "Whole", chunk: len(stream)},
+		{name: "SplitPrefix", chunk: 2},
+		{name: "OneByte", chunk: 1},
+		{name: "Chunk100", chunk: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c messageCounter
+			for i := 0; i < len(stream); i += tt.chunk {
+				c.feed(stream[i:min(i+tt.chunk, len(stream))])
+			}
+			count, sizes := c.take()
+			assert.Equal(t, 4, count)
+			assert.Equal(t, []int{3, 0, 300, 7}, sizes)
+
+			count, sizes = c.take()
+			assert.Equal(t, 0, count)
+			assert.Nil(t, sizes)
+		})
+	}
+}
@@ -15,10 +15,14 @@ package phttp2
 
 import (
 	"bytes"
+	"encoding/binary"
+	"math"
+	"sync"
 	"time"
 
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/bufpool"
+	"github.com/packetd/packetd/protocol"
 	"github.com/packetd/packetd/protocol/role"
 )
 
@@ -27,10 +31,12 @@ const (
 )
 
 var (
-	errInvalidPadding         = newError("invalid padding")
-	errInvalidStreamID        = newError("invalid streamID")
-	errDecodeHeaderFrame      = newError("decode Header frame failed")
-	errDecodePushPromiseFrame = newError("decode PushPromise frame failed")
+	errInvalidPadding         = newError(protocol.ErrCodeMalformed, "invalid padding")
+	errInvalidStreamID        = newError(protocol.ErrCodeMalformed, "invalid streamID")
+	errUnknownFrameType       = newError(protocol.ErrCodeMalformed, "unknown frame type")
+	errPriorityTooShort       = newError(protocol.ErrCodeHeaderTooShort, "priority fields too short")
+	errDecodeHeaderFrame      = newError(protocol.ErrCodeHeaderTooShort, "incomplete Header frame")
+	errDecodePushPromiseFrame = newError(protocol.ErrCodeHeaderTooShort, "incomplete PushPromise frame")
 )
 
 // 在 HTTP/2 请求中 必须包含以下伪头部
@@ -103,8 +109,119 @@ const (
 	// flagPriority 用于 HEADERS 帧 表示包含优先级信息
 	// 当设置时，帧负载会包含 31 位 Stream Dependency + 1 位 Exclusive 标志 + 8 位 Weight
 	flagPriority = 0x20
+
+	// flagAck 用于 SETTINGS / PING 帧 表示为对端帧的确认
+	flagAck = 0x1
+)
+
+// settingsMaxConcurrentStreams SETTINGS_MAX_CONCURRENT_STREAMS 参数标识
+//
+// 该参数是单向的 发送方通过它限制接收方可以创建的并发流数量
+const settingsMaxConcurrentStreams = 0x3
+
+// connSettings 链接级别的协商参数 由同一方向的所有 streamDecoder 共享
+type connSettings struct {
+	maxConcurrentStreams uint32 // 0 表示未声明 即不限制
+}
+
+// ctxOpenStreams openStreams 挂载在 protocol.ConnContext 中的 key
+const ctxOpenStreams = "http2.streams"
+
+const (
+	endClient uint8 = 1 << iota
+	endServer
 )
 
+// openStreams 链接内处于打开状态的流 由两个方向的 decoder 共享
+//
+// 客户端的 HEADERS 帧打开流 双方均发送 END_STREAM 或者任意一方发送 RST_STREAM 后关闭
+// 两个方向的数据包可能乱序到达 先于 HEADERS 到达的 END_STREAM 同样需要记录
+type openStreams struct {
+	mut     sync.Mutex
+	max     int
+	streams map[uint32]*openStream
+}
+
+type openStream struct {
+	ends uint8  // 已发送 END_STREAM 的方向
+	path string // 请求的 :path 供响应方向的增量消息统计使用
+}
+
+func newOpenStreams(max int) *openStreams {
+	return &openStreams{
+		max:     max,
+		streams: make(map[uint32]*openStream),
+	}
+}
+
+// connOpenStreams 返回链接上下文中挂载的 openStreams
+func connOpenStreams(ctx *protocol.ConnContext, max int) *openStreams {
+	return ctx.Value(ctxOpenStreams, func() any { return newOpenStreams(max) }).(*openStreams)
+}
+
+// open 打开流 返回当前处于打开状态的流数量（包含自身）
+func (s *openStreams) open(id uint32, path string) int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.track(id).path = path
+	return len(s.streams)
+}
+
+// path 返回流的请求路径 流未打开时为空
+func (s *openStreams) path(id uint32) string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if stream, ok := s.streams[id]; ok {
+		return stream.path
+	}
+	return ""
+}
+
+// end 记录某一方向的 END_STREAM 双方均已结束时关闭流
+func (s *openStreams) end(id uint32, client bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	flag := endServer
+	if client {
+		flag = endClient
+	}
+	stream := s.track(id)
+	stream.ends |= flag
+	if stream.ends == endClient|endServer {
+		delete(s.streams, id)
+	}
+}
+
+// close 关闭流 用于 RST_STREAM 以及被回收的流
+func (s *openStreams) close(id uint32) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	delete(s.streams, id)
+}
+
+// track 记录流 超出上限时淘汰 StreamID 最小的流 避免未正常结束的流导致泄漏
+func (s *openStreams) track(id uint32) *openStream {
+	if stream, ok := s.streams[id]; ok {
+		return stream
+	}
+	if len(s.streams) >= s.max {
+		minV := uint32(math.MaxUint32)
+		for sid := range s.streams {
+			if minV > sid {
+				minV = sid
+			}
+		}
+		delete(s.streams, minV)
+	}
+	stream := &openStream{}
+	s.streams[id] = stream
+	return stream
+}
+
 const (
 	// headerLength HTTP/2 标准定义的头部长度
 	headerLength = 9
@@ -131,19 +248,28 @@ type streamDecoder struct {
 	header        *HeaderFields
 	headerDecoder *HeaderFieldDecoder
 	headerBuf     *bytes.Buffer
+	settings      *connSettings
+	open          *openStreams
+	messages      *messageCounter // 仅 gRPC 场景下开启
+	flushInterval time.Duration   // 增量消息统计的上报间隔 为 0 时仅在流结束时上报
+	lastFlush     time.Time
 
 	drainBytes int
 	end        bool
 	reqTime    time.Time
+	outcome    string
+	concurrent int // 请求发出时链接内处于打开状态的流数量
 }
 
-func newStreamDecoder(id uint32, st socket.TupleRaw, serverPort socket.Port, hfd *HeaderFieldDecoder) *streamDecoder {
+func newStreamDecoder(id uint32, st socket.TupleRaw, serverPort socket.Port, hfd *HeaderFieldDecoder, settings *connSettings, open *openStreams) *streamDecoder {
 	return &streamDecoder{
 		id:            id,
 		st:            st,
 		headerBuf:     bufpool.Acquire(),
 		serverPort:    serverPort,
 		headerDecoder: hfd,
+		settings:      settings,
+		open:          open,
 	}
 }
 
@@ -173,7 +299,7 @@ func (sd *streamDecoder) Decode(cut bool, b []byte, t time.Time) (*role.Object,
 	// 解析 header 获取 payload / flags 等信息
 	if sd.state == stateDecodeHeader {
 		if len(b) < headerLength {
-			return nil, errDecodeHeader
+			return nil, errHeaderTooShort
 		}
 		err := sd.decodeHeader(b[:headerLength])
 		if err != nil {
@@ -208,6 +334,53 @@ func (sd *streamDecoder) reset() {
 	sd.payloadConsumed = 0
 	sd.drainBytes = 0
 	sd.flags = 0
+	sd.outcome = ""
+	sd.concurrent = 0
+	sd.lastFlush = time.Time{}
+}
+
+// progress 未结束的流距离上一次上报超过 flushInterval 时 以单向事件提交期间的增量消息统计
+func (sd *streamDecoder) progress(t time.Time) *role.Object {
+	if sd.messages == nil || sd.flushInterval <= 0 || sd.header == nil || sd.end {
+		return nil
+	}
+	if sd.lastFlush.IsZero() {
+		sd.lastFlush = t
+		return nil
+	}
+	if t.Sub(sd.lastFlush) < sd.flushInterval || sd.messages.count == 0 {
+		return nil
+	}
+	sd.lastFlush = t
+
+	messages, messageSizes := sd.messages.take()
+	p := &Progress{
+		StreamID:     sd.id,
+		Host:         sd.st.SrcIP,
+		Port:         sd.st.SrcPort,
+		ServerHost:   sd.st.DstIP,
+		ServerPort:   sd.st.DstPort,
+		Direction:    DirectionRequest,
+		Messages:     messages,
+		MessageSizes: messageSizes,
+		Time:         t,
+	}
+	if !sd.isClient() {
+		p.Host, p.ServerHost = p.ServerHost, p.Host
+		p.Port, p.ServerPort = p.ServerPort, p.Port
+		p.Direction = DirectionResponse
+	}
+	p.Path = sd.open.path(sd.id)
+	return role.NewOneWayObject(p)
+}
+
+// abandon 回收尚未结束的流 已经解析出 Header 的流强制归档为 OutcomeIncomplete
+func (sd *streamDecoder) abandon() *role.Object {
+	if sd.header == nil {
+		return nil
+	}
+	sd.outcome = OutcomeIncomplete
+	return sd.archive()
 }
 
 // archive 归档请求
@@ -215,6 +388,12 @@ func (sd *streamDecoder) archive() *role.Object {
 	if sd.header == nil {
 		return nil
 	}
+	var messages int
+	var messageSizes []int
+	if sd.messages != nil {
+		messages, messageSizes = sd.messages.take()
+	}
+
 	if sd.isClient() {
 		field, hdr := sd.header.RequestHeader()
 		obj := role.NewRequestObject(&Request{
@@ -226,9 +405,17 @@ func (sd *streamDecoder) archive() *role.Object {
 			Scheme:    field.Scheme,
 			Path:      field.Path,
 			Authority: field.Authority,
+			Client:    protocol.ParseUserAgent(hdr.Get("User-Agent")),
+			Trace:     protocol.ParseTraceContext(hdr),
 			Header:    hdr,
 			Size:      sd.drainBytes,
 			Time:      sd.reqTime,
+
+			ConcurrentStreams: sd.concurrent,
+
+			Messages:     messages,
+			MessageSizes: messageSizes,
+			Outcome:      sd.outcome,
 		})
 		sd.reset()
 		return obj
@@ -236,14 +423,18 @@ func (sd *streamDecoder) archive() *role.Object {
 
 	field, hdr := sd.header.ResponseHeader()
 	obj := role.NewResponseObject(&Response{
-		StreamID: sd.id,
-		Proto:    PROTO,
-		Host:     sd.st.SrcIP,
-		Port:     sd.st.SrcPort,
-		Status:   field.Status,
-		Header:   hdr,
-		Size:     sd.drainBytes,
-		Time:     sd.t0,
+		StreamID:             sd.id,
+		Proto:                PROTO,
+		Host:                 sd.st.SrcIP,
+		Port:                 sd.st.SrcPort,
+		Status:               field.Status,
+		Header:               hdr,
+		Size:                 sd.drainBytes,
+		MaxConcurrentStreams: sd.settings.maxConcurrentStreams,
+		Time:                 sd.t0,
+		Messages:             messages,
+		MessageSizes:         messageSizes,
+		Outcome:              sd.outcome,
 	})
 	sd.reset()
 	return obj
@@ -269,10 +460,13 @@ func (sd *streamDecoder) decodePayload(cut bool, b []byte) (bool, error) {
 	case frameRSTStream:
 		return sd.decodeRstStreamFrame(b)
 
-	case framePriority, frameSettings, framePing, frameGoAway, frameWindowUpdate:
+	case frameSettings:
+		return sd.decodeSettingsFrame(cut, b)
+
+	case framePriority, framePing, frameGoAway, frameWindowUpdate:
 		return sd.decodeTheRestFrames(b)
 	}
-	return false, errInvalidBytes // 切割数据包的时候出问题了 提前终止
+	return false, errUnknownFrameType // 切割数据包的时候出问题了 提前终止
 }
 
 // decodeHeader 解析 Header 固定 9 字节 布局如下
@@ -297,7 +491,7 @@ func (sd *streamDecoder) decodeHeader(b []byte) error {
 	sd.drainBytes += len(b)
 	payloadLen := uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
 	if payloadLen > maxPayloadSize {
-		return errDecodeHeader
+		return errPayloadOverflow
 	}
 
 	sd.state = stateDecodePayload
@@ -386,7 +580,7 @@ func (sd *streamDecoder) decodeHeaderFrame(b []byte) (bool, error) {
 	// Priority Flag 需要剔除接下来的 4 字节
 	if sd.flags&flagPriority != 0 {
 		if len(b) < 5 {
-			return false, errInvalidBytes
+			return false, errPriorityTooShort
 		}
 		b = b[5:]
 	}
@@ -400,6 +594,7 @@ func (sd *streamDecoder) decodeHeaderFrame(b []byte) (bool, error) {
 		isTrailers = newHdr.IsTrailers()
 		if !isTrailers {
 			sd.header = newHdr
+			sd.openStream()
 		}
 		sd.headerBuf.Reset() // 复用 buffer
 		sd.reqTime = sd.t0   // Header 帧确定后即标记为请求开始时间
@@ -408,7 +603,7 @@ func (sd *streamDecoder) decodeHeaderFrame(b []byte) (bool, error) {
 	}
 
 	sd.state = stateDecodeHeader
-	sd.end = sd.flags&flagEndStream != 0
+	sd.markEnd()
 
 	if isTrailers {
 		return true, nil
@@ -434,6 +629,7 @@ func (sd *streamDecoder) decodeContinuationFrame(b []byte) (bool, error) {
 		isTrailers = newHdr.IsTrailers()
 		if !isTrailers {
 			sd.header = newHdr
+			sd.openStream()
 		}
 		sd.headerBuf.Reset()
 		sd.reqTime = sd.t0 // Header 帧确定后即标记为请求开始时间
@@ -442,10 +638,27 @@ func (sd *streamDecoder) decodeContinuationFrame(b []byte) (bool, error) {
 	}
 
 	sd.state = stateDecodeHeader
-	sd.end = sd.flags&flagEndStream != 0
+	sd.markEnd()
 	return false, nil
 }
 
+// openStream 客户端请求头解析完成后打开流 并记录此时链接内的并发流数量
+func (sd *streamDecoder) openStream() {
+	if sd.id == 0 || !sd.isClient() || sd.concurrent > 0 {
+		return
+	}
+	path, _ := sd.header.Get(headerPath)
+	sd.concurrent = sd.open.open(sd.id, path.Value)
+}
+
+// markEnd 根据 END_STREAM 标记当前方向是否结束 同时更新链接内处于打开状态的流
+func (sd *streamDecoder) markEnd() {
+	sd.end = sd.flags&flagEndStream != 0
+	if sd.end && sd.id != 0 {
+		sd.open.end(sd.id, sd.isClient())
+	}
+}
+
 // decodeDataFrame 解析 DataFrame 帧布局如下
 //
 // +---------------+
@@ -477,7 +690,10 @@ func (sd *streamDecoder) decodeDataFrame(cut bool, b []byte) (bool, error) {
 	}
 
 	sd.payloadConsumed += uint32(len(b))
-	sd.end = sd.flags&flagEndStream != 0
+	if sd.messages != nil {
+		sd.messages.feed(b)
+	}
+	sd.markEnd()
 	complete := sd.payloadLen == sd.payloadConsumed
 
 	// 必须要 stream 结束才标记为完成
@@ -503,6 +719,7 @@ func (sd *streamDecoder) decodeDataFrame(cut bool, b []byte) (bool, error) {
 // 解析到此帧时需要关闭 Stream
 func (sd *streamDecoder) decodeRstStreamFrame(b []byte) (bool, error) {
 	sd.end = true
+	sd.open.close(sd.id)
 	return false, nil
 }
 
@@ -574,6 +791,27 @@ func (sd *streamDecoder) decodePushPromise(b []byte) (bool, error) {
 	return false, nil // PushPromise 帧肯定不会是请求的结束标识
 }
 
+// decodeSettingsFrame 解析 SettingsFrame 帧布局如下
+//
+// +-------------------------------+
+// |       Identifier (16)         |
+// +-------------------------------+-------------------------------+
+// |                        Value (32)                             |
+// +---------------------------------------------------------------+
+//
+// Payload 由若干个 6 字节的参数组成 目前仅关注 SETTINGS_MAX_CONCURRENT_STREAMS
+// 被切割的帧直接忽略 SETTINGS 帧一般很小 极少出现跨包的情况
+func (sd *streamDecoder) decodeSettingsFrame(cut bool, b []byte) (bool, error) {
+	if !cut && sd.flags&flagAck == 0 && uint32(len(b)) == sd.payloadLen {
+		for i := 0; i+6 <= len(b); i += 6 {
+			if binary.BigEndian.Uint16(b[i:i+2]) == settingsMaxConcurrentStreams {
+				sd.settings.maxConcurrentStreams = binary.BigEndian.Uint32(b[i+2 : i+6])
+			}
+		}
+	}
+	return sd.decodeTheRestFrames(b)
+}
+
 // decodeTheRestFrames 解析剩余的非重要的数据帧
 func (sd *streamDecoder) decodeTheRestFrames(b []byte) (bool, error) {
 	sd.drainBytes += len(b)
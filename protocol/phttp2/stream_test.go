@@ -339,7 +339,7 @@ func TestStreamDecoderRequest(t *testing.T) {
 	var t0 time.Time
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			sd := newStreamDecoder(1, st, 0, NewHeaderFieldDecoder())
+			sd := newStreamDecoder(1, st, 0, NewHeaderFieldDecoder(), &connSettings{}, newOpenStreams(MaxConcurrentStreams))
 			defer sd.Free()
 
 			var got *role.Object
@@ -550,7 +550,7 @@ func TestStreamDecoderResponse(t *testing.T) {
 	var t0 time.Time
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			sd := newStreamDecoder(2, st, 8080, NewHeaderFieldDecoder(tt.trailerKeys...))
+			sd := newStreamDecoder(2, st, 8080, NewHeaderFieldDecoder(tt.trailerKeys...), &connSettings{}, newOpenStreams(MaxConcurrentStreams))
 			defer sd.Free()
 
 			var got *role.Object
@@ -633,7 +633,7 @@ func TestStreamDecoderFailed(t *testing.T) {
 	var t0 time.Time
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			sd := newStreamDecoder(1, st, 0, NewHeaderFieldDecoder())
+			sd := newStreamDecoder(1, st, 0, NewHeaderFieldDecoder(), &connSettings{}, newOpenStreams(MaxConcurrentStreams))
 			defer sd.Free()
 
 			var objs *role.Object
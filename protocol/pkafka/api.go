@@ -261,7 +261,10 @@ var topicRequestMap = map[apiKey][]topicRequest{
 	},
 }
 
-func matchTopicRequest(ak apiKey, version int16) (topicRequest, bool) {
+// matchTopicRequest 匹配 version 对应的解析规则
+//
+// nearest 为 true 时 规则表未覆盖的版本沿用不高于该版本的最近规则 详见 nearestRule
+func matchTopicRequest(ak apiKey, version int16, nearest bool) (topicRequest, bool) {
 	rs, ok := topicRequestMap[ak]
 	if !ok {
 		return topicRequest{}, false
@@ -271,14 +274,34 @@ func matchTopicRequest(ak apiKey, version int16) (topicRequest, bool) {
 		return rs[0], true
 	}
 
+	versions := make([][]int16, 0, len(rs))
 	for i := 0; i < len(rs); i++ {
-		for _, v := range rs[i].apiVersion {
+		versions = append(versions, rs[i].apiVersion)
+	}
+	if i := nearestRule(versions, version, nearest); i >= 0 {
+		return rs[i], true
+	}
+	return topicRequest{}, false
+}
+
+// nearestRule 返回 version 所在规则的下标 不存在时返回 -1
+//
+// nearest 为 true 时 规则表未覆盖的版本返回不高于该版本的最高版本所在的规则
+// Kafka 新增的 API 版本通常只在末尾追加字段 前部字段的布局保持不变
+func nearestRule(versions [][]int16, version int16, nearest bool) int {
+	idx := -1
+	var highest int16 = -1
+	for i := 0; i < len(versions); i++ {
+		for _, v := range versions[i] {
 			if v == version {
-				return rs[i], true
+				return i
+			}
+			if nearest && v < version && v > highest {
+				idx, highest = i, v
 			}
 		}
 	}
-	return topicRequest{}, false
+	return idx
 }
 
 type op uint8
@@ -421,7 +444,8 @@ var fieldRequestMap = map[apiKey][]fieldRequest{
 	},
 }
 
-func matchFieldRequest(ak apiKey, version int16) (fieldRequest, bool) {
+// matchFieldRequest 匹配 version 对应的解析规则 nearest 语义同 matchTopicRequest
+func matchFieldRequest(ak apiKey, version int16, nearest bool) (fieldRequest, bool) {
 	rs, ok := fieldRequestMap[ak]
 	if !ok {
 		return fieldRequest{}, false
@@ -431,12 +455,12 @@ func matchFieldRequest(ak apiKey, version int16) (fieldRequest, bool) {
 		return rs[0], true
 	}
 
+	versions := make([][]int16, 0, len(rs))
 	for i := 0; i < len(rs); i++ {
-		for _, v := range rs[i].apiVersion {
-			if v == version {
-				return rs[i], true
-			}
-		}
+		versions = append(versions, rs[i].apiVersion)
+	}
+	if i := nearestRule(versions, version, nearest); i >= 0 {
+		return rs[i], true
 	}
 	return fieldRequest{}, false
 }
@@ -20,10 +20,9 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/pkg/errors"
-
 	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/tailbuf"
 	"github.com/packetd/packetd/internal/zerocopy"
 	"github.com/packetd/packetd/protocol"
 	"github.com/packetd/packetd/protocol/role"
@@ -33,16 +32,21 @@ const (
 	PROTO = "Kafka"
 )
 
-func newError(format string, args ...any) error {
-	format = "kafka/decoder: " + format
-	return errors.Errorf(format, args...)
+func newError(code protocol.ErrorCode, format string, args ...any) error {
+	return protocol.NewDecodeError(socket.L7ProtoKafka, code, "kafka/decoder: "+format, args...)
 }
 
 var (
-	errInvalidBytes        = newError("invalid bytes")
-	errDecodeHeader        = newError("decode header failed")
-	errDecodeString        = newError("decode string failed")
-	errDecodeCompactString = newError("decode compactString failed")
+	errResyncFailed         = newError(protocol.ErrCodeResyncFailed, "packet boundary lost")
+	errUnknownApiKey        = newError(protocol.ErrCodeResyncFailed, "unknown api key")
+	errHeaderTooShort       = newError(protocol.ErrCodeHeaderTooShort, "header too short")
+	errFieldTooShort        = newError(protocol.ErrCodeHeaderTooShort, "request fields too short")
+	errUnsupportedVersion   = newError(protocol.ErrCodeUnsupportedVersion, "api version not supported by broker")
+	errClientIDOverflow     = newError(protocol.ErrCodeLengthOverflow, "clientID length overflow")
+	errPayloadOverflow      = newError(protocol.ErrCodeLengthOverflow, "payload length overflow")
+	errDecodeString         = newError(protocol.ErrCodeLengthOverflow, "decode string failed")
+	errDecodeCompactString  = newError(protocol.ErrCodeLengthOverflow, "decode compactString failed")
+	errMalformedApiVersions = newError(protocol.ErrCodeMalformed, "malformed ApiVersions")
 )
 
 // state 记录着 decoder 的处理状态
@@ -56,6 +60,34 @@ const (
 	stateDecodePayload
 )
 
+// phase 记录着链接所处的会话阶段
+//
+// Kafka 链接在进入正常的请求阶段之前 可能会先经历 TLS 握手或者 SASL 认证
+// 不同阶段下数据流的格式不同 需要区别对待以避免产生大量的解析错误
+//
+// SaslHandshake v0 之后的认证阶段两个方向会直接交换未经 Kafka 协议封装的 SASL Token（仅有 4 字节长度前缀）
+// 该阶段需要两个方向协同判断 记录在 session 中
+type phase uint8
+
+const (
+	// phaseApplication 初始值 处于正常的 Kafka 请求阶段
+	phaseApplication phase = iota
+
+	// phaseOpaque 数据流已经被 TLS 加密 后续所有数据均无法解析
+	phaseOpaque
+)
+
+const (
+	// OptLegacyVersionLag API 版本落后于 Broker 支持的最高版本多少时认为是旧版本客户端
+	OptLegacyVersionLag = "legacyVersionLag"
+)
+
+// defaultLegacyVersionLag 默认的旧版本判定阈值
+const defaultLegacyVersionLag = 4
+
+// maxApiVersionsBodySize ApiVersions 响应 Body 的最大缓存长度
+const maxApiVersionsBodySize = 4096
+
 const (
 	// reqMinHeaderLength header 最小长度
 	reqMinHeaderLength = 14
@@ -83,22 +115,60 @@ type decoder struct {
 	errCode    errorCode
 	topicDone  bool
 	packet     *Packet
-
-	tail    []byte // 尾部数据拼接 仅允许拼接一次 避免上一轮切割了部分数据
+	phase      phase
+	skipToken  bool // 当前帧是否为 SASL Token
+	filtered   bool // 当前帧的 topic / group 被过滤 仅排空不再解析
+	produce    *produceParser
+	fetch      *fetchParser
+
+	ctx         *protocol.ConnContext
+	sess        *session
+	release     func()
+	legacyLag   int16
+	filter      *filter
+	detail      bool   // 开启 OptDecodeRecordBatches
+	apiVersions int16  // ApiVersions 响应对应的请求版本 -1 表示当前响应不是 ApiVersions
+	versionsBuf []byte // ApiVersions 响应 Body 缓存
+
+	tail    tailbuf.Buffer // 尾部数据拼接 仅允许拼接一次 避免上一轮切割了部分数据
 	partial uint8
 }
 
-func NewDecoder(st socket.Tuple, serverPort socket.Port, _ common.Options) protocol.Decoder {
+// NewDecoder 创建 Kafka 解码器
+//
+// 独立创建的 decoder 无法与另一个方向共享 ApiVersions 协商结果 链接池内应使用 newDecoder
+func NewDecoder(st socket.Tuple, serverPort socket.Port, opts common.Options) protocol.Decoder {
+	return newDecoder(st, serverPort, opts, protocol.NewConnContext(0), nil)
+}
+
+func newDecoder(st socket.Tuple, serverPort socket.Port, opts common.Options, ctx *protocol.ConnContext, release func()) *decoder {
+	legacyLag, err := opts.GetInt(OptLegacyVersionLag)
+	if err != nil || legacyLag <= 0 {
+		legacyLag = defaultLegacyVersionLag
+	}
+	detail, _ := opts.GetBool(OptDecodeRecordBatches)
 	return &decoder{
-		st:         st.ToRaw(),
-		serverPort: serverPort,
-		ak:         math.MaxUint16,
-		errCode:    math.MaxInt16,
+		st:          st.ToRaw(),
+		serverPort:  serverPort,
+		ak:          math.MaxUint16,
+		errCode:     math.MaxInt16,
+		ctx:         ctx,
+		sess:        connSession(ctx),
+		release:     release,
+		legacyLag:   int16(legacyLag),
+		filter:      newFilter(opts),
+		detail:      detail,
+		apiVersions: -1,
 	}
 }
 
 func (d *decoder) Free() {
-	d.tail = nil
+	d.tail.Free()
+	d.versionsBuf = nil
+	if d.release != nil {
+		d.release()
+		d.release = nil
+	}
 }
 
 // Decode 持续从 zerocopy.Reader 解析 Kafka 协议数据流，构建并返回 RoundTrip 对象
@@ -137,21 +207,28 @@ func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error)
 		return nil, nil
 	}
 
+	// 加密流量无需再做任何解析
+	if d.phase == phaseOpaque {
+		return nil, nil
+	}
+
 	var complete bool
 
 	// 持续解析读取到的所有字节 直到 EOF
 	for len(b) > 0 {
 		// 如果已经出现过两次拼接 返回解析错误
 		if d.partial > 1 {
-			return nil, errInvalidBytes
+			return nil, errResyncFailed
 		}
 
 		if d.partial == 1 {
-			b = append(d.tail, b...)
+			b = d.tail.Join(b)
 		}
 		b, complete, err = d.decode(b)
 		if err != nil {
 			if d.partial == 1 {
+				// b 指向 Join 返回的内存 下一轮 Join 会复用该数组 需先拷贝
+				b = bytes.Clone(b)
 				continue
 			}
 			d.reset() // 错误即重置
@@ -179,12 +256,32 @@ func (d *decoder) reset() {
 	d.ak = math.MaxUint16
 	d.errCode = math.MaxInt16
 	d.packet = nil
+	d.skipToken = false
+	d.filtered = false
+	d.produce = nil
+	d.fetch = nil
+	d.apiVersions = -1
+	d.versionsBuf = nil
 }
 
 // archive 归档请求
 func (d *decoder) archive() []*role.Object {
+	if d.skipToken {
+		return d.archiveToken()
+	}
 	if d.isClient() {
-		obj := role.NewRequestObject(&Request{
+		// SaslHandshake v0 之后紧跟着的是原始 SASL Token 交换
+		saslToken := d.ak == apiSaslHandshake && d.reqHdr.apiVersion == 0
+		if d.ak == apiApiVersions {
+			d.sess.expectApiVersions(d.reqHdr.correlationID, d.reqHdr.apiVersion)
+		}
+		if d.ak == apiFetch && d.detail {
+			d.sess.expectFetch(d.reqHdr.correlationID, d.reqHdr.apiVersion)
+		}
+		if d.produce != nil {
+			d.packet.Produce = d.produce.Stats()
+		}
+		req := &Request{
 			CorrelationID: d.reqHdr.correlationID,
 			Size:          d.drainBytes,
 			Proto:         PROTO,
@@ -192,12 +289,24 @@ func (d *decoder) archive() []*role.Object {
 			Host:          d.st.SrcIP,
 			Port:          d.st.SrcPort,
 			Packet:        d.packet,
-		})
+			Client:        d.client(),
+		}
+
+		// acks=0 的 Produce 请求 Broker 不会返回任何响应
+		obj := role.NewRequestObject(req)
+		if stats := d.packet.Produce; stats != nil && stats.Acks == 0 {
+			req.ServerHost = d.st.DstIP
+			req.ServerPort = d.st.DstPort
+			obj = role.NewOneWayObject(req)
+		}
 		d.reset()
+		if saslToken {
+			d.sess.startSaslToken(req.Packet.Mechanism)
+		}
 		return []*role.Object{obj}
 	}
 
-	obj := role.NewResponseObject(&Response{
+	rsp := &Response{
 		CorrelationID: d.rspHdr.correlationID,
 		Size:          d.drainBytes,
 		Time:          d.t0,
@@ -205,7 +314,11 @@ func (d *decoder) archive() []*role.Object {
 		Host:          d.st.SrcIP,
 		Port:          d.st.SrcPort,
 		ErrorCode:     errCodes[d.errCode],
-	})
+	}
+	if d.fetch != nil {
+		rsp.Fetch = d.fetch.Stats()
+	}
+	obj := role.NewResponseObject(rsp)
 	d.reset()
 	return []*role.Object{obj}
 }
@@ -214,11 +327,24 @@ func (d *decoder) archive() []*role.Object {
 func (d *decoder) decode(b []byte) ([]byte, bool, error) {
 	// 首先处理 Header 部分 需要区分 client/server
 	if d.state == stateDecodeHeader {
+		// TLS 加密的 listener 无法解析 标记为 opaque 后不再产生任何解析错误
+		if isTLSRecord(b) {
+			d.phase = phaseOpaque
+			return nil, false, nil
+		}
+
+		if d.isClient() && d.sess.inSaslToken() && !looksLikeRequest(b) {
+			return d.skipSaslToken(b)
+		}
+		if !d.isClient() && d.sess.expectServerToken() {
+			return d.skipSaslToken(b)
+		}
+
 		if d.isClient() {
 			if len(b) < reqMinHeaderLength {
 				d.partial++
-				d.tail = bytes.Clone(b) // 留着下轮拼接
-				return nil, false, errDecodeHeader
+				d.tail.Set(b) // 留着下轮拼接
+				return nil, false, errHeaderTooShort
 			}
 
 			// 解析 client Request
@@ -226,6 +352,7 @@ func (d *decoder) decode(b []byte) ([]byte, bool, error) {
 			if err != nil {
 				return nil, false, err
 			}
+			d.sess.endSaslToken() // 已经回到正常的请求阶段
 
 			// 追加 clientID 字符串长度
 			d.drainBytes += reqMinHeaderLength + len(reqHdr.clientID)
@@ -238,8 +365,8 @@ func (d *decoder) decode(b []byte) ([]byte, bool, error) {
 		} else {
 			if len(b) < rspMinHeaderLength {
 				d.partial++
-				d.tail = bytes.Clone(b)
-				return nil, false, errDecodeHeader
+				d.tail.Set(b)
+				return nil, false, errHeaderTooShort
 			}
 
 			// 解析 server Request
@@ -252,6 +379,14 @@ func (d *decoder) decode(b []byte) ([]byte, bool, error) {
 			d.payloadConsumed += 4
 			d.payloadLen = uint32(rspHdr.length)
 
+			if version, ok := d.sess.takeApiVersions(rspHdr.correlationID); ok {
+				d.apiVersions = version
+			}
+			d.filtered = d.sess.takeFiltered(rspHdr.correlationID)
+			if version, ok := d.sess.takeFetch(rspHdr.correlationID); ok && !d.filtered {
+				d.fetch = newFetchParser(version)
+			}
+
 			d.rspHdr = rspHdr
 			d.state = stateDecodePayload
 			b = b[rspMinHeaderLength:]
@@ -269,6 +404,134 @@ func (d *decoder) isClient() bool {
 	return uint16(d.serverPort) == d.st.DstPort
 }
 
+// skipSaslToken 排空未经 Kafka 协议封装的 SASL Token 帧
+//
+// Token 帧仅有 4 字节的长度前缀 复用 payload 的排空逻辑即可 排空后由 archiveToken 归档
+func (d *decoder) skipSaslToken(b []byte) ([]byte, bool, error) {
+	if len(b) < 4 {
+		return nil, false, nil
+	}
+
+	d.skipToken = true
+	d.reqTime = d.t0
+	d.drainBytes = 4
+	d.payloadLen = binary.BigEndian.Uint32(b[:4])
+	if d.payloadLen == 0 {
+		return b[4:], true, nil // 如 SASL/PLAIN 认证成功时 Broker 回复的空 Token
+	}
+	d.state = stateDecodePayload
+	return d.decodePayload(b[4:])
+}
+
+// archiveToken 归档 SASL Token 帧
+//
+// 每一轮 Token 交换作为一次 SaslAuthenticate 请求 与 v1 认证一样统计认证耗时以及失败
+func (d *decoder) archiveToken() []*role.Object {
+	defer d.reset()
+
+	if d.isClient() {
+		seq, mechanism := d.sess.clientToken()
+		return []*role.Object{role.NewRequestObject(&Request{
+			CorrelationID: seq,
+			Size:          d.drainBytes,
+			Proto:         PROTO,
+			Time:          d.reqTime,
+			Host:          d.st.SrcIP,
+			Port:          d.st.SrcPort,
+			Packet: &Packet{
+				API:           apiKeys[apiSaslAuthenticate],
+				CorrelationID: seq,
+				Mechanism:     mechanism,
+			},
+		})}
+	}
+
+	seq, ok := d.sess.serverToken()
+	if !ok {
+		return nil
+	}
+	return []*role.Object{role.NewResponseObject(&Response{
+		CorrelationID: seq,
+		Size:          d.drainBytes,
+		Time:          d.t0,
+		Proto:         PROTO,
+		Host:          d.st.SrcIP,
+		Port:          d.st.SrcPort,
+		ErrorCode:     errCodes[codeNoError],
+	})}
+}
+
+// ServerClose 实现 protocol.ServerCloser 接口
+//
+// SASL v0 认证失败时 Broker 不会回复 Token 而是直接断开链接 此时以认证失败归档等待回复的 Token
+func (d *decoder) ServerClose(t time.Time) []*role.Object {
+	if d.isClient() || !d.sess.inSaslToken() {
+		return nil
+	}
+	seq, ok := d.sess.serverToken()
+	if !ok {
+		return nil
+	}
+	return []*role.Object{role.NewResponseObject(&Response{
+		CorrelationID: seq,
+		Time:          t,
+		Proto:         PROTO,
+		Host:          d.st.SrcIP,
+		Port:          d.st.SrcPort,
+		ErrorCode:     errCodes[codeSASLAuthenticationFailed],
+	})}
+}
+
+// isTLSRecord 判断数据是否以 TLS Record 开头
+//
+// Kafka 帧以 4 字节长度开头 若首字节为 0x14~0x17 则意味着帧长度超过 300MB
+// 远大于 broker 默认的 socket.request.max.bytes（100MB）因此可以认为是 TLS 加密流量
+func isTLSRecord(b []byte) bool {
+	if len(b) < 3 {
+		return false
+	}
+
+	switch b[0] {
+	case 0x14, 0x15, 0x16, 0x17: // ChangeCipherSpec / Alert / Handshake / ApplicationData
+	default:
+		return false
+	}
+	return b[1] == 0x03 && b[2] <= 0x04
+}
+
+// looksLikeRequest 判断数据是否可能为合法的 Kafka Request 帧
+func looksLikeRequest(b []byte) bool {
+	if len(b) < 6 {
+		return false
+	}
+	_, ok := apiKeys[apiKey(binary.BigEndian.Uint16(b[4:6]))]
+	return ok
+}
+
+// decodeApiVersions 缓存并解析 ApiVersions 响应 记录 Broker 支持的版本区间
+//
+// 响应可能跨越多个数据包 读取完毕后才进行解析 超出缓存长度则放弃
+func (d *decoder) decodeApiVersions(b []byte) {
+	if len(d.versionsBuf)+len(b) > maxApiVersionsBodySize {
+		d.apiVersions = -1
+		d.versionsBuf = nil
+		return
+	}
+	d.versionsBuf = append(d.versionsBuf, b...)
+	if !d.readall {
+		return
+	}
+
+	// 首个数据包中已经记录过 errCode 协商失败时 Broker 仅会返回其支持的 ApiVersions 版本
+	if d.errCode == 0 {
+		if versions, err := decodeApiVersionsResponse(d.versionsBuf, d.apiVersions); err == nil {
+			d.sess.setVersions(versions)
+		}
+	}
+	d.apiVersions = -1
+	d.versionsBuf = nil
+}
+
 // decodePayload 解析协议 Payload 不定长度
 //
 // Payload 可能包含【多种】类型的数据包 需要按 Length-Payload 的顺序交替解析
@@ -300,7 +563,7 @@ func (d *decoder) decodePayload(b []byte) ([]byte, bool, error) {
 	// 则需要消费剩下的内容并将 tail 返回
 	if n > d.payloadLen {
 		if d.payloadLen < d.payloadConsumed {
-			return nil, false, errInvalidBytes
+			return nil, false, errPayloadOverflow
 		}
 		consumed := d.payloadLen - d.payloadConsumed
 		d.payloadConsumed += consumed
@@ -319,22 +582,62 @@ func (d *decoder) decodePayload(b []byte) ([]byte, bool, error) {
 
 // decodePacket 根据 API/Version 进行真正的协议解析
 func (d *decoder) decodePacket(b []byte) (bool, error) {
+	// SASL Token 帧排空后由 archiveToken 归档
+	if d.skipToken {
+		return d.readall, nil
+	}
+
+	// 被过滤的帧仅排空 不生成任何请求
+	if d.filtered {
+		if d.readall {
+			d.reset()
+		}
+		return false, nil
+	}
+
 	if !d.isClient() {
 		_, ok := apiKeys[d.ak]
 		if ok && d.packet == nil {
 			d.updatePacket("", "") // 服务端请求仅需记录长度
 		}
+		if d.apiVersions >= 0 {
+			d.decodeApiVersions(b)
+		}
+		if d.fetch != nil {
+			d.fetch.feed(b)
+		}
 		return d.readall, nil
 	}
 
 	// 不允许非法 apikey
 	if _, ok := apiKeys[d.ak]; !ok {
-		return false, newError("api (%d) not found", d.ak)
+		return false, newError(protocol.ErrCodeResyncFailed, "api (%d) not found", d.ak)
+	}
+
+	// Produce 请求体需要跨数据块持续解析
+	if d.ak == apiProduce {
+		if d.produce == nil {
+			d.produce = newProduceParser(d.reqHdr.apiVersion, d.detail)
+		}
+		d.produce.feed(b)
 	}
 
 	var err error
 	var decoded bool // 记录是否已经处理过
-	if _, ok := topicRequestMap[d.ak]; ok {
+
+	// ApiVersions v3+ 的 Body 为客户端软件名称以及版本 不存在 topic 字段
+	// 解析失败也不影响请求本身
+	if d.ak == apiApiVersions && d.reqHdr.apiVersion >= 3 {
+		if d.packet == nil {
+			if client, err := decodeApiVersionsRequest(b); err == nil && client != nil {
+				d.sess.setClient(client)
+			}
+			d.updatePacket("", "")
+		}
+		decoded = true
+	}
+
+	if _, ok := topicRequestMap[d.ak]; ok && !decoded {
 		err = d.decodeTopicRequests(b)
 		decoded = true
 	}
@@ -343,6 +646,15 @@ func (d *decoder) decodePacket(b []byte) (bool, error) {
 		decoded = true
 	}
 
+	// SaslHandshake 仅携带 mechanism 字段 解析失败也不影响请求本身
+	if d.ak == apiSaslHandshake && d.packet == nil {
+		d.updatePacket("", "")
+		if mechanism, _, err := decodeStringType(b, false); err == nil {
+			d.packet.Mechanism = mechanism
+		}
+		decoded = true
+	}
+
 	// 当 b 还没被处理过时 可能是无 group/topic 的请求
 	// 那直接生成 packet 即可
 	if !decoded && d.packet == nil {
@@ -356,6 +668,18 @@ func (d *decoder) decodePacket(b []byte) (bool, error) {
 		return false, err
 	}
 
+	// topic / group 一经解析即可判定 剩余的 payload 无需再解析
+	// 对应的响应也一并丢弃 避免产生孤立的 Response
+	if !d.filter.accept(d.packet) {
+		filteredRequestsTotal.WithLabelValues(d.packet.API).Inc()
+		d.sess.expectFiltered(d.reqHdr.correlationID)
+		d.filtered = true
+		if d.readall {
+			d.reset()
+		}
+		return false, nil
+	}
+
 	// 当且仅当已经读取完请求所有数据并构建成 packet 才算解析完成
 	if d.readall && d.packet != nil {
 		return true, nil
@@ -419,26 +743,30 @@ type requestHeader struct {
 // Payload 需根据 API Key / API Version 共同决定如何解析 详见 api.go
 func (d *decoder) decodeRequestHeader(b []byte) (*requestHeader, error) {
 	if len(b) < reqMinHeaderLength {
-		return nil, errDecodeHeader
+		return nil, errHeaderTooShort
 	}
 
 	length := int32(binary.BigEndian.Uint32(b[:4]))
 	ak := apiKey(binary.BigEndian.Uint16(b[4:6]))
 	if _, ok := apiKeys[ak]; !ok {
-		return nil, errDecodeHeader
+		return nil, errUnknownApiKey
 	}
 	d.ak = ak // apikey 在单次请求中需要持续记录
 
 	apiVersion := int16(binary.BigEndian.Uint16(b[6:8]))
+	// 已经协商过版本的链接 不允许出现 Broker 不支持的版本（ApiVersions 本身除外）
+	if ak != apiApiVersions && !d.sess.supported(ak, apiVersion) {
+		return nil, errUnsupportedVersion
+	}
 	correlation := int32(binary.BigEndian.Uint32(b[8:12]))
 
 	clientIDLen := binary.BigEndian.Uint16(b[12:14])
 	if int(clientIDLen+14) > len(b) {
-		return nil, errDecodeHeader
+		return nil, errClientIDOverflow
 	}
 	// 避免数组溢出
 	if 14+int(clientIDLen) > math.MaxUint16 {
-		return nil, errDecodeHeader
+		return nil, errClientIDOverflow
 	}
 
 	clientID := string(b[14 : 14+clientIDLen])
@@ -490,7 +818,7 @@ type responseHeader struct {
 // Payload 需根据 API Key / API Version 共同决定如何解析
 func (d *decoder) decodeResponseHeader(b []byte) (*responseHeader, error) {
 	if len(b) < rspMinHeaderLength {
-		return nil, errDecodeHeader
+		return nil, errHeaderTooShort
 	}
 
 	length := int32(binary.BigEndian.Uint32(b[:4]))
@@ -508,6 +836,16 @@ type Packet struct {
 	ClientID      string
 	GroupID       string
 	Topic         string
+	LastTopic     string `json:",omitempty"` // 请求本身不携带 topic 时（如 Heartbeat/OffsetCommit）链接最近一次请求的 topic
+	MaxVersion    int16  `json:",omitempty"` // ApiVersions 协商得到的 Broker 支持的最高版本 未协商时为空
+	Mechanism     string
+	Legacy        bool          // API 版本远低于 Broker 支持的最高版本
+	Produce       *ProduceStats `json:",omitempty"`
+}
+
+// IsAuthentication 返回是否为 SASL 认证阶段的请求
+func (p *Packet) IsAuthentication() bool {
+	return p.API == apiKeys[apiSaslHandshake] || p.API == apiKeys[apiSaslAuthenticate]
 }
 
 func (d *decoder) updatePacket(groupID, topic string) {
@@ -518,7 +856,46 @@ func (d *decoder) updatePacket(groupID, topic string) {
 		ClientID:      d.reqHdr.clientID,
 		GroupID:       groupID,
 		Topic:         topic,
+		Legacy:        d.isLegacy(),
+	}
+	if topic != "" {
+		d.ctx.Set(protocol.CtxTopic, topic)
+	} else {
+		d.packet.LastTopic = d.ctx.Get(protocol.CtxTopic)
+	}
+	if vr, ok := d.sess.versionRange(d.ak); ok {
+		d.packet.MaxVersion = vr.max
+	}
+}
+
+// client 返回客户端指纹
+//
+// 优先使用 ApiVersions 协商时声明的客户端软件信息 否则退化为 clientID
+func (d *decoder) client() *protocol.Client {
+	if client := d.sess.getClient(); client != nil {
+		return client
+	}
+	return protocol.NewClient(d.reqHdr.clientID, "")
+}
+
+// negotiated 判断 ApiVersions 协商结果是否确认 Broker 支持当前请求的版本
+//
+// 确认支持时 规则表未覆盖的新版本同样可以按照最近的规则解析 而不是当作未知版本仅统计大小
+func (d *decoder) negotiated() bool {
+	vr, ok := d.sess.versionRange(d.ak)
+	if !ok {
+		return false
+	}
+	return d.reqHdr.apiVersion >= vr.min && d.reqHdr.apiVersion <= vr.max
+}
+
+// isLegacy 判断当前请求是否使用了过旧的 API 版本 未协商过版本时无法判断
+func (d *decoder) isLegacy() bool {
+	vr, ok := d.sess.versionRange(d.ak)
+	if !ok {
+		return false
 	}
+	return vr.max-d.reqHdr.apiVersion >= d.legacyLag
 }
 
 // decodeFieldRequest 提供了一种按配置解析不同 API 不同版本字段的能力
@@ -530,9 +907,10 @@ func (d *decoder) decodeFieldRequest(b []byte) error {
 	}
 
 	// 提取解析规则
-	opField, ok := matchFieldRequest(d.ak, d.reqHdr.apiVersion)
+	opField, ok := matchFieldRequest(d.ak, d.reqHdr.apiVersion, d.negotiated())
 	if !ok {
-		return newError("field request/version=(%d/%d) not found", d.ak, d.reqHdr.apiVersion)
+		d.unsupportedVersion()
+		return nil
 	}
 
 	var skip int
@@ -542,28 +920,28 @@ func (d *decoder) decodeFieldRequest(b []byte) error {
 		switch opField.ops[i] {
 		case opInt16:
 			if len(b) < skip+2 {
-				return errInvalidBytes
+				return errFieldTooShort
 			}
 			skip += 2
 			round++
 
 		case opInt32:
 			if len(b) < skip+4 {
-				return errInvalidBytes
+				return errFieldTooShort
 			}
 			skip += 4
 			round++
 
 		case opInt64:
 			if len(b) < skip+8 {
-				return errInvalidBytes
+				return errFieldTooShort
 			}
 			skip += 8
 			round++
 
 		case opUvarint:
 			if len(b) < skip+1 {
-				return errInvalidBytes
+				return errFieldTooShort
 			}
 			_, n := binary.Uvarint(b)
 			skip += n
@@ -578,13 +956,13 @@ func (d *decoder) decodeFieldRequest(b []byte) error {
 			if opField.compact {
 				s, offset, err = decodeCompactStringType(b[skip:])
 				if err != nil {
-					return errInvalidBytes
+					return err
 				}
 
 			} else {
 				s, offset, err = decodeStringType(b[skip:], true)
 				if err != nil {
-					return errInvalidBytes
+					return err
 				}
 			}
 
@@ -600,7 +978,7 @@ func (d *decoder) decodeFieldRequest(b []byte) error {
 
 	// 解析轮次要求等于 ops 长度
 	if round != len(opField.ops) || skip > len(b) {
-		return errInvalidBytes
+		return errFieldTooShort
 	}
 
 	if !opField.withTopic {
@@ -639,20 +1017,31 @@ func (d *decoder) decodeFieldRequest(b []byte) error {
 	return nil
 }
 
+// unsupportedVersion 请求的 API 版本没有对应的解析规则
+//
+// 帧长度与 API 版本无关 因此仍然可以完整排空请求 仅生成不包含 group/topic 的 packet
+// 请求依旧能与响应配对 保留大小以及耗时
+func (d *decoder) unsupportedVersion() {
+	protocol.RecordUnsupportedVersion(socket.L7ProtoKafka, apiKeys[d.ak]+"/v"+strconv.Itoa(int(d.reqHdr.apiVersion)))
+	d.updatePacket("", "")
+	d.topicDone = true
+}
+
 // decodeTopicRequests 仅解析 Request 的 Topic 字段
 func (d *decoder) decodeTopicRequests(b []byte) error {
 	if d.topicDone {
 		return nil
 	}
 
-	tr, ok := matchTopicRequest(d.ak, d.reqHdr.apiVersion)
+	tr, ok := matchTopicRequest(d.ak, d.reqHdr.apiVersion, d.negotiated())
 	if !ok {
-		return newError("topic request/version=(%d/%d) not found", d.ak, d.reqHdr.apiVersion)
+		d.unsupportedVersion()
+		return nil
 	}
 
 	skip := tr.skip
 	if len(b) < skip+4 {
-		return newError("decode %d request failed", d.ak)
+		return newError(protocol.ErrCodeHeaderTooShort, "decode %d request failed", d.ak)
 	}
 
 	if tr.topicType == topicTypeUUID {
@@ -688,7 +1077,7 @@ func decodeStringType(b []byte, nullable bool) (string, int, error) {
 		return "", 0, errDecodeString
 	}
 
-	n := int(binary.BigEndian.Uint16(b[:2]))
+	n := int(int16(binary.BigEndian.Uint16(b[:2])))
 	if n == -1 {
 		if !nullable {
 			return "", 0, errDecodeString
@@ -22,6 +22,7 @@ import (
 	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol"
 	"github.com/packetd/packetd/protocol/role"
 )
 
@@ -366,6 +367,29 @@ func TestDecodeRequest(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "UnsupportedVersion",
+			input: [][]byte{
+				{
+					0x00, 0x00, 0x00, 0x18,
+					0x00, 0x02,
+					0x00, 0x00,
+					0x00, 0x00,
+					0x00, 0x01, 0x00, 0x06, 'c', 'l', 'i', 'e', 'n', 't',
+					0xFF, 0xFF, 0xFF, 0xFF,
+					0x00, 0x00, 0x00, 0x01,
+				},
+			},
+			request: &Request{
+				Size: 28,
+				Packet: &Packet{
+					API:           "ListOffsets",
+					APIVersion:    0,
+					CorrelationID: 1,
+					ClientID:      "client",
+				},
+			},
+		},
 	}
 
 	var st socket.Tuple
@@ -728,6 +752,310 @@ func TestDecodeFailed(t *testing.T) {
 	}
 }
 
+func TestDecodeSaslHandshake(t *testing.T) {
+	metadata := []byte{
+		0x00, 0x00, 0x00, 0x1B,
+		0x00, 0x03,
+		0x00, 0x00,
+		0x00, 0x00,
+		0x00, 0x02, 0x00, 0x06, 'c', 'l', 'i', 'e', 'n', 't',
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x05, 't', 'o', 'p', 'i', 'c',
+	}
+
+	handshake := []byte{
+		0x00, 0x00, 0x00, 0x17,
+		0x00, 0x11,
+		0x00, 0x00,
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x06, 'c', 'l', 'i', 'e', 'n', 't',
+		0x00, 0x05, 'P', 'L', 'A', 'I', 'N',
+	}
+	token := []byte{
+		0x00, 0x00, 0x00, 0x0C,
+		0x00, 'u', 's', 'e', 'r', 0x00, 's', 'e', 'c', 'r', 'e', 't',
+	}
+
+	t.Run("HandshakeV0WithToken", func(t *testing.T) {
+		var st socket.Tuple
+		ctx := protocol.NewConnContext(0)
+		d := newDecoder(st, 0, common.NewOptions(), ctx, nil)
+		server := newDecoder(st, 9092, common.NewOptions(), ctx, nil)
+
+		objs, err := d.Decode(zerocopy.NewBuffer(handshake), time.Time{})
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+
+		packet := objs[0].Obj.(*Request).Packet
+		assert.Equal(t, "SaslHandshake", packet.API)
+		assert.Equal(t, "PLAIN", packet.Mechanism)
+		assert.True(t, packet.IsAuthentication())
+
+		// SaslHandshake 响应仍按照 Kafka 响应解析
+		objs, err = server.Decode(zerocopy.NewBuffer([]byte{
+			0x00, 0x00, 0x00, 0x0E,
+			0x00, 0x00, 0x00, 0x01,
+			0x00, 0x00,
+			0x00, 0x00, 0x00, 0x01, 0x00, 0x02, 'P', 'L',
+		}), time.Time{})
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+		assert.Equal(t, int32(1), objs[0].Obj.(*Response).CorrelationID)
+
+		// 原始 SASL Token 帧作为一次 SaslAuthenticate 归档 Broker 回复空 Token 表示认证成功
+		objs, err = d.Decode(zerocopy.NewBuffer(token), time.Time{})
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+		req := objs[0].Obj.(*Request)
+		assert.Equal(t, "SaslAuthenticate", req.Packet.API)
+		assert.Equal(t, "PLAIN", req.Packet.Mechanism)
+		assert.Equal(t, 16, req.Size)
+
+		objs, err = server.Decode(zerocopy.NewBuffer([]byte{0x00, 0x00, 0x00, 0x00}), time.Time{})
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+		rsp := objs[0].Obj.(*Response)
+		assert.Equal(t, req.CorrelationID, rsp.CorrelationID)
+		assert.Equal(t, "NoError", rsp.ErrorCode)
+
+		objs, err = d.Decode(zerocopy.NewBuffer(metadata), time.Time{})
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+		assert.Equal(t, int32(2), objs[0].Obj.(*Request).CorrelationID)
+	})
+
+	t.Run("HandshakeV0Failed", func(t *testing.T) {
+		var st socket.Tuple
+		ctx := protocol.NewConnContext(0)
+		d := newDecoder(st, 0, common.NewOptions(), ctx, nil)
+		server := newDecoder(st, 9092, common.NewOptions(), ctx, nil)
+
+		objs, err := d.Decode(zerocopy.NewBuffer(handshake), time.Time{})
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+
+		objs, err = d.Decode(zerocopy.NewBuffer(token), time.Time{})
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+		seq := objs[0].Obj.(*Request).CorrelationID
+
+		// Broker 不回复 Token 直接断开链接
+		objs = server.ServerClose(time.Time{})
+		assert.Len(t, objs, 1)
+		rsp := objs[0].Obj.(*Response)
+		assert.Equal(t, seq, rsp.CorrelationID)
+		assert.Equal(t, "SASLAuthenticationFailed", rsp.ErrorCode)
+		assert.Nil(t, server.ServerClose(time.Time{}))
+	})
+
+	t.Run("TLSListener", func(t *testing.T) {
+		var st socket.Tuple
+		d := NewDecoder(st, 0, common.NewOptions())
+
+		objs, err := d.Decode(zerocopy.NewBuffer([]byte{
+			0x16, 0x03, 0x01, 0x00, 0x05,
+			0x01, 0x00, 0x00, 0x01, 0x00,
+		}), time.Time{})
+		assert.NoError(t, err)
+		assert.Nil(t, objs)
+
+		// 加密之后的任何数据均不再解析
+		objs, err = d.Decode(zerocopy.NewBuffer(metadata), time.Time{})
+		assert.NoError(t, err)
+		assert.Nil(t, objs)
+	})
+}
+
+func TestDecodeApiVersions(t *testing.T) {
+	var st socket.Tuple
+	ctx := protocol.NewConnContext(0)
+	client := newDecoder(st, 0, common.NewOptions(), ctx, nil)
+	server := newDecoder(st, 9092, common.NewOptions(), ctx, nil)
+
+	objs, err := client.Decode(zerocopy.NewBuffer([]byte{
+		0x00, 0x00, 0x00, 0x16,
+		0x00, 0x12,
+		0x00, 0x02,
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x06, 'c', 'l', 'i', 'e', 'n', 't',
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.False(t, objs[0].Obj.(*Request).Packet.Legacy)
+
+	objs, err = server.Decode(zerocopy.NewBuffer([]byte{
+		0x00, 0x00, 0x00, 0x1A,
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00,
+		0x00, 0x00, 0x00, 0x02,
+		0x00, 0x03, 0x00, 0x00, 0x00, 0x0C,
+		0x00, 0x00, 0x00, 0x03, 0x00, 0x09,
+		0x00, 0x00, 0x00, 0x00,
+	}), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+
+	vr, ok := connSession(ctx).versionRange(apiMetadata)
+	assert.True(t, ok)
+	assert.Equal(t, versionRange{min: 0, max: 12}, vr)
+
+	// Metadata v0 远低于 Broker 支持的 v12
+	objs, err = client.Decode(zerocopy.NewBuffer([]byte{
+		0x00, 0x00, 0x00, 0x1B,
+		0x00, 0x03,
+		0x00, 0x00,
+		0x00, 0x00, 0x00, 0x02,
+		0x00, 0x06, 'c', 'l', 'i', 'e', 'n', 't',
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x05, 't', 'o', 'p', 'i', 'c',
+	}), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	packet := objs[0].Obj.(*Request).Packet
+	assert.True(t, packet.Legacy)
+	assert.Equal(t, int16(12), packet.MaxVersion)
+	assert.Equal(t, "topic", ctx.Get(protocol.CtxTopic))
+
+	// ApiVersions 请求不携带 topic 记录链接最近一次请求的 topic
+	objs, err = client.Decode(zerocopy.NewBuffer([]byte{
+		0x00, 0x00, 0x00, 0x16,
+		0x00, 0x12,
+		0x00, 0x02,
+		0x00, 0x00, 0x00, 0x04,
+		0x00, 0x06, 'c', 'l', 'i', 'e', 'n', 't',
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.Equal(t, "topic", objs[0].Obj.(*Request).Packet.LastTopic)
+
+	// Produce v2 低于 Broker 支持的最低版本
+	objs, err = client.Decode(zerocopy.NewBuffer([]byte{
+		0x00, 0x00, 0x00, 0x1B,
+		0x00, 0x00,
+		0x00, 0x02,
+		0x00, 0x00, 0x00, 0x03,
+		0x00, 0x06, 'c', 'l', 'i', 'e', 'n', 't',
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x05, 't', 'o', 'p', 'i', 'c',
+	}), time.Time{})
+	assert.Equal(t, errUnsupportedVersion, err)
+	assert.Nil(t, objs)
+}
+
+func TestNearestRule(t *testing.T) {
+	versions := [][]int16{{2, 3, 4}, {5, 6}, {7, 8, 9}}
+	tests := []struct {
+		version int16
+		nearest bool
+		want    int
+	}{
+		{version: 6, want: 1},
+		{version: 10, want: -1},
+		{version: 10, nearest: true, want: 2},
+		{version: 1, nearest: true, want: -1},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, nearestRule(versions, tt.version, tt.nearest))
+	}
+}
+
+func TestDecodeClient(t *testing.T) {
+	var st socket.Tuple
+	d := newDecoder(st, 0, common.NewOptions(), protocol.NewConnContext(0), nil)
+
+	metadata := []byte{
+		0x00, 0x00, 0x00, 0x1B,
+		0x00, 0x03,
+		0x00, 0x00,
+		0x00, 0x00, 0x00, 0x02,
+		0x00, 0x06, 'c', 'l', 'i', 'e', 'n', 't',
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x05, 't', 'o', 'p', 'i', 'c',
+	}
+
+	// 未观测到 ApiVersions 时退化为 clientID
+	objs, err := d.Decode(zerocopy.NewBuffer(metadata), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.Equal(t, &protocol.Client{Name: "client"}, objs[0].Obj.(*Request).Client)
+
+	objs, err = d.Decode(zerocopy.NewBuffer([]byte{
+		0x00, 0x00, 0x00, 0x23,
+		0x00, 0x12,
+		0x00, 0x03,
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x06, 'c', 'l', 'i', 'e', 'n', 't',
+		0x00,
+		0x0B, 'l', 'i', 'b', 'r', 'd', 'k', 'a', 'f', 'k', 'a',
+		0x06, '2', '.', '3', '.', '0',
+		0x00,
+	}), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+
+	expected := &protocol.Client{Name: "librdkafka", Version: "2.3.0"}
+	assert.Equal(t, expected, objs[0].Obj.(*Request).Client)
+
+	objs, err = d.Decode(zerocopy.NewBuffer(metadata), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.Equal(t, expected, objs[0].Obj.(*Request).Client)
+}
+
+func TestDecodeApiVersionsResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		version  int16
+		versions map[apiKey]versionRange
+		err      bool
+	}{
+		{
+			name: "V0",
+			input: []byte{
+				0x00, 0x00,
+				0x00, 0x00, 0x00, 0x01,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x09,
+			},
+			versions: map[apiKey]versionRange{apiProduce: {min: 0, max: 9}},
+		},
+		{
+			name:    "V3Compact",
+			version: 3,
+			input: []byte{
+				0x00, 0x00,
+				0x02,
+				0x00, 0x12, 0x00, 0x00, 0x00, 0x03, 0x00,
+				0x00,
+			},
+			versions: map[apiKey]versionRange{apiApiVersions: {min: 0, max: 3}},
+		},
+		{
+			name: "Truncated",
+			input: []byte{
+				0x00, 0x00,
+				0x00, 0x00, 0x00, 0x02,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x09,
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versions, err := decodeApiVersionsResponse(tt.input, tt.version)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.versions, versions)
+		})
+	}
+}
+
 func TestDecodeStringType(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -751,7 +1079,7 @@ func TestDecodeStringType(t *testing.T) {
 			input:    []byte{0xFF, 0xFF},
 			nullable: true,
 			s:        "",
-			offset:   0,
+			offset:   2,
 		},
 		{
 			name:     "NullStringWithNullableFalse",
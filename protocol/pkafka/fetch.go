@@ -0,0 +1,308 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkafka
+
+import (
+	"encoding/binary"
+)
+
+// FetchStats Fetch 响应的拉取统计 仅在开启 OptDecodeRecordBatches 时解析
+type FetchStats struct {
+	Partitions int           // 响应内包含的分区数量
+	Records    int           // 响应内包含的完整 batch 的消息数量
+	Bytes      int           // 所有分区 records 字段的字节数
+	Batches    []RecordBatch `json:",omitempty"`
+}
+
+type fetchState uint8
+
+const (
+	fetchStateHeaderTags fetchState = iota
+	fetchStateThrottle
+	fetchStateSession
+	fetchStateTopicCount
+	fetchStateTopic
+	fetchStatePartitionCount
+	fetchStatePartitionHeader
+	fetchStateAbortedCount
+	fetchStateAborted
+	fetchStatePreferredReplica
+	fetchStateRecordsLength
+	fetchStateRecords
+	fetchStateRecordsEnd
+	fetchStatePartitionEnd
+	fetchStateTopicEnd
+	fetchStateTagCount
+	fetchStateTagKey
+	fetchStateTagSize
+	fetchStateSkip
+	fetchStateDone
+	fetchStateFailed
+)
+
+// fetchParser 流式解析 Fetch 响应体
+//
+// 字段随版本变化较多 各版本的差异集中在 partition 头部 topic 标识以及 aborted_transactions
+type fetchParser struct {
+	fieldReader // v12+ 使用紧凑格式以及 tagged fields
+	version     int16
+	state       fetchState
+	skipNext    fetchState // skip 结束后进入的状态
+	tagsNext    fetchState // tagged fields 结束后进入的状态
+	skip        int
+	topics      int
+	partitions  int
+	aborted     int
+	tags        int
+	partition   int32
+	records     recordsReader
+	stats       FetchStats
+}
+
+func newFetchParser(version int16) *fetchParser {
+	p := &fetchParser{
+		fieldReader: fieldReader{flexible: version >= 12},
+		version:     version,
+		records:     recordsReader{detail: true},
+	}
+	switch {
+	case p.flexible:
+		p.state = fetchStateHeaderTags
+	case version >= 1:
+		p.state = fetchStateThrottle
+	default:
+		p.state = fetchStateTopicCount
+	}
+	return p
+}
+
+// Stats 返回完整解析后的统计结果 未解析完成时返回 nil
+func (p *fetchParser) Stats() *FetchStats {
+	if p.state != fetchStateDone {
+		return nil
+	}
+	stats := p.stats
+	stats.Records = p.records.records
+	stats.Batches = p.records.batches
+	return &stats
+}
+
+// partitionHeaderLength partition_index 至 log_start_offset 的定长字段长度
+func (p *fetchParser) partitionHeaderLength() int {
+	switch {
+	case p.version >= 5:
+		return 30
+	case p.version >= 4:
+		return 22
+	}
+	return 14
+}
+
+func (p *fetchParser) skipTo(n int, next fetchState) {
+	if n <= 0 {
+		p.state = next
+		return
+	}
+	p.skip = n
+	p.skipNext = next
+	p.state = fetchStateSkip
+}
+
+// tagsTo 紧凑格式下先跳过 tagged fields 再进入 next 状态
+func (p *fetchParser) tagsTo(next fetchState) {
+	if !p.flexible {
+		p.state = next
+		return
+	}
+	p.tagsNext = next
+	p.state = fetchStateTagCount
+}
+
+// afterAborted aborted_transactions 之后的状态 v11+ 需先读取 preferred_read_replica
+func (p *fetchParser) afterAborted() fetchState {
+	if p.version >= 11 {
+		return fetchStatePreferredReplica
+	}
+	return fetchStateRecordsLength
+}
+
+// needsData 当前状态是否需要读取数据才能推进
+func (p *fetchParser) needsData() bool {
+	switch p.state {
+	case fetchStateHeaderTags, fetchStateRecordsEnd, fetchStatePartitionEnd, fetchStateTopicEnd:
+		return false
+	case fetchStateAborted:
+		return p.aborted > 0
+	case fetchStateTagKey:
+		return p.tags > 0
+	case fetchStateRecords:
+		return !p.records.done()
+	}
+	return true
+}
+
+func (p *fetchParser) feed(b []byte) {
+	for p.state != fetchStateDone && p.state != fetchStateFailed {
+		if len(b) == 0 && p.needsData() {
+			return
+		}
+
+		var ok bool
+		var n int
+		var field []byte
+
+		switch p.state {
+		case fetchStateSkip:
+			l := min(p.skip, len(b))
+			p.skip -= l
+			b = b[l:]
+			if p.skip == 0 {
+				p.state = p.skipNext
+			}
+
+		case fetchStateHeaderTags:
+			p.tagsTo(fetchStateThrottle)
+
+		case fetchStateThrottle:
+			if _, b, ok = p.readFixed(b, 4); ok {
+				p.state = fetchStateTopicCount
+				if p.version >= 7 {
+					p.state = fetchStateSession
+				}
+			}
+
+		case fetchStateSession:
+			// error_code(2) | session_id(4)
+			if _, b, ok = p.readFixed(b, 6); ok {
+				p.state = fetchStateTopicCount
+			}
+
+		case fetchStateTopicCount:
+			if n, b, ok = p.readLength(b, 4); ok {
+				p.topics = n
+				p.state = fetchStateTopic
+				if n <= 0 {
+					p.state = fetchStateDone
+				}
+			}
+
+		case fetchStateTopic:
+			// v13+ 使用 topic_id(uuid) 替代 topic 名称
+			if p.version >= 13 {
+				p.skipTo(16, fetchStatePartitionCount)
+				break
+			}
+			if n, b, ok = p.readLength(b, 2); ok {
+				p.skipTo(n, fetchStatePartitionCount)
+			}
+
+		case fetchStatePartitionCount:
+			if n, b, ok = p.readLength(b, 4); ok {
+				p.partitions = n
+				p.state = fetchStatePartitionHeader
+				if n <= 0 {
+					p.tagsTo(fetchStateTopicEnd)
+				}
+			}
+
+		case fetchStatePartitionHeader:
+			if field, b, ok = p.readFixed(b, p.partitionHeaderLength()); ok {
+				p.partition = int32(binary.BigEndian.Uint32(field))
+				p.state = p.afterAborted()
+				if p.version >= 4 {
+					p.state = fetchStateAbortedCount
+				}
+			}
+
+		case fetchStateAbortedCount:
+			if n, b, ok = p.readLength(b, 4); ok {
+				p.aborted = max(n, 0)
+				p.state = fetchStateAborted
+			}
+
+		case fetchStateAborted:
+			if p.aborted <= 0 {
+				p.state = p.afterAborted()
+				break
+			}
+			// producer_id(8) | first_offset(8)
+			if _, b, ok = p.readFixed(b, 16); ok {
+				p.aborted--
+				p.tagsTo(fetchStateAborted)
+			}
+
+		case fetchStatePreferredReplica:
+			if _, b, ok = p.readFixed(b, 4); ok {
+				p.state = fetchStateRecordsLength
+			}
+
+		case fetchStateRecordsLength:
+			if n, b, ok = p.readLength(b, 4); ok {
+				n = max(n, 0)
+				p.stats.Partitions++
+				p.stats.Bytes += n
+				p.records.begin(p.partition, n)
+				p.state = fetchStateRecords
+			}
+
+		case fetchStateRecords:
+			if b = p.records.feed(b); p.records.done() {
+				p.state = fetchStateRecordsEnd
+			}
+
+		case fetchStateRecordsEnd:
+			p.tagsTo(fetchStatePartitionEnd)
+
+		case fetchStatePartitionEnd:
+			p.partitions--
+			p.state = fetchStatePartitionHeader
+			if p.partitions <= 0 {
+				p.tagsTo(fetchStateTopicEnd)
+			}
+
+		case fetchStateTopicEnd:
+			p.topics--
+			p.state = fetchStateTopic
+			if p.topics <= 0 {
+				p.state = fetchStateDone
+			}
+
+		case fetchStateTagCount:
+			if n, b, ok = p.readLength(b, 0); ok {
+				p.tags = n + 1 // tagged fields 的数量不需要减一
+				p.state = fetchStateTagKey
+			}
+
+		case fetchStateTagKey:
+			if p.tags <= 0 {
+				p.state = p.tagsNext
+				break
+			}
+			if _, b, ok = p.readUvarint(b); ok {
+				p.state = fetchStateTagSize
+			}
+
+		case fetchStateTagSize:
+			var size uint64
+			if size, b, ok = p.readUvarint(b); ok {
+				p.tags--
+				p.skipTo(int(size), fetchStateTagKey)
+			}
+		}
+
+		if p.failed {
+			p.state = fetchStateFailed
+		}
+	}
+}
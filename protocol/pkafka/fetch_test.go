@@ -0,0 +1,211 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkafka
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol"
+)
+
+type fetchPartition struct {
+	index   int32
+	aborted int
+	records []byte
+}
+
+func buildFetchBody(version int16, partitions ...fetchPartition) []byte {
+	flexible := version >= 12
+
+	var buf bytes.Buffer
+	putInt16 := func(v int16) { _ = binary.Write(&buf, binary.BigEndian, v) }
+	putInt32 := func(v int32) { _ = binary.Write(&buf, binary.BigEndian, v) }
+	putInt64 := func(v int64) { _ = binary.Write(&buf, binary.BigEndian, v) }
+	putLength := func(n int, size int) {
+		switch {
+		case flexible:
+			buf.Write(binary.AppendUvarint(nil, uint64(n+1)))
+		case size == 2:
+			putInt16(int16(n))
+		default:
+			putInt32(int32(n))
+		}
+	}
+	putTags := func() {
+		if flexible {
+			buf.WriteByte(0)
+		}
+	}
+
+	putTags() // response header tagged fields
+	if version >= 1 {
+		putInt32(0) // throttle_time_ms
+	}
+	if version >= 7 {
+		putInt16(0) // error_code
+		putInt32(1) // session_id
+	}
+	putLength(1, 4) // responses
+	if version >= 13 {
+		buf.Write(make([]byte, 16)) // topic_id
+	} else {
+		putLength(6, 2)
+		buf.WriteString("orders")
+	}
+	putLength(len(partitions), 4)
+	for _, p := range partitions {
+		putInt32(p.index)
+		putInt16(0)   // error_code
+		putInt64(100) // high_watermark
+		if version >= 4 {
+			putInt64(100) // last_stable_offset
+		}
+		if version >= 5 {
+			putInt64(0) // log_start_offset
+		}
+		if version >= 4 {
+			putLength(p.aborted, 4)
+			for i := 0; i < p.aborted; i++ {
+				putInt64(1) // producer_id
+				putInt64(2) // first_offset
+				if flexible {
+					// 携带一个 tagged field
+					buf.Write([]byte{1, 0, 2, 0xAA, 0xBB})
+				}
+			}
+		}
+		if version >= 11 {
+			putInt32(-1) // preferred_read_replica
+		}
+		putLength(len(p.records), 4)
+		buf.Write(p.records)
+		putTags()
+	}
+	putTags() // topic tagged fields
+	putTags() // response tagged fields
+	return buf.Bytes()
+}
+
+func TestFetchParser(t *testing.T) {
+	full := buildRecordBatch(3, 20)
+	// 响应末尾被 max_bytes 截断的 batch 不计入统计
+	partial := buildRecordBatch(5, 40)[:recordBatchHeaderLength+10]
+
+	partitions := []fetchPartition{
+		{index: 0, records: append(append([]byte(nil), full...), partial...)},
+		{index: 2, aborted: 2, records: buildRecordBatch(4, 8)},
+		{index: 3},
+	}
+	expected := &FetchStats{
+		Partitions: 3,
+		Records:    7,
+		Bytes:      len(full) + len(partial) + recordBatchHeaderLength + 8,
+		Batches: []RecordBatch{
+			{Partition: 0, Records: 3, Compression: "none", CompressedSize: 20, UncompressedSize: 20},
+			{Partition: 2, Records: 4, Compression: "none", CompressedSize: 8, UncompressedSize: 8},
+		},
+	}
+
+	for _, version := range []int16{0, 4, 5, 7, 11, 12, 13} {
+		body := buildFetchBody(version, partitions...)
+		for _, chunk := range []int{len(body), 1, 7, 64} {
+			p := newFetchParser(version)
+			for i := 0; i < len(body); i += chunk {
+				p.feed(body[i:min(i+chunk, len(body))])
+			}
+			assert.Equal(t, expected, p.Stats(), "version=%d/chunk=%d", version, chunk)
+		}
+	}
+}
+
+func TestFetchParserIncomplete(t *testing.T) {
+	body := buildFetchBody(11, fetchPartition{records: buildRecordBatch(3, 20)})
+
+	p := newFetchParser(11)
+	p.feed(body[:len(body)-1])
+	assert.Nil(t, p.Stats())
+}
+
+func TestDecodeFetchStats(t *testing.T) {
+	request := []byte{
+		0x00, 0x00, 0x00, 0x39,
+		0x00, 0x01,
+		0x00, 0x07,
+		0x00, 0x00,
+		0x00, 0x03, 0x00, 0x07, 'c', 'o', 'n', 's', 'u', 'm', 'e',
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x64,
+		0x00, 0x00, 0x10, 0x00,
+		0x00, 0x00, 0x00, 0x02,
+		0x00, 0x06, 't', 'o', 'p', 'i', 'c', '1',
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	body := buildFetchBody(7, fetchPartition{index: 1, records: buildRecordBatch(5, 40)})
+
+	var response bytes.Buffer
+	_ = binary.Write(&response, binary.BigEndian, int32(4+len(body)))
+	_ = binary.Write(&response, binary.BigEndian, int32(3))
+	response.Write(body)
+
+	tests := []struct {
+		name   string
+		detail bool
+		stats  *FetchStats
+	}{
+		{name: "disabled"},
+		{
+			name:   "enabled",
+			detail: true,
+			stats: &FetchStats{
+				Partitions: 1,
+				Records:    5,
+				Bytes:      recordBatchHeaderLength + 40,
+				Batches:    []RecordBatch{{Partition: 1, Records: 5, Compression: "none", CompressedSize: 40, UncompressedSize: 40}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := common.NewOptions()
+			opts.Merge(OptDecodeRecordBatches, tt.detail)
+
+			var st socket.Tuple
+			ctx := protocol.NewConnContext(0)
+			client := newDecoder(st, 0, opts, ctx, nil)
+			server := newDecoder(st, 9092, opts, ctx, nil)
+
+			objs, err := client.Decode(zerocopy.NewBuffer(request), time.Time{})
+			assert.NoError(t, err)
+			assert.Len(t, objs, 1)
+
+			data := response.Bytes()
+			for i := 0; i < len(data); i += 32 {
+				objs, err = server.Decode(zerocopy.NewBuffer(data[i:min(i+32, len(data))]), time.Time{})
+			}
+			assert.NoError(t, err)
+			assert.Len(t, objs, 1)
+			assert.Equal(t, tt.stats, objs[0].Obj.(*Response).Fetch)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkafka
+
+import (
+	"encoding/binary"
+)
+
+// fieldReader 流式读取 Kafka 协议字段
+//
+// 定长字段以及 uvarint 跨数据块时先暂存在 buf 中 凑齐后再返回
+type fieldReader struct {
+	flexible bool // 使用紧凑格式以及 tagged fields
+	failed   bool // 遇到非法的 uvarint 后续数据无法再解析
+	buf      []byte
+}
+
+// readFixed 读取 n 字节定长字段 数据不足时暂存并返回 false
+func (r *fieldReader) readFixed(b []byte, n int) ([]byte, []byte, bool) {
+	if len(r.buf) == 0 && len(b) >= n {
+		return b[:n], b[n:], true
+	}
+
+	l := min(n-len(r.buf), len(b))
+	r.buf = append(r.buf, b[:l]...)
+	b = b[l:]
+	if len(r.buf) < n {
+		return nil, b, false
+	}
+
+	field := r.buf
+	r.buf = nil
+	return field, b, true
+}
+
+// readUvarint 读取 uvarint 字段 数据不足时暂存并返回 false
+func (r *fieldReader) readUvarint(b []byte) (uint64, []byte, bool) {
+	for len(b) > 0 {
+		c := b[0]
+		b = b[1:]
+		r.buf = append(r.buf, c)
+		if c&0x80 != 0 {
+			if len(r.buf) >= binary.MaxVarintLen64 {
+				r.failed = true
+				return 0, nil, false
+			}
+			continue
+		}
+
+		v, n := binary.Uvarint(r.buf)
+		r.buf = nil
+		if n <= 0 {
+			r.failed = true
+			return 0, nil, false
+		}
+		return v, b, true
+	}
+	return 0, b, false
+}
+
+// readLength 读取 string / array / bytes 的长度 紧凑格式下长度需减一 -1 表示 null
+func (r *fieldReader) readLength(b []byte, size int) (int, []byte, bool) {
+	if r.flexible {
+		v, rest, ok := r.readUvarint(b)
+		if !ok {
+			return 0, rest, false
+		}
+		return int(v) - 1, rest, true
+	}
+
+	field, rest, ok := r.readFixed(b, size)
+	if !ok {
+		return 0, rest, false
+	}
+	if size == 2 {
+		return int(int16(binary.BigEndian.Uint16(field))), rest, true
+	}
+	return int(int32(binary.BigEndian.Uint32(field))), rest, true
+}
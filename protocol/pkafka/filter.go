@@ -0,0 +1,101 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkafka
+
+import (
+	"path"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/packetd/packetd/common"
+)
+
+const (
+	// OptTopicAllowlist 仅允许匹配的 topic 生成 RoundTrip 支持 glob 通配
+	OptTopicAllowlist = "topicAllowlist"
+
+	// OptTopicDenylist 匹配的 topic 不生成 RoundTrip 优先级高于 allowlist
+	OptTopicDenylist = "topicDenylist"
+
+	// OptGroupAllowlist 仅允许匹配的消费组生成 RoundTrip 支持 glob 通配
+	OptGroupAllowlist = "groupAllowlist"
+
+	// OptGroupDenylist 匹配的消费组不生成 RoundTrip 优先级高于 allowlist
+	OptGroupDenylist = "groupDenylist"
+)
+
+var filteredRequestsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: common.App,
+		Name:      "kafka_filtered_requests_total",
+		Help:      "Kafka requests dropped by topic/group filters total",
+	},
+	[]string{"api"},
+)
+
+// filter 按照 topic / group 过滤请求
+//
+// 未携带 topic / group 的请求（如 ApiVersions）不受影响
+type filter struct {
+	topicAllow []string
+	topicDeny  []string
+	groupAllow []string
+	groupDeny  []string
+}
+
+// newFilter 从 opts 中读取过滤规则 未配置任何规则时返回 nil
+func newFilter(opts common.Options) *filter {
+	topicAllow, _ := opts.GetStringSlice(OptTopicAllowlist)
+	topicDeny, _ := opts.GetStringSlice(OptTopicDenylist)
+	groupAllow, _ := opts.GetStringSlice(OptGroupAllowlist)
+	groupDeny, _ := opts.GetStringSlice(OptGroupDenylist)
+
+	if len(topicAllow)+len(topicDeny)+len(groupAllow)+len(groupDeny) == 0 {
+		return nil
+	}
+	return &filter{
+		topicAllow: topicAllow,
+		topicDeny:  topicDeny,
+		groupAllow: groupAllow,
+		groupDeny:  groupDeny,
+	}
+}
+
+// accept 判断 packet 是否需要生成 RoundTrip
+func (f *filter) accept(p *Packet) bool {
+	if f == nil || p == nil {
+		return true
+	}
+	return allowed(p.Topic, f.topicAllow, f.topicDeny) && allowed(p.GroupID, f.groupAllow, f.groupDeny)
+}
+
+func allowed(s string, allow, deny []string) bool {
+	if s == "" {
+		return true
+	}
+	if matchAny(s, deny) {
+		return false
+	}
+	return len(allow) == 0 || matchAny(s, allow)
+}
+
+func matchAny(s string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, s); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol"
+)
+
+func TestFilterAccept(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   common.Options
+		packet *Packet
+		accept bool
+	}{
+		{
+			name:   "NoRules",
+			opts:   common.Options{},
+			packet: &Packet{Topic: "orders"},
+			accept: true,
+		},
+		{
+			name:   "TopicAllowed",
+			opts:   common.Options{OptTopicAllowlist: []string{"orders-*"}},
+			packet: &Packet{Topic: "orders-eu"},
+			accept: true,
+		},
+		{
+			name:   "TopicNotAllowed",
+			opts:   common.Options{OptTopicAllowlist: []string{"orders-*"}},
+			packet: &Packet{Topic: "clicks"},
+			accept: false,
+		},
+		{
+			name:   "TopicDenyOverAllow",
+			opts:   common.Options{OptTopicAllowlist: []string{"orders-*"}, OptTopicDenylist: []string{"orders-test"}},
+			packet: &Packet{Topic: "orders-test"},
+			accept: false,
+		},
+		{
+			name:   "GroupDenied",
+			opts:   common.Options{OptGroupDenylist: []any{"batch-*"}},
+			packet: &Packet{GroupID: "batch-etl"},
+			accept: false,
+		},
+		{
+			name:   "EmptyTopicAndGroup",
+			opts:   common.Options{OptTopicAllowlist: []string{"orders"}, OptGroupAllowlist: []string{"billing"}},
+			packet: &Packet{API: "ApiVersions"},
+			accept: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.accept, newFilter(tt.opts).accept(tt.packet))
+		})
+	}
+}
+
+func TestDecodeFiltered(t *testing.T) {
+	var st socket.Tuple
+	opts := common.Options{OptTopicAllowlist: []string{"orders"}}
+	ctx := protocol.NewConnContext(0)
+	client := newDecoder(st, 0, opts, ctx, nil)
+	server := newDecoder(st, 9092, opts, ctx, nil)
+
+	metadata := func(correlationID byte, topic string) []byte {
+		b := []byte{
+			0x00, 0x00, 0x00, byte(22 + len(topic)),
+			0x00, 0x03,
+			0x00, 0x00,
+			0x00, 0x00, 0x00, correlationID,
+			0x00, 0x06, 'c', 'l', 'i', 'e', 'n', 't',
+			0x00, 0x00, 0x00, 0x01,
+			0x00, byte(len(topic)),
+		}
+		return append(b, topic...)
+	}
+	response := func(correlationID byte) []byte {
+		return []byte{
+			0x00, 0x00, 0x00, 0x0C,
+			0x00, 0x00, 0x00, correlationID,
+			0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00,
+		}
+	}
+
+	// 被过滤的请求以及对应响应均不产生任何对象
+	objs, err := client.Decode(zerocopy.NewBuffer(metadata(1, "clicks")), time.Time{})
+	assert.NoError(t, err)
+	assert.Nil(t, objs)
+
+	objs, err = server.Decode(zerocopy.NewBuffer(response(1)), time.Time{})
+	assert.NoError(t, err)
+	assert.Nil(t, objs)
+
+	// 解析状态已经重置 后续请求不受影响
+	objs, err = client.Decode(zerocopy.NewBuffer(metadata(2, "orders")), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.Equal(t, "orders", objs[0].Obj.(*Request).Packet.Topic)
+
+	objs, err = server.Decode(zerocopy.NewBuffer(response(2)), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.Equal(t, int32(2), objs[0].Obj.(*Response).CorrelationID)
+}
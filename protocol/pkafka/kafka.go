@@ -24,12 +24,19 @@ import (
 
 func init() {
 	protocol.Register(socket.L7ProtoKafka, NewConnPool)
+	protocol.Describe(socket.L7ProtoKafka, protocol.Capability{
+		Versions: []string{"0.8", "1.x", "2.x", "3.x"},
+		Options:  []string{OptLegacyVersionLag, OptTopicAllowlist, OptTopicDenylist, OptGroupAllowlist, OptGroupDenylist, OptDecodeRecordBatches},
+	})
 }
 
 const maxRecordSize = 64
 
 // NewConnPool 创建 Kafka 协议连接池
+//
+// 同一条链接两个方向的 decoder 共享链接上下文 以便利用 ApiVersions 的协商结果以及最近一次请求的 topic
 func NewConnPool(opts common.Options) protocol.ConnPool {
+	cs := protocol.NewConnContexts(0)
 	return protocol.NewL7TCPConnPool(
 		func() role.Matcher {
 			return role.NewListMatcher(maxRecordSize, func(req, rsp *role.Object) bool {
@@ -37,13 +44,17 @@ func NewConnPool(opts common.Options) protocol.ConnPool {
 			})
 		},
 		func(pair *role.Pair) socket.RoundTrip {
-			return &RoundTrip{
-				request:  pair.Request.Obj.(*Request),
-				response: pair.Response.Obj.(*Response),
+			rt := &RoundTrip{request: pair.Request.Obj.(*Request)}
+			if pair.Response != nil {
+				rt.response = pair.Response.Obj.(*Response)
 			}
+			return rt
 		},
 		func(st socket.Tuple, serverPort socket.Port) protocol.Decoder {
-			return NewDecoder(st, serverPort, opts)
+			ctx := cs.Acquire(st, serverPort)
+			return newDecoder(st, serverPort, opts, ctx, func() {
+				cs.Release(st, serverPort)
+			})
 		},
 	)
 }
@@ -57,6 +68,11 @@ type Request struct {
 	Size          int
 	Time          time.Time
 	Packet        *Packet
+	Client        *protocol.Client `json:",omitempty"`
+
+	// ServerHost / ServerPort 仅在 acks=0 的单向事件中填充 此时没有 Response 可以提供 Broker 地址
+	ServerHost string `json:",omitempty"`
+	ServerPort uint16 `json:",omitempty"`
 }
 
 // Response Kafka 响应
@@ -68,6 +84,7 @@ type Response struct {
 	Size          int
 	Time          time.Time
 	ErrorCode     string
+	Fetch         *FetchStats `json:",omitempty"`
 }
 
 // RoundTrip Kafka 单次请求来回
@@ -91,9 +108,20 @@ func (rt RoundTrip) Response() any {
 }
 
 func (rt RoundTrip) Duration() time.Duration {
+	if rt.OneWay() {
+		return 0
+	}
 	return rt.response.Time.Sub(rt.request.Time)
 }
 
 func (rt RoundTrip) Validate() bool {
+	if rt.OneWay() {
+		return true
+	}
 	return rt.response.Time.After(rt.request.Time)
 }
+
+// OneWay 实现了 socket.OneWayRoundTrip 接口
+func (rt RoundTrip) OneWay() bool {
+	return rt.response == nil
+}
@@ -0,0 +1,264 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkafka
+
+import (
+	"encoding/binary"
+)
+
+// ProduceStats Produce 请求的批量写入统计
+//
+// 用于被动识别生产者的配置问题 如过小的 batch 或者 acks=all 带来的延迟
+type ProduceStats struct {
+	Acks       int16 // -1 表示 all
+	Partitions int   // 请求内包含的分区数量
+	Records    int   // 请求内包含的消息数量 仅统计 magic=2 的 RecordBatch
+	BatchSizes []int // 每个分区 records 字段的字节数
+
+	// Batches 每个 RecordBatch 的统计 仅在开启 OptDecodeRecordBatches 时记录
+	Batches []RecordBatch `json:",omitempty"`
+}
+
+type produceState uint8
+
+const (
+	produceStateHeaderTags produceState = iota
+	produceStateTransactionalID
+	produceStateAcks
+	produceStateTimeout
+	produceStateTopicCount
+	produceStateTopicName
+	produceStatePartitionCount
+	produceStatePartitionIndex
+	produceStateRecordsLength
+	produceStateRecords
+	produceStateRecordsEnd
+	produceStatePartitionEnd
+	produceStateTopicEnd
+	produceStateTagCount
+	produceStateTagKey
+	produceStateTagSize
+	produceStateSkip
+	produceStateDone
+	produceStateFailed
+)
+
+// produceParser 流式解析 Produce 请求体
+//
+// Produce 请求体通常远大于单次读取的数据块 无法一次性拿到完整的 payload
+// 因此按字段逐个推进状态 定长字段跨数据块时先暂存在 buf 中 records 部分交由 recordsReader 处理
+type produceParser struct {
+	fieldReader        // v9+ 使用紧凑格式以及 tagged fields
+	transactional bool // v3+ 携带 transactional_id
+	state         produceState
+	skipNext      produceState // skip 结束后进入的状态
+	tagsNext      produceState // tagged fields 结束后进入的状态
+	skip          int
+	topics        int
+	partitions    int
+	tags          int
+	partition     int32
+	records       recordsReader
+	stats         ProduceStats
+}
+
+func newProduceParser(version int16, detail bool) *produceParser {
+	p := &produceParser{
+		fieldReader:   fieldReader{flexible: version >= 9},
+		transactional: version >= 3,
+		records:       recordsReader{detail: detail},
+	}
+	switch {
+	case p.flexible:
+		p.state = produceStateHeaderTags
+	case p.transactional:
+		p.state = produceStateTransactionalID
+	default:
+		p.state = produceStateAcks
+	}
+	return p
+}
+
+// Stats 返回完整解析后的统计结果 未解析完成时返回 nil
+func (p *produceParser) Stats() *ProduceStats {
+	if p.state != produceStateDone {
+		return nil
+	}
+	stats := p.stats
+	stats.Records = p.records.records
+	stats.Batches = p.records.batches
+	return &stats
+}
+
+func (p *produceParser) skipTo(n int, next produceState) {
+	if n <= 0 {
+		p.state = next
+		return
+	}
+	p.skip = n
+	p.skipNext = next
+	p.state = produceStateSkip
+}
+
+// tagsTo 紧凑格式下先跳过 tagged fields 再进入 next 状态
+func (p *produceParser) tagsTo(next produceState) {
+	if !p.flexible {
+		p.state = next
+		return
+	}
+	p.tagsNext = next
+	p.state = produceStateTagCount
+}
+
+// needsData 当前状态是否需要读取数据才能推进
+func (p *produceParser) needsData() bool {
+	switch p.state {
+	case produceStateHeaderTags, produceStateRecordsEnd, produceStatePartitionEnd, produceStateTopicEnd:
+		return false
+	case produceStateTagKey:
+		return p.tags > 0
+	case produceStateRecords:
+		return !p.records.done()
+	}
+	return true
+}
+
+func (p *produceParser) feed(b []byte) {
+	for p.state != produceStateDone && p.state != produceStateFailed {
+		if len(b) == 0 && p.needsData() {
+			return
+		}
+
+		var ok bool
+		var n int
+		var field []byte
+
+		switch p.state {
+		case produceStateSkip:
+			l := min(p.skip, len(b))
+			p.skip -= l
+			b = b[l:]
+			if p.skip == 0 {
+				p.state = p.skipNext
+			}
+
+		case produceStateHeaderTags:
+			p.tagsTo(produceStateTransactionalID)
+
+		case produceStateTransactionalID:
+			if n, b, ok = p.readLength(b, 2); ok {
+				p.skipTo(n, produceStateAcks)
+			}
+
+		case produceStateAcks:
+			if field, b, ok = p.readFixed(b, 2); ok {
+				p.stats.Acks = int16(binary.BigEndian.Uint16(field))
+				p.state = produceStateTimeout
+			}
+
+		case produceStateTimeout:
+			if _, b, ok = p.readFixed(b, 4); ok {
+				p.state = produceStateTopicCount
+			}
+
+		case produceStateTopicCount:
+			if n, b, ok = p.readLength(b, 4); ok {
+				p.topics = n
+				p.state = produceStateTopicName
+				if n <= 0 {
+					p.state = produceStateDone
+				}
+			}
+
+		case produceStateTopicName:
+			if n, b, ok = p.readLength(b, 2); ok {
+				p.skipTo(n, produceStatePartitionCount)
+			}
+
+		case produceStatePartitionCount:
+			if n, b, ok = p.readLength(b, 4); ok {
+				p.partitions = n
+				p.state = produceStatePartitionIndex
+				if n <= 0 {
+					p.tagsTo(produceStateTopicEnd)
+				}
+			}
+
+		case produceStatePartitionIndex:
+			if field, b, ok = p.readFixed(b, 4); ok {
+				p.partition = int32(binary.BigEndian.Uint32(field))
+				p.state = produceStateRecordsLength
+			}
+
+		case produceStateRecordsLength:
+			if n, b, ok = p.readLength(b, 4); ok {
+				n = max(n, 0)
+				p.stats.Partitions++
+				if len(p.stats.BatchSizes) < maxRecordedBatches {
+					p.stats.BatchSizes = append(p.stats.BatchSizes, n)
+				}
+				p.records.begin(p.partition, n)
+				p.state = produceStateRecords
+			}
+
+		case produceStateRecords:
+			if b = p.records.feed(b); p.records.done() {
+				p.state = produceStateRecordsEnd
+			}
+
+		case produceStateRecordsEnd:
+			p.tagsTo(produceStatePartitionEnd)
+
+		case produceStatePartitionEnd:
+			p.partitions--
+			p.state = produceStatePartitionIndex
+			if p.partitions <= 0 {
+				p.tagsTo(produceStateTopicEnd)
+			}
+
+		case produceStateTopicEnd:
+			p.topics--
+			p.state = produceStateTopicName
+			if p.topics <= 0 {
+				p.state = produceStateDone
+			}
+
+		case produceStateTagCount:
+			if n, b, ok = p.readLength(b, 0); ok {
+				p.tags = n + 1 // tagged fields 的数量不需要减一
+				p.state = produceStateTagKey
+			}
+
+		case produceStateTagKey:
+			if p.tags <= 0 {
+				p.state = p.tagsNext
+				break
+			}
+			if _, b, ok = p.readUvarint(b); ok {
+				p.state = produceStateTagSize
+			}
+
+		case produceStateTagSize:
+			var size uint64
+			if size, b, ok = p.readUvarint(b); ok {
+				p.tags--
+				p.skipTo(int(size), produceStateTagKey)
+			}
+		}
+
+		if p.failed {
+			p.state = produceStateFailed
+		}
+	}
+}
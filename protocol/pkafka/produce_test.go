@@ -0,0 +1,181 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkafka
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+func buildRecordBatch(records int32, bodySize int) []byte {
+	b := make([]byte, recordBatchHeaderLength+bodySize)
+	binary.BigEndian.PutUint32(b[8:12], uint32(recordBatchHeaderLength-12+bodySize))
+	b[16] = 2
+	binary.BigEndian.PutUint32(b[57:61], uint32(records))
+	return b
+}
+
+func buildProduceBody(flexible bool, batches ...[]byte) []byte {
+	var buf bytes.Buffer
+	putInt16 := func(v int16) { _ = binary.Write(&buf, binary.BigEndian, v) }
+	putInt32 := func(v int32) { _ = binary.Write(&buf, binary.BigEndian, v) }
+	putLength := func(n int, size int) {
+		switch {
+		case flexible:
+			buf.Write(binary.AppendUvarint(nil, uint64(n+1)))
+		case size == 2:
+			putInt16(int16(n))
+		default:
+			putInt32(int32(n))
+		}
+	}
+
+	if flexible {
+		buf.WriteByte(0) // header tagged fields
+	}
+	putLength(-1, 2) // transactional_id
+	putInt16(-1)     // acks
+	putInt32(3000)   // timeout_ms
+	putLength(1, 4)  // topics
+	putLength(6, 2)
+	buf.WriteString("orders")
+	putLength(len(batches), 4)
+	for i, batch := range batches {
+		putInt32(int32(i))
+		putLength(len(batch), 4)
+		buf.Write(batch)
+		if flexible {
+			buf.WriteByte(0)
+		}
+	}
+	if flexible {
+		buf.Write([]byte{0, 0})
+	}
+	return buf.Bytes()
+}
+
+func TestProduceParser(t *testing.T) {
+	twoBatches := append(buildRecordBatch(3, 20), buildRecordBatch(2, 5)...)
+	expected := &ProduceStats{
+		Acks:       -1,
+		Partitions: 3,
+		Records:    9,
+		BatchSizes: []int{len(twoBatches), 81, 0},
+	}
+
+	tests := []struct {
+		name     string
+		version  int16
+		flexible bool
+	}{
+		{name: "V7", version: 7},
+		{name: "V9Flexible", version: 9, flexible: true},
+	}
+
+	for _, tt := range tests {
+		body := buildProduceBody(tt.flexible, twoBatches, buildRecordBatch(4, 20), nil)
+		for _, chunk := range []int{len(body), 1, 7, 64} {
+			p := newProduceParser(tt.version, false)
+			for i := 0; i < len(body); i += chunk {
+				p.feed(body[i:min(i+chunk, len(body))])
+			}
+			assert.Equal(t, expected, p.Stats(), "%s/chunk=%d", tt.name, chunk)
+		}
+	}
+}
+
+func TestProduceParserIncomplete(t *testing.T) {
+	body := buildProduceBody(false, buildRecordBatch(3, 20))
+
+	p := newProduceParser(7, false)
+	p.feed(body[:len(body)-1])
+	assert.Nil(t, p.Stats())
+}
+
+func TestDecodeProduceStats(t *testing.T) {
+	body := buildProduceBody(false, buildRecordBatch(5, 40))
+
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.BigEndian, int32(10+len("producer")+len(body)))
+	_ = binary.Write(&buf, binary.BigEndian, []int16{int16(apiProduce), 7})
+	_ = binary.Write(&buf, binary.BigEndian, int32(1))
+	_ = binary.Write(&buf, binary.BigEndian, int16(len("producer")))
+	buf.WriteString("producer")
+	buf.Write(body)
+
+	var st socket.Tuple
+	d := NewDecoder(st, 0, common.NewOptions())
+	var objs []*role.Object
+	var err error
+	data := buf.Bytes()
+	for i := 0; i < len(data); i += 64 {
+		objs, err = d.Decode(zerocopy.NewBuffer(data[i:min(i+64, len(data))]), time.Time{})
+	}
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+
+	packet := objs[0].Obj.(*Request).Packet
+	assert.Equal(t, "producer", packet.ClientID)
+	assert.Equal(t, &ProduceStats{
+		Acks:       -1,
+		Partitions: 1,
+		Records:    5,
+		BatchSizes: []int{recordBatchHeaderLength + 40},
+	}, packet.Produce)
+}
+
+func TestDecodeProduceAcksZero(t *testing.T) {
+	body := buildProduceBody(false, buildRecordBatch(1, 10))
+	binary.BigEndian.PutUint16(body[2:4], 0) // acks=0
+
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.BigEndian, int32(10+len("producer")+len(body)))
+	_ = binary.Write(&buf, binary.BigEndian, []int16{int16(apiProduce), 7})
+	_ = binary.Write(&buf, binary.BigEndian, int32(1))
+	_ = binary.Write(&buf, binary.BigEndian, int16(len("producer")))
+	buf.WriteString("producer")
+	buf.Write(body)
+
+	st := socket.Tuple{
+		SrcIP:   socket.ToIPV4(net.IPv4(10, 0, 0, 1).To4()),
+		DstIP:   socket.ToIPV4(net.IPv4(10, 0, 0, 2).To4()),
+		SrcPort: 50000,
+		DstPort: 9092,
+	}
+	d := NewDecoder(st, 9092, common.NewOptions())
+	objs, err := d.Decode(zerocopy.NewBuffer(buf.Bytes()), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.Equal(t, role.Role(role.OneWay), objs[0].Role)
+
+	req := objs[0].Obj.(*Request)
+	assert.Equal(t, int16(0), req.Packet.Produce.Acks)
+	assert.Equal(t, "10.0.0.2", req.ServerHost)
+	assert.Equal(t, uint16(9092), req.ServerPort)
+
+	rt := RoundTrip{request: req}
+	assert.True(t, socket.IsOneWay(rt))
+	assert.True(t, rt.Validate())
+	assert.Zero(t, rt.Duration())
+}
@@ -0,0 +1,243 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// OptDecodeRecordBatches 是否记录每个 RecordBatch 的分区 消息数 压缩算法以及压缩前后的大小
+	// 同时开启 Fetch 响应的解析 压缩的 batch 需要解压才能得到原始大小 CPU 开销较大 默认关闭
+	OptDecodeRecordBatches = "decodeRecordBatches"
+
+	// recordBatchHeaderLength RecordBatch(magic=2) 从 baseOffset 至 recordsCount 的固定头部长度
+	recordBatchHeaderLength = 61
+
+	// maxRecordedBatches 单个请求最多记录的 batch 个数
+	maxRecordedBatches = 256
+
+	// maxDecompressBatchSize 超出该大小的压缩 batch 不再暂存解压 原始大小记为 0
+	maxDecompressBatchSize = 1 << 20
+
+	// maxUncompressedSize 解压后的大小上限 避免异常数据导致的内存膨胀
+	maxUncompressedSize = 64 << 20
+)
+
+const (
+	compressionNone   = 0
+	compressionGzip   = 1
+	compressionSnappy = 2
+	compressionLz4    = 3
+	compressionZstd   = 4
+)
+
+var compressionNames = map[int]string{
+	compressionNone:   "none",
+	compressionGzip:   "gzip",
+	compressionSnappy: "snappy",
+	compressionLz4:    "lz4",
+	compressionZstd:   "zstd",
+}
+
+// RecordBatch 单个 RecordBatch(magic=2) 的统计
+type RecordBatch struct {
+	Partition        int32
+	Records          int
+	Compression      string
+	CompressedSize   int // 头部之后 records 部分在链路上的字节数
+	UncompressedSize int // records 部分解压后的字节数 lz4 或超出解压上限时为 0
+}
+
+// recordsReader 流式解析分区的 records 字段 依次读取其中每个 RecordBatch 的头部
+//
+// 默认读取头部后直接跳过 batch 内容 开启 detail 后压缩的 batch 需要暂存内容用于计算原始大小
+type recordsReader struct {
+	detail    bool
+	partition int32
+	remain    int // 当前分区 records 剩余未读取的字节数
+	skip      int
+	header    []byte
+
+	body       []byte // 暂存的压缩 batch 内容
+	bodyLen    int
+	collecting bool
+
+	records int // 累计的消息数 仅统计完整的 batch
+	batches []RecordBatch
+}
+
+// begin 开始读取 partition 分区长度为 n 的 records 字段
+func (r *recordsReader) begin(partition int32, n int) {
+	r.partition = partition
+	r.remain = max(n, 0)
+}
+
+// done 当前分区的 records 字段是否已经读取完毕
+func (r *recordsReader) done() bool {
+	return r.remain == 0 && r.skip == 0 && !r.collecting
+}
+
+// feed 消费 records 数据 返回剩余的数据
+func (r *recordsReader) feed(b []byte) []byte {
+	for !r.done() {
+		switch {
+		case r.collecting:
+			l := min(r.bodyLen-len(r.body), len(b))
+			r.body = append(r.body, b[:l]...)
+			r.remain -= l
+			b = b[l:]
+			if len(r.body) < r.bodyLen {
+				return b
+			}
+			last := &r.batches[len(r.batches)-1]
+			last.UncompressedSize = uncompressedSize(last.Compression, r.body)
+			r.body = r.body[:0]
+			r.collecting = false
+
+		case r.skip > 0:
+			l := min(r.skip, len(b))
+			r.skip -= l
+			r.remain -= l
+			b = b[l:]
+			if r.skip > 0 {
+				return b
+			}
+
+		// 剩余字节不足一个 RecordBatch 头部 可能是旧版本的 MessageSet 直接跳过
+		case len(r.header) == 0 && r.remain < recordBatchHeaderLength:
+			r.skip = r.remain
+
+		default:
+			l := min(recordBatchHeaderLength-len(r.header), len(b))
+			r.header = append(r.header, b[:l]...)
+			r.remain -= l
+			b = b[l:]
+			if len(r.header) < recordBatchHeaderLength {
+				return b
+			}
+			r.onHeader(r.header)
+			r.header = r.header[:0]
+		}
+	}
+	return b
+}
+
+// onHeader 处理完整的 RecordBatch 头部 决定跳过或者暂存 batch 内容
+func (r *recordsReader) onHeader(h []byte) {
+	if magic := h[16]; magic != 2 {
+		r.skip = r.remain
+		return
+	}
+
+	// batchLength 不包含 baseOffset 以及 batchLength 自身
+	body := int(int32(binary.BigEndian.Uint32(h[8:12]))) + 12 - recordBatchHeaderLength
+	if body < 0 || body > r.remain {
+		// Fetch 响应末尾可能携带不完整的 batch 客户端会直接丢弃
+		r.skip = r.remain
+		return
+	}
+	r.skip = body
+
+	records := int(int32(binary.BigEndian.Uint32(h[57:61])))
+	r.records += records
+	if !r.detail || len(r.batches) >= maxRecordedBatches {
+		return
+	}
+
+	codec := int(binary.BigEndian.Uint16(h[21:23]) & 0x07)
+	batch := RecordBatch{
+		Partition:      r.partition,
+		Records:        records,
+		Compression:    compressionNames[codec],
+		CompressedSize: body,
+	}
+	switch {
+	case codec == compressionNone:
+		batch.UncompressedSize = body
+	case codec != compressionLz4 && body <= maxDecompressBatchSize:
+		r.skip = 0
+		r.bodyLen = body
+		r.collecting = true
+	}
+	r.batches = append(r.batches, batch)
+}
+
+var zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(maxUncompressedSize))
+
+// xerialHeader Java 客户端的 snappy 压缩使用 xerial 分块格式
+var xerialHeader = []byte{0x82, 'S', 'N', 'A', 'P', 'P', 'Y', 0}
+
+// uncompressedSize 返回 batch 内容解压后的字节数 解压失败时返回 0
+func uncompressedSize(compression string, b []byte) int {
+	switch compression {
+	case compressionNames[compressionGzip]:
+		zr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return 0
+		}
+		n, err := io.Copy(io.Discard, io.LimitReader(zr, maxUncompressedSize))
+		if err != nil {
+			return 0
+		}
+		return int(n)
+
+	case compressionNames[compressionSnappy]:
+		return snappyDecodedLen(b)
+
+	case compressionNames[compressionZstd]:
+		dst, err := zstdDecoder.DecodeAll(b, nil)
+		if err != nil {
+			return 0
+		}
+		return len(dst)
+	}
+	return 0
+}
+
+// snappyDecodedLen 返回 snappy 数据解压后的字节数 仅需读取每个分块头部记录的长度 无需真正解压
+//
+// xerial 格式: Header(8) | Version(4) | Compatible(4) | [ChunkLength(4) | Chunk]...
+func snappyDecodedLen(b []byte) int {
+	if !bytes.HasPrefix(b, xerialHeader) {
+		n, err := snappy.DecodedLen(b)
+		if err != nil {
+			return 0
+		}
+		return n
+	}
+
+	var total int
+	b = b[min(len(xerialHeader)+8, len(b)):]
+	for len(b) >= 4 {
+		size := int(binary.BigEndian.Uint32(b[:4]))
+		b = b[4:]
+		if size > len(b) {
+			return 0
+		}
+		n, err := snappy.DecodedLen(b[:size])
+		if err != nil {
+			return 0
+		}
+		total += n
+		b = b[size:]
+	}
+	return total
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+// buildCompressedBatch 构造 body 经过 codec 压缩的 RecordBatch
+func buildCompressedBatch(records int32, codec int, body []byte) []byte {
+	b := buildRecordBatch(records, len(body))
+	binary.BigEndian.PutUint16(b[21:23], uint16(codec))
+	copy(b[recordBatchHeaderLength:], body)
+	return b
+}
+
+func TestUncompressedSize(t *testing.T) {
+	raw := bytes.Repeat([]byte("packetd-kafka-record"), 100)
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, _ = w.Write(raw)
+	_ = w.Close()
+
+	enc, _ := zstd.NewWriter(nil)
+	zst := enc.EncodeAll(raw, nil)
+
+	xerial := append([]byte(nil), xerialHeader...)
+	xerial = append(xerial, 0, 0, 0, 1, 0, 0, 0, 1)
+	for _, chunk := range [][]byte{raw[:1000], raw[1000:]} {
+		block := snappy.Encode(nil, chunk)
+		xerial = binary.BigEndian.AppendUint32(xerial, uint32(len(block)))
+		xerial = append(xerial, block...)
+	}
+
+	tests := []struct {
+		name        string
+		compression string
+		input       []byte
+		size        int
+	}{
+		{name: "gzip", compression: "gzip", input: gz.Bytes(), size: len(raw)},
+		{name: "snappy", compression: "snappy", input: snappy.Encode(nil, raw), size: len(raw)},
+		{name: "snappy xerial", compression: "snappy", input: xerial, size: len(raw)},
+		{name: "zstd", compression: "zstd", input: zst, size: len(raw)},
+		{name: "lz4", compression: "lz4", input: raw},
+		{name: "corrupted gzip", compression: "gzip", input: raw},
+		{name: "corrupted xerial", compression: "snappy", input: append(append([]byte(nil), xerial[:16]...), 0xff, 0xff, 0xff, 0xff)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.size, uncompressedSize(tt.compression, tt.input))
+		})
+	}
+}
+
+func TestProduceParserDetail(t *testing.T) {
+	raw := bytes.Repeat([]byte("record"), 50)
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, _ = w.Write(raw)
+	_ = w.Close()
+
+	gzBatch := buildCompressedBatch(6, compressionGzip, gz.Bytes())
+	lz4Batch := buildCompressedBatch(2, compressionLz4, make([]byte, 30))
+	body := buildProduceBody(false, append(buildRecordBatch(3, 20), gzBatch...), lz4Batch)
+
+	expected := []RecordBatch{
+		{Partition: 0, Records: 3, Compression: "none", CompressedSize: 20, UncompressedSize: 20},
+		{Partition: 0, Records: 6, Compression: "gzip", CompressedSize: gz.Len(), UncompressedSize: len(raw)},
+		{Partition: 1, Records: 2, Compression: "lz4", CompressedSize: 30},
+	}
+	for _, chunk := range []int{len(body), 1, 7, 64} {
+		p := newProduceParser(7, true)
+		for i := 0; i < len(body); i += chunk {
+			p.feed(body[i:min(i+chunk, len(body))])
+		}
+		stats := p.Stats()
+		if assert.NotNil(t, stats, "chunk=%d", chunk) {
+			assert.Equal(t, 11, stats.Records, "chunk=%d", chunk)
+			assert.Equal(t, expected, stats.Batches, "chunk=%d", chunk)
+		}
+	}
+
+	p := newProduceParser(7, false)
+	p.feed(body)
+	assert.Nil(t, p.Stats().Batches)
+}
@@ -0,0 +1,360 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkafka
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/packetd/packetd/protocol"
+)
+
+// maxPendingApiVersions 单链接最多同时追踪的 ApiVersions 请求数量
+const maxPendingApiVersions = 4
+
+// maxPendingFiltered 单链接最多同时追踪的被过滤请求数量
+const maxPendingFiltered = 64
+
+// maxPendingFetches 单链接最多同时追踪的 Fetch 请求数量
+const maxPendingFetches = 64
+
+// maxPendingTokens 单链接最多同时追踪的 SASL Token 数量
+const maxPendingTokens = 4
+
+// versionRange Broker 支持的 API 版本区间
+type versionRange struct {
+	min int16
+	max int16
+}
+
+// session 记录着单条 Kafka 链接中 client/server 两个方向的 decoder 共享的上下文
+//
+// client 端 decoder 记录 ApiVersions 请求的 correlationID 以及版本
+// server 端 decoder 据此识别 ApiVersions 响应并解析 Broker 支持的版本区间
+//
+// SaslHandshake v0 之后的原始 Token 交换同样记录在 session 中
+// Token 帧不携带 correlationID 两个方向按照发送顺序以 session 分配的序号配对
+type session struct {
+	mut      sync.Mutex
+	pending  map[int32]int16 // correlationID -> ApiVersions 请求版本
+	versions map[apiKey]versionRange
+	client   *protocol.Client   // ApiVersions v3+ 请求中声明的客户端软件
+	filtered map[int32]struct{} // 被过滤请求的 correlationID 对应的响应同样需要丢弃
+	fetches  map[int32]int16    // correlationID -> Fetch 请求版本
+
+	saslToken bool    // 处于 SaslHandshake v0 之后的 Token 交换阶段
+	mechanism string  // SaslHandshake v0 声明的认证机制
+	tokenSeq  int32   // Token 帧序号 取负值避免与 correlationID 冲突
+	tokens    []int32 // 已发送但 Broker 尚未回复的客户端 Token 序号
+}
+
+// ctxSession session 挂载在 protocol.ConnContext 中的 key
+const ctxSession = "kafka.session"
+
+// connSession 返回链接上下文中挂载的 session
+func connSession(ctx *protocol.ConnContext) *session {
+	return ctx.Value(ctxSession, func() any { return newSession() }).(*session)
+}
+
+func newSession() *session {
+	return &session{
+		pending:  make(map[int32]int16),
+		filtered: make(map[int32]struct{}),
+		fetches:  make(map[int32]int16),
+	}
+}
+
+// startSaslToken 进入 Token 交换阶段
+func (s *session) startSaslToken(mechanism string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.saslToken = true
+	s.mechanism = mechanism
+	s.tokens = s.tokens[:0]
+}
+
+// endSaslToken 客户端重新发送 Kafka 请求 Token 交换阶段结束
+func (s *session) endSaslToken() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.saslToken = false
+	s.tokens = s.tokens[:0]
+}
+
+// inSaslToken 返回是否处于 Token 交换阶段
+func (s *session) inSaslToken() bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.saslToken
+}
+
+// clientToken 记录一个客户端 Token 帧 返回其序号以及认证机制
+func (s *session) clientToken() (int32, string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.tokenSeq--
+	if s.tokenSeq >= 0 {
+		s.tokenSeq = -1
+	}
+	if len(s.tokens) >= maxPendingTokens {
+		s.tokens = s.tokens[1:]
+	}
+	s.tokens = append(s.tokens, s.tokenSeq)
+	return s.tokenSeq, s.mechanism
+}
+
+// expectServerToken 判断 Broker 发送的下一帧是否为 Token 帧
+//
+// 仅在客户端已经发送了 Token 时成立 SaslHandshake 本身的响应仍按照 Kafka 响应解析
+func (s *session) expectServerToken() bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.saslToken && len(s.tokens) > 0
+}
+
+// serverToken 出队 Broker 回复的 Token 对应的客户端 Token 序号
+func (s *session) serverToken() (int32, bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if len(s.tokens) == 0 {
+		return 0, false
+	}
+	seq := s.tokens[0]
+	s.tokens = s.tokens[1:]
+	return seq, true
+}
+
+// expectFetch 记录一次 Fetch 请求 响应的结构取决于请求版本
+func (s *session) expectFetch(correlationID int32, version int16) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if len(s.fetches) >= maxPendingFetches {
+		clear(s.fetches)
+	}
+	s.fetches[correlationID] = version
+}
+
+// takeFetch 判断 correlationID 是否对应 Fetch 请求 并返回请求版本
+func (s *session) takeFetch(correlationID int32) (int16, bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	version, ok := s.fetches[correlationID]
+	if ok {
+		delete(s.fetches, correlationID)
+	}
+	return version, ok
+}
+
+// expectFiltered 记录一次被过滤的请求
+//
+// acks=0 的 Produce 请求不会有响应 超出上限时直接清空
+func (s *session) expectFiltered(correlationID int32) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if len(s.filtered) >= maxPendingFiltered {
+		clear(s.filtered)
+	}
+	s.filtered[correlationID] = struct{}{}
+}
+
+// takeFiltered 判断 correlationID 对应的请求是否已被过滤
+func (s *session) takeFiltered(correlationID int32) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	_, ok := s.filtered[correlationID]
+	if ok {
+		delete(s.filtered, correlationID)
+	}
+	return ok
+}
+
+// expectApiVersions 记录一次 ApiVersions 请求
+func (s *session) expectApiVersions(correlationID int32, version int16) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if len(s.pending) >= maxPendingApiVersions {
+		clear(s.pending)
+	}
+	s.pending[correlationID] = version
+}
+
+// takeApiVersions 判断 correlationID 是否对应 ApiVersions 请求 并返回请求版本
+func (s *session) takeApiVersions(correlationID int32) (int16, bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	version, ok := s.pending[correlationID]
+	if ok {
+		delete(s.pending, correlationID)
+	}
+	return version, ok
+}
+
+// setVersions 更新 Broker 支持的版本区间
+func (s *session) setVersions(versions map[apiKey]versionRange) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.versions = versions
+}
+
+// setClient 记录客户端软件信息
+func (s *session) setClient(client *protocol.Client) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.client = client
+}
+
+// getClient 返回客户端软件信息 未观测到 ApiVersions v3+ 请求时返回 nil
+func (s *session) getClient() *protocol.Client {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.client
+}
+
+// versionRange 返回 Broker 对 ak 支持的版本区间 未协商时返回 false
+func (s *session) versionRange(ak apiKey) (versionRange, bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	vr, ok := s.versions[ak]
+	return vr, ok
+}
+
+// supported 判断 Broker 是否支持 ak 的 version 版本
+//
+// 尚未观测到 ApiVersions 协商时一律认为支持
+func (s *session) supported(ak apiKey, version int16) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.versions == nil {
+		return true
+	}
+	vr, ok := s.versions[ak]
+	if !ok {
+		return false
+	}
+	return version >= vr.min && version <= vr.max
+}
+
+// decodeApiVersionsResponse 解析 ApiVersions 响应 Body
+//
+// 为了兼容旧版本客户端 ApiVersions 响应 Header 固定为 v0 版本（不携带 tagged fields）
+// Body 布局如下
+//
+// # v0-v2
+// - error_code(int16)
+// - api_keys(array)
+//   - api_key(int16)
+//   - min_version(int16)
+//   - max_version(int16)
+//
+// # v3+（flexible）
+// - error_code(int16)
+// - api_keys(compact_array)
+//   - api_key(int16)
+//   - min_version(int16)
+//   - max_version(int16)
+//   - tagged_fields
+func decodeApiVersionsResponse(b []byte, version int16) (map[apiKey]versionRange, error) {
+	if len(b) < 2 {
+		return nil, errMalformedApiVersions
+	}
+	b = b[2:]
+
+	flexible := version >= 3
+	var n int
+	if flexible {
+		l, offset := binary.Uvarint(b)
+		if offset <= 0 || l == 0 {
+			return nil, errMalformedApiVersions
+		}
+		n = int(l - 1)
+		b = b[offset:]
+	} else {
+		if len(b) < 4 {
+			return nil, errMalformedApiVersions
+		}
+		n = int(int32(binary.BigEndian.Uint32(b[:4])))
+		b = b[4:]
+	}
+
+	// 目前 API 数量不足 100 个 超出即认为非法
+	if n < 0 || n > 256 {
+		return nil, errMalformedApiVersions
+	}
+
+	versions := make(map[apiKey]versionRange, n)
+	for i := 0; i < n; i++ {
+		if len(b) < 6 {
+			return nil, errMalformedApiVersions
+		}
+		ak := apiKey(binary.BigEndian.Uint16(b[:2]))
+		versions[ak] = versionRange{
+			min: int16(binary.BigEndian.Uint16(b[2:4])),
+			max: int16(binary.BigEndian.Uint16(b[4:6])),
+		}
+		b = b[6:]
+
+		if flexible {
+			// 跳过 tagged_fields 仅支持空 tagged_fields
+			tags, offset := binary.Uvarint(b)
+			if offset <= 0 || tags != 0 {
+				return nil, errMalformedApiVersions
+			}
+			b = b[offset:]
+		}
+	}
+	return versions, nil
+}
+
+// decodeApiVersionsRequest 解析 ApiVersions v3+ 请求 Body 中的客户端软件信息
+//
+// Request Header v2 在 client_id 之后还携带 tagged_fields Body 布局如下
+// - client_software_name(compact_string)
+// - client_software_version(compact_string)
+// - tagged_fields
+func decodeApiVersionsRequest(b []byte) (*protocol.Client, error) {
+	tags, n := binary.Uvarint(b)
+	if n <= 0 || tags != 0 {
+		return nil, errMalformedApiVersions // 客户端通常不会携带 header tagged fields
+	}
+	b = b[n:]
+
+	// decodeCompactStringType 会过滤掉 `.` 等字符 版本号需要保留原始内容
+	var fields [2]string
+	for i := range fields {
+		l, n := binary.Uvarint(b)
+		if n <= 0 || l == 0 || n+int(l-1) > len(b) {
+			return nil, errDecodeCompactString
+		}
+		fields[i] = string(b[n : n+int(l-1)])
+		b = b[n+int(l-1):]
+	}
+	return protocol.NewClient(fields[0], fields[1]), nil
+}
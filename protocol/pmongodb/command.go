@@ -82,3 +82,16 @@ var opcodes = map[opcode]string{
 	opcodeDelete:     "DELETE",
 	opcodeKillCursor: "KILL_CURSORS",
 }
+
+// OP_MSG flagBits
+// https://www.mongodb.com/docs/manual/reference/mongodb-wire-protocol/#flag-bits
+const (
+	// flagChecksumPresent 消息末尾携带 4 字节的 CRC-32C 校验和
+	flagChecksumPresent uint32 = 1 << 0
+
+	// flagMoreToCome 发送方还会继续发送消息 接收方无需响应
+	//
+	// 请求携带此标识表示为不需要确认的写入（w:0）
+	// 响应携带此标识表示为 exhaust 流（请求携带 exhaustAllowed）服务端会持续推送后续响应
+	flagMoreToCome uint32 = 1 << 1
+)
@@ -19,8 +19,6 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/pkg/errors"
-
 	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/zerocopy"
@@ -32,14 +30,16 @@ const (
 	PROTO = "MongoDB"
 )
 
-func newError(format string, args ...any) error {
-	format = "mongodb/decoder: " + format
-	return errors.Errorf(format, args...)
+func newError(code protocol.ErrorCode, format string, args ...any) error {
+	return protocol.NewDecodeError(socket.L7ProtoMongoDB, code, "mongodb/decoder: "+format, args...)
 }
 
 var (
-	errDecodeInt32  = newError("decode int32 bytes failed")
-	errDecodeHeader = newError("decode header failed")
+	errDecodeInt32     = newError(protocol.ErrCodeHeaderTooShort, "decode int32 bytes failed")
+	errInt32Overflow   = newError(protocol.ErrCodeLengthOverflow, "int32 overflow")
+	errHeaderTooShort  = newError(protocol.ErrCodeHeaderTooShort, "header too short")
+	errPayloadOverflow = newError(protocol.ErrCodeLengthOverflow, "message length overflow")
+	errUnknownOpCode   = newError(protocol.ErrCodeResyncFailed, "unknown opcode")
 )
 
 const (
@@ -70,6 +70,17 @@ const (
 	// bsonInt64Type bson.Int64 类型标识
 	bsonInt64Type = 0x12
 
+	// 以下类型仅用于跳过元素时计算长度
+	bsonDocumentType   = 0x03
+	bsonArrayType      = 0x04
+	bsonBinaryType     = 0x05
+	bsonObjectIDType   = 0x07
+	bsonBoolType       = 0x08
+	bsonDatetimeType   = 0x09
+	bsonNullType       = 0x0A
+	bsonTimestampType  = 0x11
+	bsonDecimal128Type = 0x13
+
 	// bsonBodySection body section 起始标识
 	bsonBodySection = 0x00
 )
@@ -87,6 +98,12 @@ const (
 
 const (
 	OptEnableResponseCode = "enableResponseCode"
+	OptEnableQueryShape   = "enableQueryShape"
+)
+
+var (
+	responseCodeDecodedTotal = protocol.NewOptionCounter(socket.L7ProtoMongoDB, OptEnableResponseCode, "decoded")
+	queryShapeExtractedTotal = protocol.NewOptionCounter(socket.L7ProtoMongoDB, OptEnableQueryShape, "extracted")
 )
 
 type decoder struct {
@@ -102,15 +119,41 @@ type decoder struct {
 	payloadConsumed       int
 	bodySectionSize       int
 	bodySectionDrainBytes int
-
-	enableRspCode bool
+	flagBits              uint32
+	txnKey                txnKey
+	txnTracker            *txnTracker
+	concern               concern
+	queryShape            string
+	sections              sectionWalker
+
+	// exhaustTo 上一个携带 moreToCome 标识的响应 ID
+	// exhaust 流中后续响应的 responseTo 指向的是上一个响应而非请求
+	exhaustTo int32
+
+	enableRspCode    bool
+	enableQueryShape bool
+	client           *protocol.Client // 握手命令中解析到的驱动信息
+	handshaked       bool             // 仅尝试解析链接中的首个请求 避免后续请求重复遍历文档
+	guard            protocol.PayloadGuard
+	ctx              *protocol.ConnContext
+	release          func()
 }
 
 func NewDecoder(st socket.Tuple, _ socket.Port, opts common.Options) protocol.Decoder {
+	return newDecoder(st, opts, protocol.NewConnContext(0), newTxnTracker(), nil)
+}
+
+func newDecoder(st socket.Tuple, opts common.Options, ctx *protocol.ConnContext, txns *txnTracker, release func()) *decoder {
 	enableRspCode, _ := opts.GetBool(OptEnableResponseCode)
+	enableQueryShape, _ := opts.GetBool(OptEnableQueryShape)
 	return &decoder{
-		st:            st.ToRaw(),
-		enableRspCode: enableRspCode,
+		st:               st.ToRaw(),
+		txnTracker:       txns,
+		enableRspCode:    enableRspCode,
+		enableQueryShape: enableQueryShape,
+		guard:            protocol.NewPayloadGuard(socket.L7ProtoMongoDB, opts, maxPayloadSize),
+		ctx:              ctx,
+		release:          release,
 	}
 }
 
@@ -120,6 +163,11 @@ func (d *decoder) reset() {
 	d.payloadConsumed = 0
 	d.bodySectionSize = 0
 	d.bodySectionDrainBytes = 0
+	d.flagBits = 0
+	d.txnKey = txnKey{}
+	d.concern = concern{}
+	d.queryShape = ""
+	d.sections.reset()
 	d.sourceCmd = sourceCommand{}
 	d.msgHdr = nil
 }
@@ -127,6 +175,10 @@ func (d *decoder) reset() {
 // Free 释放持有的资源
 func (d *decoder) Free() {
 	d.msgHdr = nil
+	if d.release != nil {
+		d.release()
+		d.release = nil
+	}
 }
 
 // Decode 持续从 zerocopy.Reader 解析 MongoDB 协议数据流，构建并返回 RoundTrip 对象
@@ -180,9 +232,16 @@ func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error)
 
 // decode 真正的解析入口
 func (d *decoder) decode(b []byte) (*role.Object, error) {
+	// 排空被跳过的消息后 剩余字节从下一条消息的 header 开始解析
+	if d.guard.Skipping() {
+		if _, b = d.guard.Drain(b); len(b) == 0 {
+			return nil, nil
+		}
+	}
+
 	if d.state == stateDecodeHeader {
 		if len(b) < headerLength {
-			return nil, errDecodeHeader
+			return nil, errHeaderTooShort
 		}
 
 		msgHdr, err := d.decodeHeader(b[:headerLength])
@@ -190,6 +249,13 @@ func (d *decoder) decode(b []byte) (*role.Object, error) {
 			return nil, err
 		}
 
+		// 超长的消息不做解析 按照 messageLength 整体跳过
+		if d.guard.Exceeded(int(msgHdr.length)) {
+			d.guard.Skip(int(msgHdr.length) - headerLength)
+			d.guard.Drain(b[headerLength:])
+			return nil, nil
+		}
+
 		if msgHdr.isRequest() {
 			d.reqTime = d.t0
 		}
@@ -216,6 +282,23 @@ func (d *decoder) archive() *role.Object {
 			return nil
 		}
 
+		// 携带 moreToCome 的请求服务端不会响应（如 w:0 写入）归档后只会一直占用匹配队列
+		if d.flagBits&flagMoreToCome != 0 {
+			return nil
+		}
+
+		// 未声明 $db 的请求（如旧版本驱动的 OP_QUERY）沿用链接最近一次声明的 $db
+		if d.sourceCmd.source != "" {
+			d.ctx.Set(protocol.CtxDatabase, d.sourceCmd.source)
+		} else {
+			d.sourceCmd.source = d.ctx.Get(protocol.CtxDatabase)
+		}
+
+		var txn *Transaction
+		if !d.txnKey.IsEmpty() {
+			txn = d.txnTracker.Track(d.txnKey, d.sourceCmd.cmdName, d.reqTime)
+		}
+
 		obj := role.NewRequestObject(&Request{
 			Host:       d.st.SrcIP,
 			Port:       d.st.SrcPort,
@@ -228,10 +311,29 @@ func (d *decoder) archive() *role.Object {
 			CmdValue:   d.sourceCmd.cmdValue,
 			Size:       d.payloadConsumed,
 			Time:       d.reqTime,
+			Txn:        txn,
+			Client:     d.client,
+
+			ReadConcern:  d.concern.read,
+			WriteConcern: d.concern.write,
+			QueryShape:   d.queryShape,
+			Sequences:    d.sections.sequences(),
 		})
 		return obj
 	}
 
+	// exhaust 流（如 hello/heartbeat 的 streaming 模式）中仅有首个响应对应着请求
+	// 后续响应均无请求与之配对 直接丢弃
+	continued := d.exhaustTo != 0 && d.msgHdr.rspTo == d.exhaustTo
+	if d.flagBits&flagMoreToCome != 0 {
+		d.exhaustTo = d.msgHdr.reqID
+	} else {
+		d.exhaustTo = 0
+	}
+	if continued {
+		return nil
+	}
+
 	obj := role.NewResponseObject(&Response{
 		Host:    d.st.SrcIP,
 		Port:    d.st.SrcPort,
@@ -284,7 +386,7 @@ func (h msgHeader) isValid() bool {
 // - opcode: 操作类型标识符（如 OP_MSG=2013、OP_REPLY=1）
 func (d *decoder) decodeHeader(b []byte) (*msgHeader, error) {
 	if len(b) < headerLength {
-		return nil, errDecodeHeader
+		return nil, errHeaderTooShort
 	}
 
 	length, err := decodeInt32(b[:4])
@@ -311,7 +413,10 @@ func (d *decoder) decodeHeader(b []byte) (*msgHeader, error) {
 		opCode: opCode,
 	}
 	if !hdr.isValid() {
-		return nil, errDecodeHeader
+		if hdr.length > maxPayloadSize || hdr.length < 0 {
+			return nil, errPayloadOverflow
+		}
+		return nil, errUnknownOpCode
 	}
 	return hdr, nil
 }
@@ -381,6 +486,22 @@ func (d *decoder) decodeBodySection(b []byte) {
 	d.bodySectionSize += r - l // 记录已经消费的 body section 长度
 
 	if d.msgHdr.isRequest() {
+		// lsid/txnNumber/concern 以及握手命令的 client metadata 位于顶层文档 仅在首个分片中解析
+		if l == bsonGapKeyValue {
+			d.txnKey = decodeTxnKey(b[l:r])
+			d.concern = decodeConcern(b[l:r])
+			if d.enableQueryShape {
+				d.queryShape = decodeQueryShape(b[l:r])
+				if d.queryShape != "" {
+					queryShapeExtractedTotal.Inc()
+				}
+			}
+			if !d.handshaked {
+				d.client = decodeClientMetadata(b[l:r])
+				d.handshaked = true
+			}
+		}
+
 		sc := decodeSourceCommand(b[l:r])
 		if sc.source != "" {
 			d.sourceCmd.source = sc.source
@@ -401,6 +522,9 @@ func (d *decoder) decodeBodySection(b []byte) {
 		oc := decodeOkCode(b[l:r])
 		d.okCode.ok = oc.ok
 		d.okCode.code = oc.code
+		if oc.ok != -1 || oc.code != -1 {
+			responseCodeDecodedTotal.Inc()
+		}
 	}
 }
 
@@ -428,8 +552,22 @@ func (d *decoder) decodePayload(b []byte) bool {
 	// 从 MongoDB 3.6 开始 OP_MSG 支持传输任意 BSON 数据
 	// OP_QUERY 不再做兼容支持
 	if opcode(d.msgHdr.opCode) == opcodeMsg {
+		// flagBits 位于 payload 起始的 4 字节
+		if d.payloadConsumed == headerLength && len(b) >= 4 {
+			d.flagBits = binary.LittleEndian.Uint32(b[:4])
+			if d.msgHdr.isRequest() {
+				d.sections.begin(int(d.msgHdr.length)-headerLength-4, d.flagBits)
+				d.sections.feed(b[4:])
+			}
+		} else {
+			d.sections.feed(b)
+		}
 		d.decodeBodySection(b)
 	}
+	if opcode(d.msgHdr.opCode) == opcodeQuery && !d.handshaked && d.payloadConsumed == headerLength {
+		d.client = decodeClientMetadata(decodeQueryDocument(b))
+		d.handshaked = true
+	}
 
 	n := d.payloadConsumed + len(b)
 	if n == int(d.msgHdr.length) {
@@ -454,7 +592,7 @@ func decodeInt32(b []byte) (int32, error) {
 
 	n := int64(binary.LittleEndian.Uint32(b))
 	if n > math.MaxInt32 {
-		return 0, errDecodeInt32
+		return 0, errInt32Overflow
 	}
 	return int32(n), nil
 }
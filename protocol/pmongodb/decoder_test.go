@@ -21,11 +21,13 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/splitio"
 	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol"
 	"github.com/packetd/packetd/protocol/role"
 )
 
@@ -779,6 +781,203 @@ func TestDecodeResponse(t *testing.T) {
 	}
 }
 
+func buildFlagMessage(doc bson.D, reqID, rspTo int32, flags uint32) []byte {
+	payload := bsonDocBytes(doc)
+	msg := make([]byte, 21+len(payload))
+	binary.LittleEndian.PutUint32(msg[0:4], uint32(len(msg)))
+	binary.LittleEndian.PutUint32(msg[4:8], uint32(reqID))
+	binary.LittleEndian.PutUint32(msg[8:12], uint32(rspTo))
+	binary.LittleEndian.PutUint32(msg[12:16], uint32(opcodeMsg))
+	binary.LittleEndian.PutUint32(msg[16:20], flags)
+	copy(msg[21:], payload)
+	return msg
+}
+
+func TestDecodeMoreToCome(t *testing.T) {
+	var st socket.Tuple
+	var t0 time.Time
+
+	t.Run("UnacknowledgedWrite", func(t *testing.T) {
+		d := NewDecoder(st, 0, common.NewOptions())
+		doc := bson.D{
+			{Key: "insert", Value: "users"},
+			{Key: "$db", Value: "test"},
+		}
+
+		objs, err := d.Decode(zerocopy.NewBuffer(buildFlagMessage(doc, 1, 0, flagMoreToCome)), t0)
+		assert.NoError(t, err)
+		assert.Nil(t, objs)
+
+		objs, err = d.Decode(zerocopy.NewBuffer(buildFlagMessage(doc, 2, 0, 0)), t0)
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+		assert.Equal(t, int32(2), objs[0].Obj.(*Request).ID)
+	})
+
+	t.Run("ExhaustStream", func(t *testing.T) {
+		d := NewDecoder(st, 0, common.NewOptions())
+		doc := bson.D{{Key: "ok", Value: 1.0}}
+
+		tests := []struct {
+			reqID int32
+			rspTo int32
+			flags uint32
+			id    int32 // 0 表示不产生响应
+		}{
+			{reqID: 10, rspTo: 1, flags: flagMoreToCome, id: 1},
+			{reqID: 11, rspTo: 10, flags: flagMoreToCome},
+			{reqID: 12, rspTo: 11},
+			{reqID: 13, rspTo: 2, id: 2},
+		}
+		for _, tt := range tests {
+			objs, err := d.Decode(zerocopy.NewBuffer(buildFlagMessage(doc, tt.reqID, tt.rspTo, tt.flags)), t0)
+			assert.NoError(t, err)
+			if tt.id == 0 {
+				assert.Nil(t, objs)
+				continue
+			}
+			assert.Len(t, objs, 1)
+			assert.Equal(t, tt.id, objs[0].Obj.(*Response).ID)
+		}
+	})
+}
+
+func TestDecodeConnDatabase(t *testing.T) {
+	var st socket.Tuple
+	var t0 time.Time
+
+	ctx := protocol.NewConnContext(0)
+	d := newDecoder(st, common.NewOptions(), ctx, newTxnTracker(), nil)
+	objs, err := d.Decode(zerocopy.NewBuffer(buildFlagMessage(bson.D{
+		{Key: "find", Value: "users"},
+		{Key: "$db", Value: "orders"},
+	}, 1, 0, 0)), t0)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.Equal(t, "orders", ctx.Get(protocol.CtxDatabase))
+
+	// 未声明 $db 的请求沿用链接最近一次声明的 $db
+	objs, err = d.Decode(zerocopy.NewBuffer(buildFlagMessage(bson.D{
+		{Key: "count", Value: "users"},
+	}, 2, 0, 0)), t0)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.Equal(t, "orders", objs[0].Obj.(*Request).Source)
+}
+
+func TestDecodeSkipOversized(t *testing.T) {
+	var st socket.Tuple
+	var t0 time.Time
+
+	opts := common.NewOptions()
+	opts.Merge(protocol.OptMaxPayloadSize, 64)
+	d := NewDecoder(st, 0, opts)
+
+	oversized := buildFlagMessage(buildNDocs(10), 1, 0, 0)
+	assert.Greater(t, len(oversized), 64)
+
+	// 超长消息跨越两次读取 第二次读取的尾部紧跟着下一条消息
+	objs, err := d.Decode(zerocopy.NewBuffer(oversized[:40]), t0)
+	assert.NoError(t, err)
+	assert.Nil(t, objs)
+
+	doc := bson.D{
+		{Key: "find", Value: "users"},
+		{Key: "$db", Value: "test"},
+	}
+	b := append(oversized[40:], buildFlagMessage(doc, 2, 0, 0)...)
+	objs, err = d.Decode(zerocopy.NewBuffer(b), t0)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.Equal(t, int32(2), objs[0].Obj.(*Request).ID)
+	assert.Equal(t, "users", objs[0].Obj.(*Request).Collection)
+}
+
+// transactionDocs 返回同一事务内的 insert / update / commitTransaction 请求
+func transactionDocs() []bson.D {
+	lsid := bson.D{{Key: "id", Value: primitive.Binary{Subtype: 4, Data: []byte{
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+		0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, 0x10,
+	}}}}
+	return []bson.D{
+		{
+			{Key: "insert", Value: "users"},
+			{Key: "ordered", Value: true},
+			{Key: "lsid", Value: lsid},
+			{Key: "txnNumber", Value: int64(3)},
+			{Key: "startTransaction", Value: true},
+			{Key: "autocommit", Value: false},
+			{Key: "$db", Value: "test"},
+		},
+		{
+			{Key: "update", Value: "users"},
+			{Key: "lsid", Value: lsid},
+			{Key: "txnNumber", Value: int64(3)},
+			{Key: "autocommit", Value: false},
+			{Key: "$db", Value: "test"},
+		},
+		{
+			{Key: "commitTransaction", Value: 1},
+			{Key: "lsid", Value: lsid},
+			{Key: "txnNumber", Value: int64(3)},
+			{Key: "autocommit", Value: false},
+			{Key: "$db", Value: "admin"},
+		},
+	}
+}
+
+func TestDecodeTransaction(t *testing.T) {
+	var st socket.Tuple
+	d := NewDecoder(st, 0, common.NewOptions())
+	docs := transactionDocs()
+
+	t0 := time.Unix(1, 0)
+	var objs []*role.Object
+	for i, doc := range docs {
+		var err error
+		objs, err = d.Decode(zerocopy.NewBuffer(buildFlagMessage(doc, int32(i+1), 0, 0)), t0.Add(time.Duration(i)*time.Second))
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+		if i < len(docs)-1 {
+			assert.Nil(t, objs[0].Obj.(*Request).Txn)
+		}
+	}
+
+	txn := objs[0].Obj.(*Request).Txn
+	assert.Equal(t, &Transaction{
+		LSID:       "0102030405060708090a0b0c0d0e0f10",
+		TxnNumber:  3,
+		Operations: 2,
+		StartTime:  t0,
+		Outcome:    "commit",
+	}, txn)
+}
+
+func TestDecodeTransactionAcrossConns(t *testing.T) {
+	// 驱动连接池中同一事务的语句经由不同链接发送
+	txns := newTxnTracker()
+	decoders := []*decoder{
+		newDecoder(socket.Tuple{SrcPort: 50001, DstPort: 27017}, common.NewOptions(), protocol.NewConnContext(0), txns, nil),
+		newDecoder(socket.Tuple{SrcPort: 50002, DstPort: 27017}, common.NewOptions(), protocol.NewConnContext(0), txns, nil),
+	}
+
+	t0 := time.Unix(1, 0)
+	var objs []*role.Object
+	for i, doc := range transactionDocs() {
+		var err error
+		d := decoders[i%len(decoders)]
+		objs, err = d.Decode(zerocopy.NewBuffer(buildFlagMessage(doc, int32(i+1), 0, 0)), t0.Add(time.Duration(i)*time.Second))
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+	}
+
+	txn := objs[0].Obj.(*Request).Txn
+	assert.NotNil(t, txn)
+	assert.Equal(t, 2, txn.Operations)
+	assert.Equal(t, t0, txn.StartTime)
+	assert.Empty(t, txns.txns)
+}
+
 func buildWithHeader(payload []byte) []byte {
 	header := make([]byte, 16)
 	binary.LittleEndian.PutUint32(header, uint32(16+len(payload)))
@@ -0,0 +1,89 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pmongodb
+
+import (
+	"bytes"
+
+	"github.com/packetd/packetd/protocol"
+)
+
+// maxHandshakeScanElements 解析 client metadata 时最多遍历的元素数量
+const maxHandshakeScanElements = 16
+
+// decodeClientMetadata 解析 hello/isMaster 握手命令中的驱动信息
+//
+// 驱动在建链后的首个命令中会携带如下 metadata
+//
+//	{ hello: 1, client: { driver: { name: "mongo-go-driver", version: "1.12.1" }, os: {...} } }
+//
+// b 为完整的 bson 文档（包含长度前缀）
+func decodeClientMetadata(b []byte) *protocol.Client {
+	if len(b) < 5 {
+		return nil
+	}
+
+	var client *protocol.Client
+	walkBsonElements(b[4:], maxHandshakeScanElements, func(typ byte, name, val []byte) bool {
+		if typ != bsonDocumentType || string(name) != "client" || len(val) <= 4 {
+			return false
+		}
+
+		walkBsonElements(val[4:], maxHandshakeScanElements, func(typ byte, name, val []byte) bool {
+			if typ != bsonDocumentType || string(name) != "driver" || len(val) <= 4 {
+				return false
+			}
+
+			var driver, version string
+			walkBsonElements(val[4:], maxHandshakeScanElements, func(typ byte, name, val []byte) bool {
+				if typ != bsonStringType || len(val) < 5 {
+					return false
+				}
+				switch string(name) {
+				case "name":
+					driver = string(val[4 : len(val)-1])
+				case "version":
+					version = string(val[4 : len(val)-1])
+				}
+				return driver != "" && version != ""
+			})
+			client = protocol.NewClient(driver, version)
+			return true
+		})
+		return true
+	})
+	return client
+}
+
+// decodeQueryDocument 返回 OP_QUERY 中的查询文档 布局如下
+//
+// - flags(int32)
+// - fullCollectionName(cstring)
+// - numberToSkip(int32)
+// - numberToReturn(int32)
+// - query(document)
+//
+// 新版本驱动仍然使用 OP_QUERY 发送建链时的首个 isMaster/hello 命令
+func decodeQueryDocument(b []byte) []byte {
+	if len(b) < 4 {
+		return nil
+	}
+	b = b[4:]
+
+	idx := bytes.IndexByte(b, bsonStringEnd)
+	if idx < 0 || len(b) < idx+1+8 {
+		return nil
+	}
+	return b[idx+1+8:]
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pmongodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol"
+)
+
+func buildHelloDoc(driver bson.D) bson.D {
+	return bson.D{
+		{Key: "hello", Value: 1},
+		{Key: "client", Value: bson.D{
+			{Key: "application", Value: bson.D{{Key: "name", Value: "billing"}}},
+			{Key: "driver", Value: driver},
+			{Key: "os", Value: bson.D{{Key: "type", Value: "linux"}}},
+		}},
+		{Key: "$db", Value: "admin"},
+	}
+}
+
+func TestDecodeClientMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  bson.D
+		want *protocol.Client
+	}{
+		{
+			name: "GoDriver",
+			doc:  buildHelloDoc(bson.D{{Key: "name", Value: "mongo-go-driver"}, {Key: "version", Value: "v1.12.1"}}),
+			want: &protocol.Client{Name: "mongo-go-driver", Version: "1.12.1"},
+		},
+		{
+			name: "WithoutVersion",
+			doc:  buildHelloDoc(bson.D{{Key: "name", Value: "PyMongo"}}),
+			want: &protocol.Client{Name: "pymongo"},
+		},
+		{
+			name: "WithoutClient",
+			doc:  bson.D{{Key: "find", Value: "users"}, {Key: "$db", Value: "test"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, decodeClientMetadata(bsonDocBytes(tt.doc)))
+		})
+	}
+}
+
+func TestDecodeQueryDocument(t *testing.T) {
+	doc := bsonDocBytes(buildHelloDoc(bson.D{{Key: "name", Value: "nodejs"}, {Key: "version", Value: "6.3.0"}}))
+
+	b := []byte{0x00, 0x00, 0x00, 0x00}
+	b = append(b, "admin.$cmd\x00"...)
+	b = append(b, 0x00, 0x00, 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0xFF)
+	b = append(b, doc...)
+
+	assert.Equal(t, doc, decodeQueryDocument(b))
+	assert.Nil(t, decodeQueryDocument(b[:8]))
+}
+
+func TestDecodeClient(t *testing.T) {
+	var st socket.Tuple
+	d := NewDecoder(st, 0, common.NewOptions())
+
+	hello := buildHelloDoc(bson.D{{Key: "name", Value: "mongo-go-driver"}, {Key: "version", Value: "1.12.1"}})
+	find := bson.D{{Key: "find", Value: "users"}, {Key: "$db", Value: "test"}}
+
+	expected := &protocol.Client{Name: "mongo-go-driver", Version: "1.12.1"}
+	for i, doc := range []bson.D{hello, find} {
+		objs, err := d.Decode(zerocopy.NewBuffer(buildFlagMessage(doc, int32(i+1), 0, 0)), time.Time{})
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+		assert.Equal(t, expected, objs[0].Obj.(*Request).Client)
+	}
+}
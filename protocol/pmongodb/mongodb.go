@@ -24,12 +24,21 @@ import (
 
 func init() {
 	protocol.Register(socket.L7ProtoMongoDB, NewConnPool)
+	protocol.Describe(socket.L7ProtoMongoDB, protocol.Capability{
+		Versions: []string{"OP_MSG"},
+		Options:  []string{OptEnableResponseCode, OptEnableQueryShape, protocol.OptMaxPayloadSize},
+	})
 }
 
 const maxRecordSize = 64
 
 // NewConnPool 创建 MongoDB 协议连接池
+//
+// 同一条链接两个方向的 decoder 共享链接上下文 记录最近一次请求声明的 $db
+// 所有链接共享事务追踪 同一事务经由驱动连接池中的不同链接发送时仍归为同一事务
 func NewConnPool(opts common.Options) protocol.ConnPool {
+	cs := protocol.NewConnContexts(0)
+	txns := newTxnTracker()
 	return protocol.NewL7TCPConnPool(
 		func() role.Matcher {
 			return role.NewListMatcher(maxRecordSize, func(req, rsp *role.Object) bool {
@@ -43,7 +52,10 @@ func NewConnPool(opts common.Options) protocol.ConnPool {
 			}
 		},
 		func(st socket.Tuple, serverPort socket.Port) protocol.Decoder {
-			return NewDecoder(st, serverPort, opts)
+			ctx := cs.Acquire(st, serverPort)
+			return newDecoder(st, opts, ctx, txns, func() {
+				cs.Release(st, serverPort)
+			})
 		},
 	)
 }
@@ -61,6 +73,18 @@ type Request struct {
 	CmdValue   string
 	Size       int
 	Time       time.Time
+	Txn        *Transaction     // 仅事务结束的请求携带
+	Client     *protocol.Client `json:",omitempty"`
+
+	// ReadConcern / WriteConcern 命令中声明的 readConcern.level 以及 writeConcern.w
+	ReadConcern  string `json:",omitempty"`
+	WriteConcern string `json:",omitempty"`
+
+	// QueryShape 开启 OptEnableQueryShape 后解析的查询形状 如 {age,name}
+	QueryShape string `json:",omitempty"`
+
+	// Sequences 文档序列（Kind 1 Section）用于区分单条与批量写入
+	Sequences []DocumentSequence `json:",omitempty"`
 }
 
 // Response MongoDB 响应
@@ -0,0 +1,227 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pmongodb
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	// sectionKindBody Kind 0 Section 即命令文档
+	sectionKindBody = 0x00
+
+	// sectionKindSequence Kind 1 Section 即文档序列
+	sectionKindSequence = 0x01
+
+	// maxSequenceIdentifierSize identifier 最大长度 超出视为非法数据
+	maxSequenceIdentifierSize = 64
+
+	// maxDocumentSequences 单条消息最多记录的文档序列数量
+	maxDocumentSequences = 8
+)
+
+// DocumentSequence OP_MSG 文档序列（Kind 1 Section）
+//
+// 驱动会将 insert/update/delete 的批量参数从命令文档中剥离 以文档序列的形式发送
+// Identifier 为参数名（如 documents / updates / deletes）Documents 为序列中的文档数量
+type DocumentSequence struct {
+	Identifier string
+	Documents  int
+}
+
+type sectionState uint8
+
+const (
+	// sectionStateIdle 未开始或者已经停止遍历
+	sectionStateIdle sectionState = iota
+	sectionStateKind
+	sectionStateBodyLength
+	sectionStateSequenceSize
+	sectionStateIdentifier
+	sectionStateDocumentLength
+	sectionStateSkip
+)
+
+// sectionWalker 按照 Section 边界遍历 OP_MSG payload 并统计文档序列
+//
+// payload 可能跨越多个数据包 仅缓存长度字段以及 identifier 文档内容直接跳过
+// 出现任何不合法的长度时停止遍历 已统计的结果仍然保留
+//
+//	Kind 0: | 0x00 | document(int32 length + ...) |
+//	Kind 1: | 0x01 | int32 size | identifier cstring | document* |
+type sectionWalker struct {
+	state     sectionState
+	next      sectionState // 跳过完成后的状态
+	remain    int          // payload 中尚未遍历的字节数 不含 checksum
+	skip      int
+	seqRemain int // 当前文档序列尚未遍历的字节数
+	buf       []byte
+	seqs      []DocumentSequence
+}
+
+// begin 开始遍历新的消息 size 为 flagBits 之后的 payload 长度
+func (w *sectionWalker) begin(size int, flags uint32) {
+	if flags&flagChecksumPresent != 0 {
+		size -= 4
+	}
+	w.reset()
+	if size > 0 {
+		w.state = sectionStateKind
+		w.remain = size
+	}
+}
+
+// reset 停止遍历 保留 buf 以供复用
+func (w *sectionWalker) reset() {
+	*w = sectionWalker{buf: w.buf[:0]}
+}
+
+// sequences 返回统计到的文档序列
+func (w *sectionWalker) sequences() []DocumentSequence {
+	return w.seqs
+}
+
+func (w *sectionWalker) feed(b []byte) {
+	if w.state == sectionStateIdle {
+		return
+	}
+	if len(b) > w.remain {
+		b = b[:w.remain] // 剩余部分为 checksum 或者下一条消息
+	}
+	w.remain -= len(b)
+
+	for len(b) > 0 && w.state != sectionStateIdle {
+		switch w.state {
+		case sectionStateSkip:
+			n := min(w.skip, len(b))
+			w.skip -= n
+			b = b[n:]
+			if w.skip == 0 {
+				w.state = w.next
+			}
+
+		case sectionStateKind:
+			switch b[0] {
+			case sectionKindBody:
+				w.state = sectionStateBodyLength
+			case sectionKindSequence:
+				w.state = sectionStateSequenceSize
+			default:
+				w.state = sectionStateIdle // 未知的 Section 类型无法继续定位
+			}
+			b = b[1:]
+
+		case sectionStateIdentifier:
+			i := bytes.IndexByte(b, 0)
+			if i < 0 {
+				w.buf = append(w.buf, b...)
+				if len(w.buf) > maxSequenceIdentifierSize {
+					w.state = sectionStateIdle
+				}
+				return
+			}
+			w.buf = append(w.buf, b[:i]...)
+			b = b[i+1:]
+			w.onIdentifier()
+
+		default:
+			var n int
+			var ok bool
+			if n, b, ok = w.readInt32(b); !ok {
+				return
+			}
+			w.onLength(n)
+		}
+	}
+}
+
+// readInt32 读取长度字段 数据不足时缓存至 buf
+func (w *sectionWalker) readInt32(b []byte) (int, []byte, bool) {
+	need := 4 - len(w.buf)
+	if len(b) < need {
+		w.buf = append(w.buf, b...)
+		return 0, nil, false
+	}
+	w.buf = append(w.buf, b[:need]...)
+	n := int(int32(binary.LittleEndian.Uint32(w.buf)))
+	w.buf = w.buf[:0]
+	return n, b[need:], true
+}
+
+func (w *sectionWalker) onLength(n int) {
+	switch w.state {
+	case sectionStateBodyLength:
+		if n < 5 {
+			w.state = sectionStateIdle
+			return
+		}
+		w.skipTo(n-4, sectionStateKind)
+
+	case sectionStateSequenceSize:
+		// size 包含自身的 4 字节以及 identifier
+		if n < 6 {
+			w.state = sectionStateIdle
+			return
+		}
+		w.seqRemain = n - 4
+		w.state = sectionStateIdentifier
+
+	case sectionStateDocumentLength:
+		if n < 5 || n > w.seqRemain {
+			w.state = sectionStateIdle
+			return
+		}
+		w.seqRemain -= n
+		if len(w.seqs) > 0 {
+			w.seqs[len(w.seqs)-1].Documents++
+		}
+		w.skipTo(n-4, w.afterDocument())
+	}
+}
+
+func (w *sectionWalker) onIdentifier() {
+	w.seqRemain -= len(w.buf) + 1
+	if w.seqRemain < 0 {
+		w.state = sectionStateIdle
+		return
+	}
+	if len(w.seqs) >= maxDocumentSequences {
+		w.buf = w.buf[:0]
+		w.skipTo(w.seqRemain, sectionStateKind)
+		return
+	}
+
+	w.seqs = append(w.seqs, DocumentSequence{Identifier: string(w.buf)})
+	w.buf = w.buf[:0]
+	w.state = w.afterDocument()
+}
+
+// afterDocument 当前文档序列遍历完成后回到 Section 类型的解析
+func (w *sectionWalker) afterDocument() sectionState {
+	if w.seqRemain > 0 {
+		return sectionStateDocumentLength
+	}
+	return sectionStateKind
+}
+
+func (w *sectionWalker) skipTo(n int, next sectionState) {
+	if n == 0 {
+		w.state = next
+		return
+	}
+	w.skip = n
+	w.next = next
+	w.state = sectionStateSkip
+}
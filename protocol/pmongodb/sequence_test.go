@@ -0,0 +1,158 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pmongodb
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+)
+
+type testSequence struct {
+	identifier string
+	docs       []bson.D
+}
+
+// buildSequenceMessage 构造携带文档序列的 OP_MSG 请求 开启 checksum 时末尾追加 4 字节
+func buildSequenceMessage(cmd bson.D, flags uint32, seqs ...testSequence) []byte {
+	msg := make([]byte, headerLength)
+	binary.LittleEndian.PutUint32(msg[4:8], 1)
+	binary.LittleEndian.PutUint32(msg[12:16], uint32(opcodeMsg))
+	msg = binary.LittleEndian.AppendUint32(msg, flags)
+
+	msg = append(msg, sectionKindBody)
+	msg = append(msg, bsonDocBytes(cmd)...)
+	for _, seq := range seqs {
+		section := append([]byte(seq.identifier), 0)
+		for _, doc := range seq.docs {
+			section = append(section, bsonDocBytes(doc)...)
+		}
+		msg = append(msg, sectionKindSequence)
+		msg = binary.LittleEndian.AppendUint32(msg, uint32(len(section)+4))
+		msg = append(msg, section...)
+	}
+	if flags&flagChecksumPresent != 0 {
+		msg = append(msg, 0xEF, 0xCD, 0xAB, 0x89)
+	}
+	binary.LittleEndian.PutUint32(msg[0:4], uint32(len(msg)))
+	return msg
+}
+
+func TestDecodeDocumentSequence(t *testing.T) {
+	docs := func(n int) []bson.D {
+		var ds []bson.D
+		for i := 0; i < n; i++ {
+			ds = append(ds, bson.D{{Key: "_id", Value: i}, {Key: "name", Value: "user"}})
+		}
+		return ds
+	}
+
+	tests := []struct {
+		name  string
+		input []byte
+		chunk int // 命令文档之后的数据按照该大小切分数据包 为 0 时一次性读取
+		want  []DocumentSequence
+	}{
+		{
+			name: "SingleInsert",
+			input: buildSequenceMessage(bson.D{{Key: "insert", Value: "users"}, {Key: "$db", Value: "test"}}, 0,
+				testSequence{identifier: "documents", docs: docs(1)}),
+			want: []DocumentSequence{{Identifier: "documents", Documents: 1}},
+		},
+		{
+			name: "BulkInsertWithChecksum",
+			input: buildSequenceMessage(bson.D{{Key: "insert", Value: "users"}, {Key: "$db", Value: "test"}}, flagChecksumPresent,
+				testSequence{identifier: "documents", docs: docs(3)}),
+			want: []DocumentSequence{{Identifier: "documents", Documents: 3}},
+		},
+		{
+			name: "FragmentedUpdates",
+			input: buildSequenceMessage(bson.D{{Key: "update", Value: "users"}, {Key: "$db", Value: "test"}}, 0,
+				testSequence{identifier: "updates", docs: docs(4)}),
+			chunk: 3,
+			want:  []DocumentSequence{{Identifier: "updates", Documents: 4}},
+		},
+		{
+			name: "MultipleSequences",
+			input: buildSequenceMessage(bson.D{{Key: "bulkWrite", Value: 1}, {Key: "$db", Value: "admin"}}, 0,
+				testSequence{identifier: "ops", docs: docs(2)},
+				testSequence{identifier: "nsInfo", docs: docs(1)}),
+			chunk: 7,
+			want: []DocumentSequence{
+				{Identifier: "ops", Documents: 2},
+				{Identifier: "nsInfo", Documents: 1},
+			},
+		},
+		{
+			name:  "BodyOnly",
+			input: buildSequenceMessage(bson.D{{Key: "find", Value: "users"}, {Key: "$db", Value: "test"}}, 0),
+		},
+	}
+
+	var st socket.Tuple
+	var t0 time.Time
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(st, 0, common.NewOptions())
+			chunk := tt.chunk
+			if chunk == 0 {
+				chunk = len(tt.input)
+			}
+
+			// 首个数据包包含命令文档以及文档序列的首个字节 后续分片从 size 字段中间切开
+			bodyLen := int(binary.LittleEndian.Uint32(tt.input[headerLength+5:]))
+			first := min(headerLength+5+bodyLen+2, len(tt.input))
+
+			var req *Request
+			for b := tt.input; len(b) > 0; {
+				n := min(chunk, len(b))
+				if len(b) == len(tt.input) {
+					n = first
+				}
+				objs, err := d.Decode(zerocopy.NewBuffer(b[:n]), t0)
+				assert.NoError(t, err)
+				if len(objs) > 0 {
+					req = objs[0].Obj.(*Request)
+				}
+				b = b[n:]
+			}
+
+			require.NotNil(t, req)
+			assert.Equal(t, len(tt.input), req.Size)
+			assert.Equal(t, tt.want, req.Sequences)
+		})
+	}
+}
+
+func TestSectionWalkerInvalid(t *testing.T) {
+	msg := buildSequenceMessage(bson.D{{Key: "insert", Value: "users"}}, 0,
+		testSequence{identifier: "documents", docs: []bson.D{{{Key: "a", Value: 1}}}})
+
+	// 文档长度超出所属的文档序列
+	binary.LittleEndian.PutUint32(msg[len(msg)-12:], 1024)
+
+	var w sectionWalker
+	w.begin(len(msg)-headerLength-4, 0)
+	w.feed(msg[headerLength+4:])
+	assert.Equal(t, sectionStateIdle, w.state)
+	assert.Equal(t, []DocumentSequence{{Identifier: "documents"}}, w.sequences())
+}
@@ -0,0 +1,129 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pmongodb
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxShapeScanElements 解析 concern/query shape 时顶层文档最多遍历的元素数量
+	maxShapeScanElements = 32
+
+	// maxShapeKeys query shape 最多保留的 key 数量 超出部分以 `...` 代替 避免维度膨胀
+	maxShapeKeys = 8
+)
+
+// concern 命令中声明的一致性级别
+type concern struct {
+	read  string
+	write string
+}
+
+// decodeConcern 解析命令顶层文档中的 readConcern.level 以及 writeConcern.w
+//
+//	{ find: "users", readConcern: { level: "majority" }, writeConcern: { w: "majority", wtimeout: 1000 } }
+//
+// w 可以为字符串（majority / tag）或者数字 数字统一转换为字符串
+func decodeConcern(b []byte) concern {
+	var c concern
+	if len(b) < 5 {
+		return c
+	}
+
+	walkBsonElements(b[4:], maxShapeScanElements, func(typ byte, name, val []byte) bool {
+		if typ != bsonDocumentType || len(val) <= 4 {
+			return false
+		}
+		switch string(name) {
+		case "readConcern":
+			c.read = decodeConcernField(val, "level")
+		case "writeConcern":
+			c.write = decodeConcernField(val, "w")
+		}
+		return c.read != "" && c.write != ""
+	})
+	return c
+}
+
+func decodeConcernField(doc []byte, field string) string {
+	var s string
+	walkBsonElements(doc[4:], maxShapeScanElements, func(typ byte, name, val []byte) bool {
+		if string(name) != field {
+			return false
+		}
+		switch typ {
+		case bsonStringType:
+			if len(val) >= 5 {
+				s = string(val[4 : len(val)-1])
+			}
+		case bsonInt32Type:
+			s = strconv.Itoa(int(int32(binary.LittleEndian.Uint32(val))))
+		case bsonInt64Type:
+			s = strconv.FormatInt(int64(binary.LittleEndian.Uint64(val)), 10)
+		case bsonDoubleType:
+			s = strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(val)), 'f', -1, 64)
+		}
+		return true
+	})
+	return s
+}
+
+// decodeQueryShape 返回查询条件的形状 即 filter/query 文档顶层 key 排序后以 `,` 拼接 不包含任何值
+//
+//	{ find: "users", filter: { name: "john", age: { $gt: 18 } } } => "{age,name}"
+//
+// 空的 filter 返回 "{}" 即全表扫描 命令中不包含 filter/query 时返回空字符串
+//
+// 仅用于按查询形状聚合慢查询 不会泄露文档内容
+func decodeQueryShape(b []byte) string {
+	if len(b) < 5 {
+		return ""
+	}
+
+	var keys []string
+	var found bool
+	walkBsonElements(b[4:], maxShapeScanElements, func(typ byte, name, val []byte) bool {
+		if typ != bsonDocumentType || len(val) <= 4 {
+			return false
+		}
+		if s := string(name); s != "filter" && s != "query" {
+			return false
+		}
+
+		found = true
+		walkBsonElements(val[4:], maxShapeKeys+1, func(_ byte, name, _ []byte) bool {
+			keys = append(keys, string(name))
+			return false
+		})
+		return true
+	})
+	if !found {
+		return ""
+	}
+
+	truncated := len(keys) > maxShapeKeys
+	if truncated {
+		keys = keys[:maxShapeKeys]
+	}
+	sort.Strings(keys)
+	if truncated {
+		keys = append(keys, "...")
+	}
+	return "{" + strings.Join(keys, ",") + "}"
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pmongodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+)
+
+func TestDecodeConcern(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  bson.D
+		want concern
+	}{
+		{
+			name: "ReadConcern",
+			doc: bson.D{
+				{Key: "find", Value: "users"},
+				{Key: "readConcern", Value: bson.D{{Key: "level", Value: "majority"}}},
+			},
+			want: concern{read: "majority"},
+		},
+		{
+			name: "WriteConcernString",
+			doc: bson.D{
+				{Key: "insert", Value: "users"},
+				{Key: "writeConcern", Value: bson.D{{Key: "w", Value: "majority"}, {Key: "wtimeout", Value: int32(1000)}}},
+			},
+			want: concern{write: "majority"},
+		},
+		{
+			name: "WriteConcernNumber",
+			doc: bson.D{
+				{Key: "update", Value: "users"},
+				{Key: "writeConcern", Value: bson.D{{Key: "wtimeout", Value: int32(1000)}, {Key: "w", Value: int32(2)}}},
+				{Key: "readConcern", Value: bson.D{{Key: "level", Value: "local"}}},
+			},
+			want: concern{read: "local", write: "2"},
+		},
+		{
+			name: "None",
+			doc:  bson.D{{Key: "find", Value: "users"}, {Key: "$db", Value: "test"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, decodeConcern(bsonDocBytes(tt.doc)))
+		})
+	}
+}
+
+func TestDecodeQueryShape(t *testing.T) {
+	manyKeys := bson.D{}
+	for _, k := range []string{"j", "i", "h", "g", "f", "e", "d", "c", "b", "a"} {
+		manyKeys = append(manyKeys, bson.E{Key: k, Value: 1})
+	}
+
+	tests := []struct {
+		name string
+		doc  bson.D
+		want string
+	}{
+		{
+			name: "Filter",
+			doc: bson.D{
+				{Key: "find", Value: "users"},
+				{Key: "filter", Value: bson.D{
+					{Key: "name", Value: "john"},
+					{Key: "age", Value: bson.D{{Key: "$gt", Value: 18}}},
+				}},
+			},
+			want: "{age,name}",
+		},
+		{
+			name: "Query",
+			doc: bson.D{
+				{Key: "count", Value: "users"},
+				{Key: "query", Value: bson.D{{Key: "$or", Value: bson.A{bson.D{{Key: "a", Value: 1}}}}}},
+			},
+			want: "{$or}",
+		},
+		{
+			name: "EmptyFilter",
+			doc:  bson.D{{Key: "find", Value: "users"}, {Key: "filter", Value: bson.D{}}},
+			want: "{}",
+		},
+		{
+			name: "Truncated",
+			doc:  bson.D{{Key: "find", Value: "users"}, {Key: "filter", Value: manyKeys}},
+			want: "{c,d,e,f,g,h,i,j,...}",
+		},
+		{
+			name: "WithoutFilter",
+			doc:  bson.D{{Key: "insert", Value: "users"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, decodeQueryShape(bsonDocBytes(tt.doc)))
+		})
+	}
+}
+
+func TestDecodeRequestShape(t *testing.T) {
+	var st socket.Tuple
+	doc := bson.D{
+		{Key: "find", Value: "users"},
+		{Key: "filter", Value: bson.D{{Key: "name", Value: "john"}}},
+		{Key: "readConcern", Value: bson.D{{Key: "level", Value: "majority"}}},
+		{Key: "$db", Value: "test"},
+	}
+
+	d := NewDecoder(st, 0, common.NewOptions())
+	objs, err := d.Decode(zerocopy.NewBuffer(buildFlagMessage(doc, 1, 0, 0)), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	req := objs[0].Obj.(*Request)
+	assert.Equal(t, "majority", req.ReadConcern)
+	assert.Empty(t, req.QueryShape)
+
+	opts := common.NewOptions()
+	opts[OptEnableQueryShape] = true
+	d = NewDecoder(st, 0, opts)
+	objs, err = d.Decode(zerocopy.NewBuffer(buildFlagMessage(doc, 1, 0, 0)), time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.Equal(t, "{name}", objs[0].Obj.(*Request).QueryShape)
+}
@@ -0,0 +1,196 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pmongodb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const (
+	// maxTxnRecords 单个连接池最多同时追踪的事务数量
+	maxTxnRecords = 4096
+
+	// maxTxnScanElements 解析 lsid/txnNumber 时最多遍历的顶层元素数量
+	maxTxnScanElements = 32
+)
+
+const (
+	txnOutcomeCommit = "commit"
+	txnOutcomeAbort  = "abort"
+)
+
+// Transaction MongoDB 事务摘要
+//
+// 仅在事务结束时（commitTransaction/abortTransaction）随请求一同提交
+type Transaction struct {
+	LSID       string
+	TxnNumber  int64
+	Operations int // 事务内的操作数量 不包含 commitTransaction/abortTransaction 本身
+	StartTime  time.Time
+	Outcome    string
+}
+
+type txnKey struct {
+	lsid      string
+	txnNumber int64
+}
+
+func (k txnKey) IsEmpty() bool {
+	return k.lsid == "" || k.txnNumber <= 0
+}
+
+// txnTracker 按照 lsid/txnNumber 对请求进行分组
+//
+// 驱动的连接池中 同一事务的不同语句可能经由不同的链接发送 因此由连接池内的所有链接共享
+type txnTracker struct {
+	mut  sync.Mutex
+	txns map[txnKey]*Transaction
+}
+
+func newTxnTracker() *txnTracker {
+	return &txnTracker{txns: make(map[txnKey]*Transaction)}
+}
+
+// Track 记录一次事务内的请求 当请求为事务结束命令时返回事务摘要
+func (tt *txnTracker) Track(key txnKey, cmdName string, t time.Time) *Transaction {
+	tt.mut.Lock()
+	defer tt.mut.Unlock()
+
+	txn, ok := tt.txns[key]
+	if !ok {
+		// 超限直接清空 避免事务未正常结束导致的内存泄漏
+		if len(tt.txns) >= maxTxnRecords {
+			clear(tt.txns)
+		}
+		txn = &Transaction{
+			LSID:      key.lsid,
+			TxnNumber: key.txnNumber,
+			StartTime: t,
+		}
+		tt.txns[key] = txn
+	}
+
+	switch cmdName {
+	case "commitTransaction":
+		txn.Outcome = txnOutcomeCommit
+	case "abortTransaction":
+		txn.Outcome = txnOutcomeAbort
+	default:
+		txn.Operations++
+		return nil
+	}
+
+	delete(tt.txns, key)
+	return txn
+}
+
+// decodeTxnKey 解析 Body Section 顶层文档中的 lsid/txnNumber 字段
+//
+// lsid 为嵌套文档 { id: BinData(4, <UUID>) } txnNumber 为 int64
+// 仅遍历有限数量的顶层元素 遇到无法识别或不完整的元素即停止
+func decodeTxnKey(b []byte) txnKey {
+	var key txnKey
+	if len(b) < 5 {
+		return key
+	}
+
+	walkBsonElements(b[4:], maxTxnScanElements, func(typ byte, name, val []byte) bool {
+		switch string(name) {
+		case "lsid":
+			if typ == bsonDocumentType && len(val) > 4 {
+				walkBsonElements(val[4:], maxTxnScanElements, func(typ byte, name, val []byte) bool {
+					if typ == bsonBinaryType && string(name) == "id" && len(val) > 5 {
+						key.lsid = hex.EncodeToString(val[5:])
+						return true
+					}
+					return false
+				})
+			}
+		case "txnNumber":
+			if typ == bsonInt64Type {
+				key.txnNumber = int64(binary.LittleEndian.Uint64(val))
+			}
+		}
+		return key.lsid != "" && key.txnNumber != 0
+	})
+	return key
+}
+
+// walkBsonElements 依次遍历 bson 文档中的元素（不包含文档长度前缀）
+//
+// f 返回值表示是否结束流程
+func walkBsonElements(b []byte, limit int, f func(typ byte, name, val []byte) bool) {
+	var cursor int
+	for i := 0; i < limit && cursor < len(b); i++ {
+		typ := b[cursor]
+		if typ == bsonStringEnd { // 文档结束
+			return
+		}
+		cursor++
+
+		end := bytes.IndexByte(b[cursor:], bsonStringEnd)
+		if end < 0 {
+			return
+		}
+		name := b[cursor : cursor+end]
+		cursor += end + 1
+
+		n := bsonValueSize(typ, b[cursor:])
+		if n < 0 || cursor+n > len(b) {
+			return
+		}
+		if f(typ, name, b[cursor:cursor+n]) {
+			return
+		}
+		cursor += n
+	}
+}
+
+// bsonValueSize 返回 bson 值占用的字节数 无法识别时返回 -1
+func bsonValueSize(typ byte, b []byte) int {
+	switch typ {
+	case bsonDoubleType, bsonInt64Type, bsonDatetimeType, bsonTimestampType:
+		return 8
+	case bsonInt32Type:
+		return 4
+	case bsonBoolType:
+		return 1
+	case bsonNullType:
+		return 0
+	case bsonObjectIDType:
+		return 12
+	case bsonDecimal128Type:
+		return 16
+	case bsonStringType, bsonDocumentType, bsonArrayType, bsonBinaryType:
+		if len(b) < 4 {
+			return -1
+		}
+		n := int(int32(binary.LittleEndian.Uint32(b[:4])))
+		switch typ {
+		case bsonStringType:
+			n += 4
+		case bsonBinaryType:
+			n += 5 // 长度 + subtype
+		}
+		if n < 0 {
+			return -1
+		}
+		return n
+	}
+	return -1
+}
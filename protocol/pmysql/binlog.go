@@ -0,0 +1,220 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pmysql
+
+import (
+	"encoding/binary"
+	"strconv"
+	"time"
+
+	"github.com/packetd/packetd/protocol/role"
+)
+
+const (
+	// binlogEventHeaderLength 事件固定头长度
+	// Timestamp(4) + EventType(1) + ServerID(4) + EventSize(4) + LogPos(4) + Flags(2)
+	binlogEventHeaderLength = 19
+
+	// semiSyncIndicator 半同步复制下事件前附带的标识 其后为 1 字节的 ACK 请求标记
+	semiSyncIndicator = 0xEF
+
+	// binlogHeartbeatEvent HEARTBEAT_LOG_EVENT 主库空闲时按 MASTER_HEARTBEAT_PERIOD 发送
+	binlogHeartbeatEvent = 0x1B
+
+	// binlogReportInterval 统计窗口时长 每个窗口归档为一次 RoundTrip
+	binlogReportInterval = 10 * time.Second
+)
+
+type BinlogStreamPacket struct {
+	Events        int
+	Heartbeats    int
+	Bytes         int
+	LogPos        uint32
+	LastEventTime time.Time `json:",omitempty"`
+}
+
+func (p BinlogStreamPacket) Name() string {
+	return "BinlogStream"
+}
+
+// Lag 返回窗口结束时间与最后一个事件在主库上的写入时间之差
+//
+// 事件时间戳仅精确到秒 且依赖主从时钟同步 窗口内没有数据事件时返回 false
+func (p BinlogStreamPacket) Lag(end time.Time) (time.Duration, bool) {
+	if p.LastEventTime.IsZero() {
+		return 0, false
+	}
+	if d := end.Sub(p.LastEventTime); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// binlogStream 记录 COM_BINLOG_DUMP 之后服务端推送的事件流
+type binlogStream struct {
+	paired    bool // 首个窗口与客户端的 COM_BINLOG_DUMP 请求配对
+	continued bool // 上一个数据包长度为 maxPayloadSize 当前数据包为事件的后续分片
+	start     time.Time
+	packet    BinlogStreamPacket
+}
+
+// eventOffset 返回数据包内事件头的偏移 非 binlog 事件时返回 false
+//
+// ┌──────────┬──────────────────────┬───────────────────────────────────────────┐
+// │ OK (1B)  │ SemiSync (2B 可选)   │ Event Header (19B)         │ Event Body   │
+// ├──────────┼──────────────────────┼────────────────────────────┼──────────────┤
+// │   0x00   │ 0xEF + NeedAck       │ Timestamp Type ServerID .. │ ...          │
+// └──────────┴──────────────────────┴────────────────────────────┴──────────────┘
+//
+// 事件头中的 EventSize 与数据包长度严格相等 普通 OKPacket 几乎不可能满足该条件
+func eventOffset(b []byte, payloadLen uint32) (int, bool) {
+	if len(b) == 0 || b[0] != packetOK {
+		return 0, false
+	}
+	off := 1
+	if len(b) > 2 && b[1] == semiSyncIndicator {
+		off = 3
+	}
+	if len(b) < off+binlogEventHeaderLength {
+		return 0, false
+	}
+
+	size := binary.LittleEndian.Uint32(b[off+9 : off+13])
+	return off, size >= binlogEventHeaderLength && size == payloadLen-uint32(off)
+}
+
+// isBinlogEvent 判断服务端数据包是否为 binlog 事件 仅在数据包的首个分段上判断
+func (d *decoder) isBinlogEvent(b []byte) bool {
+	if d.isClient() || d.seqID == 0 || d.payloadConsumed != 0 {
+		return false
+	}
+	_, ok := eventOffset(b, d.payloadLen)
+	return ok
+}
+
+// decodeBinlogPayload 解析事件流中的数据包 仅统计事件头 事件内容直接跳过
+//
+// 事件流以 EOFPacket（非阻塞模式）或 ErrorPacket 结束 此时退出事件流交由常规逻辑处理
+func (d *decoder) decodeBinlogPayload(b []byte) ([]byte, bool, error) {
+	s := d.binlog
+	if d.payloadConsumed == 0 && !s.continued {
+		if b[0] == packetEOF || b[0] == packetError {
+			d.binlog = nil
+			return d.decodePayload(b)
+		}
+		if off, ok := eventOffset(b, d.payloadLen); ok {
+			s.observe(b[off:])
+		}
+	}
+
+	d.role = role.Response
+	n := d.payloadConsumed + uint32(len(b))
+	if n < d.payloadLen {
+		d.payloadConsumed = n
+		d.drainBytes += len(b)
+		return nil, false, nil
+	}
+
+	consumed := d.payloadLen - d.payloadConsumed
+	d.payloadConsumed += consumed
+	d.drainBytes += int(consumed)
+	d.state = stateDecodeHeader
+	s.continued = d.payloadLen == maxPayloadSize
+	return b[consumed:], s.due(d.t0), nil
+}
+
+func (s *binlogStream) observe(hdr []byte) {
+	ts := binary.LittleEndian.Uint32(hdr[0:4])
+	s.packet.LogPos = binary.LittleEndian.Uint32(hdr[13:17])
+	if hdr[4] == binlogHeartbeatEvent {
+		s.packet.Heartbeats++
+		return
+	}
+
+	s.packet.Events++
+	// 复制开始时伪造的 ROTATE_EVENT 时间戳为 0
+	if ts > 0 {
+		s.packet.LastEventTime = time.Unix(int64(ts), 0)
+	}
+}
+
+// due 首个事件到达后立即归档 用于衡量 COM_BINLOG_DUMP 的响应耗时 后续按照固定窗口归档
+func (s *binlogStream) due(t time.Time) bool {
+	if s.packet.Events == 0 && s.packet.Heartbeats == 0 {
+		return false
+	}
+	return !s.paired || t.Sub(s.start) >= binlogReportInterval
+}
+
+// archiveBinlog 归档统计窗口
+//
+// 除首个窗口外 服务端 decoder 需要自行构造窗口开始时刻的请求 以完成配对
+func (d *decoder) archiveBinlog() []*role.Object {
+	s := d.binlog
+	packet := s.packet
+	packet.Bytes = d.drainBytes
+
+	var objs []*role.Object
+	if s.paired {
+		objs = append(objs, role.NewRequestObject(&Request{
+			Host:    d.st.DstIP,
+			Port:    d.st.DstPort,
+			Proto:   PROTO,
+			Command: commands[cmdBinlogDump],
+			Time:    s.start,
+			Client:  d.client,
+		}))
+	}
+	objs = append(objs, role.NewResponseObject(&Response{
+		Host:   d.st.SrcIP,
+		Port:   d.st.SrcPort,
+		Proto:  PROTO,
+		Size:   packet.Bytes,
+		Packet: &packet,
+		Time:   d.t0,
+	}))
+
+	s.paired = true
+	s.start = d.t0
+	s.packet = BinlogStreamPacket{LogPos: packet.LogPos}
+	d.reset()
+	return objs
+}
+
+// decodeBinlogDumpStatement 将 COM_BINLOG_DUMP(_GTID) 请求中的 binlog 文件及位置格式化为 `file:pos`
+//
+// COM_BINLOG_DUMP: Pos(4) Flags(2) ServerID(4) Filename(EOF)
+// COM_BINLOG_DUMP_GTID: Flags(2) ServerID(4) NameSize(4) Filename(NameSize) Pos(8) ...
+func decodeBinlogDumpStatement(cmd uint8, b []byte) string {
+	switch cmd {
+	case cmdBinlogDump:
+		if len(b) < 10 {
+			return ""
+		}
+		pos := binary.LittleEndian.Uint32(b[0:4])
+		return string(b[10:]) + ":" + strconv.FormatUint(uint64(pos), 10)
+
+	case cmdBinlogDumpGTID:
+		if len(b) < 10 {
+			return ""
+		}
+		size := int(binary.LittleEndian.Uint32(b[6:10]))
+		if len(b) < 10+size+8 {
+			return ""
+		}
+		pos := binary.LittleEndian.Uint64(b[10+size : 18+size])
+		return string(b[10:10+size]) + ":" + strconv.FormatUint(pos, 10)
+	}
+	return ""
+}
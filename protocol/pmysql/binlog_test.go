@@ -0,0 +1,128 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pmysql
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+func buildPacket(seq uint8, payload []byte) []byte {
+	n := len(payload)
+	b := []byte{byte(n), byte(n >> 8), byte(n >> 16), seq}
+	return append(b, payload...)
+}
+
+func buildBinlogEvent(seq uint8, semiSync bool, ts uint32, typ uint8, logPos uint32, body int) []byte {
+	payload := []byte{packetOK}
+	if semiSync {
+		payload = append(payload, semiSyncIndicator, 0x00)
+	}
+	payload = binary.LittleEndian.AppendUint32(payload, ts)
+	payload = append(payload, typ)
+	payload = binary.LittleEndian.AppendUint32(payload, 1)
+	payload = binary.LittleEndian.AppendUint32(payload, uint32(binlogEventHeaderLength+body))
+	payload = binary.LittleEndian.AppendUint32(payload, logPos)
+	payload = append(payload, 0x00, 0x00)
+	payload = append(payload, make([]byte, body)...)
+	return buildPacket(seq, payload)
+}
+
+func TestDecodeBinlogDump(t *testing.T) {
+	var st socket.Tuple
+	t0 := time.Unix(1751356800, 0)
+
+	payload := []byte{cmdBinlogDump}
+	payload = binary.LittleEndian.AppendUint32(payload, 4)
+	payload = append(payload, 0x00, 0x00)
+	payload = binary.LittleEndian.AppendUint32(payload, 2)
+	payload = append(payload, "mysql-bin.000003"...)
+
+	client := NewDecoder(st, 0, common.NewOptions())
+	objs, err := client.Decode(zerocopy.NewBuffer(buildPacket(0, payload)), t0)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	req := objs[0].Obj.(*Request)
+	assert.Equal(t, "BINLOG_DUMP", req.Command)
+	assert.Equal(t, "mysql-bin.000003:4", req.Statement)
+
+	// 半同步 ACK 不作为请求
+	ack := append([]byte{semiSyncIndicator}, make([]byte, 8)...)
+	objs, err = client.Decode(zerocopy.NewBuffer(buildPacket(0, ack)), t0)
+	assert.NoError(t, err)
+	assert.Empty(t, objs)
+
+	server := NewDecoder(st, 3306, common.NewOptions())
+	decode := func(b []byte, at time.Time) []*role.Object {
+		objs, err := server.Decode(zerocopy.NewBuffer(b), at)
+		assert.NoError(t, err)
+		return objs
+	}
+
+	// 首个事件到达即与 COM_BINLOG_DUMP 配对
+	objs = decode(buildBinlogEvent(1, false, 0, 0x04, 0, 24), t0.Add(time.Millisecond))
+	assert.Len(t, objs, 1)
+	assert.EqualValues(t, role.Response, objs[0].Role)
+	assert.Equal(t, &BinlogStreamPacket{Events: 1, Bytes: 4 + 1 + 19 + 24}, objs[0].Obj.(*Response).Packet)
+
+	// 单次读取包含多个事件 窗口未结束前仅累计
+	var b []byte
+	b = append(b, buildBinlogEvent(2, false, 1751356795, 0x02, 120, 100)...)
+	b = append(b, buildBinlogEvent(3, true, 1751356796, 0x10, 200, 31)...)
+	b = append(b, buildBinlogEvent(4, false, 0, binlogHeartbeatEvent, 200, 20)...)
+	assert.Empty(t, decode(b, t0.Add(5*time.Second)))
+
+	objs = decode(buildBinlogEvent(5, false, 1751356808, 0x10, 231, 31), t0.Add(11*time.Second))
+	assert.Len(t, objs, 2)
+	assert.EqualValues(t, role.Request, objs[0].Role)
+	assert.Equal(t, t0.Add(time.Millisecond), objs[0].Obj.(*Request).Time)
+
+	rsp := objs[1].Obj.(*Response)
+	packet := rsp.Packet.(*BinlogStreamPacket)
+	assert.Equal(t, &BinlogStreamPacket{
+		Events:        3,
+		Heartbeats:    1,
+		Bytes:         len(b) + 4 + 1 + 19 + 31,
+		LogPos:        231,
+		LastEventTime: time.Unix(1751356808, 0),
+	}, packet)
+	lag, ok := packet.Lag(rsp.Time)
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, lag)
+
+	// 事件流结束后恢复常规解析
+	decode(buildPacket(6, eofPacket), t0.Add(12*time.Second))
+	assert.Nil(t, server.(*decoder).binlog)
+}
+
+func TestDecodeBinlogDumpGTIDStatement(t *testing.T) {
+	payload := []byte{0x00, 0x00}
+	payload = binary.LittleEndian.AppendUint32(payload, 2)
+	payload = binary.LittleEndian.AppendUint32(payload, 16)
+	payload = append(payload, "mysql-bin.000007"...)
+	payload = binary.LittleEndian.AppendUint64(payload, 1024)
+	payload = binary.LittleEndian.AppendUint32(payload, 0)
+
+	assert.Equal(t, "mysql-bin.000007:1024", decodeBinlogDumpStatement(cmdBinlogDumpGTID, payload))
+	assert.Empty(t, decodeBinlogDumpStatement(cmdBinlogDumpGTID, payload[:12]))
+	assert.Empty(t, decodeBinlogDumpStatement(cmdBinlogDump, payload[:4]))
+}
@@ -13,6 +13,8 @@
 
 package pmysql
 
+import "strings"
+
 const (
 	cmdQuit            = 0x01 // COM_QUIT 关闭连接请求
 	cmdInitDB          = 0x02 // COM_INIT_DB 切换数据库（等价于 USE database）
@@ -88,3 +90,12 @@ const (
 	packetAuthSwitch  = 0x01 // 认证切换请求（服务端要求客户端更换认证方式）
 	packetLocalInfile = 0xFB // 服务端请求客户端发送本地文件（用于 LOAD DATA LOCAL INFILE 命令）
 )
+
+// parseUseStatement 解析 `USE db` 语句 返回切换后的数据库名称
+func parseUseStatement(statement string) (string, bool) {
+	fields := strings.Fields(strings.TrimSuffix(strings.TrimSpace(statement), ";"))
+	if len(fields) != 2 || !strings.EqualFold(fields[0], "use") {
+		return "", false
+	}
+	return strings.Trim(fields[1], "`"), true
+}
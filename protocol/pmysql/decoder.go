@@ -18,12 +18,11 @@ import (
 	"encoding/binary"
 	"time"
 
-	"github.com/pkg/errors"
-
 	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/bufbytes"
 	"github.com/packetd/packetd/internal/splitio"
+	"github.com/packetd/packetd/internal/tailbuf"
 	"github.com/packetd/packetd/internal/zerocopy"
 	"github.com/packetd/packetd/protocol"
 	"github.com/packetd/packetd/protocol/role"
@@ -33,18 +32,19 @@ const (
 	PROTO = "MySQL"
 )
 
-func newError(format string, args ...any) error {
-	format = "mysql/decoder: " + format
-	return errors.Errorf(format, args...)
+func newError(code protocol.ErrorCode, format string, args ...any) error {
+	return protocol.NewDecodeError(socket.L7ProtoMySQL, code, "mysql/decoder: "+format, args...)
 }
 
 var (
-	errInvalidBytes    = newError("invalid bytes")
-	errDecodeHeader    = newError("decode Header failed")
-	errDecodeResponse  = newError("decode Response failed")
-	errDecodeOKPacket  = newError("decode OKPacket failed")
-	errDecodeErrPacket = newError("decode ErrPacket failed")
-	errDecodeEOFPacket = newError("decode EOFPacket failed")
+	errResyncFailed      = newError(protocol.ErrCodeResyncFailed, "packet boundary lost")
+	errHeaderTooShort    = newError(protocol.ErrCodeHeaderTooShort, "header too short")
+	errPayloadOverflow   = newError(protocol.ErrCodeLengthOverflow, "payload length overflow")
+	errDecodeResponse    = newError(protocol.ErrCodeLengthOverflow, "response length overflow")
+	errDecodeOKPacket    = newError(protocol.ErrCodeHeaderTooShort, "OKPacket too short")
+	errDecodeErrPacket   = newError(protocol.ErrCodeHeaderTooShort, "ErrPacket too short")
+	errDecodeEOFPacket   = newError(protocol.ErrCodeHeaderTooShort, "EOFPacket too short")
+	errMalformedOKPacket = newError(protocol.ErrCodeMalformed, "malformed OKPacket")
 )
 
 // state 记录着 decoder 的处理状态
@@ -63,6 +63,7 @@ const (
 	headerLength = 4
 
 	// maxPayloadSize 单 payload 最大长度 超过此长度服务端会进行切片
+	// 同时也是 OptMaxPayloadSize 允许配置的上限
 	maxPayloadSize = 0xFFFFFF
 
 	// maxStatementSize SQL 语句缓冲区大小
@@ -72,6 +73,9 @@ const (
 	maxErrMsgSize = 256
 )
 
+// statementTruncatedTotal 超出 maxStatementSize 被截断的语句数量 该上限暂不支持配置
+var statementTruncatedTotal = protocol.NewOptionCounter(socket.L7ProtoMySQL, "maxStatementSize", "truncated")
+
 type decoder struct {
 	t0         time.Time
 	st         socket.TupleRaw
@@ -86,23 +90,53 @@ type decoder struct {
 	payloadConsumed uint32
 	drainBytes      int
 	eofPackets      int
-	headers         int
+	continued       bool // 上一个数据包长度为 maxPayloadSize 当前数据包为其后续分片
+
+	// 结果集统计 columns 取自首个数据包 rows / rowBytes 为两个 EOFPacket 之间的数据行
+	columns  int
+	rows     int
+	rowBytes int
 
 	obj        any
 	cmdType    uint8
 	packetType uint8
 	statement  *bufbytes.Bytes
+	client     *protocol.Client // 链接握手阶段解析到的客户端指纹
 
-	tail       []byte // 尾部数据拼接 仅允许拼接一次 避免上一轮切割了部分数据
+	tail       tailbuf.Buffer // 尾部数据拼接 仅允许拼接一次 避免上一轮切割了部分数据
 	partial    uint8
 	waitForRsp bool
+
+	binlog *binlogStream // 仅 server 端 识别到 binlog 事件流后不再按照查询响应解析
+
+	guard         protocol.PayloadGuard
+	skipContinued bool // 被跳过的数据包长度为 maxPayloadSize 其后续分片同样需要跳过
+
+	progressBytes int       // 响应每传输该字节数输出一次进度 为 0 时不输出
+	progressed    int       // 当前响应已输出的进度次数
+	rspStart      time.Time // 当前响应首个数据包的时间
+
+	ctx     *protocol.ConnContext
+	release func()
 }
 
-func NewDecoder(st socket.Tuple, serverPort socket.Port, _ common.Options) protocol.Decoder {
+// NewDecoder 创建 MySQL 解码器
+//
+// 独立创建的 decoder 无法与另一个方向共享链接上下文 链接池内应使用 newDecoder
+func NewDecoder(st socket.Tuple, serverPort socket.Port, opts common.Options) protocol.Decoder {
+	return newDecoder(st, serverPort, opts, protocol.NewConnContext(0), nil)
+}
+
+func newDecoder(st socket.Tuple, serverPort socket.Port, opts common.Options, ctx *protocol.ConnContext, release func()) *decoder {
+	progressBytes, _ := opts.GetInt(protocol.OptProgressBytes)
 	return &decoder{
-		st:         st.ToRaw(),
-		serverPort: serverPort,
-		statement:  bufbytes.New(maxStatementSize), // 执行语句 buffer
+		st:            st.ToRaw(),
+		serverPort:    serverPort,
+		statement:     bufbytes.New(maxStatementSize), // 执行语句 buffer
+		guard:         protocol.NewPayloadGuard(socket.L7ProtoMySQL, opts, maxPayloadSize),
+		progressBytes: progressBytes,
+		ctx:           ctx,
+		release:       release,
 	}
 }
 
@@ -116,11 +150,15 @@ func (d *decoder) reset() {
 	d.drainBytes = 0
 	d.cmdType = 0
 	d.seqID = 0
-	d.headers = 0
-	d.tail = nil
+	d.continued = false
+	d.columns = 0
+	d.rows = 0
+	d.rowBytes = 0
+	d.tail.Reset()
 	d.partial = 0
 	d.waitForRsp = false
 	d.statement.Reset()
+	d.progressed = 0
 }
 
 // Decode 从 zerocopy.Reader 中不断解析来自 Request / Response 的数据 并判断是否能构建成 RoundTrip
@@ -166,28 +204,38 @@ func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error)
 	}
 
 	var complete bool
+	var objs []*role.Object
 
 	// 持续解析读取到的所有字节 直到 EOF
+	// binlog 事件流中单次读取可能包含多个事件 需要全部消费
 	for len(b) > 0 {
 		// 如果已经出现过两次拼接 返回解析错误
 		if d.partial > 1 {
-			return nil, errInvalidBytes
+			return nil, errResyncFailed
 		}
 
 		if d.partial == 1 {
-			b = append(d.tail, b...)
+			b = d.tail.Join(b)
 		}
 		b, complete, err = d.decode(b)
 		if err != nil {
 			if d.partial == 1 {
+				// b 指向 Join 返回的内存 下一轮 Join 会复用该数组 需先拷贝
+				b = bytes.Clone(b)
 				continue
 			}
 			d.reset() // 错误即重置
-			return nil, err
+			return objs, err
 		}
 
 		d.partial = 0 // 当轮次解析没问题
 		if !complete {
+			d.reportProgress()
+			continue
+		}
+
+		if d.binlog != nil {
+			objs = append(objs, d.archiveBinlog()...)
 			continue
 		}
 
@@ -199,30 +247,62 @@ func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error)
 		// maxPayloadSize 表示后续还有数据包
 		if len(b) == 0 && d.payloadLen != maxPayloadSize && d.payloadConsumed == d.payloadLen {
 			if d.obj == nil {
-				return nil, err
+				return objs, err
 			}
 			switch d.obj.(type) {
 			case *OKPacket:
-				return d.archive(), nil
+				return append(objs, d.archive()...), nil
 			case *ErrorPacket:
-				return d.archive(), nil
+				return append(objs, d.archive()...), nil
 			default:
 				// *EOFPacket
 				// *ResultSetPacket
 				if d.eofPackets != 2 {
-					return nil, nil
+					return objs, nil
 				}
-				return d.archive(), nil
+				return append(objs, d.archive()...), nil
 			}
 		}
 	}
 
-	return nil, nil
+	return objs, nil
+}
+
+// reportProgress 响应每传输 progressBytes 字节输出一次进度事件
+//
+// 结果集在收到最后一个 EOFPacket 之前不会归档 导出大结果集等长耗时查询只能通过进度事件观测
+func (d *decoder) reportProgress() {
+	if d.progressBytes <= 0 || d.role != role.Response || d.binlog != nil {
+		return
+	}
+	n := d.drainBytes / d.progressBytes
+	if n <= d.progressed {
+		return
+	}
+	d.progressed = n
+
+	protocol.EmitProgress(protocol.Progress{
+		Proto:         socket.L7ProtoMySQL,
+		Time:          d.t0,
+		ClientAddress: d.st.DstIP,
+		ClientPort:    d.st.DstPort,
+		ServerAddress: d.st.SrcIP,
+		ServerPort:    d.st.SrcPort,
+		Database:      d.ctx.Get(protocol.CtxDatabase),
+		Rows:          d.rows,
+		Bytes:         d.drainBytes,
+		Elapsed:       d.t0.Sub(d.rspStart).String(),
+	})
 }
 
 // Free 释放持有的资源
 func (d *decoder) Free() {
 	d.statement = nil
+	d.tail.Free()
+	if d.release != nil {
+		d.release()
+		d.release = nil
+	}
 }
 
 var ignoreCmdStatement = map[uint8]struct{}{
@@ -237,6 +317,15 @@ func (d *decoder) normalizeStatement() string {
 	if ok {
 		return ""
 	}
+	switch d.cmdType {
+	case cmdBinlogDump, cmdBinlogDumpGTID:
+		return decodeBinlogDumpStatement(d.cmdType, d.statement.Clone())
+	case cmdRegister:
+		return ""
+	}
+	if d.payloadConsumed > uint32(d.statement.Len()) {
+		statementTruncatedTotal.Inc()
+	}
 	cloned := d.statement.Clone()
 	b := bytes.ReplaceAll(cloned, splitio.CharLF, []byte(" "))
 	return string(b)
@@ -244,15 +333,33 @@ func (d *decoder) normalizeStatement() string {
 
 // archive 归档请求
 func (d *decoder) archive() []*role.Object {
+	// 半同步复制中从库回复的 ACK 不是命令 忽略即可
+	if d.role == role.Request && d.cmdType == semiSyncIndicator {
+		d.reset()
+		return nil
+	}
 	if d.role == role.Request {
+		statement := d.normalizeStatement() // 内存拷贝
+		switch d.cmdType {
+		case cmdInitDB:
+			d.ctx.Set(protocol.CtxDatabase, statement)
+		case cmdQuery:
+			if db, ok := parseUseStatement(statement); ok {
+				d.ctx.Set(protocol.CtxDatabase, db)
+			}
+		}
+
 		obj := role.NewRequestObject(&Request{
 			Host:      d.st.SrcIP,
 			Port:      d.st.SrcPort,
 			Proto:     PROTO,
 			Command:   commands[d.cmdType],
-			Statement: d.normalizeStatement(), // 内存拷贝
+			Statement: statement,
+			Database:  d.ctx.Get(protocol.CtxDatabase),
+			Comment:   protocol.ParseSQLComment(statement),
 			Size:      d.drainBytes,
 			Time:      d.reqTime,
+			Client:    d.client,
 		})
 		d.reset()
 		return []*role.Object{obj}
@@ -265,7 +372,11 @@ func (d *decoder) archive() []*role.Object {
 	case *ErrorPacket:
 		packet = v
 	case *EOFPacket:
-		packet = &ResultSetPacket{Rows: d.headers - 1} // 减去最后一个 EOFPacket
+		packet = &ResultSetPacket{
+			Rows:    d.rows,
+			Columns: d.columns,
+			Bytes:   d.rowBytes,
+		}
 	}
 
 	obj := role.NewResponseObject(&Response{
@@ -282,17 +393,27 @@ func (d *decoder) archive() []*role.Object {
 
 // decode 真正的解析入口
 func (d *decoder) decode(b []byte) ([]byte, bool, error) {
+	if d.guard.Skipping() {
+		return d.drainSkipped(b), false, nil
+	}
+
 	if d.state == stateDecodeHeader {
 		if len(b) < headerLength {
 			d.partial++
-			d.tail = bytes.Clone(b)
-			return nil, false, errDecodeHeader
+			d.tail.Set(b)
+			return nil, false, errHeaderTooShort
 		}
 		if err := d.decodeHeader(b[:headerLength]); err != nil {
 			return nil, false, err
 		}
 		b = b[headerLength:]
 		d.drainBytes += headerLength
+
+		if d.skipContinued || d.guard.Exceeded(int(d.payloadLen)) {
+			d.countRow()
+			d.skipPayload()
+			return d.drainSkipped(b), false, nil
+		}
 	}
 
 	if len(b) == 0 {
@@ -312,17 +433,58 @@ func (d *decoder) decode(b []byte) ([]byte, bool, error) {
 func (d *decoder) decodeHeader(b []byte) error {
 	n := decode3ByteN(b)
 	if n > maxPayloadSize || n < 0 {
-		return errDecodeHeader
+		return errPayloadOverflow
 	}
 
+	d.continued = d.payloadLen == maxPayloadSize
 	d.payloadLen = uint32(n)
 	d.seqID = b[3]
 	d.state = stateDecodePayload
 	d.payloadConsumed = 0
-	d.headers++
 	return nil
 }
 
+// countRow 在数据包起始位置统计结果集的数据行 超长的数据行会被切分为多个分片 仅首个分片计入行数
+func (d *decoder) countRow() {
+	if d.isClient() || d.eofPackets != 1 {
+		return
+	}
+	if !d.continued {
+		d.rows++
+	}
+	d.rowBytes += int(d.payloadLen)
+}
+
+// isEOFPacket 判断是否为 EOFPacket
+//
+// 数据行首列的长度编码同样可能以 0xFE 开头（8 字节长度）EOFPacket 的 payload 不超过 9 字节
+func isEOFPacket(b []byte, payloadLen uint32) bool {
+	return b[0] == packetEOF && payloadLen < 9
+}
+
+// skipPayload 跳过超长的数据包
+//
+// 数据包位于结果集等响应中间时仅跳过当前数据包 响应依旧按照后续的 EOFPacket 归档
+// 否则该数据包即为一条完整的命令 直接丢弃已经累计的状态
+func (d *decoder) skipPayload() {
+	n := d.payloadLen
+	d.skipContinued = n == maxPayloadSize
+	if d.role == "" {
+		d.reset()
+	}
+	d.state = stateDecodeHeader
+	d.guard.Skip(int(n))
+}
+
+// drainSkipped 排空被跳过数据包的剩余字节 返回属于后续数据包的部分
+func (d *decoder) drainSkipped(b []byte) []byte {
+	n, tail := d.guard.Drain(b)
+	if d.role != "" {
+		d.drainBytes += n
+	}
+	return tail
+}
+
 func (d *decoder) isClient() bool {
 	return uint16(d.serverPort) == d.st.DstPort
 }
@@ -350,10 +512,22 @@ func (d *decoder) decodePayload(b []byte) ([]byte, bool, error) {
 		return nil, complete, err
 	}
 
+	if d.binlog != nil {
+		return d.decodeBinlogPayload(b)
+	}
+
 	// 匹配到 cmdType 则确认为 Request
 	if d.isClient() {
 		d.role = ""
 	}
+	// 握手阶段客户端的 HandshakeResponse 序列号为 1（或者 SSLRequest 之后的 2）
+	if d.isClient() && d.client == nil && d.seqID > 0 && d.payloadConsumed == 0 {
+		var database string
+		d.client, database = decodeHandshakeResponse(b)
+		if database != "" {
+			d.ctx.Set(protocol.CtxDatabase, database)
+		}
+	}
 	if d.role == "" && d.guessRequest(b[0]) {
 		d.state = stateDecodePayload
 		d.role = role.Request
@@ -380,6 +554,32 @@ func (d *decoder) decodePayload(b []byte) ([]byte, bool, error) {
 		return b[n:], true, nil
 	}
 
+	if d.isBinlogEvent(b) {
+		d.binlog = &binlogStream{}
+		return d.decodeBinlogPayload(b)
+	}
+
+	// 数据包起始位置 根据结果集所处的阶段识别列数量以及数据行
+	// 数据行的首字节可能与 OKPacket 等标识相同 不能进入下方的类型判断
+	if !d.isClient() && d.payloadConsumed == 0 {
+		switch {
+		case d.continued:
+			d.countRow()
+			return d.decodeResponse(b)
+
+		case d.role == "" && d.eofPackets == 0:
+			switch b[0] {
+			case packetOK, packetError, packetEOF, packetLocalInfile:
+			default:
+				d.columns, _, _ = decodeLenEncodedInteger(b)
+			}
+
+		case d.eofPackets == 1 && !isEOFPacket(b, d.payloadLen) && b[0] != packetError:
+			d.countRow()
+			return d.decodeResponse(b)
+		}
+	}
+
 	// 根据首字节判断数据包类型
 	switch b[0] {
 	case packetEOF:
@@ -398,7 +598,6 @@ func (d *decoder) decodePayload(b []byte) ([]byte, bool, error) {
 		if d.eofPackets == 2 {
 			return nil, true, nil
 		}
-		d.headers = 0 // 首次解析数据行
 		return d.decodeResponse(b)
 
 	case packetError:
@@ -434,8 +633,13 @@ func (d *decoder) decodePayload(b []byte) ([]byte, bool, error) {
 	return d.decodeResponse(b)
 }
 
+// ResultSetPacket 结果集统计
+//
+// Bytes 为数据行的 payload 总字节数 与 Rows 一起可以区分"行数多"与"单行大"两类慢查询
 type ResultSetPacket struct {
-	Rows int
+	Rows    int
+	Columns int
+	Bytes   int
 }
 
 func (p ResultSetPacket) Name() string {
@@ -480,6 +684,9 @@ func (d *decoder) decodeResponse(b []byte) ([]byte, bool, error) {
 		return nil, false, errDecodeResponse
 	}
 
+	if d.role != role.Response {
+		d.rspStart = d.t0
+	}
 	d.role = role.Response
 	n := d.payloadConsumed + uint32(len(b))
 
@@ -516,7 +723,7 @@ func (d *decoder) decodeRequest(b []byte) (bool, error) {
 
 	d.statement.Write(b)
 	if d.payloadConsumed > d.payloadLen {
-		return false, errInvalidBytes
+		return false, errPayloadOverflow
 	}
 	if d.payloadConsumed == d.payloadLen {
 		return true, nil
@@ -549,7 +756,7 @@ func (p EOFPacket) Name() string {
 func (d *decoder) decodeEOFPacket(b []byte) ([]byte, *EOFPacket, error) {
 	if len(b) < 4 {
 		d.partial++
-		d.tail = bytes.Clone(b)
+		d.tail.Set(b)
 		return nil, nil, errDecodeEOFPacket
 	}
 
@@ -586,7 +793,7 @@ func (p ErrorPacket) Name() string {
 func (d *decoder) decodeErrPacket(b []byte) ([]byte, *ErrorPacket, error) {
 	if len(b) < 8 {
 		d.partial++
-		d.tail = bytes.Clone(b)
+		d.tail.Set(b)
 		return nil, nil, errDecodeErrPacket
 	}
 
@@ -637,26 +844,26 @@ func (p OKPacket) Name() string {
 func (d *decoder) decodeOkPacket(b []byte) ([]byte, *OKPacket, error) {
 	if len(b) < 6 {
 		d.partial++
-		d.tail = bytes.Clone(b)
+		d.tail.Set(b)
 		return nil, nil, errDecodeOKPacket
 	}
 
 	prevLen := len(b)
 	affectedRows, b, ok := decodeLenEncodedInteger(b)
 	if !ok {
-		return nil, nil, errDecodeOKPacket
+		return nil, nil, errMalformedOKPacket
 	}
 	lastInsertID, b, ok := decodeLenEncodedInteger(b)
 	if !ok {
-		return nil, nil, errDecodeOKPacket
+		return nil, nil, errMalformedOKPacket
 	}
 	status, b, ok := decodeLenEncodedInteger(b)
 	if !ok {
-		return nil, nil, errDecodeOKPacket
+		return nil, nil, errMalformedOKPacket
 	}
 	warnings, b, ok := decodeLenEncodedInteger(b)
 	if !ok {
-		return nil, nil, errDecodeOKPacket
+		return nil, nil, errMalformedOKPacket
 	}
 
 	d.payloadConsumed += uint32(prevLen - len(b))
@@ -27,6 +27,7 @@ import (
 	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol"
 	"github.com/packetd/packetd/protocol/role"
 )
 
@@ -210,6 +211,24 @@ func TestDecodeRequest(t *testing.T) {
 				Size:      25,
 			},
 		},
+		{
+			name:  "SELECT with sqlcommenter",
+			input: buildQueryPacket("SELECT * FROM users /*traceparent='00-5bd66ef5095369c7b0d1f8f4bd33716a-c532cb4098ac3dd2-01',route='%2Fusers%2F%3Aid'*/"),
+			request: &Request{
+				Command:   "QUERY",
+				Statement: "SELECT * FROM users /*traceparent='00-5bd66ef5095369c7b0d1f8f4bd33716a-c532cb4098ac3dd2-01',route='%2Fusers%2F%3Aid'*/",
+				Size:      123,
+				Comment: &protocol.SQLComment{
+					TraceID: "5bd66ef5095369c7b0d1f8f4bd33716a",
+					SpanID:  "c532cb4098ac3dd2",
+					Route:   "/users/:id",
+					Tags: map[string]string{
+						"traceparent": "00-5bd66ef5095369c7b0d1f8f4bd33716a-c532cb4098ac3dd2-01",
+						"route":       "/users/:id",
+					},
+				},
+			},
+		},
 	}
 
 	var st socket.Tuple
@@ -229,6 +248,7 @@ func TestDecodeRequest(t *testing.T) {
 			assert.Equal(t, tt.request.Command, obj.Command)
 			assert.Equal(t, tt.request.Statement, obj.Statement)
 			assert.Equal(t, tt.request.Size, obj.Size)
+			assert.Equal(t, tt.request.Comment, obj.Comment)
 		})
 	}
 }
@@ -246,6 +266,19 @@ func writePacket(buf *bytes.Buffer, payload []byte) {
 	buf.Write(payload)
 }
 
+// buildAmbiguousResultSetPacket 数据行的首字节与 OKPacket / EOFPacket 标识相同
+func buildAmbiguousResultSetPacket() []byte {
+	var buf bytes.Buffer
+	writePacket(&buf, []byte{0x01})
+	writePacket(&buf, []byte{0x03, 0x64, 0x65, 0x66, 0x00, 0x00, 0x00, 0x01, 0x69, 0x64, 0x00})
+	writePacket(&buf, eofPacket)
+	writePacket(&buf, []byte{0x00})                                                                // 空字符串
+	writePacket(&buf, []byte{0xfe, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 'a', 'b', 'c'}) // 8 字节长度编码
+	writePacket(&buf, []byte{0x01, 'x'})
+	writePacket(&buf, eofPacket)
+	return buf.Bytes()
+}
+
 func buildResultSetPacket(n int) [][]byte {
 	var buf bytes.Buffer
 
@@ -364,7 +397,9 @@ func TestDecodeResponse(t *testing.T) {
 			response: &Response{
 				Size: 1664,
 				Packet: &ResultSetPacket{
-					Rows: 100,
+					Rows:    100,
+					Columns: 2,
+					Bytes:   1192,
 				},
 			},
 		},
@@ -374,7 +409,21 @@ func TestDecodeResponse(t *testing.T) {
 			response: &Response{
 				Size: 178966,
 				Packet: &ResultSetPacket{
-					Rows: 10000,
+					Rows:    10000,
+					Columns: 2,
+					Bytes:   138894,
+				},
+			},
+		},
+		{
+			name:   "ResultSetAmbiguousRows",
+			inputs: [][]byte{buildAmbiguousResultSetPacket()},
+			response: &Response{
+				Size: 65,
+				Packet: &ResultSetPacket{
+					Rows:    3,
+					Columns: 1,
+					Bytes:   15,
 				},
 			},
 		},
@@ -384,7 +433,8 @@ func TestDecodeResponse(t *testing.T) {
 			response: &Response{
 				Size: 72,
 				Packet: &ResultSetPacket{
-					Rows: 0,
+					Rows:    0,
+					Columns: 2,
 				},
 			},
 		},
@@ -574,3 +624,108 @@ func TestDecodeLenEncodedInteger(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeDatabaseContext(t *testing.T) {
+	var st socket.Tuple
+	var t0 time.Time
+
+	ctx := protocol.NewConnContext(0)
+	d := newDecoder(st, 0, nil, ctx, nil)
+	decode := func(payload []byte) *Request {
+		var buf bytes.Buffer
+		writePacket(&buf, payload)
+		objs, err := d.Decode(zerocopy.NewBuffer(buf.Bytes()), t0)
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+		return objs[0].Obj.(*Request)
+	}
+
+	assert.Empty(t, decode(append([]byte{cmdQuery}, "SELECT 1"...)).Database)
+	assert.Equal(t, "orders", decode(append([]byte{cmdInitDB}, "orders"...)).Database)
+	assert.Equal(t, "orders", decode(append([]byte{cmdQuery}, "SELECT 1"...)).Database)
+	assert.Equal(t, "users", decode(append([]byte{cmdQuery}, "use `users`;"...)).Database)
+	assert.Equal(t, "users", ctx.Get(protocol.CtxDatabase))
+}
+
+func TestDecodeSkipOversized(t *testing.T) {
+	var st socket.Tuple
+	var t0 time.Time
+
+	var buf bytes.Buffer
+	writePacket(&buf, append([]byte{cmdQuery}, "INSERT INTO t VALUES ('xxxxxxxxxxxxxxxx')"...))
+	oversized := buf.Len()
+	writePacket(&buf, append([]byte{cmdQuery}, "SELECT 1"...))
+	b := buf.Bytes()
+
+	tests := []struct {
+		name   string
+		chunks [][]byte
+	}{
+		{
+			name:   "single chunk",
+			chunks: [][]byte{b},
+		},
+		{
+			name:   "split oversized payload",
+			chunks: [][]byte{b[:10], b[10:]},
+		},
+		{
+			name:   "split at boundary",
+			chunks: [][]byte{b[:oversized], b[oversized:]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := common.NewOptions()
+			opts.Merge(protocol.OptMaxPayloadSize, 16)
+			d := newDecoder(st, 0, opts, protocol.NewConnContext(0), nil)
+
+			var objs []*role.Object
+			for _, chunk := range tt.chunks {
+				lst, err := d.Decode(zerocopy.NewBuffer(chunk), t0)
+				assert.NoError(t, err)
+				objs = append(objs, lst...)
+			}
+			assert.Len(t, objs, 1)
+			req := objs[0].Obj.(*Request)
+			assert.Equal(t, "SELECT 1", req.Statement)
+			assert.Equal(t, 13, req.Size)
+		})
+	}
+}
+
+func TestDecodeResponseProgress(t *testing.T) {
+	var lst []protocol.Progress
+	protocol.SetProgressHandler(func(p protocol.Progress) {
+		lst = append(lst, p)
+	})
+	defer protocol.SetProgressHandler(nil)
+
+	var st socket.Tuple
+	t0 := time.Now()
+	opts := common.NewOptions()
+	opts.Merge(protocol.OptProgressBytes, 4096)
+	d := NewDecoder(st, 3306, opts)
+
+	var objs []*role.Object
+	chunks := buildResultSetPacket(1000)
+	for i, chunk := range chunks {
+		res, err := d.Decode(zerocopy.NewBuffer(chunk), t0.Add(time.Duration(i)*time.Second))
+		assert.NoError(t, err)
+		objs = append(objs, res...)
+	}
+	assert.Len(t, objs, 1)
+	rsp := objs[0].Obj.(*Response)
+	assert.Equal(t, 1000, rsp.Packet.(*ResultSetPacket).Rows)
+
+	// 最后一个分片内完成归档 不再输出进度
+	assert.Len(t, lst, rsp.Size/4096)
+	for i, p := range lst {
+		assert.Equal(t, "progress", p.Event)
+		assert.Equal(t, socket.L7ProtoMySQL, p.Proto)
+		assert.GreaterOrEqual(t, p.Bytes, (i+1)*4096)
+		assert.Greater(t, p.Rows, 0)
+		assert.Equal(t, (time.Duration(i) * time.Second).String(), p.Elapsed)
+	}
+}
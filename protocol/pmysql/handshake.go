@@ -0,0 +1,151 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pmysql
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/packetd/packetd/protocol"
+)
+
+const (
+	capConnectWithDB              = 0x00000008
+	capProtocol41                 = 0x00000200
+	capSecureConnection           = 0x00008000
+	capPluginAuth                 = 0x00080000
+	capConnectAttrs               = 0x00100000
+	capPluginAuthLenEncClientData = 0x00200000
+)
+
+const (
+	// handshakeFixedLength HandshakeResponse41 中 username 之前的定长部分
+	// capability(4) + max_packet_size(4) + charset(1) + filler(23)
+	handshakeFixedLength = 32
+
+	attrClientName    = "_client_name"
+	attrClientVersion = "_client_version"
+)
+
+// decodeHandshakeResponse 解析客户端 HandshakeResponse41 中的 connect attrs 以及初始数据库
+//
+// 布局如下 仅在 capability 声明了对应标识时才携带相应字段
+// - capability_flags(4) / max_packet_size(4) / character_set(1) / filler(23 字节 0x00)
+// - username(string[NUL])
+// - auth_response(length encoded 或者 1 字节长度前缀)
+// - database(string[NUL]) if CLIENT_CONNECT_WITH_DB
+// - client_plugin_name(string[NUL]) if CLIENT_PLUGIN_AUTH
+// - connect_attrs(length encoded key/value) if CLIENT_CONNECT_ATTRS
+//
+// 其中 `_client_name` / `_client_version` 由各驱动填充（如 libmysql / mysql-connector-java）
+func decodeHandshakeResponse(b []byte) (*protocol.Client, string) {
+	if len(b) <= handshakeFixedLength {
+		return nil, "" // SSLRequest 仅有定长部分
+	}
+
+	capability := binary.LittleEndian.Uint32(b[:4])
+	if capability&capProtocol41 == 0 {
+		return nil, ""
+	}
+	// filler 必须全为 0 用于排除误判
+	for _, c := range b[9:handshakeFixedLength] {
+		if c != 0 {
+			return nil, ""
+		}
+	}
+
+	b = b[handshakeFixedLength:]
+	b, ok := skipNulString(b) // username
+	if !ok {
+		return nil, ""
+	}
+
+	switch {
+	case capability&capPluginAuthLenEncClientData != 0:
+		var n int
+		n, b, ok = decodeLenEncodedInteger(b)
+		if !ok || n > len(b) {
+			return nil, ""
+		}
+		b = b[n:]
+	case capability&capSecureConnection != 0:
+		if len(b) == 0 || int(b[0]) >= len(b) {
+			return nil, ""
+		}
+		b = b[1+int(b[0]):]
+	default:
+		if b, ok = skipNulString(b); !ok {
+			return nil, ""
+		}
+	}
+
+	var database string
+	if capability&capConnectWithDB != 0 {
+		idx := bytes.IndexByte(b, 0x00)
+		if idx < 0 {
+			return nil, ""
+		}
+		database, b = string(b[:idx]), b[idx+1:]
+	}
+	if capability&capPluginAuth != 0 {
+		if b, ok = skipNulString(b); !ok {
+			return nil, database
+		}
+	}
+	if capability&capConnectAttrs == 0 {
+		return nil, database
+	}
+
+	total, b, ok := decodeLenEncodedInteger(b)
+	if !ok {
+		return nil, database
+	}
+	if total < len(b) {
+		b = b[:total]
+	}
+
+	var name, version string
+	for len(b) > 0 {
+		var key, val []byte
+		if key, b, ok = decodeLenEncodedString(b); !ok {
+			break
+		}
+		if val, b, ok = decodeLenEncodedString(b); !ok {
+			break
+		}
+		switch string(key) {
+		case attrClientName:
+			name = string(val)
+		case attrClientVersion:
+			version = string(val)
+		}
+	}
+	return protocol.NewClient(name, version), database
+}
+
+func skipNulString(b []byte) ([]byte, bool) {
+	idx := bytes.IndexByte(b, 0x00)
+	if idx < 0 {
+		return nil, false
+	}
+	return b[idx+1:], true
+}
+
+func decodeLenEncodedString(b []byte) ([]byte, []byte, bool) {
+	n, b, ok := decodeLenEncodedInteger(b)
+	if !ok || n > len(b) {
+		return nil, nil, false
+	}
+	return b[:n], b[n:], true
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pmysql
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/protocol"
+)
+
+func buildHandshakeResponse(capability uint32, attrs ...string) []byte {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, capability)
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(1<<24))
+	buf.WriteByte(0x21)
+	buf.Write(make([]byte, 23))
+	buf.WriteString("root\x00")
+	buf.Write([]byte{0x03, 'a', 'b', 'c'})
+	if capability&capConnectWithDB != 0 {
+		buf.WriteString("test\x00")
+	}
+	if capability&capPluginAuth != 0 {
+		buf.WriteString("caching_sha2_password\x00")
+	}
+
+	var kv []byte
+	for _, attr := range attrs {
+		kv = append(kv, byte(len(attr)))
+		kv = append(kv, attr...)
+	}
+	buf.WriteByte(byte(len(kv)))
+	buf.Write(kv)
+	return buf.Bytes()
+}
+
+func TestDecodeHandshakeResponse(t *testing.T) {
+	full := uint32(capProtocol41 | capSecureConnection | capConnectWithDB | capPluginAuth | capConnectAttrs)
+
+	tests := []struct {
+		name     string
+		input    []byte
+		want     *protocol.Client
+		database string
+	}{
+		{
+			name:     "ConnectorJ",
+			input:    buildHandshakeResponse(full, "_os", "Linux", "_client_name", "MySQL Connector/J", "_client_version", "8.0.33"),
+			want:     &protocol.Client{Name: "mysql-connector/j", Version: "8.0.33"},
+			database: "test",
+		},
+		{
+			name:  "LenEncAuthWithoutDB",
+			input: buildHandshakeResponse(capProtocol41|capPluginAuthLenEncClientData|capPluginAuth|capConnectAttrs, "_client_name", "libmysql", "_client_version", "8.0.36"),
+			want:  &protocol.Client{Name: "libmysql", Version: "8.0.36"},
+		},
+		{
+			name:     "WithoutClientName",
+			input:    buildHandshakeResponse(full, "_os", "Linux"),
+			database: "test",
+		},
+		{
+			name:  "WithoutConnectAttrs",
+			input: buildHandshakeResponse(capProtocol41 | capSecureConnection),
+		},
+		{
+			name:     "WithDBWithoutConnectAttrs",
+			input:    buildHandshakeResponse(capProtocol41 | capSecureConnection | capConnectWithDB),
+			database: "test",
+		},
+		{
+			name:  "SSLRequest",
+			input: buildHandshakeResponse(full)[:handshakeFixedLength],
+		},
+		{
+			name:  "Query",
+			input: append([]byte{cmdQuery}, "SELECT * FROM users WHERE id = 1 AND name = 'packetd'"...),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, database := decodeHandshakeResponse(tt.input)
+			assert.Equal(t, tt.want, client)
+			assert.Equal(t, tt.database, database)
+		})
+	}
+}
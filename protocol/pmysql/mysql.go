@@ -24,10 +24,17 @@ import (
 
 func init() {
 	protocol.Register(socket.L7ProtoMySQL, NewConnPool)
+	protocol.Describe(socket.L7ProtoMySQL, protocol.Capability{
+		Versions: []string{"protocol-v10"},
+		Options:  []string{protocol.OptMaxPayloadSize, protocol.OptProgressBytes},
+	})
 }
 
 // NewConnPool 创建 MySQL 协议连接池
+//
+// 同一条链接两个方向的 decoder 共享链接上下文 记录握手以及 USE / COM_INIT_DB 切换后的当前数据库
 func NewConnPool(opts common.Options) protocol.ConnPool {
+	cs := protocol.NewConnContexts(0)
 	return protocol.NewL7TCPConnPool(
 		role.NewSingleMatcher,
 		func(pair *role.Pair) socket.RoundTrip {
@@ -37,7 +44,10 @@ func NewConnPool(opts common.Options) protocol.ConnPool {
 			}
 		},
 		func(st socket.Tuple, serverPort socket.Port) protocol.Decoder {
-			return NewDecoder(st, serverPort, opts)
+			ctx := cs.Acquire(st, serverPort)
+			return newDecoder(st, serverPort, opts, ctx, func() {
+				cs.Release(st, serverPort)
+			})
 		},
 	)
 }
@@ -50,7 +60,10 @@ type Request struct {
 	Command   string
 	Size      int
 	Statement string
+	Database  string               `json:",omitempty"` // 链接当前所在的数据库 未捕获到握手或者切换语句时为空
+	Comment   *protocol.SQLComment `json:",omitempty"`
 	Time      time.Time
+	Client    *protocol.Client `json:",omitempty"`
 }
 
 // Response MySQL 响应
@@ -0,0 +1,161 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pntp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+const (
+	PROTO = "NTP"
+)
+
+func newError(code protocol.ErrorCode, format string, args ...any) error {
+	return protocol.NewDecodeError(socket.L7ProtoNTP, code, "ntp/decoder: "+format, args...)
+}
+
+const (
+	headerLength = 48
+
+	modeClient = 3
+	modeServer = 4
+)
+
+var modeNames = map[uint8]string{
+	0: "Reserved",
+	1: "SymmetricActive",
+	2: "SymmetricPassive",
+	3: "Client",
+	4: "Server",
+	5: "Broadcast",
+	6: "Control",
+	7: "Private",
+}
+
+type decoder struct {
+	st socket.TupleRaw
+}
+
+func NewDecoder(st socket.Tuple, _ socket.Port, _ common.Options) protocol.Decoder {
+	return &decoder{
+		st: st.ToRaw(),
+	}
+}
+
+// Decode 从 zerocopy.Reader 中解析 NTP 报文
+//
+// rfc: https://www.rfc-editor.org/rfc/rfc5905 7.3. Packet Header Variables
+//
+//	 0                   1                   2                   3
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|LI | VN  |Mode |    Stratum     |     Poll      |  Precision   |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                         Root Delay                            |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                         Root Dispersion                       |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                          Reference ID                         |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	+                     Reference Timestamp (64)                  +
+//	+                     Origin Timestamp (64)                     +
+//	+                     Receive Timestamp (64)                    +
+//	+                     Transmit Timestamp (64)                   +
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//
+// 仅处理 Client / Server 模式 对称模式以及 Control（ntpq）等报文直接忽略
+// 报文末尾可能携带扩展字段或者 MAC 不影响配对 Size 按照整个数据包计算
+func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error) {
+	b, err := r.Read(common.ReadWriteBlockSize)
+	if err != nil {
+		return nil, nil
+	}
+
+	if len(b) < headerLength {
+		return nil, newError(protocol.ErrCodeHeaderTooShort, "packet too short (%d bytes)", len(b))
+	}
+
+	version := (b[0] >> 3) & 0x07
+	mode := b[0] & 0x07
+	if version < 1 || version > 4 {
+		return nil, newError(protocol.ErrCodeUnsupportedVersion, "unsupported version (%d)", version)
+	}
+
+	switch mode {
+	case modeClient:
+		return []*role.Object{role.NewRequestObject(&Request{
+			Host:        d.st.SrcIP,
+			Port:        d.st.SrcPort,
+			Proto:       PROTO,
+			Size:        len(b),
+			Time:        t,
+			Version:     version,
+			Mode:        modeNames[mode],
+			transaction: hex.EncodeToString(b[40:48]), // Transmit Timestamp
+		})}, nil
+
+	case modeServer:
+		stratum := b[1]
+		rsp := &Response{
+			Host:           d.st.SrcIP,
+			Port:           d.st.SrcPort,
+			Proto:          PROTO,
+			Size:           len(b),
+			Time:           t,
+			Version:        version,
+			Mode:           modeNames[mode],
+			Leap:           b[0] >> 6,
+			Stratum:        stratum,
+			ReferenceID:    decodeReferenceID(stratum, b[12:16]),
+			RootDelay:      decodeShortFormat(b[4:8]),
+			RootDispersion: decodeShortFormat(b[8:12]),
+			transaction:    hex.EncodeToString(b[24:32]), // Origin Timestamp
+		}
+		if stratum == 0 {
+			rsp.KissCode = rsp.ReferenceID
+		}
+		return []*role.Object{role.NewResponseObject(rsp)}, nil
+	}
+	return nil, nil
+}
+
+// Free 释放持有的资源
+func (d *decoder) Free() {}
+
+// decodeShortFormat 解析 NTP Short Format 即 16 位整数秒 + 16 位小数秒
+func decodeShortFormat(b []byte) time.Duration {
+	v := binary.BigEndian.Uint32(b)
+	return time.Duration(uint64(v) * uint64(time.Second) >> 16)
+}
+
+// decodeReferenceID 解析 Reference ID
+//
+// Stratum 0（Kiss-o'-Death）以及 1（一级时钟源）为 4 字节 ASCII 编码 如 GPS、RATE
+// 其余情况为上游服务器的 IPv4 地址（IPv6 上游为地址哈希 同样按照 IPv4 格式展示）
+func decodeReferenceID(stratum uint8, b []byte) string {
+	if stratum <= 1 {
+		return strings.TrimRight(string(b), "\x00")
+	}
+	return net.IP(b).String()
+}
@@ -0,0 +1,189 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pntp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+var (
+	transmitTimestamp = []byte{0xe9, 0x4a, 0x1b, 0x2c, 0x11, 0x22, 0x33, 0x44}
+	receiveTimestamp  = []byte{0xe9, 0x4a, 0x1b, 0x2c, 0x55, 0x66, 0x77, 0x88}
+)
+
+func buildPacket(li, vn, mode, stratum uint8, refID []byte, origin, transmit []byte) []byte {
+	b := make([]byte, headerLength)
+	b[0] = li<<6 | vn<<3 | mode
+	b[1] = stratum
+	b[2] = 6                                      // Poll
+	b[3] = 0xe9                                   // Precision
+	copy(b[4:8], []byte{0x00, 0x01, 0x80, 0x00})  // Root Delay 1.5s
+	copy(b[8:12], []byte{0x00, 0x00, 0x40, 0x00}) // Root Dispersion 0.25s
+	copy(b[12:16], refID)
+	copy(b[24:32], origin)
+	copy(b[32:40], receiveTimestamp)
+	copy(b[40:48], transmit)
+	return b
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  any
+	}{
+		{
+			name:  "Client Request",
+			input: buildPacket(0, 4, modeClient, 0, nil, nil, transmitTimestamp),
+			want: &Request{
+				Host:        "0.0.0.0",
+				Proto:       PROTO,
+				Size:        48,
+				Version:     4,
+				Mode:        "Client",
+				transaction: "e94a1b2c11223344",
+			},
+		},
+		{
+			name:  "Server Response",
+			input: buildPacket(0, 4, modeServer, 2, []byte{10, 0, 0, 1}, transmitTimestamp, receiveTimestamp),
+			want: &Response{
+				Host:           "0.0.0.0",
+				Proto:          PROTO,
+				Size:           48,
+				Version:        4,
+				Mode:           "Server",
+				Stratum:        2,
+				ReferenceID:    "10.0.0.1",
+				RootDelay:      1500 * time.Millisecond,
+				RootDispersion: 250 * time.Millisecond,
+				transaction:    "e94a1b2c11223344",
+			},
+		},
+		{
+			name:  "Primary Server Response",
+			input: buildPacket(0, 3, modeServer, 1, []byte("GPS"), transmitTimestamp, receiveTimestamp),
+			want: &Response{
+				Host:           "0.0.0.0",
+				Proto:          PROTO,
+				Size:           48,
+				Version:        3,
+				Mode:           "Server",
+				Stratum:        1,
+				ReferenceID:    "GPS",
+				RootDelay:      1500 * time.Millisecond,
+				RootDispersion: 250 * time.Millisecond,
+				transaction:    "e94a1b2c11223344",
+			},
+		},
+		{
+			name:  "Kiss-o'-Death Response",
+			input: buildPacket(3, 4, modeServer, 0, []byte("RATE"), transmitTimestamp, receiveTimestamp),
+			want: &Response{
+				Host:           "0.0.0.0",
+				Proto:          PROTO,
+				Size:           48,
+				Version:        4,
+				Mode:           "Server",
+				Leap:           3,
+				Stratum:        0,
+				ReferenceID:    "RATE",
+				KissCode:       "RATE",
+				RootDelay:      1500 * time.Millisecond,
+				RootDispersion: 250 * time.Millisecond,
+				transaction:    "e94a1b2c11223344",
+			},
+		},
+		{
+			name:  "Symmetric Active Ignored",
+			input: buildPacket(0, 4, 1, 2, nil, nil, transmitTimestamp),
+		},
+	}
+
+	var st socket.Tuple
+	var t0 time.Time
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(st, 0, common.NewOptions())
+			objs, err := d.Decode(zerocopy.NewBuffer(tt.input), t0)
+			assert.NoError(t, err)
+
+			if tt.want == nil {
+				assert.Empty(t, objs)
+				return
+			}
+			assert.Len(t, objs, 1)
+			assert.Equal(t, tt.want, objs[0].Obj)
+		})
+	}
+}
+
+func TestDecodeFailed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{
+			name:  "Too Short",
+			input: []byte{0x23, 0x00, 0x06},
+		},
+		{
+			name:  "Invalid Version",
+			input: buildPacket(0, 7, modeClient, 0, nil, nil, transmitTimestamp),
+		},
+	}
+
+	var st socket.Tuple
+	var t0 time.Time
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(st, 0, common.NewOptions())
+			objs, err := d.Decode(zerocopy.NewBuffer(tt.input), t0)
+			assert.Error(t, err)
+			assert.Empty(t, objs)
+		})
+	}
+}
+
+func TestMatchTransaction(t *testing.T) {
+	t0 := time.Now()
+	var st socket.Tuple
+	client := NewDecoder(st, 0, common.NewOptions())
+	server := NewDecoder(st.Mirror(), 0, common.NewOptions())
+
+	matcher := role.NewTransactionMatcher(maxRecordSize, defaultTimeout, extractTransaction)
+
+	other := []byte{0xe9, 0x4a, 0x1b, 0x2c, 0x99, 0x99, 0x99, 0x99}
+	reqs, _ := client.Decode(zerocopy.NewBuffer(buildPacket(0, 4, modeClient, 0, nil, nil, transmitTimestamp)), t0)
+	unmatched, _ := server.Decode(zerocopy.NewBuffer(buildPacket(0, 4, modeServer, 2, nil, other, receiveTimestamp)), t0.Add(time.Millisecond))
+	rsps, _ := server.Decode(zerocopy.NewBuffer(buildPacket(0, 4, modeServer, 2, nil, transmitTimestamp, receiveTimestamp)), t0.Add(2*time.Millisecond))
+
+	assert.Nil(t, matcher.Match(reqs[0]))
+	assert.Nil(t, matcher.Match(unmatched[0]))
+
+	pair := matcher.Match(rsps[0])
+	assert.NotNil(t, pair)
+
+	rt := &RoundTrip{request: pair.Request.Obj.(*Request), response: pair.Response.Obj.(*Response)}
+	assert.True(t, rt.Validate())
+	assert.Equal(t, 2*time.Millisecond, rt.Duration())
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pntp
+
+import (
+	"time"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+func init() {
+	protocol.Register(socket.L7ProtoNTP, NewConnPool)
+	protocol.Describe(socket.L7ProtoNTP, protocol.Capability{
+		Versions: []string{"1", "2", "3", "4"},
+		Options:  []string{protocol.OptTransactionTimeout},
+	})
+}
+
+const (
+	maxRecordSize = 16
+
+	// defaultTimeout ntpd / chrony 等待响应约 1~2s 后即判定丢包 之后的响应不再被客户端采纳
+	defaultTimeout = 2 * time.Second
+)
+
+// extractTransaction 客户端请求的 Transmit Timestamp 会被服务端原样回填至 Origin Timestamp
+//
+// 二者即构成事务 ID 且不依赖双方时钟是否同步
+func extractTransaction(o *role.Object) (string, time.Time, bool) {
+	switch obj := o.Obj.(type) {
+	case *Request:
+		return obj.transaction, obj.Time, true
+	case *Response:
+		return obj.transaction, obj.Time, true
+	}
+	return "", time.Time{}, false
+}
+
+// NewConnPool 创建 NTP 协议连接池
+func NewConnPool(opts common.Options) protocol.ConnPool {
+	return protocol.NewL7UDPTransactionConnPool(
+		opts,
+		protocol.UDPTransaction{
+			Size:    maxRecordSize,
+			Timeout: defaultTimeout,
+			Extract: extractTransaction,
+		},
+		func(pair *role.Pair) socket.RoundTrip {
+			return &RoundTrip{
+				request:  pair.Request.Obj.(*Request),
+				response: pair.Response.Obj.(*Response),
+			}
+		},
+		func(st socket.Tuple, serverPort socket.Port) protocol.Decoder {
+			return NewDecoder(st, serverPort, opts)
+		},
+	)
+}
+
+// Request NTP 请求
+type Request struct {
+	Host    string
+	Port    uint16
+	Proto   string
+	Size    int
+	Time    time.Time
+	Version uint8
+	Mode    string
+
+	transaction string
+}
+
+// Response NTP 响应
+//
+// Stratum 为 0 时代表 Kiss-o'-Death 报文 KissCode 记录服务端拒绝的原因（如 RATE、DENY）
+type Response struct {
+	Host           string
+	Port           uint16
+	Proto          string
+	Size           int
+	Time           time.Time
+	Version        uint8
+	Mode           string
+	Leap           uint8
+	Stratum        uint8
+	ReferenceID    string
+	KissCode       string `json:",omitempty"`
+	RootDelay      time.Duration
+	RootDispersion time.Duration
+
+	transaction string
+}
+
+var _ socket.RoundTrip = (*RoundTrip)(nil)
+
+// RoundTrip NTP 单次请求来回
+//
+// 实现了 socket.RoundTrip 接口
+type RoundTrip struct {
+	request  *Request
+	response *Response
+}
+
+func (rt RoundTrip) Proto() socket.L7Proto {
+	return socket.L7ProtoNTP
+}
+
+func (rt RoundTrip) Request() any {
+	return rt.request
+}
+
+func (rt RoundTrip) Response() any {
+	return rt.response
+}
+
+func (rt RoundTrip) Duration() time.Duration {
+	return rt.response.Time.Sub(rt.request.Time)
+}
+
+func (rt RoundTrip) Validate() bool {
+	return rt.response.Time.After(rt.request.Time)
+}
@@ -14,11 +14,14 @@
 package protocol
 
 import (
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 
 	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/common/socket"
@@ -44,7 +47,25 @@ func Get(name socket.L7Proto) (CreateConnPool, error) {
 	if !ok {
 		return nil, errors.Errorf("connpool factory (%s) not found", name)
 	}
-	return f, nil
+
+	// 记录连接池所属的协议 截断抓包时生成的 RoundTrip 需要据此标识协议
+	return func(opts common.Options) ConnPool {
+		pool := f(opts)
+		if cp, ok := pool.(*connPool); ok {
+			cp.l7Proto = name
+		}
+		return pool
+	}, nil
+}
+
+// Protocols 返回已注册的协议列表 按名称排序
+func Protocols() []socket.L7Proto {
+	lst := make([]socket.L7Proto, 0, len(poolFactory))
+	for name := range poolFactory {
+		lst = append(lst, name)
+	}
+	slices.Sort(lst)
+	return lst
 }
 
 // CreateConnFunc 根据传入的 socket.Tuple 创建对应协议的 Conn
@@ -71,6 +92,11 @@ type ConnPool interface {
 	// ActiveConns 返回活跃的 Connection 数量
 	ActiveConns() int
 
+	// RangeConns 遍历所有链接 每个链接仅回调一次
+	//
+	// st 为链接任意一个方向的四元组
+	RangeConns(f func(st socket.Tuple, conn Conn))
+
 	// RemoveExpired 清理超过 duration 未收到任何数据包的 Conn
 	//
 	// 返回被删除的过期链接数量
@@ -109,6 +135,7 @@ type ConnPool interface {
 // 因此需要一个 frozen 机制 不同协议可能有不同的周期 frozen 为空则代表无需此机制
 type connPool struct {
 	l4Proto    socket.L4Proto
+	l7Proto    socket.L7Proto
 	createConn CreateConnFunc
 	mut        sync.RWMutex
 	conns      map[socket.Tuple]Conn
@@ -173,6 +200,9 @@ func (cp *connPool) GetOrCreate(st socket.Tuple, serverPort socket.Port) Conn {
 	}
 
 	conn = cp.createConn(st, serverPort)
+	if c, ok := conn.(*L7TCPConn); ok {
+		c.l7Proto = cp.l7Proto
+	}
 	cp.conns[st] = conn
 	cp.conns[st.Mirror()] = conn
 	return conn
@@ -191,6 +221,21 @@ func (cp *connPool) OnStats(f func(connstream.TupleStats)) {
 	}
 }
 
+// RangeConns 遍历所有链接
+func (cp *connPool) RangeConns(f func(st socket.Tuple, conn Conn)) {
+	cp.mut.RLock()
+	defer cp.mut.RUnlock()
+
+	visited := make(map[Conn]struct{}, len(cp.conns)/2)
+	for st, conn := range cp.conns {
+		if _, ok := visited[conn]; ok {
+			continue
+		}
+		visited[conn] = struct{}{}
+		f(st, conn)
+	}
+}
+
 // Clean 清理资源 调用后请勿再次使用
 func (cp *connPool) Clean() {
 	if cp.frozen != nil {
@@ -290,6 +335,45 @@ func NewL7UDPConnPool(createMatcher CreateMatcherFunc, createRoundTrip CreateRou
 	)
 }
 
+// OptTransactionTimeout 基于事务 ID 配对的 UDP 协议中请求等待响应的最长时间
+const OptTransactionTimeout = "transactionTimeout"
+
+var transactionExpiredTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: common.App,
+		Name:      "transaction_expired_requests_total",
+		Help:      "UDP requests dropped because no response arrived within the transaction timeout",
+	},
+	[]string{"proto"},
+)
+
+// UDPTransaction 描述 UDP 协议如何按照事务 ID 配对请求与响应
+//
+// Size 为单链接最多等待配对的请求数量 Timeout 为协议默认超时时间 可被 OptTransactionTimeout 覆盖
+type UDPTransaction struct {
+	Size    int
+	Timeout time.Duration
+	Extract role.TransactionFunc
+}
+
+// NewL7UDPTransactionConnPool 创建基于 UDP 协议且按照 (四元组, 事务 ID) 配对的 Layer7 连接池
+//
+// NTP、SNMP 以及自定义 UDP RPC 等协议仅需提供事务 ID 提取函数 即可复用 TCP 协议的 RoundTrip 语义
+func NewL7UDPTransactionConnPool(opts common.Options, tx UDPTransaction, createRoundTrip CreateRoundTripFunc, createDecoder CreateDecoderFunc) ConnPool {
+	timeout := tx.Timeout
+	if v, err := opts.GetDuration(OptTransactionTimeout); err == nil && v > 0 {
+		timeout = v
+	}
+
+	return NewL7UDPConnPool(
+		func() role.Matcher {
+			return role.NewTransactionMatcher(tx.Size, timeout, tx.Extract)
+		},
+		createRoundTrip,
+		createDecoder,
+	)
+}
+
 type socketDecoder struct {
 	st socket.Tuple
 	d  Decoder
@@ -305,6 +389,23 @@ type L7TCPConn struct {
 	matcher    role.Matcher
 
 	l, r *socketDecoder
+	skew skewOffset
+
+	// clientFIN 客户端已经半关闭链接 服务端仍可以继续发送响应
+	clientFIN bool
+
+	// truncated 非空代表链接已经收到过截断的数据包 此后不再调用 Decoder
+	l7Proto   socket.L7Proto
+	truncated *truncatedTracker
+
+	// iface 数据包所属的网卡 由 sniffer 设置 输出的 RoundTrip 均会附加该网卡
+	iface string
+
+	// halfOpenAt 链接最近一次被判定为半开的时间（UnixNano）为 0 代表未被判定
+	halfOpenAt atomic.Int64
+
+	// seq 链接内已输出的 RoundTrip 数量 用于区分同一数据包内大小相同的流水线请求
+	seq uint64
 
 	once     sync.Once
 	released atomic.Bool
@@ -347,7 +448,55 @@ func (c *L7TCPConn) ActiveAt() time.Time {
 	return c.conn.ActiveAt()
 }
 
+func (c *L7TCPConn) AckStates() []connstream.AckState {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	return c.conn.AckStates()
+}
+
+// Pending 返回 Matcher 中等待响应的请求数量 Matcher 无法统计时返回 0
+func (c *L7TCPConn) Pending() int {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if m, ok := c.matcher.(interface{ Pending() int }); ok {
+		return m.Pending()
+	}
+	return 0
+}
+
+// MarkHalfOpen 标记链接于 t 时刻被判定为半开
+func (c *L7TCPConn) MarkHalfOpen(t time.Time) {
+	c.halfOpenAt.Store(t.UnixNano())
+}
+
+// annotate 为输出的 RoundTrip 附加链接级别的标记
+//
+// 请求先于半开判定发出的 RoundTrip 其耗时包含了链接半开期间的等待 判定之后发出的请求不受影响
+func (c *L7TCPConn) annotate(rt socket.RoundTrip) socket.RoundTrip {
+	c.seq++
+	rt = socket.WithSequence(rt, c.seq)
+	rt = socket.WithIface(rt, c.iface)
+	if at := c.halfOpenAt.Load(); at > 0 {
+		if req, ok := socket.PeerOf(rt.Request()); ok && req.Time.UnixNano() <= at {
+			rt = socket.WithHalfOpen(rt)
+		}
+	}
+	return rt
+}
+
+func (c *L7TCPConn) Flows() []connstream.FlowStats {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	return c.conn.Flows()
+}
+
 func (c *L7TCPConn) Stats() []connstream.TupleStats {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
 	return c.conn.Stats()
 }
 
@@ -360,31 +509,92 @@ func (c *L7TCPConn) OnL4Packet(pkt socket.L4Packet, ch chan<- socket.RoundTrip)
 	c.mut.Lock()
 	defer c.mut.Unlock()
 
-	d := c.getDecoder(pkt.SocketTuple())
+	st := pkt.SocketTuple()
+	t := c.skew.adjust(st, c.serverPort, pkt.ArrivedTime())
+	if iface := socket.PacketIface(pkt); iface != "" {
+		c.iface = iface
+	}
+
+	size, truncated := payloadLen(pkt)
+	if truncated && c.truncated == nil {
+		c.truncated = newTruncatedTracker(c.l7Proto, c.serverPort)
+	}
+	if c.truncated != nil {
+		return c.onTruncatedPacket(pkt, size, t, ch)
+	}
+
+	d := c.getDecoder(st)
 	err := c.conn.Write(pkt, func(r zerocopy.Reader) {
-		objs, err := d.Decode(r, pkt.ArrivedTime())
+		objs, err := d.Decode(r, t)
 		if err != nil {
+			recordDecodeError(pkt, d, err)
 			return
 		}
+		c.emit(objs, ch)
+	})
 
-		for i := 0; i < len(objs); i++ {
-			obj := objs[i]
-			if obj == nil {
-				continue
-			}
+	if seg, ok := pkt.(*socket.TCPSegment); ok {
+		c.onTeardown(seg, st, t, ch)
+	}
 
-			pair := c.matcher.Match(obj)
-			if pair == nil {
-				continue
-			}
+	if errors.Is(err, connstream.ErrClosed) {
+		return ErrConnClosed
+	}
+	return err
+}
 
-			roundTrip := c.createRoundTrip(pair)
-			if !roundTrip.Validate() {
-				continue
-			}
-			ch <- roundTrip
+// onTeardown 判断客户端是否中断了链接
+//
+// 客户端 RST 即为中断 FIN 仅代表半关闭 服务端仍可以继续发送响应
+// 只有在客户端 FIN 之后服务端也随之 RST/FIN 关闭链接时 尚未传输完成的响应才视为中断
+// 服务端先行关闭时交由 ServerCloser 处理
+func (c *L7TCPConn) onTeardown(seg *socket.TCPSegment, st socket.Tuple, t time.Time, ch chan<- socket.RoundTrip) {
+	if st.DstPort == c.serverPort {
+		switch {
+		case seg.RST:
+			c.abort(st.Mirror(), t, ch)
+		case seg.FIN:
+			c.clientFIN = true
 		}
-	})
+		return
+	}
+	if c.clientFIN && (seg.FIN || seg.RST) {
+		c.abort(st, t, ch)
+		return
+	}
+	if seg.FIN || seg.RST {
+		c.serverClose(st, t, ch)
+	}
+}
+
+// serverClose 服务端先于客户端关闭链接时通知服务端方向的 Decoder
+func (c *L7TCPConn) serverClose(st socket.Tuple, t time.Time, ch chan<- socket.RoundTrip) {
+	var d Decoder
+	switch {
+	case c.l != nil && c.l.st == st:
+		d = c.l.d
+	case c.r != nil && c.r.st == st:
+		d = c.r.d
+	}
+
+	if sc, ok := d.(ServerCloser); ok {
+		c.emit(sc.ServerClose(t), ch)
+	}
+}
+
+// onTruncatedPacket 截断抓包模式下仅统计字节数以及耗时
+//
+// Payload 不完整时 Decoder 解析结果不可信 字节流仍然写入 connstream 以维持 Layer4 统计
+func (c *L7TCPConn) onTruncatedPacket(pkt socket.L4Packet, size int, t time.Time, ch chan<- socket.RoundTrip) error {
+	err := c.conn.Write(pkt, nil)
+
+	rt := c.truncated.observe(pkt.SocketTuple(), size, t)
+	if seg, ok := pkt.(*socket.TCPSegment); ok && (seg.FIN || seg.RST) && rt == nil {
+		rt = c.truncated.flush()
+	}
+	if rt != nil && rt.Validate() {
+		ch <- c.annotate(rt)
+	}
 
 	if errors.Is(err, connstream.ErrClosed) {
 		return ErrConnClosed
@@ -392,6 +602,78 @@ func (c *L7TCPConn) OnL4Packet(pkt socket.L4Packet, ch chan<- socket.RoundTrip)
 	return err
 }
 
+// payloadLen 返回数据包 Payload 在链路上的实际长度以及是否被截断
+func payloadLen(pkt socket.L4Packet) (int, bool) {
+	switch p := pkt.(type) {
+	case *socket.TCPSegment:
+		return p.PayloadLen(), p.Truncated()
+	case *socket.UDPDatagram:
+		return p.PayloadLen(), p.Truncated()
+	}
+	return 0, false
+}
+
+// recordExpired 记录 Matcher 中因超时被丢弃的请求数量
+func (c *L7TCPConn) recordExpired() {
+	m, ok := c.matcher.(interface{ Expired() int })
+	if !ok {
+		return
+	}
+	if n := m.Expired(); n > 0 {
+		transactionExpiredTotal.WithLabelValues(string(c.l7Proto)).Add(float64(n))
+	}
+}
+
+// emit 配对 Decoder 归档的对象并投递 RoundTrip
+func (c *L7TCPConn) emit(objs []*role.Object, ch chan<- socket.RoundTrip) {
+	for i := 0; i < len(objs); i++ {
+		obj := objs[i]
+		if obj == nil {
+			continue
+		}
+
+		var pair *role.Pair
+		if obj.Role == role.OneWay {
+			pair = &role.Pair{Request: obj} // 单向事件不存在响应 无需配对
+		} else {
+			pair = c.matcher.Match(obj)
+			c.recordExpired()
+		}
+		if pair == nil {
+			continue
+		}
+
+		roundTrip := c.createRoundTrip(pair)
+		if !roundTrip.Validate() {
+			c.skew.observe(roundTrip)
+			continue
+		}
+		if s, ok := roundTrip.(TCPConnectSetter); ok {
+			if d, ok := c.conn.Handshake(); ok {
+				s.SetTCPConnect(d)
+			}
+		}
+		ch <- c.annotate(roundTrip)
+	}
+}
+
+// abort 客户端中断链接时通知服务端方向的 Decoder 提前归档未传输完成的响应
+//
+// 仅查找已经存在的 Decoder 服务端尚未发送过数据时无需处理
+func (c *L7TCPConn) abort(st socket.Tuple, t time.Time, ch chan<- socket.RoundTrip) {
+	var d Decoder
+	switch {
+	case c.l != nil && c.l.st == st:
+		d = c.l.d
+	case c.r != nil && c.r.st == st:
+		d = c.r.d
+	}
+
+	if a, ok := d.(Aborter); ok {
+		c.emit(a.Abort(t), ch)
+	}
+}
+
 // getDecoder 匹配 Decoder
 //
 // 从 l->r 顺序匹配 会比 Map 更高效
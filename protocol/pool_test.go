@@ -0,0 +1,178 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/connstream"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+type abortDecoder struct {
+	aborts *[]socket.Tuple
+	st     socket.Tuple
+}
+
+func (d abortDecoder) Decode(zerocopy.Reader, time.Time) ([]*role.Object, error) {
+	return nil, nil
+}
+
+func (d abortDecoder) Abort(time.Time) []*role.Object {
+	*d.aborts = append(*d.aborts, d.st)
+	return nil
+}
+
+func (d abortDecoder) Free() {}
+
+func TestL7TCPConnAbort(t *testing.T) {
+	client := socket.Tuple{SrcIP: socket.ToIPV4([]byte{10, 0, 0, 1}), SrcPort: 50001, DstIP: socket.ToIPV4([]byte{10, 0, 0, 2}), DstPort: 80}
+	server := client.Mirror()
+	start := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name string
+		segs []*socket.TCPSegment
+		want []socket.Tuple
+	}{
+		{
+			name: "HalfClose",
+			segs: []*socket.TCPSegment{
+				{Tuple: client, Seq: 1, Payload: []byte("GET"), FIN: true},
+				{Tuple: server, Seq: 1, Payload: []byte("200")},
+			},
+		},
+		{
+			name: "HalfCloseThenServerClose",
+			segs: []*socket.TCPSegment{
+				{Tuple: client, Seq: 1, Payload: []byte("GET"), FIN: true},
+				{Tuple: server, Seq: 1, Payload: []byte("200")},
+				{Tuple: server, Seq: 4, RST: true},
+			},
+			want: []socket.Tuple{server},
+		},
+		{
+			name: "ServerCloseOnly",
+			segs: []*socket.TCPSegment{
+				{Tuple: client, Seq: 1, Payload: []byte("GET")},
+				{Tuple: server, Seq: 1, Payload: []byte("200"), FIN: true},
+			},
+		},
+		{
+			name: "ClientReset",
+			segs: []*socket.TCPSegment{
+				{Tuple: client, Seq: 1, Payload: []byte("GET")},
+				{Tuple: server, Seq: 1, Payload: []byte("200")},
+				{Tuple: client, Seq: 4, RST: true},
+			},
+			want: []socket.Tuple{server},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var aborts []socket.Tuple
+			conn := NewL7Conn(
+				connstream.NewConn(client, connstream.NewTCPStream),
+				80,
+				nil,
+				nil,
+				func(st socket.Tuple, _ socket.Port) Decoder { return abortDecoder{aborts: &aborts, st: st} },
+			)
+
+			ch := make(chan socket.RoundTrip, 4)
+			for _, seg := range tt.segs {
+				seg.Time = start
+				conn.OnL4Packet(seg, ch)
+			}
+			assert.Equal(t, tt.want, aborts)
+		})
+	}
+}
+
+func TestL7TCPConnMarkHalfOpen(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	newRoundTrip := func(reqTime time.Time) socket.RoundTrip {
+		return &TruncatedRoundTrip{
+			proto:    socket.L7ProtoHTTP,
+			request:  &TruncatedMessage{Host: "10.0.0.1", Port: 50001, Time: reqTime},
+			response: &TruncatedMessage{Host: "10.0.0.2", Port: 80, Time: reqTime.Add(time.Minute)},
+		}
+	}
+
+	c := &L7TCPConn{}
+	assert.False(t, socket.IsHalfOpen(c.annotate(newRoundTrip(t0))))
+
+	// 仅判定之前发出的请求受影响
+	c.MarkHalfOpen(t0.Add(30 * time.Second))
+	marked := c.annotate(newRoundTrip(t0))
+	assert.True(t, socket.IsHalfOpen(marked))
+	assert.True(t, socket.IsTruncatedCapture(marked))
+	assert.False(t, socket.IsHalfOpen(c.annotate(newRoundTrip(t0.Add(time.Minute)))))
+}
+
+// pipelineDecoder 每个数据包归档两个大小相同的对象 模拟同一分段内的流水线请求
+type pipelineDecoder struct {
+	st         socket.Tuple
+	serverPort socket.Port
+}
+
+func (d pipelineDecoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error) {
+	b, _ := r.Read(1024)
+	msg := func() *TruncatedMessage {
+		return &TruncatedMessage{Host: d.st.SrcIP.String(), Port: uint16(d.st.SrcPort), Size: len(b) / 2, Time: t}
+	}
+	if d.st.DstPort == d.serverPort {
+		return []*role.Object{role.NewRequestObject(msg()), role.NewRequestObject(msg())}, nil
+	}
+	return []*role.Object{role.NewResponseObject(msg()), role.NewResponseObject(msg())}, nil
+}
+
+func (d pipelineDecoder) Free() {}
+
+func TestL7TCPConnPipelinedEventID(t *testing.T) {
+	client := socket.Tuple{SrcIP: socket.ToIPV4([]byte{10, 0, 0, 1}), SrcPort: 50001, DstIP: socket.ToIPV4([]byte{10, 0, 0, 2}), DstPort: 6379}
+	t0 := time.Unix(1700000000, 0)
+
+	conn := NewL7Conn(
+		connstream.NewConn(client, connstream.NewTCPStream),
+		6379,
+		role.NewListMatcher(8, func(req, rsp *role.Object) bool { return true }),
+		func(pair *role.Pair) socket.RoundTrip {
+			return &TruncatedRoundTrip{
+				proto:    socket.L7ProtoRedis,
+				request:  pair.Request.Obj.(*TruncatedMessage),
+				response: pair.Response.Obj.(*TruncatedMessage),
+			}
+		},
+		func(st socket.Tuple, serverPort socket.Port) Decoder {
+			return pipelineDecoder{st: st, serverPort: serverPort}
+		},
+	)
+
+	ch := make(chan socket.RoundTrip, 2)
+	conn.OnL4Packet(&socket.TCPSegment{Tuple: client, Time: t0, Seq: 1, Payload: []byte("PINGPING")}, ch)
+	conn.OnL4Packet(&socket.TCPSegment{Tuple: client.Mirror(), Time: t0.Add(time.Millisecond), Seq: 1, Payload: []byte("PONGPONG")}, ch)
+	assert.Len(t, ch, 2)
+
+	rt1, rt2 := <-ch, <-ch
+	assert.Equal(t, rt1.Request(), rt2.Request())
+	assert.Equal(t, rt1.Response(), rt2.Response())
+	assert.NotEqual(t, socket.EventID(rt1), socket.EventID(rt2))
+}
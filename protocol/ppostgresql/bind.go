@@ -0,0 +1,156 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ppostgresql
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol"
+)
+
+const (
+	// OptCaptureBindParams 是否解析 Bind 消息中的参数 记录至 QueryPacket.Params
+	OptCaptureBindParams = "captureBindParams"
+
+	// OptMaxBindParamSize 单个参数值记录的最大字节数 超出部分截断
+	OptMaxBindParamSize = "maxBindParamSize"
+
+	// OptRedactBindParams 是否隐去参数值 仅保留格式以及长度
+	OptRedactBindParams = "redactBindParams"
+
+	defaultMaxBindParamSize = 64
+
+	// maxBindSize Bind 消息缓冲区大小 超出部分的参数不再解析
+	maxBindSize = 4096
+
+	// maxBindParams 单条 Bind 消息最多记录的参数个数
+	maxBindParams = 32
+)
+
+var bindParamsCapturedTotal = protocol.NewOptionCounter(socket.L7ProtoPostgreSQL, OptCaptureBindParams, "captured")
+
+// 参数格式
+const (
+	bindFormatText   = "text"
+	bindFormatBinary = "binary"
+)
+
+// BindParam Bind 消息中的参数摘要
+//
+// Value 在 text 格式下为原文 binary 格式下为十六进制编码 NULL 参数的 Size 为 -1
+type BindParam struct {
+	Format    string
+	Size      int
+	Value     string `json:",omitempty"`
+	Truncated bool   `json:",omitempty"`
+}
+
+type bindOptions struct {
+	enabled bool
+	maxSize int
+	redact  bool
+}
+
+func newBindOptions(opts common.Options) bindOptions {
+	enabled, _ := opts.GetBool(OptCaptureBindParams)
+	redact, _ := opts.GetBool(OptRedactBindParams)
+	maxSize, err := opts.GetInt(OptMaxBindParamSize)
+	if err != nil || maxSize <= 0 {
+		maxSize = defaultMaxBindParamSize
+	}
+	return bindOptions{
+		enabled: enabled,
+		maxSize: maxSize,
+		redact:  redact,
+	}
+}
+
+// parseBindParams 解析 Bind 消息中语句名称之后的参数部分 布局如下
+//
+// ┌───────────────┬─────────────────┬─────────────┬──────────────────────────────┐
+// │ Format Count  │ Format Codes    │ Param Count │ Param Values                 │
+// │ (2B)          │ [C] (2B each)   │ (2B)        │ [N] Length (4B) + Value      │
+// └───────────────┴─────────────────┴─────────────┴──────────────────────────────┘
+//
+// - Format Count 为 0 时所有参数均为 text 格式 为 1 时所有参数共用同一格式 否则与参数一一对应
+// - Length 为 -1 时表示 NULL 且没有后续的 Value
+//
+// b 可能因缓冲区上限被截断 仅返回完整解析出长度的参数
+func parseBindParams(b []byte, opts bindOptions) []BindParam {
+	if len(b) < 2 {
+		return nil
+	}
+	formatCount := int(binary.BigEndian.Uint16(b))
+	b = b[2:]
+	if len(b) < formatCount*2+2 {
+		return nil
+	}
+	formats := b[:formatCount*2]
+	b = b[formatCount*2:]
+
+	paramCount := int(binary.BigEndian.Uint16(b))
+	b = b[2:]
+
+	var params []BindParam
+	for i := 0; i < paramCount && i < maxBindParams; i++ {
+		if len(b) < 4 {
+			break
+		}
+		size := int(int32(binary.BigEndian.Uint32(b)))
+		b = b[4:]
+
+		param := BindParam{Format: bindFormat(formats, i), Size: size}
+		if size < 0 {
+			params = append(params, param)
+			continue
+		}
+
+		value := b
+		if len(value) > size {
+			value = value[:size]
+		}
+		b = b[len(value):]
+		if !opts.redact {
+			if len(value) > opts.maxSize {
+				value = value[:opts.maxSize]
+			}
+			if param.Format == bindFormatBinary {
+				param.Value = hex.EncodeToString(value)
+			} else {
+				param.Value = string(value)
+			}
+			param.Truncated = len(value) < size
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+// bindFormat 返回第 i 个参数的格式
+func bindFormat(formats []byte, i int) string {
+	var code uint16
+	switch n := len(formats) / 2; {
+	case n == 1:
+		code = binary.BigEndian.Uint16(formats)
+	case i < n:
+		code = binary.BigEndian.Uint16(formats[i*2:])
+	}
+	if code == 1 {
+		return bindFormatBinary
+	}
+	return bindFormatText
+}
@@ -0,0 +1,161 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ppostgresql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol"
+)
+
+func TestParseBindParams(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		opts  bindOptions
+		want  []BindParam
+	}{
+		{
+			name: "AllText",
+			input: []byte{
+				0x00, 0x00, // format count
+				0x00, 0x02, // param count
+				0x00, 0x00, 0x00, 0x03, '1', '2', '3',
+				0xff, 0xff, 0xff, 0xff, // NULL
+			},
+			opts: bindOptions{maxSize: 64},
+			want: []BindParam{
+				{Format: bindFormatText, Size: 3, Value: "123"},
+				{Format: bindFormatText, Size: -1},
+			},
+		},
+		{
+			name: "SharedBinaryFormat",
+			input: []byte{
+				0x00, 0x01, 0x00, 0x01,
+				0x00, 0x01,
+				0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x7b,
+			},
+			opts: bindOptions{maxSize: 64},
+			want: []BindParam{
+				{Format: bindFormatBinary, Size: 4, Value: "0000007b"},
+			},
+		},
+		{
+			name: "PerParamFormat",
+			input: []byte{
+				0x00, 0x02, 0x00, 0x00, 0x00, 0x01,
+				0x00, 0x02,
+				0x00, 0x00, 0x00, 0x01, 'a',
+				0x00, 0x00, 0x00, 0x01, 0x01,
+			},
+			opts: bindOptions{maxSize: 64},
+			want: []BindParam{
+				{Format: bindFormatText, Size: 1, Value: "a"},
+				{Format: bindFormatBinary, Size: 1, Value: "01"},
+			},
+		},
+		{
+			name: "Truncated",
+			input: []byte{
+				0x00, 0x00,
+				0x00, 0x01,
+				0x00, 0x00, 0x00, 0x05, 'h', 'e', 'l', 'l', 'o',
+			},
+			opts: bindOptions{maxSize: 2},
+			want: []BindParam{
+				{Format: bindFormatText, Size: 5, Value: "he", Truncated: true},
+			},
+		},
+		{
+			name: "Redacted",
+			input: []byte{
+				0x00, 0x00,
+				0x00, 0x01,
+				0x00, 0x00, 0x00, 0x05, 'h', 'e', 'l', 'l', 'o',
+			},
+			opts: bindOptions{maxSize: 64, redact: true},
+			want: []BindParam{
+				{Format: bindFormatText, Size: 5},
+			},
+		},
+		{
+			name: "BufferExhausted",
+			input: []byte{
+				0x00, 0x00,
+				0x00, 0x02,
+				0x00, 0x00, 0x00, 0x05, 'h', 'e',
+			},
+			opts: bindOptions{maxSize: 64},
+			want: []BindParam{
+				{Format: bindFormatText, Size: 5, Value: "he", Truncated: true},
+			},
+		},
+		{
+			name:  "Malformed",
+			input: []byte{0x00, 0x05, 0x00},
+			opts:  bindOptions{maxSize: 64},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseBindParams(tt.input, tt.opts))
+		})
+	}
+}
+
+func TestDecodeBindParams(t *testing.T) {
+	var st socket.Tuple
+	var t0 time.Time
+
+	opts := common.NewOptions()
+	opts.Merge(OptCaptureBindParams, true)
+	d := newDecoder(st, 5432, opts, protocol.NewConnContext(0), nil)
+	d.st.DstPort = 5432
+
+	parse := append([]byte("s1"), 0x00)
+	parse = append(parse, []byte("SELECT * FROM users WHERE id = $1")...)
+	parse = append(parse, 0x00, 0x00, 0x00)
+	_, err := d.Decode(zerocopy.NewBuffer(buildMessage('P', parse...)), t0)
+	assert.NoError(t, err)
+
+	bind := buildMessage('B',
+		0x00,           // portal
+		's', '1', 0x00, // statement
+		0x00, 0x00, // format count
+		0x00, 0x01, // param count
+		0x00, 0x00, 0x00, 0x02, '4', '2',
+		0x00, 0x00, // result format count
+	)
+
+	// Bind 消息跨越多次 Decode
+	objs, err := d.Decode(zerocopy.NewBuffer(bind[:9]), t0)
+	assert.NoError(t, err)
+	assert.Empty(t, objs)
+
+	objs, err = d.Decode(zerocopy.NewBuffer(bind[9:]), t0)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.Equal(t, &QueryPacket{
+		Statement: "SELECT * FROM users WHERE id = $1",
+		Params:    []BindParam{{Format: bindFormatText, Size: 2, Value: "42"}},
+	}, objs[0].Obj.(*Request).Packet)
+}
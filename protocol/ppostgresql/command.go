@@ -13,8 +13,6 @@
 
 package ppostgresql
 
-import "container/list"
-
 const (
 	flagExecuteOrErrorResponse = 'E'
 	flagDescribeOrDataRow      = 'D'
@@ -98,39 +96,3 @@ var serverFlagNames = map[uint8]string{
 	flagEmptyQueryResponse:              "EmptyQueryResponse",
 	flagParameterStatus:                 "ParameterStatus",
 }
-
-type namedStatement struct {
-	name      string
-	statement string
-}
-
-type namedStatementCache struct {
-	size int
-	l    *list.List
-}
-
-func newNamedStatementCache(size int) *namedStatementCache {
-	return &namedStatementCache{
-		size: size,
-		l:    list.New(),
-	}
-}
-
-func (c *namedStatementCache) Set(name, statement string) {
-	if c.l.Len() >= c.size {
-		c.l.Remove(c.l.Front())
-	}
-	c.l.PushBack(namedStatement{
-		name:      name,
-		statement: statement,
-	})
-}
-
-func (c *namedStatementCache) Get(name string) string {
-	for e := c.l.Front(); e != nil; e = e.Next() {
-		if e.Value.(namedStatement).name == name {
-			return e.Value.(namedStatement).statement
-		}
-	}
-	return ""
-}
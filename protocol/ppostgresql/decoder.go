@@ -20,11 +20,10 @@ import (
 	"strings"
 	"time"
 
-	"github.com/pkg/errors"
-
 	"github.com/packetd/packetd/common"
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/bufbytes"
+	"github.com/packetd/packetd/internal/tailbuf"
 	"github.com/packetd/packetd/internal/zerocopy"
 	"github.com/packetd/packetd/protocol"
 	"github.com/packetd/packetd/protocol/role"
@@ -34,14 +33,14 @@ const (
 	PROTO = "PostgreSQL"
 )
 
-func newError(format string, args ...any) error {
-	format = "postgresql/decoder: " + format
-	return errors.Errorf(format, args...)
+func newError(code protocol.ErrorCode, format string, args ...any) error {
+	return protocol.NewDecodeError(socket.L7ProtoPostgreSQL, code, "postgresql/decoder: "+format, args...)
 }
 
 var (
-	errInvalidBytes = newError("invalid bytes")
-	errDecodeHeader = newError("decode Header failed")
+	errResyncFailed    = newError(protocol.ErrCodeResyncFailed, "message boundary lost")
+	errHeaderTooShort  = newError(protocol.ErrCodeHeaderTooShort, "header too short")
+	errPayloadOverflow = newError(protocol.ErrCodeLengthOverflow, "payload length overflow")
 )
 
 const (
@@ -63,8 +62,8 @@ const (
 	// startupMessage startup message 标识
 	startupMessage uint32 = 196608
 
-	// maxNamedCacheSize named cache 缓冲区大小
-	maxNamedCacheSize = 16
+	// ctxStatementPrefix 预处理语句在链接上下文中的 key 前缀
+	ctxStatementPrefix = "statement:"
 )
 
 // state 记录着 decoder 的处理状态
@@ -88,30 +87,56 @@ type decoder struct {
 	state           state
 	drainBytes      int
 
-	nsc     *namedStatementCache
+	ctx     *protocol.ConnContext
 	reqTime time.Time
+	pipe    *pipeline
+	skipped uint64 // ReadyForQuery 时被服务端跳过的语句的最大序号
+	release func()
 
 	statementName *bufbytes.Bytes
 	statement     *bufbytes.Bytes
+	bind          *bufbytes.Bytes // 仅开启 OptCaptureBindParams 时缓存完整的 Bind 消息
+	bindOpts      bindOptions
 	describe      *bufbytes.Bytes
 	readall       bool
 	count         int
 
 	flag    uint8
 	packet  any
-	tail    []byte // 尾部数据拼接 仅允许拼接一次 避免上一轮切割了部分数据
+	tail    tailbuf.Buffer // 尾部数据拼接 仅允许拼接一次 避免上一轮切割了部分数据
 	partial uint8
+
+	// 流复制状态 slot 仅 client 端记录 streaming 与 stream 仅 server 端记录
+	copyHeader *bufbytes.Bytes
+	slot       *StartReplicationPacket
+	streaming  bool
+	stream     replicationStream
+}
+
+// NewDecoder 创建 PostgreSQL 解码器
+//
+// 独立创建的 decoder 无法与另一个方向共享 pipeline 链接池内应使用 newDecoder
+func NewDecoder(st socket.Tuple, serverPort socket.Port, opts common.Options) protocol.Decoder {
+	return newDecoder(st, serverPort, opts, protocol.NewConnContext(0), nil)
 }
 
-func NewDecoder(st socket.Tuple, serverPort socket.Port, _ common.Options) protocol.Decoder {
-	return &decoder{
+func newDecoder(st socket.Tuple, serverPort socket.Port, opts common.Options, ctx *protocol.ConnContext, release func()) *decoder {
+	d := &decoder{
 		st:            st.ToRaw(),
 		serverPort:    serverPort,
 		statement:     bufbytes.New(maxStatementSize),
 		statementName: bufbytes.New(maxStatementNameSize),
 		describe:      bufbytes.New(maxDescribeSize),
-		nsc:           newNamedStatementCache(maxNamedCacheSize),
+		copyHeader:    bufbytes.New(maxCopyDataHeaderSize),
+		bindOpts:      newBindOptions(opts),
+		ctx:           ctx,
+		pipe:          connPipeline(ctx),
+		release:       release,
 	}
+	if d.bindOpts.enabled {
+		d.bind = bufbytes.New(maxBindSize)
+	}
+	return d
 }
 
 // Decode 从 zerocopy.Reader 中不断解析来自 Request / Response 的数据 并判断是否能构建成 RoundTrip
@@ -129,6 +154,10 @@ func NewDecoder(st socket.Tuple, serverPort socket.Port, _ common.Options) proto
 // C: P / B / E / C
 // S: R / D / D... / C
 //
+// - Pipeline:
+// C: P / B / E / P / B / E ... / S
+// S: 1 / 2 / C / 1 / 2 / C ... / Z
+//
 // # 下面是一个 ExtendQuery 的具体示例
 //
 // +--------------------+                      +-----------------+
@@ -176,24 +205,28 @@ func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error)
 	}
 
 	var complete bool
+	var objs []*role.Object
 
 	// 持续解析读取到的所有字节 直到 EOF
+	// pipeline 模式下单次读取可能包含多个语句 需要全部归档
 	for len(b) > 0 {
 		// 如果已经出现过两次拼接 返回解析错误
 		if d.partial > 1 {
-			return nil, errInvalidBytes
+			return nil, errResyncFailed
 		}
 
 		if d.partial == 1 {
-			b = append(d.tail, b...)
+			b = d.tail.Join(b)
 		}
 		b, complete, err = d.decode(b)
 		if err != nil {
 			if d.partial == 1 {
+				// b 指向 Join 返回的内存 下一轮 Join 会复用该数组 需先拷贝
+				b = bytes.Clone(b)
 				continue
 			}
 			d.reset() // 错误即重置
-			return nil, err
+			return objs, err
 		}
 
 		d.partial = 0 // 当轮次解析没问题
@@ -201,38 +234,82 @@ func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error)
 			continue
 		}
 
-		return d.archive(), nil
+		objs = append(objs, d.archive()...)
 	}
-	return nil, nil
+	return objs, nil
 }
 
 // Free 释放持有的资源
 func (d *decoder) Free() {
-	d.tail = nil
+	d.tail.Free()
+	if d.release != nil {
+		d.release()
+		d.release = nil
+	}
 }
 
 // archive 归档请求
 func (d *decoder) archive() []*role.Object {
 	if d.isClient() {
+		var seq uint64
+		switch d.flag {
+		case flagQuery:
+			seq = d.pipe.Statement(kindStatement)
+			d.pipe.Sync() // SimpleQuery 隐含了 Sync 语义
+		case flagBind:
+			seq = d.pipe.Statement(kindStatement)
+		case flagDescribe:
+			seq = d.pipe.Statement(kindDescribe)
+		case flagClose:
+			seq = d.pipe.Statement(kindClose)
+		case flagCopyData:
+			seq = d.pipe.Statement(kindStatement) // 流复制汇报 由服务端推送的下一个数据包完成
+		}
+
 		obj := role.NewRequestObject(&Request{
-			Size:   d.drainBytes,
-			Proto:  PROTO,
-			Time:   d.reqTime,
-			Host:   d.st.SrcIP,
-			Port:   d.st.SrcPort,
-			Packet: d.packet,
+			Seq:      seq,
+			Size:     d.drainBytes,
+			Proto:    PROTO,
+			Time:     d.reqTime,
+			Host:     d.st.SrcIP,
+			Port:     d.st.SrcPort,
+			Database: d.ctx.Get(protocol.CtxDatabase),
+			Packet:   d.packet,
+			Comment:  parseComment(d.packet),
 		})
 		d.reset()
 		return []*role.Object{obj}
 	}
 
+	var seq uint64
+	var flush bool
+	switch d.flag {
+	case flagCommandComplete, flagCopyData:
+		seq, _ = d.pipe.Complete(kindStatement)
+	case flagErrorResponse:
+		seq, _ = d.pipe.Complete(kindAny)
+	case flagRowDescription, flagNoData:
+		seq, _ = d.pipe.Complete(kindDescribe)
+	case flagCloseCompleteOrDescribeResponse:
+		seq, _ = d.pipe.Complete(kindClose)
+	case flagCopyBothResponse:
+		// 流复制期间不会再有 ReadyForQuery 需要同时丢弃 START_REPLICATION 隐含的 Sync
+		seq, _ = d.pipe.Complete(kindStatement)
+		d.pipe.Ready()
+	case flagReadyForQuery:
+		seq, flush = d.skipped, true
+		d.skipped = 0
+	}
+
 	obj := role.NewResponseObject(&Response{
+		Seq:    seq,
 		Size:   d.drainBytes,
 		Time:   d.t0,
 		Proto:  PROTO,
 		Host:   d.st.SrcIP,
 		Port:   d.st.SrcPort,
 		Packet: d.packet,
+		flush:  flush,
 	})
 	d.reset()
 	return []*role.Object{obj}
@@ -242,8 +319,8 @@ func (d *decoder) decode(b []byte) ([]byte, bool, error) {
 	if d.state == stateDecodeHeader {
 		if len(b) < headerLength {
 			d.partial++
-			d.tail = bytes.Clone(b)
-			return nil, false, errDecodeHeader
+			d.tail.Set(b)
+			return nil, false, errHeaderTooShort
 		}
 
 		// 如果是 StartupMessage 则表示是客户端发起的连接
@@ -256,6 +333,7 @@ func (d *decoder) decode(b []byte) ([]byte, bool, error) {
 		//└─────────────┴─────────────┴───────────────────────────┘
 		if len(b) >= 8 && d.count == 1 {
 			if binary.BigEndian.Uint32(b[4:8]) == startupMessage {
+				d.decodeStartupMessage(b[8:])
 				return nil, false, nil
 			}
 		}
@@ -265,6 +343,7 @@ func (d *decoder) decode(b []byte) ([]byte, bool, error) {
 		}
 
 		d.flag = b[0]
+		d.readall = false // Parse 等不产生 packet 的消息不会触发 reset 需在此清除上一个消息的状态
 		if d.isClient() && d.drainBytes == 0 {
 			d.reqTime = d.t0
 		}
@@ -286,7 +365,8 @@ func (d *decoder) decode(b []byte) ([]byte, bool, error) {
 // 传入 f 用于解析觉得类型的 CommandPacket 返回是否已构建成一个 Request / Response
 // 中间状态会记录在 decoder 的各种字段中
 func (d *decoder) decodePayload(b []byte) ([]byte, bool, error) {
-	if len(b) == 0 {
+	// 无 payload 的数据包（如 Sync）在 header 解析完成时即已完整
+	if len(b) == 0 && d.payloadConsumed != d.payloadLen {
 		return nil, false, nil
 	}
 
@@ -305,7 +385,7 @@ func (d *decoder) decodePayload(b []byte) ([]byte, bool, error) {
 	if n > d.payloadLen {
 		consumed := d.payloadLen - d.payloadConsumed
 		if d.payloadLen < d.payloadConsumed {
-			return nil, false, errInvalidBytes
+			return nil, false, errPayloadOverflow
 		}
 		d.payloadConsumed += consumed
 		d.drainBytes += int(consumed)
@@ -328,6 +408,10 @@ func (d *decoder) reset() {
 	d.statementName.Reset()
 	d.statement.Reset()
 	d.describe.Reset()
+	d.copyHeader.Reset()
+	if d.bind != nil {
+		d.bind.Reset()
+	}
 	d.flag = 0
 	d.readall = false
 	d.packet = nil
@@ -347,7 +431,9 @@ func (d *decoder) decodePacket(b []byte) bool {
 		d.decodeBindPacket(b)
 
 	case flagCloseOrCommandComplete:
-		if !d.isClient() {
+		if d.isClient() {
+			d.decodeClosePacket(b)
+		} else {
 			d.decodeCommandCompletePacket(b)
 		}
 
@@ -365,6 +451,37 @@ func (d *decoder) decodePacket(b []byte) bool {
 		if !d.isClient() {
 			d.decodeOnlyFlagPacket()
 		}
+
+	case flagRowDescription, flagNoData:
+		// 仅 Describe 的结果单独归档 SimpleQuery 的 RowDescription 属于查询结果的一部分
+		if !d.isClient() && d.pipe.Pending(kindDescribe) {
+			d.decodeOnlyFlagPacket()
+		}
+
+	case flagSync:
+		if d.isClient() && d.readall {
+			d.pipe.Sync()
+		}
+
+	case flagReadyForQuery:
+		if !d.isClient() && d.readall {
+			// 存在被跳过的语句时归档 flush 响应 清除其对应的未配对请求
+			if seq, ok := d.pipe.Ready(); ok {
+				d.skipped = seq
+				d.decodeOnlyFlagPacket()
+			}
+		}
+
+	case flagCopyBothResponse:
+		if !d.isClient() {
+			d.decodeCopyBothPacket()
+		}
+
+	case flagCopyData:
+		d.decodeCopyDataPacket(b)
+
+	case flagCancelRequestOrCopyDone:
+		d.decodeCopyDonePacket()
 	}
 
 	// 当且仅当数据包被完整被消费且已经构建成 packet 再返回
@@ -378,6 +495,35 @@ func (d *decoder) isClient() bool {
 	return uint16(d.serverPort) == d.st.DstPort
 }
 
+// decodeStartupMessage 解析 StartupMessage 中的参数 记录链接所连接的数据库
+//
+// 参数为若干组以 \x00 结尾的 key/value 并以单独的 \x00 结束 未指定 database 时默认与 user 同名
+func (d *decoder) decodeStartupMessage(b []byte) {
+	var user, database string
+	for len(b) > 0 && b[0] != cStringEnd {
+		key, rest, ok := bytes.Cut(b, []byte{cStringEnd})
+		if !ok {
+			break
+		}
+		val, rest, ok := bytes.Cut(rest, []byte{cStringEnd})
+		if !ok {
+			break
+		}
+		switch string(key) {
+		case "user":
+			user = string(val)
+		case "database":
+			database = string(val)
+		}
+		b = rest
+	}
+
+	if database == "" {
+		database = user
+	}
+	d.ctx.Set(protocol.CtxDatabase, database)
+}
+
 // decodeHeader 解析数据包 Header 布局如下
 
 // ┌─────────────────────────────────────────────┐
@@ -484,7 +630,7 @@ func (d *decoder) decodeParsePacket(b []byte) {
 
 	// 仅记录有效的 name / statement
 	if name != "" && statement != "" {
-		d.nsc.Set(name, statement)
+		d.ctx.Set(ctxStatementPrefix+name, statement)
 	}
 }
 
@@ -523,9 +669,42 @@ func (p DescribePacket) Name() string {
 // * Target 为 'S': 预处理语句名称（由 Parse 命令定义）
 // * Target 为 'P': 门户名称（由 Bind 命令定义）
 func (d *decoder) decodeDescribePacket(b []byte) {
-	if len(b) <= 1 {
+	typ, object, ok := d.decodeObjectName(b)
+	if !ok {
 		return
 	}
+	d.packet = &DescribePacket{
+		Type:   typ,
+		Object: object,
+	}
+}
+
+type ClosePacket struct {
+	Type   string
+	Object string
+}
+
+func (p ClosePacket) Name() string {
+	return "ClosePacket"
+}
+
+// decodeClosePacket 解析 Close 数据包 布局与 Describe 相同
+func (d *decoder) decodeClosePacket(b []byte) {
+	typ, object, ok := d.decodeObjectName(b)
+	if !ok {
+		return
+	}
+	d.packet = &ClosePacket{
+		Type:   typ,
+		Object: object,
+	}
+}
+
+// decodeObjectName 解析 Describe / Close 数据包中的对象类型（S 预处理语句 / P portal）以及名称
+func (d *decoder) decodeObjectName(b []byte) (string, string, bool) {
+	if len(b) <= 1 {
+		return "", "", false
+	}
 
 	idx := bytes.IndexByte(b, cStringEnd)
 	if idx == -1 {
@@ -539,16 +718,14 @@ func (d *decoder) decodeDescribePacket(b []byte) {
 	}
 
 	if !d.readall {
-		return
-	}
-	d.packet = &DescribePacket{
-		Type:   string(b[0]),
-		Object: d.describe.TrimCStringText(),
+		return "", "", false
 	}
+	return string(b[0]), d.describe.TrimCStringText(), true
 }
 
 type QueryPacket struct {
 	Statement string
+	Params    []BindParam `json:",omitempty"` // 仅开启 OptCaptureBindParams 时解析 Bind 消息中的参数
 }
 
 func (p QueryPacket) Name() string {
@@ -583,8 +760,15 @@ func (d *decoder) decodeQueryPacket(b []byte) {
 	if !d.readall {
 		return
 	}
+
+	statement := d.statement.TrimCStringText()
+	if packet, ok := parseStartReplication(statement); ok {
+		d.slot = packet
+		d.packet = packet
+		return
+	}
 	d.packet = &QueryPacket{
-		Statement: d.statement.TrimCStringText(),
+		Statement: statement,
 	}
 }
 
@@ -610,11 +794,21 @@ func (d *decoder) decodeQueryPacket(b []byte) {
 // - Statement (变长)
 // 预处理语句名称（由 Parse 命令创建）以 \x00 结尾的字符串 为空字符串表示未命名的预处理语句
 //
-// - Param Formats (变长): 参数格式列表（这里不做解析）
-// - Param Values (变长): 参数格式列表（这里不做解析）
+// - Param Formats (变长): 参数格式列表
+// - Param Values (变长): 参数值列表
 //
+// 参数仅在开启 OptCaptureBindParams 时解析 详见 parseBindParams
 // 记录最终 statement 时需要注意去除结尾的 NULL 字节
 func (d *decoder) decodeBindPacket(b []byte) {
+	if d.bind != nil {
+		// 参数位于消息尾部 需缓存至消息完整后统一解析
+		d.bind.Write(b)
+		if !d.readall {
+			return
+		}
+		b = d.bind.Clone()
+	}
+
 	idx := bytes.IndexByte(b, cStringEnd)
 	if idx == -1 {
 		return
@@ -636,9 +830,22 @@ func (d *decoder) decodeBindPacket(b []byte) {
 	if !d.readall {
 		return
 	}
-	d.packet = &QueryPacket{
-		Statement: d.nsc.Get(d.statementName.TrimCStringText()),
+	packet := &QueryPacket{
+		Statement: d.ctx.Get(ctxStatementPrefix + d.statementName.TrimCStringText()),
+	}
+	if d.bind != nil && idx != -1 {
+		packet.Params = parseBindParams(buf[idx+1:], d.bindOpts)
+		bindParamsCapturedTotal.Inc()
 	}
+	d.packet = packet
+}
+
+// parseComment 解析 Query 以及 Bind 对应语句中的注释
+func parseComment(packet any) *protocol.SQLComment {
+	if p, ok := packet.(*QueryPacket); ok {
+		return protocol.ParseSQLComment(p.Statement)
+	}
+	return nil
 }
 
 type CommandCompletePacket struct {
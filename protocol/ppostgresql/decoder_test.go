@@ -15,6 +15,8 @@ package ppostgresql
 
 import (
 	"bytes"
+	"encoding/binary"
+	"strconv"
 	"testing"
 	"time"
 
@@ -24,6 +26,7 @@ import (
 	"github.com/packetd/packetd/common/socket"
 	"github.com/packetd/packetd/internal/splitio"
 	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol"
 	"github.com/packetd/packetd/protocol/role"
 )
 
@@ -384,6 +387,142 @@ func TestDecodeResponse(t *testing.T) {
 	}
 }
 
+func buildMessage(flag byte, payload ...byte) []byte {
+	b := []byte{flag, 0x00, 0x00, 0x00, byte(len(payload) + 4)}
+	return append(b, payload...)
+}
+
+func buildPipelineRequest(statements ...string) []byte {
+	var b []byte
+	for i, statement := range statements {
+		name := "s" + strconv.Itoa(i)
+		parse := append([]byte(name), 0x00)
+		parse = append(parse, []byte(statement)...)
+		parse = append(parse, 0x00, 0x00, 0x00)
+		b = append(b, buildMessage('P', parse...)...)
+
+		bind := append([]byte{0x00}, []byte(name)...)
+		bind = append(bind, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+		b = append(b, buildMessage('B', bind...)...)
+		b = append(b, buildMessage('E', 0x00, 0x00, 0x00, 0x00, 0x00)...)
+	}
+	return append(b, buildMessage('S')...)
+}
+
+func TestDecodePipeline(t *testing.T) {
+	var st socket.Tuple
+	var t0 time.Time
+
+	commandComplete := buildMessage('C', 'S', 'E', 'L', 'E', 'C', 'T', ' ', '1', 0x00)
+	errorResponse := buildMessage('E', 'S', 'E', 'R', 'R', 'O', 'R', 0x00, 0x00)
+	readyForQuery := buildMessage('Z', 'I')
+
+	ctx := protocol.NewConnContext(0)
+	client := newDecoder(st, 0, common.NewOptions(), ctx, nil)
+	server := newDecoder(st, 5432, common.NewOptions(), ctx, nil)
+
+	decodeSeqs := func(d *decoder, b []byte) []uint64 {
+		objs, err := d.Decode(zerocopy.NewBuffer(b), t0)
+		assert.NoError(t, err)
+
+		var seqs []uint64
+		for _, obj := range objs {
+			switch o := obj.Obj.(type) {
+			case *Request:
+				seqs = append(seqs, o.Seq)
+			case *Response:
+				seqs = append(seqs, o.Seq)
+			}
+		}
+		return seqs
+	}
+
+	// 同一个 Sync 内的多个语句按顺序完成
+	assert.Equal(t, []uint64{1, 2}, decodeSeqs(client, buildPipelineRequest("SELECT 1", "SELECT 2")))
+
+	var rsp []byte
+	rsp = append(rsp, buildMessage('1')...)
+	rsp = append(rsp, buildMessage('2')...)
+	rsp = append(rsp, commandComplete...)
+	rsp = append(rsp, buildMessage('1')...)
+	rsp = append(rsp, buildMessage('2')...)
+	rsp = append(rsp, commandComplete...)
+	rsp = append(rsp, readyForQuery...)
+	assert.Equal(t, []uint64{1, 2}, decodeSeqs(server, rsp))
+
+	// 首个语句执行失败 服务端跳过同一 Sync 内的剩余语句
+	assert.Equal(t, []uint64{3, 4}, decodeSeqs(client, buildPipelineRequest("SELECT 3", "SELECT 4")))
+
+	// ReadyForQuery 归档 flush 响应 携带被跳过的语句的最大序号
+	rsp = append(errorResponse, readyForQuery...)
+	assert.Equal(t, []uint64{3, 4}, decodeSeqs(server, rsp))
+
+	assert.Equal(t, []uint64{5}, decodeSeqs(client, buildPipelineRequest("SELECT 5")))
+
+	rsp = append(buildMessage('1'), buildMessage('2')...)
+	rsp = append(rsp, commandComplete...)
+	rsp = append(rsp, readyForQuery...)
+	assert.Equal(t, []uint64{5}, decodeSeqs(server, rsp))
+}
+
+func TestDecodePipelineDescribeClose(t *testing.T) {
+	var st socket.Tuple
+	var t0 time.Time
+
+	ctx := protocol.NewConnContext(0)
+	client := newDecoder(st, 0, common.NewOptions(), ctx, nil)
+	server := newDecoder(st, 5432, common.NewOptions(), ctx, nil)
+	m := newMatcher(maxRecordSize)
+
+	var pairs []*role.Pair
+	decode := func(d *decoder, b []byte) {
+		objs, err := d.Decode(zerocopy.NewBuffer(b), t0)
+		assert.NoError(t, err)
+		for _, obj := range objs {
+			if pair := m.Match(obj); pair != nil {
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+
+	// Bind / Describe / Execute 中 Describe 的结果先于语句的结果返回
+	var req []byte
+	req = append(req, buildMessage('B', 0x00, 's', '0', 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)...)
+	req = append(req, buildMessage('D', 'P', 0x00)...)
+	req = append(req, buildMessage('E', 0x00, 0x00, 0x00, 0x00, 0x00)...)
+	req = append(req, buildMessage('C', 'S', 's', '0', 0x00)...)
+	req = append(req, buildMessage('S')...)
+	decode(client, req)
+
+	var rsp []byte
+	rsp = append(rsp, buildMessage('2')...)
+	rsp = append(rsp, buildMessage('n')...)
+	rsp = append(rsp, buildMessage('C', 'S', 'E', 'L', 'E', 'C', 'T', ' ', '1', 0x00)...)
+	rsp = append(rsp, buildMessage('3')...)
+	rsp = append(rsp, buildMessage('Z', 'I')...)
+	decode(server, rsp)
+
+	assert.Len(t, pairs, 3)
+	assert.IsType(t, &DescribePacket{}, pairs[0].Request.Obj.(*Request).Packet)
+	assert.Equal(t, &FlagPacket{Flag: "NoData"}, pairs[0].Response.Obj.(*Response).Packet)
+	assert.IsType(t, &QueryPacket{}, pairs[1].Request.Obj.(*Request).Packet)
+	assert.IsType(t, &CommandCompletePacket{}, pairs[1].Response.Obj.(*Response).Packet)
+	assert.Equal(t, &ClosePacket{Type: "S", Object: "s0"}, pairs[2].Request.Obj.(*Request).Packet)
+	assert.Equal(t, 0, m.(*matcher).Pending())
+
+	// Bind 执行失败 同一 Sync 内被跳过的 Describe 在 ReadyForQuery 时清除
+	pairs = nil
+	req = append(buildMessage('B', 0x00, 's', '1', 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00), buildMessage('D', 'P', 0x00)...)
+	req = append(req, buildMessage('S')...)
+	decode(client, req)
+	assert.Equal(t, 2, m.(*matcher).Pending())
+
+	decode(server, append(buildMessage('E', 'S', 'E', 'R', 'R', 'O', 'R', 0x00, 0x00), buildMessage('Z', 'I')...))
+	assert.Len(t, pairs, 1)
+	assert.IsType(t, &ErrorPacket{}, pairs[0].Response.Obj.(*Response).Packet)
+	assert.Equal(t, 0, m.(*matcher).Pending())
+}
+
 func TestDecodeFailed(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -466,3 +605,130 @@ func TestDecodeFailed(t *testing.T) {
 		})
 	}
 }
+
+func buildCopyData(sub byte, lsns ...LSN) []byte {
+	payload := []byte{sub}
+	for _, lsn := range lsns {
+		payload = binary.BigEndian.AppendUint64(payload, uint64(lsn))
+	}
+	return buildMessage('d', payload...)
+}
+
+func TestDecodeReplication(t *testing.T) {
+	var st socket.Tuple
+	t0 := time.Unix(1751356800, 0)
+
+	ctx := protocol.NewConnContext(0)
+	client := newDecoder(st, 0, common.NewOptions(), ctx, nil)
+	server := newDecoder(st, 5432, common.NewOptions(), ctx, nil)
+
+	decode := func(d *decoder, b []byte) []*role.Object {
+		objs, err := d.Decode(zerocopy.NewBuffer(b), t0)
+		assert.NoError(t, err)
+		return objs
+	}
+
+	query := append([]byte("START_REPLICATION SLOT replica_1 LOGICAL 16/B374D848 (proto_version '1')"), 0x00)
+	objs := decode(client, buildMessage('Q', query...))
+	assert.Len(t, objs, 1)
+	req := objs[0].Obj.(*Request)
+	assert.Equal(t, uint64(1), req.Seq)
+	assert.Equal(t, &StartReplicationPacket{Slot: "replica_1", Logical: true, StartLSN: 0x16B374D848}, req.Packet)
+
+	objs = decode(server, buildMessage('W', 0x00, 0x00, 0x00))
+	assert.Len(t, objs, 1)
+	assert.Equal(t, uint64(1), objs[0].Obj.(*Response).Seq)
+	assert.Equal(t, &FlagPacket{Flag: "CopyBothResponse"}, objs[0].Obj.(*Response).Packet)
+
+	// 汇报之前推送的 WAL 仅累计统计
+	xlog := append(buildCopyData('w', 0x100, 0x180, 0), make([]byte, 64)...)
+	xlog[4] += 64
+	assert.Empty(t, decode(server, xlog))
+
+	// 汇报未被响应前的后续汇报被忽略
+	objs = decode(client, buildCopyData('r', 0x100, 0x100, 0xF0, 0))
+	assert.Len(t, objs, 1)
+	req = objs[0].Obj.(*Request)
+	assert.Equal(t, uint64(2), req.Seq)
+	assert.Equal(t, &StandbyStatusPacket{Slot: "replica_1", WriteLSN: 0x100, FlushLSN: 0x100, ApplyLSN: 0xF0}, req.Packet)
+	assert.Empty(t, decode(client, buildCopyData('r', 0x100, 0x100, 0x100, 0)))
+
+	keepalive := buildCopyData('k', 0x200, 0)
+	keepalive = append(keepalive, 0x00) // ReplyRequested
+	keepalive[4]++
+	objs = decode(server, keepalive)
+	assert.Len(t, objs, 1)
+	rsp := objs[0].Obj.(*Response)
+	assert.Equal(t, uint64(2), rsp.Seq)
+	packet := rsp.Packet.(*ReplicationStreamPacket)
+	assert.Equal(t, &ReplicationStreamPacket{WALStart: 0x100, WALEnd: 0x200, WALBytes: 64, Messages: 1}, packet)
+	assert.Equal(t, int64(0x100), packet.LagBytes(req.Packet.(*StandbyStatusPacket)))
+
+	// 复制结束后的 CopyData 不再解析
+	assert.Empty(t, decode(client, buildMessage('c')))
+	assert.Empty(t, decode(client, buildCopyData('r', 0x200, 0x200, 0x200, 0)))
+}
+
+func TestParseStartReplication(t *testing.T) {
+	tests := []struct {
+		statement string
+		packet    *StartReplicationPacket
+	}{
+		{
+			statement: "START_REPLICATION 0/3000000 TIMELINE 1",
+			packet:    &StartReplicationPacket{StartLSN: 0x3000000},
+		},
+		{
+			statement: `start_replication slot "standby" physical 1/A;`,
+			packet:    &StartReplicationPacket{Slot: "standby", StartLSN: 0x10000000A},
+		},
+		{
+			statement: "SELECT 1",
+		},
+	}
+
+	for _, tt := range tests {
+		packet, ok := parseStartReplication(tt.statement)
+		assert.Equal(t, tt.packet != nil, ok)
+		assert.Equal(t, tt.packet, packet)
+	}
+	assert.Equal(t, "16/B374D848", LSN(0x16B374D848).String())
+}
+
+func TestDecodeStartupDatabase(t *testing.T) {
+	var st socket.Tuple
+	var t0 time.Time
+
+	buildStartup := func(params ...string) []byte {
+		payload := []byte{0x00, 0x03, 0x00, 0x00}
+		for _, p := range params {
+			payload = append(payload, p...)
+			payload = append(payload, 0x00)
+		}
+		payload = append(payload, 0x00)
+		return append(binary.BigEndian.AppendUint32(nil, uint32(len(payload)+4)), payload...)
+	}
+
+	tests := []struct {
+		name     string
+		params   []string
+		database string
+	}{
+		{name: "Database", params: []string{"user", "postgres", "database", "orders"}, database: "orders"},
+		{name: "DefaultToUser", params: []string{"user", "postgres"}, database: "postgres"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDecoder(st, 0, common.NewOptions(), protocol.NewConnContext(0), nil)
+			objs, err := d.Decode(zerocopy.NewBuffer(buildStartup(tt.params...)), t0)
+			assert.NoError(t, err)
+			assert.Empty(t, objs)
+
+			objs, err = d.Decode(zerocopy.NewBuffer(buildMessage('Q', append([]byte("SELECT 1"), 0x00)...)), t0)
+			assert.NoError(t, err)
+			assert.Len(t, objs, 1)
+			assert.Equal(t, tt.database, objs[0].Obj.(*Request).Database)
+		})
+	}
+}
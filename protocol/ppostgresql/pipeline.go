@@ -0,0 +1,196 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ppostgresql
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+// maxPipelineSize 单链接最多同时追踪的语句数量（包含 Sync 标记）
+const maxPipelineSize = 64
+
+// entryKind 需要等待服务端结果的消息类型 同类消息按照发送顺序完成 不同类型之间则不一定
+//
+// 如 Bind/Describe/Execute 中 Describe 的 RowDescription 先于 Execute 的 CommandComplete 返回
+type entryKind uint8
+
+const (
+	kindAny       entryKind = iota
+	kindStatement           // Query / Bind 以 CommandComplete 完成
+	kindDescribe            // Describe 以 RowDescription / NoData 完成 Describe 语句时前面还有 ParameterDescription
+	kindClose               // Close 以 CloseComplete 完成
+)
+
+type pipelineEntry struct {
+	seq  uint64
+	kind entryKind
+	sync bool
+}
+
+// pipeline 记录着单条链接中已发送但尚未完成的语句
+//
+// libpq pipeline 模式下客户端会在单个 Sync 之前连续发送多组 Parse/Bind/Execute
+// 服务端则按照相同的顺序依次返回每个语句的执行结果 最后以 ReadyForQuery 结束
+//
+// client 端 decoder 按发送顺序入队语句 server 端 decoder 按完成顺序出队同类的语句 两者以 seq 进行配对
+// 当某个语句执行失败时 服务端会跳过直到 Sync 之前的所有语句 此时在 ReadyForQuery 时统一丢弃
+type pipeline struct {
+	mut   sync.Mutex
+	seq   uint64
+	queue []pipelineEntry
+}
+
+// ctxPipeline pipeline 挂载在 protocol.ConnContext 中的 key
+const ctxPipeline = "postgresql.pipeline"
+
+// connPipeline 返回链接上下文中挂载的 pipeline
+func connPipeline(ctx *protocol.ConnContext) *pipeline {
+	return ctx.Value(ctxPipeline, func() any { return newPipeline() }).(*pipeline)
+}
+
+func newPipeline() *pipeline {
+	return &pipeline{}
+}
+
+func (p *pipeline) push(entry pipelineEntry) {
+	if len(p.queue) >= maxPipelineSize {
+		p.queue = p.queue[1:]
+	}
+	p.queue = append(p.queue, entry)
+}
+
+// Statement 入队一个语句并返回其序号
+func (p *pipeline) Statement(kind entryKind) uint64 {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	p.seq++
+	p.push(pipelineEntry{seq: p.seq, kind: kind})
+	return p.seq
+}
+
+// Sync 入队一个 Sync 标记
+func (p *pipeline) Sync() {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	p.push(pipelineEntry{sync: true})
+}
+
+// find 返回当前 Sync 区间内最早的指定类型的语句位置 kindAny 匹配任意类型
+func (p *pipeline) find(kind entryKind) int {
+	for i, entry := range p.queue {
+		if entry.sync {
+			return -1
+		}
+		if kind == kindAny || entry.kind == kind {
+			return i
+		}
+	}
+	return -1
+}
+
+// Complete 出队当前 Sync 区间内最早的指定类型的语句
+//
+// ErrorResponse 可能由任意类型的消息引起 此时传入 kindAny 出队区间内最早的语句
+// 若区间内已没有待完成的语句则返回 false
+func (p *pipeline) Complete(kind entryKind) (uint64, bool) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	i := p.find(kind)
+	if i < 0 {
+		return 0, false
+	}
+	entry := p.queue[i]
+	p.queue = append(p.queue[:i], p.queue[i+1:]...)
+	return entry.seq, true
+}
+
+// Pending 返回当前 Sync 区间内是否存在指定类型的待完成语句
+func (p *pipeline) Pending(kind entryKind) bool {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	return p.find(kind) >= 0
+}
+
+// Ready 丢弃截至（包含）首个 Sync 标记的所有记录
+//
+// 返回被丢弃的语句中最大的序号 即因前序语句失败而被服务端跳过的语句 没有语句被丢弃时返回 false
+func (p *pipeline) Ready() (uint64, bool) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	var skipped uint64
+	for i, entry := range p.queue {
+		if entry.sync {
+			p.queue = p.queue[i+1:]
+			return skipped, skipped > 0
+		}
+		skipped = max(skipped, entry.seq)
+	}
+	p.queue = p.queue[:0]
+	return skipped, skipped > 0
+}
+
+// matcher 按照 Seq 配对请求与响应
+//
+// 被服务端跳过的语句不会再有响应 ReadyForQuery 时归档一个 flush 响应
+// flush 响应不参与配对 仅清除序号不大于其 Seq 的未配对请求 避免其长期占用队列
+type matcher struct {
+	l    *list.List
+	size int
+}
+
+func newMatcher(size int) role.Matcher {
+	return &matcher{l: list.New(), size: size}
+}
+
+// Pending 返回尚未完成配对的请求数量
+func (m *matcher) Pending() int {
+	return m.l.Len()
+}
+
+func (m *matcher) Match(o *role.Object) *role.Pair {
+	if o.Role == role.Request {
+		if m.l.Len() >= m.size {
+			m.l.Remove(m.l.Front())
+		}
+		m.l.PushBack(o)
+		return nil
+	}
+
+	rsp := o.Obj.(*Response)
+	for e := m.l.Front(); e != nil; {
+		next := e.Next()
+		req := e.Value.(*role.Object)
+		seq := req.Obj.(*Request).Seq
+		switch {
+		case rsp.flush:
+			if seq <= rsp.Seq {
+				m.l.Remove(e)
+			}
+		case seq == rsp.Seq:
+			m.l.Remove(e)
+			return &role.Pair{Request: req, Response: o}
+		}
+		e = next
+	}
+	return nil
+}
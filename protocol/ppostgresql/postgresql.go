@@ -24,12 +24,24 @@ import (
 
 func init() {
 	protocol.Register(socket.L7ProtoPostgreSQL, NewConnPool)
+	protocol.Describe(socket.L7ProtoPostgreSQL, protocol.Capability{
+		Versions: []string{"3.0"},
+		Options:  []string{OptCaptureBindParams, OptMaxBindParamSize, OptRedactBindParams},
+	})
 }
 
+const maxRecordSize = 64
+
 // NewConnPool 创建 PostgreSQL 协议连接池
+//
+// 同一条链接两个方向的 decoder 共享链接上下文 记录当前数据库以及预处理语句
+// 并在其中挂载 pipeline 按语句的完成顺序进行配对
 func NewConnPool(opts common.Options) protocol.ConnPool {
+	cs := protocol.NewConnContexts(0)
 	return protocol.NewL7TCPConnPool(
-		role.NewSingleMatcher,
+		func() role.Matcher {
+			return newMatcher(maxRecordSize)
+		},
 		func(pair *role.Pair) socket.RoundTrip {
 			return &RoundTrip{
 				request:  pair.Request.Obj.(*Request),
@@ -37,29 +49,38 @@ func NewConnPool(opts common.Options) protocol.ConnPool {
 			}
 		},
 		func(st socket.Tuple, serverPort socket.Port) protocol.Decoder {
-			return NewDecoder(st, serverPort, opts)
+			ctx := cs.Acquire(st, serverPort)
+			return newDecoder(st, serverPort, opts, ctx, func() {
+				cs.Release(st, serverPort)
+			})
 		},
 	)
 }
 
 // Request PostgreSQL 请求
 type Request struct {
-	Host   string
-	Port   uint16
-	Proto  string
-	Size   int
-	Packet any
-	Time   time.Time
+	Seq      uint64 // 链接内的语句序号 用于 pipeline 模式下的请求配对
+	Host     string
+	Port     uint16
+	Proto    string
+	Size     int
+	Database string `json:",omitempty"` // 仅捕获到链接的 StartupMessage 时存在
+	Packet   any
+	Comment  *protocol.SQLComment `json:",omitempty"`
+	Time     time.Time
 }
 
 // Response PostgreSQL 响应
 type Response struct {
+	Seq    uint64
 	Host   string
 	Port   uint16
 	Proto  string
 	Size   int
 	Packet any
 	Time   time.Time
+
+	flush bool // ReadyForQuery 归档的 flush 响应 不参与配对
 }
 
 var _ socket.RoundTrip = (*RoundTrip)(nil)
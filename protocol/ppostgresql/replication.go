@@ -0,0 +1,259 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ppostgresql
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// copyDataXLogData 服务端推送的 WAL 数据
+	copyDataXLogData = 'w'
+
+	// copyDataKeepalive 服务端心跳
+	copyDataKeepalive = 'k'
+
+	// copyDataStandbyStatus 备库汇报的复制进度
+	copyDataStandbyStatus = 'r'
+
+	// xlogDataHeaderLength XLogData 固定头长度 Type(1) + WALStart(8) + WALEnd(8) + SendTime(8)
+	xlogDataHeaderLength = 25
+
+	// maxCopyDataHeaderSize CopyData 需要解析的最大头部长度 即 StandbyStatusUpdate 的完整长度
+	maxCopyDataHeaderSize = 34
+
+	startReplication = "START_REPLICATION"
+)
+
+// LSN WAL 位置 序列化为 PostgreSQL 的 `高32位/低32位` 十六进制格式
+type LSN uint64
+
+func (l LSN) String() string {
+	return fmt.Sprintf("%X/%X", uint32(l>>32), uint32(l))
+}
+
+func (l LSN) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// ParseLSN 解析 `X/X` 格式的 WAL 位置
+func ParseLSN(s string) (LSN, bool) {
+	hi, lo, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, false
+	}
+	h, err := strconv.ParseUint(hi, 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	l, err := strconv.ParseUint(lo, 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return LSN(h<<32 | l), true
+}
+
+type StartReplicationPacket struct {
+	Slot     string
+	Logical  bool
+	StartLSN LSN
+}
+
+func (p StartReplicationPacket) Name() string {
+	return "StartReplication"
+}
+
+// parseStartReplication 解析复制链接发起的 START_REPLICATION 命令
+//
+// START_REPLICATION [ SLOT slot_name ] [ PHYSICAL ] XXX/XXX [ TIMELINE tli ]
+// START_REPLICATION SLOT slot_name LOGICAL XXX/XXX [ ( option_name [ option_value ] [, ...] ) ]
+func parseStartReplication(statement string) (*StartReplicationPacket, bool) {
+	fields := strings.Fields(strings.TrimSuffix(strings.TrimSpace(statement), ";"))
+	if len(fields) < 2 || !strings.EqualFold(fields[0], startReplication) {
+		return nil, false
+	}
+
+	packet := &StartReplicationPacket{}
+	for i := 1; i < len(fields); i++ {
+		switch strings.ToUpper(fields[i]) {
+		case "SLOT":
+			if i+1 < len(fields) {
+				i++
+				packet.Slot = strings.Trim(fields[i], `"`)
+			}
+		case "LOGICAL":
+			packet.Logical = true
+		case "PHYSICAL":
+		default:
+			if lsn, ok := ParseLSN(fields[i]); ok {
+				packet.StartLSN = lsn
+				return packet, true
+			}
+		}
+	}
+	return packet, true
+}
+
+type StandbyStatusPacket struct {
+	Slot     string
+	WriteLSN LSN
+	FlushLSN LSN
+	ApplyLSN LSN
+}
+
+func (p StandbyStatusPacket) Name() string {
+	return "StandbyStatusUpdate"
+}
+
+type ReplicationStreamPacket struct {
+	WALStart LSN
+	WALEnd   LSN
+	WALBytes int
+	Messages int
+}
+
+func (p ReplicationStreamPacket) Name() string {
+	return "ReplicationStream"
+}
+
+// LagBytes 返回服务端 WAL 末尾与备库已落盘位置的差值
+func (p ReplicationStreamPacket) LagBytes(status *StandbyStatusPacket) int64 {
+	if status == nil || p.WALEnd <= status.FlushLSN {
+		return 0
+	}
+	return int64(p.WALEnd - status.FlushLSN)
+}
+
+// replicationStream 记录复制链接上服务端两次汇报之间推送的 WAL 统计
+type replicationStream struct {
+	walStart LSN
+	walEnd   LSN
+	walBytes int
+	messages int
+}
+
+func (s *replicationStream) reset() {
+	*s = replicationStream{}
+}
+
+// decodeCopyBothPacket 服务端以 CopyBothResponse 响应 START_REPLICATION 后链接进入流复制状态
+//
+// 此后两个方向均只会传输 CopyData 直到复制结束
+func (d *decoder) decodeCopyBothPacket() {
+	if !d.readall {
+		return
+	}
+	d.streaming = true
+	d.stream.reset()
+	d.packet = &FlagPacket{
+		Flag: "CopyBothResponse",
+	}
+}
+
+// decodeCopyDonePacket 任一方向发送 CopyDone 即表示流复制结束
+func (d *decoder) decodeCopyDonePacket() {
+	if !d.readall {
+		return
+	}
+	d.slot = nil
+	d.streaming = false
+}
+
+// decodeCopyDataPacket 解析流复制状态下的 CopyData 数据包 布局如下
+//
+// ┌─────────┬──────────┬─────────┬──────────────────────────────────────┐
+// │  Type   │ Length   │ SubType │              Payload                 │
+// │ (1B)    │ (4B)     │ (1B)    │                                      │
+// ├─────────┼──────────┼─────────┼──────────────────────────────────────┤
+// │  'd'    │  N + 4   │  'w'    │ WALStart(8) WALEnd(8) SendTime(8) .. │
+// │ (0x64)  │ (Big-End)│  'k'    │ WALEnd(8) SendTime(8) Reply(1)       │
+// │         │          │  'r'    │ Write(8) Flush(8) Apply(8) Time(8).. │
+// └─────────┴──────────┴─────────┴──────────────────────────────────────┘
+//
+// - w: XLogData 服务端推送的 WAL 数据
+// - k: Primary keepalive 服务端心跳 携带当前 WAL 末尾位置
+// - r: Standby status update 备库汇报已写入 / 已落盘 / 已回放的位置
+//
+// 每次备库汇报作为一个请求 其后服务端推送的第一个数据包作为响应 响应中携带两次汇报之间的 WAL 统计
+// 非流复制状态下（如 COPY 命令）的 CopyData 不做解析
+func (d *decoder) decodeCopyDataPacket(b []byte) {
+	if d.slot == nil && !d.streaming {
+		return
+	}
+
+	d.copyHeader.Write(b)
+	if !d.readall {
+		return
+	}
+
+	// 未产生 packet 的数据包不会触发 reset 需要自行清理
+	hdr := d.copyHeader.Clone()
+	d.copyHeader.Reset()
+	if len(hdr) == 0 {
+		return
+	}
+
+	if d.isClient() {
+		// 主库空闲时仅按 wal_sender_timeout 发送心跳 期间的多次汇报只保留最早的一次
+		// 被忽略的数据包不计入下一个请求的耗时以及大小
+		if hdr[0] != copyDataStandbyStatus || len(hdr) < 1+8*3 || d.pipe.Pending(kindAny) {
+			d.drainBytes = 0
+			return
+		}
+		d.packet = &StandbyStatusPacket{
+			Slot:     d.slot.Slot,
+			WriteLSN: LSN(binary.BigEndian.Uint64(hdr[1:9])),
+			FlushLSN: LSN(binary.BigEndian.Uint64(hdr[9:17])),
+			ApplyLSN: LSN(binary.BigEndian.Uint64(hdr[17:25])),
+		}
+		return
+	}
+
+	switch hdr[0] {
+	case copyDataXLogData:
+		if len(hdr) < xlogDataHeaderLength {
+			return
+		}
+		start := LSN(binary.BigEndian.Uint64(hdr[1:9]))
+		if d.stream.messages == 0 {
+			d.stream.walStart = start
+		}
+		d.stream.walEnd = LSN(binary.BigEndian.Uint64(hdr[9:17]))
+		d.stream.walBytes += int(d.payloadLen) - (headerLength - 1) - xlogDataHeaderLength
+		d.stream.messages++
+
+	case copyDataKeepalive:
+		if len(hdr) < 1+8 {
+			return
+		}
+		d.stream.walEnd = LSN(binary.BigEndian.Uint64(hdr[1:9]))
+
+	default:
+		return
+	}
+
+	if !d.pipe.Pending(kindAny) {
+		return
+	}
+	d.packet = &ReplicationStreamPacket{
+		WALStart: d.stream.walStart,
+		WALEnd:   d.stream.walEnd,
+		WALBytes: d.stream.walBytes,
+		Messages: d.stream.messages,
+	}
+	d.stream.reset()
+}
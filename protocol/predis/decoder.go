@@ -19,6 +19,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/pkg/errors"
 
 	"github.com/packetd/packetd/common"
@@ -33,15 +34,14 @@ const (
 	PROTO = "Redis"
 )
 
-func newError(format string, args ...any) error {
-	format = "redis/decoder: " + format
-	return errors.Errorf(format, args...)
+func newError(code protocol.ErrorCode, format string, args ...any) error {
+	return protocol.NewDecodeError(socket.L7ProtoRedis, code, "redis/decoder: "+format, args...)
 }
 
 var (
-	errInvalidBytes     = newError("invalid bytes")
-	errDecodeBulkString = newError("decode BulkString failed")
-	errDecodeN          = newError("decode NField failed")
+	errUnknownDataType  = newError(protocol.ErrCodeResyncFailed, "unknown data type")
+	errDecodeBulkString = newError(protocol.ErrCodeLengthOverflow, "BulkString length overflow")
+	errDecodeN          = newError(protocol.ErrCodeMalformed, "decode NField failed")
 )
 
 // decoder Redis RESP 协议解析器
@@ -61,14 +61,27 @@ type decoder struct {
 	command     string   // 记录 RESP 命令
 	prevSeenCmd bool     // 记录前一次解析是否已经遇到过命令
 	stack       *stack
+
+	// 拓扑变化识别相关状态 仅开启 OptTrackTopology 时在响应方向上记录
+	trackTopology bool
+	started       bool           // 本轮是否已经读取过首行
+	arrayN        int            // 顶层 Array 的元素个数
+	elems         [][]byte       // 依次记录的 BulkStrings 内容 最多 maxPushElements 个
+	capturing     bool           // 当前 BulkStrings 是否需要记录
+	redirect      *Redirect      // Errors 中解析出的重定向
+	digest        *xxhash.Digest // 响应内容摘要
 }
 
-func NewDecoder(st socket.Tuple, serverPort socket.Port, _ common.Options) protocol.Decoder {
-	return &decoder{
+func NewDecoder(st socket.Tuple, serverPort socket.Port, opts common.Options) protocol.Decoder {
+	d := &decoder{
 		st:         st.ToRaw(),
 		serverPort: serverPort,
 		stack:      newStack(),
 	}
+	if d.trackTopology, _ = opts.GetBool(OptTrackTopology); d.trackTopology {
+		d.digest = xxhash.New()
+	}
+	return d
 }
 
 // reset 重置单次请求状态
@@ -78,6 +91,29 @@ func (d *decoder) reset() {
 	d.role = ""
 	d.dataType = ""
 	d.prevSeenCmd = false
+
+	d.started = false
+	d.arrayN = 0
+	d.elems = d.elems[:0]
+	d.capturing = false
+	d.redirect = nil
+	if d.digest != nil {
+		d.digest.Reset()
+	}
+}
+
+// observeTopology 是否需要记录拓扑相关的响应内容
+func (d *decoder) observeTopology() bool {
+	return d.trackTopology && !d.isClient()
+}
+
+// captureLine 记录 BulkStrings 的一行内容 超出 maxElementSize 的部分直接丢弃
+func (d *decoder) captureLine(b []byte) {
+	last := len(d.elems) - 1
+	if n := maxElementSize - len(d.elems[last]); n < len(b) {
+		b = b[:max(n, 0)]
+	}
+	d.elems[last] = append(d.elems[last], b...)
 }
 
 // Decode 从 zerocopy.Reader 中不断解析来自 Request / Response 的数据 并判断是否能构建成 RoundTrip
@@ -151,6 +187,9 @@ func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error)
 			continue
 		}
 
+		if d.trackTopology {
+			countTopology(obj)
+		}
 		objs = append(objs, obj)
 		d.reset()
 		return objs, err
@@ -175,15 +214,38 @@ func (d *decoder) archive() *role.Object {
 		return obj
 	}
 
-	obj := role.NewResponseObject(&Response{
+	if d.trackTopology && d.arrayN > 0 && len(d.elems) == d.arrayN {
+		if kind, channel, payload, ok := sentinelEvent(d.elems); ok {
+			return role.NewOneWayObject(&Request{
+				Command:    kind,
+				Channel:    channel,
+				Message:    payload,
+				Size:       d.drainBytes,
+				Proto:      PROTO,
+				Time:       d.t0,
+				Host:       d.st.DstIP,
+				Port:       d.st.DstPort,
+				ServerHost: d.st.SrcIP,
+				ServerPort: d.st.SrcPort,
+			})
+		}
+	}
+
+	rsp := &Response{
 		DataType: string(d.dataType),
 		Size:     d.drainBytes,
 		Time:     d.t0,
 		Proto:    PROTO,
 		Host:     d.st.SrcIP,
 		Port:     d.st.SrcPort,
-	})
-	return obj
+	}
+	if d.trackTopology {
+		rsp.Redirect = d.redirect
+		if d.arrayN > 0 {
+			rsp.Digest = strconv.FormatUint(d.digest.Sum64(), 16)
+		}
+	}
+	return role.NewResponseObject(rsp)
 }
 
 // decodeContinue 继续解析后续数据包
@@ -235,6 +297,12 @@ func (d *decoder) decode(r *splitio.Reader) (*role.Object, error) {
 		return nil, io.ErrShortBuffer
 	}
 
+	top := !d.started
+	d.started = true
+	if d.observeTopology() {
+		d.digest.Write(line)
+	}
+
 	complete := true
 	switch line[0] {
 	case '*':
@@ -255,6 +323,9 @@ func (d *decoder) decode(r *splitio.Reader) (*role.Object, error) {
 		if err != nil {
 			return nil, errDecodeN
 		}
+		if top {
+			d.arrayN = n
+		}
 		d.stack.push(&register{
 			dataType: Array,
 			arrayN:   n,
@@ -277,6 +348,10 @@ func (d *decoder) decode(r *splitio.Reader) (*role.Object, error) {
 		if err != nil {
 			return nil, errDecodeN
 		}
+		if d.observeTopology() && len(d.elems) < maxPushElements {
+			d.elems = append(d.elems, nil)
+			d.capturing = true
+		}
 		d.stack.push(&register{
 			dataType:     BulkStrings,
 			bulkStringsN: n,
@@ -296,6 +371,9 @@ func (d *decoder) decode(r *splitio.Reader) (*role.Object, error) {
 		// "-Error message\r\n"
 		d.decodeOneLine(line[1:])
 		d.dataType = Errors
+		if d.observeTopology() {
+			d.redirect = parseRedirect(bytes.TrimSuffix(line[1:], splitio.CharCRLF))
+		}
 
 	case '+':
 		// 解码 SimpleStrings
@@ -318,7 +396,7 @@ func (d *decoder) decode(r *splitio.Reader) (*role.Object, error) {
 
 	default:
 		if d.stack.empty() {
-			return nil, errInvalidBytes
+			return nil, errUnknownDataType
 		}
 		d.decodeOneLine(line)
 	}
@@ -347,6 +425,7 @@ func (d *decoder) decodeBulkStrings(r *splitio.Reader) (bool, error) {
 	}
 
 	if reg.bulkStringsN <= 0 {
+		d.capturing = false
 		return true, nil
 	}
 
@@ -361,6 +440,12 @@ func (d *decoder) decodeBulkStrings(r *splitio.Reader) (bool, error) {
 		if bytes.HasSuffix(line, splitio.CharCRLF) {
 			n = len(line) - 2
 		}
+		if d.observeTopology() {
+			d.digest.Write(line)
+			if d.capturing {
+				d.captureLine(line[:n])
+			}
+		}
 
 		// 判断是否为 Request 并记录命令
 		if d.role != role.Request && d.isClient() {
@@ -384,6 +469,7 @@ func (d *decoder) decodeBulkStrings(r *splitio.Reader) (bool, error) {
 		d.drainBytes += n
 
 		if reg.bulkStringsConsume == reg.bulkStringsN {
+			d.capturing = false
 			return true, nil
 		}
 		if reg.bulkStringsConsume > reg.bulkStringsN {
@@ -332,7 +332,7 @@ func TestDecodeFailed(t *testing.T) {
 		{
 			name:    "Invalid first byte",
 			input:   "invalid\r\n",
-			wantErr: errInvalidBytes,
+			wantErr: errUnknownDataType,
 		},
 		{
 			name:    "Invalid number format",
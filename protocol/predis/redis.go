@@ -24,6 +24,10 @@ import (
 
 func init() {
 	protocol.Register(socket.L7ProtoRedis, NewConnPool)
+	protocol.Describe(socket.L7ProtoRedis, protocol.Capability{
+		Versions: []string{"RESP2"},
+		Options:  []string{OptTrackTopology},
+	})
 }
 
 // NewConnPool 创建 Redis 协议连接池
@@ -31,9 +35,17 @@ func NewConnPool(opts common.Options) protocol.ConnPool {
 	return protocol.NewL7TCPConnPool(
 		role.NewSingleMatcher,
 		func(pair *role.Pair) socket.RoundTrip {
+			if pair.Response == nil {
+				return &RoundTrip{request: pair.Request.Obj.(*Request)}
+			}
+			req := pair.Request.Obj.(*Request)
+			rsp := pair.Response.Obj.(*Response)
+			if req.Command != cmdClusterSlots {
+				rsp.Digest = "" // 其余命令的响应内容与拓扑无关
+			}
 			return &RoundTrip{
-				request:  pair.Request.Obj.(*Request),
-				response: pair.Response.Obj.(*Response),
+				request:  req,
+				response: rsp,
 			}
 		},
 		func(st socket.Tuple, serverPort socket.Port) protocol.Decoder {
@@ -80,6 +92,13 @@ type Request struct {
 	Host    string
 	Port    uint16
 	Time    time.Time
+
+	// Channel / Message 开启 OptTrackTopology 后 Sentinel 推送的事件以单向事件输出 Command 为 MESSAGE / PMESSAGE
+	// Channel 为事件名称（如 +switch-master）Message 为事件内容 ServerHost / ServerPort 为 Sentinel 地址
+	Channel    string `json:",omitempty"`
+	Message    string `json:",omitempty"`
+	ServerHost string `json:",omitempty"`
+	ServerPort uint16 `json:",omitempty"`
 }
 
 // Response Redis 响应
@@ -90,6 +109,19 @@ type Response struct {
 	Proto    string
 	Port     uint16
 	Time     time.Time
+
+	// Redirect 集群返回的 MOVED / ASK 重定向 仅开启 OptTrackTopology 时解析
+	Redirect *Redirect `json:",omitempty"`
+
+	// Digest CLUSTER SLOTS 响应内容的摘要 摘要变化即代表 slot 分布发生了变化 仅开启 OptTrackTopology 时计算
+	Digest string `json:",omitempty"`
+}
+
+// Redirect 集群重定向 如 -MOVED 3999 127.0.0.1:6381
+type Redirect struct {
+	Type    string // MOVED / ASK
+	Slot    int
+	Address string
 }
 
 var _ socket.RoundTrip = (*RoundTrip)(nil)
@@ -115,9 +147,20 @@ func (rt RoundTrip) Response() any {
 }
 
 func (rt RoundTrip) Duration() time.Duration {
+	if rt.OneWay() {
+		return 0
+	}
 	return rt.response.Time.Sub(rt.request.Time)
 }
 
 func (rt RoundTrip) Validate() bool {
+	if rt.OneWay() {
+		return true
+	}
 	return rt.response.Time.After(rt.request.Time)
 }
+
+// OneWay 实现了 socket.OneWayRoundTrip 接口
+func (rt RoundTrip) OneWay() bool {
+	return rt.response == nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package predis
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+// OptTrackTopology 识别 Sentinel 推送的事件以及集群的 MOVED / ASK 重定向 并计算 CLUSTER SLOTS 响应的摘要
+//
+// Redis 主从切换以及 slot 迁移期间客户端需要重新建连或者重试 是延迟突变的常见原因
+const OptTrackTopology = "trackTopology"
+
+const (
+	cmdClusterSlots = "CLUSTER SLOTS"
+
+	// maxPushElements Sentinel 推送最多包含 4 个元素 即 pmessage / pattern / channel / message
+	maxPushElements = 4
+
+	// maxElementSize 单个元素保留的最大长度 Sentinel 事件内容通常不超过百字节
+	maxElementSize = 512
+)
+
+// 可选功能的生效次数 见 protocol.NewOptionCounter
+var (
+	sentinelEventsTotal = protocol.NewOptionCounter(socket.L7ProtoRedis, OptTrackTopology, "sentinel_event")
+	redirectsTotal      = protocol.NewOptionCounter(socket.L7ProtoRedis, OptTrackTopology, "redirected")
+)
+
+// countTopology 统计识别出的拓扑信息
+//
+// archive 在解析嵌套 Array 的元素时同样会被调用 因此仅对最终输出的对象计数
+func countTopology(obj *role.Object) {
+	switch o := obj.Obj.(type) {
+	case *Request:
+		if obj.Role == role.OneWay {
+			sentinelEventsTotal.Inc()
+		}
+	case *Response:
+		if o.Redirect != nil {
+			redirectsTotal.Inc()
+		}
+	}
+}
+
+// parseRedirect 解析集群重定向错误 格式为 MOVED|ASK <slot> <host>:<port>
+func parseRedirect(b []byte) *Redirect {
+	fields := strings.Fields(string(b))
+	if len(fields) != 3 || (fields[0] != "MOVED" && fields[0] != "ASK") {
+		return nil
+	}
+	slot, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil
+	}
+	return &Redirect{
+		Type:    fields[0],
+		Slot:    slot,
+		Address: fields[2],
+	}
+}
+
+// sentinelEvent 判断推送消息是否为 Sentinel 事件 返回事件名称以及内容
+//
+// 推送格式为 [message, channel, payload] 或者 [pmessage, pattern, channel, payload]
+// Sentinel 的事件名称均以 + / - 开头 如 +switch-master / +sdown / -odown
+func sentinelEvent(elems [][]byte) (string, string, string, bool) {
+	if len(elems) < 3 {
+		return "", "", "", false
+	}
+
+	var kind string
+	var channel, payload []byte
+	switch {
+	case len(elems) == 3 && bytes.EqualFold(elems[0], []byte("message")):
+		kind, channel, payload = "MESSAGE", elems[1], elems[2]
+	case len(elems) == 4 && bytes.EqualFold(elems[0], []byte("pmessage")):
+		kind, channel, payload = "PMESSAGE", elems[2], elems[3]
+	default:
+		return "", "", "", false
+	}
+
+	if len(channel) < 2 || (channel[0] != '+' && channel[0] != '-') {
+		return "", "", "", false
+	}
+	return kind, string(channel), string(payload), true
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package predis
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+func TestDecodeTopology(t *testing.T) {
+	const slots = "*2\r\n" +
+		"*3\r\n:0\r\n:8191\r\n*3\r\n$8\r\n10.0.0.1\r\n:6379\r\n$4\r\nabcd\r\n" +
+		"*3\r\n:8192\r\n:16383\r\n*3\r\n$8\r\n10.0.0.2\r\n:6379\r\n$4\r\nefgh\r\n"
+
+	// 服务端至客户端方向
+	st := socket.Tuple{SrcPort: 6379, DstPort: 50001}
+	opts := common.Options{OptTrackTopology: true}
+
+	decode := func(t *testing.T, input ...string) *role.Object {
+		d := NewDecoder(st, 6379, opts)
+		var objs []*role.Object
+		for _, s := range input {
+			ret, err := d.Decode(zerocopy.NewBuffer([]byte(s)), time.Time{})
+			require.NoError(t, err)
+			objs = append(objs, ret...)
+		}
+		require.Len(t, objs, 1)
+		return objs[0]
+	}
+
+	t.Run("Sentinel", func(t *testing.T) {
+		events := testutil.ToFloat64(sentinelEventsTotal)
+		obj := decode(t, "*3\r\n$7\r\nmessage\r\n$14\r\n+switch-master\r\n$36\r\nmymaster 10.0.0.1 6379 10.0.0.2 6379\r\n")
+		assert.EqualValues(t, role.OneWay, obj.Role)
+
+		req := obj.Obj.(*Request)
+		assert.Equal(t, "MESSAGE", req.Command)
+		assert.Equal(t, "+switch-master", req.Channel)
+		assert.Equal(t, "mymaster 10.0.0.1 6379 10.0.0.2 6379", req.Message)
+		assert.Equal(t, uint16(6379), req.ServerPort)
+		assert.Equal(t, uint16(50001), req.Port)
+		assert.Equal(t, events+1, testutil.ToFloat64(sentinelEventsTotal))
+	})
+
+	t.Run("SentinelPattern", func(t *testing.T) {
+		obj := decode(t, "*4\r\n$8\r\npmessage\r\n$1\r\n*\r\n$6\r\n+sdown\r\n", "$29\r\nmaster mymaster 10.0.0.1 6379\r\n")
+		req := obj.Obj.(*Request)
+		assert.Equal(t, "PMESSAGE", req.Command)
+		assert.Equal(t, "+sdown", req.Channel)
+		assert.Equal(t, "master mymaster 10.0.0.1 6379", req.Message)
+	})
+
+	t.Run("PlainMessage", func(t *testing.T) {
+		obj := decode(t, "*3\r\n$7\r\nmessage\r\n$6\r\norders\r\n$5\r\nhello\r\n")
+		assert.EqualValues(t, role.Response, obj.Role)
+	})
+
+	t.Run("Redirect", func(t *testing.T) {
+		obj := decode(t, "-MOVED 3999 10.0.0.2:6379\r\n")
+		rsp := obj.Obj.(*Response)
+		assert.Equal(t, &Redirect{Type: "MOVED", Slot: 3999, Address: "10.0.0.2:6379"}, rsp.Redirect)
+		assert.Empty(t, rsp.Digest)
+
+		obj = decode(t, "-ERR unknown command\r\n")
+		assert.Nil(t, obj.Obj.(*Response).Redirect)
+	})
+
+	t.Run("Digest", func(t *testing.T) {
+		whole := decode(t, slots).Obj.(*Response)
+		i := strings.Index(slots, ":6379")
+		split := decode(t, slots[:i], slots[i:]).Obj.(*Response)
+		assert.NotEmpty(t, whole.Digest)
+		assert.Equal(t, whole.Digest, split.Digest)
+
+		other := decode(t, slots[:len(slots)-6]+"ijkl\r\n").Obj.(*Response)
+		assert.NotEqual(t, whole.Digest, other.Digest)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		d := NewDecoder(st, 6379, common.NewOptions())
+		objs, err := d.Decode(zerocopy.NewBuffer([]byte("-MOVED 3999 10.0.0.2:6379\r\n")), time.Time{})
+		require.NoError(t, err)
+		assert.Nil(t, objs[0].Obj.(*Response).Redirect)
+	})
+}
+
+func TestSentinelEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		elems []string
+		ok    bool
+	}{
+		{name: "Message", elems: []string{"message", "+odown", "master mymaster 10.0.0.1 6379 #quorum 2/2"}, ok: true},
+		{name: "Pattern", elems: []string{"pmessage", "*", "-sdown", "slave 10.0.0.3:6379"}, ok: true},
+		{name: "PlainChannel", elems: []string{"message", "orders", "hello"}},
+		{name: "Subscribe", elems: []string{"subscribe", "+switch-master", "1"}},
+		{name: "Short", elems: []string{"message", "+"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var elems [][]byte
+			for _, s := range tt.elems {
+				elems = append(elems, []byte(s))
+			}
+			_, _, _, ok := sentinelEvent(elems)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+// OptProgressBytes 响应每传输该字节数输出一次进度事件 为 0 时不输出
+const OptProgressBytes = "progressBytes"
+
+// Progress 仍在传输中的响应进度 如导出大结果集
+//
+// 响应结束之前按照字节数周期性输出 Elapsed 为自响应首个数据包起的耗时
+type Progress struct {
+	Event         string
+	Proto         socket.L7Proto
+	Time          time.Time
+	ClientAddress string
+	ClientPort    uint16
+	ServerAddress string
+	ServerPort    uint16
+	Database      string `json:",omitempty"`
+	Rows          int
+	Bytes         int
+	Elapsed       string
+}
+
+var globalProgress atomic.Pointer[func(Progress)]
+
+// SetProgressHandler 设置进度事件的处理函数 传入 nil 则不再输出
+func SetProgressHandler(f func(Progress)) {
+	if f == nil {
+		globalProgress.Store(nil)
+		return
+	}
+	globalProgress.Store(&f)
+}
+
+// EmitProgress 输出进度事件 未设置处理函数时直接丢弃
+func EmitProgress(p Progress) {
+	if f := globalProgress.Load(); f != nil {
+		p.Event = "progress"
+		(*f)(p)
+	}
+}
@@ -0,0 +1,704 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ptls
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+const (
+	PROTO = "TLS"
+)
+
+func newError(code protocol.ErrorCode, format string, args ...any) error {
+	return protocol.NewDecodeError(socket.L7ProtoTLS, code, "tls/decoder: "+format, args...)
+}
+
+var (
+	errInvalidRecord   = newError(protocol.ErrCodeMalformed, "invalid record header")
+	errRecordOverflow  = newError(protocol.ErrCodeLengthOverflow, "record length overflow")
+	errMessageOverflow = newError(protocol.ErrCodeLengthOverflow, "handshake message length overflow")
+	errMalformedHello  = newError(protocol.ErrCodeMalformed, "malformed hello message")
+)
+
+const (
+	recordHeaderLength    = 5
+	handshakeHeaderLength = 4
+
+	// maxRecordLength 密文 record 的上限为 2^14 + 2048
+	maxRecordLength = 1<<14 + 2048
+
+	// maxHandshakeLength 证书链通常仅有数 KB 超过此长度的握手消息不再缓存
+	maxHandshakeLength = 64 * 1024
+)
+
+const (
+	contentTypeChangeCipherSpec = 20
+	contentTypeAlert            = 21
+	contentTypeHandshake        = 22
+	contentTypeApplicationData  = 23
+)
+
+const (
+	handshakeClientHello = 1
+	handshakeServerHello = 2
+	handshakeCertificate = 11
+)
+
+const (
+	extServerName          = 0
+	extSupportedGroups     = 10
+	extECPointFormats      = 11
+	extSignatureAlgorithms = 13
+	extALPN                = 16
+	extSupportedVersions   = 43
+)
+
+// versionName 返回协议版本名称 GREASE 等未知版本以十六进制展示
+func versionName(v uint16) string {
+	if v == 0x0300 {
+		return "SSL 3.0"
+	}
+	return tls.VersionName(v)
+}
+
+// isGREASE 判断是否为 RFC 8701 定义的 GREASE 占位值
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+// maxTrailingRecords 开启阶段拆分后服务端方向最多跟踪的 ApplicationData record 数量
+const maxTrailingRecords = 32
+
+// appRecord 握手结束后观测到的 ApplicationData record 仅用于阶段拆分 由 phaseMatcher 消费
+type appRecord struct {
+	Time  time.Time
+	abort bool // 客户端中断链接 不再有后续数据
+}
+
+type decoder struct {
+	st socket.TupleRaw
+
+	buf  []byte // 尚未完整的 record
+	hs   []byte // 尚未完整的握手消息 单条消息允许跨越多个 record
+	t0   time.Time
+	size int
+
+	rsp  *Response // 已解析 ServerHello 等待 Certificate 消息
+	done bool      // 明文握手阶段已经结束 后续均为密文
+
+	// 开启阶段拆分后 明文握手结束时进入 trailing 状态 仅解析 record header 以定位 ApplicationData
+	enablePhases bool
+	role         role.Role // 由解析到的 ClientHello / ServerHello 确定
+	trailing     bool
+	hdr          []byte    // 尚未完整的 record header
+	hdrAt        time.Time // record header 首字节到达的时间
+	skip         int       // 当前 record 尚未跳过的字节数
+	appRecords   int
+	markedAt     time.Time
+
+	// 开启重协商跟踪后 trailing 状态持续至链接结束 phasesDone 表示阶段拆分所需的 record 已记录完毕
+	trackRenegotiation bool
+	phasesDone         bool
+	renegotiating      bool // 已输出本轮重协商 再次出现 ApplicationData 前不重复输出
+}
+
+func NewDecoder(st socket.Tuple, _ socket.Port, opts common.Options) protocol.Decoder {
+	enablePhases, _ := opts.GetBool(OptEnablePhases)
+	trackRenegotiation, _ := opts.GetBool(OptTrackRenegotiation)
+	return &decoder{
+		st:                 st.ToRaw(),
+		enablePhases:       enablePhases,
+		trackRenegotiation: trackRenegotiation,
+		phasesDone:         !enablePhases,
+	}
+}
+
+// Free 释放持有的资源
+func (d *decoder) Free() {
+	d.buf = nil
+	d.hs = nil
+	d.rsp = nil
+}
+
+// finish 结束明文握手的解析 此后链接中的数据均被忽略
+//
+// 开启阶段拆分或者重协商跟踪时转入 trailing 状态 尚未解析的 buf 交由 walk 处理
+func (d *decoder) finish() {
+	if (d.enablePhases || d.trackRenegotiation) && d.role != "" && !d.done {
+		d.trailing = true
+		d.hs = nil
+		d.rsp = nil
+		return
+	}
+	d.stop()
+}
+
+// stop 不再处理链接中的任何数据
+func (d *decoder) stop() {
+	d.done = true
+	d.trailing = false
+	d.hdr = nil
+	d.Free()
+}
+
+// walk 跳过密文内容 仅解析 record header 每个 ApplicationData record 交由 mark 处理 Handshake record 交由 renegotiate 处理
+func (d *decoder) walk(b []byte, t time.Time, objs []*role.Object) []*role.Object {
+	for len(b) > 0 && d.trailing {
+		if d.skip > 0 {
+			n := min(d.skip, len(b))
+			d.skip -= n
+			b = b[n:]
+			continue
+		}
+
+		if len(d.hdr) == 0 {
+			d.hdrAt = t
+		}
+		n := min(recordHeaderLength-len(d.hdr), len(b))
+		d.hdr = append(d.hdr, b[:n]...)
+		b = b[n:]
+		if len(d.hdr) < recordHeaderLength {
+			break
+		}
+
+		typ := d.hdr[0]
+		length := int(binary.BigEndian.Uint16(d.hdr[3:5]))
+		valid := typ >= contentTypeChangeCipherSpec && typ <= contentTypeApplicationData && d.hdr[1] == 3 && length <= maxRecordLength
+		d.hdr = d.hdr[:0]
+		if !valid {
+			d.stop()
+			break
+		}
+
+		d.skip = length
+		switch typ {
+		case contentTypeApplicationData:
+			objs = d.mark(d.hdrAt, objs)
+		case contentTypeHandshake:
+			objs = d.renegotiate(d.hdrAt, recordHeaderLength+length, objs)
+		}
+	}
+	return objs
+}
+
+// mark 记录一个 ApplicationData record
+//
+// 客户端方向仅需要前两个 record（TLS 1.3 的首个 record 为加密的 Finished）
+// 服务端方向每个数据包至多记录一次 由 phaseMatcher 选择客户端发送应用数据之后的首个 record
+func (d *decoder) mark(t time.Time, objs []*role.Object) []*role.Object {
+	d.appRecords++
+	d.renegotiating = false
+	if d.phasesDone {
+		return objs
+	}
+
+	switch d.role {
+	case role.Request:
+		objs = append(objs, role.NewRequestObject(&appRecord{Time: t}))
+		if d.appRecords >= 2 {
+			d.endPhases()
+		}
+	case role.Response:
+		if !t.Equal(d.markedAt) {
+			d.markedAt = t
+			objs = append(objs, role.NewResponseObject(&appRecord{Time: t}))
+		}
+		if d.appRecords >= maxTrailingRecords {
+			d.endPhases()
+		}
+	}
+	return objs
+}
+
+// endPhases 阶段拆分所需的 record 已记录完毕 未开启重协商跟踪时不再处理后续数据
+func (d *decoder) endPhases() {
+	d.phasesDone = true
+	if !d.trackRenegotiation {
+		d.stop()
+	}
+}
+
+// renegotiate 处理握手完成后出现的 Handshake record
+//
+// TLS 1.2 及以下版本 CCS 之后紧跟的加密 Finished 同样为 Handshake record 因此仅在出现过 ApplicationData 之后才视为重协商
+// 同一轮重协商包含多个 Handshake record 仅在首个 record 时输出一次
+// TLS 1.3 的 KeyUpdate / NewSessionTicket 均以 ApplicationData 的形式加密传输 无法观测
+func (d *decoder) renegotiate(t time.Time, size int, objs []*role.Object) []*role.Object {
+	if !d.trackRenegotiation || d.appRecords == 0 || d.renegotiating {
+		return objs
+	}
+	d.renegotiating = true
+
+	req := &Request{
+		Proto:         PROTO,
+		Size:          size,
+		Time:          t,
+		Renegotiation: true,
+	}
+	switch d.role {
+	case role.Request:
+		req.Host, req.Port = d.st.SrcIP, d.st.SrcPort
+		req.ServerHost, req.ServerPort = d.st.DstIP, d.st.DstPort
+		req.Initiator = initiatorClient
+	case role.Response:
+		req.Host, req.Port = d.st.DstIP, d.st.DstPort
+		req.ServerHost, req.ServerPort = d.st.SrcIP, d.st.SrcPort
+		req.Initiator = initiatorServer
+	}
+	renegotiationsTotal.Inc()
+	return append(objs, role.NewOneWayObject(req))
+}
+
+// Abort 客户端中断链接时通知 phaseMatcher 不会再有应用数据 尚未归档的握手直接输出
+func (d *decoder) Abort(t time.Time) []*role.Object {
+	if !d.trailing || d.phasesDone || d.role != role.Response {
+		return nil
+	}
+	d.stop()
+	return []*role.Object{role.NewResponseObject(&appRecord{Time: t, abort: true})}
+}
+
+// Decode 从 zerocopy.Reader 中解析 TLS 握手阶段的明文消息
+//
+// rfc: https://www.rfc-editor.org/rfc/rfc8446 5.1. Record Layer
+//
+//	+-------------+---------------------+----------------+------------------------+
+//	| Type (1)    | Legacy Version (2)  | Length (2)     | Fragment (Length)      |
+//	+-------------+---------------------+----------------+------------------------+
+//
+// 客户端方向的 ClientHello 作为 Request 服务端方向的 ServerHello 作为 Response
+// TLS 1.2 及以下版本的 Certificate 为明文传输 会等待该消息解析出服务端证书后再归档 Response
+// TLS 1.3 的证书已被加密 ServerHello 之后即归档
+//
+// 出现 ChangeCipherSpec / ApplicationData / Alert 即代表明文握手阶段结束 之后的数据不再解析
+// 因此对于已经建立的长链接 decoder 不会产生任何 RoundTrip
+func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error) {
+	var objs []*role.Object
+	for {
+		b, err := r.Read(common.ReadWriteBlockSize)
+		if err != nil {
+			break
+		}
+		switch {
+		case d.done:
+		case d.trailing:
+			objs = d.walk(b, t, objs)
+		default:
+			d.buf = append(d.buf, b...)
+		}
+	}
+	if d.done || d.trailing {
+		return objs, nil
+	}
+	if d.t0.IsZero() {
+		d.t0 = t
+	}
+
+	objs, err := d.decodeRecords(t)
+	if d.trailing {
+		buf := d.buf
+		d.buf = nil
+		objs = d.walk(buf, t, objs)
+	}
+	return objs, err
+}
+
+// decodeRecords 解析 buf 中完整的 record
+func (d *decoder) decodeRecords(t time.Time) ([]*role.Object, error) {
+	var objs []*role.Object
+	for len(d.buf) >= recordHeaderLength {
+		typ := d.buf[0]
+		length := int(binary.BigEndian.Uint16(d.buf[3:5]))
+		if typ < contentTypeChangeCipherSpec || typ > contentTypeApplicationData || d.buf[1] != 3 {
+			d.stop()
+			return objs, errInvalidRecord
+		}
+		if length > maxRecordLength {
+			d.stop()
+			return objs, errRecordOverflow
+		}
+		if len(d.buf) < recordHeaderLength+length {
+			break
+		}
+
+		fragment := d.buf[recordHeaderLength : recordHeaderLength+length]
+		d.buf = d.buf[recordHeaderLength+length:]
+		d.size += recordHeaderLength + length
+
+		if typ != contentTypeHandshake {
+			if d.rsp != nil {
+				objs = append(objs, d.archiveResponse(nil, t))
+			}
+			d.finish()
+			if d.trailing && typ == contentTypeApplicationData {
+				objs = d.mark(t, objs)
+			}
+			return objs, nil
+		}
+
+		d.hs = append(d.hs, fragment...)
+		for len(d.hs) >= handshakeHeaderLength {
+			msgLen := int(d.hs[1])<<16 | int(d.hs[2])<<8 | int(d.hs[3])
+			if msgLen > maxHandshakeLength {
+				d.stop()
+				return objs, errMessageOverflow
+			}
+			if len(d.hs) < handshakeHeaderLength+msgLen {
+				break
+			}
+
+			msgType := d.hs[0]
+			body := d.hs[handshakeHeaderLength : handshakeHeaderLength+msgLen]
+			d.hs = d.hs[handshakeHeaderLength+msgLen:]
+
+			obj, err := d.decodeHandshake(msgType, body, t)
+			if err != nil {
+				d.stop()
+				return objs, err
+			}
+			if obj != nil {
+				objs = append(objs, obj)
+			}
+			if d.done || d.trailing {
+				return objs, nil
+			}
+		}
+	}
+	return objs, nil
+}
+
+func (d *decoder) decodeHandshake(msgType uint8, body []byte, t time.Time) (*role.Object, error) {
+	switch msgType {
+	case handshakeClientHello:
+		req, err := decodeClientHello(body)
+		if err != nil {
+			return nil, err
+		}
+		req.Host = d.st.SrcIP
+		req.Port = d.st.SrcPort
+		req.Proto = PROTO
+		req.Size = d.size
+		req.Time = d.t0
+		d.role = role.Request
+		d.reset()
+		return role.NewRequestObject(req), nil
+
+	case handshakeServerHello:
+		rsp, err := decodeServerHello(body)
+		if err != nil {
+			return nil, err
+		}
+		d.rsp = rsp
+		d.role = role.Response
+		if rsp.version >= tls.VersionTLS13 {
+			obj := d.archiveResponse(nil, t)
+			d.finish()
+			return obj, nil
+		}
+		return nil, nil
+
+	case handshakeCertificate:
+		if d.rsp == nil {
+			return nil, nil
+		}
+		obj := d.archiveResponse(decodeCertificate(body), t)
+		d.finish()
+		return obj, nil
+	}
+
+	// 会话复用等场景下 ServerHello 之后不会有 Certificate 消息
+	if d.rsp != nil {
+		obj := d.archiveResponse(nil, t)
+		d.finish()
+		return obj, nil
+	}
+	return nil, nil
+}
+
+// reset 重置单条消息的计量状态
+func (d *decoder) reset() {
+	d.t0 = time.Time{}
+	d.size = 0
+}
+
+func (d *decoder) archiveResponse(cert *Certificate, t time.Time) *role.Object {
+	rsp := d.rsp
+	rsp.Host = d.st.SrcIP
+	rsp.Port = d.st.SrcPort
+	rsp.Proto = PROTO
+	rsp.Size = d.size
+	rsp.Time = t
+	rsp.Certificate = cert
+	d.rsp = nil
+	d.reset()
+	return role.NewResponseObject(rsp)
+}
+
+// reader 握手消息的顺序读取工具 任何越界读取都会使 ok 置为 false
+type reader struct {
+	b  []byte
+	ok bool
+}
+
+func newReader(b []byte) *reader {
+	return &reader{b: b, ok: true}
+}
+
+func (r *reader) bytes(n int) []byte {
+	if !r.ok || n > len(r.b) {
+		r.ok = false
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *reader) uint8() uint8 {
+	b := r.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) uint16() uint16 {
+	b := r.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (r *reader) uint24() int {
+	b := r.bytes(3)
+	if b == nil {
+		return 0
+	}
+	return int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+}
+
+// vector8 / vector16 读取带长度前缀的字段
+func (r *reader) vector8() []byte {
+	return r.bytes(int(r.uint8()))
+}
+
+func (r *reader) vector16() []byte {
+	return r.bytes(int(r.uint16()))
+}
+
+// extension 读取下一个扩展字段
+func (r *reader) extension() (uint16, []byte) {
+	typ := r.uint16()
+	return typ, r.vector16()
+}
+
+// decodeClientHello 解析 ClientHello
+//
+//	legacy_version (2) | random (32) | legacy_session_id <0..32>
+//	cipher_suites <2..2^16-2> | legacy_compression_methods <1..2^8-1>
+//	extensions <8..2^16-1>
+func decodeClientHello(b []byte) (*Request, error) {
+	r := newReader(b)
+	hello := clientHello{legacyVersion: r.uint16()}
+	r.bytes(32)
+	r.vector8()
+	hello.ciphers = uint16s(r.vector16())
+	r.vector8()
+	if !r.ok {
+		return nil, errMalformedHello
+	}
+
+	req := &Request{}
+	version := hello.legacyVersion
+	exts := newReader(r.vector16())
+	for exts.ok && len(exts.b) > 0 {
+		typ, data := exts.extension()
+		if !exts.ok {
+			break
+		}
+		hello.extensions = append(hello.extensions, typ)
+
+		switch typ {
+		case extServerName:
+			req.ServerName = decodeServerName(data)
+		case extALPN:
+			req.ALPN = decodeALPN(data)
+		case extSupportedGroups:
+			hello.groups = uint16s(newReader(data).vector16())
+		case extECPointFormats:
+			hello.pointFormats = newReader(data).vector8()
+		case extSignatureAlgorithms:
+			hello.signatureAlgorithms = uint16s(newReader(data).vector16())
+		case extSupportedVersions:
+			// 客户端支持的版本列表 取其中非 GREASE 的最高版本
+			for _, v := range uint16s(newReader(data).vector8()) {
+				if !isGREASE(v) && v > version {
+					version = v
+				}
+			}
+		}
+	}
+
+	hello.version = version
+	hello.serverName = req.ServerName
+	hello.alpn = req.ALPN
+	req.Version = versionName(version)
+	req.JA3 = hello.ja3()
+	req.JA4 = hello.ja4()
+	return req, nil
+}
+
+// uint16s 将字节切片按照大端序解析为 uint16 列表 末尾不足 2 字节的部分被忽略
+func uint16s(b []byte) []uint16 {
+	vs := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		vs = append(vs, binary.BigEndian.Uint16(b[i:]))
+	}
+	return vs
+}
+
+// decodeServerHello 解析 ServerHello
+//
+//	legacy_version (2) | random (32) | legacy_session_id_echo <0..32>
+//	cipher_suite (2) | legacy_compression_method (1) | extensions <6..2^16-1>
+func decodeServerHello(b []byte) (*Response, error) {
+	r := newReader(b)
+	version := r.uint16()
+	r.bytes(32)
+	r.vector8()
+	cipherSuite := r.uint16()
+	r.uint8()
+	if !r.ok {
+		return nil, errMalformedHello
+	}
+
+	rsp := &Response{}
+	// TLS 1.2 以前的 ServerHello 允许不携带扩展
+	if len(r.b) > 0 {
+		exts := newReader(r.vector16())
+		for exts.ok && len(exts.b) > 0 {
+			typ, data := exts.extension()
+			switch typ {
+			case extALPN:
+				if protos := decodeALPN(data); len(protos) > 0 {
+					rsp.ALPN = protos[0]
+				}
+			case extSupportedVersions:
+				if vr := newReader(data); len(data) == 2 {
+					version = vr.uint16()
+				}
+			}
+		}
+	}
+
+	rsp.version = version
+	rsp.Version = versionName(version)
+	rsp.CipherSuite = tls.CipherSuiteName(cipherSuite)
+	return rsp, nil
+}
+
+// decodeServerName 解析 server_name 扩展 仅取 host_name 类型
+func decodeServerName(b []byte) string {
+	r := newReader(b)
+	names := newReader(r.vector16())
+	for names.ok && len(names.b) > 0 {
+		typ := names.uint8()
+		name := names.vector16()
+		if names.ok && typ == 0 {
+			return string(name)
+		}
+	}
+	return ""
+}
+
+// decodeALPN 解析 application_layer_protocol_negotiation 扩展
+func decodeALPN(b []byte) []string {
+	r := newReader(b)
+	list := newReader(r.vector16())
+	var protos []string
+	for list.ok && len(list.b) > 0 {
+		if proto := list.vector8(); list.ok {
+			protos = append(protos, string(proto))
+		}
+	}
+	return protos
+}
+
+// decodeCertificate 解析 TLS 1.2 及以下版本的 Certificate 消息
+//
+//	certificate_list <0..2^24-1> : { cert_data <1..2^24-1> }*
+//
+// 仅解析首个证书（服务端证书）的元信息 ChainHash 为整条证书链 DER 编码的 SHA-256
+// 证书无法解析时返回 nil 不影响 RoundTrip 的归档
+func decodeCertificate(b []byte) *Certificate {
+	r := newReader(b)
+	list := newReader(r.bytes(r.uint24()))
+
+	h := sha256.New()
+	var leaf *x509.Certificate
+	var n int
+	for list.ok && len(list.b) > 0 {
+		der := list.bytes(list.uint24())
+		if !list.ok {
+			break
+		}
+		if leaf == nil {
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return nil
+			}
+			leaf = cert
+		}
+		h.Write(der)
+		n++
+	}
+	if leaf == nil {
+		return nil
+	}
+
+	sans := make([]string, 0, len(leaf.DNSNames)+len(leaf.IPAddresses))
+	sans = append(sans, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return &Certificate{
+		Subject:   leaf.Subject.String(),
+		Issuer:    leaf.Issuer.String(),
+		SANs:      sans,
+		NotBefore: leaf.NotBefore,
+		NotAfter:  leaf.NotAfter,
+		ChainHash: hex.EncodeToString(h.Sum(nil)),
+		ChainSize: n,
+		leaf:      leaf,
+	}
+}
+
+// matchHostname 判断证书是否适用于 SNI 中的主机名
+func (c *Certificate) matchHostname(host string) bool {
+	return c.leaf.VerifyHostname(strings.TrimSuffix(host, ".")) == nil
+}
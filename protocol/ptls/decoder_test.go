@@ -0,0 +1,405 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ptls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+func vec8(b []byte) []byte {
+	return append([]byte{byte(len(b))}, b...)
+}
+
+func vec16(b []byte) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(b))), b...)
+}
+
+func vec24(b []byte) []byte {
+	return append([]byte{byte(len(b) >> 16), byte(len(b) >> 8), byte(len(b))}, b...)
+}
+
+func extension(typ uint16, data []byte) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, typ), vec16(data)...)
+}
+
+func handshake(typ uint8, body []byte) []byte {
+	return append([]byte{typ}, vec24(body)...)
+}
+
+func record(typ uint8, fragment []byte) []byte {
+	return append([]byte{typ, 0x03, 0x03}, vec16(fragment)...)
+}
+
+func buildClientHello(serverName string, alpn []string, versions ...uint16) []byte {
+	var exts []byte
+	if serverName != "" {
+		exts = append(exts, extension(extServerName, vec16(append([]byte{0}, vec16([]byte(serverName))...)))...)
+	}
+	if len(alpn) > 0 {
+		var list []byte
+		for _, proto := range alpn {
+			list = append(list, vec8([]byte(proto))...)
+		}
+		exts = append(exts, extension(extALPN, vec16(list))...)
+	}
+	if len(versions) > 0 {
+		var list []byte
+		for _, v := range versions {
+			list = binary.BigEndian.AppendUint16(list, v)
+		}
+		exts = append(exts, extension(extSupportedVersions, vec8(list))...)
+	}
+
+	b := []byte{0x03, 0x03}
+	b = append(b, make([]byte, 32)...)
+	b = append(b, vec8(nil)...)
+	b = append(b, vec16([]byte{0x13, 0x01, 0xc0, 0x2f})...)
+	b = append(b, vec8([]byte{0})...)
+	b = append(b, vec16(exts)...)
+	return handshake(handshakeClientHello, b)
+}
+
+func buildServerHello(cipherSuite uint16, exts []byte) []byte {
+	b := []byte{0x03, 0x03}
+	b = append(b, make([]byte, 32)...)
+	b = append(b, vec8(nil)...)
+	b = binary.BigEndian.AppendUint16(b, cipherSuite)
+	b = append(b, 0)
+	if exts != nil {
+		b = append(b, vec16(exts)...)
+	}
+	return handshake(handshakeServerHello, b)
+}
+
+func buildCertificate(t *testing.T, notAfter time.Time, dnsNames ...string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		DNSNames:     dnsNames,
+		NotBefore:    notAfter.AddDate(-1, 0, 0),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	return handshake(handshakeCertificate, vec24(vec24(der)))
+}
+
+func TestDecodeClientHello(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  *Request
+	}{
+		{
+			name:  "TLS 1.2",
+			input: buildClientHello("example.com", nil),
+			want: &Request{
+				Version:    "TLS 1.2",
+				ServerName: "example.com",
+				JA3:        "349640b9e304f8ea23a8e0f71dde2248",
+				JA4:        "t12d020100_c1929292aa6b_000000000000",
+			},
+		},
+		{
+			name:  "TLS 1.3 with GREASE",
+			input: buildClientHello("example.com", []string{"h2", "http/1.1"}, 0x7a7a, tls.VersionTLS13, tls.VersionTLS12),
+			want: &Request{
+				Version:    "TLS 1.3",
+				ServerName: "example.com",
+				ALPN:       []string{"h2", "http/1.1"},
+				JA3:        "336f5f33f4497e06a9cd5231997c3982",
+				JA4:        "t13d0203h2_c1929292aa6b_b9a491fefe05",
+			},
+		},
+		{
+			name:  "Without SNI",
+			input: buildClientHello("", nil, tls.VersionTLS12),
+			want: &Request{
+				Version: "TLS 1.2",
+				JA3:     "b46c6295f2988c92872ed92441a25ba0",
+				JA4:     "t12i020100_c1929292aa6b_b9a491fefe05",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := decodeClientHello(tt.input[handshakeHeaderLength:])
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, req)
+		})
+	}
+
+	_, err := decodeClientHello([]byte{0x03, 0x03, 0x00})
+	assert.Equal(t, errMalformedHello, err)
+}
+
+func TestDecodeServerHello(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  *Response
+	}{
+		{
+			name:  "TLS 1.2 without extensions",
+			input: buildServerHello(tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, nil),
+			want: &Response{
+				Version:     "TLS 1.2",
+				CipherSuite: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+				version:     tls.VersionTLS12,
+			},
+		},
+		{
+			name: "TLS 1.3 with ALPN",
+			input: buildServerHello(tls.TLS_AES_128_GCM_SHA256, append(
+				extension(extSupportedVersions, []byte{0x03, 0x04}),
+				extension(extALPN, vec16(vec8([]byte("h2"))))...,
+			)),
+			want: &Response{
+				Version:     "TLS 1.3",
+				CipherSuite: "TLS_AES_128_GCM_SHA256",
+				ALPN:        "h2",
+				version:     tls.VersionTLS13,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rsp, err := decodeServerHello(tt.input[handshakeHeaderLength:])
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, rsp)
+		})
+	}
+}
+
+func decode(d *decoder, b []byte, t time.Time) ([]*role.Object, error) {
+	return d.Decode(zerocopy.NewBuffer(b), t)
+}
+
+func TestDecoder(t *testing.T) {
+	t0 := time.Unix(1751356800, 0)
+	st := socket.Tuple{SrcPort: 51000, DstPort: 443}
+
+	t.Run("TLS 1.2 full handshake", func(t *testing.T) {
+		client := NewDecoder(st, 443, common.NewOptions()).(*decoder)
+		hello := record(contentTypeHandshake, buildClientHello("api.example.com", nil))
+
+		// ClientHello 跨越两个数据包 Request.Time 以首个数据包为准
+		objs, err := decode(client, hello[:10], t0)
+		assert.NoError(t, err)
+		assert.Empty(t, objs)
+		objs, err = decode(client, hello[10:], t0.Add(time.Millisecond))
+		assert.NoError(t, err)
+		require.Len(t, objs, 1)
+		assert.EqualValues(t, role.Request, objs[0].Role)
+		req := objs[0].Obj.(*Request)
+		assert.Equal(t, t0, req.Time)
+		assert.Equal(t, len(hello), req.Size)
+		assert.Equal(t, "api.example.com", req.ServerName)
+
+		// 握手结束后的数据不再解析
+		objs, err = decode(client, record(contentTypeChangeCipherSpec, []byte{1}), t0)
+		assert.NoError(t, err)
+		assert.Empty(t, objs)
+		assert.True(t, client.done)
+
+		server := NewDecoder(st.Mirror(), 443, common.NewOptions()).(*decoder)
+		notAfter := t0.Add(72 * time.Hour).UTC()
+		flight := append(buildServerHello(tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, nil), buildCertificate(t, notAfter, "api.example.com")...)
+		flight = append(flight, handshake(14, nil)...) // ServerHelloDone
+		objs, err = decode(server, record(contentTypeHandshake, flight), t0.Add(2*time.Millisecond))
+		assert.NoError(t, err)
+		require.Len(t, objs, 1)
+		assert.EqualValues(t, role.Response, objs[0].Role)
+		rsp := objs[0].Obj.(*Response)
+		assert.Equal(t, "TLS 1.2", rsp.Version)
+		require.NotNil(t, rsp.Certificate)
+		assert.Equal(t, "CN=example.com", rsp.Certificate.Subject)
+		assert.Equal(t, []string{"api.example.com"}, rsp.Certificate.SANs)
+		assert.Equal(t, notAfter, rsp.Certificate.NotAfter)
+		assert.Equal(t, 1, rsp.Certificate.ChainSize)
+		assert.Len(t, rsp.Certificate.ChainHash, 64)
+	})
+
+	t.Run("Session resumption", func(t *testing.T) {
+		server := NewDecoder(st.Mirror(), 443, common.NewOptions()).(*decoder)
+		b := record(contentTypeHandshake, buildServerHello(tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, nil))
+		objs, err := decode(server, b, t0)
+		assert.NoError(t, err)
+		assert.Empty(t, objs)
+
+		objs, err = decode(server, record(contentTypeChangeCipherSpec, []byte{1}), t0.Add(time.Millisecond))
+		assert.NoError(t, err)
+		require.Len(t, objs, 1)
+		assert.Nil(t, objs[0].Obj.(*Response).Certificate)
+	})
+
+	t.Run("Established connection", func(t *testing.T) {
+		d := NewDecoder(st, 443, common.NewOptions()).(*decoder)
+		objs, err := decode(d, record(contentTypeApplicationData, []byte("ciphertext")), t0)
+		assert.NoError(t, err)
+		assert.Empty(t, objs)
+		assert.True(t, d.done)
+	})
+
+	t.Run("Invalid record", func(t *testing.T) {
+		d := NewDecoder(st, 443, common.NewOptions()).(*decoder)
+		_, err := decode(d, []byte("GET / HTTP/1.1\r\n"), t0)
+		assert.Equal(t, errInvalidRecord, err)
+
+		// 后续数据直接忽略 不再重复报错
+		_, err = decode(d, []byte("Host: example.com\r\n"), t0)
+		assert.NoError(t, err)
+	})
+}
+
+func TestCertInspector(t *testing.T) {
+	t0 := time.Unix(1751356800, 0)
+	cert := decodeCertificate(buildCertificate(t, t0.Add(24*time.Hour), "*.example.com")[handshakeHeaderLength:])
+	require.NotNil(t, cert)
+
+	tests := []struct {
+		name       string
+		serverName string
+		time       time.Time
+		want       []string
+	}{
+		{
+			name:       "Valid",
+			serverName: "api.example.com",
+			time:       t0.Add(-30 * 24 * time.Hour),
+		},
+		{
+			name:       "Expiring",
+			serverName: "api.example.com",
+			time:       t0,
+			want:       []string{WarningExpiring},
+		},
+		{
+			name:       "Expired and mismatch",
+			serverName: "example.org",
+			time:       t0.Add(48 * time.Hour),
+			want:       []string{WarningExpired, WarningHostnameMismatch},
+		},
+	}
+
+	ci := newCertInspector(defaultExpiryWarning)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &RoundTrip{
+				request:  &Request{ServerName: tt.serverName},
+				response: &Response{Host: "10.0.0.2", Port: 443, Time: tt.time, Certificate: cert},
+			}
+			ci.Inspect(rt)
+			assert.Equal(t, tt.want, rt.response.Warnings)
+		})
+	}
+
+	// 同一目的端的相同证书仅告警一次
+	assert.False(t, ci.report("10.0.0.2", 443, "api.example.com", WarningExpiring, cert.ChainHash))
+	assert.True(t, ci.report("10.0.0.2", 443, "api.example.com", WarningExpiring, "rotated"))
+}
+
+func TestRenegotiation(t *testing.T) {
+	t0 := time.Unix(1751356800, 0)
+	st := socket.Tuple{SrcPort: 51000, DstPort: 443}
+	appData := record(contentTypeApplicationData, make([]byte, 32))
+	ccs := record(contentTypeChangeCipherSpec, []byte{1})
+	encrypted := record(contentTypeHandshake, make([]byte, 40))
+
+	tests := []struct {
+		name       string
+		opts       common.Options
+		client     bool
+		data       []byte
+		initiators []string
+	}{
+		{
+			name:       "Client",
+			opts:       common.Options{OptTrackRenegotiation: true},
+			client:     true,
+			data:       concat(ccs, encrypted, appData, encrypted, encrypted, appData, encrypted),
+			initiators: []string{initiatorClient, initiatorClient},
+		},
+		{
+			name:       "Server",
+			opts:       common.Options{OptTrackRenegotiation: true, OptEnablePhases: true},
+			data:       concat(ccs, encrypted, appData, appData, appData, encrypted),
+			initiators: []string{initiatorServer},
+		},
+		{
+			name:   "Disabled",
+			opts:   common.NewOptions(),
+			client: true,
+			data:   concat(ccs, encrypted, appData, encrypted),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d *decoder
+			var hello []byte
+			if tt.client {
+				d = NewDecoder(st, 443, tt.opts).(*decoder)
+				hello = record(contentTypeHandshake, buildClientHello("api.example.com", nil))
+			} else {
+				d = NewDecoder(st.Mirror(), 443, tt.opts).(*decoder)
+				hello = record(contentTypeHandshake, append(buildServerHello(tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, nil), handshake(14, nil)...))
+			}
+			_, err := decode(d, hello, t0)
+			require.NoError(t, err)
+
+			objs, err := decode(d, tt.data, t0.Add(time.Second))
+			require.NoError(t, err)
+
+			var initiators []string
+			for _, obj := range objs {
+				if obj.Role != role.OneWay {
+					continue
+				}
+				req := obj.Obj.(*Request)
+				assert.True(t, req.Renegotiation)
+				assert.Equal(t, uint16(443), req.ServerPort)
+				assert.Equal(t, uint16(51000), req.Port)
+				initiators = append(initiators, req.Initiator)
+
+				rt := RoundTrip{request: req}
+				assert.True(t, rt.OneWay())
+				assert.True(t, rt.Validate())
+				assert.Zero(t, rt.Duration())
+			}
+			assert.Equal(t, tt.initiators, initiators)
+		})
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ptls
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// clientHello 计算客户端指纹所需的 ClientHello 参数 均保持报文中的原始顺序
+type clientHello struct {
+	legacyVersion       uint16
+	version             uint16 // supported_versions 中的最高版本
+	ciphers             []uint16
+	extensions          []uint16
+	groups              []uint16
+	pointFormats        []uint8
+	signatureAlgorithms []uint16
+	serverName          string
+	alpn                []string
+}
+
+func withoutGREASE(vs []uint16) []uint16 {
+	out := make([]uint16, 0, len(vs))
+	for _, v := range vs {
+		if !isGREASE(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func joinInts[T uint8 | uint16](vs []T) string {
+	s := make([]string, 0, len(vs))
+	for _, v := range vs {
+		s = append(s, strconv.Itoa(int(v)))
+	}
+	return strings.Join(s, "-")
+}
+
+func joinHex(vs []uint16) string {
+	s := make([]string, 0, len(vs))
+	for _, v := range vs {
+		s = append(s, fmt.Sprintf("%04x", v))
+	}
+	return strings.Join(s, ",")
+}
+
+// ja3 计算 JA3 指纹
+//
+// https://github.com/salesforce/ja3
+//
+//	MD5(SSLVersion,Ciphers,Extensions,EllipticCurves,EllipticCurvePointFormats)
+//
+// 各字段内的数值以十进制表示并使用 '-' 连接 GREASE 值不参与计算
+func (h clientHello) ja3() string {
+	s := strings.Join([]string{
+		strconv.Itoa(int(h.legacyVersion)),
+		joinInts(withoutGREASE(h.ciphers)),
+		joinInts(withoutGREASE(h.extensions)),
+		joinInts(withoutGREASE(h.groups)),
+		joinInts(h.pointFormats),
+	}, ",")
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+var ja4Versions = map[uint16]string{
+	0x0304: "13",
+	0x0303: "12",
+	0x0302: "11",
+	0x0301: "10",
+	0x0300: "s3",
+	0x0002: "s2",
+}
+
+// ja4Hash 返回 SHA-256 的前 12 位十六进制字符 内容为空时以 0 填充
+func ja4Hash(s string) string {
+	if s == "" {
+		return "000000000000"
+	}
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+func ja4Count(n int) string {
+	return fmt.Sprintf("%02d", min(n, 99))
+}
+
+func isAlnum(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// ja4ALPN 取首个 ALPN 的首尾字符 非字母数字时取首字节高位与尾字节低位的十六进制
+func ja4ALPN(alpn []string) string {
+	if len(alpn) == 0 || alpn[0] == "" {
+		return "00"
+	}
+	first, last := alpn[0][0], alpn[0][len(alpn[0])-1]
+	if isAlnum(first) && isAlnum(last) {
+		return string([]byte{first, last})
+	}
+	return hex.EncodeToString([]byte{first})[:1] + hex.EncodeToString([]byte{last})[1:]
+}
+
+// ja4 计算 JA4 指纹
+//
+// https://github.com/FoxIO-LLC/ja4/blob/main/technical_details/JA4.md
+//
+//	t13d1516h2_8daaf6152771_b186095e22b6
+//	|  | | | |  |            |
+//	|  | | | |  |            +- SHA256(排序后的扩展 除 SNI/ALPN _ 签名算法原始顺序)[:12]
+//	|  | | | |  +- SHA256(排序后的加密套件)[:12]
+//	|  | | | +- 首个 ALPN 的首尾字符
+//	|  | | +- 扩展数量
+//	|  | +- 加密套件数量
+//	|  +- d: 携带 SNI i: 未携带 SNI
+//	+- t: TCP 以及 TLS 版本
+//
+// 与 JA3 不同 JA4 对加密套件和扩展做了排序 不受客户端随机打乱扩展顺序的影响
+func (h clientHello) ja4() string {
+	ciphers := withoutGREASE(h.ciphers)
+	extensions := withoutGREASE(h.extensions)
+
+	version, ok := ja4Versions[h.version]
+	if !ok {
+		version = "00"
+	}
+	sni := "i"
+	if h.serverName != "" {
+		sni = "d"
+	}
+	a := "t" + version + sni + ja4Count(len(ciphers)) + ja4Count(len(extensions)) + ja4ALPN(h.alpn)
+
+	slices.Sort(ciphers)
+	b := ja4Hash(joinHex(ciphers))
+
+	sorted := make([]uint16, 0, len(extensions))
+	for _, ext := range extensions {
+		if ext != extServerName && ext != extALPN {
+			sorted = append(sorted, ext)
+		}
+	}
+	slices.Sort(sorted)
+	c := joinHex(sorted)
+	if sigs := joinHex(h.signatureAlgorithms); sigs != "" && c != "" {
+		c += "_" + sigs
+	}
+
+	return a + "_" + b + "_" + ja4Hash(c)
+}
@@ -0,0 +1,92 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ptls
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJA3(t *testing.T) {
+	hello := clientHello{
+		legacyVersion: 0x0301,
+		ciphers:       []uint16{0x0a0a, 47, 53, 5, 10, 49161, 49162, 49171, 49172, 50, 56, 19, 4},
+		extensions:    []uint16{0, 10, 11, 0x1a1a},
+		groups:        []uint16{23, 24, 25},
+		pointFormats:  []uint8{0},
+	}
+	// 769,47-53-5-10-49161-49162-49171-49172-50-56-19-4,0-10-11,23-24-25,0
+	assert.Equal(t, "ada70206e40642a3e4461f35503241d5", hello.ja3())
+}
+
+func TestJA4(t *testing.T) {
+	chrome := clientHello{
+		version: 0x0304,
+		ciphers: []uint16{
+			0x2a2a, 0x1301, 0x1302, 0x1303, 0xc02b, 0xc02f, 0xc02c, 0xc030,
+			0xcca9, 0xcca8, 0xc013, 0xc014, 0x009c, 0x009d, 0x002f, 0x0035,
+		},
+		extensions: []uint16{
+			0x3a3a, 0x0000, 0x0017, 0xff01, 0x000a, 0x000b, 0x0023, 0x0010, 0x0005,
+			0x000d, 0x0012, 0x0033, 0x002d, 0x002b, 0x001b, 0x4469, 0x0015,
+		},
+		signatureAlgorithms: []uint16{0x0403, 0x0804, 0x0401, 0x0503, 0x0805, 0x0501, 0x0806, 0x0601},
+		serverName:          "example.com",
+		alpn:                []string{"h2", "http/1.1"},
+	}
+
+	tests := []struct {
+		name  string
+		hello func() clientHello
+		want  string
+	}{
+		{
+			name:  "Chrome",
+			hello: func() clientHello { return chrome },
+			want:  "t13d1516h2_8daaf6152771_e5627efa2ab1",
+		},
+		{
+			name: "Without SNI and ALPN",
+			hello: func() clientHello {
+				h := chrome
+				h.serverName = ""
+				h.alpn = nil
+				return h
+			},
+			want: "t13i151600_8daaf6152771_e5627efa2ab1",
+		},
+		{
+			name: "Non-alphanumeric ALPN",
+			hello: func() clientHello {
+				h := chrome
+				h.version = 0x0303
+				h.alpn = []string{"\xabhttp\xcd"}
+				return h
+			},
+			want: "t12d1516ad_8daaf6152771_e5627efa2ab1",
+		},
+		{
+			name:  "Empty",
+			hello: func() clientHello { return clientHello{version: 0x0303} },
+			want:  "t12i000000_000000000000_000000000000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.hello().ja4())
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ptls
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+var phasesAttributedTotal = protocol.NewOptionCounter(socket.L7ProtoTLS, OptEnablePhases, "attributed")
+
+// Phases 链接首个请求的耗时拆分 用于区分证书校验 / OCSP 等握手阶段的慢与后端处理的慢
+//
+// TCPConnect 为 SYN 至客户端确认 SYN-ACK 的耗时 未观测到三次握手时为 0
+// TLSHandshake 为 ClientHello 至客户端发出首个应用数据 record 的耗时
+// Application 为客户端发出首个应用数据 record 至服务端返回首个应用数据 record 的耗时
+// 客户端在发送应用数据之前断开链接时仅记录 TCPConnect 未收到服务端应用数据时 Application 为 0
+//
+// TLS 1.3 的服务端可能在握手之后立即下发 NewSessionTicket 若其与客户端请求处于同一时刻 Application 会偏小
+type Phases struct {
+	TCPConnect   time.Duration
+	TLSHandshake time.Duration
+	Application  time.Duration
+}
+
+// phaseMatcher 开启阶段拆分时使用的匹配器 每个链接独立一个实例
+//
+// ServerHello（或 Certificate）归档后暂不输出 等待首次应用数据交换完成后再连同 Phases 一起输出
+// 客户端中断链接时提前输出已知的阶段
+type phaseMatcher struct {
+	req  *role.Object
+	rsp  *role.Object
+	apps []time.Time // 客户端方向的 ApplicationData record 时间
+}
+
+func newPhaseMatcher() role.Matcher {
+	return &phaseMatcher{}
+}
+
+// Pending 返回尚未完成配对的请求数量
+func (m *phaseMatcher) Pending() int {
+	if m.req != nil && m.rsp == nil {
+		return 1
+	}
+	return 0
+}
+
+func (m *phaseMatcher) Match(o *role.Object) *role.Pair {
+	switch obj := o.Obj.(type) {
+	case *Request:
+		*m = phaseMatcher{req: o}
+
+	case *Response:
+		if m.req != nil && m.rsp == nil {
+			m.rsp = o
+		}
+
+	case *appRecord:
+		if m.rsp == nil {
+			return nil
+		}
+		if o.Role == role.Request {
+			m.apps = append(m.apps, obj.Time)
+			return nil
+		}
+
+		start, ok := m.applicationStart()
+		switch {
+		case obj.abort:
+			if ok {
+				return m.archive(start, start)
+			}
+			return m.archive(time.Time{}, time.Time{})
+		case ok && !obj.Time.Before(start):
+			return m.archive(start, obj.Time)
+		}
+	}
+	return nil
+}
+
+// applicationStart 返回客户端首个应用数据 record 的时间
+//
+// TLS 1.3 客户端的 Finished 同样以 ApplicationData 的形式加密传输 需要跳过
+func (m *phaseMatcher) applicationStart() (time.Time, bool) {
+	idx := 0
+	if m.rsp.Obj.(*Response).version >= tls.VersionTLS13 {
+		idx = 1
+	}
+	if len(m.apps) <= idx {
+		return time.Time{}, false
+	}
+	return m.apps[idx], true
+}
+
+func (m *phaseMatcher) archive(start, end time.Time) *role.Pair {
+	req := m.req.Obj.(*Request)
+	rsp := m.rsp.Obj.(*Response)
+	rsp.Phases = &Phases{}
+	if !start.IsZero() {
+		rsp.Phases.TLSHandshake = start.Sub(req.Time)
+		rsp.Phases.Application = end.Sub(start)
+		phasesAttributedTotal.Inc()
+	}
+
+	pair := &role.Pair{Request: m.req, Response: m.rsp}
+	*m = phaseMatcher{}
+	return pair
+}
@@ -0,0 +1,144 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ptls
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+func TestPhases(t *testing.T) {
+	t0 := time.Unix(1751356800, 0)
+	st := socket.Tuple{SrcPort: 51000, DstPort: 443}
+	ms := func(n int) time.Time { return t0.Add(time.Duration(n) * time.Millisecond) }
+	appData := record(contentTypeApplicationData, make([]byte, 32))
+	ccs := record(contentTypeChangeCipherSpec, []byte{1})
+	tls13 := buildServerHello(tls.TLS_AES_128_GCM_SHA256, extension(extSupportedVersions, []byte{0x03, 0x04}))
+
+	type packet struct {
+		client bool
+		ms     int
+		data   []byte
+		abort  bool
+	}
+
+	tests := []struct {
+		name    string
+		packets []packet
+		phases  *Phases
+	}{
+		{
+			name: "TLS 1.2",
+			packets: []packet{
+				{client: true, ms: 0, data: record(contentTypeHandshake, buildClientHello("api.example.com", nil))},
+				{ms: 20, data: record(contentTypeHandshake, append(buildServerHello(tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, nil), handshake(14, nil)...))},
+				{client: true, ms: 21, data: concat(record(contentTypeHandshake, handshake(16, nil)), ccs, record(contentTypeHandshake, make([]byte, 40)))},
+				{ms: 40, data: concat(ccs, record(contentTypeHandshake, make([]byte, 40)))},
+				{client: true, ms: 41, data: appData},
+				{ms: 141, data: concat(appData, appData)},
+			},
+			phases: &Phases{TLSHandshake: 41 * time.Millisecond, Application: 100 * time.Millisecond},
+		},
+		{
+			name: "TLS 1.3",
+			packets: []packet{
+				{client: true, ms: 0, data: record(contentTypeHandshake, buildClientHello("api.example.com", nil, tls.VersionTLS13))},
+				{ms: 20, data: concat(record(contentTypeHandshake, tls13), ccs, appData, appData)},
+				{client: true, ms: 21, data: concat(ccs, appData)}, // Finished
+				{ms: 22, data: appData}, // NewSessionTicket
+				{client: true, ms: 30, data: appData},
+				{ms: 80, data: appData[:3]},
+				{ms: 81, data: appData[3:]},
+			},
+			phases: &Phases{TLSHandshake: 30 * time.Millisecond, Application: 50 * time.Millisecond},
+		},
+		{
+			name: "Aborted before application data",
+			packets: []packet{
+				{client: true, ms: 0, data: record(contentTypeHandshake, buildClientHello("api.example.com", nil))},
+				{ms: 20, data: concat(record(contentTypeHandshake, buildServerHello(tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, nil)), ccs)},
+				{ms: 500, abort: true},
+			},
+			phases: &Phases{},
+		},
+	}
+
+	opts := common.Options{OptEnablePhases: true}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewDecoder(st, 443, opts).(*decoder)
+			server := NewDecoder(st.Mirror(), 443, opts).(*decoder)
+			matcher := newPhaseMatcher()
+
+			var pairs []*role.Pair
+			for _, pkt := range tt.packets {
+				var objs []*role.Object
+				var err error
+				switch {
+				case pkt.abort:
+					objs = server.Abort(ms(pkt.ms))
+				case pkt.client:
+					objs, err = decode(client, pkt.data, ms(pkt.ms))
+				default:
+					objs, err = decode(server, pkt.data, ms(pkt.ms))
+				}
+				require.NoError(t, err)
+				for _, obj := range objs {
+					if pair := matcher.Match(obj); pair != nil {
+						pairs = append(pairs, pair)
+					}
+				}
+			}
+
+			require.Len(t, pairs, 1)
+			rt := RoundTrip{
+				request:  pairs[0].Request.Obj.(*Request),
+				response: pairs[0].Response.Obj.(*Response),
+			}
+			rt.SetTCPConnect(time.Millisecond)
+			tt.phases.TCPConnect = time.Millisecond
+			assert.Equal(t, tt.phases, rt.response.Phases)
+			assert.True(t, rt.Validate())
+		})
+	}
+}
+
+func TestPhasesDisabled(t *testing.T) {
+	st := socket.Tuple{SrcPort: 51000, DstPort: 443}
+	d := NewDecoder(st, 443, common.NewOptions()).(*decoder)
+	_, err := decode(d, record(contentTypeHandshake, buildClientHello("api.example.com", nil)), time.Unix(1751356800, 0))
+	require.NoError(t, err)
+
+	objs, err := decode(d, record(contentTypeChangeCipherSpec, []byte{1}), time.Unix(1751356800, 0))
+	assert.NoError(t, err)
+	assert.Empty(t, objs)
+	assert.True(t, d.done)
+	assert.Nil(t, d.Abort(time.Unix(1751356800, 0)))
+}
+
+func concat(bs ...[]byte) []byte {
+	var b []byte
+	for _, p := range bs {
+		b = append(b, p...)
+	}
+	return b
+}
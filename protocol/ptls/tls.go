@@ -0,0 +1,283 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ptls
+
+import (
+	"crypto/x509"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/logger"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+func init() {
+	protocol.Register(socket.L7ProtoTLS, NewConnPool)
+	protocol.Describe(socket.L7ProtoTLS, protocol.Capability{
+		Versions: []string{"1.0", "1.1", "1.2", "1.3"},
+		Options:  []string{OptExpiryWarning, OptEnablePhases, OptTrackRenegotiation},
+	})
+}
+
+const (
+	// OptExpiryWarning 证书剩余有效期低于该值时告警
+	OptExpiryWarning = "expiryWarning"
+
+	// OptEnablePhases 是否将链接首个请求的耗时拆分为 TCP 建连 / TLS 握手 / 应用交互三个阶段
+	OptEnablePhases = "enablePhases"
+
+	// OptTrackRenegotiation 是否在握手完成后继续跟踪链接中的 record 以发现重协商
+	OptTrackRenegotiation = "trackRenegotiation"
+
+	defaultExpiryWarning = 14 * 24 * time.Hour
+
+	// maxTrackedDestinations 已告警目的端的记录上限 超限后清空重新记录
+	maxTrackedDestinations = 4096
+)
+
+// Warning 证书告警类型
+const (
+	WarningExpired          = "expired"
+	WarningExpiring         = "expiring"
+	WarningHostnameMismatch = "hostname_mismatch"
+)
+
+// 重协商的发起方
+const (
+	initiatorClient = "client"
+	initiatorServer = "server"
+)
+
+var renegotiationsTotal = protocol.NewOptionCounter(socket.L7ProtoTLS, OptTrackRenegotiation, "renegotiated")
+
+var certificateWarningsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: common.App,
+		Name:      "tls_certificate_warnings_total",
+		Help:      "TLS certificate warnings observed on the wire total",
+	},
+	[]string{"reason"},
+)
+
+// NewConnPool 创建 TLS 协议连接池
+func NewConnPool(opts common.Options) protocol.ConnPool {
+	expiryWarning, err := opts.GetDuration(OptExpiryWarning)
+	if err != nil || expiryWarning <= 0 {
+		expiryWarning = defaultExpiryWarning
+	}
+	inspector := newCertInspector(expiryWarning)
+
+	createMatcher := role.NewSingleMatcher
+	if enablePhases, _ := opts.GetBool(OptEnablePhases); enablePhases {
+		createMatcher = newPhaseMatcher
+	}
+
+	return protocol.NewL7TCPConnPool(
+		createMatcher,
+		func(pair *role.Pair) socket.RoundTrip {
+			if pair.Response == nil {
+				return &RoundTrip{request: pair.Request.Obj.(*Request)}
+			}
+			rt := &RoundTrip{
+				request:  pair.Request.Obj.(*Request),
+				response: pair.Response.Obj.(*Response),
+			}
+			inspector.Inspect(rt)
+			return rt
+		},
+		func(st socket.Tuple, serverPort socket.Port) protocol.Decoder {
+			return NewDecoder(st, serverPort, opts)
+		},
+	)
+}
+
+// certInspector 检查握手中的服务端证书
+//
+// 同一目的端（服务端地址 + SNI）的同一证书链 每类告警仅输出一次日志 指标则按照握手次数累加
+type certInspector struct {
+	mut           sync.Mutex
+	expiryWarning time.Duration
+	reported      map[string]string // 目的端 + 告警类型 -> 证书链哈希
+}
+
+func newCertInspector(expiryWarning time.Duration) *certInspector {
+	return &certInspector{
+		expiryWarning: expiryWarning,
+		reported:      make(map[string]string),
+	}
+}
+
+// Inspect 检查证书有效期以及与 SNI 的匹配情况 并将告警记录至 Response.Warnings
+//
+// 有效期以握手时间为基准 而非当前时间 保证离线回放的结果稳定
+func (ci *certInspector) Inspect(rt *RoundTrip) {
+	req, rsp := rt.request, rt.response
+	cert := rsp.Certificate
+	if cert == nil {
+		return
+	}
+
+	switch remain := cert.NotAfter.Sub(rsp.Time); {
+	case remain <= 0:
+		rsp.Warnings = append(rsp.Warnings, WarningExpired)
+	case remain < ci.expiryWarning:
+		rsp.Warnings = append(rsp.Warnings, WarningExpiring)
+	}
+	if req.ServerName != "" && !cert.matchHostname(req.ServerName) {
+		rsp.Warnings = append(rsp.Warnings, WarningHostnameMismatch)
+	}
+
+	for _, warning := range rsp.Warnings {
+		certificateWarningsTotal.WithLabelValues(warning).Inc()
+		if ci.report(rsp.Host, rsp.Port, req.ServerName, warning, cert.ChainHash) {
+			logger.Warnf("tls certificate %s: server=%s:%d, serverName=%q, subject=%q, issuer=%q, notAfter=%s, chainHash=%s",
+				warning, rsp.Host, rsp.Port, req.ServerName, cert.Subject, cert.Issuer, cert.NotAfter.Format(time.RFC3339), cert.ChainHash)
+		}
+	}
+}
+
+// report 返回是否为首次观测到该告警
+func (ci *certInspector) report(host string, port uint16, serverName, warning, chainHash string) bool {
+	ci.mut.Lock()
+	defer ci.mut.Unlock()
+
+	key := fmt.Sprintf("%s:%d/%s/%s", host, port, serverName, warning)
+	if ci.reported[key] == chainHash {
+		return false
+	}
+	if len(ci.reported) >= maxTrackedDestinations {
+		ci.reported = make(map[string]string)
+	}
+	ci.reported[key] = chainHash
+	return true
+}
+
+// Request TLS ClientHello
+type Request struct {
+	Host       string
+	Port       uint16
+	Proto      string
+	Size       int
+	Time       time.Time
+	Version    string // 客户端支持的最高版本
+	ServerName string
+	ALPN       []string `json:",omitempty"`
+	JA3        string   // 客户端指纹 同一 TLS 库及其配置的指纹相同
+	JA4        string
+
+	// Renegotiation 握手完成后在加密链路中再次出现握手消息 开启 OptTrackRenegotiation 后以单向事件输出
+	// 此时 Initiator 为发起方（client / server）ServerHost / ServerPort 为服务端地址 ClientHello 相关字段均为空
+	Renegotiation bool   `json:",omitempty"`
+	Initiator     string `json:",omitempty"`
+	ServerHost    string `json:",omitempty"`
+	ServerPort    uint16 `json:",omitempty"`
+}
+
+// Certificate 服务端证书元信息
+type Certificate struct {
+	Subject   string
+	Issuer    string
+	SANs      []string
+	NotBefore time.Time
+	NotAfter  time.Time
+	ChainHash string
+	ChainSize int
+
+	leaf *x509.Certificate
+}
+
+// Response TLS ServerHello
+//
+// Certificate 仅在 TLS 1.2 及以下版本的完整握手中存在
+type Response struct {
+	Host        string
+	Port        uint16
+	Proto       string
+	Size        int
+	Time        time.Time
+	Version     string // 协商后的版本
+	CipherSuite string
+	ALPN        string       `json:",omitempty"`
+	Certificate *Certificate `json:",omitempty"`
+	Warnings    []string     `json:",omitempty"`
+	Phases      *Phases      `json:",omitempty"`
+
+	version uint16
+}
+
+var _ socket.RoundTrip = (*RoundTrip)(nil)
+
+// NewRoundTrip 根据配对成功的 *role.Pair 创建 RoundTrip
+//
+// HTTP CONNECT 隧道内的握手复用此函数 不经过证书检查
+func NewRoundTrip(pair *role.Pair) socket.RoundTrip {
+	return &RoundTrip{
+		request:  pair.Request.Obj.(*Request),
+		response: pair.Response.Obj.(*Response),
+	}
+}
+
+// RoundTrip TLS 握手来回
+//
+// 实现了 socket.RoundTrip 接口 Duration 为 ClientHello 至服务端证书（或 ServerHello）的耗时
+// 重协商事件没有 Response 见 Request.Renegotiation
+type RoundTrip struct {
+	request  *Request
+	response *Response
+}
+
+func (rt RoundTrip) Proto() socket.L7Proto {
+	return socket.L7ProtoTLS
+}
+
+func (rt RoundTrip) Request() any {
+	return rt.request
+}
+
+func (rt RoundTrip) Response() any {
+	return rt.response
+}
+
+func (rt RoundTrip) Duration() time.Duration {
+	if rt.OneWay() {
+		return 0
+	}
+	return rt.response.Time.Sub(rt.request.Time)
+}
+
+func (rt RoundTrip) Validate() bool {
+	if rt.OneWay() {
+		return true
+	}
+	return rt.response.Time.After(rt.request.Time)
+}
+
+// OneWay 实现了 socket.OneWayRoundTrip 接口
+func (rt RoundTrip) OneWay() bool {
+	return rt.response == nil
+}
+
+// SetTCPConnect 实现 protocol.TCPConnectSetter 接口 仅在开启阶段拆分时记录
+func (rt RoundTrip) SetTCPConnect(d time.Duration) {
+	if rt.response != nil && rt.response.Phases != nil {
+		rt.response.Phases.TCPConnect = d
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package pudprpc
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+const (
+	PROTO = "UDPRPC"
+
+	// OptTransactionIDOffset 事务 ID 在报文中的起始偏移 单位 Bytes
+	OptTransactionIDOffset = "transactionIdOffset"
+
+	// OptTransactionIDLength 事务 ID 的长度 单位 Bytes
+	OptTransactionIDLength = "transactionIdLength"
+
+	defaultIDLength = 4
+	maxIDLength     = 16
+)
+
+func newError(code protocol.ErrorCode, format string, args ...any) error {
+	return protocol.NewDecodeError(socket.L7ProtoUDPRPC, code, "udprpc/decoder: "+format, args...)
+}
+
+type decoder struct {
+	st         socket.TupleRaw
+	serverPort socket.Port
+	offset     int
+	length     int
+}
+
+func NewDecoder(st socket.Tuple, serverPort socket.Port, opts common.Options) protocol.Decoder {
+	offset, _ := opts.GetInt(OptTransactionIDOffset)
+	length, _ := opts.GetInt(OptTransactionIDLength)
+	if length <= 0 || length > maxIDLength {
+		length = defaultIDLength
+	}
+	return &decoder{
+		st:         st.ToRaw(),
+		serverPort: serverPort,
+		offset:     max(offset, 0),
+		length:     length,
+	}
+}
+
+// Decode 从 zerocopy.Reader 中解析自定义 UDP RPC 报文
+//
+// 协议内容未知 仅按照配置的偏移以及长度提取事务 ID（十六进制编码）发往服务端端口的报文即为请求
+// 适用于请求与响应在相同位置携带事务 ID 的私有协议 如内部 RPC 以及游戏、物联网等场景的自定义协议
+func (d *decoder) Decode(r zerocopy.Reader, t time.Time) ([]*role.Object, error) {
+	b, err := r.Read(common.ReadWriteBlockSize)
+	if err != nil {
+		return nil, nil
+	}
+
+	end := d.offset + d.length
+	if len(b) < end {
+		return nil, newError(protocol.ErrCodeHeaderTooShort, "packet too short (%d bytes)", len(b))
+	}
+	id := hex.EncodeToString(b[d.offset:end])
+
+	if socket.Port(d.st.DstPort) == d.serverPort {
+		return []*role.Object{role.NewRequestObject(&Request{
+			Host:          d.st.SrcIP,
+			Port:          d.st.SrcPort,
+			Proto:         PROTO,
+			Size:          len(b),
+			Time:          t,
+			TransactionID: id,
+		})}, nil
+	}
+	return []*role.Object{role.NewResponseObject(&Response{
+		Host:          d.st.SrcIP,
+		Port:          d.st.SrcPort,
+		Proto:         PROTO,
+		Size:          len(b),
+		Time:          t,
+		TransactionID: id,
+	})}, nil
+}
+
+// Free 释放持有的资源
+func (d *decoder) Free() {}
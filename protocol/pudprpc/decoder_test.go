@@ -0,0 +1,121 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package pudprpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+var (
+	client = socket.Tuple{SrcIP: socket.ToIPV4([]byte{10, 0, 0, 1}), SrcPort: 50001, DstIP: socket.ToIPV4([]byte{10, 0, 0, 2}), DstPort: 9000}
+	server = client.Mirror()
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		st    socket.Tuple
+		opts  common.Options
+		input []byte
+		want  any
+	}{
+		{
+			name:  "Default Request",
+			st:    client,
+			opts:  common.NewOptions(),
+			input: []byte{0x00, 0x00, 0x00, 0x2a, 'p', 'i', 'n', 'g'},
+			want: &Request{
+				Host:          "10.0.0.1",
+				Port:          50001,
+				Proto:         PROTO,
+				Size:          8,
+				TransactionID: "0000002a",
+			},
+		},
+		{
+			name:  "Offset Response",
+			st:    server,
+			opts:  common.Options{OptTransactionIDOffset: 2, OptTransactionIDLength: 2},
+			input: []byte{0x01, 0x80, 0xbe, 0xef, 'p', 'o', 'n', 'g'},
+			want: &Response{
+				Host:          "10.0.0.2",
+				Port:          9000,
+				Proto:         PROTO,
+				Size:          8,
+				TransactionID: "beef",
+			},
+		},
+		{
+			name:  "Invalid Length",
+			st:    client,
+			opts:  common.Options{OptTransactionIDLength: 64},
+			input: []byte{0xca, 0xfe, 0xba, 0xbe},
+			want: &Request{
+				Host:          "10.0.0.1",
+				Port:          50001,
+				Proto:         PROTO,
+				Size:          4,
+				TransactionID: "cafebabe",
+			},
+		},
+	}
+
+	var t0 time.Time
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(tt.st, 9000, tt.opts)
+			objs, err := d.Decode(zerocopy.NewBuffer(tt.input), t0)
+			assert.NoError(t, err)
+			assert.Len(t, objs, 1)
+			assert.Equal(t, tt.want, objs[0].Obj)
+		})
+	}
+}
+
+func TestDecodeFailed(t *testing.T) {
+	d := NewDecoder(client, 9000, common.Options{OptTransactionIDOffset: 4})
+	objs, err := d.Decode(zerocopy.NewBuffer([]byte{0x00, 0x00, 0x00, 0x2a, 0x01}), time.Time{})
+	assert.Error(t, err)
+	assert.Empty(t, objs)
+}
+
+func TestMatchTransaction(t *testing.T) {
+	t0 := time.Now()
+	c := NewDecoder(client, 9000, common.NewOptions())
+	s := NewDecoder(server, 9000, common.NewOptions())
+
+	matcher := role.NewTransactionMatcher(maxRecordSize, defaultTimeout, extractTransaction)
+
+	first, _ := c.Decode(zerocopy.NewBuffer([]byte{0, 0, 0, 1}), t0)
+	second, _ := c.Decode(zerocopy.NewBuffer([]byte{0, 0, 0, 2}), t0.Add(time.Millisecond))
+	rsps, _ := s.Decode(zerocopy.NewBuffer([]byte{0, 0, 0, 1}), t0.Add(3*time.Millisecond))
+
+	assert.Nil(t, matcher.Match(first[0]))
+	assert.Nil(t, matcher.Match(second[0]))
+
+	// 响应乱序到达时按照事务 ID 配对
+	pair := matcher.Match(rsps[0])
+	assert.NotNil(t, pair)
+
+	rt := &RoundTrip{request: pair.Request.Obj.(*Request), response: pair.Response.Obj.(*Response)}
+	assert.True(t, rt.Validate())
+	assert.Equal(t, 3*time.Millisecond, rt.Duration())
+}
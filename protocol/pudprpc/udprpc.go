@@ -0,0 +1,118 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package pudprpc
+
+import (
+	"time"
+
+	"github.com/packetd/packetd/common"
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/protocol"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+func init() {
+	protocol.Register(socket.L7ProtoUDPRPC, NewConnPool)
+	protocol.Describe(socket.L7ProtoUDPRPC, protocol.Capability{
+		Options: []string{protocol.OptTransactionTimeout, OptTransactionIDOffset, OptTransactionIDLength},
+	})
+}
+
+const (
+	maxRecordSize = 64
+
+	// defaultTimeout 自定义协议的重试间隔未知 与 DNS 保持一致
+	defaultTimeout = 5 * time.Second
+)
+
+// extractTransaction 请求与响应在相同位置携带的事务 ID
+func extractTransaction(o *role.Object) (string, time.Time, bool) {
+	switch obj := o.Obj.(type) {
+	case *Request:
+		return obj.TransactionID, obj.Time, true
+	case *Response:
+		return obj.TransactionID, obj.Time, true
+	}
+	return "", time.Time{}, false
+}
+
+// NewConnPool 创建自定义 UDP RPC 协议连接池
+func NewConnPool(opts common.Options) protocol.ConnPool {
+	return protocol.NewL7UDPTransactionConnPool(
+		opts,
+		protocol.UDPTransaction{
+			Size:    maxRecordSize,
+			Timeout: defaultTimeout,
+			Extract: extractTransaction,
+		},
+		func(pair *role.Pair) socket.RoundTrip {
+			return &RoundTrip{
+				request:  pair.Request.Obj.(*Request),
+				response: pair.Response.Obj.(*Response),
+			}
+		},
+		func(st socket.Tuple, serverPort socket.Port) protocol.Decoder {
+			return NewDecoder(st, serverPort, opts)
+		},
+	)
+}
+
+// Request 自定义 UDP RPC 请求
+type Request struct {
+	Host          string
+	Port          uint16
+	Proto         string
+	Size          int
+	Time          time.Time
+	TransactionID string
+}
+
+// Response 自定义 UDP RPC 响应
+type Response struct {
+	Host          string
+	Port          uint16
+	Proto         string
+	Size          int
+	Time          time.Time
+	TransactionID string
+}
+
+var _ socket.RoundTrip = (*RoundTrip)(nil)
+
+// RoundTrip 自定义 UDP RPC 单次请求来回
+//
+// 实现了 socket.RoundTrip 接口
+type RoundTrip struct {
+	request  *Request
+	response *Response
+}
+
+func (rt RoundTrip) Proto() socket.L7Proto {
+	return socket.L7ProtoUDPRPC
+}
+
+func (rt RoundTrip) Request() any {
+	return rt.request
+}
+
+func (rt RoundTrip) Response() any {
+	return rt.response
+}
+
+func (rt RoundTrip) Duration() time.Duration {
+	return rt.response.Time.Sub(rt.request.Time)
+}
+
+func (rt RoundTrip) Validate() bool {
+	return rt.response.Time.After(rt.request.Time)
+}
@@ -15,6 +15,7 @@ package role
 
 import (
 	"container/list"
+	"time"
 )
 
 // Role 代表着一个网络来回中通信双方的角色
@@ -26,6 +27,11 @@ type Role string
 const (
 	Request  = "Request"
 	Response = "Response"
+
+	// OneWay 单向事件 协议语义上不存在响应的请求
+	// 如 AMQP 未开启 Confirm 模式的 Basic.Publish 以及 Kafka acks=0 的 Produce
+	// 此类对象无需经过 Matcher 配对 直接生成只有 Request 的 RoundTrip
+	OneWay = "OneWay"
 )
 
 // Object 代表通信一方归档的对象
@@ -52,7 +58,17 @@ func NewResponseObject(obj any) *Object {
 	}
 }
 
+// NewOneWayObject 创建一个单向事件对象 Obj 为协议的 *Request
+func NewOneWayObject(obj any) *Object {
+	return &Object{
+		Role: OneWay,
+		Obj:  obj,
+	}
+}
+
 // Pair 代表一个完整的网络请求中的 *Request 和 *Response 组合
+//
+// 单向事件的 Response 为 nil
 type Pair struct {
 	Request  *Object
 	Response *Object
@@ -76,6 +92,14 @@ func NewSingleMatcher() Matcher {
 	return &SingleMatcher{}
 }
 
+// Pending 返回尚未完成配对的请求数量
+func (m *SingleMatcher) Pending() int {
+	if m.o != nil && m.o.Role == Request {
+		return 1
+	}
+	return 0
+}
+
 func (m *SingleMatcher) Match(o *Object) *Pair {
 	if m.o == nil {
 		if o.Role == Response {
@@ -119,6 +143,11 @@ func NewListMatcher(size int, matchFunc func(req, rsp *Object) bool) Matcher {
 	}
 }
 
+// Pending 返回尚未完成配对的请求数量
+func (m *ListMatcher) Pending() int {
+	return m.l.Len()
+}
+
 func (m *ListMatcher) Match(o *Object) *Pair {
 	if o.Role == Request {
 		if m.l.Len() >= m.size {
@@ -201,3 +230,100 @@ func (m *FuzzyMatcher) Match(o *Object) *Pair {
 
 	return nil
 }
+
+// TransactionFunc 提取 *Object 的事务 ID 以及归档时间
+//
+// ok 为 false 表示该对象不参与配对
+type TransactionFunc func(o *Object) (id string, t time.Time, ok bool)
+
+type transaction struct {
+	id string
+	t  time.Time
+	o  *Object
+}
+
+// TransactionMatcher 事务匹配器
+//
+// 适用于 UDP 之类无流语义的协议 同一个四元组上请求与响应依靠报文中的事务 ID 配对（如 DNS ID、NTP Origin Timestamp）
+// 请求在 timeout 内未收到响应视为超时丢弃 超时以数据包时间为准 回放离线数据包时同样生效
+// 同一事务 ID 的重复请求（重传）保留最新一次 与 TCP 协议中未响应的请求被新请求覆盖的语义一致
+type TransactionMatcher struct {
+	l       *list.List
+	pending map[string]*list.Element
+	size    int // 超限驱逐
+	timeout time.Duration
+	extract TransactionFunc
+	expired int
+}
+
+func NewTransactionMatcher(size int, timeout time.Duration, extract TransactionFunc) Matcher {
+	return &TransactionMatcher{
+		l:       list.New(),
+		pending: make(map[string]*list.Element),
+		size:    size,
+		timeout: timeout,
+		extract: extract,
+	}
+}
+
+// Pending 返回尚未完成配对的请求数量
+func (m *TransactionMatcher) Pending() int {
+	return m.l.Len()
+}
+
+// Expired 返回因超时被丢弃的请求数量
+//
+// 读取即重置
+func (m *TransactionMatcher) Expired() int {
+	n := m.expired
+	m.expired = 0
+	return n
+}
+
+func (m *TransactionMatcher) remove(e *list.Element) {
+	delete(m.pending, e.Value.(*transaction).id)
+	m.l.Remove(e)
+}
+
+// expire 淘汰 t 时刻已经超时的请求 list 按照到达顺序排列 仅需从头部检查
+func (m *TransactionMatcher) expire(t time.Time) {
+	if m.timeout <= 0 {
+		return
+	}
+	for e := m.l.Front(); e != nil; e = m.l.Front() {
+		if t.Sub(e.Value.(*transaction).t) <= m.timeout {
+			return
+		}
+		m.remove(e)
+		m.expired++
+	}
+}
+
+func (m *TransactionMatcher) Match(o *Object) *Pair {
+	id, t, ok := m.extract(o)
+	if !ok {
+		return nil
+	}
+	m.expire(t)
+
+	if o.Role == Request {
+		if e, ok := m.pending[id]; ok {
+			m.remove(e)
+		}
+		if m.l.Len() >= m.size {
+			m.remove(m.l.Front())
+		}
+		m.pending[id] = m.l.PushBack(&transaction{id: id, t: t, o: o})
+		return nil
+	}
+
+	e, ok := m.pending[id]
+	if !ok {
+		return nil
+	}
+	m.remove(e)
+	return &Pair{
+		Request:  e.Value.(*transaction).o,
+		Response: o,
+	}
+}
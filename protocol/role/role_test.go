@@ -14,7 +14,9 @@
 package role
 
 import (
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -243,3 +245,92 @@ func TestFuzzyMatcher(t *testing.T) {
 		})
 	}
 }
+
+type txObject struct {
+	id int
+	ms int
+}
+
+func TestTransactionMatcher(t *testing.T) {
+	t0 := time.Unix(0, 0)
+	extract := func(o *Object) (string, time.Time, bool) {
+		obj := o.Obj.(txObject)
+		return strconv.Itoa(obj.id), t0.Add(time.Duration(obj.ms) * time.Millisecond), true
+	}
+
+	tests := []struct {
+		name    string
+		objs    []*Object
+		want    int
+		expired int
+	}{
+		{
+			name: "out of order",
+			objs: []*Object{
+				NewRequestObject(txObject{id: 1}),
+				NewRequestObject(txObject{id: 2}),
+				NewResponseObject(txObject{id: 2, ms: 10}),
+				NewResponseObject(txObject{id: 1, ms: 20}),
+			},
+			want: 2,
+		},
+		{
+			name: "unknown response",
+			objs: []*Object{
+				NewResponseObject(txObject{id: 1}),
+				NewRequestObject(txObject{id: 2}),
+				NewResponseObject(txObject{id: 3}),
+			},
+			want: 0,
+		},
+		{
+			name: "retransmission",
+			objs: []*Object{
+				NewRequestObject(txObject{id: 1}),
+				NewRequestObject(txObject{id: 1, ms: 50}),
+				NewResponseObject(txObject{id: 1, ms: 60}),
+				NewResponseObject(txObject{id: 1, ms: 70}),
+			},
+			want: 1,
+		},
+		{
+			name: "timeout",
+			objs: []*Object{
+				NewRequestObject(txObject{id: 1}),
+				NewRequestObject(txObject{id: 2, ms: 80}),
+				NewResponseObject(txObject{id: 1, ms: 150}),
+				NewResponseObject(txObject{id: 2, ms: 160}),
+			},
+			want:    1,
+			expired: 1,
+		},
+		{
+			name: "evicted",
+			objs: []*Object{
+				NewRequestObject(txObject{id: 1}),
+				NewRequestObject(txObject{id: 2}),
+				NewRequestObject(txObject{id: 3}),
+				NewRequestObject(txObject{id: 4}), // 触发淘汰
+				NewResponseObject(txObject{id: 1}),
+				NewResponseObject(txObject{id: 4}),
+			},
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher := NewTransactionMatcher(3, 100*time.Millisecond, extract).(*TransactionMatcher)
+
+			var count int
+			for _, obj := range tt.objs {
+				if pair := matcher.Match(obj); pair != nil {
+					assert.Equal(t, pair.Request.Obj.(txObject).id, pair.Response.Obj.(txObject).id)
+					count++
+				}
+			}
+			assert.Equal(t, tt.want, count)
+			assert.Equal(t, tt.expired, matcher.Expired())
+		})
+	}
+}
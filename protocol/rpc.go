@@ -0,0 +1,139 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	RPCSystemTwirp   = "twirp"
+	RPCSystemConnect = "connect_rpc"
+
+	// RPCOutcomeOK 调用成功 失败时 Outcome 为框架定义的错误码 如 not_found / unavailable
+	RPCOutcomeOK      = "ok"
+	RPCOutcomeUnknown = "unknown"
+)
+
+// RPC 基于 HTTP 的 RPC 框架调用 目前识别 Twirp 以及 Connect
+//
+// 两者均使用 POST /<package.Service>/<Method> 的路由约定 请求体为 protobuf 或者 json
+// 与 gRPC 不同 无需 HTTP/2 也没有 trailer 错误码记录在响应体的 JSON 中
+//
+// 详见 https://twitchtv.github.io/twirp/docs/spec_v7.html 以及 https://connectrpc.com/docs/protocol
+type RPC struct {
+	System  string
+	Service string
+	Method  string
+	Outcome string
+
+	// JSON-RPC 调用的请求 id（原始 JSON 表示）以及错误码 批量调用时 Batch 为请求数量
+	RequestID    string `json:",omitempty"`
+	Batch        int    `json:",omitempty"`
+	ErrorCode    int    `json:",omitempty"`
+	ErrorMessage string `json:",omitempty"`
+}
+
+// DetectRPC 根据请求的路由以及 Header 识别 Twirp / Connect 调用 无法识别时返回 nil
+//
+// Twirp 路由中包含 /twirp/ 前缀（可自定义前缀 此时无法识别）
+// Connect 需要携带 Connect-Protocol-Version 或者使用 application/proto / application/connect+* 类型
+// 以免将 POST /a.b/c 这类普通的 json 接口误判为 RPC
+func DetectRPC(method, path string, header http.Header) *RPC {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	ct, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+
+	service, rpcMethod, prefix, ok := splitRPCPath(path)
+	if !ok {
+		return nil
+	}
+
+	if strings.HasSuffix(prefix, "/twirp") && method == http.MethodPost {
+		if ct == "application/protobuf" || ct == "application/json" {
+			return &RPC{System: RPCSystemTwirp, Service: service, Method: rpcMethod}
+		}
+		return nil
+	}
+
+	// Connect 路由不存在前缀 service 需为 package.Service 格式
+	if prefix != "" || !strings.Contains(service, ".") {
+		return nil
+	}
+	switch {
+	case strings.HasPrefix(ct, "application/connect+"):
+	case header.Get("Connect-Protocol-Version") != "":
+	case ct == "application/proto" && method == http.MethodPost:
+	default:
+		return nil
+	}
+	return &RPC{System: RPCSystemConnect, Service: service, Method: rpcMethod}
+}
+
+// splitRPCPath 拆分路由最后两段作为 Service 与 Method 返回剩余的前缀
+func splitRPCPath(path string) (string, string, string, bool) {
+	i := strings.LastIndexByte(path, '/')
+	if i <= 0 || i == len(path)-1 {
+		return "", "", "", false
+	}
+	j := strings.LastIndexByte(path[:i], '/')
+	if j < 0 || j == i-1 {
+		return "", "", "", false
+	}
+	return path[j+1 : i], path[i+1:], path[:j], true
+}
+
+// Resolve 根据响应状态码以及响应体填充 Outcome
+//
+// 响应体仅在开启了 Body 捕获且为 json 时可用 否则按照框架约定的状态码映射推断错误码
+// Connect 流式调用的错误位于响应体末尾的 EndStreamResponse 中 HTTP 状态码始终为 200 无法识别
+func (r *RPC) Resolve(status int, body []byte) {
+	if status == http.StatusOK {
+		r.Outcome = RPCOutcomeOK
+		return
+	}
+
+	var e struct {
+		Code string `json:"code"`
+	}
+	if len(body) > 0 && json.Unmarshal(body, &e) == nil && e.Code != "" {
+		r.Outcome = e.Code
+		return
+	}
+	r.Outcome = rpcCodeFromStatus(r.System, status)
+}
+
+// rpcCodeFromStatus 响应体不是框架生成的错误时（如网关返回）按照状态码推断错误码
+func rpcCodeFromStatus(system string, status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "internal"
+	case http.StatusUnauthorized:
+		return "unauthenticated"
+	case http.StatusForbidden:
+		return "permission_denied"
+	case http.StatusNotFound:
+		if system == RPCSystemTwirp {
+			return "bad_route"
+		}
+		return "unimplemented"
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return "unavailable"
+	}
+	return RPCOutcomeUnknown
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectRPC(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header http.Header
+		want   *RPC
+	}{
+		{
+			name:   "twirp protobuf",
+			method: http.MethodPost,
+			path:   "/twirp/example.haberdasher.Haberdasher/MakeHat",
+			header: http.Header{"Content-Type": []string{"application/protobuf"}},
+			want:   &RPC{System: RPCSystemTwirp, Service: "example.haberdasher.Haberdasher", Method: "MakeHat"},
+		},
+		{
+			name:   "twirp with custom prefix",
+			method: http.MethodPost,
+			path:   "/api/twirp/pkg.Svc/Do?x=1",
+			header: http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+			want:   &RPC{System: RPCSystemTwirp, Service: "pkg.Svc", Method: "Do"},
+		},
+		{
+			name:   "twirp GET",
+			method: http.MethodGet,
+			path:   "/twirp/pkg.Svc/Do",
+			header: http.Header{"Content-Type": []string{"application/json"}},
+		},
+		{
+			name:   "connect unary json",
+			method: http.MethodPost,
+			path:   "/acme.user.v1.UserService/GetUser",
+			header: http.Header{"Content-Type": []string{"application/json"}, "Connect-Protocol-Version": []string{"1"}},
+			want:   &RPC{System: RPCSystemConnect, Service: "acme.user.v1.UserService", Method: "GetUser"},
+		},
+		{
+			name:   "connect unary proto",
+			method: http.MethodPost,
+			path:   "/acme.user.v1.UserService/GetUser",
+			header: http.Header{"Content-Type": []string{"application/proto"}},
+			want:   &RPC{System: RPCSystemConnect, Service: "acme.user.v1.UserService", Method: "GetUser"},
+		},
+		{
+			name:   "connect streaming",
+			method: http.MethodPost,
+			path:   "/acme.user.v1.UserService/Watch",
+			header: http.Header{"Content-Type": []string{"application/connect+proto"}},
+			want:   &RPC{System: RPCSystemConnect, Service: "acme.user.v1.UserService", Method: "Watch"},
+		},
+		{
+			name:   "plain json api",
+			method: http.MethodPost,
+			path:   "/a.b/c",
+			header: http.Header{"Content-Type": []string{"application/json"}},
+		},
+		{
+			name:   "service without package",
+			method: http.MethodPost,
+			path:   "/UserService/GetUser",
+			header: http.Header{"Content-Type": []string{"application/proto"}},
+		},
+		{
+			name:   "short path",
+			method: http.MethodPost,
+			path:   "/twirp/",
+			header: http.Header{"Content-Type": []string{"application/protobuf"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DetectRPC(tt.method, tt.path, tt.header))
+		})
+	}
+}
+
+func TestRPCResolve(t *testing.T) {
+	tests := []struct {
+		name   string
+		system string
+		status int
+		body   []byte
+		want   string
+	}{
+		{name: "ok", system: RPCSystemTwirp, status: http.StatusOK, want: RPCOutcomeOK},
+		{name: "json code", system: RPCSystemConnect, status: http.StatusNotFound, body: []byte(`{"code":"not_found","message":"no user"}`), want: "not_found"},
+		{name: "twirp 404", system: RPCSystemTwirp, status: http.StatusNotFound, want: "bad_route"},
+		{name: "connect 404", system: RPCSystemConnect, status: http.StatusNotFound, want: "unimplemented"},
+		{name: "gateway", system: RPCSystemConnect, status: http.StatusBadGateway, body: []byte("<html>"), want: "unavailable"},
+		{name: "unknown", system: RPCSystemTwirp, status: http.StatusTeapot, want: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &RPC{System: tt.system}
+			r.Resolve(tt.status, tt.body)
+			assert.Equal(t, tt.want, r.Outcome)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/packetd/packetd/internal/tracekit"
+)
+
+// SQLComment 语句中携带的 sqlcommenter 注释以及优化器 hint
+//
+// sqlcommenter 格式为追加在语句末尾的 /*key='value',...*/ key 与 value 均经过 URL 编码
+// 应用侧的 ORM 插件会写入 traceparent / route / controller 等字段 据此可将数据库调用关联至应用的 Trace
+// 语句被截断时末尾的注释同样会丢失
+//
+// 详见 https://google.github.io/sqlcommenter/spec/
+type SQLComment struct {
+	TraceID string            `json:",omitempty"`
+	SpanID  string            `json:",omitempty"`
+	Route   string            `json:",omitempty"` // 应用路由 未携带 route 时取 action
+	Tags    map[string]string `json:",omitempty"`
+	Hints   []string          `json:",omitempty"` // /*+ ... */ 优化器 hint
+}
+
+// TraceContext 返回注释中携带的 Trace 上下文
+func (c *SQLComment) TraceContext() (tracekit.TraceContext, bool) {
+	if c == nil {
+		return tracekit.TraceContext{}, false
+	}
+	return tracekit.TraceContextFromTraceparent(c.Tags["traceparent"])
+}
+
+// ParseSQLComment 解析语句中的注释 不存在 sqlcommenter 注释以及 hint 时返回 nil
+func ParseSQLComment(statement string) *SQLComment {
+	if !strings.Contains(statement, "/*") {
+		return nil
+	}
+
+	var comment SQLComment
+	comment.Hints = parseSQLHints(statement)
+
+	if tags := parseSQLCommenter(statement); len(tags) > 0 {
+		comment.Tags = tags
+		comment.Route = tags["route"]
+		if comment.Route == "" {
+			comment.Route = tags["action"]
+		}
+		if tc, ok := comment.TraceContext(); ok {
+			comment.TraceID = tc.TraceID.String()
+			comment.SpanID = tc.SpanID.String()
+		}
+	}
+
+	if len(comment.Hints) == 0 && len(comment.Tags) == 0 {
+		return nil
+	}
+	return &comment
+}
+
+// parseSQLCommenter 解析语句末尾的注释 任一片段不是 key=value 形式时视为普通注释
+func parseSQLCommenter(statement string) map[string]string {
+	s := strings.TrimRight(statement, " \t\r\n;")
+	if !strings.HasSuffix(s, "*/") {
+		return nil
+	}
+	start := strings.LastIndex(s, "/*")
+	if start < 0 {
+		return nil
+	}
+	body := strings.TrimSpace(s[start+2 : len(s)-2])
+	if body == "" || body[0] == '+' || body[0] == '!' {
+		return nil
+	}
+
+	tags := make(map[string]string)
+	for _, kv := range strings.Split(body, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if !ok || k == "" {
+			return nil
+		}
+
+		key, err := url.PathUnescape(k)
+		if err != nil {
+			return nil
+		}
+		if len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'' {
+			v = strings.ReplaceAll(v[1:len(v)-1], `\'`, "'")
+		}
+		val, err := url.PathUnescape(v)
+		if err != nil {
+			return nil
+		}
+		tags[key] = val
+	}
+	return tags
+}
+
+// parseSQLHints 提取 /*+ ... */ 形式的优化器 hint MySQL 与 pg_hint_plan 均使用该格式
+func parseSQLHints(statement string) []string {
+	var hints []string
+	for s := statement; ; {
+		start := strings.Index(s, "/*+")
+		if start < 0 {
+			return hints
+		}
+		end := strings.Index(s[start+3:], "*/")
+		if end < 0 {
+			return hints
+		}
+		if hint := strings.TrimSpace(s[start+3 : start+3+end]); hint != "" {
+			hints = append(hints, hint)
+		}
+		s = s[start+3+end+2:]
+	}
+}
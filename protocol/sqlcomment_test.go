@@ -0,0 +1,98 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSQLComment(t *testing.T) {
+	const traceparent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+	tests := []struct {
+		name      string
+		statement string
+		want      *SQLComment
+	}{
+		{
+			name:      "NoComment",
+			statement: "SELECT 1",
+		},
+		{
+			name:      "PlainComment",
+			statement: "SELECT 1 /* nightly report */",
+		},
+		{
+			name:      "Quoted",
+			statement: "SELECT * FROM orders WHERE id = 1 /*action='show',controller='orders',framework='rails%3A7.1',traceparent='" + traceparent + "'*/;",
+			want: &SQLComment{
+				TraceID: "0af7651916cd43dd8448eb211c80319c",
+				SpanID:  "b7ad6b7169203331",
+				Route:   "show",
+				Tags: map[string]string{
+					"action":      "show",
+					"controller":  "orders",
+					"framework":   "rails:7.1",
+					"traceparent": traceparent,
+				},
+			},
+		},
+		{
+			name:      "Unquoted",
+			statement: "UPDATE t SET a = 1 /*traceparent=" + traceparent + ",route=/api/items*/",
+			want: &SQLComment{
+				TraceID: "0af7651916cd43dd8448eb211c80319c",
+				SpanID:  "b7ad6b7169203331",
+				Route:   "/api/items",
+				Tags: map[string]string{
+					"traceparent": traceparent,
+					"route":       "/api/items",
+				},
+			},
+		},
+		{
+			name:      "EscapedQuote",
+			statement: `SELECT 1 /*application='it\'s'*/`,
+			want: &SQLComment{
+				Tags: map[string]string{"application": "it's"},
+			},
+		},
+		{
+			name:      "InvalidTraceparent",
+			statement: "SELECT 1 /*traceparent='00-zz-yy-01'*/",
+			want: &SQLComment{
+				Tags: map[string]string{"traceparent": "00-zz-yy-01"},
+			},
+		},
+		{
+			name:      "Hints",
+			statement: "SELECT /*+ INDEX(t idx_a) */ /*+ MAX_EXECUTION_TIME(1000) */ * FROM t",
+			want: &SQLComment{
+				Hints: []string{"INDEX(t idx_a)", "MAX_EXECUTION_TIME(1000)"},
+			},
+		},
+		{
+			name:      "Truncated",
+			statement: "SELECT 1 /*traceparent='00-0af7651916cd43dd",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseSQLComment(tt.statement))
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/packetd/packetd/internal/tracekit"
+)
+
+// TraceContext 请求携带的 W3C Trace Context 即 traceparent / tracestate Header（gRPC 中为同名 metadata）
+//
+// 据此导出的 Span 沿用上游的 TraceID 并挂载到上游 Span 之下 从而与应用侧上报的链路关联
+//
+// 详见 https://www.w3.org/TR/trace-context/
+type TraceContext struct {
+	TraceID string
+	SpanID  string
+	State   string `json:",omitempty"`
+}
+
+// ParseTraceContext 解析 Header 中的 Trace 上下文 未携带或者 traceparent 格式非法时返回 nil
+func ParseTraceContext(h http.Header) *TraceContext {
+	tc, ok := tracekit.TraceIDFromHTTPHeader(h)
+	if !ok {
+		return nil
+	}
+	return &TraceContext{
+		TraceID: tc.TraceID.String(),
+		SpanID:  tc.SpanID.String(),
+		State:   tc.State,
+	}
+}
+
+// Context 还原为 tracekit.TraceContext
+func (c *TraceContext) Context() (tracekit.TraceContext, bool) {
+	if c == nil {
+		return tracekit.TraceContext{}, false
+	}
+	traceID, err := trace.TraceIDFromHex(c.TraceID)
+	if err != nil {
+		return tracekit.TraceContext{}, false
+	}
+	spanID, err := trace.SpanIDFromHex(c.SpanID)
+	if err != nil {
+		return tracekit.TraceContext{}, false
+	}
+	return tracekit.TraceContext{
+		TraceID: pcommon.TraceID(traceID),
+		SpanID:  pcommon.SpanID(spanID),
+		State:   c.State,
+	}, true
+}
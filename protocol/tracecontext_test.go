@@ -0,0 +1,72 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceContext(t *testing.T) {
+	const traceparent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   *TraceContext
+	}{
+		{
+			name:   "Missing",
+			header: http.Header{"User-Agent": {"curl/8.0"}},
+		},
+		{
+			name:   "Invalid",
+			header: http.Header{"Traceparent": {"00-xyz-b7ad6b7169203331-01"}},
+		},
+		{
+			name:   "Traceparent",
+			header: http.Header{"Traceparent": {traceparent}},
+			want: &TraceContext{
+				TraceID: "0af7651916cd43dd8448eb211c80319c",
+				SpanID:  "b7ad6b7169203331",
+			},
+		},
+		{
+			name:   "Tracestate",
+			header: http.Header{"Traceparent": {traceparent}, "Tracestate": {"congo=t61rcWkgMzE"}},
+			want: &TraceContext{
+				TraceID: "0af7651916cd43dd8448eb211c80319c",
+				SpanID:  "b7ad6b7169203331",
+				State:   "congo=t61rcWkgMzE",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseTraceContext(tt.header)
+			assert.Equal(t, tt.want, got)
+
+			tc, ok := got.Context()
+			assert.Equal(t, tt.want != nil, ok)
+			if ok {
+				assert.Equal(t, tt.want.TraceID, tc.TraceID.String())
+				assert.Equal(t, tt.want.SpanID, tc.SpanID.String())
+				assert.Equal(t, tt.want.State, tc.State)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"time"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+// TruncatedMessage 截断抓包时的请求或响应 仅记录字节数
+//
+// Size 为链路上的实际字节数 Time 为最后一个数据包的到达时间
+type TruncatedMessage struct {
+	Host string
+	Port uint16
+	Size int
+	Time time.Time
+}
+
+var _ socket.RoundTrip = (*TruncatedRoundTrip)(nil)
+
+// TruncatedRoundTrip 截断抓包时按照数据流方向切换估算的单次请求来回
+//
+// 实现了 socket.RoundTrip 以及 socket.TruncatedCaptureRoundTrip 接口
+type TruncatedRoundTrip struct {
+	proto    socket.L7Proto
+	request  *TruncatedMessage
+	response *TruncatedMessage
+}
+
+func (rt *TruncatedRoundTrip) Proto() socket.L7Proto {
+	return rt.proto
+}
+
+func (rt *TruncatedRoundTrip) Request() any {
+	return rt.request
+}
+
+func (rt *TruncatedRoundTrip) Response() any {
+	return rt.response
+}
+
+func (rt *TruncatedRoundTrip) Duration() time.Duration {
+	return rt.response.Time.Sub(rt.request.Time)
+}
+
+func (rt *TruncatedRoundTrip) Validate() bool {
+	return !rt.response.Time.Before(rt.request.Time)
+}
+
+func (rt *TruncatedRoundTrip) TruncatedCapture() bool {
+	return true
+}
+
+// truncatedTracker 截断抓包模式下的请求来回估算
+//
+// Payload 不完整时无法解析协议边界 只能假定客户端与服务端交替发送数据
+// 客户端在收到响应后再次发送数据即视为上一次请求来回结束 Pipeline 等并发请求会被合并统计
+type truncatedTracker struct {
+	proto      socket.L7Proto
+	serverPort socket.Port
+	req, rsp   *TruncatedMessage
+}
+
+func newTruncatedTracker(proto socket.L7Proto, serverPort socket.Port) *truncatedTracker {
+	return &truncatedTracker{
+		proto:      proto,
+		serverPort: serverPort,
+	}
+}
+
+// observe 记录 st 方向 size 字节的数据 返回已经结束的请求来回
+func (t *truncatedTracker) observe(st socket.Tuple, size int, at time.Time) socket.RoundTrip {
+	if size == 0 {
+		return nil
+	}
+
+	if st.DstPort == t.serverPort {
+		rt := t.flush()
+		if t.req == nil {
+			t.req = &TruncatedMessage{Host: st.SrcIP.String(), Port: uint16(st.SrcPort)}
+		}
+		t.req.Size += size
+		t.req.Time = at
+		return rt
+	}
+
+	// 服务端主动推送的数据无法关联到请求
+	if t.req == nil {
+		return nil
+	}
+	if t.rsp == nil {
+		t.rsp = &TruncatedMessage{Host: st.SrcIP.String(), Port: uint16(st.SrcPort)}
+	}
+	t.rsp.Size += size
+	t.rsp.Time = at
+	return nil
+}
+
+// flush 归档已经收到响应的请求来回 链接关闭时同样需要调用
+func (t *truncatedTracker) flush() socket.RoundTrip {
+	if t.req == nil || t.rsp == nil {
+		return nil
+	}
+
+	rt := &TruncatedRoundTrip{
+		proto:    t.proto,
+		request:  t.req,
+		response: t.rsp,
+	}
+	t.req, t.rsp = nil, nil
+	return rt
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/connstream"
+	"github.com/packetd/packetd/internal/zerocopy"
+	"github.com/packetd/packetd/protocol/role"
+)
+
+func TestTruncatedTracker(t *testing.T) {
+	client := socket.Tuple{SrcIP: socket.ToIPV4([]byte{10, 0, 0, 1}), SrcPort: 50001, DstIP: socket.ToIPV4([]byte{10, 0, 0, 2}), DstPort: 6379}
+	server := client.Mirror()
+	start := time.Unix(1700000000, 0)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	tracker := newTruncatedTracker(socket.L7ProtoRedis, 6379)
+	assert.Nil(t, tracker.observe(server, 100, at(0))) // 无请求时的推送数据被忽略
+	assert.Nil(t, tracker.observe(client, 1500, at(1)))
+	assert.Nil(t, tracker.observe(client, 500, at(2)))
+	assert.Nil(t, tracker.observe(server, 3000, at(5)))
+	assert.Nil(t, tracker.observe(server, 1000, at(8)))
+	assert.Nil(t, tracker.observe(client, 0, at(9)))
+
+	rt := tracker.observe(client, 200, at(10))
+	require.NotNil(t, rt)
+	assert.True(t, socket.IsTruncatedCapture(rt))
+	assert.Equal(t, socket.L7ProtoRedis, rt.Proto())
+	assert.Equal(t, 6*time.Millisecond, rt.Duration())
+
+	req := rt.Request().(*TruncatedMessage)
+	rsp := rt.Response().(*TruncatedMessage)
+	assert.Equal(t, 2000, req.Size)
+	assert.Equal(t, "10.0.0.1", req.Host)
+	assert.Equal(t, uint16(50001), req.Port)
+	assert.Equal(t, 4000, rsp.Size)
+	assert.Equal(t, "10.0.0.2", rsp.Host)
+	assert.Equal(t, uint16(6379), rsp.Port)
+
+	assert.Nil(t, tracker.flush()) // 请求尚未收到响应
+	assert.Nil(t, tracker.observe(server, 10, at(12)))
+	rt = tracker.flush()
+	require.NotNil(t, rt)
+	assert.Equal(t, 200, rt.Request().(*TruncatedMessage).Size)
+	assert.Equal(t, 10, rt.Response().(*TruncatedMessage).Size)
+}
+
+type panicDecoder struct{}
+
+func (panicDecoder) Decode(zerocopy.Reader, time.Time) ([]*role.Object, error) {
+	panic("decoder must not be called")
+}
+
+func (panicDecoder) Free() {}
+
+func TestL7TCPConnTruncated(t *testing.T) {
+	client := socket.Tuple{SrcIP: socket.ToIPV4([]byte{10, 0, 0, 1}), SrcPort: 50001, DstIP: socket.ToIPV4([]byte{10, 0, 0, 2}), DstPort: 6379}
+	server := client.Mirror()
+	start := time.Unix(1700000000, 0)
+
+	conn := NewL7Conn(
+		connstream.NewConn(client, connstream.NewTCPStream),
+		6379,
+		nil,
+		nil,
+		func(socket.Tuple, socket.Port) Decoder { return panicDecoder{} },
+	)
+	conn.l7Proto = socket.L7ProtoRedis
+
+	ch := make(chan socket.RoundTrip, 4)
+	segs := []*socket.TCPSegment{
+		{Tuple: client, Time: start, Seq: 1, Payload: make([]byte, 64), Length: 2000},
+		{Tuple: server, Time: start.Add(time.Millisecond), Seq: 1, Payload: []byte("+OK\r\n")},
+		{Tuple: client, Time: start.Add(2 * time.Millisecond), Seq: 2001, Payload: []byte("*1\r\n$4\r\nPING\r\n")},
+		{Tuple: server, Time: start.Add(3 * time.Millisecond), Seq: 6, Payload: []byte("+PONG\r\n"), FIN: true},
+	}
+	for _, seg := range segs {
+		assert.NoError(t, conn.OnL4Packet(seg, ch))
+	}
+	close(ch)
+
+	var sizes [][2]int
+	for rt := range ch {
+		assert.True(t, socket.IsTruncatedCapture(rt))
+		sizes = append(sizes, [2]int{rt.Request().(*TruncatedMessage).Size, rt.Response().(*TruncatedMessage).Size})
+	}
+	assert.Equal(t, [][2]int{{2000, 5}, {14, 7}}, sizes)
+}
@@ -14,6 +14,8 @@
 package sniffer
 
 import (
+	"net"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -29,7 +31,9 @@ type Config struct {
 	// Ifaces 指定监听的网卡 与 tcpdump 的 -i 参数一致
 	Ifaces string `config:"ifaces"`
 
-	// Engine 指定监听引擎 目前仅支持 pcap
+	// Engine 指定监听引擎 可选值为
+	// - pcap: 本地网卡抓包或者读取 pcap 文件
+	// - sflow: 接收交换机等设备发送的 sFlow 采样数据
 	Engine string `config:"engine"`
 
 	// IPVersion 指定监听 ipv4/ipv6 可选值为
@@ -51,6 +55,58 @@ type Config struct {
 	// 实际代表着生成的 buffer 区域空间为 (1/2 * blockNum) MB 即默认 bufferSize 为 8MB
 	// 该数值仅能设置为 16 的倍数 非法数值将重置为默认值
 	BlockNum int `config:"blockNum"`
+
+	// TimeSource 指定数据包时间来源 可选值为
+	// - packet: 内核抓取时间戳
+	// - hardware: 网卡硬件时间戳 仅非 Linux 平台且设备支持时生效 否则等同于 packet
+	// - system: 数据包被处理时的本地时钟
+	// 空值或其他非法值均代表 packet 读取文件时固定使用文件中的时间戳
+	TimeSource string `config:"timeSource"`
+
+	// CPUs 指定设备监听协程绑定的 CPU（仅 Linux 生效）可选值为
+	// - numa: 网卡所在 NUMA 节点的 CPU
+	// - numa:N: NUMA 节点 N 的 CPU
+	// - cpulist: 如 0-3,8
+	// 空值代表不绑定
+	CPUs string `config:"cpus"`
+
+	// SnapLen 单个数据包的最大抓取长度 单位为 bytes 0 代表抓取完整的数据包
+	// 调小后 Payload 被截断的链接不再解析应用层内容 仅上报请求响应的字节数以及耗时
+	SnapLen int `config:"snapLen"`
+
+	// Dedup 数据包去重配置 用于镜像端口场景
+	Dedup DedupConfig `config:"dedup"`
+
+	// SFlow sflow 引擎配置
+	SFlow SFlowConfig `config:"sflow"`
+}
+
+// SFlowConfig sFlow 采样数据接收配置
+type SFlowConfig struct {
+	// Listen UDP 监听地址
+	Listen string `config:"listen"`
+
+	// Agents 允许的 agent 地址列表 为空时接收所有 agent 的数据
+	Agents []string `config:"agents"`
+
+	// ReadBuffer socket 接收缓冲区大小 单位为 bytes
+	ReadBuffer int `config:"readBuffer"`
+}
+
+// minSnapLen 最小抓取长度 需容纳 Ethernet+VLAN+IPv6+TCP 的最大头部长度
+const minSnapLen = 128
+
+// CaptureLen 返回实际生效的抓取长度
+//
+// 未设置或者超过 IPv6 数据包上限时抓取完整数据包 过小时重置为 minSnapLen 避免丢失 Layer4 头部
+func (c *Config) CaptureLen() int {
+	switch {
+	case c.SnapLen <= 0 || c.SnapLen >= socket.MaxIPV6PacketSize:
+		return socket.MaxIPV6PacketSize
+	case c.SnapLen < minSnapLen:
+		return minSnapLen
+	}
+	return c.SnapLen
 }
 
 type IPVPicker string
@@ -156,3 +212,27 @@ func (ps Protocols) L7Ports() []socket.L7Ports {
 	}
 	return ports
 }
+
+// Match 判断数据包是否命中任一协议规则
+//
+// 用于无法下发 BPF 过滤规则的监听引擎 规则中的 host 仅支持 IP 地址 非 IP 地址的规则不生效
+func (ps Protocols) Match(pkt socket.L4Packet) bool {
+	st := pkt.SocketTuple()
+	for _, p := range ps.Rules {
+		l4, ok := socket.L7ProtoBased(socket.L7Proto(p.Protocol))
+		if !ok || l4 != pkt.Proto() {
+			continue
+		}
+		if !slices.Contains(p.Ports, uint16(st.SrcPort)) && !slices.Contains(p.Ports, uint16(st.DstPort)) {
+			continue
+		}
+		if p.Host != "" {
+			ip := net.ParseIP(p.Host)
+			if ip == nil || (!ip.Equal(st.SrcIP.NetIP()) && !ip.Equal(st.DstIP.NetIP())) {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
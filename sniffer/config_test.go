@@ -14,9 +14,12 @@
 package sniffer
 
 import (
+	"net"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common/socket"
 )
 
 func TestCompileBPFFilter(t *testing.T) {
@@ -95,3 +98,82 @@ func TestCompileBPFFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestProtocolsMatch(t *testing.T) {
+	ps := Protocols{
+		Rules: []ProtoRule{
+			{Protocol: "redis", Ports: []uint16{6379}},
+			{Protocol: "dns", Host: "10.0.0.53", Ports: []uint16{53}},
+			{Protocol: "http", Host: "example.com", Ports: []uint16{80}},
+		},
+	}
+	tuple := func(dst string, port socket.Port) socket.Tuple {
+		return socket.Tuple{
+			SrcIP:   socket.ToIPV4(net.ParseIP("10.0.0.1").To4()),
+			DstIP:   socket.ToIPV4(net.ParseIP(dst).To4()),
+			SrcPort: 50001,
+			DstPort: port,
+		}
+	}
+
+	tests := []struct {
+		name string
+		pkt  socket.L4Packet
+		want bool
+	}{
+		{
+			name: "Port matched",
+			pkt:  &socket.TCPSegment{Tuple: tuple("10.0.0.2", 6379)},
+			want: true,
+		},
+		{
+			name: "Mirrored port matched",
+			pkt:  &socket.TCPSegment{Tuple: tuple("10.0.0.2", 6379).Mirror()},
+			want: true,
+		},
+		{
+			name: "L4 protocol mismatched",
+			pkt:  &socket.UDPDatagram{Tuple: tuple("10.0.0.2", 6379)},
+			want: false,
+		},
+		{
+			name: "Host matched",
+			pkt:  &socket.UDPDatagram{Tuple: tuple("10.0.0.53", 53)},
+			want: true,
+		},
+		{
+			name: "Host mismatched",
+			pkt:  &socket.UDPDatagram{Tuple: tuple("10.0.0.54", 53)},
+			want: false,
+		},
+		{
+			name: "Hostname ignored",
+			pkt:  &socket.TCPSegment{Tuple: tuple("10.0.0.2", 80)},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ps.Match(tt.pkt))
+		})
+	}
+}
+
+func TestConfigCaptureLen(t *testing.T) {
+	tests := []struct {
+		snapLen int
+		want    int
+	}{
+		{snapLen: 0, want: socket.MaxIPV6PacketSize},
+		{snapLen: -1, want: socket.MaxIPV6PacketSize},
+		{snapLen: 100000, want: socket.MaxIPV6PacketSize},
+		{snapLen: 64, want: minSnapLen},
+		{snapLen: 256, want: 256},
+	}
+
+	for _, tt := range tests {
+		conf := Config{SnapLen: tt.snapLen}
+		assert.Equal(t, tt.want, conf.CaptureLen())
+	}
+}
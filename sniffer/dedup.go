@@ -0,0 +1,161 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sniffer
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+const (
+	// defaultDedupWindow 默认去重窗口 镜像端口复制的数据包通常在毫秒级内先后到达
+	defaultDedupWindow = 50 * time.Millisecond
+
+	// maxDedupEntries 单个窗口最多记录的数据包数量 超出后提前轮转
+	maxDedupEntries = 1 << 16
+
+	// dedupPayloadBytes 参与计算指纹的 payload 前缀长度
+	dedupPayloadBytes = 64
+)
+
+// DedupConfig 数据包去重配置
+type DedupConfig struct {
+	// Enabled 是否开启去重 适用于 SPAN/镜像端口同时复制出入方向数据包的场景
+	Enabled bool `config:"enabled"`
+
+	// Window 去重窗口 窗口期内指纹相同的数据包视为重复
+	Window time.Duration `config:"window"`
+}
+
+// Deduper 对端口镜像场景下重复出现的数据包进行去重
+//
+// 镜像端口可能同时复制 ingress 以及 egress 方向的数据包 导致同一个数据包被抓取两次
+// 数据包指纹由四元组 IPv4 ID TCP 序号以及 payload 前缀计算得出
+// 使用两个窗口交替轮转 保证窗口期内的指纹均可被命中 同时内存占用有上限
+type Deduper struct {
+	mut     sync.Mutex
+	window  time.Duration
+	rotated time.Time
+	curr    map[uint64]struct{}
+	prev    map[uint64]struct{}
+}
+
+// NewDeduper 创建并返回 Deduper 实例 未开启时返回 nil
+func NewDeduper(conf DedupConfig) *Deduper {
+	if !conf.Enabled {
+		return nil
+	}
+
+	window := conf.Window
+	if window <= 0 {
+		window = defaultDedupWindow
+	}
+	return &Deduper{
+		window: window,
+		curr:   make(map[uint64]struct{}),
+		prev:   make(map[uint64]struct{}),
+	}
+}
+
+// Duplicate 判断数据包是否在窗口期内已经出现过
+//
+// ipLyr 为数据包的 IP 层 用于提取 IPv4 ID
+func (d *Deduper) Duplicate(ipLyr gopacket.Layer, pkt socket.L4Packet) bool {
+	fp := fingerprint(ipLyr, pkt)
+	t := pkt.ArrivedTime()
+
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	if t.Sub(d.rotated) >= d.window || t.Before(d.rotated) || len(d.curr) >= maxDedupEntries {
+		d.prev, d.curr = d.curr, d.prev
+		clear(d.curr)
+		d.rotated = t
+	}
+
+	if _, ok := d.curr[fp]; ok {
+		return true
+	}
+	if _, ok := d.prev[fp]; ok {
+		return true
+	}
+	d.curr[fp] = struct{}{}
+	return false
+}
+
+// tcpFlags 将 TCP 标志位编码为单个字节
+//
+// 同一序号的纯 ACK 与 SYN/RST 等控制报文需要区分开
+func tcpFlags(seg *socket.TCPSegment) byte {
+	var flags byte
+	if seg.FIN {
+		flags |= 1 << 0
+	}
+	if seg.SYN {
+		flags |= 1 << 1
+	}
+	if seg.RST {
+		flags |= 1 << 2
+	}
+	if seg.ACK {
+		flags |= 1 << 4
+	}
+	return flags
+}
+
+// fingerprint 计算数据包指纹
+//
+// IPv6 没有 ID 字段 仅依赖 TCP 序号 确认号 标志位以及 payload 前缀区分
+func fingerprint(ipLyr gopacket.Layer, pkt socket.L4Packet) uint64 {
+	var buf [2*net.IPv6len + 32 + dedupPayloadBytes]byte
+	b := buf[:0]
+
+	st := pkt.SocketTuple()
+	b = append(b, st.SrcIP.IP[:]...)
+	b = append(b, st.DstIP.IP[:]...)
+	b = binary.BigEndian.AppendUint16(b, uint16(st.SrcPort))
+	b = binary.BigEndian.AppendUint16(b, uint16(st.DstPort))
+
+	if ip4, ok := ipLyr.(*layers.IPv4); ok {
+		b = binary.BigEndian.AppendUint16(b, ip4.Id)
+	}
+
+	var payload []byte
+	switch p := pkt.(type) {
+	case *socket.TCPSegment:
+		b = append(b, 't')
+		b = binary.BigEndian.AppendUint32(b, p.Seq)
+		b = binary.BigEndian.AppendUint32(b, p.Ack)
+		b = append(b, tcpFlags(p))
+		payload = p.Payload
+	case *socket.UDPDatagram:
+		b = append(b, 'u')
+		payload = p.Payload
+	}
+	b = binary.BigEndian.AppendUint32(b, uint32(len(payload)))
+
+	if len(payload) > dedupPayloadBytes {
+		payload = payload[:dedupPayloadBytes]
+	}
+	b = append(b, payload...)
+	return xxhash.Sum64(b)
+}
@@ -0,0 +1,70 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sniffer
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket/layers"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/packetd/packetd/common/socket"
+)
+
+func TestDeduper(t *testing.T) {
+	assert.Nil(t, NewDeduper(DedupConfig{}))
+
+	d := NewDeduper(DedupConfig{Enabled: true, Window: 10 * time.Millisecond})
+	t0 := time.Now()
+	st := socket.Tuple{
+		SrcIP:   socket.ToIPV4(net.ParseIP("10.0.0.1").To4()),
+		DstIP:   socket.ToIPV4(net.ParseIP("10.0.0.2").To4()),
+		SrcPort: 52000,
+		DstPort: 80,
+	}
+	newSegment := func(ts time.Time, seq uint32) *socket.TCPSegment {
+		return &socket.TCPSegment{Tuple: st, Time: ts, Seq: seq, Payload: []byte("GET / HTTP/1.1\r\n")}
+	}
+	ip := func(id uint16) *layers.IPv4 {
+		return &layers.IPv4{Id: id}
+	}
+
+	// ingress/egress 两份相同的数据包
+	assert.False(t, d.Duplicate(ip(1), newSegment(t0, 100)))
+	assert.True(t, d.Duplicate(ip(1), newSegment(t0.Add(time.Millisecond), 100)))
+
+	// 重传的数据包 IP ID 不同 交由 TCP 流处理
+	assert.False(t, d.Duplicate(ip(2), newSegment(t0.Add(2*time.Millisecond), 100)))
+
+	// 相邻窗口内仍可命中
+	assert.True(t, d.Duplicate(ip(1), newSegment(t0.Add(12*time.Millisecond), 100)))
+
+	// 超出窗口后不再视为重复
+	assert.False(t, d.Duplicate(ip(1), newSegment(t0.Add(40*time.Millisecond), 100)))
+
+	udp := &socket.UDPDatagram{Tuple: st, Time: t0.Add(41 * time.Millisecond), Payload: []byte{0x01}}
+	assert.False(t, d.Duplicate(&layers.IPv6{}, udp))
+	assert.True(t, d.Duplicate(&layers.IPv6{}, udp))
+
+	// IPv6 下序号相同的纯 ACK 通过确认号以及标志位区分
+	ack := func(ack uint32, rst bool) *socket.TCPSegment {
+		return &socket.TCPSegment{Tuple: st, Time: t0.Add(42 * time.Millisecond), Seq: 200, Ack: ack, ACK: true, RST: rst}
+	}
+	assert.False(t, d.Duplicate(&layers.IPv6{}, ack(1000, false)))
+	assert.False(t, d.Duplicate(&layers.IPv6{}, ack(2000, false)))
+	assert.False(t, d.Duplicate(&layers.IPv6{}, ack(2000, true)))
+	assert.True(t, d.Duplicate(&layers.IPv6{}, ack(2000, false)))
+}
@@ -17,8 +17,15 @@ import (
 	"net"
 	"time"
 
+	"github.com/gopacket/gopacket"
 	"github.com/gopacket/gopacket/pcap"
 	"github.com/pkg/errors"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/affinity"
+	"github.com/packetd/packetd/internal/clock"
+	"github.com/packetd/packetd/logger"
+	"github.com/packetd/packetd/sniffer"
 )
 
 const (
@@ -70,3 +77,87 @@ func makeFileHandle(path, bpfFilter string) (*pcap.Handle, error) {
 	}
 	return handle, nil
 }
+
+// hardwareTimestampSource 与主机时钟同步的硬件时间戳来源
+//
+// adapter_unsynced 为网卡自身时钟（PHC） 与退化时使用的主机时间戳不在同一时间基准 不予使用
+const hardwareTimestampSource = "adapter"
+
+// openLiveHandle 打开设备监听句柄
+//
+// timeSource 为 clock.SourceHardware 且设备支持时使用网卡硬件时间戳 不会修改网卡的时间戳配置
+func openLiveHandle(device string, snapLen int, promisc bool, timeSource clock.Source) (*pcap.Handle, error) {
+	ih, err := pcap.NewInactiveHandle(device)
+	if err != nil {
+		return nil, err
+	}
+	defer ih.CleanUp()
+
+	if err := ih.SetSnapLen(snapLen); err != nil {
+		return nil, err
+	}
+	if err := ih.SetPromisc(promisc); err != nil {
+		return nil, err
+	}
+	if err := ih.SetTimeout(pcap.BlockForever); err != nil {
+		return nil, err
+	}
+
+	if timeSource == clock.SourceHardware {
+		if src, ok := pickHardwareTimestamp(ih.SupportedTimestamps()); ok {
+			// 设置失败时退化为默认的主机时间戳
+			_ = ih.SetTimestampSource(src)
+		}
+	}
+	return ih.Activate()
+}
+
+func pickHardwareTimestamp(supported []pcap.TimestampSource) (pcap.TimestampSource, bool) {
+	for _, src := range supported {
+		if src.String() == hardwareTimestampSource {
+			return src, true
+		}
+	}
+	return 0, false
+}
+
+// newClock 根据句柄类型创建 clock.Clock 读取文件时固定使用文件中的时间戳
+func newClock(conf *sniffer.Config, replay bool) clock.Clock {
+	if replay {
+		return clock.NewReplay()
+	}
+	return clock.New(clock.Source(conf.TimeSource))
+}
+
+// emit 去重后回调 OnL4Packet
+func (ps *pcapSniffer) emit(ph *handler, ipLyr gopacket.Layer, pkt socket.L4Packet) {
+	if ps.dedup != nil && ps.dedup.Duplicate(ipLyr, pkt) {
+		ph.duplicates.Add(1)
+		return
+	}
+	if ps.onL4Packet != nil {
+		ps.onL4Packet(pkt)
+	}
+}
+
+// resolveCPUs 解析设备监听协程需要绑定的 CPU 解析失败时不绑定
+func resolveCPUs(conf *sniffer.Config, iface string) []int {
+	cpus, err := affinity.Resolve(conf.CPUs, iface)
+	if err != nil {
+		logger.Warnf("resolve iface (%s) cpus failed: %v", iface, err)
+		return nil
+	}
+	return cpus
+}
+
+// pinHandler 将当前监听协程绑定至 cpus
+func pinHandler(name string, cpus []int) {
+	if len(cpus) == 0 {
+		return
+	}
+	if err := affinity.Pin(cpus); err != nil {
+		logger.Warnf("pin pcap handle (%s) to cpus %v failed: %v", name, cpus, err)
+		return
+	}
+	logger.Infof("pin pcap handle (%s) to cpus %v", name, cpus)
+}
@@ -19,6 +19,7 @@ import (
 	"net"
 	"regexp"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gopacket/gopacket"
@@ -29,6 +30,7 @@ import (
 	"golang.org/x/net/bpf"
 
 	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/clock"
 	"github.com/packetd/packetd/logger"
 	"github.com/packetd/packetd/sniffer"
 )
@@ -41,6 +43,11 @@ type handler struct {
 	name   string
 	handle *afpacket.TPacket
 	pfile  *pcap.Handle
+	ngfile *ngFile
+	clock  clock.Clock
+	cpus   []int
+
+	duplicates atomic.Uint64
 }
 
 type pcapSniffer struct {
@@ -50,11 +57,13 @@ type pcapSniffer struct {
 	handlers   []*handler
 	wg         sync.WaitGroup
 	onL4Packet sniffer.OnL4Packet
+	dedup      *sniffer.Deduper
 }
 
 func New(conf *sniffer.Config) (sniffer.Sniffer, error) {
 	snif := &pcapSniffer{
-		conf: conf,
+		conf:  conf,
+		dedup: sniffer.NewDeduper(conf.Dedup),
 	}
 
 	snif.ctx, snif.cancel = context.WithCancel(context.Background())
@@ -92,6 +101,20 @@ func (ps *pcapSniffer) makeHandlers() error {
 		return err
 	}
 
+	if len(ps.conf.File) > 0 && isPcapngFile(ps.conf.File) {
+		nf, err := openNgFile(ps.conf.File, bpfFilter)
+		if err != nil {
+			return err
+		}
+		ps.handlers = append(ps.handlers, &handler{
+			name:   fmt.Sprintf("pcap.file: %s", ps.conf.File),
+			ngfile: nf,
+			clock:  newClock(ps.conf, true),
+		})
+		logger.Infof("sniffer add pcapng file (%s)", ps.conf.File)
+		return nil
+	}
+
 	if len(ps.conf.File) > 0 {
 		tp, err := makeFileHandle(ps.conf.File, bpfFilter)
 		if err != nil {
@@ -100,11 +123,17 @@ func (ps *pcapSniffer) makeHandlers() error {
 		ps.handlers = append(ps.handlers, &handler{
 			name:  fmt.Sprintf("pcap.file: %s", ps.conf.File),
 			pfile: tp,
+			clock: newClock(ps.conf, true),
 		})
 		logger.Infof("sniffer add pcap file (%s)", ps.conf.File)
 		return nil
 	}
 
+	// afpacket 不提供开启硬件时间戳的接口 使用内核抓取时间戳
+	if clock.Source(ps.conf.TimeSource) == clock.SourceHardware {
+		logger.Warnf("sniffer timeSource (%s) is not supported by afpacket, fallback to %s", clock.SourceHardware, clock.SourcePacket)
+	}
+
 	for _, iface := range ifaces {
 		tp, err := ps.getTpacket(iface.Name)
 		if err != nil {
@@ -112,14 +141,20 @@ func (ps *pcapSniffer) makeHandlers() error {
 			continue
 		}
 
-		if bpfFilter != "" {
-			if err = ps.setBPFFilter(tp, bpfFilter); err != nil {
+		// afpacket 依赖 BPF 程序的返回值截断数据包 设置了 snapLen 时即使没有过滤规则也需要下发
+		if bpfFilter != "" || ps.conf.CaptureLen() < socket.MaxIPV6PacketSize {
+			if err = ps.setBPFFilter(tp, bpfFilter, ps.conf.CaptureLen()); err != nil {
 				tp.Close()
 				return errors.Wrapf(err, "set bpf-filter (%s) failed", bpfFilter)
 			}
 		}
 
-		ps.handlers = append(ps.handlers, &handler{handle: tp, name: iface.Name})
+		ps.handlers = append(ps.handlers, &handler{
+			handle: tp,
+			name:   iface.Name,
+			clock:  newClock(ps.conf, false),
+			cpus:   resolveCPUs(ps.conf, iface.Name),
+		})
 		logger.Infof("sniffer add device (%s), address=%v", iface.Name, ifaceAddress(iface))
 	}
 
@@ -160,8 +195,8 @@ func (ps *pcapSniffer) getTpacket(device string) (*afpacket.TPacket, error) {
 	return afpacket.NewTPacket(afpacket.OptInterface(device), blockNumOpt, pollTimeout)
 }
 
-func (ps *pcapSniffer) setBPFFilter(tp *afpacket.TPacket, filter string) error {
-	pcapBPF, err := pcap.CompileBPFFilter(layers.LinkTypeEthernet, socket.MaxIPV6PacketSize, filter)
+func (ps *pcapSniffer) setBPFFilter(tp *afpacket.TPacket, filter string, snapLen int) error {
+	pcapBPF, err := pcap.CompileBPFFilter(layers.LinkTypeEthernet, snapLen, filter)
 	if err != nil {
 		return err
 	}
@@ -177,46 +212,20 @@ func (ps *pcapSniffer) setBPFFilter(tp *afpacket.TPacket, filter string) error {
 	return tp.SetBPF(bpfIns)
 }
 
-func (ps *pcapSniffer) parsePacket(pkt []byte, ts time.Time) {
-	payload, lyr, next, err := sniffer.DecodeIPLayer(pkt, sniffer.IPVPicker(ps.conf.IPVersion))
-	if err != nil || lyr == nil {
-		return
-	}
-
-	switch next {
-	case layers.LayerTypeTCP:
-		var tcpPkt layers.TCP
-		err := tcpPkt.DecodeFromBytes(payload, gopacket.NilDecodeFeedback)
-		if err != nil {
-			return
-		}
-
-		if l4pkt := sniffer.ParseTCPPacket(ts, lyr, &tcpPkt); l4pkt != nil {
-			if ps.onL4Packet != nil {
-				ps.onL4Packet(l4pkt)
-			}
-		}
-
-	case layers.LayerTypeUDP:
-		var udpPkt layers.UDP
-		err := udpPkt.DecodeFromBytes(payload, gopacket.NilDecodeFeedback)
-		if err != nil {
-			return
-		}
-
-		if l4pkt := sniffer.ParseUDPDatagram(ts, lyr, &udpPkt); l4pkt != nil {
-			if ps.onL4Packet != nil {
-				ps.onL4Packet(l4pkt)
-			}
-		}
-	}
+func (ps *pcapSniffer) parsePacket(ph *handler, pkt []byte, ts time.Time) {
+	ps.parseLinkPacket(ph, layers.LinkTypeEthernet, "", pkt, ts)
 }
 
 func (ps *pcapSniffer) listen(ph *handler) {
+	pinHandler(ph.name, ph.cpus)
 	if ph.pfile != nil {
 		ps.listenPcapFile(ph)
 		return
 	}
+	if ph.ngfile != nil {
+		ps.listenNgFile(ph)
+		return
+	}
 
 	ps.listenAfPacket(ph)
 }
@@ -242,7 +251,7 @@ func (ps *pcapSniffer) listenAfPacket(ph *handler) {
 				}
 				continue
 			}
-			ps.parsePacket(pkt, ci.Timestamp)
+			ps.parsePacket(ph, pkt, ph.clock.Stamp(ci.Timestamp))
 		}
 	}
 }
@@ -262,7 +271,7 @@ func (ps *pcapSniffer) listenPcapFile(ph *handler) {
 				logger.Infof("pcap handle (%s) closed", ph.name)
 				return
 			}
-			ps.parsePacket(packet.Data(), time.Now())
+			ps.parsePacket(ph, packet.Data(), ph.clock.Stamp(packet.Metadata().Timestamp))
 		}
 	}
 }
@@ -270,14 +279,22 @@ func (ps *pcapSniffer) listenPcapFile(ph *handler) {
 func (ps *pcapSniffer) Stats() []sniffer.Stats {
 	lst := make([]sniffer.Stats, 0, len(ps.handlers))
 	for _, ph := range ps.handlers {
+		if ph.ngfile != nil {
+			lst = append(lst, ph.ngfile.stats(ph.name)...)
+			continue
+		}
+		if ph.handle == nil {
+			continue
+		}
 		_, stats, err := ph.handle.SocketStats()
 		if err != nil {
 			continue
 		}
 		lst = append(lst, sniffer.Stats{
-			Name:    ph.name,
-			Packets: stats.Packets(),
-			Drops:   stats.Drops(),
+			Name:       ph.name,
+			Packets:    stats.Packets(),
+			Drops:      stats.Drops(),
+			Duplicates: uint(ph.duplicates.Load()),
 		})
 	}
 	return lst
@@ -289,7 +306,14 @@ func (ps *pcapSniffer) Reload(conf *sniffer.Config) error {
 		return err
 	}
 	for _, h := range ps.handlers {
-		if err := ps.setBPFFilter(h.handle, bpfFilter); err != nil {
+		if h.ngfile != nil {
+			h.ngfile.setFilter(bpfFilter)
+			continue
+		}
+		if h.handle == nil {
+			continue
+		}
+		if err := ps.setBPFFilter(h.handle, bpfFilter, conf.CaptureLen()); err != nil {
 			return err
 		}
 	}
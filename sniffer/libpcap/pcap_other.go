@@ -21,6 +21,7 @@ import (
 	"net"
 	"regexp"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gopacket/gopacket"
@@ -29,6 +30,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/clock"
 	"github.com/packetd/packetd/logger"
 	"github.com/packetd/packetd/sniffer"
 )
@@ -40,6 +42,11 @@ func init() {
 type handler struct {
 	name   string
 	handle *pcap.Handle
+	ngfile *ngFile
+	clock  clock.Clock
+	cpus   []int
+
+	duplicates atomic.Uint64
 }
 
 type pcapSniffer struct {
@@ -49,6 +56,7 @@ type pcapSniffer struct {
 	handlers   []*handler
 	wg         sync.WaitGroup
 	onL4Packet sniffer.OnL4Packet
+	dedup      *sniffer.Deduper
 }
 
 func (ps *pcapSniffer) Name() string {
@@ -65,7 +73,8 @@ func (ps *pcapSniffer) L7Ports() []socket.L7Ports {
 
 func New(conf *sniffer.Config) (sniffer.Sniffer, error) {
 	snif := &pcapSniffer{
-		conf: conf,
+		conf:  conf,
+		dedup: sniffer.NewDeduper(conf.Dedup),
 	}
 
 	snif.ctx, snif.cancel = context.WithCancel(context.Background())
@@ -95,6 +104,20 @@ func (ps *pcapSniffer) makeHandlers() error {
 		return err
 	}
 
+	if len(ps.conf.File) > 0 && isPcapngFile(ps.conf.File) {
+		nf, err := openNgFile(ps.conf.File, bpfFilter)
+		if err != nil {
+			return err
+		}
+		ps.handlers = append(ps.handlers, &handler{
+			name:   fmt.Sprintf("pcap.file: %s", ps.conf.File),
+			ngfile: nf,
+			clock:  newClock(ps.conf, true),
+		})
+		logger.Infof("sniffer add pcapng file (%s)", ps.conf.File)
+		return nil
+	}
+
 	if len(ps.conf.File) > 0 {
 		tp, err := makeFileHandle(ps.conf.File, bpfFilter)
 		if err != nil {
@@ -103,6 +126,7 @@ func (ps *pcapSniffer) makeHandlers() error {
 		ps.handlers = append(ps.handlers, &handler{
 			name:   fmt.Sprintf("pcap.file: %s", ps.conf.File),
 			handle: tp,
+			clock:  newClock(ps.conf, true),
 		})
 		logger.Infof("sniffer add pcap file (%s)", ps.conf.File)
 		return nil
@@ -118,6 +142,8 @@ func (ps *pcapSniffer) makeHandlers() error {
 		ps.handlers = append(ps.handlers, &handler{
 			name:   fmt.Sprintf("pcap.device: %s", iface.Name),
 			handle: tp,
+			clock:  newClock(ps.conf, false),
+			cpus:   resolveCPUs(ps.conf, iface.Name),
 		})
 		logger.Infof("sniffer add device (%s), address=%v", iface.Name, ifaceAddress(iface))
 	}
@@ -129,7 +155,7 @@ func (ps *pcapSniffer) makeHandlers() error {
 }
 
 func (ps *pcapSniffer) getHandle(device, bpfFilter string) (*pcap.Handle, error) {
-	handle, err := pcap.OpenLive(device, socket.MaxIPV6PacketSize, !ps.conf.NoPromisc, pcap.BlockForever)
+	handle, err := openLiveHandle(device, ps.conf.CaptureLen(), !ps.conf.NoPromisc, clock.Source(ps.conf.TimeSource))
 	if err != nil {
 		return nil, err
 	}
@@ -143,40 +169,17 @@ func (ps *pcapSniffer) getHandle(device, bpfFilter string) (*pcap.Handle, error)
 	return handle, nil
 }
 
-func (ps *pcapSniffer) parsePacket(packet gopacket.Packet) {
-	payload, lyr, next, err := sniffer.DecodeIPLayer(packet.Data(), sniffer.IPVPicker(ps.conf.IPVersion))
-	if err != nil {
-		return
-	}
-
-	switch next {
-	case layers.LayerTypeTCP:
-		var tcpPkt layers.TCP
-		err := tcpPkt.DecodeFromBytes(payload, gopacket.NilDecodeFeedback)
-		if err != nil {
-			return
-		}
-		if l4pkt := sniffer.ParseTCPPacket(time.Now(), lyr, &tcpPkt); l4pkt != nil {
-			if ps.onL4Packet != nil {
-				ps.onL4Packet(l4pkt)
-			}
-		}
-
-	case layers.LayerTypeUDP:
-		var udpPkt layers.UDP
-		err := udpPkt.DecodeFromBytes(payload, gopacket.NilDecodeFeedback)
-		if err != nil {
-			return
-		}
-		if l4pkt := sniffer.ParseUDPDatagram(time.Now(), lyr, &udpPkt); l4pkt != nil {
-			if ps.onL4Packet != nil {
-				ps.onL4Packet(l4pkt)
-			}
-		}
-	}
+func (ps *pcapSniffer) parsePacket(ph *handler, packet gopacket.Packet, ts time.Time) {
+	ps.parseLinkPacket(ph, layers.LinkTypeEthernet, "", packet.Data(), ts)
 }
 
 func (ps *pcapSniffer) listen(ph *handler) {
+	pinHandler(ph.name, ph.cpus)
+	if ph.ngfile != nil {
+		ps.listenNgFile(ph)
+		return
+	}
+
 	ps.wg.Add(1)
 	defer ps.wg.Done()
 
@@ -191,7 +194,7 @@ func (ps *pcapSniffer) listen(ph *handler) {
 				logger.Infof("pcap handle (%s) closed", ph.name)
 				return
 			}
-			ps.parsePacket(packet)
+			ps.parsePacket(ph, packet, ph.clock.Stamp(packet.Metadata().Timestamp))
 		}
 	}
 }
@@ -199,14 +202,19 @@ func (ps *pcapSniffer) listen(ph *handler) {
 func (ps *pcapSniffer) Stats() []sniffer.Stats {
 	lst := make([]sniffer.Stats, 0, len(ps.handlers))
 	for _, ph := range ps.handlers {
+		if ph.ngfile != nil {
+			lst = append(lst, ph.ngfile.stats(ph.name)...)
+			continue
+		}
 		stats, err := ph.handle.Stats()
 		if err != nil {
 			continue
 		}
 		lst = append(lst, sniffer.Stats{
-			Name:    ph.name,
-			Packets: uint(stats.PacketsReceived),
-			Drops:   uint(stats.PacketsDropped),
+			Name:       ph.name,
+			Packets:    uint(stats.PacketsReceived),
+			Drops:      uint(stats.PacketsDropped),
+			Duplicates: uint(ph.duplicates.Load()),
 		})
 	}
 	return lst
@@ -218,6 +226,10 @@ func (ps *pcapSniffer) Reload(conf *sniffer.Config) error {
 		return err
 	}
 	for _, h := range ps.handlers {
+		if h.ngfile != nil {
+			h.ngfile.setFilter(bpfFilter)
+			continue
+		}
 		if err := h.handle.SetBPFFilter(bpfFilter); err != nil {
 			return err
 		}
@@ -227,8 +239,11 @@ func (ps *pcapSniffer) Reload(conf *sniffer.Config) error {
 }
 
 func (ps *pcapSniffer) Close() {
+	ps.cancel()
 	for _, h := range ps.handlers {
-		h.handle.Close()
+		if h.handle != nil {
+			h.handle.Close()
+		}
 	}
 	ps.wg.Wait()
 }
@@ -0,0 +1,218 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libpcap
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/pcap"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/pcapng"
+	"github.com/packetd/packetd/logger"
+	"github.com/packetd/packetd/sniffer"
+)
+
+// isPcapngFile 判断文件是否为 pcapng 格式 读取失败时交由 libpcap 处理
+func isPcapngFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(pcapng.Magic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false
+	}
+	return bytes.Equal(magic, pcapng.Magic)
+}
+
+// ngFile pcapng 文件数据源
+//
+// libpcap 读取 pcapng 时要求所有接口链路类型一致且时间戳精度固定为微秒
+// 这里使用 pcapng.Reader 按接口解析链路类型以及时间戳 并按接口名称统计收包数量
+type ngFile struct {
+	f      *os.File
+	reader *pcapng.Reader
+
+	mut     sync.Mutex
+	filter  string
+	bpfs    map[layers.LinkType]*pcap.BPF
+	packets map[string]uint
+	labels  []string // 按首次出现顺序记录接口名称 保证 Stats 输出稳定
+}
+
+func openNgFile(path, bpfFilter string) (*ngFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := pcapng.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &ngFile{
+		f:       f,
+		reader:  reader,
+		filter:  bpfFilter,
+		bpfs:    make(map[layers.LinkType]*pcap.BPF),
+		packets: make(map[string]uint),
+	}, nil
+}
+
+// setFilter 更新 BPF 过滤规则 各链路类型的规则在下次使用时重新编译
+func (nf *ngFile) setFilter(bpfFilter string) {
+	nf.mut.Lock()
+	defer nf.mut.Unlock()
+
+	nf.filter = bpfFilter
+	nf.bpfs = make(map[layers.LinkType]*pcap.BPF)
+}
+
+// match 判断数据包是否命中 BPF 过滤规则
+//
+// 过滤规则需要按照链路类型分别编译 无法编译的链路类型不做过滤
+func (nf *ngFile) match(lt layers.LinkType, pkt *pcapng.Packet) bool {
+	nf.mut.Lock()
+	defer nf.mut.Unlock()
+
+	if nf.filter == "" {
+		return true
+	}
+
+	bpf, ok := nf.bpfs[lt]
+	if !ok {
+		var err error
+		bpf, err = pcap.NewBPF(lt, socket.MaxIPV6PacketSize, nf.filter)
+		if err != nil {
+			logger.Warnf("compile bpf-filter (%s) for link type (%s) failed: %v", nf.filter, lt, err)
+		}
+		nf.bpfs[lt] = bpf
+	}
+	if bpf == nil {
+		return true
+	}
+
+	ci := gopacket.CaptureInfo{
+		Timestamp:     pkt.Timestamp,
+		CaptureLength: len(pkt.Data),
+		Length:        pkt.Length,
+	}
+	return bpf.Matches(ci, pkt.Data)
+}
+
+func (nf *ngFile) incPackets(label string) {
+	nf.mut.Lock()
+	defer nf.mut.Unlock()
+
+	if _, ok := nf.packets[label]; !ok {
+		nf.labels = append(nf.labels, label)
+	}
+	nf.packets[label]++
+}
+
+// stats 按接口输出统计数据 name 为 handler 名称
+func (nf *ngFile) stats(name string) []sniffer.Stats {
+	nf.mut.Lock()
+	defer nf.mut.Unlock()
+
+	lst := make([]sniffer.Stats, 0, len(nf.labels))
+	for _, label := range nf.labels {
+		lst = append(lst, sniffer.Stats{
+			Name:    fmt.Sprintf("%s [%s]", name, label),
+			Packets: nf.packets[label],
+		})
+	}
+	return lst
+}
+
+func (nf *ngFile) Close() {
+	nf.f.Close()
+}
+
+func (ps *pcapSniffer) listenNgFile(ph *handler) {
+	ps.wg.Add(1)
+	defer ps.wg.Done()
+
+	defer ph.ngfile.Close()
+
+	for {
+		select {
+		case <-ps.ctx.Done():
+			logger.Infof("pcap handle (%s) closed", ph.name)
+			return
+
+		default:
+			pkt, err := ph.ngfile.reader.Next()
+			if err != nil {
+				if err != io.EOF {
+					logger.Errorf("read pcap handle (%s) failed: %v", ph.name, err)
+				}
+				logger.Infof("pcap handle (%s) closed", ph.name)
+				return
+			}
+
+			// Section 切换后接口列表会被重置 因此每个数据包都需要重新查询所属接口
+			iface := ph.ngfile.reader.Interfaces()[pkt.Interface]
+			label := iface.Label(pkt.Interface)
+			for _, comment := range pkt.Comments {
+				logger.Debugf("pcap handle (%s) interface (%s) packet at %s comment: %s", ph.name, label, pkt.Timestamp.Format(time.RFC3339Nano), comment)
+			}
+			if !ph.ngfile.match(iface.LinkType, pkt) {
+				continue
+			}
+			ph.ngfile.incPackets(label)
+			ps.parseLinkPacket(ph, iface.LinkType, label, pkt.Data, ph.clock.Stamp(pkt.Timestamp))
+		}
+	}
+}
+
+// parseLinkPacket 按照链路类型解析数据包 iface 非空时记录数据包所属的网卡
+func (ps *pcapSniffer) parseLinkPacket(ph *handler, lt layers.LinkType, iface string, pkt []byte, ts time.Time) {
+	payload, lyr, next, err := sniffer.DecodeLinkIPLayer(pkt, lt, sniffer.IPVPicker(ps.conf.IPVersion))
+	if err != nil || lyr == nil {
+		return
+	}
+
+	switch next {
+	case layers.LayerTypeTCP:
+		var tcpPkt layers.TCP
+		if err := tcpPkt.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err != nil {
+			return
+		}
+		if l4pkt := sniffer.ParseTCPPacket(ts, lyr, &tcpPkt); l4pkt != nil {
+			l4pkt.Iface = iface
+			ps.emit(ph, lyr, l4pkt)
+		}
+
+	case layers.LayerTypeUDP:
+		var udpPkt layers.UDP
+		if err := udpPkt.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err != nil {
+			return
+		}
+		if l4pkt := sniffer.ParseUDPDatagram(ts, lyr, &udpPkt); l4pkt != nil {
+			l4pkt.Iface = iface
+			ps.emit(ph, lyr, l4pkt)
+		}
+	}
+}
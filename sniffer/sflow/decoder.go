@@ -0,0 +1,219 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sflow
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+/*
+* sFlow v5 Datagram Layout
++----------------------------------------------+
+| version (4) = 5                              |
++----------------------------------------------+
+| agent address type (4) 1: IPv4 / 2: IPv6     |
++----------------------------------------------+
+| agent address (4/16)                         |
++----------------------------------------------+
+| sub agent id (4)                             |
++----------------------------------------------+
+| sequence number (4)                          |
++----------------------------------------------+
+| uptime (4)                                   |
++----------------------------------------------+
+| samples (4)                                  |
++----------------------------------------------+
+| sample: data format (4) | length (4) | data  |
++----------------------------------------------+
+*/
+
+const (
+	version5 = 5
+
+	addressIPv4 = 1
+	addressIPv6 = 2
+
+	// sample 类型 enterprise 均为 0
+	formatFlowSample         = 1
+	formatExpandedFlowSample = 3
+
+	// flow record 类型
+	formatRawPacketHeader = 1
+
+	// HeaderProtocolEthernet raw packet header 中以太网帧的协议类型 (ETHERNET-ISO88023)
+	HeaderProtocolEthernet = 1
+)
+
+var (
+	errTruncated          = errors.New("sflow: truncated datagram")
+	errUnsupportedVersion = errors.New("sflow: unsupported version")
+	errInvalidAddress     = errors.New("sflow: invalid agent address type")
+)
+
+// Datagram sFlow 数据报 仅保留流采样中的原始数据包头
+type Datagram struct {
+	Agent    net.IP
+	SubAgent uint32
+	Sequence uint32
+	Samples  []Sample
+}
+
+// Sample 流采样
+type Sample struct {
+	SourceID     uint32
+	SamplingRate uint32 // 采样率 即平均每 SamplingRate 个数据包采样一个
+	Drops        uint32 // agent 因资源不足未能采样的数据包数量 累计值
+	Packets      []RawPacket
+}
+
+// RawPacket 被采样数据包的头部
+//
+// Header 为数据包的前若干个字节 通常为 128 bytes 应用层 payload 大概率被截断
+type RawPacket struct {
+	Protocol    uint32
+	FrameLength uint32
+	Header      []byte
+}
+
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) uint32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.b) < n {
+		r.err = errTruncated
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+// opaque 读取 XDR opaque 数据 长度按 4 字节对齐
+func (r *reader) opaque(n uint32) []byte {
+	padded := (uint64(n) + 3) &^ 3
+	if padded > uint64(len(r.b)) {
+		r.err = errTruncated
+		return nil
+	}
+	b := r.bytes(int(padded))
+	return b[:n]
+}
+
+// Decode 解析 sFlow v5 数据报
+//
+// 计数器采样以及非原始数据包头的流记录会被忽略 返回的 Header 引用 b 的内存
+func Decode(b []byte) (*Datagram, error) {
+	r := &reader{b: b}
+	if v := r.uint32(); r.err == nil && v != version5 {
+		return nil, errors.Wrapf(errUnsupportedVersion, "version %d", v)
+	}
+
+	var dg Datagram
+	switch r.uint32() {
+	case addressIPv4:
+		dg.Agent = net.IP(r.bytes(net.IPv4len))
+	case addressIPv6:
+		dg.Agent = net.IP(r.bytes(net.IPv6len))
+	default:
+		if r.err == nil {
+			return nil, errInvalidAddress
+		}
+	}
+	dg.SubAgent = r.uint32()
+	dg.Sequence = r.uint32()
+	_ = r.uint32() // uptime
+	n := r.uint32()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	for i := uint32(0); i < n; i++ {
+		format := r.uint32()
+		body := r.opaque(r.uint32())
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		var sample Sample
+		var err error
+		switch format {
+		case formatFlowSample:
+			sample, err = decodeFlowSample(&reader{b: body}, false)
+		case formatExpandedFlowSample:
+			sample, err = decodeFlowSample(&reader{b: body}, true)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		dg.Samples = append(dg.Samples, sample)
+	}
+	return &dg, nil
+}
+
+func decodeFlowSample(r *reader, expanded bool) (Sample, error) {
+	var sample Sample
+	_ = r.uint32() // sequence number
+	if expanded {
+		_ = r.uint32() // source id type
+	}
+	sample.SourceID = r.uint32()
+	sample.SamplingRate = r.uint32()
+	_ = r.uint32() // sample pool
+	sample.Drops = r.uint32()
+	if expanded {
+		r.bytes(16) // input/output interface format & value
+	} else {
+		r.bytes(8) // input/output interface
+	}
+
+	n := r.uint32()
+	for i := uint32(0); i < n && r.err == nil; i++ {
+		format := r.uint32()
+		body := r.opaque(r.uint32())
+		if r.err != nil || format != formatRawPacketHeader {
+			continue
+		}
+
+		rec := &reader{b: body}
+		pkt := RawPacket{
+			Protocol:    rec.uint32(),
+			FrameLength: rec.uint32(),
+		}
+		_ = rec.uint32() // stripped
+		pkt.Header = rec.opaque(rec.uint32())
+		if rec.err != nil {
+			return sample, rec.err
+		}
+		sample.Packets = append(sample.Packets, pkt)
+	}
+	return sample, r.err
+}
@@ -0,0 +1,280 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sflow
+
+import (
+	"net"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/pkg/errors"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/internal/clock"
+	"github.com/packetd/packetd/logger"
+	"github.com/packetd/packetd/sniffer"
+)
+
+const (
+	Name = "sflow"
+)
+
+const (
+	// defaultListen sFlow collector 默认端口
+	defaultListen = ":6343"
+
+	maxDatagramSize = 65535
+)
+
+func init() {
+	sniffer.Register(New, Name)
+}
+
+// agentStats 单个 agent 的统计数据
+type agentStats struct {
+	packets    uint
+	duplicates uint
+	drops      map[uint32]uint32 // 各数据源上报的累计丢弃数量
+}
+
+// sflowSniffer 以 sFlow collector 的身份接收交换机等设备发送的采样数据
+//
+// 采样数据仅包含数据包头部 应用层 payload 大概率被截断且同一链接的数据包并不连续
+// 因此数据包均携带采样率 上层不再交由应用层协议解析 仅按照采样率放大后计入四层的流统计
+type sflowSniffer struct {
+	conn       *net.UDPConn
+	conf       atomic.Pointer[sniffer.Config]
+	agents     []net.IP
+	clock      clock.Clock
+	dedup      *sniffer.Deduper
+	onL4Packet atomic.Pointer[sniffer.OnL4Packet] // 监听协程先于回调启动 回调设置前到达的数据包直接丢弃
+	wg         sync.WaitGroup
+
+	mut   sync.Mutex
+	stats map[string]*agentStats
+}
+
+func New(conf *sniffer.Config) (sniffer.Sniffer, error) {
+	var agents []net.IP
+	for _, s := range conf.SFlow.Agents {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, errors.Errorf("invalid sflow agent address (%s)", s)
+		}
+		agents = append(agents, ip)
+	}
+
+	listen := conf.SFlow.Listen
+	if listen == "" {
+		listen = defaultListen
+	}
+	addr, err := net.ResolveUDPAddr("udp", listen)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resolve sflow listen address (%s) failed", listen)
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "listen sflow address (%s) failed", listen)
+	}
+	if conf.SFlow.ReadBuffer > 0 {
+		if err := conn.SetReadBuffer(conf.SFlow.ReadBuffer); err != nil {
+			logger.Warnf("set sflow read buffer (%d) failed: %v", conf.SFlow.ReadBuffer, err)
+		}
+	}
+
+	snif := &sflowSniffer{
+		conn:   conn,
+		agents: agents,
+		clock:  clock.New(clock.SourceSystem),
+		dedup:  sniffer.NewDeduper(conf.Dedup),
+		stats:  make(map[string]*agentStats),
+	}
+	snif.conf.Store(conf)
+
+	logger.Infof("sniffer listen sflow on (%s)", conn.LocalAddr())
+	snif.wg.Add(1)
+	go snif.listen()
+	return snif, nil
+}
+
+func (ss *sflowSniffer) Name() string {
+	return Name
+}
+
+func (ss *sflowSniffer) SetOnL4Packet(f sniffer.OnL4Packet) {
+	ss.onL4Packet.Store(&f)
+}
+
+func (ss *sflowSniffer) L7Ports() []socket.L7Ports {
+	return ss.conf.Load().Protocols.L7Ports()
+}
+
+// Reload 仅重载协议规则 监听地址变更需要重启进程
+func (ss *sflowSniffer) Reload(conf *sniffer.Config) error {
+	if _, err := conf.Protocols.CompileBPFFilter(); err != nil {
+		return err
+	}
+	ss.conf.Store(conf)
+	return nil
+}
+
+func (ss *sflowSniffer) Stats() []sniffer.Stats {
+	ss.mut.Lock()
+	defer ss.mut.Unlock()
+
+	lst := make([]sniffer.Stats, 0, len(ss.stats))
+	for agent, as := range ss.stats {
+		var drops uint
+		for _, n := range as.drops {
+			drops += uint(n)
+		}
+		lst = append(lst, sniffer.Stats{
+			Name:       "sflow.agent: " + agent,
+			Packets:    as.packets,
+			Drops:      drops,
+			Duplicates: as.duplicates,
+		})
+	}
+	sort.Slice(lst, func(i, j int) bool {
+		return lst[i].Name < lst[j].Name
+	})
+	return lst
+}
+
+func (ss *sflowSniffer) Close() {
+	_ = ss.conn.Close()
+	ss.wg.Wait()
+}
+
+func (ss *sflowSniffer) listen() {
+	defer ss.wg.Done()
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, _, err := ss.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				logger.Infof("sflow listener (%s) closed", ss.conn.LocalAddr())
+				return
+			}
+			logger.Warnf("read sflow datagram failed: %v", err)
+			continue
+		}
+
+		dg, err := Decode(buf[:n])
+		if err != nil {
+			logger.Debugf("decode sflow datagram failed: %v", err)
+			continue
+		}
+		ss.handleDatagram(dg, ss.clock.Stamp(time.Now()))
+	}
+}
+
+func (ss *sflowSniffer) allowed(agent net.IP) bool {
+	if len(ss.agents) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(ss.agents, agent.Equal)
+}
+
+func (ss *sflowSniffer) handleDatagram(dg *Datagram, ts time.Time) {
+	if !ss.allowed(dg.Agent) {
+		return
+	}
+
+	conf := ss.conf.Load()
+	onL4Packet := ss.onL4Packet.Load()
+	var packets, duplicates uint
+	for _, sample := range dg.Samples {
+		for _, raw := range sample.Packets {
+			if raw.Protocol != HeaderProtocolEthernet {
+				continue
+			}
+			packets++
+
+			ipLyr, pkt := parsePacket(raw.Header, ts, sniffer.IPVPicker(conf.IPVersion))
+			if pkt == nil || !conf.Protocols.Match(pkt) {
+				continue
+			}
+			if ss.dedup != nil && ss.dedup.Duplicate(ipLyr, pkt) {
+				duplicates++
+				continue
+			}
+			markSampled(pkt, sample.SamplingRate)
+			if onL4Packet != nil {
+				(*onL4Packet)(pkt)
+			}
+		}
+	}
+
+	ss.mut.Lock()
+	defer ss.mut.Unlock()
+
+	agent := dg.Agent.String()
+	as, ok := ss.stats[agent]
+	if !ok {
+		as = &agentStats{drops: make(map[uint32]uint32)}
+		ss.stats[agent] = as
+	}
+	as.packets += packets
+	as.duplicates += duplicates
+	for _, sample := range dg.Samples {
+		as.drops[sample.SourceID] = sample.Drops
+	}
+}
+
+// markSampled 记录数据包的采样率 agent 未声明采样率时视为未采样（1:1）
+func markSampled(pkt socket.L4Packet, rate uint32) {
+	rate = max(rate, 1)
+	switch p := pkt.(type) {
+	case *socket.TCPSegment:
+		p.SamplingRate = rate
+	case *socket.UDPDatagram:
+		p.SamplingRate = rate
+	}
+}
+
+// parsePacket 解析以太网帧头部 被截断的 payload 按实际采样长度处理
+func parsePacket(b []byte, ts time.Time, picker sniffer.IPVPicker) (gopacket.Layer, socket.L4Packet) {
+	payload, lyr, next, err := sniffer.DecodeIPLayer(b, picker)
+	if err != nil || lyr == nil {
+		return nil, nil
+	}
+
+	switch next {
+	case layers.LayerTypeTCP:
+		var tcpPkt layers.TCP
+		if err := tcpPkt.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err != nil {
+			return nil, nil
+		}
+		if pkt := sniffer.ParseTCPPacket(ts, lyr, &tcpPkt); pkt != nil {
+			return lyr, pkt
+		}
+
+	case layers.LayerTypeUDP:
+		var udpPkt layers.UDP
+		if err := udpPkt.DecodeFromBytes(payload, gopacket.NilDecodeFeedback); err != nil {
+			return nil, nil
+		}
+		if pkt := sniffer.ParseUDPDatagram(ts, lyr, &udpPkt); pkt != nil {
+			return lyr, pkt
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,217 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sflow
+
+import (
+	"encoding/binary"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/packetd/packetd/common/socket"
+	"github.com/packetd/packetd/sniffer"
+)
+
+func ethernetFrame(t *testing.T, dstPort uint16, payload string) []byte {
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0, 1, 2, 3, 4, 5},
+		DstMAC:       net.HardwareAddr{0, 1, 2, 3, 4, 6},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolTCP,
+		SrcIP:    net.ParseIP("10.0.0.1").To4(),
+		DstIP:    net.ParseIP("10.0.0.2").To4(),
+	}
+	tcp := &layers.TCP{SrcPort: 50001, DstPort: layers.TCPPort(dstPort), Seq: 100, ACK: true, PSH: true}
+	require.NoError(t, tcp.SetNetworkLayerForChecksum(ip))
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	require.NoError(t, gopacket.SerializeLayers(buf, opts, eth, ip, tcp, gopacket.Payload(payload)))
+	return buf.Bytes()
+}
+
+type xdr []byte
+
+func (x xdr) u32(vs ...uint32) xdr {
+	for _, v := range vs {
+		x = binary.BigEndian.AppendUint32(x, v)
+	}
+	return x
+}
+
+func (x xdr) opaque(b []byte) xdr {
+	x = x.u32(uint32(len(b)))
+	x = append(x, b...)
+	for len(x)%4 != 0 {
+		x = append(x, 0)
+	}
+	return x
+}
+
+// rawPacketRecord 采样长度为 snap 的原始数据包头记录
+func rawPacketRecord(frame []byte, snap int) xdr {
+	header := frame
+	if len(header) > snap {
+		header = header[:snap]
+	}
+	body := xdr(nil).u32(HeaderProtocolEthernet, uint32(len(frame)), 4).opaque(header)
+	return xdr(nil).u32(formatRawPacketHeader).opaque(body)
+}
+
+func flowSample(expanded bool, sourceID, drops uint32, records ...xdr) xdr {
+	body := xdr(nil).u32(1)
+	if expanded {
+		body = body.u32(0, sourceID, 512, 4096, drops, 0, 1, 0, 2)
+	} else {
+		body = body.u32(sourceID, 512, 4096, drops, 1, 2)
+	}
+	body = body.u32(uint32(len(records)))
+	for _, rec := range records {
+		body = append(body, rec...)
+	}
+
+	format := uint32(formatFlowSample)
+	if expanded {
+		format = formatExpandedFlowSample
+	}
+	return xdr(nil).u32(format).opaque(body)
+}
+
+func datagram(samples ...xdr) []byte {
+	b := xdr(nil).u32(version5, addressIPv4)
+	b = append(b, 192, 168, 1, 254)
+	b = b.u32(0, 42, 1000, uint32(len(samples)+1))
+	// 计数器采样会被忽略
+	b = append(b, xdr(nil).u32(2).opaque(xdr(nil).u32(1, 2, 0))...)
+	for _, sample := range samples {
+		b = append(b, sample...)
+	}
+	return b
+}
+
+func TestDecode(t *testing.T) {
+	frame := ethernetFrame(t, 6379, "GET key\r\n")
+	b := datagram(
+		flowSample(false, 7, 3, rawPacketRecord(frame, 128)),
+		flowSample(true, 8, 5, rawPacketRecord(frame, 57), xdr(nil).u32(1001).opaque([]byte{1, 2, 3, 4})),
+	)
+
+	dg, err := Decode(b)
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.1.254", dg.Agent.String())
+	assert.Equal(t, uint32(42), dg.Sequence)
+	require.Len(t, dg.Samples, 2)
+
+	assert.Equal(t, uint32(7), dg.Samples[0].SourceID)
+	assert.Equal(t, uint32(512), dg.Samples[0].SamplingRate)
+	assert.Equal(t, uint32(3), dg.Samples[0].Drops)
+	assert.Equal(t, []RawPacket{{Protocol: HeaderProtocolEthernet, FrameLength: uint32(len(frame)), Header: frame}}, dg.Samples[0].Packets)
+
+	assert.Equal(t, uint32(8), dg.Samples[1].SourceID)
+	assert.Equal(t, uint32(5), dg.Samples[1].Drops)
+	require.Len(t, dg.Samples[1].Packets, 1)
+	assert.Equal(t, frame[:57], dg.Samples[1].Packets[0].Header)
+
+	for i := 0; i < len(b); i++ {
+		_, err := Decode(b[:i])
+		assert.Error(t, err, "truncated at %d", i)
+	}
+
+	_, err = Decode(xdr(nil).u32(4, addressIPv4))
+	assert.ErrorIs(t, err, errUnsupportedVersion)
+}
+
+func TestHandleDatagram(t *testing.T) {
+	conf := &sniffer.Config{
+		Protocols: sniffer.Protocols{
+			Rules: []sniffer.ProtoRule{{Protocol: "redis", Ports: []uint16{6379}}},
+		},
+	}
+	ss := &sflowSniffer{stats: make(map[string]*agentStats)}
+	ss.conf.Store(conf)
+
+	var pkts []socket.L4Packet
+	ss.SetOnL4Packet(func(pkt socket.L4Packet) {
+		pkts = append(pkts, pkt)
+	})
+
+	redis := ethernetFrame(t, 6379, "GET key\r\n")
+	http := ethernetFrame(t, 80, "GET / HTTP/1.1\r\n")
+	dg, err := Decode(datagram(
+		flowSample(false, 7, 3, rawPacketRecord(redis, 128), rawPacketRecord(http, 128)),
+		flowSample(false, 8, 5, rawPacketRecord(redis, 61)),
+	))
+	require.NoError(t, err)
+
+	ts := time.Unix(1751356800, 0)
+	ss.handleDatagram(dg, ts)
+	require.Len(t, pkts, 2)
+
+	seg := pkts[0].(*socket.TCPSegment)
+	assert.Equal(t, ts, seg.Time)
+	assert.Equal(t, socket.Port(6379), seg.Tuple.DstPort)
+	assert.Equal(t, "GET key\r\n", string(seg.Payload))
+	assert.Equal(t, uint32(512), seg.SamplingRate)
+	assert.Equal(t, uint32(512), socket.SamplingRate(pkts[1]))
+
+	// 截断的 payload 按照实际采样长度处理
+	assert.Equal(t, "GET key", string(pkts[1].(*socket.TCPSegment).Payload))
+
+	assert.Equal(t, []sniffer.Stats{{Name: "sflow.agent: 192.168.1.254", Packets: 3, Drops: 8}}, ss.Stats())
+
+	ss.agents = []net.IP{net.ParseIP("192.168.1.1")}
+	ss.handleDatagram(dg, ts)
+	assert.Len(t, pkts, 2)
+}
+
+func TestListen(t *testing.T) {
+	snif, err := New(&sniffer.Config{
+		SFlow: sniffer.SFlowConfig{Listen: "127.0.0.1:0"},
+		Protocols: sniffer.Protocols{
+			Rules: []sniffer.ProtoRule{{Protocol: "redis", Ports: []uint16{6379}}},
+		},
+	})
+	require.NoError(t, err)
+	defer snif.Close()
+
+	conn, err := net.Dial("udp", snif.(*sflowSniffer).conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	b := datagram(flowSample(false, 7, 3, rawPacketRecord(ethernetFrame(t, 6379, "GET key\r\n"), 128)))
+
+	// 回调设置前到达的数据包直接丢弃
+	_, err = conn.Write(b)
+	require.NoError(t, err)
+
+	var received atomic.Int32
+	snif.SetOnL4Packet(func(socket.L4Packet) {
+		received.Add(1)
+	})
+	assert.Eventually(t, func() bool {
+		_, err := conn.Write(b)
+		require.NoError(t, err)
+		return received.Load() > 0
+	}, 3*time.Second, 10*time.Millisecond)
+}
@@ -14,7 +14,9 @@
 package sniffer
 
 import (
+	"encoding/binary"
 	"runtime"
+	"slices"
 	"time"
 
 	"github.com/gopacket/gopacket"
@@ -33,6 +35,8 @@ type Stats struct {
 	Name    string // 设备名称
 	Packets uint   // 收包数量
 	Drops   uint   // 丢包数量
+
+	Duplicates uint // 去重丢弃的数据包数量
 }
 
 // Sniffer 负责实现网络数据包的嗅探并调用 On* 函数进行处理
@@ -68,6 +72,19 @@ func Register(f CreateFunc, names ...string) {
 	}
 }
 
+// Engines 返回已注册的 Sniffer 引擎名称 按名称排序
+func Engines() []string {
+	lst := make([]string, 0, len(snifferFactory))
+	for name := range snifferFactory {
+		if name == "" {
+			continue // 默认引擎的别名
+		}
+		lst = append(lst, name)
+	}
+	slices.Sort(lst)
+	return lst
+}
+
 // Get 获取 Sniffer 工厂函数
 func Get(name string) (CreateFunc, error) {
 	f, ok := snifferFactory[name]
@@ -132,43 +149,72 @@ func parsePacket(ts time.Time, lyrs ...gopacket.Layer) socket.L4Packet {
 	var protocol socket.L4Proto
 	var payload []byte
 
+	// ipLen 为 IP 层承载的数据长度 用于识别被截断的数据包
+	var ipLen int
+
 	// TCP 字段
 	var seq uint32
+	var synFlag bool
 	var finFlag bool
+	var rstFlag bool
+	var ackFlag bool
+	var ack uint32
+	var tsval uint32
 
 	for _, layerType := range lyrs {
 		switch lyr := layerType.(type) {
 		case *layers.IPv4:
 			srcIP = socket.ToIPV4(lyr.SrcIP)
 			dstIP = socket.ToIPV4(lyr.DstIP)
+			ipLen = int(lyr.Length) - int(lyr.IHL)*4
 
 		case *layers.IPv6:
 			srcIP = socket.ToIPV6(lyr.SrcIP)
 			dstIP = socket.ToIPV6(lyr.DstIP)
+			ipLen = int(lyr.Length) // Jumbo Payload 时为 0 视为未截断
 
 		case *layers.TCP:
 			protocol = socket.L4ProtoTCP
 			srcPort = socket.Port(lyr.SrcPort)
 			dstPort = socket.Port(lyr.DstPort)
 			payload = lyr.Payload
+			ipLen -= int(lyr.DataOffset) * 4
 			seq = lyr.Seq
+			synFlag = lyr.SYN
 			finFlag = lyr.FIN
+			rstFlag = lyr.RST
+			ackFlag = lyr.ACK
+			ack = lyr.Ack
+			tsval = tcpTimestamp(lyr.Options)
 
 		case *layers.UDP:
 			protocol = socket.L4ProtoUDP
 			srcPort = socket.Port(lyr.SrcPort)
 			dstPort = socket.Port(lyr.DstPort)
 			payload = lyr.Payload
+			ipLen = int(lyr.Length) - 8
 		}
 	}
 
+	// 仅在截断时记录实际长度 链路层填充等导致的长度差异不影响解析
+	var length int
+	if ipLen > len(payload) {
+		length = ipLen
+	}
+
 	switch protocol {
 	case socket.L4ProtoTCP:
 		return &socket.TCPSegment{
 			Time:    ts,
 			Seq:     seq,
+			SYN:     synFlag,
 			FIN:     finFlag,
+			RST:     rstFlag,
+			ACK:     ackFlag,
+			Ack:     ack,
+			TSVal:   tsval,
 			Payload: payload,
+			Length:  length,
 			Tuple: socket.Tuple{
 				SrcIP:   srcIP,
 				SrcPort: srcPort,
@@ -180,6 +226,7 @@ func parsePacket(ts time.Time, lyrs ...gopacket.Layer) socket.L4Packet {
 		return &socket.UDPDatagram{
 			Time:    ts,
 			Payload: payload,
+			Length:  length,
 			Tuple: socket.Tuple{
 				SrcIP:   srcIP,
 				SrcPort: srcPort,
@@ -192,6 +239,16 @@ func parsePacket(ts time.Time, lyrs ...gopacket.Layer) socket.L4Packet {
 	}
 }
 
+// tcpTimestamp 返回 Timestamps 选项中的 TSval 未携带时返回 0
+func tcpTimestamp(opts []layers.TCPOption) uint32 {
+	for _, opt := range opts {
+		if opt.OptionType == layers.TCPOptionKindTimestamps && len(opt.OptionData) >= 8 {
+			return binary.BigEndian.Uint32(opt.OptionData)
+		}
+	}
+	return 0
+}
+
 // DecodeIPLayer 解析 IP 层
 //
 // 返回数据包 Payload 以及所处 Layer
@@ -204,7 +261,78 @@ func DecodeIPLayer(b []byte, ipvPicker IPVPicker) ([]byte, gopacket.Layer, gopac
 	if err != nil {
 		return nil, nil, 0, err
 	}
+	return decodeIP(content, ipv, ipvPicker)
+}
+
+// DecodeLinkIPLayer 按照指定的链路类型解析 IP 层
+//
+// 用于读取 pcapng 等可能混合多种链路类型的文件 Ethernet 与 DecodeIPLayer 行为一致
+func DecodeLinkIPLayer(b []byte, lt layers.LinkType, ipvPicker IPVPicker) ([]byte, gopacket.Layer, gopacket.LayerType, error) {
+	if lt == layers.LinkTypeEthernet {
+		return DecodeIPLayer(b, ipvPicker)
+	}
+
+	content, ipv, err := stripLinkLayer(b, lt)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return decodeIP(content, ipv, ipvPicker)
+}
+
+// stripLinkLayer 剥离非 Ethernet 的链路层头部
+func stripLinkLayer(b []byte, lt layers.LinkType) ([]byte, uint8, error) {
+	var next gopacket.LayerType
+	var content []byte
+
+	switch lt {
+	case layers.LinkTypeRaw, layers.LinkTypeIPv4, layers.LinkTypeIPv6:
+		if len(b) == 0 {
+			return nil, 0, errors.New("empty raw packet")
+		}
+		// Raw 链路类型通过版本号区分 IPv4/IPv6
+		switch b[0] >> 4 {
+		case 4:
+			return b, layerIpv4, nil
+		case 6:
+			return b, layerIpv6, nil
+		}
+		return nil, 0, errors.New("unknown raw ip version")
+
+	case layers.LinkTypeLinuxSLL:
+		var sll layers.LinuxSLL
+		if err := sll.DecodeFromBytes(b, gopacket.NilDecodeFeedback); err != nil {
+			return nil, 0, err
+		}
+		next, content = sll.NextLayerType(), sll.Payload
+
+	case layers.LinkTypeLinuxSLL2:
+		var sll layers.LinuxSLL2
+		if err := sll.DecodeFromBytes(b, gopacket.NilDecodeFeedback); err != nil {
+			return nil, 0, err
+		}
+		next, content = sll.NextLayerType(), sll.Payload
+
+	case layers.LinkTypeNull, layers.LinkTypeLoop:
+		var lb layers.Loopback
+		if err := lb.DecodeFromBytes(b, gopacket.NilDecodeFeedback); err != nil {
+			return nil, 0, err
+		}
+		next, content = lb.NextLayerType(), lb.Payload
+
+	default:
+		return nil, 0, errors.Errorf("unsupported link type (%s)", lt)
+	}
+
+	switch next {
+	case layers.LayerTypeIPv4:
+		return content, layerIpv4, nil
+	case layers.LayerTypeIPv6:
+		return content, layerIpv6, nil
+	}
+	return nil, 0, errors.Errorf("unknown %s nextLayer", lt)
+}
 
+func decodeIP(content []byte, ipv uint8, ipvPicker IPVPicker) ([]byte, gopacket.Layer, gopacket.LayerType, error) {
 	var lyr gopacket.Layer
 	var next gopacket.LayerType
 	var payload []byte
@@ -0,0 +1,173 @@
+// Copyright 2025 The packetd Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sniffer
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serializeLayers(t *testing.T, lyrs ...gopacket.SerializableLayer) []byte {
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	require.NoError(t, gopacket.SerializeLayers(buf, opts, lyrs...))
+	return buf.Bytes()
+}
+
+func TestDecodeLinkIPLayer(t *testing.T) {
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    net.IPv4(10, 0, 0, 1),
+		DstIP:    net.IPv4(10, 0, 0, 2),
+	}
+	udp := &layers.UDP{SrcPort: 52314, DstPort: 53}
+	require.NoError(t, udp.SetNetworkLayerForChecksum(ip))
+	payload := gopacket.Payload("query")
+
+	tests := []struct {
+		name string
+		lt   layers.LinkType
+		data []byte
+	}{
+		{
+			name: "ethernet",
+			lt:   layers.LinkTypeEthernet,
+			data: serializeLayers(t, &layers.Ethernet{
+				SrcMAC:       net.HardwareAddr{0, 1, 2, 3, 4, 5},
+				DstMAC:       net.HardwareAddr{0, 1, 2, 3, 4, 6},
+				EthernetType: layers.EthernetTypeIPv4,
+			}, ip, udp, payload),
+		},
+		{
+			name: "raw",
+			lt:   layers.LinkTypeRaw,
+			data: serializeLayers(t, ip, udp, payload),
+		},
+		{
+			name: "linux sll",
+			lt:   layers.LinkTypeLinuxSLL,
+			data: append([]byte{0, 0, 0, 1, 0, 6, 0, 1, 2, 3, 4, 5, 0, 0, 0x08, 0x00}, serializeLayers(t, ip, udp, payload)...),
+		},
+		{
+			name: "null",
+			lt:   layers.LinkTypeNull,
+			data: append([]byte{2, 0, 0, 0}, serializeLayers(t, ip, udp, payload)...),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, lyr, next, err := DecodeLinkIPLayer(tt.data, tt.lt, "")
+			require.NoError(t, err)
+			assert.Equal(t, layers.LayerTypeUDP, next)
+			assert.Equal(t, layers.LayerTypeIPv4, lyr.LayerType())
+
+			var udpPkt layers.UDP
+			require.NoError(t, udpPkt.DecodeFromBytes(b, gopacket.NilDecodeFeedback))
+			pkt := ParseUDPDatagram(time.Time{}, lyr, &udpPkt)
+			require.NotNil(t, pkt)
+			assert.Equal(t, []byte("query"), pkt.Payload)
+		})
+	}
+
+	_, _, _, err := DecodeLinkIPLayer([]byte{0x10}, layers.LinkTypeRaw, "")
+	assert.Error(t, err)
+
+	_, _, _, err = DecodeLinkIPLayer([]byte{0x45}, layers.LinkTypeIEEE802_11, "")
+	assert.Error(t, err)
+}
+
+func TestParseTruncatedPacket(t *testing.T) {
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolTCP,
+		SrcIP:    net.IPv4(10, 0, 0, 1),
+		DstIP:    net.IPv4(10, 0, 0, 2),
+	}
+	tcp := &layers.TCP{SrcPort: 52314, DstPort: 6379, Seq: 100, ACK: true, PSH: true}
+	require.NoError(t, tcp.SetNetworkLayerForChecksum(ip))
+	data := serializeLayers(t, ip, tcp, gopacket.Payload(make([]byte, 1000)))
+
+	tests := []struct {
+		name      string
+		snapLen   int
+		payload   int
+		length    int
+		truncated bool
+	}{
+		{name: "full", snapLen: len(data), payload: 1000},
+		{name: "truncated", snapLen: 128, payload: 128 - 40, length: 1000, truncated: true},
+		{name: "header only", snapLen: 40, payload: 0, length: 1000, truncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, lyr, next, err := DecodeLinkIPLayer(data[:tt.snapLen], layers.LinkTypeRaw, "")
+			require.NoError(t, err)
+			assert.Equal(t, layers.LayerTypeTCP, next)
+
+			var tcpPkt layers.TCP
+			require.NoError(t, tcpPkt.DecodeFromBytes(b, gopacket.NilDecodeFeedback))
+			seg := ParseTCPPacket(time.Time{}, lyr, &tcpPkt)
+			require.NotNil(t, seg)
+			assert.Len(t, seg.Payload, tt.payload)
+			assert.Equal(t, tt.length, seg.Length)
+			assert.Equal(t, tt.truncated, seg.Truncated())
+			assert.Equal(t, 1000, seg.PayloadLen())
+			assert.Zero(t, seg.TSVal)
+		})
+	}
+}
+
+func TestParseTCPTimestamp(t *testing.T) {
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolTCP,
+		SrcIP:    net.IPv4(10, 0, 0, 1),
+		DstIP:    net.IPv4(10, 0, 0, 2),
+	}
+	tcp := &layers.TCP{
+		SrcPort: 52314,
+		DstPort: 6379,
+		ACK:     true,
+		PSH:     true,
+		Options: []layers.TCPOption{
+			{OptionType: layers.TCPOptionKindNop},
+			{OptionType: layers.TCPOptionKindNop},
+			{OptionType: layers.TCPOptionKindTimestamps, OptionLength: 10, OptionData: []byte{0, 0, 0x30, 0x39, 0, 0, 0, 1}},
+		},
+	}
+	require.NoError(t, tcp.SetNetworkLayerForChecksum(ip))
+	data := serializeLayers(t, ip, tcp, gopacket.Payload([]byte("PING\r\n")))
+
+	b, lyr, _, err := DecodeLinkIPLayer(data, layers.LinkTypeRaw, "")
+	require.NoError(t, err)
+
+	var tcpPkt layers.TCP
+	require.NoError(t, tcpPkt.DecodeFromBytes(b, gopacket.NilDecodeFeedback))
+	seg := ParseTCPPacket(time.Time{}, lyr, &tcpPkt)
+	require.NotNil(t, seg)
+	assert.Equal(t, uint32(12345), seg.TSVal)
+	assert.Equal(t, []byte("PING\r\n"), seg.Payload)
+}